@@ -0,0 +1,89 @@
+package cmd
+
+import (
+	"fmt"
+	"github.com/aws/aws-cloud-map-mcs-controller-for-k8s/pkg/cloudmap"
+	"github.com/aws/aws-cloud-map-mcs-controller-for-k8s/pkg/common"
+	"github.com/aws/aws-cloud-map-mcs-controller-for-k8s/pkg/route53"
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/sts"
+	"github.com/spf13/cobra"
+	"io"
+)
+
+func newCheckIamCommand() *cobra.Command {
+	awsConfig := cloudmap.NewDefaultAwsConfig()
+	timeoutConfig := cloudmap.NewDefaultSdTimeoutConfig()
+	logConfig := common.NewDefaultLogConfig()
+	var checkRoute53 bool
+
+	cmd := &cobra.Command{
+		Use:   "check-iam",
+		Short: "Checks the credentials are authorized for the Cloud Map actions the controller requires",
+		Long: "Calls each Cloud Map action the controller requires with the configured credentials, referencing " +
+			"resources which don't exist so nothing is modified, and reports the actions which are not authorized. " +
+			"Fails if any action is denied.",
+		Args:         cobra.NoArgs,
+		SilenceUsage: true,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if err := setupLogger(logConfig); err != nil {
+				return fmt.Errorf("invalid log configuration: %w", err)
+			}
+			if err := timeoutConfig.Validate(); err != nil {
+				return fmt.Errorf("invalid Cloud Map timeouts: %w", err)
+			}
+
+			awsCfg, err := awsConfig.Load(cmd.Context())
+			if err != nil {
+				return fmt.Errorf("unable to configure AWS session: %w", err)
+			}
+			timeoutConfig.Apply(&awsCfg)
+
+			identity, err := sts.NewFromConfig(awsCfg).GetCallerIdentity(cmd.Context(), &sts.GetCallerIdentityInput{})
+			if err != nil {
+				return fmt.Errorf("unable to identify the credentials: %w", err)
+			}
+			fmt.Fprintf(cmd.OutOrStdout(), "Checking %s in %s\n\n", aws.ToString(identity.Arn), awsCfg.Region)
+
+			checks := cloudmap.CheckPermissions(cmd.Context(), cloudmap.NewAwsFacadeFromConfig(&awsCfg),
+				awsCfg.Region, aws.ToString(identity.Account))
+			if checkRoute53 {
+				checks = append(checks, route53.CheckPermissions(cmd.Context(), route53.NewAwsFacadeFromConfig(&awsCfg))...)
+			} else {
+				checks = append(checks, cloudmap.PermissionCheck{
+					Action: "route53:*",
+					Result: cloudmap.PermissionNotChecked,
+					Detail: "DNS namespaces and the Route 53 registry require Route 53 actions, use --check-route53",
+				})
+			}
+			return printPermissionChecks(cmd.OutOrStdout(), checks)
+		},
+	}
+
+	cmd.Flags().BoolVar(&checkRoute53, "check-route53", false,
+		"Also check the Route 53 actions required by DNS namespaces and the Route 53 registry.")
+	bindGoFlags(cmd, awsConfig.BindFlags)
+	bindGoFlags(cmd, timeoutConfig.BindFlags)
+	bindGoFlags(cmd, logConfig.BindFlags)
+	return cmd
+}
+
+// printPermissionChecks prints the checks as a table, and returns an error if any action is denied.
+func printPermissionChecks(out io.Writer, checks []cloudmap.PermissionCheck) error {
+	w := newTableWriter(out, "ACTION", "RESULT", "DETAIL")
+	denied := 0
+	for _, check := range checks {
+		if check.Result == cloudmap.PermissionDenied {
+			denied++
+		}
+		w.row(check.Action, check.Result, orNone(check.Detail))
+	}
+	if err := w.flush(); err != nil {
+		return err
+	}
+
+	if denied > 0 {
+		return fmt.Errorf("%d required actions are denied", denied)
+	}
+	return nil
+}
@@ -0,0 +1,25 @@
+package cmd
+
+import (
+	"bytes"
+	"github.com/aws/aws-cloud-map-mcs-controller-for-k8s/pkg/cloudmap"
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func TestPrintPermissionChecks(t *testing.T) {
+	checks := []cloudmap.PermissionCheck{
+		{Action: "servicediscovery:ListNamespaces", Result: cloudmap.PermissionGranted},
+		{Action: "servicediscovery:CreateService", Result: cloudmap.PermissionDenied, Detail: "AccessDeniedException"},
+	}
+
+	out := &bytes.Buffer{}
+	err := printPermissionChecks(out, checks)
+	assert.EqualError(t, err, "1 required actions are denied")
+	assert.Equal(t, "ACTION                            RESULT   DETAIL\n"+
+		"servicediscovery:ListNamespaces   PASS     <none>\n"+
+		"servicediscovery:CreateService    FAIL     AccessDeniedException\n", out.String())
+
+	out.Reset()
+	assert.NoError(t, printPermissionChecks(out, checks[:1]))
+}
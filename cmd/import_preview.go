@@ -0,0 +1,153 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"github.com/aws/aws-cloud-map-mcs-controller-for-k8s/pkg/api/v1alpha1"
+	"github.com/aws/aws-cloud-map-mcs-controller-for-k8s/pkg/cloudmap"
+	"github.com/aws/aws-cloud-map-mcs-controller-for-k8s/pkg/common"
+	"github.com/aws/aws-cloud-map-mcs-controller-for-k8s/pkg/controllers"
+	"github.com/spf13/cobra"
+	"io"
+	v1 "k8s.io/api/core/v1"
+	discovery "k8s.io/api/discovery/v1beta1"
+	"sigs.k8s.io/yaml"
+	"strconv"
+)
+
+const (
+	tableOutput = "table"
+	yamlOutput  = "yaml"
+)
+
+func newImportPreviewCommand(kube *kubeFlags) *cobra.Command {
+	var watchNamespaces string
+	var output string
+	awsConfig := cloudmap.NewDefaultAwsConfig()
+	timeoutConfig := cloudmap.NewDefaultSdTimeoutConfig()
+	logConfig := common.NewDefaultLogConfig()
+
+	cmd := &cobra.Command{
+		Use:   "import-preview CLOUDMAP_NAMESPACE",
+		Short: "Shows the resources the controller would create to import a Cloud Map namespace",
+		Long: "Shows the ServiceImports, derived Services and EndpointSlices the controller would create to import " +
+			"the services of a Cloud Map namespace, in each Kubernetes namespace mapped to it, and the ServiceImports " +
+			"it would delete. Nothing is modified.",
+		Args:         cobra.ExactArgs(1),
+		SilenceUsage: true,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if output != tableOutput && output != yamlOutput {
+				return errors.New("expected --output table or yaml")
+			}
+			if err := setupLogger(logConfig); err != nil {
+				return fmt.Errorf("invalid log configuration: %w", err)
+			}
+			if err := timeoutConfig.Validate(); err != nil {
+				return fmt.Errorf("invalid Cloud Map timeouts: %w", err)
+			}
+
+			awsCfg, err := awsConfig.Load(cmd.Context())
+			if err != nil {
+				return fmt.Errorf("unable to configure AWS session: %w", err)
+			}
+			timeoutConfig.Apply(&awsCfg)
+
+			c, err := kube.newClient()
+			if err != nil {
+				return err
+			}
+			clusterConfig, err := controllers.LoadClusterConfig(cmd.Context(), c)
+			if err != nil {
+				return fmt.Errorf("unable to read the ClusterCloudMapConfig: %w", err)
+			}
+
+			reconciler := &controllers.CloudMapReconciler{
+				Client:        c,
+				Registry:      cloudmap.NewServiceDiscoveryClient(&awsCfg, &cloudmap.SdClientConfig{Timeouts: timeoutConfig}),
+				Log:           common.NewLogger("import-preview"),
+				Namespaces:    common.SplitNamespaces(watchNamespaces),
+				ClusterConfig: clusterConfig,
+			}
+			previews, err := reconciler.PreviewImports(cmd.Context(), args[0])
+			if err != nil {
+				return err
+			}
+			if len(previews) == 0 {
+				return fmt.Errorf("no Kubernetes namespace is mapped to the Cloud Map namespace %s", args[0])
+			}
+
+			if output == yamlOutput {
+				return printImportManifests(cmd.OutOrStdout(), previews)
+			}
+			return printImportPreviews(cmd.OutOrStdout(), previews)
+		},
+	}
+
+	fs := cmd.Flags()
+	kube.bindClusterFlags(fs)
+	fs.StringVar(&watchNamespaces, "watch-namespaces", "",
+		"Comma separated list of namespaces the controller is restricted to, all namespaces are considered if empty.")
+	fs.StringVarP(&output, "output", "o", tableOutput,
+		"Output format, table lists the resources and yaml prints the manifests of the resources.")
+
+	bindGoFlags(cmd, awsConfig.BindFlags)
+	bindGoFlags(cmd, timeoutConfig.BindFlags)
+	bindGoFlags(cmd, logConfig.BindFlags)
+	return cmd
+}
+
+func printImportPreviews(out io.Writer, previews []*controllers.NamespaceImportPreview) error {
+	w := newTableWriter(out, "NAMESPACE", "NAME", "SERVICEIMPORT", "DERIVED SERVICE", "ENDPOINTS", "ENDPOINTSLICES",
+		"PORTS")
+	for _, preview := range previews {
+		for _, imp := range preview.Imports {
+			endpoints := 0
+			for _, slice := range imp.EndpointSlices {
+				endpoints += len(slice.Endpoints)
+			}
+			w.row(preview.Namespace, imp.ServiceImport.Name, importAction(imp.ServiceImportExists),
+				imp.DerivedService.Name+" ("+importAction(imp.DerivedServiceExists)+")", strconv.Itoa(endpoints),
+				strconv.Itoa(len(imp.EndpointSlices)), importPorts(imp.ServiceImport.Spec.Ports))
+		}
+		for _, name := range preview.DeletedImports {
+			w.row(preview.Namespace, name, "delete", "<none>", "0", "0", "<none>")
+		}
+	}
+	return w.flush()
+}
+
+func importAction(exists bool) string {
+	if exists {
+		return "exists"
+	}
+	return "create"
+}
+
+// printImportManifests prints the imported resources as a multi-document YAML stream.
+func printImportManifests(out io.Writer, previews []*controllers.NamespaceImportPreview) error {
+	for _, preview := range previews {
+		for _, imp := range preview.Imports {
+			imp.ServiceImport.APIVersion = v1alpha1.GroupVersion.String()
+			imp.ServiceImport.Kind = "ServiceImport"
+			imp.DerivedService.APIVersion = v1.SchemeGroupVersion.String()
+			imp.DerivedService.Kind = "Service"
+			objects := []interface{}{imp.ServiceImport, imp.DerivedService}
+			for _, slice := range imp.EndpointSlices {
+				slice.APIVersion = discovery.SchemeGroupVersion.String()
+				slice.Kind = "EndpointSlice"
+				objects = append(objects, slice)
+			}
+
+			for _, obj := range objects {
+				data, err := yaml.Marshal(obj)
+				if err != nil {
+					return err
+				}
+				if _, err = fmt.Fprintf(out, "---\n%s", data); err != nil {
+					return err
+				}
+			}
+		}
+	}
+	return nil
+}
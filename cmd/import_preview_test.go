@@ -0,0 +1,54 @@
+package cmd
+
+import (
+	"bytes"
+	"github.com/aws/aws-cloud-map-mcs-controller-for-k8s/pkg/api/v1alpha1"
+	"github.com/aws/aws-cloud-map-mcs-controller-for-k8s/pkg/controllers"
+	"github.com/aws/aws-cloud-map-mcs-controller-for-k8s/test"
+	"github.com/stretchr/testify/assert"
+	v1 "k8s.io/api/core/v1"
+	discovery "k8s.io/api/discovery/v1beta1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"strings"
+	"testing"
+)
+
+func TestPrintImportPreviews(t *testing.T) {
+	previews := testImportPreviews()
+
+	out := &bytes.Buffer{}
+	assert.NoError(t, printImportPreviews(out, previews))
+	lines := strings.Split(strings.TrimSpace(out.String()), "\n")
+	assert.Len(t, lines, 3)
+	assert.Regexp(t, test.SvcName+`\s+create\s+imported-svc \(exists\)\s+1\s+1\s+11/TCP$`, lines[1])
+	assert.Regexp(t, `stale\s+delete\s+<none>`, lines[2])
+}
+
+func TestPrintImportManifests(t *testing.T) {
+	out := &bytes.Buffer{}
+	assert.NoError(t, printImportManifests(out, testImportPreviews()))
+	assert.Equal(t, 3, strings.Count(out.String(), "---\n"))
+	assert.Contains(t, out.String(), "kind: ServiceImport")
+	assert.Contains(t, out.String(), "kind: Service\n")
+	assert.Contains(t, out.String(), "kind: EndpointSlice")
+}
+
+func testImportPreviews() []*controllers.NamespaceImportPreview {
+	return []*controllers.NamespaceImportPreview{{
+		Namespace: test.NsName,
+		Imports: []*controllers.ImportPreview{{
+			ServiceImport: &v1alpha1.ServiceImport{
+				ObjectMeta: metav1.ObjectMeta{Namespace: test.NsName, Name: test.SvcName},
+				Spec: v1alpha1.ServiceImportSpec{
+					Ports: []v1alpha1.ServicePort{{Port: test.ServicePort1, Protocol: test.Protocol1}},
+				},
+			},
+			DerivedService: &v1.Service{ObjectMeta: metav1.ObjectMeta{Namespace: test.NsName, Name: "imported-svc"}},
+			EndpointSlices: []*discovery.EndpointSlice{{
+				Endpoints: []discovery.Endpoint{{Addresses: []string{test.EndptIp1}}},
+			}},
+			DerivedServiceExists: true,
+		}},
+		DeletedImports: []string{"stale"},
+	}}
+}
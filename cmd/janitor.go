@@ -0,0 +1,130 @@
+package cmd
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/aws/aws-cloud-map-mcs-controller-for-k8s/pkg/cloudmap"
+	"github.com/aws/aws-cloud-map-mcs-controller-for-k8s/pkg/common"
+	"github.com/aws/aws-cloud-map-mcs-controller-for-k8s/pkg/janitor"
+	"github.com/spf13/cobra"
+)
+
+type janitorFlags struct {
+	opts           janitor.Options
+	namespaceGlob  string
+	namespaceRegex string
+	auditLogPath   string
+}
+
+func newJanitorCommand() *cobra.Command {
+	flags := &janitorFlags{opts: janitor.Options{Concurrency: janitor.DefaultConcurrency}}
+	awsConfig := cloudmap.NewDefaultAwsConfig()
+	timeoutConfig := cloudmap.NewDefaultSdTimeoutConfig()
+	logConfig := common.NewDefaultLogConfig()
+
+	cmd := &cobra.Command{
+		Use:   "janitor [namespace]",
+		Short: "Deletes the Cloud Map resources created by the controller",
+		Long: "Deletes the instances, services and namespace created by the controller from Cloud Map, " +
+			"for a single namespace or all namespaces matching --namespace-glob or --namespace-regex. " +
+			"Only resources carrying the ownership tags of the controller are deleted, unless --force-untagged is set.",
+		Args:         cobra.MaximumNArgs(1),
+		SilenceUsage: true,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if err := flags.validate(args); err != nil {
+				return err
+			}
+			if err := setupLogger(logConfig); err != nil {
+				return fmt.Errorf("invalid log configuration: %w", err)
+			}
+			if err := timeoutConfig.Validate(); err != nil {
+				return fmt.Errorf("invalid Cloud Map timeouts: %w", err)
+			}
+
+			awsCfg, err := awsConfig.Load(cmd.Context())
+			if err != nil {
+				return fmt.Errorf("unable to configure AWS session: %w", err)
+			}
+			timeoutConfig.Apply(&awsCfg)
+
+			if flags.auditLogPath != "" {
+				auditLogger, closer, err := cloudmap.NewAuditLoggerFromPath(flags.auditLogPath, "")
+				if err != nil {
+					return fmt.Errorf("unable to open audit log: %w", err)
+				}
+				defer closer.Close()
+				flags.opts.Audit = auditLogger
+			}
+
+			return flags.run(cmd.Context(), janitor.NewJanitor(&awsCfg, flags.opts), args)
+		},
+	}
+
+	fs := cmd.Flags()
+	fs.BoolVar(&flags.opts.ForceUntagged, "force-untagged", false,
+		"Delete namespaces and services which don't carry the ownership tags of the controller.")
+	fs.BoolVar(&flags.opts.DryRun, "dry-run", false,
+		"List the namespace, services and instances which would be deleted without deleting them.")
+	fs.DurationVar(&flags.opts.OlderThan, "older-than", 0,
+		"Only delete namespaces and services created longer ago than this duration, e.g. 24h.")
+	fs.StringVar(&flags.namespaceGlob, "namespace-glob", "",
+		"Clean up all namespaces with names matching this glob pattern instead of a single namespace.")
+	fs.StringVar(&flags.namespaceRegex, "namespace-regex", "",
+		"Clean up all namespaces with names matching this regular expression instead of a single namespace.")
+	fs.IntVar(&flags.opts.Concurrency, "concurrency", flags.opts.Concurrency,
+		"Number of namespaces, and of services per namespace, cleaned up in parallel.")
+	fs.StringVar(&flags.opts.DrainClusterId, "drain-cluster-id", "",
+		"Only de-register the instances of the cluster with this ID, keeping services and namespaces.")
+	fs.StringVar(&flags.opts.ReportFile, "report", "",
+		"Write a report of the discovered resources, actions taken and failures to this file, - writes to stdout.")
+	fs.StringVar(&flags.opts.ReportFormat, "report-format", janitor.JsonReportFormat,
+		"Format of the report, json or yaml.")
+	fs.StringVar(&flags.auditLogPath, "audit-log", "",
+		"Append the de-registrations and deletions to this audit log file, - writes to stdout.")
+
+	bindGoFlags(cmd, awsConfig.BindFlags)
+	bindGoFlags(cmd, timeoutConfig.BindFlags)
+	bindGoFlags(cmd, logConfig.BindFlags)
+	return cmd
+}
+
+func (f *janitorFlags) validate(args []string) error {
+	if f.opts.Concurrency < 1 {
+		return errors.New("expected --concurrency of at least 1")
+	}
+	if f.opts.ReportFormat != janitor.JsonReportFormat && f.opts.ReportFormat != janitor.YamlReportFormat {
+		return errors.New("expected --report-format json or yaml")
+	}
+
+	patterns := 0
+	if f.namespaceGlob != "" {
+		patterns++
+	}
+	if f.namespaceRegex != "" {
+		patterns++
+	}
+	if patterns+len(args) != 1 {
+		return errors.New("expected either a namespace name argument, --namespace-glob or --namespace-regex")
+	}
+	return nil
+}
+
+func (f *janitorFlags) run(ctx context.Context, j janitor.CloudMapJanitor, args []string) error {
+	if len(args) == 1 {
+		return j.Cleanup(ctx, args[0])
+	}
+
+	var matcher janitor.NamespaceMatcher
+	var err error
+	if f.namespaceGlob != "" {
+		matcher, err = janitor.NewGlobMatcher(f.namespaceGlob)
+	} else {
+		matcher, err = janitor.NewRegexMatcher(f.namespaceRegex)
+	}
+	if err != nil {
+		return err
+	}
+
+	return j.CleanupMatching(ctx, matcher)
+}
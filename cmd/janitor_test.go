@@ -0,0 +1,64 @@
+package cmd
+
+import (
+	"bytes"
+	"errors"
+	"github.com/aws/aws-cloud-map-mcs-controller-for-k8s/pkg/janitor"
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func TestNewRootCommand_RunsManager(t *testing.T) {
+	for _, args := range [][]string{
+		{"--leader-elect", "--cluster-id", "cluster-1"},
+		{"manager", "--leader-elect", "--cluster-id", "cluster-1"},
+	} {
+		var managerArgs []string
+		root := newRootCommand(func(args []string) error {
+			managerArgs = args
+			return nil
+		})
+		root.SetArgs(args)
+
+		assert.NoError(t, root.Execute())
+		assert.Equal(t, []string{"--leader-elect", "--cluster-id", "cluster-1"}, managerArgs)
+	}
+}
+
+func TestNewRootCommand_ReturnsManagerError(t *testing.T) {
+	root := newRootCommand(func(args []string) error { return errors.New("unable to start manager") })
+	root.SetArgs([]string{"--leader-elect"})
+	root.SetErr(&bytes.Buffer{})
+
+	assert.EqualError(t, root.Execute(), "unable to start manager")
+}
+
+func TestNewRootCommand_Janitor(t *testing.T) {
+	root := newRootCommand(func(args []string) error { t.Fatal("manager must not run"); return nil })
+	cmd, _, err := root.Find([]string{"janitor", "--dry-run", "e2e"})
+	assert.NoError(t, err)
+	assert.Equal(t, "janitor", cmd.Name())
+
+	for _, flag := range []string{"aws-region", "aws-api-call-timeout", "log-level", "namespace-glob", "audit-log"} {
+		assert.NotNil(t, cmd.Flags().Lookup(flag), "flag %s", flag)
+	}
+}
+
+func TestJanitorFlags_Validate(t *testing.T) {
+	valid := &janitorFlags{opts: janitor.Options{Concurrency: 1, ReportFormat: janitor.JsonReportFormat}}
+	assert.NoError(t, valid.validate([]string{"e2e"}))
+	assert.Error(t, valid.validate(nil), "no namespace")
+
+	glob := *valid
+	glob.namespaceGlob = "e2e-*"
+	assert.NoError(t, glob.validate(nil))
+	assert.Error(t, glob.validate([]string{"e2e"}), "namespace and pattern")
+
+	noConcurrency := *valid
+	noConcurrency.opts.Concurrency = 0
+	assert.Error(t, noConcurrency.validate([]string{"e2e"}))
+
+	badFormat := *valid
+	badFormat.opts.ReportFormat = "xml"
+	assert.Error(t, badFormat.validate([]string{"e2e"}))
+}
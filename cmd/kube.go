@@ -0,0 +1,84 @@
+package cmd
+
+import (
+	cloudmapv1alpha1 "github.com/aws/aws-cloud-map-mcs-controller-for-k8s/pkg/api/cloudmap/v1alpha1"
+	multiclusterv1alpha1 "github.com/aws/aws-cloud-map-mcs-controller-for-k8s/pkg/api/v1alpha1"
+	multiclusterv1beta1 "github.com/aws/aws-cloud-map-mcs-controller-for-k8s/pkg/api/v1beta1"
+	"github.com/spf13/pflag"
+	"k8s.io/apimachinery/pkg/runtime"
+	utilruntime "k8s.io/apimachinery/pkg/util/runtime"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	"k8s.io/client-go/tools/clientcmd"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+var scheme = runtime.NewScheme()
+
+func init() {
+	utilruntime.Must(clientgoscheme.AddToScheme(scheme))
+	utilruntime.Must(multiclusterv1alpha1.AddToScheme(scheme))
+	utilruntime.Must(multiclusterv1beta1.AddToScheme(scheme))
+	utilruntime.Must(cloudmapv1alpha1.AddToScheme(scheme))
+	//+kubebuilder:scaffold:scheme
+}
+
+// kubeFlags select the cluster and namespace of commands reading Kubernetes resources, like kubectl does.
+type kubeFlags struct {
+	kubeconfig    string
+	context       string
+	namespace     string
+	allNamespaces bool
+
+	// newClient creates the Kubernetes client, replaced in tests
+	newClient func() (client.Client, error)
+}
+
+func newKubeFlags() *kubeFlags {
+	f := &kubeFlags{}
+	f.newClient = f.defaultClient
+	return f
+}
+
+func (f *kubeFlags) bindFlags(fs *pflag.FlagSet) {
+	f.bindClusterFlags(fs)
+	fs.StringVarP(&f.namespace, "namespace", "n", "", "The namespace, the namespace of the context if empty.")
+	fs.BoolVarP(&f.allNamespaces, "all-namespaces", "A", false, "List resources across all namespaces.")
+}
+
+// bindClusterFlags binds the flags selecting the cluster only, for commands which select namespaces otherwise.
+func (f *kubeFlags) bindClusterFlags(fs *pflag.FlagSet) {
+	fs.StringVar(&f.kubeconfig, "kubeconfig", "", "Path to the kubeconfig file, the default loading rules apply if empty.")
+	fs.StringVar(&f.context, "context", "", "The kubeconfig context to use, the current context if empty.")
+}
+
+func (f *kubeFlags) clientConfig() clientcmd.ClientConfig {
+	loadingRules := clientcmd.NewDefaultClientConfigLoadingRules()
+	loadingRules.ExplicitPath = f.kubeconfig
+	return clientcmd.NewNonInteractiveDeferredLoadingClientConfig(loadingRules,
+		&clientcmd.ConfigOverrides{CurrentContext: f.context})
+}
+
+func (f *kubeFlags) defaultClient() (client.Client, error) {
+	restConfig, err := f.clientConfig().ClientConfig()
+	if err != nil {
+		return nil, err
+	}
+	return client.New(restConfig, client.Options{Scheme: scheme})
+}
+
+// listNamespace returns the namespace to list resources in, empty for all namespaces.
+func (f *kubeFlags) listNamespace() (string, error) {
+	if f.allNamespaces {
+		return "", nil
+	}
+	return f.resourceNamespace()
+}
+
+// resourceNamespace returns the namespace of a named resource.
+func (f *kubeFlags) resourceNamespace() (string, error) {
+	if f.namespace != "" {
+		return f.namespace, nil
+	}
+	namespace, _, err := f.clientConfig().Namespace()
+	return namespace, err
+}
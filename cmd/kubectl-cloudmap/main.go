@@ -0,0 +1,15 @@
+package main
+
+import (
+	"github.com/aws/aws-cloud-map-mcs-controller-for-k8s/cmd"
+	"os"
+
+	// Import all Kubernetes client auth plugins (e.g. Azure, GCP, OIDC, etc.)
+	_ "k8s.io/client-go/plugin/pkg/client/auth"
+)
+
+func main() {
+	if err := cmd.NewKubectlPluginCommand().Execute(); err != nil {
+		os.Exit(1)
+	}
+}
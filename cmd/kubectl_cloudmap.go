@@ -0,0 +1,297 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"github.com/aws/aws-cloud-map-mcs-controller-for-k8s/pkg/api/v1alpha1"
+	"github.com/aws/aws-cloud-map-mcs-controller-for-k8s/pkg/cloudmap"
+	"github.com/aws/aws-cloud-map-mcs-controller-for-k8s/pkg/controllers"
+	"github.com/aws/aws-cloud-map-mcs-controller-for-k8s/pkg/model"
+	"github.com/spf13/cobra"
+	"io"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/duration"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sort"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+	"time"
+)
+
+// NewKubectlPluginCommand returns the kubectl-cloudmap plugin command, which inspects the exported and imported
+// services of the clusterset in the cluster and in Cloud Map.
+func NewKubectlPluginCommand() *cobra.Command {
+	return newKubectlPluginCommand(newKubeFlags())
+}
+
+func newKubectlPluginCommand(kube *kubeFlags) *cobra.Command {
+	root := &cobra.Command{
+		Use:          "kubectl-cloudmap",
+		Short:        "Inspects the multi-cluster services of the AWS Cloud Map MCS Controller",
+		SilenceUsage: true,
+	}
+	kube.bindFlags(root.PersistentFlags())
+
+	root.AddCommand(
+		newExportsCommand(kube),
+		newImportsCommand(kube),
+		newStatusCommand(kube),
+		newResolveCommand(kube),
+	)
+	return root
+}
+
+func newExportsCommand(kube *kubeFlags) *cobra.Command {
+	return &cobra.Command{
+		Use:   "exports",
+		Short: "Lists the ServiceExports",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			exports, err := listExports(cmd.Context(), kube)
+			if err != nil {
+				return err
+			}
+
+			w := newTableWriter(cmd.OutOrStdout(), "NAMESPACE", "NAME", "VALID", "CONFLICT", "ENDPOINTS",
+				"CLOUDMAP SERVICE", "AGE")
+			for _, export := range exports {
+				w.row(export.Namespace, export.Name,
+					conditionStatus(export.Status.Conditions, string(v1alpha1.ServiceExportValid)),
+					conditionStatus(export.Status.Conditions, string(v1alpha1.ServiceExportConflict)),
+					strconv.Itoa(int(export.Status.Endpoints)), orNone(export.Status.CloudMapServiceId),
+					age(export.CreationTimestamp))
+			}
+			return w.flush()
+		},
+	}
+}
+
+func newImportsCommand(kube *kubeFlags) *cobra.Command {
+	return &cobra.Command{
+		Use:   "imports",
+		Short: "Lists the ServiceImports",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			imports, err := listImports(cmd.Context(), kube)
+			if err != nil {
+				return err
+			}
+
+			w := newTableWriter(cmd.OutOrStdout(), "NAMESPACE", "NAME", "TYPE", "IP", "PORTS", "CLUSTERS", "AGE")
+			for _, svcImport := range imports {
+				w.row(svcImport.Namespace, svcImport.Name, string(svcImport.Spec.Type),
+					orNone(strings.Join(svcImport.Spec.IPs, ",")), importPorts(svcImport.Spec.Ports),
+					orNone(importClusters(svcImport.Status.Clusters)), age(svcImport.CreationTimestamp))
+			}
+			return w.flush()
+		},
+	}
+}
+
+func newStatusCommand(kube *kubeFlags) *cobra.Command {
+	return &cobra.Command{
+		Use:   "status",
+		Short: "Shows the sync status of the exported services",
+		Long: "Shows the sync status of each ServiceExport, with the message of its conditions and the clusters " +
+			"of the matching ServiceImport.",
+		Args: cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			exports, err := listExports(cmd.Context(), kube)
+			if err != nil {
+				return err
+			}
+			imports, err := listImports(cmd.Context(), kube)
+			if err != nil {
+				return err
+			}
+
+			importClustersByName := make(map[string]string, len(imports))
+			for i := range imports {
+				importClustersByName[objectKey(&imports[i])] = importClusters(imports[i].Status.Clusters)
+			}
+
+			w := newTableWriter(cmd.OutOrStdout(), "NAMESPACE", "NAME", "STATUS", "ENDPOINTS", "IMPORTED FROM",
+				"MESSAGE")
+			for _, export := range exports {
+				status, message := exportStatus(export)
+				clusters, imported := importClustersByName[objectKey(&export)]
+				if !imported {
+					clusters = "not imported"
+				}
+				w.row(export.Namespace, export.Name, status, strconv.Itoa(int(export.Status.Endpoints)),
+					orNone(clusters), message)
+			}
+			return w.flush()
+		},
+	}
+}
+
+func newResolveCommand(kube *kubeFlags) *cobra.Command {
+	awsConfig := cloudmap.NewDefaultAwsConfig()
+	cmd := &cobra.Command{
+		Use:   "resolve SERVICE",
+		Short: "Lists the Cloud Map instances of a service",
+		Long: "Lists the instances currently registered in Cloud Map for the service in the namespace, " +
+			"across all clusters of the clusterset.",
+		Args: cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			namespace, err := kube.resourceNamespace()
+			if err != nil {
+				return err
+			}
+
+			awsCfg, err := awsConfig.Load(cmd.Context())
+			if err != nil {
+				return fmt.Errorf("unable to configure AWS session: %w", err)
+			}
+			sdClient := cloudmap.NewServiceDiscoveryClient(&awsCfg, &cloudmap.SdClientConfig{})
+			svc, err := sdClient.GetService(cmd.Context(), namespace, args[0])
+			if err != nil {
+				return err
+			}
+			if svc == nil {
+				return fmt.Errorf("service %s/%s not found in Cloud Map", namespace, args[0])
+			}
+
+			return printInstances(cmd.OutOrStdout(), svc)
+		},
+	}
+	bindGoFlags(cmd, awsConfig.BindFlags)
+	return cmd
+}
+
+func printInstances(out io.Writer, svc *model.Service) error {
+	endpoints := append([]*model.Endpoint{}, svc.Endpoints...)
+	sort.Slice(endpoints, func(i, j int) bool {
+		return endpoints[i].Id < endpoints[j].Id
+	})
+
+	w := newTableWriter(out, "INSTANCE", "IP", "PORT", "SERVICE PORT", "PROTOCOL", "CLUSTER")
+	for _, endpoint := range endpoints {
+		w.row(endpoint.Id, endpoint.IP, strconv.Itoa(int(endpoint.EndpointPort.Port)),
+			strconv.Itoa(int(endpoint.ServicePort.Port)), endpoint.EndpointPort.Protocol,
+			orNone(endpoint.Attributes[controllers.ClusterIdAttr]))
+	}
+	return w.flush()
+}
+
+func listExports(ctx context.Context, kube *kubeFlags) ([]v1alpha1.ServiceExport, error) {
+	c, namespace, err := listClient(kube)
+	if err != nil {
+		return nil, err
+	}
+	exports := &v1alpha1.ServiceExportList{}
+	if err = c.List(ctx, exports, client.InNamespace(namespace)); err != nil {
+		return nil, err
+	}
+	sort.Slice(exports.Items, func(i, j int) bool {
+		return objectKey(&exports.Items[i]) < objectKey(&exports.Items[j])
+	})
+	return exports.Items, nil
+}
+
+func listImports(ctx context.Context, kube *kubeFlags) ([]v1alpha1.ServiceImport, error) {
+	c, namespace, err := listClient(kube)
+	if err != nil {
+		return nil, err
+	}
+	imports := &v1alpha1.ServiceImportList{}
+	if err = c.List(ctx, imports, client.InNamespace(namespace)); err != nil {
+		return nil, err
+	}
+	sort.Slice(imports.Items, func(i, j int) bool {
+		return objectKey(&imports.Items[i]) < objectKey(&imports.Items[j])
+	})
+	return imports.Items, nil
+}
+
+func objectKey(obj metav1.Object) string {
+	return obj.GetNamespace() + "/" + obj.GetName()
+}
+
+func listClient(kube *kubeFlags) (client.Client, string, error) {
+	namespace, err := kube.listNamespace()
+	if err != nil {
+		return nil, "", err
+	}
+	c, err := kube.newClient()
+	return c, namespace, err
+}
+
+// exportStatus summarizes the conditions of an export: Conflict, Invalid, Exported or Pending.
+func exportStatus(export v1alpha1.ServiceExport) (status string, message string) {
+	conflict := meta.FindStatusCondition(export.Status.Conditions, string(v1alpha1.ServiceExportConflict))
+	if conflict != nil && conflict.Status == metav1.ConditionTrue {
+		return "Conflict", conflict.Message
+	}
+
+	valid := meta.FindStatusCondition(export.Status.Conditions, string(v1alpha1.ServiceExportValid))
+	switch {
+	case valid == nil:
+		return "Pending", ""
+	case valid.Status == metav1.ConditionFalse:
+		return "Invalid", valid.Message
+	case valid.Status == metav1.ConditionTrue:
+		return "Exported", valid.Message
+	default:
+		return "Pending", valid.Message
+	}
+}
+
+func conditionStatus(conditions []metav1.Condition, conditionType string) string {
+	if condition := meta.FindStatusCondition(conditions, conditionType); condition != nil {
+		return string(condition.Status)
+	}
+	return "<none>"
+}
+
+func importPorts(ports []v1alpha1.ServicePort) string {
+	result := make([]string, 0, len(ports))
+	for _, port := range ports {
+		result = append(result, fmt.Sprintf("%d/%s", port.Port, port.Protocol))
+	}
+	return orNone(strings.Join(result, ","))
+}
+
+func importClusters(clusters []v1alpha1.ClusterStatus) string {
+	result := make([]string, 0, len(clusters))
+	for _, cluster := range clusters {
+		result = append(result, cluster.Cluster)
+	}
+	return strings.Join(result, ",")
+}
+
+func age(timestamp metav1.Time) string {
+	if timestamp.IsZero() {
+		return "<unknown>"
+	}
+	return duration.HumanDuration(time.Since(timestamp.Time))
+}
+
+func orNone(value string) string {
+	if value == "" {
+		return "<none>"
+	}
+	return value
+}
+
+// tableWriter prints kubectl style tables.
+type tableWriter struct {
+	w *tabwriter.Writer
+}
+
+func newTableWriter(out io.Writer, headers ...string) *tableWriter {
+	t := &tableWriter{w: tabwriter.NewWriter(out, 0, 8, 3, ' ', 0)}
+	t.row(headers...)
+	return t
+}
+
+func (t *tableWriter) row(columns ...string) {
+	fmt.Fprintln(t.w, strings.Join(columns, "\t"))
+}
+
+func (t *tableWriter) flush() error {
+	return t.w.Flush()
+}
@@ -0,0 +1,95 @@
+package cmd
+
+import (
+	"bytes"
+	"github.com/aws/aws-cloud-map-mcs-controller-for-k8s/pkg/api/v1alpha1"
+	"github.com/aws/aws-cloud-map-mcs-controller-for-k8s/pkg/controllers"
+	"github.com/aws/aws-cloud-map-mcs-controller-for-k8s/pkg/model"
+	"github.com/aws/aws-cloud-map-mcs-controller-for-k8s/test"
+	"github.com/stretchr/testify/assert"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	"strings"
+	"testing"
+)
+
+func TestKubectlPlugin_Status(t *testing.T) {
+	exported := exportWithConditions(test.SvcName, metav1.Condition{
+		Type: string(v1alpha1.ServiceExportValid), Status: metav1.ConditionTrue, Message: "exported to Cloud Map",
+	})
+	conflicting := exportWithConditions("conflicting", metav1.Condition{
+		Type: string(v1alpha1.ServiceExportValid), Status: metav1.ConditionTrue,
+	}, metav1.Condition{
+		Type: string(v1alpha1.ServiceExportConflict), Status: metav1.ConditionTrue, Message: "conflicting ports",
+	})
+	svcImport := &v1alpha1.ServiceImport{
+		ObjectMeta: metav1.ObjectMeta{Namespace: test.NsName, Name: test.SvcName},
+		Status:     v1alpha1.ServiceImportStatus{Clusters: []v1alpha1.ClusterStatus{{Cluster: test.ClusterId}}},
+	}
+
+	out := executePlugin(t, []string{"status", "-n", test.NsName}, exported, conflicting, svcImport)
+	lines := strings.Split(strings.TrimSpace(out), "\n")
+	assert.Len(t, lines, 3)
+	assert.Regexp(t, `^NAMESPACE\s+NAME\s+STATUS`, lines[0])
+	assert.Regexp(t, `conflicting\s+Conflict\s+0\s+not imported\s+conflicting ports$`, lines[1])
+	assert.Regexp(t, test.SvcName+`\s+Exported\s+0\s+`+test.ClusterId+`\s+exported to Cloud Map$`, lines[2])
+}
+
+func TestKubectlPlugin_Exports(t *testing.T) {
+	export := exportWithConditions(test.SvcName)
+	other := exportWithConditions("other")
+	other.Namespace = "other-ns"
+
+	out := executePlugin(t, []string{"exports", "-A"}, export, other)
+	assert.Contains(t, out, test.SvcName)
+	assert.Contains(t, out, "other-ns")
+
+	out = executePlugin(t, []string{"exports", "--namespace", test.NsName}, export, other)
+	assert.Contains(t, out, test.SvcName)
+	assert.NotContains(t, out, "other-ns")
+}
+
+func TestPrintInstances(t *testing.T) {
+	svc := &model.Service{Endpoints: []*model.Endpoint{
+		{
+			Id: test.EndptId2, IP: test.EndptIp2,
+			EndpointPort: model.Port{Port: test.Port2, Protocol: test.Protocol2},
+			ServicePort:  model.Port{Port: test.ServicePort2},
+		},
+		{
+			Id: test.EndptId1, IP: test.EndptIp1,
+			EndpointPort: model.Port{Port: test.Port1, Protocol: test.Protocol1},
+			ServicePort:  model.Port{Port: test.ServicePort1},
+			Attributes:   map[string]string{controllers.ClusterIdAttr: test.ClusterId},
+		},
+	}}
+
+	out := &bytes.Buffer{}
+	assert.NoError(t, printInstances(out, svc))
+	lines := strings.Split(strings.TrimSpace(out.String()), "\n")
+	assert.Len(t, lines, 3)
+	assert.Regexp(t, "^"+test.EndptId1+`\s+`+test.EndptIp1+`.*`+test.ClusterId+"$", lines[1])
+	assert.Regexp(t, "^"+test.EndptId2+`.*<none>$`, lines[2])
+}
+
+func executePlugin(t *testing.T, args []string, objs ...runtime.Object) string {
+	fakeClient := fake.NewClientBuilder().WithScheme(scheme).WithRuntimeObjects(objs...).Build()
+	kube := newKubeFlags()
+	kube.newClient = func() (client.Client, error) { return fakeClient, nil }
+	plugin := newKubectlPluginCommand(kube)
+
+	out := &bytes.Buffer{}
+	plugin.SetOut(out)
+	plugin.SetArgs(args)
+	assert.NoError(t, plugin.Execute())
+	return out.String()
+}
+
+func exportWithConditions(name string, conditions ...metav1.Condition) *v1alpha1.ServiceExport {
+	return &v1alpha1.ServiceExport{
+		ObjectMeta: metav1.ObjectMeta{Namespace: test.NsName, Name: name},
+		Status:     v1alpha1.ServiceExportStatus{Conditions: conditions},
+	}
+}
@@ -0,0 +1,188 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"github.com/aws/aws-cloud-map-mcs-controller-for-k8s/pkg/cloudmap"
+	"github.com/aws/aws-cloud-map-mcs-controller-for-k8s/pkg/cloudmap/cloudmaptest"
+	"github.com/aws/aws-cloud-map-mcs-controller-for-k8s/pkg/common"
+	"github.com/aws/aws-cloud-map-mcs-controller-for-k8s/pkg/controllers"
+	"github.com/aws/aws-cloud-map-mcs-controller-for-k8s/pkg/loadtest"
+	sdtypes "github.com/aws/aws-sdk-go-v2/service/servicediscovery/types"
+	"github.com/spf13/cobra"
+	"io"
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/tools/record"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	"time"
+)
+
+type loadTestFlags struct {
+	opts        loadtest.Options
+	keep        bool
+	fake        bool
+	fakeLatency time.Duration
+	fakeWorkers int
+}
+
+func newLoadTestCommand(kube *kubeFlags) *cobra.Command {
+	flags := &loadTestFlags{}
+	awsConfig := cloudmap.NewDefaultAwsConfig()
+	timeoutConfig := cloudmap.NewDefaultSdTimeoutConfig()
+	logConfig := common.NewDefaultLogConfig()
+
+	cmd := &cobra.Command{
+		Use:   "load-test",
+		Short: "Measures the propagation latency of synthetic ServiceExports to Cloud Map",
+		Long: "Creates synthetic Services with fake endpoints and exports them, then polls Cloud Map until all " +
+			"endpoints are registered and reports the propagation latencies, to validate the sizing of the " +
+			"controller and Cloud Map quotas before production rollout. Run it against a sandbox cluster and AWS " +
+			"account running the controller, or with --fake against an in-process controller and an in-memory " +
+			"Cloud Map. The created resources are deleted afterwards unless --keep is set.",
+		Args:         cobra.NoArgs,
+		SilenceUsage: true,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if err := flags.opts.Validate(); err != nil {
+				return err
+			}
+			if err := setupLogger(logConfig); err != nil {
+				return fmt.Errorf("invalid log configuration: %w", err)
+			}
+
+			var result *loadtest.Result
+			var err error
+			if flags.fake {
+				result, err = flags.runFake(cmd.Context())
+			} else {
+				result, err = flags.run(cmd.Context(), kube, awsConfig, timeoutConfig)
+			}
+			if err != nil {
+				return err
+			}
+			return printLoadTestResult(cmd.OutOrStdout(), result)
+		},
+	}
+
+	fs := cmd.Flags()
+	kube.bindClusterFlags(fs)
+	fs.StringVarP(&flags.opts.Namespace, "namespace", "n", "cloudmap-load-test",
+		"The existing namespace the synthetic services are created in.")
+	fs.IntVar(&flags.opts.Services, "services", 10, "The number of ServiceExports to create.")
+	fs.IntVar(&flags.opts.EndpointsPerService, "endpoints", 10, "The number of endpoints of each service.")
+	fs.DurationVar(&flags.opts.PollInterval, "poll-interval", 5*time.Second,
+		"The interval Cloud Map is polled for the registered endpoints, use a shorter interval with --fake.")
+	fs.DurationVar(&flags.opts.Timeout, "timeout", 10*time.Minute,
+		"The time the services may take to be registered in Cloud Map.")
+	fs.BoolVar(&flags.keep, "keep", false, "Keep the synthetic services after the test.")
+	fs.BoolVar(&flags.fake, "fake", false,
+		"Run the ServiceExport reconciler in-process against an in-memory cluster and Cloud Map.")
+	fs.DurationVar(&flags.fakeLatency, "fake-latency", 50*time.Millisecond,
+		"The latency of each call to the in-memory Cloud Map.")
+	fs.IntVar(&flags.fakeWorkers, "fake-workers", 1,
+		"The number of concurrent reconciles of the in-process reconciler.")
+
+	bindGoFlags(cmd, awsConfig.BindFlags)
+	bindGoFlags(cmd, timeoutConfig.BindFlags)
+	bindGoFlags(cmd, logConfig.BindFlags)
+	return cmd
+}
+
+// run creates the synthetic services in the cluster, and waits for the controller of the cluster to register them.
+func (f *loadTestFlags) run(ctx context.Context, kube *kubeFlags, awsConfig *cloudmap.AwsConfig, timeoutConfig *cloudmap.SdTimeoutConfig) (*loadtest.Result, error) {
+	if err := timeoutConfig.Validate(); err != nil {
+		return nil, fmt.Errorf("invalid Cloud Map timeouts: %w", err)
+	}
+	awsCfg, err := awsConfig.Load(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("unable to configure AWS session: %w", err)
+	}
+	timeoutConfig.Apply(&awsCfg)
+
+	c, err := kube.newClient()
+	if err != nil {
+		return nil, err
+	}
+	clusterConfig, err := controllers.LoadClusterConfig(ctx, c)
+	if err != nil {
+		return nil, fmt.Errorf("unable to read the ClusterCloudMapConfig: %w", err)
+	}
+	settings, err := controllers.ResolveSyncSettings(ctx, c, clusterConfig, f.opts.Namespace)
+	if err != nil {
+		return nil, err
+	}
+
+	if !f.keep {
+		defer func() {
+			if err := loadtest.Cleanup(context.Background(), c, f.opts.Namespace); err != nil {
+				ctrl.Log.Error(err, "unable to delete the synthetic services")
+			}
+		}()
+	}
+	created, err := loadtest.Create(ctx, c, f.opts)
+	if err != nil {
+		return nil, err
+	}
+
+	// endpoints are polled more often than the default cache settings allow
+	sdClient := cloudmap.NewServiceDiscoveryClient(&awsCfg, &cloudmap.SdClientConfig{
+		Timeouts: timeoutConfig,
+		Cache:    &cloudmap.SdCacheConfig{NsTTL: time.Minute, SvcTTL: time.Minute, EndptTTL: f.opts.PollInterval / 2},
+	})
+	return loadtest.Await(ctx, sdClient, settings.CloudMapNamespace, created, f.opts)
+}
+
+// runFake creates the synthetic services in an in-memory cluster, and reconciles them with an in-process reconciler
+// exporting to an in-memory Cloud Map.
+func (f *loadTestFlags) runFake(ctx context.Context) (*loadtest.Result, error) {
+	namespace := &v1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: f.opts.Namespace}}
+	c := fake.NewClientBuilder().WithScheme(scheme).WithRuntimeObjects(namespace).Build()
+	server := cloudmaptest.NewServer(cloudmaptest.Options{Latency: f.fakeLatency})
+	server.AddNamespace(f.opts.Namespace, sdtypes.NamespaceTypeHttp)
+	sdClient := server.NewServiceDiscoveryClient(cloudmap.SdClientConfig{
+		Cache: &cloudmap.SdCacheConfig{NsTTL: time.Minute, SvcTTL: time.Minute, EndptTTL: f.opts.PollInterval / 2},
+	})
+	reconciler := &controllers.ServiceExportReconciler{
+		Client:   c,
+		Log:      common.NewLogger("load-test"),
+		Scheme:   scheme,
+		Registry: sdClient,
+		// events are discarded
+		Recorder: &record.FakeRecorder{},
+	}
+
+	created, err := loadtest.Create(ctx, c, f.opts)
+	if err != nil {
+		return nil, err
+	}
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	go loadtest.Reconcile(ctx, created, f.fakeWorkers, time.Second, func(ctx context.Context, name string) error {
+		req := ctrl.Request{NamespacedName: types.NamespacedName{Namespace: f.opts.Namespace, Name: name}}
+		_, err := reconciler.Reconcile(ctx, req)
+		return err
+	})
+	return loadtest.Await(ctx, sdClient, f.opts.Namespace, created, f.opts)
+}
+
+func printLoadTestResult(out io.Writer, result *loadtest.Result) error {
+	w := newTableWriter(out, "SERVICES", "ENDPOINTS", "REGISTERED", "TIMED OUT", "P50", "P90", "P99", "MAX")
+	w.row(fmt.Sprint(result.Services), fmt.Sprint(result.Endpoints), fmt.Sprint(len(result.Latencies)),
+		fmt.Sprint(result.TimedOut), formatLatency(result.Percentile(50)), formatLatency(result.Percentile(90)),
+		formatLatency(result.Percentile(99)), formatLatency(result.Percentile(100)))
+	if err := w.flush(); err != nil {
+		return err
+	}
+
+	if result.TimedOut > 0 {
+		return fmt.Errorf("%d services were not registered in Cloud Map within the timeout", result.TimedOut)
+	}
+	return nil
+}
+
+func formatLatency(latency time.Duration) string {
+	return latency.Round(time.Millisecond).String()
+}
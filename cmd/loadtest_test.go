@@ -0,0 +1,26 @@
+package cmd
+
+import (
+	"bytes"
+	"github.com/aws/aws-cloud-map-mcs-controller-for-k8s/pkg/loadtest"
+	"github.com/stretchr/testify/assert"
+	"testing"
+	"time"
+)
+
+func TestPrintLoadTestResult(t *testing.T) {
+	result := &loadtest.Result{
+		Services:  2,
+		Endpoints: 20,
+		Latencies: []time.Duration{1200 * time.Millisecond, 3 * time.Second},
+	}
+
+	out := &bytes.Buffer{}
+	assert.NoError(t, printLoadTestResult(out, result))
+	assert.Equal(t, "SERVICES   ENDPOINTS   REGISTERED   TIMED OUT   P50    P90   P99   MAX\n"+
+		"2          20          2            0           1.2s   3s    3s    3s\n", out.String())
+
+	result.TimedOut = 1
+	assert.EqualError(t, printLoadTestResult(out, result),
+		"1 services were not registered in Cloud Map within the timeout")
+}
@@ -0,0 +1,1071 @@
+package cmd
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	multiclusterv1alpha1 "github.com/aws/aws-cloud-map-mcs-controller-for-k8s/pkg/api/v1alpha1"
+	"github.com/aws/aws-cloud-map-mcs-controller-for-k8s/pkg/cloudmap"
+	"github.com/aws/aws-cloud-map-mcs-controller-for-k8s/pkg/common"
+	"github.com/aws/aws-cloud-map-mcs-controller-for-k8s/pkg/controllers"
+	"github.com/aws/aws-cloud-map-mcs-controller-for-k8s/pkg/debug"
+	"github.com/aws/aws-cloud-map-mcs-controller-for-k8s/pkg/events"
+	"github.com/aws/aws-cloud-map-mcs-controller-for-k8s/pkg/options"
+	"github.com/aws/aws-cloud-map-mcs-controller-for-k8s/pkg/preflight"
+	"github.com/aws/aws-cloud-map-mcs-controller-for-k8s/pkg/registry"
+	"github.com/aws/aws-cloud-map-mcs-controller-for-k8s/pkg/replication"
+	"github.com/aws/aws-cloud-map-mcs-controller-for-k8s/pkg/route53"
+	"github.com/aws/aws-cloud-map-mcs-controller-for-k8s/pkg/tenancy"
+	"github.com/aws/aws-cloud-map-mcs-controller-for-k8s/pkg/version"
+	"github.com/aws/aws-cloud-map-mcs-controller-for-k8s/pkg/webhooks"
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/spf13/cobra"
+	"io"
+	"k8s.io/client-go/rest"
+	"os"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/cache"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/healthz"
+	"sigs.k8s.io/controller-runtime/pkg/webhook"
+	"time"
+)
+
+var log = ctrl.Log.WithName("main")
+
+// managerFlags are the settings of the controller manager. They bind to a Go flag set, which the configuration file
+// is applied to.
+type managerFlags struct {
+	configFile                string
+	metricsAddr               string
+	enableLeaderElection      bool
+	probeAddr                 string
+	clusterId                 string
+	clusterSetId              string
+	auditLogPath              string
+	slowReconcileThreshold    time.Duration
+	statusUpdateInterval      time.Duration
+	exportResyncPeriod        time.Duration
+	quarantineThreshold       int
+	quarantineRetryInterval   time.Duration
+	enableWebhooks            bool
+	allowMissingServices      bool
+	protectedNamespaces       string
+	webhookCertDir            string
+	watchNamespaces           string
+	tenancyPolicyPath         string
+	cloudMapSyncPeriod        time.Duration
+	startupConcurrency        int
+	warmUpCache               bool
+	skipPreflight             bool
+	changeSource              string
+	changeEventsQueueUrl      string
+	revisionInterval          time.Duration
+	eventsTopicArn            string
+	eventsBusName             string
+	importWebhookUrls         string
+	route53HostedZoneId       string
+	externalDnsMode           string
+	externalDnsDomain         string
+	externalDnsTTL            int64
+	istioServiceEntries       bool
+	istioHostSuffix           string
+	gatewayBackends           bool
+	gatewayRouteNamespaces    string
+	warmUpTimeout             time.Duration
+	deregisterConcurrency     int
+	registerConcurrency       int
+	syncChunkSize             int
+	discoverMaxResults        int
+	instancePaging            string
+	resourceTags              string
+	attributeLimitPolicy      string
+	nodeAttributes            bool
+	multiPortInstances        bool
+	endpointSliceManagers     string
+	replicaRegions            string
+	importRegions             string
+	heartbeatNamespace        string
+	heartbeatInterval         time.Duration
+	heartbeatExpiry           time.Duration
+	orphanGCInterval          time.Duration
+	deleteEmptyNamespaces     bool
+	emptyNamespaceGracePeriod time.Duration
+	enableDebugState          bool
+
+	eventConfig       *common.EventConfig
+	logConfig         *common.LogConfig
+	exportQuota       webhooks.ExportQuota
+	certRotatorConfig *webhooks.CertRotatorConfig
+	cacheConfig       *cloudmap.SdCacheConfig
+	awsConfig         *cloudmap.AwsConfig
+	timeoutConfig     *cloudmap.SdTimeoutConfig
+	faultConfig       *cloudmap.FaultConfig
+}
+
+func newManagerFlags() *managerFlags {
+	return &managerFlags{
+		eventConfig:       common.NewDefaultEventConfig(),
+		logConfig:         common.NewDefaultLogConfig(),
+		certRotatorConfig: webhooks.NewDefaultCertRotatorConfig(),
+		cacheConfig:       cloudmap.NewDefaultSdCacheConfig(),
+		awsConfig:         cloudmap.NewDefaultAwsConfig(),
+		timeoutConfig:     cloudmap.NewDefaultSdTimeoutConfig(),
+		faultConfig:       &cloudmap.FaultConfig{},
+	}
+}
+
+func (f *managerFlags) bindFlags(fs *flag.FlagSet) {
+	fs.StringVar(&f.configFile, "config", "",
+		"The controller configuration file. Flags set on the command line take precedence over the file. Changes "+
+			"of the log levels, the Cloud Map sync period and the protected namespaces are applied at runtime, "+
+			"other settings require a restart. The Cloud Map rate limit is adjusted at runtime with the "+
+			"ClusterCloudMapConfig.")
+	fs.StringVar(&f.metricsAddr, "metrics-bind-address", ":8080", "The address the metric endpoint binds to.")
+	fs.StringVar(&f.probeAddr, "health-probe-bind-address", ":8081", "The address the probe endpoint binds to.")
+	fs.BoolVar(&f.enableLeaderElection, "leader-elect", false,
+		"Enable leader election for controller manager. "+
+			"Enabling this will ensure there is only one active controller manager.")
+	fs.StringVar(&f.clusterId, "cluster-id", "",
+		"The identifier of this cluster, recorded as actor in the audit log and as owner of created Cloud Map resources.")
+	fs.StringVar(&f.clusterSetId, "clusterset-id", "",
+		"The identifier of the clusterset this cluster belongs to, recorded as owner of created Cloud Map resources.")
+	fs.StringVar(&f.resourceTags, "aws-resource-tags", "",
+		"Comma separated key=value tags added to the Cloud Map namespaces and services created by the controller, "+
+			"e.g. \"cost-center=1234,env=prod\".")
+	fs.StringVar(&f.auditLogPath, "audit-log", "",
+		"The file to append the audit log of Cloud Map mutations to, or '-' for standard output. "+
+			"Auditing is disabled if empty. Not supported with --route53-hosted-zone-id.")
+	fs.DurationVar(&f.slowReconcileThreshold, "slow-reconcile-threshold", 10*time.Second,
+		"The reconcile time of a ServiceExport or import time of a Cloud Map service above which per-phase "+
+			"timings are logged, 0 disables logging.")
+	fs.DurationVar(&f.statusUpdateInterval, "status-update-interval", controllers.DefaultStatusUpdateInterval,
+		"The minimum interval between the writes of minor ServiceExport status changes, e.g. endpoint counts, which "+
+			"are batched in between. Condition transitions are written right away. 0 writes every change.")
+	fs.DurationVar(&f.exportResyncPeriod, "export-resync-period", controllers.DefaultExportResyncPeriod,
+		"The interval ServiceExports are periodically re-exported to Cloud Map at, overridden per ServiceExport by the "+
+			controllers.SyncIntervalAnnotation+" annotation. 0 disables the periodic re-export.")
+	fs.IntVar(&f.quarantineThreshold, "quarantine-threshold", controllers.DefaultQuarantineThreshold,
+		"The number of consecutive failures after which a ServiceExport is quarantined and only retried at the "+
+			"--quarantine-retry-interval, until it succeeds or changes. 0 disables the quarantine.")
+	fs.DurationVar(&f.quarantineRetryInterval, "quarantine-retry-interval", controllers.DefaultQuarantineRetryInterval,
+		"The interval quarantined ServiceExports are retried at.")
+	fs.BoolVar(&f.enableWebhooks, "enable-webhooks", false,
+		"Enable the admission webhooks, which requires a serving certificate for the webhook server.")
+	fs.BoolVar(&f.allowMissingServices, "webhook-allow-missing-services", false,
+		"Admit ServiceExports whose Service doesn't exist yet with a warning instead of denying them, e.g. for GitOps "+
+			"tools applying both at once. The export is pending until the Service is created.")
+	fs.StringVar(&f.protectedNamespaces, "protected-namespaces", webhooks.DefaultProtectedNamespaces,
+		"Comma separated list of namespaces whose Services can never be exported. "+
+			"The namespace the controller runs in, read from POD_NAMESPACE, is always protected.")
+	fs.StringVar(&f.watchNamespaces, "watch-namespaces", "",
+		"Comma separated list of namespaces to restrict watches and writes to, all namespaces are watched if empty. "+
+			"Restricting namespaces allows running with namespaced Roles instead of a ClusterRole.")
+	fs.StringVar(&f.tenancyPolicyPath, "tenancy-policy", "",
+		"The file mapping Kubernetes namespaces and teams to the Cloud Map namespaces they may publish into and "+
+			"delete from. All namespaces are permitted if empty.")
+	fs.DurationVar(&f.cloudMapSyncPeriod, "cloudmap-sync-period", controllers.DefaultSyncPeriod,
+		"The interval Cloud Map services are imported into the cluster.")
+	fs.IntVar(&f.startupConcurrency, "startup-concurrency", controllers.DefaultStartupConcurrency,
+		"The number of Cloud Map namespaces whose services are listed concurrently on startup.")
+	fs.StringVar(&f.changeSource, "change-source", "",
+		"How changed Cloud Map services are detected: 'poll' imports changes on the next sync, 'revision' lists the "+
+			"Cloud Map operations which succeeded since the previous listing, 'events' receives change events from "+
+			"--change-events-queue-url. Changed services are imported right away instead of on the next sync, which "+
+			"allows a longer sync period. Defaults to 'events' if a queue URL is set, 'poll' otherwise.")
+	fs.StringVar(&f.changeEventsQueueUrl, "change-events-queue-url", "",
+		"The URL of the SQS queue an EventBridge rule delivers the CloudTrail records of Cloud Map API calls to.")
+	fs.DurationVar(&f.revisionInterval, "revision-interval", cloudmap.DefaultRevisionInterval,
+		"The interval the 'revision' change source lists Cloud Map operations.")
+	fs.StringVar(&f.eventsTopicArn, "clusterset-events-topic-arn", "",
+		"The ARN of an SNS topic clusterset events are published to, e.g. service exported, endpoints added or "+
+			"removed, cluster joined or left. Mutually exclusive with --clusterset-events-bus-name.")
+	fs.StringVar(&f.eventsBusName, "clusterset-events-bus-name", "",
+		"The name or ARN of an EventBridge bus clusterset events are published to.")
+	fs.StringVar(&f.importWebhookUrls, "import-webhook-urls", "",
+		"A comma separated list of HTTP webhook URLs which receive a JSON notification when a ServiceImport or its "+
+			"endpoints change in the cluster.")
+	fs.StringVar(&f.route53HostedZoneId, "route53-hosted-zone-id", "",
+		"The ID of a private Route53 hosted zone the controller writes the A and SRV records of exported services to "+
+			"instead of Cloud Map, for clustersets which only need DNS. Requires --cluster-id.")
+	fs.StringVar(&f.externalDnsMode, "external-dns-mode", "",
+		"Publish imported services through external-dns: 'dnsendpoint' creates a DNSEndpoint per ServiceImport with "+
+			"the endpoint IPs of all clusters, 'annotation' annotates the derived Service with its hostname. "+
+			"Disabled if empty.")
+	fs.StringVar(&f.externalDnsDomain, "external-dns-domain", "",
+		"The domain imported services are published under by external-dns, as <service>.<namespace>.<domain>.")
+	fs.Int64Var(&f.externalDnsTTL, "external-dns-ttl", 0,
+		"The TTL in seconds of the records external-dns publishes for imported services, 0 uses the external-dns default.")
+	fs.BoolVar(&f.istioServiceEntries, "istio-service-entries", false,
+		"Create an Istio ServiceEntry per ServiceImport with the endpoints of all clusters, so the mesh discovers "+
+			"imported services without Istio multicluster.")
+	fs.StringVar(&f.istioHostSuffix, "istio-host-suffix", controllers.DefaultIstioHostSuffix,
+		"The suffix of the ServiceEntry hosts, as <service>.<namespace>.<suffix>.")
+	fs.BoolVar(&f.gatewayBackends, "gateway-api-backends", false,
+		"Prepare the derived Services of imported services as Gateway API backends: annotate them with their "+
+			"ServiceImport and the zones of their endpoints, and set the application protocol of their ports.")
+	fs.StringVar(&f.gatewayRouteNamespaces, "gateway-route-namespaces", "",
+		"Comma separated list of namespaces whose Gateway API routes may reference the derived Services, through a "+
+			"ReferenceGrant per ServiceImport. Requires --gateway-api-backends.")
+	fs.StringVar(&f.replicaRegions, "replica-regions", "",
+		"Comma separated list of secondary regions the endpoints of the cluster are replicated to, with the "+
+			replication.SourceRegionAttr+" attribute holding the region of the controller, so clients in those "+
+			"regions discover the services locally. Requires --cluster-id.")
+	fs.StringVar(&f.importRegions, "import-regions", "",
+		"Comma separated list of the regions services are imported from, by priority: imports fall back to the "+
+			"next region if Cloud Map is unavailable in a region. Each region is the region of the controller or "+
+			"one of --replica-regions, the region of the controller then the replica regions if unset.")
+	fs.StringVar(&f.heartbeatNamespace, "heartbeat-namespace", "",
+		"Dedicated Cloud Map HTTP namespace the heartbeat of the cluster is refreshed in. The instances of clusters "+
+			"whose heartbeat expired are de-registered from the Cloud Map namespaces of the namespaces of the "+
+			"cluster. Requires --cluster-id.")
+	fs.DurationVar(&f.heartbeatInterval, "heartbeat-interval", controllers.DefaultHeartbeatInterval,
+		"The interval of the heartbeat refreshes. Requires --heartbeat-namespace.")
+	fs.DurationVar(&f.heartbeatExpiry, "heartbeat-expiry", controllers.DefaultHeartbeatExpiry,
+		"The time after its last refresh the heartbeat of a cluster expires, which must exceed the heartbeat "+
+			"interval. Requires --heartbeat-namespace.")
+	fs.DurationVar(&f.orphanGCInterval, "orphan-gc-interval", 0,
+		"The interval of the collection of the instances registered by the cluster for ServiceExports which don't "+
+			"exist anymore, according to the cleanup policy of their namespace. Requires --cluster-id, 0 disables "+
+			"the collection.")
+	fs.BoolVar(&f.deleteEmptyNamespaces, "delete-empty-namespaces", false,
+		"Delete the Cloud Map namespaces created by the cluster once the last service in them is removed and the "+
+			"grace period elapsed, e.g. in ephemeral environments. Requires --cluster-id and Cloud Map.")
+	fs.DurationVar(&f.emptyNamespaceGracePeriod, "empty-namespace-grace-period",
+		controllers.DefaultEmptyNamespaceGracePeriod,
+		"The time a Cloud Map namespace stays empty before it is deleted. Requires --delete-empty-namespaces.")
+	fs.BoolVar(&f.warmUpCache, "warm-up-cache", false,
+		"Populate the Cloud Map cache with all namespaces, services and endpoints before starting the controllers, "+
+			"so the first reconciles after a restart don't each call Cloud Map.")
+	fs.DurationVar(&f.warmUpTimeout, "warm-up-timeout", 30*time.Second,
+		"The time the cache warm-up may take, the controllers start with the cache populated so far once exceeded.")
+	fs.BoolVar(&f.skipPreflight, "skip-preflight", false,
+		"Start without checking the CRDs, the Cloud Map region, the namespace mapping and the cluster IDs first. "+
+			"Only for environments the checks don't apply to, the controller fails at runtime otherwise.")
+	fs.IntVar(&f.deregisterConcurrency, "deregister-concurrency", cloudmap.DefaultDeregisterConcurrency,
+		"The number of Cloud Map instances of a service de-registered concurrently.")
+	fs.IntVar(&f.registerConcurrency, "register-concurrency", cloudmap.DefaultRegisterConcurrency,
+		"The number of Cloud Map instances of a service registered concurrently.")
+	fs.IntVar(&f.syncChunkSize, "sync-chunk-size", cloudmap.DefaultSyncChunkSize,
+		"The number of endpoints of a service registered or de-registered before polling their operations. "+
+			"The progress of larger changes is reported in the Syncing condition of the ServiceExport.")
+	fs.IntVar(&f.discoverMaxResults, "discover-max-results", cloudmap.MaxDiscoverMaxResults,
+		"The maximum number of instances returned by a Cloud Map DiscoverInstances request, at most 1000.")
+	fs.StringVar(&f.instancePaging, "instance-paging", string(cloudmap.InstancePagingAuto),
+		"How the instances of Cloud Map services are listed: 'auto' pages through the instances with ListInstances "+
+			"if DiscoverInstances returns the maximum number of instances, 'list' always pages with ListInstances, "+
+			"'none' truncates services to the maximum number of instances of DiscoverInstances.")
+	fs.StringVar(&f.attributeLimitPolicy, "attribute-limit-policy", string(controllers.AttributeLimitFail),
+		"How instance attributes exceeding the Cloud Map limits are handled: 'fail' fails the export with the "+
+			"Synced condition of the ServiceExport, 'drop' drops the exceeding attributes, 'truncate' truncates "+
+			"values exceeding the limits and drops the attributes which can't be truncated.")
+	fs.BoolVar(&f.nodeAttributes, "node-attributes", false,
+		"Add the provider ID and EC2 instance ID of the node of each endpoint to the attributes of its Cloud Map "+
+			"instance, for tooling acting on the nodes, e.g. building target groups. Requires permission to get nodes.")
+	fs.BoolVar(&f.multiPortInstances, "multi-port-instances", false,
+		"Register the ports of an endpoint as a single Cloud Map instance with the ports encoded in its attributes, "+
+			"instead of an instance per port. Only enable once the controllers of all clusters of the clusterset "+
+			"decode multi-port instances. Not supported with --route53-hosted-zone-id.")
+	fs.StringVar(&f.endpointSliceManagers, "export-endpointslice-managers", "",
+		"Comma separated list of the managers of EndpointSlices exported besides the EndpointSlice controller, as "+
+			"in their endpointslice.kubernetes.io/managed-by label, e.g. "+controllers.EndpointSliceMirroringManager+
+			" to export Services without selector. '"+controllers.AllEndpointSliceManagers+"' exports all "+
+			"EndpointSlices of exported Services.")
+	fs.BoolVar(&f.enableDebugState, "enable-debug-state", false,
+		"Serve the internal state of the controller as JSON at "+debug.StatePath+" of the metrics endpoint, to "+
+			"requests whose bearer token is allowed to get the path as non-resource URL.")
+	fs.StringVar(&f.webhookCertDir, "webhook-cert-dir", "/tmp/k8s-webhook-server/serving-certs",
+		"The directory containing the webhook serving certificate tls.crt and key tls.key.")
+	f.eventConfig.BindFlags(fs)
+	f.logConfig.BindFlags(fs)
+	f.exportQuota.BindFlags(fs)
+	f.certRotatorConfig.BindFlags(fs)
+	f.cacheConfig.BindFlags(fs)
+	f.awsConfig.BindFlags(fs)
+	f.timeoutConfig.BindFlags(fs)
+	f.faultConfig.BindFlags(fs)
+}
+
+// requiresClusterId returns whether a feature owning Cloud Map resources per cluster is enabled.
+func (f *managerFlags) requiresClusterId() bool {
+	return f.route53HostedZoneId != "" || f.replicaRegions != "" || f.heartbeatNamespace != "" ||
+		f.orphanGCInterval > 0 || f.deleteEmptyNamespaces
+}
+
+func newManagerCommand(runManager func(args []string) error) *cobra.Command {
+	return &cobra.Command{
+		Use:   "manager",
+		Short: "Runs the controller manager",
+		Long: "Runs the controller manager, as does the controller binary without command. The manager parses its " +
+			"own flags, to apply the configuration file to them, see --help for its flags.",
+		Args:               cobra.ArbitraryArgs,
+		DisableFlagParsing: true,
+		SilenceUsage:       true,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return runManager(args)
+		},
+	}
+}
+
+// runManager runs the controller manager, configured by the command line flags in args.
+func runManager(args []string) error {
+	flags := newManagerFlags()
+	// the command line flag set holds the flags of the libraries too, e.g. --kubeconfig, and exits on parse errors
+	flags.bindFlags(flag.CommandLine)
+	_ = flag.CommandLine.Parse(args)
+
+	s := &managerSetup{flags: flags}
+	defer s.close()
+	if flags.configFile != "" {
+		s.configReloader = options.NewConfigReloader(flags.configFile, flag.CommandLine)
+		if err := s.configReloader.Load(); err != nil {
+			return fmt.Errorf("invalid configuration file: %w", err)
+		}
+	}
+	if err := setupLogger(flags.logConfig); err != nil {
+		return fmt.Errorf("invalid log configuration: %w", err)
+	}
+	log.Info("starting AWS Cloud Map MCS Controller for K8s", "version", version.GetVersion())
+
+	for _, setup := range []func() error{
+		s.setupManager,
+		s.setupAws,
+		s.runPreflight,
+		s.setupClusterConfig,
+		s.setupCloudMap,
+		s.setupRegistries,
+		s.setupExports,
+		s.setupImports,
+		s.setupCollectors,
+		s.setupConfigControllers,
+		s.setupDebugState,
+		s.setupWebhooks,
+		s.setupConfigReloader,
+		s.setupHealthChecks,
+	} {
+		if err := setup(); err != nil {
+			return err
+		}
+	}
+
+	log.Info("starting manager")
+	if err := s.mgr.Start(ctrl.SetupSignalHandler()); err != nil {
+		return fmt.Errorf("problem running manager: %w", err)
+	}
+	return nil
+}
+
+// managerSetup holds what the setup steps of the controller manager share, each step adds one feature to the manager.
+type managerSetup struct {
+	flags          *managerFlags
+	configReloader *options.ConfigReloader
+	closers        []io.Closer
+
+	restConfig    *rest.Config
+	mgr           ctrl.Manager
+	namespaces    []string
+	awsCfg        aws.Config
+	clusterConfig *controllers.ClusterConfig
+	rateLimiter   *cloudmap.RateLimiter
+	tags          map[string]string
+	tenancyPolicy *tenancy.Policy
+
+	sdClientConfig *cloudmap.SdClientConfig
+	sdClient       cloudmap.ServiceDiscoveryClient
+	// serviceRegistry registers the exported services, importRegistry looks up the imported services, with failover
+	// to replica regions if replication is enabled
+	serviceRegistry registry.ServiceRegistry
+	importRegistry  registry.ServiceRegistry
+
+	exportStates           *controllers.ExportStates
+	cloudMapReconciler     *controllers.CloudMapReconciler
+	serviceExportValidator *webhooks.ServiceExportValidator
+}
+
+// close releases the resources held by the setup once the manager stopped, e.g. the audit log.
+func (s *managerSetup) close() {
+	for _, closer := range s.closers {
+		if err := closer.Close(); err != nil {
+			log.Error(err, "unable to close")
+		}
+	}
+}
+
+func (s *managerSetup) setupManager() error {
+	f := s.flags
+	mgrOptions := ctrl.Options{
+		Scheme:                 scheme,
+		MetricsBindAddress:     f.metricsAddr,
+		Port:                   9443,
+		CertDir:                f.webhookCertDir,
+		HealthProbeBindAddress: f.probeAddr,
+		LeaderElection:         f.enableLeaderElection,
+		LeaderElectionID:       "db692913.x-k8s.io",
+		EventBroadcaster:       common.NewEventBroadcaster(f.eventConfig),
+	}
+	s.namespaces = common.SplitNamespaces(f.watchNamespaces)
+	if len(s.namespaces) > 0 {
+		log.Info("restricting controller to namespaces", "namespaces", s.namespaces)
+		mgrOptions.NewCache = cache.MultiNamespacedCacheBuilder(s.namespaces)
+	}
+
+	restConfig, err := ctrl.GetConfig()
+	if err != nil {
+		return fmt.Errorf("unable to get kubeconfig: %w", err)
+	}
+	s.restConfig = restConfig
+	if s.mgr, err = ctrl.NewManager(restConfig, mgrOptions); err != nil {
+		return fmt.Errorf("unable to start manager: %w", err)
+	}
+	return nil
+}
+
+func (s *managerSetup) setupAws() error {
+	f := s.flags
+	log.Info("configuring AWS session", "credentialSource", f.awsConfig.CredentialSource())
+	f.awsConfig.ClusterId = f.clusterId
+	f.awsConfig.ClusterSetId = f.clusterSetId
+	awsCfg, err := f.awsConfig.Load(context.TODO())
+	if err != nil {
+		return fmt.Errorf("unable to configure AWS session: %w", err)
+	}
+	if awsCfg.Region == "" {
+		return fmt.Errorf("unable to configure AWS session: no AWS region set")
+	}
+	log.Info("Running with AWS region", "AWS_REGION", awsCfg.Region)
+
+	if err = f.timeoutConfig.Validate(); err != nil {
+		return fmt.Errorf("invalid Cloud Map timeouts: %w", err)
+	}
+	f.timeoutConfig.Apply(&awsCfg)
+	s.awsCfg = awsCfg
+	return nil
+}
+
+func (s *managerSetup) runPreflight() error {
+	f := s.flags
+	if f.skipPreflight {
+		log.Info("WARNING: skipping the preflight checks")
+		return nil
+	}
+	preflightConfig := preflight.Config{
+		Mapper:           s.mgr.GetRESTMapper(),
+		Reader:           s.mgr.GetAPIReader(),
+		Region:           s.awsCfg.Region,
+		ClusterId:        f.clusterId,
+		ClusterSetId:     f.clusterSetId,
+		RequireClusterId: f.requiresClusterId(),
+	}
+	if f.route53HostedZoneId == "" {
+		preflightConfig.Namespaces = f.awsConfig.NewAwsFacade(&s.awsCfg)
+	}
+	preflightCtx, cancel := context.WithTimeout(context.Background(), preflight.DefaultTimeout)
+	findings := preflight.Run(preflightCtx, preflightConfig)
+	cancel()
+	for _, finding := range findings {
+		log.Info("preflight finding", "check", finding.Check, "severity", finding.Severity,
+			"message", finding.Message)
+	}
+	if preflight.HasErrors(findings) {
+		return fmt.Errorf("preflight checks failed, fix the findings above, or start with --skip-preflight to " +
+			"bypass the checks")
+	}
+	return nil
+}
+
+func (s *managerSetup) setupClusterConfig() error {
+	// the ClusterCloudMapConfig adjusts these at runtime
+	s.clusterConfig = controllers.NewClusterConfig()
+	s.rateLimiter = cloudmap.NewRateLimiter()
+	if len(s.namespaces) == 0 {
+		// the controllers must not start with the default namespace mapping and policies before the
+		// ClusterCloudMapConfig reconciler applied the config, the cache of the manager isn't started yet
+		clusterConfig, err := controllers.LoadClusterConfig(context.TODO(), s.mgr.GetAPIReader())
+		if err != nil {
+			return fmt.Errorf("unable to read the ClusterCloudMapConfig: %w", err)
+		}
+		s.clusterConfig = clusterConfig
+		if rateLimit := clusterConfig.Spec().RateLimit; rateLimit != nil {
+			s.rateLimiter.SetLimit(float32(rateLimit.QPS), int(rateLimit.Burst))
+		}
+	}
+	s.awsCfg.APIOptions = append(s.awsCfg.APIOptions, s.rateLimiter.AddMiddleware)
+	return nil
+}
+
+func (s *managerSetup) setupCloudMap() error {
+	f := s.flags
+	tags, err := cloudmap.ParseTags(f.resourceTags)
+	if err == nil {
+		err = cloudmap.ValidateResourceTags(tags)
+	}
+	if err != nil {
+		return fmt.Errorf("invalid Cloud Map resource tags: %w", err)
+	}
+	s.tags = tags
+
+	sdClientConfig := &cloudmap.SdClientConfig{
+		Cache:        f.cacheConfig,
+		Timeouts:     f.timeoutConfig,
+		ClusterId:    f.clusterId,
+		ClusterSetId: f.clusterSetId,
+		Tags:         tags,
+
+		DeregisterConcurrency: f.deregisterConcurrency,
+		RegisterConcurrency:   f.registerConcurrency,
+		SyncChunkSize:         f.syncChunkSize,
+		DiscoverMaxResults:    f.discoverMaxResults,
+		InstancePaging:        cloudmap.InstancePaging(f.instancePaging),
+	}
+	// the facade assumes the IAM roles of the namespaces, and of the read and write paths if split
+	sdClientConfig.AwsFacade = f.awsConfig.NewAwsFacade(&s.awsCfg)
+	if f.awsConfig.SplitsPaths() {
+		log.Info("calling Cloud Map with separate credentials for reads and writes", "readRoleArn",
+			f.awsConfig.ReadRoleArn, "writeRoleArn", f.awsConfig.WriteRoleArn, "readOnly", f.awsConfig.ReadOnly)
+	}
+	if err = cloudmap.ValidateDiscoverMaxResults(f.discoverMaxResults); err != nil {
+		return fmt.Errorf("invalid DiscoverInstances max results: %w", err)
+	}
+	if err = sdClientConfig.InstancePaging.Validate(); err != nil {
+		return fmt.Errorf("invalid instance paging: %w", err)
+	}
+	if err = controllers.AttributeLimitPolicy(f.attributeLimitPolicy).Validate(); err != nil {
+		return fmt.Errorf("invalid attribute limit policy: %w", err)
+	}
+	if err = f.faultConfig.Validate(); err != nil {
+		return fmt.Errorf("invalid chaos mode settings: %w", err)
+	}
+	if f.faultConfig.Enabled() {
+		sdClientConfig.Faults = f.faultConfig
+		log.Info("WARNING: chaos mode injects faults into Cloud Map API calls, do not use in production",
+			"latency", f.faultConfig.Latency, "latencyJitter", f.faultConfig.LatencyJitter,
+			"throttleRate", f.faultConfig.ThrottleRate, "errorRate", f.faultConfig.ErrorRate)
+	}
+	if f.auditLogPath != "" {
+		auditLogger, closer, err := cloudmap.NewAuditLoggerFromPath(f.auditLogPath, f.clusterId)
+		if err != nil {
+			return fmt.Errorf("unable to open audit log %s: %w", f.auditLogPath, err)
+		}
+		s.closers = append(s.closers, closer)
+		sdClientConfig.AuditLogger = auditLogger
+		log.Info("auditing Cloud Map mutations", "path", f.auditLogPath, "clusterId", f.clusterId)
+	}
+	s.sdClientConfig = sdClientConfig
+
+	if f.tenancyPolicyPath != "" {
+		if s.tenancyPolicy, err = tenancy.LoadPolicy(f.tenancyPolicyPath); err != nil {
+			return fmt.Errorf("unable to load tenancy policy %s: %w", f.tenancyPolicyPath, err)
+		}
+		log.Info("enforcing tenancy policy", "path", f.tenancyPolicyPath)
+	}
+
+	s.sdClient = cloudmap.NewServiceDiscoveryClient(&s.awsCfg, sdClientConfig)
+	if f.warmUpCache {
+		warmUpCtx, cancel := context.WithTimeout(context.Background(), f.warmUpTimeout)
+		start := time.Now()
+		summary, err := s.sdClient.WarmUp(warmUpCtx, f.startupConcurrency)
+		cancel()
+		if err != nil {
+			// the reconciles fetch what couldn't be cached
+			log.Error(err, "unable to warm up the Cloud Map cache completely")
+		}
+		log.Info("warmed up the Cloud Map cache", "namespaces", summary.Namespaces, "services", summary.Services,
+			"endpoints", summary.Endpoints, "duration", time.Since(start).String())
+	}
+	return nil
+}
+
+func (s *managerSetup) setupRegistries() error {
+	f := s.flags
+	s.serviceRegistry = s.sdClient
+	if f.route53HostedZoneId != "" {
+		if f.clusterId == "" {
+			return fmt.Errorf("invalid Route53 settings: --route53-hosted-zone-id requires --cluster-id")
+		}
+		if f.multiPortInstances {
+			return fmt.Errorf("invalid Route53 settings: --multi-port-instances requires Cloud Map")
+		}
+		if f.auditLogPath != "" {
+			// the audit log records the mutations of the Cloud Map client, which Route53 records bypass
+			return fmt.Errorf("invalid Route53 settings: --audit-log requires Cloud Map")
+		}
+		s.serviceRegistry = route53.NewRegistry(route53.NewAwsFacadeFromConfig(&s.awsCfg), f.route53HostedZoneId,
+			f.clusterId)
+		log.Info("managing Route53 records instead of Cloud Map services", "hostedZoneId", f.route53HostedZoneId)
+	}
+
+	regions := common.SplitNamespaces(f.replicaRegions)
+	if len(regions) == 0 {
+		if f.importRegions != "" {
+			return fmt.Errorf("invalid import regions: --import-regions requires --replica-regions")
+		}
+		s.importRegistry = s.serviceRegistry
+		return nil
+	}
+	if f.clusterId == "" || f.route53HostedZoneId != "" {
+		return fmt.Errorf("invalid replication settings: --replica-regions requires --cluster-id and Cloud Map")
+	}
+	replicas := make([]replication.Replica, 0, len(regions))
+	for _, region := range regions {
+		if region == s.awsCfg.Region {
+			return fmt.Errorf("invalid replication settings: replica region %s is the region of the controller",
+				region)
+		}
+		regionCfg := s.awsCfg.Copy()
+		regionCfg.Region = region
+		regionClientConfig := *s.sdClientConfig
+		regionClientConfig.AwsFacade = f.awsConfig.NewAwsFacade(&regionCfg)
+		replicas = append(replicas, replication.Replica{
+			Region:   region,
+			Registry: cloudmap.NewServiceDiscoveryClient(&regionCfg, &regionClientConfig),
+		})
+	}
+	replicatingRegistry := replication.NewRegistry(s.serviceRegistry, s.awsCfg.Region, replicas, f.clusterId)
+	if err := s.mgr.Add(replicatingRegistry); err != nil {
+		return fmt.Errorf("unable to create the replication of registrations: %w", err)
+	}
+	log.Info("replicating registrations", "primaryRegion", s.awsCfg.Region, "replicaRegions", regions)
+
+	lookupRegions, err := importLookupRegions(common.SplitNamespaces(f.importRegions),
+		append([]replication.Replica{{Region: s.awsCfg.Region, Registry: s.serviceRegistry}}, replicas...))
+	if err != nil {
+		return fmt.Errorf("invalid import regions: %w", err)
+	}
+	s.importRegistry = replication.NewFailover(replicatingRegistry, lookupRegions)
+	s.serviceRegistry = replicatingRegistry
+	return nil
+}
+
+func (s *managerSetup) setupExports() error {
+	f := s.flags
+	var publisher *events.Publisher
+	switch {
+	case f.eventsTopicArn != "" && f.eventsBusName != "":
+		return fmt.Errorf("invalid clusterset events target: both an SNS topic and an EventBridge bus are set")
+	case f.eventsTopicArn != "":
+		publisher = events.NewSnsPublisher(events.NewSnsFacadeFromConfig(&s.awsCfg), f.eventsTopicArn,
+			f.clusterId, f.clusterSetId)
+		log.Info("publishing clusterset events", "topicArn", f.eventsTopicArn)
+	case f.eventsBusName != "":
+		publisher = events.NewEventBridgePublisher(events.NewEventBridgeFacadeFromConfig(&s.awsCfg), f.eventsBusName,
+			f.clusterId, f.clusterSetId)
+		log.Info("publishing clusterset events", "busName", f.eventsBusName)
+	}
+	if f.enableDebugState {
+		s.exportStates = controllers.NewExportStates()
+	}
+	var statusBatcher *controllers.StatusBatcher
+	if f.statusUpdateInterval > 0 {
+		statusBatcher = controllers.NewStatusBatcher(f.statusUpdateInterval)
+	}
+	var quarantine *controllers.Quarantine
+	if f.quarantineThreshold > 0 {
+		quarantine = controllers.NewQuarantine(f.quarantineThreshold, f.quarantineRetryInterval)
+	}
+	if err := (&controllers.ServiceExportReconciler{
+		Client:                 s.mgr.GetClient(),
+		Log:                    common.NewLogger("controllers", "ServiceExport"),
+		Scheme:                 s.mgr.GetScheme(),
+		Registry:               s.serviceRegistry,
+		Recorder:               s.mgr.GetEventRecorderFor("serviceexport-controller"),
+		TenancyPolicy:          s.tenancyPolicy,
+		NamespaceReader:        s.mgr.GetAPIReader(),
+		ClusterConfig:          s.clusterConfig,
+		ClusterId:              f.clusterId,
+		ClusterSetId:           f.clusterSetId,
+		ResourceTags:           s.tags,
+		SlowReconcileThreshold: f.slowReconcileThreshold,
+		ExportStates:           s.exportStates,
+		Publisher:              publisher,
+		AttributeLimitPolicy:   controllers.AttributeLimitPolicy(f.attributeLimitPolicy),
+		NodeAttributes:         f.nodeAttributes,
+		NodeReader:             s.mgr.GetAPIReader(),
+		MultiPortInstances:     f.multiPortInstances,
+		EndpointSliceManagers:  controllers.ParseEndpointSliceManagers(f.endpointSliceManagers),
+		StatusBatcher:          statusBatcher,
+		ResyncPeriod:           f.exportResyncPeriod,
+		Quarantine:             quarantine,
+	}).SetupWithManager(s.mgr); err != nil {
+		return fmt.Errorf("unable to create controller ServiceExport: %w", err)
+	}
+	return nil
+}
+
+func (s *managerSetup) setupImports() error {
+	f := s.flags
+	changeSource, err := s.newChangeSource()
+	if err != nil {
+		return fmt.Errorf("invalid change source: %w", err)
+	}
+
+	webhookUrls, err := events.ParseWebhookUrls(f.importWebhookUrls)
+	if err != nil {
+		return fmt.Errorf("invalid import webhook URLs: %w", err)
+	}
+	var notifier *events.WebhookNotifier
+	if len(webhookUrls) > 0 {
+		notifier = events.NewWebhookNotifier(webhookUrls, f.clusterId)
+		if err = s.mgr.Add(notifier); err != nil {
+			return fmt.Errorf("unable to create import webhook notifier: %w", err)
+		}
+	}
+
+	externalDns := &controllers.ExternalDnsConfig{
+		Mode:   controllers.ExternalDnsMode(f.externalDnsMode),
+		Domain: f.externalDnsDomain,
+		TTL:    f.externalDnsTTL,
+	}
+	if err = externalDns.Validate(); err != nil {
+		return fmt.Errorf("invalid external-dns settings: %w", err)
+	}
+	if externalDns.Mode == controllers.ExternalDnsDisabled {
+		externalDns = nil
+	} else {
+		log.Info("publishing imported services through external-dns", "mode", externalDns.Mode,
+			"domain", externalDns.Domain)
+	}
+
+	var istio *controllers.IstioConfig
+	if f.istioServiceEntries {
+		istio = &controllers.IstioConfig{HostSuffix: f.istioHostSuffix}
+		log.Info("creating Istio ServiceEntries for imported services", "hostSuffix", f.istioHostSuffix)
+	}
+
+	var gatewayBackends *controllers.GatewayBackendsConfig
+	if f.gatewayBackends {
+		gatewayBackends = &controllers.GatewayBackendsConfig{
+			RouteNamespaces: common.SplitNamespaces(f.gatewayRouteNamespaces),
+		}
+		log.Info("preparing imported services as Gateway API backends",
+			"routeNamespaces", gatewayBackends.RouteNamespaces)
+	} else if f.gatewayRouteNamespaces != "" {
+		return fmt.Errorf("invalid Gateway API settings: --gateway-route-namespaces requires --gateway-api-backends")
+	}
+
+	s.cloudMapReconciler = &controllers.CloudMapReconciler{
+		Client:                 s.mgr.GetClient(),
+		Registry:               s.importRegistry,
+		Log:                    common.NewLogger("controllers", "Cloudmap"),
+		Namespaces:             s.namespaces,
+		SyncPeriod:             f.cloudMapSyncPeriod,
+		ClusterConfig:          s.clusterConfig,
+		StartupConcurrency:     f.startupConcurrency,
+		ChangeSource:           changeSource,
+		Notifier:               notifier,
+		ExternalDns:            externalDns,
+		Istio:                  istio,
+		GatewayBackends:        gatewayBackends,
+		SlowReconcileThreshold: f.slowReconcileThreshold,
+	}
+	if err = s.mgr.Add(s.cloudMapReconciler); err != nil {
+		return fmt.Errorf("unable to create controller CloudMap: %w", err)
+	}
+
+	if err = (&controllers.DerivedResourceReconciler{
+		Log:      common.NewLogger("controllers", "DerivedResources"),
+		Repairer: s.cloudMapReconciler,
+	}).SetupWithManager(s.mgr); err != nil {
+		return fmt.Errorf("unable to create controller DerivedResources: %w", err)
+	}
+	return nil
+}
+
+// newChangeSource returns the source of the changed Cloud Map services, which are imported right away.
+func (s *managerSetup) newChangeSource() (cloudmap.ChangeSource, error) {
+	f := s.flags
+	sourceType := cloudmap.ChangeSourceType(f.changeSource)
+	if sourceType == "" {
+		sourceType = cloudmap.ChangeSourcePoll
+		if f.changeEventsQueueUrl != "" {
+			sourceType = cloudmap.ChangeSourceEvents
+		}
+	}
+	if err := sourceType.Validate(); err != nil {
+		return nil, err
+	}
+	log.Info("detecting Cloud Map changes", "changeSource", sourceType)
+
+	switch sourceType {
+	case cloudmap.ChangeSourceRevision:
+		return cloudmap.NewRevisionChangeSource(
+			cloudmap.NewServiceDiscoveryApiFromAwsFacade(f.awsConfig.NewAwsFacade(&s.awsCfg)),
+			f.revisionInterval), nil
+	case cloudmap.ChangeSourceEvents:
+		if f.changeEventsQueueUrl == "" {
+			return nil, fmt.Errorf("the %s change source requires a queue URL", sourceType)
+		}
+		return &events.Consumer{
+			Log:      common.NewLogger("events"),
+			Sqs:      events.NewSqsFacadeFromConfig(&s.awsCfg),
+			QueueUrl: f.changeEventsQueueUrl,
+		}, nil
+	default:
+		return cloudmap.NewPollChangeSource(), nil
+	}
+}
+
+func (s *managerSetup) setupCollectors() error {
+	f := s.flags
+	if f.heartbeatNamespace != "" {
+		if f.clusterId == "" || f.route53HostedZoneId != "" {
+			return fmt.Errorf("invalid heartbeat settings: --heartbeat-namespace requires --cluster-id and Cloud Map")
+		}
+		if f.heartbeatExpiry <= f.heartbeatInterval {
+			return fmt.Errorf("invalid heartbeat settings: --heartbeat-expiry must exceed --heartbeat-interval")
+		}
+		if err := s.mgr.Add(&controllers.StaleClusterCollector{
+			Client:             s.mgr.GetClient(),
+			Log:                common.NewLogger("controllers", "StaleClusterCollector"),
+			Registry:           s.serviceRegistry,
+			ClusterConfig:      s.clusterConfig,
+			Namespaces:         s.namespaces,
+			ClusterId:          f.clusterId,
+			HeartbeatNamespace: f.heartbeatNamespace,
+			Interval:           f.heartbeatInterval,
+			Expiry:             f.heartbeatExpiry,
+		}); err != nil {
+			return fmt.Errorf("unable to create the stale cluster collector: %w", err)
+		}
+		log.Info("collecting the instances of stale clusters", "heartbeatNamespace", f.heartbeatNamespace,
+			"interval", f.heartbeatInterval.String(), "expiry", f.heartbeatExpiry.String())
+	}
+
+	if f.orphanGCInterval > 0 {
+		if f.clusterId == "" {
+			return fmt.Errorf("invalid orphan collection settings: --orphan-gc-interval requires --cluster-id")
+		}
+		if err := s.mgr.Add(&controllers.OrphanCollector{
+			Client:          s.mgr.GetClient(),
+			Log:             common.NewLogger("controllers", "OrphanCollector"),
+			Registry:        s.serviceRegistry,
+			TenancyPolicy:   s.tenancyPolicy,
+			NamespaceReader: s.mgr.GetAPIReader(),
+			ClusterConfig:   s.clusterConfig,
+			Namespaces:      s.namespaces,
+			ClusterId:       f.clusterId,
+			Interval:        f.orphanGCInterval,
+		}); err != nil {
+			return fmt.Errorf("unable to create the orphan collector: %w", err)
+		}
+		log.Info("collecting orphaned Cloud Map instances", "interval", f.orphanGCInterval.String())
+	}
+
+	if f.deleteEmptyNamespaces {
+		if f.clusterId == "" || f.route53HostedZoneId != "" {
+			return fmt.Errorf("invalid empty namespace settings: --delete-empty-namespaces requires --cluster-id " +
+				"and Cloud Map")
+		}
+		if f.emptyNamespaceGracePeriod < 0 {
+			return fmt.Errorf("invalid empty namespace settings: --empty-namespace-grace-period must not be negative")
+		}
+		if err := s.mgr.Add(&controllers.EmptyNamespaceCollector{
+			Log:         common.NewLogger("controllers", "EmptyNamespaceCollector"),
+			Registry:    s.serviceRegistry,
+			GracePeriod: f.emptyNamespaceGracePeriod,
+		}); err != nil {
+			return fmt.Errorf("unable to create the empty namespace collector: %w", err)
+		}
+		log.Info("deleting empty Cloud Map namespaces", "gracePeriod", f.emptyNamespaceGracePeriod.String())
+	}
+	return nil
+}
+
+func (s *managerSetup) setupConfigControllers() error {
+	f := s.flags
+	if len(s.namespaces) == 0 {
+		if err := (&controllers.ClusterCloudMapConfigReconciler{
+			Client:        s.mgr.GetClient(),
+			Log:           common.NewLogger("controllers", "ClusterCloudMapConfig"),
+			ClusterConfig: s.clusterConfig,
+			RateLimiter:   s.rateLimiter,
+			Region:        s.awsCfg.Region,
+		}).SetupWithManager(s.mgr); err != nil {
+			return fmt.Errorf("unable to create controller ClusterCloudMapConfig: %w", err)
+		}
+	} else {
+		// the namespaced cache cannot watch cluster scoped resources
+		log.Info("ClusterCloudMapConfig is not supported when restricted to namespaces, using the default settings")
+	}
+
+	if err := (&controllers.CloudMapSyncConfigReconciler{
+		Client:        s.mgr.GetClient(),
+		Log:           common.NewLogger("controllers", "CloudMapSyncConfig"),
+		ClusterConfig: s.clusterConfig,
+	}).SetupWithManager(s.mgr); err != nil {
+		return fmt.Errorf("unable to create controller CloudMapSyncConfig: %w", err)
+	}
+
+	if err := (&controllers.CloudMapStaticEndpointReconciler{
+		Client:               s.mgr.GetClient(),
+		Log:                  common.NewLogger("controllers", "CloudMapStaticEndpoint"),
+		Registry:             s.serviceRegistry,
+		Recorder:             s.mgr.GetEventRecorderFor("cloudmapstaticendpoint-controller"),
+		TenancyPolicy:        s.tenancyPolicy,
+		NamespaceReader:      s.mgr.GetAPIReader(),
+		ClusterConfig:        s.clusterConfig,
+		ClusterId:            f.clusterId,
+		ClusterSetId:         f.clusterSetId,
+		AttributeLimitPolicy: controllers.AttributeLimitPolicy(f.attributeLimitPolicy),
+	}).SetupWithManager(s.mgr); err != nil {
+		return fmt.Errorf("unable to create controller CloudMapStaticEndpoint: %w", err)
+	}
+
+	//+kubebuilder:scaffold:builder
+	return nil
+}
+
+func (s *managerSetup) setupDebugState() error {
+	if !s.flags.enableDebugState {
+		return nil
+	}
+	log.Info("serving debug state", "path", debug.StatePath)
+	if err := s.mgr.AddMetricsExtraHandler(debug.StatePath, &debug.StateHandler{
+		Log:          common.NewLogger("debug"),
+		Authorizer:   &debug.KubernetesAuthorizer{Client: s.mgr.GetClient()},
+		ExportStates: s.exportStates,
+		CloudMap:     s.sdClient,
+	}); err != nil {
+		return fmt.Errorf("unable to serve debug state: %w", err)
+	}
+	return nil
+}
+
+func (s *managerSetup) setupWebhooks() error {
+	f := s.flags
+	if !f.enableWebhooks {
+		return nil
+	}
+	if f.certRotatorConfig.Enabled {
+		// the webhook server requires the certificates at start up, provision them before starting the manager
+		directClient, err := client.New(s.restConfig, client.Options{Scheme: scheme})
+		if err != nil {
+			return fmt.Errorf("unable to create client: %w", err)
+		}
+		certRotator := &webhooks.CertRotator{
+			Client:    directClient,
+			Log:       common.NewLogger("webhooks", "CertRotator"),
+			Config:    f.certRotatorConfig,
+			Namespace: os.Getenv("POD_NAMESPACE"),
+			CertDir:   f.webhookCertDir,
+			CRDs:      []string{"serviceexports.multicluster.x-k8s.io", "serviceimports.multicluster.x-k8s.io"},
+		}
+		if err = certRotator.EnsureCerts(context.TODO()); err != nil {
+			return fmt.Errorf("unable to provision webhook certificates: %w", err)
+		}
+		if err = s.mgr.Add(certRotator); err != nil {
+			return fmt.Errorf("unable to add webhook certificate rotator: %w", err)
+		}
+	}
+
+	log.Info("registering admission webhooks")
+	s.serviceExportValidator = &webhooks.ServiceExportValidator{
+		Client:               s.mgr.GetClient(),
+		Log:                  common.NewLogger("webhooks", "ServiceExport"),
+		Quota:                f.exportQuota,
+		ProtectedNamespaces:  webhooks.NewProtectedNamespaces(f.protectedNamespaces, os.Getenv("POD_NAMESPACE")),
+		AllowMissingServices: f.allowMissingServices,
+		TenancyPolicy:        s.tenancyPolicy,
+		NamespaceReader:      s.mgr.GetAPIReader(),
+		ClusterConfig:        s.clusterConfig,
+	}
+	server := s.mgr.GetWebhookServer()
+	server.Register(webhooks.ServiceExportValidatePath, &webhook.Admission{
+		Handler: s.serviceExportValidator,
+	})
+	server.Register(webhooks.ServiceImportDefaultPath, &webhook.Admission{
+		Handler: &webhooks.ServiceImportDefaulter{},
+	})
+	server.Register(webhooks.ServiceImportValidatePath, &webhook.Admission{
+		Handler: &webhooks.ServiceImportValidator{
+			Client: s.mgr.GetClient(),
+			Log:    common.NewLogger("webhooks", "ServiceImport"),
+		},
+	})
+
+	// conversion webhooks between the served API versions and the v1alpha1 storage version
+	if err := ctrl.NewWebhookManagedBy(s.mgr).For(&multiclusterv1alpha1.ServiceExport{}).Complete(); err != nil {
+		return fmt.Errorf("unable to create webhook ServiceExport: %w", err)
+	}
+	if err := ctrl.NewWebhookManagedBy(s.mgr).For(&multiclusterv1alpha1.ServiceImport{}).Complete(); err != nil {
+		return fmt.Errorf("unable to create webhook ServiceImport: %w", err)
+	}
+	return nil
+}
+
+func (s *managerSetup) setupConfigReloader() error {
+	if s.configReloader == nil {
+		return nil
+	}
+	logConfig := s.flags.logConfig
+	// only settings which are safe to change at runtime are reloaded, others require a restart: the watched
+	// namespaces restrict the cache of the manager, and the rate limit is reloaded from the ClusterCloudMapConfig
+	s.configReloader.OnChange("log-level", func(value string) error {
+		reloaded := *logConfig
+		reloaded.Level = value
+		if err := reloaded.Apply(); err != nil {
+			return err
+		}
+		*logConfig = reloaded
+		return nil
+	})
+	s.configReloader.OnChange("log-component-levels", func(value string) error {
+		reloaded := *logConfig
+		reloaded.ComponentLevels = value
+		if err := reloaded.Apply(); err != nil {
+			return err
+		}
+		*logConfig = reloaded
+		return nil
+	})
+	s.configReloader.OnChange("cloudmap-sync-period", func(value string) error {
+		period, err := time.ParseDuration(value)
+		if err != nil {
+			return err
+		}
+		s.cloudMapReconciler.SetSyncPeriod(period)
+		return nil
+	})
+	if s.serviceExportValidator != nil {
+		s.configReloader.OnChange("protected-namespaces", func(value string) error {
+			s.serviceExportValidator.SetProtectedNamespaces(
+				webhooks.NewProtectedNamespaces(value, os.Getenv("POD_NAMESPACE")))
+			return nil
+		})
+	}
+	if err := s.mgr.Add(s.configReloader); err != nil {
+		return fmt.Errorf("unable to add configuration reloader: %w", err)
+	}
+	return nil
+}
+
+func (s *managerSetup) setupHealthChecks() error {
+	if err := s.mgr.AddHealthzCheck("healthz", healthz.Ping); err != nil {
+		return fmt.Errorf("unable to set up health check: %w", err)
+	}
+	if err := s.mgr.AddReadyzCheck("readyz", healthz.Ping); err != nil {
+		return fmt.Errorf("unable to set up ready check: %w", err)
+	}
+	return nil
+}
+
+// importLookupRegions returns the regions services are imported from, in the order of the import regions, or in the
+// given order if none.
+func importLookupRegions(importRegions []string, regions []replication.Replica) ([]replication.Replica, error) {
+	if len(importRegions) == 0 {
+		return regions, nil
+	}
+	byRegion := make(map[string]replication.Replica, len(regions))
+	for _, region := range regions {
+		byRegion[region.Region] = region
+	}
+	lookupRegions := make([]replication.Replica, 0, len(importRegions))
+	for _, importRegion := range importRegions {
+		region, found := byRegion[importRegion]
+		if !found {
+			return nil, fmt.Errorf("import region %s is neither the region of the controller nor a replica region",
+				importRegion)
+		}
+		lookupRegions = append(lookupRegions, region)
+	}
+	return lookupRegions, nil
+}
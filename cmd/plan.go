@@ -0,0 +1,209 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"github.com/aws/aws-cloud-map-mcs-controller-for-k8s/pkg/cloudmap"
+	"github.com/aws/aws-cloud-map-mcs-controller-for-k8s/pkg/common"
+	"github.com/aws/aws-cloud-map-mcs-controller-for-k8s/pkg/controllers"
+	"github.com/aws/aws-cloud-map-mcs-controller-for-k8s/pkg/model"
+	"github.com/aws/aws-cloud-map-mcs-controller-for-k8s/pkg/tenancy"
+	"github.com/spf13/cobra"
+	"io"
+	"k8s.io/client-go/tools/record"
+	"sort"
+)
+
+const (
+	colorReset  = "\x1b[0m"
+	colorRed    = "\x1b[31m"
+	colorGreen  = "\x1b[32m"
+	colorYellow = "\x1b[33m"
+)
+
+type planFlags struct {
+	clusterId         string
+	clusterSetId      string
+	tenancyPolicyPath string
+	noColor           bool
+}
+
+func newPlanCommand(kube *kubeFlags) *cobra.Command {
+	flags := &planFlags{}
+	awsConfig := cloudmap.NewDefaultAwsConfig()
+	timeoutConfig := cloudmap.NewDefaultSdTimeoutConfig()
+	logConfig := common.NewDefaultLogConfig()
+
+	cmd := &cobra.Command{
+		Use:   "plan",
+		Short: "Shows the Cloud Map changes the controller would perform",
+		Long: "Reads the ServiceExports and endpoints of the cluster and the services in Cloud Map, and prints the " +
+			"services and instances the controller would create, update and delete. Nothing is modified.",
+		Args:         cobra.NoArgs,
+		SilenceUsage: true,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if err := setupLogger(logConfig); err != nil {
+				return fmt.Errorf("invalid log configuration: %w", err)
+			}
+			if err := timeoutConfig.Validate(); err != nil {
+				return fmt.Errorf("invalid Cloud Map timeouts: %w", err)
+			}
+
+			awsConfig.ClusterId = flags.clusterId
+			awsConfig.ClusterSetId = flags.clusterSetId
+			awsCfg, err := awsConfig.Load(cmd.Context())
+			if err != nil {
+				return fmt.Errorf("unable to configure AWS session: %w", err)
+			}
+			timeoutConfig.Apply(&awsCfg)
+
+			sdClient := cloudmap.NewServiceDiscoveryClient(&awsCfg, &cloudmap.SdClientConfig{
+				Timeouts:     timeoutConfig,
+				ClusterId:    flags.clusterId,
+				ClusterSetId: flags.clusterSetId,
+			})
+			plans, err := flags.plan(cmd.Context(), kube, sdClient)
+			if err != nil {
+				return err
+			}
+			return printPlans(cmd.OutOrStdout(), plans, !flags.noColor)
+		},
+	}
+
+	fs := cmd.Flags()
+	kube.bindFlags(fs)
+	fs.StringVar(&flags.clusterId, "cluster-id", "",
+		"The identifier of the cluster, as configured for the controller.")
+	fs.StringVar(&flags.clusterSetId, "clusterset-id", "",
+		"The identifier of the clusterset of the cluster, as configured for the controller.")
+	fs.StringVar(&flags.tenancyPolicyPath, "tenancy-policy", "",
+		"The tenancy policy file of the controller, all namespaces are permitted if empty.")
+	fs.BoolVar(&flags.noColor, "no-color", false, "Print the changes without colors.")
+
+	bindGoFlags(cmd, awsConfig.BindFlags)
+	bindGoFlags(cmd, timeoutConfig.BindFlags)
+	bindGoFlags(cmd, logConfig.BindFlags)
+	return cmd
+}
+
+// plan computes the changes of each ServiceExport with the settings the controller would apply.
+func (f *planFlags) plan(ctx context.Context, kube *kubeFlags, sdClient cloudmap.ServiceDiscoveryClient) ([]*controllers.ExportPlan, error) {
+	exports, err := listExports(ctx, kube)
+	if err != nil {
+		return nil, err
+	}
+	c, err := kube.newClient()
+	if err != nil {
+		return nil, err
+	}
+	clusterConfig, err := controllers.LoadClusterConfig(ctx, c)
+	if err != nil {
+		return nil, fmt.Errorf("unable to read the ClusterCloudMapConfig: %w", err)
+	}
+
+	var tenancyPolicy *tenancy.Policy
+	if f.tenancyPolicyPath != "" {
+		if tenancyPolicy, err = tenancy.LoadPolicy(f.tenancyPolicyPath); err != nil {
+			return nil, fmt.Errorf("unable to load tenancy policy: %w", err)
+		}
+	}
+
+	reconciler := &controllers.ServiceExportReconciler{
+		Client:        c,
+		Log:           common.NewLogger("plan"),
+		Scheme:        scheme,
+		Registry:      sdClient,
+		Recorder:      &record.FakeRecorder{},
+		TenancyPolicy: tenancyPolicy,
+		ClusterConfig: clusterConfig,
+		ClusterId:     f.clusterId,
+		ClusterSetId:  f.clusterSetId,
+	}
+
+	plans := make([]*controllers.ExportPlan, 0, len(exports))
+	for i := range exports {
+		plan, err := reconciler.PlanExport(ctx, &exports[i])
+		if err != nil {
+			return nil, fmt.Errorf("unable to plan ServiceExport %s: %w", objectKey(&exports[i]), err)
+		}
+		plans = append(plans, plan)
+	}
+	return plans, nil
+}
+
+// printPlans prints the changes of each ServiceExport as a diff, followed by a summary.
+func printPlans(out io.Writer, plans []*controllers.ExportPlan, color bool) error {
+	p := &planPrinter{out: out, color: color}
+	creates, updates, deletes := 0, 0, 0
+	for _, plan := range plans {
+		p.println("", "ServiceExport %s/%s -> Cloud Map %s/%s", plan.Namespace, plan.Name,
+			plan.CloudMapNamespace, plan.Name)
+		switch {
+		case plan.Skipped != "":
+			p.println("", "  skipped: %s", plan.Skipped)
+			continue
+		case plan.IsNone():
+			p.println("", "  no changes")
+			continue
+		}
+
+		if plan.CreateService {
+			creates++
+			p.println(colorGreen, "  + service %s/%s", plan.CloudMapNamespace, plan.Name)
+		}
+		for _, endpoint := range sortedEndpoints(plan.Changes.Create) {
+			creates++
+			p.println(colorGreen, "  + instance %s", describeEndpoint(endpoint))
+		}
+		for _, endpoint := range sortedEndpoints(plan.Changes.Update) {
+			updates++
+			p.println(colorYellow, "  ~ instance %s", describeEndpoint(endpoint))
+		}
+		for _, endpoint := range sortedEndpoints(plan.Changes.Delete) {
+			deletes++
+			p.println(colorRed, "  - instance %s", describeEndpoint(endpoint))
+		}
+		if plan.Withheld > 0 {
+			p.println("", "  %d changes withheld by the sync policy", plan.Withheld)
+		}
+	}
+
+	p.println("", "")
+	p.println("", "Plan: %d to create, %d to update, %d to delete.", creates, updates, deletes)
+	return p.err
+}
+
+func sortedEndpoints(endpoints []*model.Endpoint) []*model.Endpoint {
+	sorted := append([]*model.Endpoint{}, endpoints...)
+	sort.Slice(sorted, func(i, j int) bool {
+		return sorted[i].Id < sorted[j].Id
+	})
+	return sorted
+}
+
+func describeEndpoint(endpoint *model.Endpoint) string {
+	description := fmt.Sprintf("%s (%s:%d %s)", endpoint.Id, endpoint.IP, endpoint.EndpointPort.Port,
+		endpoint.EndpointPort.Protocol)
+	if clusterId := endpoint.Attributes[controllers.ClusterIdAttr]; clusterId != "" {
+		description += " of cluster " + clusterId
+	}
+	return description
+}
+
+// planPrinter writes colored lines, keeping the first write error.
+type planPrinter struct {
+	out   io.Writer
+	color bool
+	err   error
+}
+
+func (p *planPrinter) println(color string, format string, args ...interface{}) {
+	if p.err != nil {
+		return
+	}
+	line := fmt.Sprintf(format, args...)
+	if p.color && color != "" {
+		line = color + line + colorReset
+	}
+	_, p.err = io.WriteString(p.out, line+"\n")
+}
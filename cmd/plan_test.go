@@ -0,0 +1,72 @@
+package cmd
+
+import (
+	"bytes"
+	"github.com/aws/aws-cloud-map-mcs-controller-for-k8s/pkg/controllers"
+	"github.com/aws/aws-cloud-map-mcs-controller-for-k8s/pkg/model"
+	"github.com/aws/aws-cloud-map-mcs-controller-for-k8s/test"
+	"github.com/stretchr/testify/assert"
+	"strings"
+	"testing"
+)
+
+func TestPrintPlans(t *testing.T) {
+	updated := test.GetTestEndpoint1()
+	updated.Attributes[controllers.ClusterIdAttr] = test.ClusterId
+	plans := []*controllers.ExportPlan{
+		{
+			Namespace: test.NsName, Name: test.SvcName, CloudMapNamespace: test.NsName,
+			Changes: model.Changes{
+				Update: []*model.Endpoint{updated},
+				Delete: []*model.Endpoint{test.GetTestEndpoint2()},
+			},
+		},
+		{
+			Namespace: test.NsName, Name: "new", CloudMapNamespace: test.NsName, CreateService: true,
+			Changes: model.Changes{Create: []*model.Endpoint{test.GetTestEndpoint1()}},
+		},
+		{Namespace: test.NsName, Name: "unchanged", CloudMapNamespace: test.NsName},
+		{Namespace: test.NsName, Name: "denied", CloudMapNamespace: test.NsName, Skipped: "not permitted"},
+	}
+
+	out := &bytes.Buffer{}
+	assert.NoError(t, printPlans(out, plans, false))
+	assert.Equal(t, strings.Join([]string{
+		"ServiceExport ns-name/svc-name -> Cloud Map ns-name/svc-name",
+		"  ~ instance " + test.EndptId1 + " (" + test.EndptIp1 + ":" + test.PortStr1 + " TCP) of cluster " + test.ClusterId,
+		"  - instance " + test.EndptId2 + " (" + test.EndptIp2 + ":" + test.PortStr2 + " TCP)",
+		"ServiceExport ns-name/new -> Cloud Map ns-name/new",
+		"  + service ns-name/new",
+		"  + instance " + test.EndptId1 + " (" + test.EndptIp1 + ":" + test.PortStr1 + " TCP)",
+		"ServiceExport ns-name/unchanged -> Cloud Map ns-name/unchanged",
+		"  no changes",
+		"ServiceExport ns-name/denied -> Cloud Map ns-name/denied",
+		"  skipped: not permitted",
+		"",
+		"Plan: 2 to create, 1 to update, 1 to delete.",
+		"",
+	}, "\n"), out.String())
+}
+
+func TestPrintPlans_Color(t *testing.T) {
+	plans := []*controllers.ExportPlan{{
+		Namespace: test.NsName, Name: test.SvcName, CloudMapNamespace: test.NsName,
+		Changes: model.Changes{Delete: []*model.Endpoint{test.GetTestEndpoint2()}},
+	}}
+
+	out := &bytes.Buffer{}
+	assert.NoError(t, printPlans(out, plans, true))
+	assert.Contains(t, out.String(), colorRed+"  - instance "+test.EndptId2)
+	assert.NotContains(t, out.String(), colorGreen)
+}
+
+func TestNewRootCommand_Plan(t *testing.T) {
+	root := newRootCommand(func(args []string) error { t.Fatal("manager must not run"); return nil })
+	cmd, _, err := root.Find([]string{"plan"})
+	assert.NoError(t, err)
+	assert.Equal(t, "plan", cmd.Name())
+
+	for _, flag := range []string{"kubeconfig", "namespace", "all-namespaces", "cluster-id", "aws-region", "no-color"} {
+		assert.NotNil(t, cmd.Flags().Lookup(flag), "flag %s", flag)
+	}
+}
@@ -0,0 +1,115 @@
+package cmd
+
+import (
+	"fmt"
+	"github.com/aws/aws-cloud-map-mcs-controller-for-k8s/pkg/cloudmap"
+	"github.com/aws/aws-cloud-map-mcs-controller-for-k8s/pkg/common"
+	"github.com/aws/aws-cloud-map-mcs-controller-for-k8s/pkg/snapshot"
+	"github.com/spf13/cobra"
+	"io"
+	"sort"
+)
+
+func newRestoreCommand() *cobra.Command {
+	opts := snapshot.RestoreOptions{}
+	var clusterId string
+	var auditLogPath string
+	awsConfig := cloudmap.NewDefaultAwsConfig()
+	timeoutConfig := cloudmap.NewDefaultSdTimeoutConfig()
+	logConfig := common.NewDefaultLogConfig()
+
+	cmd := &cobra.Command{
+		Use:   "restore SNAPSHOT",
+		Short: "Restores Cloud Map services and instances from a snapshot",
+		Long: "Re-creates the services of a snapshot missing in Cloud Map, along with their HTTP namespace, and " +
+			"re-registers the missing instances, without waiting for every cluster to resync. Existing instances " +
+			"are kept, the controllers de-register stale instances on their next reconcile. " +
+			"The snapshot is read from stdin if SNAPSHOT is -.",
+		Args:         cobra.ExactArgs(1),
+		SilenceUsage: true,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if err := setupLogger(logConfig); err != nil {
+				return fmt.Errorf("invalid log configuration: %w", err)
+			}
+			if err := timeoutConfig.Validate(); err != nil {
+				return fmt.Errorf("invalid Cloud Map timeouts: %w", err)
+			}
+
+			s, err := snapshot.Load(args[0])
+			if err != nil {
+				return err
+			}
+
+			awsConfig.ClusterId = clusterId
+			awsConfig.ClusterSetId = s.ClusterSetId
+			awsCfg, err := awsConfig.Load(cmd.Context())
+			if err != nil {
+				return fmt.Errorf("unable to configure AWS session: %w", err)
+			}
+			timeoutConfig.Apply(&awsCfg)
+
+			sdClientConfig := &cloudmap.SdClientConfig{
+				Timeouts:     timeoutConfig,
+				ClusterId:    clusterId,
+				ClusterSetId: s.ClusterSetId,
+			}
+			if auditLogPath != "" {
+				auditLogger, closer, err := cloudmap.NewAuditLoggerFromPath(auditLogPath, clusterId)
+				if err != nil {
+					return fmt.Errorf("unable to open audit log: %w", err)
+				}
+				defer closer.Close()
+				sdClientConfig.AuditLogger = auditLogger
+			}
+			sdClient := cloudmap.NewServiceDiscoveryClient(&awsCfg, sdClientConfig)
+			sdApi := cloudmap.NewServiceDiscoveryApiFromConfig(&awsCfg)
+			ctx := cloudmap.WithAuditTrigger(cmd.Context(), "restore")
+			result, err := snapshot.Restore(ctx, sdApi, sdClient, s, opts)
+			if printErr := printRestoreResult(cmd.OutOrStdout(), result, opts.DryRun); printErr != nil && err == nil {
+				err = printErr
+			}
+			return err
+		},
+	}
+
+	fs := cmd.Flags()
+	fs.BoolVar(&opts.DryRun, "dry-run", false,
+		"List the services and instances which would be restored without changing Cloud Map.")
+	fs.StringVar(&clusterId, "cluster-id", "",
+		"The identifier of the cluster recorded as owner of re-created namespaces and services.")
+	fs.StringVar(&auditLogPath, "audit-log", "",
+		"Append the re-created namespaces and services and the re-registered instances to this audit log file, "+
+			"- writes to stdout.")
+
+	bindGoFlags(cmd, awsConfig.BindFlags)
+	bindGoFlags(cmd, timeoutConfig.BindFlags)
+	bindGoFlags(cmd, logConfig.BindFlags)
+	return cmd
+}
+
+func printRestoreResult(out io.Writer, result *snapshot.RestoreResult, dryRun bool) error {
+	created, registered := "created", "registered"
+	if dryRun {
+		created, registered = "would create", "would register"
+	}
+
+	for _, svc := range result.CreatedServices {
+		if _, err := fmt.Fprintf(out, "%s service %s\n", created, svc); err != nil {
+			return err
+		}
+	}
+
+	services := make([]string, 0, len(result.RegisteredEndpoints))
+	for svc := range result.RegisteredEndpoints {
+		services = append(services, svc)
+	}
+	sort.Strings(services)
+	for _, svc := range services {
+		for _, endpoint := range sortedEndpoints(result.RegisteredEndpoints[svc]) {
+			if _, err := fmt.Fprintf(out, "%s instance %s of service %s\n", registered, endpoint.Id, svc); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
@@ -0,0 +1,25 @@
+package cmd
+
+import (
+	"bytes"
+	"github.com/aws/aws-cloud-map-mcs-controller-for-k8s/pkg/model"
+	"github.com/aws/aws-cloud-map-mcs-controller-for-k8s/pkg/snapshot"
+	"github.com/aws/aws-cloud-map-mcs-controller-for-k8s/test"
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func TestPrintRestoreResult(t *testing.T) {
+	result := &snapshot.RestoreResult{
+		CreatedServices: []string{"ns-name/svc-name"},
+		RegisteredEndpoints: map[string][]*model.Endpoint{
+			"ns-name/svc-name": {test.GetTestEndpoint2(), test.GetTestEndpoint1()},
+		},
+	}
+
+	out := &bytes.Buffer{}
+	assert.NoError(t, printRestoreResult(out, result, true))
+	assert.Equal(t, "would create service ns-name/svc-name\n"+
+		"would register instance "+test.EndptId1+" of service ns-name/svc-name\n"+
+		"would register instance "+test.EndptId2+" of service ns-name/svc-name\n", out.String())
+}
@@ -0,0 +1,59 @@
+package cmd
+
+import (
+	"flag"
+	"github.com/aws/aws-cloud-map-mcs-controller-for-k8s/pkg/common"
+	"github.com/spf13/cobra"
+	ctrl "sigs.k8s.io/controller-runtime"
+)
+
+// NewRootCommand returns the command of the controller binary. Without subcommand it runs the controller manager,
+// which parses its own command line flags, so existing deployments keep working unchanged.
+func NewRootCommand() *cobra.Command {
+	return newRootCommand(runManager)
+}
+
+func newRootCommand(runManager func(args []string) error) *cobra.Command {
+	root := &cobra.Command{
+		Use:   "cloudmap-mcs",
+		Short: "AWS Cloud Map MCS Controller for K8s",
+		Long: "Runs the AWS Cloud Map MCS Controller for K8s, or one of its operational commands. " +
+			"Without command the controller manager is run, see --help for its flags.",
+		Args:               cobra.ArbitraryArgs,
+		DisableFlagParsing: true,
+		SilenceUsage:       true,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return runManager(args)
+		},
+	}
+
+	root.AddCommand(
+		newManagerCommand(runManager),
+		newJanitorCommand(),
+		newPlanCommand(newKubeFlags()),
+		newSnapshotCommand(),
+		newRestoreCommand(),
+		newImportPreviewCommand(newKubeFlags()),
+		newCheckIamCommand(),
+		newValidateClusterSetCommand(),
+		newLoadTestCommand(newKubeFlags()),
+	)
+	return root
+}
+
+// bindGoFlags adds settings which bind to a Go flag set, such as the shared AWS and log settings, to a command.
+func bindGoFlags(cmd *cobra.Command, bind func(fs *flag.FlagSet)) {
+	fs := flag.NewFlagSet(cmd.Name(), flag.ContinueOnError)
+	bind(fs)
+	cmd.Flags().AddGoFlagSet(fs)
+}
+
+// setupLogger configures the controller-runtime logger, which the shared Cloud Map client logs to.
+func setupLogger(logConfig *common.LogConfig) error {
+	logger, err := logConfig.NewLogr()
+	if err != nil {
+		return err
+	}
+	ctrl.SetLogger(logger)
+	return nil
+}
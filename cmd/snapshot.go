@@ -0,0 +1,77 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"github.com/aws/aws-cloud-map-mcs-controller-for-k8s/pkg/cloudmap"
+	"github.com/aws/aws-cloud-map-mcs-controller-for-k8s/pkg/common"
+	"github.com/aws/aws-cloud-map-mcs-controller-for-k8s/pkg/snapshot"
+	"github.com/spf13/cobra"
+)
+
+type snapshotFlags struct {
+	opts   snapshot.Options
+	output string
+	format string
+}
+
+func newSnapshotCommand() *cobra.Command {
+	flags := &snapshotFlags{}
+	awsConfig := cloudmap.NewDefaultAwsConfig()
+	timeoutConfig := cloudmap.NewDefaultSdTimeoutConfig()
+	logConfig := common.NewDefaultLogConfig()
+
+	cmd := &cobra.Command{
+		Use:   "snapshot [namespace...]",
+		Short: "Records the Cloud Map namespaces, services and instances to a file",
+		Long: "Records the Cloud Map namespaces, services and instances of the clusterset to a JSON or YAML file, " +
+			"for audits, support tickets and disaster recovery. All namespaces are recorded if none is given.",
+		Args:         cobra.ArbitraryArgs,
+		SilenceUsage: true,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if err := flags.validate(); err != nil {
+				return err
+			}
+			if err := setupLogger(logConfig); err != nil {
+				return fmt.Errorf("invalid log configuration: %w", err)
+			}
+			if err := timeoutConfig.Validate(); err != nil {
+				return fmt.Errorf("invalid Cloud Map timeouts: %w", err)
+			}
+
+			awsCfg, err := awsConfig.Load(cmd.Context())
+			if err != nil {
+				return fmt.Errorf("unable to configure AWS session: %w", err)
+			}
+			timeoutConfig.Apply(&awsCfg)
+
+			flags.opts.Namespaces = args
+			s, err := snapshot.Take(cmd.Context(), cloudmap.NewServiceDiscoveryApiFromConfig(&awsCfg), flags.opts)
+			if err != nil {
+				return err
+			}
+			return s.Write(flags.output, flags.format)
+		},
+	}
+
+	fs := cmd.Flags()
+	fs.StringVar(&flags.opts.ClusterSetId, "clusterset-id", "",
+		"Only record the instances registered by clusters of this clusterset, all instances are recorded if empty.")
+	fs.StringVarP(&flags.output, "output", "o", "-", "The file to write the snapshot to, - writes to stdout.")
+	fs.StringVar(&flags.format, "format", snapshot.YamlFormat, "Format of the snapshot, json or yaml.")
+
+	bindGoFlags(cmd, awsConfig.BindFlags)
+	bindGoFlags(cmd, timeoutConfig.BindFlags)
+	bindGoFlags(cmd, logConfig.BindFlags)
+	return cmd
+}
+
+func (f *snapshotFlags) validate() error {
+	if f.format != snapshot.JsonFormat && f.format != snapshot.YamlFormat {
+		return errors.New("expected --format json or yaml")
+	}
+	if f.output == "" {
+		return errors.New("expected --output file")
+	}
+	return nil
+}
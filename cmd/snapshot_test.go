@@ -0,0 +1,20 @@
+package cmd
+
+import (
+	"github.com/aws/aws-cloud-map-mcs-controller-for-k8s/pkg/snapshot"
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func TestSnapshotFlags_Validate(t *testing.T) {
+	valid := &snapshotFlags{output: "-", format: snapshot.JsonFormat}
+	assert.NoError(t, valid.validate())
+
+	badFormat := *valid
+	badFormat.format = "xml"
+	assert.Error(t, badFormat.validate())
+
+	noOutput := *valid
+	noOutput.output = ""
+	assert.Error(t, noOutput.validate())
+}
@@ -0,0 +1,102 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"github.com/aws/aws-cloud-map-mcs-controller-for-k8s/pkg/cloudmap"
+	"github.com/aws/aws-cloud-map-mcs-controller-for-k8s/pkg/clusterset"
+	"github.com/aws/aws-cloud-map-mcs-controller-for-k8s/pkg/common"
+	"github.com/spf13/cobra"
+	"io"
+)
+
+func newValidateClusterSetCommand() *cobra.Command {
+	var kubeconfig string
+	var contexts []string
+	opts := clusterset.Options{}
+	awsConfig := cloudmap.NewDefaultAwsConfig()
+	timeoutConfig := cloudmap.NewDefaultSdTimeoutConfig()
+	logConfig := common.NewDefaultLogConfig()
+
+	cmd := &cobra.Command{
+		Use:   "validate-clusterset",
+		Short: "Checks the member clusters of a clusterset respect namespace sameness",
+		Long: "Reads the exported services of each member cluster, selected by kubeconfig context, and their Cloud Map " +
+			"services, and reports violations of namespace sameness: namespaces which are missing in some clusters or " +
+			"mapped to different Cloud Map namespaces, services which are not exported by every cluster having them, " +
+			"and exported services or Cloud Map instances with different definitions. Fails if any violation is an " +
+			"error. Nothing is modified.",
+		Args:         cobra.NoArgs,
+		SilenceUsage: true,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if len(contexts) < 2 {
+				return errors.New("expected the --contexts of at least two member clusters")
+			}
+			if err := setupLogger(logConfig); err != nil {
+				return fmt.Errorf("invalid log configuration: %w", err)
+			}
+			if err := timeoutConfig.Validate(); err != nil {
+				return fmt.Errorf("invalid Cloud Map timeouts: %w", err)
+			}
+
+			members := make([]*clusterset.Member, 0, len(contexts))
+			for _, context := range contexts {
+				c, err := (&kubeFlags{kubeconfig: kubeconfig, context: context}).defaultClient()
+				if err != nil {
+					return fmt.Errorf("unable to connect to cluster %s: %w", context, err)
+				}
+				members = append(members, &clusterset.Member{Name: context, Client: c})
+			}
+
+			awsCfg, err := awsConfig.Load(cmd.Context())
+			if err != nil {
+				return fmt.Errorf("unable to configure AWS session: %w", err)
+			}
+			timeoutConfig.Apply(&awsCfg)
+
+			sdClient := cloudmap.NewServiceDiscoveryClient(&awsCfg, &cloudmap.SdClientConfig{Timeouts: timeoutConfig})
+			violations, err := clusterset.Validate(cmd.Context(), members, sdClient, opts)
+			if err != nil {
+				return err
+			}
+			return printViolations(cmd.OutOrStdout(), violations)
+		},
+	}
+
+	fs := cmd.Flags()
+	fs.StringVar(&kubeconfig, "kubeconfig", "", "Path to the kubeconfig file, the default loading rules apply if empty.")
+	fs.StringSliceVar(&contexts, "contexts", nil, "Comma separated list of the kubeconfig contexts of the member clusters.")
+	fs.StringVar(&opts.ClusterSetId, "clusterset-id", "",
+		"The identifier of the clusterset, Cloud Map instances of other clustersets are ignored. All instances are "+
+			"checked if empty.")
+
+	bindGoFlags(cmd, awsConfig.BindFlags)
+	bindGoFlags(cmd, timeoutConfig.BindFlags)
+	bindGoFlags(cmd, logConfig.BindFlags)
+	return cmd
+}
+
+// printViolations prints the violations as a table, and returns an error if any violation is an error.
+func printViolations(out io.Writer, violations []clusterset.Violation) error {
+	if len(violations) == 0 {
+		_, err := fmt.Fprintln(out, "No namespace sameness violations found.")
+		return err
+	}
+
+	w := newTableWriter(out, "SEVERITY", "NAMESPACE", "SERVICE", "MESSAGE")
+	errs := 0
+	for _, violation := range violations {
+		if violation.Severity == clusterset.SeverityError {
+			errs++
+		}
+		w.row(violation.Severity, violation.Namespace, orNone(violation.Name), violation.Message)
+	}
+	if err := w.flush(); err != nil {
+		return err
+	}
+
+	if errs > 0 {
+		return fmt.Errorf("%d namespace sameness violations are errors", errs)
+	}
+	return nil
+}
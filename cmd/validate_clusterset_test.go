@@ -0,0 +1,31 @@
+package cmd
+
+import (
+	"bytes"
+	"github.com/aws/aws-cloud-map-mcs-controller-for-k8s/pkg/clusterset"
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func TestPrintViolations(t *testing.T) {
+	violations := []clusterset.Violation{
+		{Severity: clusterset.SeverityWarning, Namespace: "ns", Message: "namespace does not exist in b"},
+		{Severity: clusterset.SeverityError, Namespace: "ns", Name: "svc", Message: "different definitions"},
+	}
+
+	out := &bytes.Buffer{}
+	assert.EqualError(t, printViolations(out, violations), "1 namespace sameness violations are errors")
+	assert.Equal(t, "SEVERITY   NAMESPACE   SERVICE   MESSAGE\n"+
+		"WARNING    ns          <none>    namespace does not exist in b\n"+
+		"ERROR      ns          svc       different definitions\n", out.String())
+
+	out.Reset()
+	assert.NoError(t, printViolations(out, nil))
+	assert.Equal(t, "No namespace sameness violations found.\n", out.String())
+}
+
+func TestNewRootCommand_ValidateClusterSet(t *testing.T) {
+	root := newRootCommand(func(args []string) error { t.Fatal("manager must not run"); return nil })
+	root.SetArgs([]string{"validate-clusterset", "--contexts", "a"})
+	assert.EqualError(t, root.Execute(), "expected the --contexts of at least two member clusters")
+}
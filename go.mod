@@ -3,19 +3,31 @@ module github.com/aws/aws-cloud-map-mcs-controller-for-k8s
 go 1.15
 
 require (
-	github.com/aws/aws-sdk-go-v2 v1.8.1
+	github.com/aws/aws-sdk-go-v2 v1.9.1
 	github.com/aws/aws-sdk-go-v2/config v1.6.1
+	github.com/aws/aws-sdk-go-v2/credentials v1.3.3
+	github.com/aws/aws-sdk-go-v2/service/eventbridge v1.7.1
+	github.com/aws/aws-sdk-go-v2/service/route53 v1.11.0
 	github.com/aws/aws-sdk-go-v2/service/servicediscovery v1.7.3
+	github.com/aws/aws-sdk-go-v2/service/sns v1.7.1
+	github.com/aws/aws-sdk-go-v2/service/sqs v1.9.0
+	github.com/aws/aws-sdk-go-v2/service/sts v1.6.2
+	github.com/aws/smithy-go v1.8.0
 	github.com/go-logr/logr v0.3.0
 	github.com/golang/mock v1.6.0
 	github.com/onsi/ginkgo v1.14.1
 	github.com/onsi/gomega v1.10.2
+	github.com/prometheus/client_golang v1.7.1
+	github.com/spf13/cobra v1.1.1
+	github.com/spf13/pflag v1.0.5
 	github.com/stretchr/testify v1.6.1
+	go.uber.org/zap v1.15.0
 	gotest.tools v2.2.0+incompatible
 	k8s.io/api v0.20.2
 	k8s.io/apimachinery v0.20.2
 	k8s.io/client-go v0.20.2
 	sigs.k8s.io/controller-runtime v0.8.3
+	sigs.k8s.io/yaml v1.2.0
 )
 
 replace github.com/spf13/viper v1.4.0 => github.com/spf13/viper v1.8.0
@@ -30,7 +30,9 @@ type exportServiceScenario struct {
 	expectedSvc model.Service
 }
 
-func NewExportServiceScenario(cfg *aws.Config, nsName string, svcName string, portStr string, servicePortStr string, ips string) (ExportServiceScenario, error) {
+// NewExportServiceScenario creates a scenario expecting the endpoints of the IPs exported by the cluster with the given
+// ID, which is empty if the controller runs without cluster ID.
+func NewExportServiceScenario(cfg *aws.Config, nsName string, svcName string, portStr string, servicePortStr string, ips string, clusterId string) (ExportServiceScenario, error) {
 	endpts := make([]*model.Endpoint, 0)
 
 	port, parseError := strconv.ParseUint(portStr, 10, 16)
@@ -47,8 +49,12 @@ func NewExportServiceScenario(cfg *aws.Config, nsName string, svcName string, po
 			Port:     int32(port),
 			Protocol: model.TCPProtocol,
 		}
+		attributes := make(map[string]string)
+		if clusterId != "" {
+			attributes[controllers.ClusterIdAttr] = clusterId
+		}
 		endpts = append(endpts, &model.Endpoint{
-			Id: model.EndpointIdFromIPAddressAndPort(ip, endpointPort),
+			Id: model.EndpointId(clusterId, ip, endpointPort),
 			IP: ip,
 			ServicePort: model.Port{
 				Port:       int32(servicePort),
@@ -56,7 +62,7 @@ func NewExportServiceScenario(cfg *aws.Config, nsName string, svcName string, po
 				Protocol:   model.TCPProtocol,
 			},
 			EndpointPort: endpointPort,
-			Attributes:   make(map[string]string),
+			Attributes:   attributes,
 		})
 	}
 
@@ -10,8 +10,9 @@ import (
 )
 
 func main() {
-	if len(os.Args) != 6 {
-		fmt.Println("Expected namespace, service, endpoint port, service port and endpoint IP list arguments")
+	if len(os.Args) != 6 && len(os.Args) != 7 {
+		fmt.Println("Expected namespace, service, endpoint port, service port, endpoint IP list and optional " +
+			"cluster ID arguments")
 		os.Exit(1)
 	}
 
@@ -20,14 +21,19 @@ func main() {
 	port := os.Args[3]
 	servicePort := os.Args[4]
 	ips := os.Args[5]
+	clusterId := ""
+	if len(os.Args) == 7 {
+		clusterId = os.Args[6]
+	}
 
-	testServiceExport(nsName, svcName, port, servicePort, ips)
+	testServiceExport(nsName, svcName, port, servicePort, ips, clusterId)
 }
 
-func testServiceExport(nsName string, svcName string, port string, servicePort string, ips string) {
+func testServiceExport(nsName string, svcName string, port string, servicePort string, ips string, clusterId string) {
 	fmt.Printf("Testing service export integration for namespace %s and service %s\n", nsName, svcName)
 
-	export, err := scenarios.NewExportServiceScenario(getAwsConfig(), nsName, svcName, port, servicePort, ips)
+	export, err := scenarios.NewExportServiceScenario(getAwsConfig(), nsName, svcName, port, servicePort, ips,
+		clusterId)
 	if err != nil {
 		fmt.Printf("Failed to setup service export integration test scenario: %s", err.Error())
 		os.Exit(1)
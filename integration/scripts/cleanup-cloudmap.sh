@@ -5,4 +5,4 @@
 set -eo pipefail
 source ./integration/scripts/common.sh
 
-go run ./integration/janitor/runner/main.go "$NAMESPACE"
+go run ./main.go janitor "$NAMESPACE"
@@ -0,0 +1,122 @@
+/*
+
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1alpha1
+
+import (
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// CloudMapStaticEndpointSpec defines endpoints outside the cluster, e.g. VMs or RDS proxies, exported to a Cloud Map
+// service.
+type CloudMapStaticEndpointSpec struct {
+	// ServiceName is the name of the service the endpoints are exported to, in the Cloud Map namespace of the
+	// namespace. The endpoints are exported alongside the endpoints of a ServiceExport of the same name.
+	// +kubebuilder:validation:MinLength=1
+	ServiceName string `json:"serviceName"`
+
+	// Addresses are the IPv4 addresses of the endpoints.
+	// +kubebuilder:validation:MinItems=1
+	Addresses []string `json:"addresses"`
+
+	// Ports are the ports of the service served by each endpoint.
+	// +kubebuilder:validation:MinItems=1
+	Ports []StaticEndpointPort `json:"ports"`
+
+	// Zone is the availability zone of the endpoints.
+	// +optional
+	Zone string `json:"zone,omitempty"`
+
+	// Attributes are custom Cloud Map instance attributes of the endpoints.
+	// +optional
+	Attributes map[string]string `json:"attributes,omitempty"`
+}
+
+// StaticEndpointPort is a service port and the port of the endpoints serving it.
+type StaticEndpointPort struct {
+	// Name is the name of the service port, which should match the port of the ServiceExport of the service if any.
+	// +optional
+	Name string `json:"name,omitempty"`
+
+	// Port is the service port.
+	// +kubebuilder:validation:Minimum=1
+	// +kubebuilder:validation:Maximum=65535
+	Port int32 `json:"port"`
+
+	// TargetPort is the port of the endpoints, the service port is used if unset.
+	// +kubebuilder:validation:Minimum=1
+	// +kubebuilder:validation:Maximum=65535
+	// +optional
+	TargetPort int32 `json:"targetPort,omitempty"`
+
+	// Protocol is the protocol of the port, TCP if unset.
+	// +kubebuilder:validation:Enum=TCP;UDP;SCTP
+	// +optional
+	Protocol string `json:"protocol,omitempty"`
+
+	// AppProtocol is the application protocol of the port.
+	// +optional
+	AppProtocol string `json:"appProtocol,omitempty"`
+}
+
+// CloudMapStaticEndpointStatus defines the observed state of CloudMapStaticEndpoint
+type CloudMapStaticEndpointStatus struct {
+	// ObservedGeneration is the generation of the endpoints last exported by the controller.
+	// +optional
+	ObservedGeneration int64 `json:"observedGeneration,omitempty"`
+
+	// Endpoints is the number of endpoints exported to Cloud Map.
+	// +optional
+	Endpoints int32 `json:"endpoints,omitempty"`
+
+	// Conditions report whether the endpoints are exported to Cloud Map.
+	// +optional
+	// +patchStrategy=merge
+	// +patchMergeKey=type
+	// +listType=map
+	// +listMapKey=type
+	Conditions []metav1.Condition `json:"conditions,omitempty"`
+}
+
+// +kubebuilder:object:root=true
+// +kubebuilder:subresource:status
+// +kubebuilder:printcolumn:name="Service",type=string,JSONPath=`.spec.serviceName`
+// +kubebuilder:printcolumn:name="Endpoints",type=integer,JSONPath=`.status.endpoints`
+// +kubebuilder:printcolumn:name="Synced",type=string,JSONPath=`.status.conditions[?(@.type=="Synced")].status`
+// +kubebuilder:printcolumn:name="Age",type=date,JSONPath=`.metadata.creationTimestamp`
+
+// CloudMapStaticEndpoint exports endpoints outside the cluster to a Cloud Map service, with the attributes and cleanup
+// policy of the endpoints of ServiceExports.
+type CloudMapStaticEndpoint struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	Spec   CloudMapStaticEndpointSpec   `json:"spec,omitempty"`
+	Status CloudMapStaticEndpointStatus `json:"status,omitempty"`
+}
+
+// +kubebuilder:object:root=true
+
+// CloudMapStaticEndpointList contains a list of CloudMapStaticEndpoint
+type CloudMapStaticEndpointList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata,omitempty"`
+	Items           []CloudMapStaticEndpoint `json:"items"`
+}
+
+func init() {
+	SchemeBuilder.Register(&CloudMapStaticEndpoint{}, &CloudMapStaticEndpointList{})
+}
@@ -0,0 +1,99 @@
+/*
+
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1alpha1
+
+import (
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// CloudMapSyncConfigName is the name of the CloudMapSyncConfig applied in each namespace, others are ignored.
+const CloudMapSyncConfigName = "default"
+
+// CloudMapSyncConfigSpec defines the sync settings of a namespace, unset fields use the cluster settings.
+type CloudMapSyncConfigSpec struct {
+	// CloudMapNamespace is the Cloud Map namespace the services of the namespace are exported to and imported from,
+	// if permitted by the cluster config. Changing it does not deregister endpoints exported to the previous one.
+	// +optional
+	CloudMapNamespace string `json:"cloudMapNamespace,omitempty"`
+
+	// DNSTTL is the TTL in seconds of the DNS records of Cloud Map services created in DNS namespaces. It does not
+	// apply to existing services.
+	// +kubebuilder:validation:Minimum=0
+	// +optional
+	DNSTTL *int64 `json:"dnsTTL,omitempty"`
+
+	// AttributeAllowlist lists the optional Cloud Map instance attributes published for the exported endpoints,
+	// all attributes permitted by the cluster config are published if empty. The endpoint address and port
+	// attributes are always published.
+	// +optional
+	AttributeAllowlist []string `json:"attributeAllowlist,omitempty"`
+
+	// CleanupPolicy controls what happens to the Cloud Map endpoints of a deleted ServiceExport.
+	// +optional
+	CleanupPolicy CleanupPolicy `json:"cleanupPolicy,omitempty"`
+
+	// RoleArn is the ARN of an IAM role the controller assumes for the Cloud Map operations of the namespace, if
+	// permitted by the cluster config, so CloudTrail attributes them to the role of the tenant. The Kubernetes namespace
+	// is passed as session tag, which the trust policy of the role may check.
+	// +kubebuilder:validation:Pattern=`^arn:aws[a-z-]*:iam::[0-9]{12}:role/.+$`
+	// +optional
+	RoleArn string `json:"roleArn,omitempty"`
+}
+
+// CloudMapSyncConfigStatus defines the observed state of CloudMapSyncConfig
+type CloudMapSyncConfigStatus struct {
+	// ObservedGeneration is the generation of the configuration last processed by the controller.
+	// +optional
+	ObservedGeneration int64 `json:"observedGeneration,omitempty"`
+
+	// Conditions report whether the configuration is valid and within the limits of the cluster config.
+	// +optional
+	// +patchStrategy=merge
+	// +patchMergeKey=type
+	// +listType=map
+	// +listMapKey=type
+	Conditions []metav1.Condition `json:"conditions,omitempty"`
+}
+
+// +kubebuilder:object:root=true
+// +kubebuilder:subresource:status
+// +kubebuilder:printcolumn:name="Cloud Map Namespace",type=string,JSONPath=`.spec.cloudMapNamespace`
+// +kubebuilder:printcolumn:name="Valid",type=string,JSONPath=`.status.conditions[?(@.type=="Valid")].status`
+// +kubebuilder:printcolumn:name="Age",type=date,JSONPath=`.metadata.creationTimestamp`
+
+// CloudMapSyncConfig overrides the sync settings of its namespace within the limits of the ClusterCloudMapConfig.
+// Only the object named default is applied, an invalid configuration is ignored.
+type CloudMapSyncConfig struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	Spec   CloudMapSyncConfigSpec   `json:"spec,omitempty"`
+	Status CloudMapSyncConfigStatus `json:"status,omitempty"`
+}
+
+// +kubebuilder:object:root=true
+
+// CloudMapSyncConfigList contains a list of CloudMapSyncConfig
+type CloudMapSyncConfigList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata,omitempty"`
+	Items           []CloudMapSyncConfig `json:"items"`
+}
+
+func init() {
+	SchemeBuilder.Register(&CloudMapSyncConfig{}, &CloudMapSyncConfigList{})
+}
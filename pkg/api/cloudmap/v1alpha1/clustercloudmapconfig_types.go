@@ -0,0 +1,182 @@
+/*
+
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1alpha1
+
+import (
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// ClusterCloudMapConfigName is the name of the ClusterCloudMapConfig applied by the controller, others are ignored.
+const ClusterCloudMapConfigName = "default"
+
+// CleanupPolicy controls what happens to the Cloud Map endpoints of a deleted ServiceExport.
+// +kubebuilder:validation:Enum=Delete;Retain
+type CleanupPolicy string
+
+const (
+	// CleanupPolicyDelete deregisters the endpoints from Cloud Map when the ServiceExport is deleted.
+	CleanupPolicyDelete CleanupPolicy = "Delete"
+	// CleanupPolicyRetain keeps the endpoints registered in Cloud Map when the ServiceExport is deleted.
+	CleanupPolicyRetain CleanupPolicy = "Retain"
+)
+
+// EmptyServicePolicy controls what happens to a Cloud Map service once no cluster has instances registered.
+// +kubebuilder:validation:Enum=Keep;Tag;Delete
+type EmptyServicePolicy string
+
+const (
+	// EmptyServicePolicyKeep keeps empty Cloud Map services unchanged.
+	EmptyServicePolicyKeep EmptyServicePolicy = "Keep"
+	// EmptyServicePolicyTag tags empty Cloud Map services with the time they became empty, the tag is removed once
+	// instances are registered again.
+	EmptyServicePolicyTag EmptyServicePolicy = "Tag"
+	// EmptyServicePolicyDelete deletes empty Cloud Map services.
+	EmptyServicePolicyDelete EmptyServicePolicy = "Delete"
+)
+
+const (
+	// ConfigValidCondition reports whether the configuration passed validation.
+	ConfigValidCondition = "Valid"
+	// ConfigAppliedCondition reports whether the configuration is in effect.
+	ConfigAppliedCondition = "Applied"
+)
+
+// NamespaceMapping derives the Cloud Map namespace name from the Kubernetes namespace name.
+type NamespaceMapping struct {
+	// Prefix is prepended to the Kubernetes namespace name.
+	// +optional
+	Prefix string `json:"prefix,omitempty"`
+
+	// Suffix is appended to the Kubernetes namespace name.
+	// +optional
+	Suffix string `json:"suffix,omitempty"`
+}
+
+// RateLimit limits the rate of Cloud Map API requests.
+type RateLimit struct {
+	// QPS is the sustained number of Cloud Map API requests per second.
+	// +kubebuilder:validation:Minimum=1
+	QPS int32 `json:"qps"`
+
+	// Burst is the number of Cloud Map API requests accepted above the sustained rate.
+	// +kubebuilder:validation:Minimum=1
+	Burst int32 `json:"burst"`
+}
+
+// SyncConfigLimits bounds the overrides namespace owners may apply with a CloudMapSyncConfig.
+type SyncConfigLimits struct {
+	// AllowCloudMapNamespaceOverride permits namespaces to export to a Cloud Map namespace other than the mapped one.
+	// +optional
+	AllowCloudMapNamespaceOverride bool `json:"allowCloudMapNamespaceOverride,omitempty"`
+
+	// AllowRoleArnOverride permits namespaces to have their Cloud Map operations authorized by an IAM role of their own.
+	// +optional
+	AllowRoleArnOverride bool `json:"allowRoleArnOverride,omitempty"`
+
+	// MinDNSTTL is the minimum DNS TTL in seconds namespaces may configure.
+	// +kubebuilder:validation:Minimum=0
+	// +optional
+	MinDNSTTL *int64 `json:"minDNSTTL,omitempty"`
+
+	// MaxDNSTTL is the maximum DNS TTL in seconds namespaces may configure.
+	// +kubebuilder:validation:Minimum=0
+	// +optional
+	MaxDNSTTL *int64 `json:"maxDNSTTL,omitempty"`
+
+	// AllowedAttributes are the optional Cloud Map instance attributes namespaces may publish, all if empty.
+	// +optional
+	AllowedAttributes []string `json:"allowedAttributes,omitempty"`
+
+	// AllowedCleanupPolicies are the cleanup policies namespaces may configure, all if empty.
+	// +optional
+	AllowedCleanupPolicies []CleanupPolicy `json:"allowedCleanupPolicies,omitempty"`
+}
+
+// ClusterCloudMapConfigSpec defines the cluster wide settings of the controller
+type ClusterCloudMapConfigSpec struct {
+	// NamespaceMapping maps Kubernetes namespaces to Cloud Map namespaces, which have the same name by default.
+	// +optional
+	NamespaceMapping NamespaceMapping `json:"namespaceMapping,omitempty"`
+
+	// Region is the expected AWS region of Cloud Map. The region is configured at start up, a differing region is
+	// reported in the status and requires restarting the controller.
+	// +optional
+	Region string `json:"region,omitempty"`
+
+	// CleanupPolicy controls what happens to the Cloud Map endpoints of a deleted ServiceExport, defaults to Delete.
+	// +optional
+	CleanupPolicy CleanupPolicy `json:"cleanupPolicy,omitempty"`
+
+	// EmptyServicePolicy controls what happens to a Cloud Map service once the last instance of any cluster is
+	// de-registered by this cluster, defaults to Keep.
+	// +optional
+	EmptyServicePolicy EmptyServicePolicy `json:"emptyServicePolicy,omitempty"`
+
+	// RateLimit limits the rate of Cloud Map API requests of the controller, unlimited if not set.
+	// +optional
+	RateLimit *RateLimit `json:"rateLimit,omitempty"`
+
+	// SyncConfigLimits bounds the overrides of CloudMapSyncConfigs, which may only override the Cloud Map namespace
+	// if explicitly allowed.
+	// +optional
+	SyncConfigLimits SyncConfigLimits `json:"syncConfigLimits,omitempty"`
+}
+
+// ClusterCloudMapConfigStatus defines the observed state of ClusterCloudMapConfig
+type ClusterCloudMapConfigStatus struct {
+	// ObservedGeneration is the generation of the configuration last processed by the controller.
+	// +optional
+	ObservedGeneration int64 `json:"observedGeneration,omitempty"`
+
+	// Conditions report whether the configuration is valid and applied.
+	// +optional
+	// +patchStrategy=merge
+	// +patchMergeKey=type
+	// +listType=map
+	// +listMapKey=type
+	Conditions []metav1.Condition `json:"conditions,omitempty"`
+}
+
+// +kubebuilder:object:root=true
+// +kubebuilder:resource:scope=Cluster
+// +kubebuilder:subresource:status
+// +kubebuilder:printcolumn:name="Valid",type=string,JSONPath=`.status.conditions[?(@.type=="Valid")].status`
+// +kubebuilder:printcolumn:name="Applied",type=string,JSONPath=`.status.conditions[?(@.type=="Applied")].status`
+// +kubebuilder:printcolumn:name="Age",type=date,JSONPath=`.metadata.creationTimestamp`
+
+// ClusterCloudMapConfig holds the cluster wide configuration of the controller. Only the object named default is
+// applied.
+type ClusterCloudMapConfig struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	Spec   ClusterCloudMapConfigSpec   `json:"spec,omitempty"`
+	Status ClusterCloudMapConfigStatus `json:"status,omitempty"`
+}
+
+// +kubebuilder:object:root=true
+
+// ClusterCloudMapConfigList contains a list of ClusterCloudMapConfig
+type ClusterCloudMapConfigList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata,omitempty"`
+	Items           []ClusterCloudMapConfig `json:"items"`
+}
+
+func init() {
+	SchemeBuilder.Register(&ClusterCloudMapConfig{}, &ClusterCloudMapConfigList{})
+}
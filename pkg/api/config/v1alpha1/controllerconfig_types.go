@@ -0,0 +1,180 @@
+/*
+
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1alpha1
+
+import (
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	cfg "sigs.k8s.io/controller-runtime/pkg/config/v1alpha1"
+)
+
+// +kubebuilder:object:root=true
+
+// ControllerConfig is the configuration file format of the controller. Settings which are not specified keep the
+// value of the corresponding command line flag. Changes of the log levels, the Cloud Map sync period and the protected
+// namespaces are applied without restarting the controller, other settings require a restart.
+type ControllerConfig struct {
+	metav1.TypeMeta `json:",inline"`
+
+	// ControllerManagerConfigurationSpec configures the controller manager, supported are the metrics and health
+	// probe addresses, leader election and the webhook certificate directory.
+	cfg.ControllerManagerConfigurationSpec `json:",inline"`
+
+	// ClusterId is the identifier of this cluster.
+	// +optional
+	ClusterId string `json:"clusterId,omitempty"`
+
+	// ClusterSetId is the identifier of the clusterset this cluster belongs to.
+	// +optional
+	ClusterSetId string `json:"clusterSetId,omitempty"`
+
+	// Logging configures the log output.
+	// +optional
+	Logging LoggingConfig `json:"logging,omitempty"`
+
+	// AWS configures the AWS client.
+	// +optional
+	AWS AWSConfig `json:"aws,omitempty"`
+
+	// Cache configures the Cloud Map resource cache.
+	// +optional
+	Cache CacheConfig `json:"cache,omitempty"`
+
+	// Sync configures the synchronization intervals.
+	// +optional
+	Sync SyncConfig `json:"sync,omitempty"`
+
+	// Filters restricts the namespaces the controller operates on.
+	// +optional
+	Filters FilterConfig `json:"filters,omitempty"`
+}
+
+// LoggingConfig configures the log output.
+type LoggingConfig struct {
+	// Level is the default log level: error, info or debug.
+	// +optional
+	Level string `json:"level,omitempty"`
+
+	// Format is the log output format: json or console.
+	// +optional
+	Format string `json:"format,omitempty"`
+
+	// ComponentLevels overrides the log level per component, e.g. cloudmap: debug.
+	// +optional
+	ComponentLevels map[string]string `json:"componentLevels,omitempty"`
+}
+
+// AWSConfig configures the AWS client.
+type AWSConfig struct {
+	// Region is the AWS region of Cloud Map, discovered from the environment if empty.
+	// +optional
+	Region string `json:"region,omitempty"`
+
+	// Profile is the shared config profile to load.
+	// +optional
+	Profile string `json:"profile,omitempty"`
+
+	// SharedCredentialsFile is the path of a shared credentials file with static credentials.
+	// +optional
+	SharedCredentialsFile string `json:"sharedCredentialsFile,omitempty"`
+
+	// WebIdentityTokenFile is the path of a web identity token exchanged for credentials of RoleARN.
+	// +optional
+	WebIdentityTokenFile string `json:"webIdentityTokenFile,omitempty"`
+
+	// RoleARN is the IAM role to assume, with the web identity token if configured.
+	// +optional
+	RoleARN string `json:"roleARN,omitempty"`
+
+	// RoleSessionName is the session name of the assumed role.
+	// +optional
+	RoleSessionName string `json:"roleSessionName,omitempty"`
+
+	// RoleSessionTags are the session tags of the assumed role.
+	// +optional
+	RoleSessionTags map[string]string `json:"roleSessionTags,omitempty"`
+
+	// STSRegionalEndpoints selects the STS endpoint for assuming roles, regional or legacy for the global endpoint.
+	// +optional
+	STSRegionalEndpoints string `json:"stsRegionalEndpoints,omitempty"`
+
+	// ResourceTags are added to the Cloud Map namespaces and services created by the controller.
+	// +optional
+	ResourceTags map[string]string `json:"resourceTags,omitempty"`
+
+	// APICallTimeout bounds a Cloud Map API call including its retries, 0 disables the timeout.
+	// +optional
+	APICallTimeout *metav1.Duration `json:"apiCallTimeout,omitempty"`
+
+	// APIAttemptTimeout bounds a single HTTP attempt of a Cloud Map API call, 0 disables the timeout.
+	// +optional
+	APIAttemptTimeout *metav1.Duration `json:"apiAttemptTimeout,omitempty"`
+
+	// OperationPollInterval is the interval between polls of asynchronous Cloud Map operations.
+	// +optional
+	OperationPollInterval *metav1.Duration `json:"operationPollInterval,omitempty"`
+
+	// OperationPollTimeout is the time until polling asynchronous Cloud Map operations is abandoned.
+	// +optional
+	OperationPollTimeout *metav1.Duration `json:"operationPollTimeout,omitempty"`
+}
+
+// CacheConfig configures the time to live of cached Cloud Map resources.
+type CacheConfig struct {
+	// NamespaceTTL is the time to live of cached Cloud Map namespaces.
+	// +optional
+	NamespaceTTL *metav1.Duration `json:"namespaceTTL,omitempty"`
+
+	// ServiceTTL is the time to live of cached Cloud Map services.
+	// +optional
+	ServiceTTL *metav1.Duration `json:"serviceTTL,omitempty"`
+
+	// EndpointTTL is the time to live of cached Cloud Map endpoints.
+	// +optional
+	EndpointTTL *metav1.Duration `json:"endpointTTL,omitempty"`
+}
+
+// SyncConfig configures the synchronization intervals.
+type SyncConfig struct {
+	// CloudMapSyncPeriod is the interval Cloud Map services are imported into the cluster.
+	// +optional
+	CloudMapSyncPeriod *metav1.Duration `json:"cloudMapSyncPeriod,omitempty"`
+
+	// SlowReconcileThreshold is the reconcile time above which per-phase timings are logged, 0 disables logging.
+	// +optional
+	SlowReconcileThreshold *metav1.Duration `json:"slowReconcileThreshold,omitempty"`
+}
+
+// FilterConfig restricts the namespaces the controller operates on.
+type FilterConfig struct {
+	// WatchNamespaces restricts watches and writes to the listed namespaces, all namespaces are watched if empty.
+	// Changes require a restart.
+	// +optional
+	WatchNamespaces []string `json:"watchNamespaces,omitempty"`
+
+	// ProtectedNamespaces are the namespaces whose Services can never be exported. Changes are applied at runtime.
+	// +optional
+	ProtectedNamespaces []string `json:"protectedNamespaces,omitempty"`
+}
+
+// Complete returns the configuration of the controller manager.
+func (c *ControllerConfig) Complete() (cfg.ControllerManagerConfigurationSpec, error) {
+	return c.ControllerManagerConfigurationSpec, nil
+}
+
+func init() {
+	SchemeBuilder.Register(&ControllerConfig{})
+}
@@ -22,6 +22,12 @@ import (
 
 // +genclient
 // +kubebuilder:object:root=true
+// +kubebuilder:subresource:status
+// +kubebuilder:storageversion
+// +kubebuilder:printcolumn:name="Synced",type=string,JSONPath=`.status.conditions[?(@.type=="Synced")].status`
+// +kubebuilder:printcolumn:name="Endpoints",type=integer,JSONPath=`.status.endpoints`
+// +kubebuilder:printcolumn:name="CloudMap Service ID",type=string,JSONPath=`.status.cloudMapServiceId`
+// +kubebuilder:printcolumn:name="Age",type=date,JSONPath=`.metadata.creationTimestamp`
 
 // ServiceExport declares that the Service with the same name and namespace
 // as this export should be consumable from other clusters.
@@ -29,6 +35,9 @@ type ServiceExport struct {
 	metav1.TypeMeta `json:",inline"`
 	// +optional
 	metav1.ObjectMeta `json:"metadata,omitempty"`
+	// spec defines the metadata to export along with the service.
+	// +optional
+	Spec ServiceExportSpec `json:"spec,omitempty"`
 	// status describes the current state of an exported service.
 	// Service configuration comes from the Service that had the same
 	// name and namespace as this ServiceExport.
@@ -37,6 +46,42 @@ type ServiceExport struct {
 	Status ServiceExportStatus `json:"status,omitempty"`
 }
 
+// ServiceExportSpec describes the metadata exported along with a service.
+type ServiceExportSpec struct {
+	// exportedLabels are the labels to apply to the ServiceImport of this
+	// service in all importing clusters. If exporting clusters specify
+	// conflicting values, the values of the oldest export are used.
+	// +optional
+	ExportedLabels map[string]string `json:"exportedLabels,omitempty"`
+	// exportedAnnotations are the annotations to apply to the ServiceImport
+	// of this service in all importing clusters. If exporting clusters
+	// specify conflicting values, the values of the oldest export are used.
+	// +optional
+	ExportedAnnotations map[string]string `json:"exportedAnnotations,omitempty"`
+	// syncPolicy controls the changes the controller may make to the
+	// Cloud Map instances of this service: FullSync registers, updates and
+	// de-registers them, UpsertOnly never de-registers them, and
+	// CreateOnly only registers new instances. Defaults to FullSync.
+	// +optional
+	SyncPolicy SyncPolicy `json:"syncPolicy,omitempty"`
+}
+
+// SyncPolicy controls the changes the controller may make to the Cloud Map
+// instances of an exported service.
+// +kubebuilder:validation:Enum=FullSync;UpsertOnly;CreateOnly
+type SyncPolicy string
+
+const (
+	// SyncPolicyFullSync registers, updates and de-registers the instances.
+	SyncPolicyFullSync SyncPolicy = "FullSync"
+	// SyncPolicyUpsertOnly registers and updates the instances, but never
+	// de-registers them, even once the ServiceExport is deleted.
+	SyncPolicyUpsertOnly SyncPolicy = "UpsertOnly"
+	// SyncPolicyCreateOnly only registers new instances, existing instances
+	// are neither updated nor de-registered.
+	SyncPolicyCreateOnly SyncPolicy = "CreateOnly"
+)
+
 // ServiceExportStatus contains the current status of an export.
 type ServiceExportStatus struct {
 	// +optional
@@ -45,8 +90,110 @@ type ServiceExportStatus struct {
 	// +listType=map
 	// +listMapKey=type
 	Conditions []metav1.Condition `json:"conditions,omitempty" patchStrategy:"merge" patchMergeKey:"type"`
+	// endpoints is the number of endpoints exported to Cloud Map.
+	// +optional
+	Endpoints int32 `json:"endpoints,omitempty"`
+	// cloudMapServiceId is the ID of the Cloud Map service the endpoints
+	// are exported to.
+	// +optional
+	CloudMapServiceId string `json:"cloudMapServiceId,omitempty"`
+	// additionalNamespaces are the additional Cloud Map namespaces the
+	// endpoints are exported to.
+	// +optional
+	AdditionalNamespaces []string `json:"additionalNamespaces,omitempty"`
+	// canary is the progress of the canary shifting the weight of the
+	// endpoints of this cluster, set while a canary is annotated.
+	// +optional
+	Canary *CanaryStatus `json:"canary,omitempty"`
+	// migration is the progress of the migration of the service from
+	// another cluster to this cluster, set while a migration is annotated.
+	// +optional
+	Migration *MigrationStatus `json:"migration,omitempty"`
+	// failedEndpoints are the endpoints which failed to be exported to
+	// Cloud Map by the last export, at most 10.
+	// +optional
+	FailedEndpoints []FailedEndpoint `json:"failedEndpoints,omitempty"`
+	// failedEndpointCount is the number of endpoints which failed to be
+	// exported by the last export, including those not listed in
+	// failedEndpoints.
+	// +optional
+	FailedEndpointCount int32 `json:"failedEndpointCount,omitempty"`
 }
 
+// FailedEndpoint describes an endpoint which failed to be exported to
+// Cloud Map.
+type FailedEndpoint struct {
+	// instanceId is the ID of the Cloud Map instance of the endpoint.
+	InstanceId string `json:"instanceId"`
+	// ip is the IP address of the endpoint.
+	// +optional
+	IP string `json:"ip,omitempty"`
+	// reason is the error code of the failure, e.g. ThrottlingException.
+	Reason string `json:"reason"`
+	// message describes the failure.
+	// +optional
+	Message string `json:"message,omitempty"`
+}
+
+// CanaryStatus describes the progress of the canary shifting traffic to
+// the endpoints of this cluster.
+type CanaryStatus struct {
+	// weight is the WEIGHT attribute of the Cloud Map instances of the
+	// endpoints of this cluster, from 0 to 100.
+	Weight int32 `json:"weight"`
+	// phase is Progressing while the weight increases, Promoted once the
+	// weight reached 100, and RolledBack once the readiness of the
+	// endpoints regressed.
+	Phase CanaryPhase `json:"phase"`
+	// lastTransitionTime is the last time the weight changed.
+	LastTransitionTime metav1.Time `json:"lastTransitionTime"`
+}
+
+// CanaryPhase is the phase of a canary.
+type CanaryPhase string
+
+const (
+	// CanaryProgressing means the weight increases at each step.
+	CanaryProgressing CanaryPhase = "Progressing"
+	// CanaryPromoted means the weight reached 100.
+	CanaryPromoted CanaryPhase = "Promoted"
+	// CanaryRolledBack means the weight was reset to 0 after the
+	// readiness of the endpoints regressed.
+	CanaryRolledBack CanaryPhase = "RolledBack"
+)
+
+// MigrationStatus describes the progress of the migration of the service
+// from a source cluster to this cluster.
+type MigrationStatus struct {
+	// sourceCluster is the ID of the cluster the service migrates from.
+	SourceCluster string `json:"sourceCluster"`
+	// phase is Standby while the endpoints of this cluster are registered
+	// but not served, Promoted once they are served along with the
+	// endpoints of the source cluster, Draining once the endpoints of the
+	// source cluster aren't served anymore, and Completed once the source
+	// cluster can stop exporting the service.
+	Phase MigrationPhase `json:"phase"`
+	// lastTransitionTime is the last time the phase changed.
+	LastTransitionTime metav1.Time `json:"lastTransitionTime"`
+}
+
+// MigrationPhase is the phase of a migration.
+type MigrationPhase string
+
+const (
+	// MigrationStandby means the endpoints are registered but not served.
+	MigrationStandby MigrationPhase = "Standby"
+	// MigrationPromoted means the endpoints are served along with the
+	// endpoints of the source cluster.
+	MigrationPromoted MigrationPhase = "Promoted"
+	// MigrationDraining means the endpoints of the source cluster aren't
+	// served anymore.
+	MigrationDraining MigrationPhase = "Draining"
+	// MigrationCompleted means the source cluster can stop exporting the
+	// service.
+	MigrationCompleted MigrationPhase = "Completed"
+)
+
 // ServiceExportConditionType identifies a specific condition.
 type ServiceExportConditionType string
 
@@ -0,0 +1,69 @@
+package v1beta1
+
+import (
+	"github.com/aws/aws-cloud-map-mcs-controller-for-k8s/pkg/api/v1alpha1"
+	"github.com/stretchr/testify/assert"
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"testing"
+)
+
+func TestServiceExport_Conversion(t *testing.T) {
+	original := &ServiceExport{
+		ObjectMeta: metav1.ObjectMeta{Namespace: "ns", Name: "svc", Finalizers: []string{"finalizer"}},
+		Spec: ServiceExportSpec{
+			ExportedLabels:      map[string]string{"team": "a"},
+			ExportedAnnotations: map[string]string{"example.com/owner": "a"},
+			SyncPolicy:          SyncPolicyUpsertOnly,
+		},
+		Status: ServiceExportStatus{
+			Conditions:           []metav1.Condition{{Type: "Synced", Status: metav1.ConditionTrue, Reason: "Synced"}},
+			Endpoints:            2,
+			CloudMapServiceId:    "srv-1",
+			AdditionalNamespaces: []string{"consumers"},
+			Canary:               &CanaryStatus{Weight: 20, Phase: CanaryProgressing},
+			Migration:            &MigrationStatus{SourceCluster: "cluster-a", Phase: MigrationStandby},
+			FailedEndpoints:      []FailedEndpoint{{InstanceId: "instance-1", Reason: "ThrottlingException"}},
+			FailedEndpointCount:  1,
+		},
+	}
+
+	hub := &v1alpha1.ServiceExport{}
+	assert.NoError(t, original.ConvertTo(hub))
+	assert.Equal(t, original.ObjectMeta, hub.ObjectMeta)
+	assert.Equal(t, "srv-1", hub.Status.CloudMapServiceId)
+	assert.Equal(t, v1alpha1.SyncPolicyUpsertOnly, hub.Spec.SyncPolicy)
+	assert.Equal(t, v1alpha1.CanaryProgressing, hub.Status.Canary.Phase)
+	assert.Equal(t, "instance-1", hub.Status.FailedEndpoints[0].InstanceId)
+
+	converted := &ServiceExport{}
+	assert.NoError(t, converted.ConvertFrom(hub))
+	assert.Equal(t, original, converted)
+}
+
+func TestServiceImport_Conversion(t *testing.T) {
+	appProtocol := "http"
+	original := &ServiceImport{
+		ObjectMeta: metav1.ObjectMeta{Namespace: "ns", Name: "svc"},
+		Spec: ServiceImportSpec{
+			Ports: []ServicePort{
+				{Name: "http", Protocol: v1.ProtocolTCP, AppProtocol: &appProtocol, Port: 80},
+			},
+			IPs:             []string{"10.0.0.1"},
+			Type:            ClusterSetIP,
+			SessionAffinity: v1.ServiceAffinityNone,
+		},
+		Status: ServiceImportStatus{
+			Clusters: []ClusterStatus{{Cluster: "cluster-1"}},
+		},
+	}
+
+	hub := &v1alpha1.ServiceImport{}
+	assert.NoError(t, original.ConvertTo(hub))
+	assert.Equal(t, v1alpha1.ClusterSetIP, hub.Spec.Type)
+	assert.Equal(t, int32(80), hub.Spec.Ports[0].Port)
+
+	converted := &ServiceImport{}
+	assert.NoError(t, converted.ConvertFrom(hub))
+	assert.Equal(t, original, converted)
+}
@@ -0,0 +1,95 @@
+/*
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package v1beta1
+
+import (
+	"github.com/aws/aws-cloud-map-mcs-controller-for-k8s/pkg/api/v1alpha1"
+	"sigs.k8s.io/controller-runtime/pkg/conversion"
+)
+
+// ConvertTo converts this ServiceExport to the hub version v1alpha1.
+func (src *ServiceExport) ConvertTo(dstRaw conversion.Hub) error {
+	dst := dstRaw.(*v1alpha1.ServiceExport)
+
+	dst.ObjectMeta = src.ObjectMeta
+	dst.Spec = v1alpha1.ServiceExportSpec{
+		ExportedLabels:      src.Spec.ExportedLabels,
+		ExportedAnnotations: src.Spec.ExportedAnnotations,
+		SyncPolicy:          v1alpha1.SyncPolicy(src.Spec.SyncPolicy),
+	}
+	dst.Status = v1alpha1.ServiceExportStatus{
+		Conditions:           src.Status.Conditions,
+		Endpoints:            src.Status.Endpoints,
+		CloudMapServiceId:    src.Status.CloudMapServiceId,
+		AdditionalNamespaces: src.Status.AdditionalNamespaces,
+		FailedEndpointCount:  src.Status.FailedEndpointCount,
+	}
+	for _, failed := range src.Status.FailedEndpoints {
+		dst.Status.FailedEndpoints = append(dst.Status.FailedEndpoints, v1alpha1.FailedEndpoint(failed))
+	}
+	if src.Status.Canary != nil {
+		dst.Status.Canary = &v1alpha1.CanaryStatus{
+			Weight:             src.Status.Canary.Weight,
+			Phase:              v1alpha1.CanaryPhase(src.Status.Canary.Phase),
+			LastTransitionTime: src.Status.Canary.LastTransitionTime,
+		}
+	}
+	if src.Status.Migration != nil {
+		dst.Status.Migration = &v1alpha1.MigrationStatus{
+			SourceCluster:      src.Status.Migration.SourceCluster,
+			Phase:              v1alpha1.MigrationPhase(src.Status.Migration.Phase),
+			LastTransitionTime: src.Status.Migration.LastTransitionTime,
+		}
+	}
+
+	return nil
+}
+
+// ConvertFrom converts from the hub version v1alpha1 to this version.
+func (dst *ServiceExport) ConvertFrom(srcRaw conversion.Hub) error {
+	src := srcRaw.(*v1alpha1.ServiceExport)
+
+	dst.ObjectMeta = src.ObjectMeta
+	dst.Spec = ServiceExportSpec{
+		ExportedLabels:      src.Spec.ExportedLabels,
+		ExportedAnnotations: src.Spec.ExportedAnnotations,
+		SyncPolicy:          SyncPolicy(src.Spec.SyncPolicy),
+	}
+	dst.Status = ServiceExportStatus{
+		Conditions:           src.Status.Conditions,
+		Endpoints:            src.Status.Endpoints,
+		CloudMapServiceId:    src.Status.CloudMapServiceId,
+		AdditionalNamespaces: src.Status.AdditionalNamespaces,
+		FailedEndpointCount:  src.Status.FailedEndpointCount,
+	}
+	for _, failed := range src.Status.FailedEndpoints {
+		dst.Status.FailedEndpoints = append(dst.Status.FailedEndpoints, FailedEndpoint(failed))
+	}
+	if src.Status.Canary != nil {
+		dst.Status.Canary = &CanaryStatus{
+			Weight:             src.Status.Canary.Weight,
+			Phase:              CanaryPhase(src.Status.Canary.Phase),
+			LastTransitionTime: src.Status.Canary.LastTransitionTime,
+		}
+	}
+	if src.Status.Migration != nil {
+		dst.Status.Migration = &MigrationStatus{
+			SourceCluster:      src.Status.Migration.SourceCluster,
+			Phase:              MigrationPhase(src.Status.Migration.Phase),
+			LastTransitionTime: src.Status.Migration.LastTransitionTime,
+		}
+	}
+
+	return nil
+}
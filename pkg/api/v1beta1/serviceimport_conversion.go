@@ -0,0 +1,75 @@
+/*
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package v1beta1
+
+import (
+	"github.com/aws/aws-cloud-map-mcs-controller-for-k8s/pkg/api/v1alpha1"
+	"sigs.k8s.io/controller-runtime/pkg/conversion"
+)
+
+// ConvertTo converts this ServiceImport to the hub version v1alpha1.
+func (src *ServiceImport) ConvertTo(dstRaw conversion.Hub) error {
+	dst := dstRaw.(*v1alpha1.ServiceImport)
+
+	dst.ObjectMeta = src.ObjectMeta
+	dst.Spec = v1alpha1.ServiceImportSpec{
+		IPs:                   src.Spec.IPs,
+		Type:                  v1alpha1.ServiceImportType(src.Spec.Type),
+		SessionAffinity:       src.Spec.SessionAffinity,
+		SessionAffinityConfig: src.Spec.SessionAffinityConfig,
+	}
+	for _, port := range src.Spec.Ports {
+		dst.Spec.Ports = append(dst.Spec.Ports, v1alpha1.ServicePort{
+			Name:        port.Name,
+			Protocol:    port.Protocol,
+			AppProtocol: port.AppProtocol,
+			Port:        port.Port,
+		})
+	}
+
+	dst.Status = v1alpha1.ServiceImportStatus{}
+	for _, cluster := range src.Status.Clusters {
+		dst.Status.Clusters = append(dst.Status.Clusters, v1alpha1.ClusterStatus{Cluster: cluster.Cluster})
+	}
+
+	return nil
+}
+
+// ConvertFrom converts from the hub version v1alpha1 to this version.
+func (dst *ServiceImport) ConvertFrom(srcRaw conversion.Hub) error {
+	src := srcRaw.(*v1alpha1.ServiceImport)
+
+	dst.ObjectMeta = src.ObjectMeta
+	dst.Spec = ServiceImportSpec{
+		IPs:                   src.Spec.IPs,
+		Type:                  ServiceImportType(src.Spec.Type),
+		SessionAffinity:       src.Spec.SessionAffinity,
+		SessionAffinityConfig: src.Spec.SessionAffinityConfig,
+	}
+	for _, port := range src.Spec.Ports {
+		dst.Spec.Ports = append(dst.Spec.Ports, ServicePort{
+			Name:        port.Name,
+			Protocol:    port.Protocol,
+			AppProtocol: port.AppProtocol,
+			Port:        port.Port,
+		})
+	}
+
+	dst.Status = ServiceImportStatus{}
+	for _, cluster := range src.Status.Clusters {
+		dst.Status.Clusters = append(dst.Status.Clusters, ClusterStatus{Cluster: cluster.Cluster})
+	}
+
+	return nil
+}
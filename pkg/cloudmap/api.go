@@ -16,6 +16,22 @@ const (
 	defaultServiceTTLInSeconds int64 = 60
 )
 
+type dnsTTLKey struct{}
+
+// WithDnsTTL returns a context which creates Cloud Map services in DNS namespaces with the given DNS record TTL in
+// seconds instead of the default TTL.
+func WithDnsTTL(ctx context.Context, ttl int64) context.Context {
+	return context.WithValue(ctx, dnsTTLKey{}, ttl)
+}
+
+// DnsTTLFromContext returns the DNS record TTL of the context or the default TTL if not set.
+func DnsTTLFromContext(ctx context.Context) int64 {
+	if ttl, ok := ctx.Value(dnsTTLKey{}).(int64); ok {
+		return ttl
+	}
+	return defaultServiceTTLInSeconds
+}
+
 // ServiceDiscoveryApi handles the AWS Cloud Map API request and response processing logic, and converts results to
 // internal data structures. It manages all interactions with the AWS SDK.
 type ServiceDiscoveryApi interface {
@@ -25,9 +41,17 @@ type ServiceDiscoveryApi interface {
 	// ListServices returns a list of services for a given namespace.
 	ListServices(ctx context.Context, namespaceId string) (services []*model.Resource, err error)
 
-	// DiscoverInstances returns a list of service instances registered to a given service.
+	// DiscoverInstances returns a list of service instances registered to a given service. At most the configured
+	// DiscoverInstances max results are returned, see InstancePaging.
 	DiscoverInstances(ctx context.Context, nsName string, svcName string) (insts []types.HttpInstanceSummary, err error)
 
+	// ListInstances returns all service instances registered to a given service, paging through the instances.
+	ListInstances(ctx context.Context, nsName string, svcName string, serviceId string) (insts []types.HttpInstanceSummary, err error)
+
+	// ListInstancePages pages through the service instances registered to a given service, passing each page to the
+	// callback, so the instances of large services aren't held at once. Paging stops at the first callback error.
+	ListInstancePages(ctx context.Context, nsName string, svcName string, serviceId string, page func(insts []types.HttpInstanceSummary) error) error
+
 	// ListOperations returns a map of operations to their status matching a list of filters.
 	ListOperations(ctx context.Context, opFilters []types.OperationFilter) (operationStatusMap map[string]types.OperationStatus, err error)
 
@@ -37,9 +61,38 @@ type ServiceDiscoveryApi interface {
 	// CreateHttpNamespace creates a HTTP namespace in AWS Cloud Map for a given name.
 	CreateHttpNamespace(ctx context.Context, namespaceName string) (operationId string, err error)
 
+	// GetNamespace returns the Cloud Map namespace with the given ID.
+	GetNamespace(ctx context.Context, namespaceId string) (namespace *types.Namespace, err error)
+
+	// DeleteNamespace deletes a namespace in AWS Cloud Map, which fails if the namespace has services.
+	DeleteNamespace(ctx context.Context, namespaceId string) (operationId string, err error)
+
 	// CreateService creates a named service in AWS Cloud Map under the given namespace.
 	CreateService(ctx context.Context, namespace model.Namespace, serviceName string) (serviceId string, err error)
 
+	// GetService returns the Cloud Map service with the given ID.
+	GetService(ctx context.Context, serviceId string) (service *types.Service, err error)
+
+	// UpdateServiceDescription updates the description of a service in AWS Cloud Map, keeping its DNS records and health
+	// check configuration.
+	UpdateServiceDescription(ctx context.Context, service *types.Service, description string) (operationId string, err error)
+
+	// UpdateServiceDnsRecords updates the DNS records of a service in AWS Cloud Map, keeping its description and health
+	// check configuration.
+	UpdateServiceDnsRecords(ctx context.Context, service *types.Service, dnsRecords []types.DnsRecord) (operationId string, err error)
+
+	// ListTagsForResource returns the tags of an AWS Cloud Map resource.
+	ListTagsForResource(ctx context.Context, resourceArn string) (tags map[string]string, err error)
+
+	// DeleteService deletes a service in AWS Cloud Map, which fails if the service has instances.
+	DeleteService(ctx context.Context, serviceId string) error
+
+	// TagResource adds tags to an AWS Cloud Map resource, overwriting the values of existing tags.
+	TagResource(ctx context.Context, resourceArn string, tags map[string]string) error
+
+	// UntagResource removes tags from an AWS Cloud Map resource.
+	UntagResource(ctx context.Context, resourceArn string, tagKeys []string) error
+
 	// RegisterInstance registers a service instance in AWS Cloud Map.
 	RegisterInstance(ctx context.Context, serviceId string, instanceId string, instanceAttrs map[string]string) (operationId string, err error)
 
@@ -53,13 +106,33 @@ type ServiceDiscoveryApi interface {
 type serviceDiscoveryApi struct {
 	log       common.Logger
 	awsFacade AwsFacade
+	timeouts  *SdTimeoutConfig
+	tags      map[string]string
+	// operations shares operation lookups between callers, operations are fetched by each caller if nil
+	operations *operationCache
+	// discoverMaxResults is the MaxResults of DiscoverInstances requests, MaxDiscoverMaxResults if not positive
+	discoverMaxResults int
 }
 
 // NewServiceDiscoveryApiFromConfig creates a new AWS Cloud Map API connection manager from an AWS client config.
 func NewServiceDiscoveryApiFromConfig(cfg *aws.Config) ServiceDiscoveryApi {
+	return NewServiceDiscoveryApiFromAwsFacade(NewAwsFacadeFromConfig(cfg))
+}
+
+// NewServiceDiscoveryApiFromAwsFacade creates a new Cloud Map API connection manager from an AWS facade.
+func NewServiceDiscoveryApiFromAwsFacade(awsFacade AwsFacade) ServiceDiscoveryApi {
+	return newServiceDiscoveryApi(common.NewLogger("cloudmap"), awsFacade, nil, OwnershipTags("", ""))
+}
+
+// newServiceDiscoveryApi creates a Cloud Map API connection manager which tags created namespaces and services.
+func newServiceDiscoveryApi(log common.Logger, awsFacade AwsFacade, timeouts *SdTimeoutConfig,
+	tags map[string]string) *serviceDiscoveryApi {
 	return &serviceDiscoveryApi{
-		log:       common.NewLogger("cloudmap"),
-		awsFacade: NewAwsFacadeFromConfig(cfg),
+		log:        log,
+		awsFacade:  awsFacade,
+		timeouts:   timeouts,
+		tags:       tags,
+		operations: newOperationCache(),
 	}
 }
 
@@ -117,7 +190,7 @@ func (sdApi *serviceDiscoveryApi) DiscoverInstances(ctx context.Context, nsName
 		NamespaceName: aws.String(nsName),
 		ServiceName:   aws.String(svcName),
 		HealthStatus:  types.HealthStatusFilterAll,
-		MaxResults:    aws.Int32(1000),
+		MaxResults:    aws.Int32(int32(sdApi.getDiscoverMaxResults())),
 	})
 
 	if err != nil {
@@ -127,6 +200,48 @@ func (sdApi *serviceDiscoveryApi) DiscoverInstances(ctx context.Context, nsName
 	return out.Instances, nil
 }
 
+func (sdApi *serviceDiscoveryApi) ListInstances(ctx context.Context, nsName string, svcName string, svcId string) (insts []types.HttpInstanceSummary, err error) {
+	err = sdApi.ListInstancePages(ctx, nsName, svcName, svcId, func(page []types.HttpInstanceSummary) error {
+		insts = append(insts, page...)
+		return nil
+	})
+	return insts, err
+}
+
+func (sdApi *serviceDiscoveryApi) ListInstancePages(ctx context.Context, nsName string, svcName string, svcId string, page func(insts []types.HttpInstanceSummary) error) error {
+	pages := sd.NewListInstancesPaginator(sdApi.awsFacade, &sd.ListInstancesInput{ServiceId: aws.String(svcId)})
+
+	for pages.HasMorePages() {
+		output, err := pages.NextPage(ctx)
+		if err != nil {
+			return err
+		}
+
+		insts := make([]types.HttpInstanceSummary, 0, len(output.Instances))
+		for _, inst := range output.Instances {
+			// the instances are returned in the format of DiscoverInstances
+			insts = append(insts, types.HttpInstanceSummary{
+				InstanceId:    inst.Id,
+				NamespaceName: aws.String(nsName),
+				ServiceName:   aws.String(svcName),
+				Attributes:    inst.Attributes,
+			})
+		}
+		if err = page(insts); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (sdApi *serviceDiscoveryApi) getDiscoverMaxResults() int {
+	if sdApi.discoverMaxResults <= 0 {
+		return MaxDiscoverMaxResults
+	}
+	return sdApi.discoverMaxResults
+}
+
 func (sdApi *serviceDiscoveryApi) ListOperations(ctx context.Context, opFilters []types.OperationFilter) (opStatusMap map[string]types.OperationStatus, err error) {
 	opStatusMap = make(map[string]types.OperationStatus, 0)
 
@@ -150,18 +265,21 @@ func (sdApi *serviceDiscoveryApi) ListOperations(ctx context.Context, opFilters
 }
 
 func (sdApi *serviceDiscoveryApi) GetOperation(ctx context.Context, opId string) (operation *types.Operation, err error) {
-	opResp, err := sdApi.awsFacade.GetOperation(ctx, &sd.GetOperationInput{OperationId: &opId})
+	getOperation := func(ctx context.Context) (*types.Operation, error) {
+		opResp, err := sdApi.awsFacade.GetOperation(ctx, &sd.GetOperationInput{OperationId: &opId})
+		if err != nil {
+			return nil, err
+		}
 
-	if err != nil {
-		return nil, err
+		return opResp.Operation, nil
 	}
-
-	return opResp.Operation, nil
+	return sdApi.operations.get(ctx, opId, sdApi.timeouts.pollTimeout(), getOperation)
 }
 
 func (sdApi *serviceDiscoveryApi) CreateHttpNamespace(ctx context.Context, nsName string) (opId string, err error) {
 	output, err := sdApi.awsFacade.CreateHttpNamespace(ctx, &sd.CreateHttpNamespaceInput{
 		Name: &nsName,
+		Tags: sdTags(sdApi.tags),
 	})
 
 	if err != nil {
@@ -171,20 +289,40 @@ func (sdApi *serviceDiscoveryApi) CreateHttpNamespace(ctx context.Context, nsNam
 	return aws.ToString(output.OperationId), nil
 }
 
+func (sdApi *serviceDiscoveryApi) GetNamespace(ctx context.Context, nsId string) (namespace *types.Namespace, err error) {
+	output, err := sdApi.awsFacade.GetNamespace(ctx, &sd.GetNamespaceInput{Id: &nsId})
+	if err != nil {
+		return nil, err
+	}
+
+	return output.Namespace, nil
+}
+
+func (sdApi *serviceDiscoveryApi) DeleteNamespace(ctx context.Context, nsId string) (opId string, err error) {
+	output, err := sdApi.awsFacade.DeleteNamespace(ctx, &sd.DeleteNamespaceInput{Id: &nsId})
+	if err != nil {
+		return "", err
+	}
+
+	return aws.ToString(output.OperationId), nil
+}
+
 func (sdApi *serviceDiscoveryApi) CreateService(ctx context.Context, namespace model.Namespace, svcName string) (svcId string, err error) {
-	var output *sd.CreateServiceOutput
+	metadata := ServiceMetadataFromContext(ctx)
+	input := &sd.CreateServiceInput{
+		NamespaceId: &namespace.Id,
+		Name:        &svcName,
+		Tags:        sdTags(sdApi.serviceTags(metadata.Tags)),
+	}
+	if metadata.Description != "" {
+		input.Description = aws.String(metadata.Description)
+	}
 	if namespace.Type == model.DnsPrivateNamespaceType {
-		dnsConfig := sdApi.getDnsConfig()
-		output, err = sdApi.awsFacade.CreateService(ctx, &sd.CreateServiceInput{
-			NamespaceId: &namespace.Id,
-			DnsConfig:   &dnsConfig,
-			Name:        &svcName})
-	} else {
-		output, err = sdApi.awsFacade.CreateService(ctx, &sd.CreateServiceInput{
-			NamespaceId: &namespace.Id,
-			Name:        &svcName})
+		dnsConfig := sdApi.getDnsConfig(DnsTTLFromContext(ctx))
+		input.DnsConfig = &dnsConfig
 	}
 
+	output, err := sdApi.awsFacade.CreateService(ctx, input)
 	if err != nil {
 		return "", err
 	}
@@ -194,11 +332,113 @@ func (sdApi *serviceDiscoveryApi) CreateService(ctx context.Context, namespace m
 	return svcId, nil
 }
 
-func (sdApi *serviceDiscoveryApi) getDnsConfig() types.DnsConfig {
+// serviceTags returns the tags of a created service, the configured tags take precedence over the service tags. The
+// service tags are omitted if the merged tags would exceed the Cloud Map limit of tags per resource.
+func (sdApi *serviceDiscoveryApi) serviceTags(svcTags map[string]string) map[string]string {
+	if len(svcTags) == 0 {
+		return sdApi.tags
+	}
+	if err := validateTagCount(svcTags, sdApi.tags, 0); err != nil {
+		// the service is created without the service tags rather than failing the export
+		sdApi.log.Info("ignoring service tags", "reason", err.Error())
+		return sdApi.tags
+	}
+
+	tags := make(map[string]string, len(svcTags)+len(sdApi.tags))
+	for key, value := range svcTags {
+		tags[key] = value
+	}
+	for key, value := range sdApi.tags {
+		tags[key] = value
+	}
+	return tags
+}
+
+func (sdApi *serviceDiscoveryApi) GetService(ctx context.Context, svcId string) (svc *types.Service, err error) {
+	output, err := sdApi.awsFacade.GetService(ctx, &sd.GetServiceInput{Id: &svcId})
+	if err != nil {
+		return nil, err
+	}
+
+	return output.Service, nil
+}
+
+func (sdApi *serviceDiscoveryApi) UpdateServiceDescription(ctx context.Context, svc *types.Service, description string) (opId string, err error) {
+	// the DNS records and health check configuration omitted from the change would be removed from the service
+	change := &types.ServiceChange{
+		Description:       &description,
+		HealthCheckConfig: svc.HealthCheckConfig,
+	}
+	if svc.DnsConfig != nil {
+		change.DnsConfig = &types.DnsConfigChange{DnsRecords: svc.DnsConfig.DnsRecords}
+	}
+	output, err := sdApi.awsFacade.UpdateService(ctx, &sd.UpdateServiceInput{
+		Id:      svc.Id,
+		Service: change,
+	})
+	if err != nil {
+		return "", err
+	}
+
+	return aws.ToString(output.OperationId), nil
+}
+
+func (sdApi *serviceDiscoveryApi) UpdateServiceDnsRecords(ctx context.Context, svc *types.Service, dnsRecords []types.DnsRecord) (opId string, err error) {
+	// the description and health check configuration omitted from the change would be removed from the service
+	output, err := sdApi.awsFacade.UpdateService(ctx, &sd.UpdateServiceInput{
+		Id: svc.Id,
+		Service: &types.ServiceChange{
+			Description:       svc.Description,
+			DnsConfig:         &types.DnsConfigChange{DnsRecords: dnsRecords},
+			HealthCheckConfig: svc.HealthCheckConfig,
+		},
+	})
+	if err != nil {
+		return "", err
+	}
+
+	return aws.ToString(output.OperationId), nil
+}
+
+func (sdApi *serviceDiscoveryApi) ListTagsForResource(ctx context.Context, resourceArn string) (tags map[string]string, err error) {
+	output, err := sdApi.awsFacade.ListTagsForResource(ctx, &sd.ListTagsForResourceInput{ResourceARN: &resourceArn})
+	if err != nil {
+		return nil, err
+	}
+
+	tags = make(map[string]string, len(output.Tags))
+	for _, tag := range output.Tags {
+		tags[aws.ToString(tag.Key)] = aws.ToString(tag.Value)
+	}
+	return tags, nil
+}
+
+func (sdApi *serviceDiscoveryApi) TagResource(ctx context.Context, resourceArn string, tags map[string]string) error {
+	_, err := sdApi.awsFacade.TagResource(ctx, &sd.TagResourceInput{
+		ResourceARN: &resourceArn,
+		Tags:        sdTags(tags),
+	})
+	return err
+}
+
+func (sdApi *serviceDiscoveryApi) UntagResource(ctx context.Context, resourceArn string, tagKeys []string) error {
+	_, err := sdApi.awsFacade.UntagResource(ctx, &sd.UntagResourceInput{
+		ResourceARN: &resourceArn,
+		TagKeys:     tagKeys,
+	})
+	return err
+}
+
+func (sdApi *serviceDiscoveryApi) DeleteService(ctx context.Context, svcId string) error {
+	_, err := sdApi.awsFacade.DeleteService(ctx, &sd.DeleteServiceInput{Id: &svcId})
+	return err
+}
+
+func (sdApi *serviceDiscoveryApi) getDnsConfig(ttl int64) types.DnsConfig {
 	dnsConfig := types.DnsConfig{
 		DnsRecords: []types.DnsRecord{
 			{
-				TTL:  aws.Int64(defaultServiceTTLInSeconds),
+				TTL:  aws.Int64(ttl),
 				Type: "SRV",
 			},
 		},
@@ -235,16 +475,27 @@ func (sdApi *serviceDiscoveryApi) DeregisterInstance(ctx context.Context, svcId
 }
 
 func (sdApi *serviceDiscoveryApi) PollNamespaceOperation(ctx context.Context, opId string) (nsId string, err error) {
-	err = wait.Poll(defaultOperationPollInterval, defaultOperationPollTimeout, func() (done bool, err error) {
+	// concurrent reconciles waiting on the same namespace share a poll loop
+	return sdApi.operations.poll(ctx, opId, sdApi.timeouts.pollTimeout(), func(ctx context.Context) (string, error) {
+		return sdApi.pollNamespaceOperation(ctx, opId)
+	})
+}
+
+func (sdApi *serviceDiscoveryApi) pollNamespaceOperation(ctx context.Context, opId string) (nsId string, err error) {
+	err = wait.Poll(sdApi.timeouts.pollInterval(), sdApi.timeouts.pollTimeout(), func() (done bool, err error) {
 		sdApi.log.Info("polling operation", "opId", opId)
 		op, err := sdApi.GetOperation(ctx, opId)
 
 		if err != nil {
+			logAwsError(sdApi.log, err, "failed to get namespace operation", "operationId", opId)
 			return true, err
 		}
 
 		if op.Status == types.OperationStatusFail {
-			return true, fmt.Errorf("failed to create namespace: %s", aws.ToString(op.ErrorMessage))
+			err = fmt.Errorf("namespace operation %s failed: %s", op.Type, aws.ToString(op.ErrorMessage))
+			sdApi.log.Error(err, "namespace operation failed",
+				"operationId", opId, "errorCode", aws.ToString(op.ErrorCode))
+			return true, err
 		}
 
 		if op.Status == types.OperationStatusSuccess {
@@ -7,6 +7,7 @@ import (
 	"github.com/aws/aws-cloud-map-mcs-controller-for-k8s/mocks/pkg/cloudmap"
 	"github.com/aws/aws-cloud-map-mcs-controller-for-k8s/pkg/common"
 	"github.com/aws/aws-cloud-map-mcs-controller-for-k8s/pkg/model"
+	"github.com/aws/aws-cloud-map-mcs-controller-for-k8s/pkg/version"
 	"github.com/aws/aws-cloud-map-mcs-controller-for-k8s/test"
 	"github.com/aws/aws-sdk-go-v2/aws"
 	sd "github.com/aws/aws-sdk-go-v2/service/servicediscovery"
@@ -114,6 +115,61 @@ func TestServiceDiscoveryApi_DiscoverInstances_HappyCase(t *testing.T) {
 	assert.Equal(t, test.EndptId2, *insts[1].InstanceId)
 }
 
+func TestServiceDiscoveryApi_ListInstances_HappyCase(t *testing.T) {
+	mockController := gomock.NewController(t)
+	defer mockController.Finish()
+
+	awsFacade := cloudmap.NewMockAwsFacade(mockController)
+	sdApi := getServiceDiscoveryApi(t, awsFacade)
+
+	awsFacade.EXPECT().ListInstances(context.TODO(), &sd.ListInstancesInput{ServiceId: aws.String(test.SvcId)}).
+		Return(&sd.ListInstancesOutput{
+			Instances: []types.InstanceSummary{{Id: aws.String(test.EndptId1)}},
+			NextToken: aws.String("next"),
+		}, nil)
+	awsFacade.EXPECT().ListInstances(context.TODO(),
+		&sd.ListInstancesInput{ServiceId: aws.String(test.SvcId), NextToken: aws.String("next")}).
+		Return(&sd.ListInstancesOutput{
+			Instances: []types.InstanceSummary{
+				{Id: aws.String(test.EndptId2), Attributes: map[string]string{model.EndpointIpv4Attr: test.EndptIp2}},
+			},
+		}, nil)
+
+	insts, err := sdApi.ListInstances(context.TODO(), test.NsName, test.SvcName, test.SvcId)
+	assert.Nil(t, err, "No error for happy case")
+	assert.Len(t, insts, 2)
+	assert.Equal(t, test.EndptId1, *insts[0].InstanceId)
+	assert.Equal(t, test.EndptId2, *insts[1].InstanceId)
+	assert.Equal(t, test.SvcName, *insts[1].ServiceName)
+	assert.Equal(t, test.EndptIp2, insts[1].Attributes[model.EndpointIpv4Attr])
+}
+
+func TestServiceDiscoveryApi_ListInstancePages(t *testing.T) {
+	mockController := gomock.NewController(t)
+	defer mockController.Finish()
+
+	awsFacade := cloudmap.NewMockAwsFacade(mockController)
+	sdApi := getServiceDiscoveryApi(t, awsFacade)
+
+	// the second page isn't requested once the callback fails
+	awsFacade.EXPECT().ListInstances(context.TODO(), &sd.ListInstancesInput{ServiceId: aws.String(test.SvcId)}).
+		Return(&sd.ListInstancesOutput{
+			Instances: []types.InstanceSummary{{Id: aws.String(test.EndptId1)}},
+			NextToken: aws.String("next"),
+		}, nil)
+
+	pages := 0
+	err := sdApi.ListInstancePages(context.TODO(), test.NsName, test.SvcName, test.SvcId,
+		func(insts []types.HttpInstanceSummary) error {
+			pages++
+			assert.Len(t, insts, 1)
+			assert.Equal(t, test.NsName, *insts[0].NamespaceName)
+			return errors.New("stop")
+		})
+	assert.Error(t, err)
+	assert.Equal(t, 1, pages)
+}
+
 func TestServiceDiscoveryApi_ListOperations_HappyCase(t *testing.T) {
 	mockController := gomock.NewController(t)
 	defer mockController.Finish()
@@ -143,7 +199,7 @@ func TestServiceDiscoveryApi_GetOperation_HappyCase(t *testing.T) {
 	sdApi := getServiceDiscoveryApi(t, awsFacade)
 
 	expectedOp := &types.Operation{Id: aws.String(test.OpId1), Status: types.OperationStatusPending}
-	awsFacade.EXPECT().GetOperation(context.TODO(), &sd.GetOperationInput{OperationId: aws.String(test.OpId1)}).
+	awsFacade.EXPECT().GetOperation(gomock.Any(), &sd.GetOperationInput{OperationId: aws.String(test.OpId1)}).
 		Return(&sd.GetOperationOutput{Operation: expectedOp}, nil)
 
 	op, err := sdApi.GetOperation(context.TODO(), test.OpId1)
@@ -151,6 +207,24 @@ func TestServiceDiscoveryApi_GetOperation_HappyCase(t *testing.T) {
 	assert.Equal(t, expectedOp, op)
 }
 
+func TestServiceDiscoveryApi_GetOperation_CachesTerminalOperation(t *testing.T) {
+	mockController := gomock.NewController(t)
+	defer mockController.Finish()
+
+	awsFacade := cloudmap.NewMockAwsFacade(mockController)
+	sdApi := getServiceDiscoveryApi(t, awsFacade)
+
+	expectedOp := &types.Operation{Id: aws.String(test.OpId1), Status: types.OperationStatusFail}
+	awsFacade.EXPECT().GetOperation(gomock.Any(), &sd.GetOperationInput{OperationId: aws.String(test.OpId1)}).
+		Return(&sd.GetOperationOutput{Operation: expectedOp}, nil).Times(1)
+
+	for i := 0; i < 2; i++ {
+		op, err := sdApi.GetOperation(context.TODO(), test.OpId1)
+		assert.NoError(t, err)
+		assert.Equal(t, expectedOp, op)
+	}
+}
+
 func TestServiceDiscoveryApi_CreateHttNamespace_HappyCase(t *testing.T) {
 	mockController := gomock.NewController(t)
 	defer mockController.Finish()
@@ -217,6 +291,195 @@ func TestServiceDiscoveryApi_CreateService_CreateForDnsNamespace(t *testing.T) {
 	assert.Equal(t, svcId, retSvcId, "Successfully created service")
 }
 
+func TestServiceDiscoveryApi_CreateService_CustomDnsTTL(t *testing.T) {
+	mockController := gomock.NewController(t)
+	defer mockController.Finish()
+
+	awsFacade := cloudmap.NewMockAwsFacade(mockController)
+	sdApi := getServiceDiscoveryApi(t, awsFacade)
+
+	ctx := WithDnsTTL(context.TODO(), 10)
+	nsId, svcId, svcName := test.NsId, test.SvcId, test.SvcName
+	awsFacade.EXPECT().CreateService(ctx, &sd.CreateServiceInput{
+		Name:        &svcName,
+		NamespaceId: &nsId,
+		DnsConfig: &types.DnsConfig{
+			DnsRecords: []types.DnsRecord{{
+				TTL:  aws.Int64(10),
+				Type: "SRV",
+			}},
+		},
+	}).
+		Return(&sd.CreateServiceOutput{
+			Service: &types.Service{
+				Id: &svcId,
+			},
+		}, nil)
+
+	retSvcId, _ := sdApi.CreateService(ctx, *test.GetTestDnsNamespace(), svcName)
+	assert.Equal(t, svcId, retSvcId, "Successfully created service")
+}
+
+func TestServiceDiscoveryApi_CreateService_OwnershipTags(t *testing.T) {
+	mockController := gomock.NewController(t)
+	defer mockController.Finish()
+
+	awsFacade := cloudmap.NewMockAwsFacade(mockController)
+	sdApi := getServiceDiscoveryApi(t, awsFacade)
+	sdApi.(*serviceDiscoveryApi).tags = OwnershipTags(test.ClusterId, "")
+
+	nsId, svcId, svcName := test.NsId, test.SvcId, test.SvcName
+	awsFacade.EXPECT().CreateService(context.TODO(), &sd.CreateServiceInput{
+		Name:        &svcName,
+		NamespaceId: &nsId,
+		Tags: []types.Tag{
+			{Key: aws.String(ClusterIdTag), Value: aws.String(test.ClusterId)},
+			{Key: aws.String(ManagedByTag), Value: aws.String(version.PackageName)},
+		},
+	}).
+		Return(&sd.CreateServiceOutput{
+			Service: &types.Service{
+				Id: &svcId,
+			},
+		}, nil)
+
+	retSvcId, _ := sdApi.CreateService(context.TODO(), *test.GetTestHttpNamespace(), svcName)
+	assert.Equal(t, svcId, retSvcId, "Successfully created service")
+}
+
+func TestServiceDiscoveryApi_CreateService_ServiceMetadata(t *testing.T) {
+	mockController := gomock.NewController(t)
+	defer mockController.Finish()
+
+	awsFacade := cloudmap.NewMockAwsFacade(mockController)
+	sdApi := getServiceDiscoveryApi(t, awsFacade)
+	sdApi.(*serviceDiscoveryApi).tags = map[string]string{"env": "prod"}
+
+	ctx := WithServiceMetadata(context.TODO(), ServiceMetadata{
+		Description: "checkout",
+		Tags:        map[string]string{"env": "dev", "team": "payments"},
+	})
+	nsId, svcId, svcName := test.NsId, test.SvcId, test.SvcName
+	awsFacade.EXPECT().CreateService(ctx, &sd.CreateServiceInput{
+		Name:        &svcName,
+		NamespaceId: &nsId,
+		Description: aws.String("checkout"),
+		Tags: []types.Tag{
+			{Key: aws.String("env"), Value: aws.String("prod")},
+			{Key: aws.String("team"), Value: aws.String("payments")},
+		},
+	}).
+		Return(&sd.CreateServiceOutput{
+			Service: &types.Service{
+				Id: &svcId,
+			},
+		}, nil)
+
+	retSvcId, _ := sdApi.CreateService(ctx, *test.GetTestHttpNamespace(), svcName)
+	assert.Equal(t, svcId, retSvcId, "Successfully created service")
+}
+
+func TestServiceDiscoveryApi_ListTagsForResource(t *testing.T) {
+	mockController := gomock.NewController(t)
+	defer mockController.Finish()
+
+	awsFacade := cloudmap.NewMockAwsFacade(mockController)
+	sdApi := getServiceDiscoveryApi(t, awsFacade)
+
+	svcArn := "arn:aws:servicediscovery:us-west-2:123456789012:service/" + test.SvcId
+	awsFacade.EXPECT().ListTagsForResource(context.TODO(), &sd.ListTagsForResourceInput{ResourceARN: &svcArn}).
+		Return(&sd.ListTagsForResourceOutput{
+			Tags: []types.Tag{{Key: aws.String("team"), Value: aws.String("payments")}},
+		}, nil)
+
+	tags, err := sdApi.ListTagsForResource(context.TODO(), svcArn)
+	assert.Nil(t, err)
+	assert.Equal(t, map[string]string{"team": "payments"}, tags)
+}
+
+func TestServiceDiscoveryApi_UpdateServiceDnsRecords(t *testing.T) {
+	mockController := gomock.NewController(t)
+	defer mockController.Finish()
+
+	awsFacade := cloudmap.NewMockAwsFacade(mockController)
+	sdApi := getServiceDiscoveryApi(t, awsFacade)
+
+	svc := &types.Service{Id: aws.String(test.SvcId), Description: aws.String("checkout")}
+	records := []types.DnsRecord{{Type: "SRV", TTL: aws.Int64(30)}}
+	// the description of the service is kept
+	awsFacade.EXPECT().UpdateService(context.TODO(), &sd.UpdateServiceInput{
+		Id: aws.String(test.SvcId),
+		Service: &types.ServiceChange{
+			Description: aws.String("checkout"),
+			DnsConfig:   &types.DnsConfigChange{DnsRecords: records},
+		},
+	}).Return(&sd.UpdateServiceOutput{OperationId: aws.String(test.OpId1)}, nil)
+
+	opId, err := sdApi.UpdateServiceDnsRecords(context.TODO(), svc, records)
+	assert.Nil(t, err)
+	assert.Equal(t, test.OpId1, opId)
+}
+
+func TestServiceDiscoveryApi_UpdateServiceDescription(t *testing.T) {
+	mockController := gomock.NewController(t)
+	defer mockController.Finish()
+
+	awsFacade := cloudmap.NewMockAwsFacade(mockController)
+	sdApi := getServiceDiscoveryApi(t, awsFacade)
+
+	records := []types.DnsRecord{{Type: "SRV", TTL: aws.Int64(60)}}
+	healthCheck := &types.HealthCheckConfig{Type: types.HealthCheckTypeHttp}
+	svc := &types.Service{
+		Id:                aws.String(test.SvcId),
+		Description:       aws.String("cart"),
+		DnsConfig:         &types.DnsConfig{DnsRecords: records},
+		HealthCheckConfig: healthCheck,
+	}
+	// the DNS records and health check configuration of the service are kept
+	awsFacade.EXPECT().UpdateService(context.TODO(), &sd.UpdateServiceInput{
+		Id: aws.String(test.SvcId),
+		Service: &types.ServiceChange{
+			Description:       aws.String("checkout"),
+			DnsConfig:         &types.DnsConfigChange{DnsRecords: records},
+			HealthCheckConfig: healthCheck,
+		},
+	}).Return(&sd.UpdateServiceOutput{OperationId: aws.String(test.OpId1)}, nil)
+
+	opId, err := sdApi.UpdateServiceDescription(context.TODO(), svc, "checkout")
+	assert.Nil(t, err)
+	assert.Equal(t, test.OpId1, opId)
+}
+
+func TestServiceDiscoveryApi_CreateService_TooManyTags(t *testing.T) {
+	mockController := gomock.NewController(t)
+	defer mockController.Finish()
+
+	awsFacade := cloudmap.NewMockAwsFacade(mockController)
+	sdApi := getServiceDiscoveryApi(t, awsFacade)
+	sdApi.(*serviceDiscoveryApi).tags = map[string]string{"env": "prod"}
+
+	svcTags := make(map[string]string, maxResourceTags)
+	for i := 0; i < maxResourceTags; i++ {
+		svcTags[fmt.Sprintf("tag%d", i)] = "value"
+	}
+	ctx := WithServiceMetadata(context.TODO(), ServiceMetadata{Tags: svcTags})
+	nsId, svcId, svcName := test.NsId, test.SvcId, test.SvcName
+	// the service is created with the configured tags only
+	awsFacade.EXPECT().CreateService(ctx, &sd.CreateServiceInput{
+		Name:        &svcName,
+		NamespaceId: &nsId,
+		Tags:        []types.Tag{{Key: aws.String("env"), Value: aws.String("prod")}},
+	}).
+		Return(&sd.CreateServiceOutput{
+			Service: &types.Service{
+				Id: &svcId,
+			},
+		}, nil)
+
+	retSvcId, _ := sdApi.CreateService(ctx, *test.GetTestHttpNamespace(), svcName)
+	assert.Equal(t, svcId, retSvcId)
+}
+
 func TestServiceDiscoveryApi_CreateService_ThrowError(t *testing.T) {
 	mockController := gomock.NewController(t)
 	defer mockController.Finish()
@@ -304,10 +567,10 @@ func TestServiceDiscoveryApi_PollNamespaceOperation_HappyCase(t *testing.T) {
 	defer mockController.Finish()
 
 	awsFacade := cloudmap.NewMockAwsFacade(mockController)
-	awsFacade.EXPECT().GetOperation(context.TODO(), &sd.GetOperationInput{OperationId: aws.String(test.OpId1)}).
+	awsFacade.EXPECT().GetOperation(gomock.Any(), &sd.GetOperationInput{OperationId: aws.String(test.OpId1)}).
 		Return(&sd.GetOperationOutput{Operation: &types.Operation{Status: types.OperationStatusPending}}, nil)
 
-	awsFacade.EXPECT().GetOperation(context.TODO(), &sd.GetOperationInput{OperationId: aws.String(test.OpId1)}).
+	awsFacade.EXPECT().GetOperation(gomock.Any(), &sd.GetOperationInput{OperationId: aws.String(test.OpId1)}).
 		Return(&sd.GetOperationOutput{Operation: &types.Operation{Status: types.OperationStatusSuccess,
 			Targets: map[string]string{string(types.OperationTargetTypeNamespace): test.NsId}}}, nil)
 
@@ -320,7 +583,8 @@ func TestServiceDiscoveryApi_PollNamespaceOperation_HappyCase(t *testing.T) {
 
 func getServiceDiscoveryApi(t *testing.T, awsFacade *cloudmap.MockAwsFacade) ServiceDiscoveryApi {
 	return &serviceDiscoveryApi{
-		log:       common.NewLoggerWithLogr(testingLogger.TestLogger{T: t}),
-		awsFacade: awsFacade,
+		log:        common.NewLoggerWithLogr(testingLogger.TestLogger{T: t}),
+		awsFacade:  awsFacade,
+		operations: newOperationCache(),
 	}
 }
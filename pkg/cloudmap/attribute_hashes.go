@@ -0,0 +1,109 @@
+package cloudmap
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"sort"
+	"sync"
+)
+
+// attributeHashes remembers a hash of the attributes of each instance, as last registered by the client or discovered
+// in Cloud Map, so instances whose attributes didn't change are not registered again. Cloud Map treats re-registration
+// as an update, which consumes API quota and creates an operation to poll. The hashes of a service are replaced
+// whenever its instances are discovered, so instances removed outside of the client are registered again after the
+// next discovery. A nil attributeHashes remembers nothing.
+type attributeHashes struct {
+	mu     sync.Mutex
+	hashes map[string]map[string]string
+}
+
+func newAttributeHashes() *attributeHashes {
+	return &attributeHashes{hashes: make(map[string]map[string]string)}
+}
+
+// unchanged returns true if the instance was registered with the same attributes.
+func (h *attributeHashes) unchanged(nsName string, svcName string, instId string, attrs map[string]string) bool {
+	if h == nil {
+		return false
+	}
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	hash, found := h.hashes[serviceKey(nsName, svcName)][instId]
+	return found && hash == hashAttributes(attrs)
+}
+
+// registered remembers the attributes of a registered instance.
+func (h *attributeHashes) registered(nsName string, svcName string, instId string, attrs map[string]string) {
+	if h == nil {
+		return
+	}
+
+	key, hash := serviceKey(nsName, svcName), hashAttributes(attrs)
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if h.hashes[key] == nil {
+		h.hashes[key] = make(map[string]string)
+	}
+	h.hashes[key][instId] = hash
+}
+
+// deregistered forgets a de-registered instance.
+func (h *attributeHashes) deregistered(nsName string, svcName string, instId string) {
+	if h == nil {
+		return
+	}
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	delete(h.hashes[serviceKey(nsName, svcName)], instId)
+}
+
+// discovered replaces the remembered instances of a service with the instances discovered in Cloud Map.
+func (h *attributeHashes) discovered(nsName string, svcName string, instAttrs map[string]map[string]string) {
+	if h == nil {
+		return
+	}
+
+	hashes := make(map[string]string, len(instAttrs))
+	for instId, attrs := range instAttrs {
+		hashes[instId] = hashAttributes(attrs)
+	}
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.hashes[serviceKey(nsName, svcName)] = hashes
+}
+
+// forget forgets the instances of a deleted service.
+func (h *attributeHashes) forget(nsName string, svcName string) {
+	if h == nil {
+		return
+	}
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	delete(h.hashes, serviceKey(nsName, svcName))
+}
+
+func serviceKey(nsName string, svcName string) string {
+	return nsName + "/" + svcName
+}
+
+// hashAttributes returns a hash of the instance attributes, independent of their order.
+func hashAttributes(attrs map[string]string) string {
+	keys := make([]string, 0, len(attrs))
+	for key := range attrs {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	hash := sha256.New()
+	for _, key := range keys {
+		// keys and values are separated by characters not allowed in Cloud Map attributes
+		hash.Write([]byte(key))
+		hash.Write([]byte{0})
+		hash.Write([]byte(attrs[key]))
+		hash.Write([]byte{0})
+	}
+	return hex.EncodeToString(hash.Sum(nil))
+}
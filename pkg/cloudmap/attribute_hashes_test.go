@@ -0,0 +1,45 @@
+package cloudmap
+
+import (
+	"github.com/aws/aws-cloud-map-mcs-controller-for-k8s/test"
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func TestAttributeHashes(t *testing.T) {
+	hashes := newAttributeHashes()
+	attrs := test.GetTestEndpoint1().GetCloudMapAttributes()
+	assert.False(t, hashes.unchanged(test.NsName, test.SvcName, test.EndptId1, attrs))
+
+	hashes.registered(test.NsName, test.SvcName, test.EndptId1, attrs)
+	assert.True(t, hashes.unchanged(test.NsName, test.SvcName, test.EndptId1, attrs))
+	assert.False(t, hashes.unchanged(test.NsName, "other", test.EndptId1, attrs))
+
+	changed := test.GetTestEndpoint1().GetCloudMapAttributes()
+	changed["key"] = "value"
+	assert.False(t, hashes.unchanged(test.NsName, test.SvcName, test.EndptId1, changed))
+
+	hashes.deregistered(test.NsName, test.SvcName, test.EndptId1)
+	assert.False(t, hashes.unchanged(test.NsName, test.SvcName, test.EndptId1, attrs))
+
+	// discovered instances replace the registered ones
+	hashes.registered(test.NsName, test.SvcName, test.EndptId1, attrs)
+	hashes.discovered(test.NsName, test.SvcName, map[string]map[string]string{test.EndptId2: changed})
+	assert.False(t, hashes.unchanged(test.NsName, test.SvcName, test.EndptId1, attrs))
+	assert.True(t, hashes.unchanged(test.NsName, test.SvcName, test.EndptId2, changed))
+
+	hashes.forget(test.NsName, test.SvcName)
+	assert.False(t, hashes.unchanged(test.NsName, test.SvcName, test.EndptId2, changed))
+}
+
+func TestAttributeHashes_Nil(t *testing.T) {
+	var hashes *attributeHashes
+	attrs := test.GetTestEndpoint1().GetCloudMapAttributes()
+	hashes.registered(test.NsName, test.SvcName, test.EndptId1, attrs)
+	assert.False(t, hashes.unchanged(test.NsName, test.SvcName, test.EndptId1, attrs))
+}
+
+func TestHashAttributes(t *testing.T) {
+	assert.Equal(t, hashAttributes(map[string]string{"a": "1", "b": "2"}), hashAttributes(map[string]string{"b": "2", "a": "1"}))
+	assert.NotEqual(t, hashAttributes(map[string]string{"a": "1b"}), hashAttributes(map[string]string{"a1": "b"}))
+}
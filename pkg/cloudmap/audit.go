@@ -0,0 +1,118 @@
+package cloudmap
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"os"
+	"sync"
+	"time"
+)
+
+const (
+	AuditActionCreateNamespace    = "CreateHttpNamespace"
+	AuditActionDeleteNamespace    = "DeleteNamespace"
+	AuditActionCreateService      = "CreateService"
+	AuditActionRegisterInstance   = "RegisterInstance"
+	AuditActionDeregisterInstance = "DeregisterInstance"
+	AuditActionDeleteService      = "DeleteService"
+	AuditActionUpdateService      = "UpdateService"
+	AuditActionTagResource        = "TagResource"
+	AuditActionUntagResource      = "UntagResource"
+
+	// AuditStdout is the audit log path which writes audit records to standard output.
+	AuditStdout = "-"
+)
+
+type auditTriggerKey struct{}
+
+type nopCloser struct{}
+
+func (nopCloser) Close() error { return nil }
+
+// AuditLogger records every Cloud Map mutation performed by the controller for compliance review.
+type AuditLogger interface {
+	// Record writes an audit record for a Cloud Map mutation.
+	Record(ctx context.Context, record AuditRecord)
+}
+
+// AuditRecord describes a single Cloud Map mutation. Before and After summarize the affected resource attributes
+// prior to and after the mutation where they are known.
+type AuditRecord struct {
+	Time        time.Time         `json:"time"`
+	Action      string            `json:"action"`
+	ClusterId   string            `json:"clusterId,omitempty"`
+	Trigger     string            `json:"trigger,omitempty"`
+	Namespace   string            `json:"namespace"`
+	Service     string            `json:"service,omitempty"`
+	ServiceId   string            `json:"serviceId,omitempty"`
+	InstanceId  string            `json:"instanceId,omitempty"`
+	OperationId string            `json:"operationId,omitempty"`
+	Before      map[string]string `json:"before,omitempty"`
+	After       map[string]string `json:"after,omitempty"`
+	Error       string            `json:"error,omitempty"`
+}
+
+type auditLogger struct {
+	mu        sync.Mutex
+	encoder   *json.Encoder
+	clusterId string
+	now       func() time.Time
+}
+
+// NewAuditLogger creates an audit logger writing JSON lines to the writer, attributing all records to the cluster.
+func NewAuditLogger(w io.Writer, clusterId string) AuditLogger {
+	return &auditLogger{
+		encoder:   json.NewEncoder(w),
+		clusterId: clusterId,
+		now:       time.Now,
+	}
+}
+
+// NewAuditLoggerFromPath creates an audit logger appending to the file at the path, or writing to standard output
+// for AuditStdout. The returned closer must be called on shutdown.
+func NewAuditLoggerFromPath(path string, clusterId string) (AuditLogger, io.Closer, error) {
+	if path == AuditStdout {
+		return NewAuditLogger(os.Stdout, clusterId), nopCloser{}, nil
+	}
+
+	f, err := os.OpenFile(path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0600)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return NewAuditLogger(f, clusterId), f, nil
+}
+
+func (a *auditLogger) Record(ctx context.Context, record AuditRecord) {
+	record.Time = a.now().UTC()
+	record.ClusterId = a.clusterId
+	record.Trigger = AuditTriggerFromContext(ctx)
+
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	// Encoding errors are deliberately ignored, auditing must not block Cloud Map synchronization.
+	_ = a.encoder.Encode(record)
+}
+
+// WithAuditTrigger returns a context which attributes Cloud Map mutations to the triggering object,
+// e.g. "ServiceExport/namespace/name".
+func WithAuditTrigger(ctx context.Context, trigger string) context.Context {
+	return context.WithValue(ctx, auditTriggerKey{}, trigger)
+}
+
+// AuditTriggerFromContext returns the triggering object of Cloud Map mutations or an empty string if not set.
+func AuditTriggerFromContext(ctx context.Context) string {
+	if trigger, ok := ctx.Value(auditTriggerKey{}).(string); ok {
+		return trigger
+	}
+	return ""
+}
+
+func errorString(err error) string {
+	if err == nil {
+		return ""
+	}
+	return err.Error()
+}
@@ -0,0 +1,45 @@
+package cloudmap
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"github.com/aws/aws-cloud-map-mcs-controller-for-k8s/test"
+	"github.com/stretchr/testify/assert"
+	"testing"
+	"time"
+)
+
+func TestAuditLogger_Record(t *testing.T) {
+	buf := &bytes.Buffer{}
+	auditLogger := NewAuditLogger(buf, "cluster-1").(*auditLogger)
+	auditLogger.now = func() time.Time { return time.Unix(100, 0) }
+
+	ctx := WithAuditTrigger(context.TODO(), "ServiceExport/"+test.NsName+"/"+test.SvcName)
+	auditLogger.Record(ctx, AuditRecord{
+		Action:      AuditActionRegisterInstance,
+		Namespace:   test.NsName,
+		Service:     test.SvcName,
+		ServiceId:   test.SvcId,
+		InstanceId:  test.EndptId1,
+		OperationId: test.OpId1,
+		After:       map[string]string{"AWS_INSTANCE_IPV4": test.EndptIp1},
+		Error:       errorString(errors.New("error")),
+	})
+
+	record := AuditRecord{}
+	assert.NoError(t, json.Unmarshal(buf.Bytes(), &record))
+	assert.Equal(t, time.Unix(100, 0).UTC(), record.Time)
+	assert.Equal(t, AuditActionRegisterInstance, record.Action)
+	assert.Equal(t, "cluster-1", record.ClusterId)
+	assert.Equal(t, "ServiceExport/"+test.NsName+"/"+test.SvcName, record.Trigger)
+	assert.Equal(t, test.OpId1, record.OperationId)
+	assert.Equal(t, test.EndptIp1, record.After["AWS_INSTANCE_IPV4"])
+	assert.Empty(t, record.Before)
+	assert.Equal(t, "error", record.Error)
+}
+
+func TestAuditTriggerFromContext_NotSet(t *testing.T) {
+	assert.Empty(t, AuditTriggerFromContext(context.TODO()))
+}
@@ -0,0 +1,331 @@
+package cloudmap
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/config"
+	"github.com/aws/aws-sdk-go-v2/credentials/stscreds"
+	"github.com/aws/aws-sdk-go-v2/service/sts"
+	ststypes "github.com/aws/aws-sdk-go-v2/service/sts/types"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+const (
+	// DefaultRoleSessionName is the session name of assumed roles, recorded in CloudTrail.
+	DefaultRoleSessionName = "aws-cloud-map-mcs-controller"
+
+	// StsRegionalEndpoints assumes roles with the STS endpoint of the configured region
+	StsRegionalEndpoints = "regional"
+	// StsLegacyEndpoints assumes roles with the global STS endpoint
+	StsLegacyEndpoints = "legacy"
+
+	// ClusterIdSessionTag is the session tag identifying the cluster of assumed role sessions in CloudTrail
+	ClusterIdSessionTag = ClusterIdTag
+	// ClusterSetIdSessionTag is the session tag identifying the clusterset of assumed role sessions in CloudTrail
+	ClusterSetIdSessionTag = ClusterSetIdTag
+
+	// reservedTagPrefix is the prefix of tags managed by the controller
+	reservedTagPrefix = "multicluster.k8s.aws/"
+	// stsGlobalRegion resolves the global STS endpoint
+	stsGlobalRegion = "aws-global"
+	// maxRoleSessionNameLength is the maximum length of STS role session names
+	maxRoleSessionNameLength = 64
+)
+
+var invalidSessionNameChars = regexp.MustCompile(`[^\w+=,.@-]`)
+
+// AwsConfig selects the AWS region and the credential source of the controller. The default credential chain of the
+// SDK is used unless a source is configured.
+type AwsConfig struct {
+	// Region is the AWS region of Cloud Map, discovered from the environment if empty
+	Region string
+	// Profile is the shared config profile to load
+	Profile string
+	// SharedCredentialsFile is the path of a shared credentials file with static credentials
+	SharedCredentialsFile string
+	// WebIdentityTokenFile is the path of the web identity token exchanged for credentials of RoleArn
+	WebIdentityTokenFile string
+	// RoleArn is the role assumed with the loaded credentials, or with the web identity token if configured
+	RoleArn string
+	// RoleSessionName is the session name of the assumed role
+	RoleSessionName string
+	// RoleSessionTags are comma separated key=value session tags of the assumed role
+	RoleSessionTags string
+	// StsRegionalEndpoints selects the STS endpoint, regional or legacy
+	StsRegionalEndpoints string
+
+	// ReadRoleArn is the role assumed for the Cloud Map reads of the import path, with the credentials of RoleArn if
+	// configured, otherwise with the loaded credentials
+	ReadRoleArn string
+	// WriteRoleArn is the role assumed for the Cloud Map writes of the export path, as ReadRoleArn
+	WriteRoleArn string
+	// ReadOnly refuses the Cloud Map writes, for clusters which only import services
+	ReadOnly bool
+
+	// ClusterId identifies the cluster in the session name and tags of the assumed role
+	ClusterId string
+	// ClusterSetId identifies the clusterset in the session tags of the assumed role
+	ClusterSetId string
+}
+
+// NewDefaultAwsConfig creates an AWS config using the default credential chain.
+func NewDefaultAwsConfig() *AwsConfig {
+	return &AwsConfig{
+		RoleSessionName:      DefaultRoleSessionName,
+		StsRegionalEndpoints: StsRegionalEndpoints,
+	}
+}
+
+// BindFlags binds the AWS settings to command line flags.
+func (c *AwsConfig) BindFlags(fs *flag.FlagSet) {
+	fs.StringVar(&c.Region, "aws-region", c.Region,
+		"The AWS region of Cloud Map, discovered from AWS_REGION, the AWS config file or EC2 IMDS if empty.")
+	fs.StringVar(&c.Profile, "aws-profile", c.Profile,
+		"The profile of the shared AWS config and credentials files to use.")
+	fs.StringVar(&c.SharedCredentialsFile, "aws-shared-credentials-file", c.SharedCredentialsFile,
+		"The path of a shared credentials file with static AWS credentials.")
+	fs.StringVar(&c.WebIdentityTokenFile, "aws-web-identity-token-file", c.WebIdentityTokenFile,
+		"The path of a web identity token file exchanged for credentials of --aws-role-arn.")
+	fs.StringVar(&c.RoleArn, "aws-role-arn", c.RoleArn,
+		"The ARN of an IAM role to assume, with the web identity token if configured, "+
+			"otherwise with the loaded credentials.")
+	fs.StringVar(&c.RoleSessionName, "aws-role-session-name", c.RoleSessionName,
+		"The session name of the assumed IAM role. The cluster ID is appended to the default session name.")
+	fs.StringVar(&c.RoleSessionTags, "aws-role-session-tags", c.RoleSessionTags,
+		"Comma separated key=value session tags of the assumed IAM role, e.g. \"team=platform,env=prod\". "+
+			"The cluster and clusterset IDs are always added as session tags, "+
+			"which requires sts:TagSession in the trust policy of the role.")
+	fs.StringVar(&c.StsRegionalEndpoints, "aws-sts-regional-endpoints", c.StsRegionalEndpoints,
+		"The STS endpoint used to assume roles: regional for the endpoint of the configured region, "+
+			"or legacy for the global endpoint.")
+	fs.StringVar(&c.ReadRoleArn, "aws-read-role-arn", c.ReadRoleArn,
+		"The ARN of an IAM role to assume for the Cloud Map reads of the import path (DiscoverInstances, List* and "+
+			"Get*), with the credentials of --aws-role-arn if set, otherwise with the loaded credentials.")
+	fs.StringVar(&c.WriteRoleArn, "aws-write-role-arn", c.WriteRoleArn,
+		"The ARN of an IAM role to assume for the Cloud Map writes of the export path (Create*, Update*, Delete*, "+
+			"Register*, Deregister* and tagging), with the credentials of --aws-role-arn if set, otherwise with the "+
+			"loaded credentials.")
+	fs.BoolVar(&c.ReadOnly, "aws-read-only", c.ReadOnly,
+		"Never call the Cloud Map actions modifying Cloud Map, for clusters which only import services. "+
+			"The credentials then only need the read permissions, exports fail.")
+}
+
+// Validate returns an error if the credential settings are inconsistent.
+func (c *AwsConfig) Validate() error {
+	if c.WebIdentityTokenFile != "" && c.RoleArn == "" {
+		return errors.New("a web identity token file requires a role ARN")
+	}
+	if c.RoleArn == "" && c.RoleSessionTags != "" {
+		return errors.New("role session tags require a role ARN")
+	}
+	if c.WebIdentityTokenFile != "" && c.RoleSessionTags != "" {
+		return errors.New("role session tags are not supported with web identity tokens, " +
+			"they are taken from the token claims")
+	}
+	if c.ReadOnly && c.WriteRoleArn != "" {
+		return errors.New("a write role ARN is not supported with read only Cloud Map access")
+	}
+	if c.StsRegionalEndpoints != StsRegionalEndpoints && c.StsRegionalEndpoints != StsLegacyEndpoints {
+		return fmt.Errorf("unsupported STS regional endpoints %q, expected %s or %s",
+			c.StsRegionalEndpoints, StsRegionalEndpoints, StsLegacyEndpoints)
+	}
+
+	tags, err := ParseTags(c.RoleSessionTags)
+	if err != nil {
+		return err
+	}
+	for key := range tags {
+		if strings.HasPrefix(key, reservedTagPrefix) {
+			return fmt.Errorf("session tag %s uses the reserved prefix %s", key, reservedTagPrefix)
+		}
+	}
+	return nil
+}
+
+// Load returns the AWS SDK config with the configured region and credential source.
+func (c *AwsConfig) Load(ctx context.Context) (aws.Config, error) {
+	if err := c.Validate(); err != nil {
+		return aws.Config{}, err
+	}
+
+	// the SDK looks for the region in order 1) AWS_REGION env var, 2) ~/.aws/config file, 3) EC2 IMDS
+	opts := []func(*config.LoadOptions) error{config.WithRegion(c.Region), config.WithEC2IMDSRegion()}
+	if c.Profile != "" {
+		opts = append(opts, config.WithSharedConfigProfile(c.Profile))
+	}
+	if c.SharedCredentialsFile != "" {
+		opts = append(opts, config.WithSharedCredentialsFiles([]string{c.SharedCredentialsFile}))
+	}
+
+	cfg, err := config.LoadDefaultConfig(ctx, opts...)
+	if err != nil {
+		return cfg, err
+	}
+
+	if c.RoleArn != "" {
+		cfg.Credentials = &aws.CredentialsCache{Provider: c.roleProvider(c.stsClient(cfg))}
+	}
+	return cfg, nil
+}
+
+// SplitsPaths returns true if the reads and writes of Cloud Map use separate credentials, or the writes are refused.
+func (c *AwsConfig) SplitsPaths() bool {
+	return c.ReadRoleArn != "" || c.WriteRoleArn != "" || c.ReadOnly
+}
+
+// NewAwsFacade creates an AWS facade from an AWS client config returned by Load, e.g. a copy for another region. If
+// the paths are split, the reads and writes are called with the credentials of the read and write roles, the
+// credentials of the client config are used for paths without a role of their own. Requests with a namespace role
+// in their context are called with the credentials of the namespace role, see WithNamespaceRole.
+func (c *AwsConfig) NewAwsFacade(cfg *aws.Config) AwsFacade {
+	if !c.SplitsPaths() {
+		return NewAwsFacadeFromConfig(c.namespaceRoleConfig(cfg))
+	}
+
+	read := NewAwsFacadeFromConfig(c.namespaceRoleConfig(c.pathConfig(cfg, c.ReadRoleArn)))
+	var write AwsFacade
+	if !c.ReadOnly {
+		write = NewAwsFacadeFromConfig(c.namespaceRoleConfig(c.pathConfig(cfg, c.WriteRoleArn)))
+	}
+	return NewSplitAwsFacade(read, write)
+}
+
+// CredentialSource describes the configured credential source for logging.
+func (c *AwsConfig) CredentialSource() string {
+	switch {
+	case c.WebIdentityTokenFile != "":
+		return "web identity"
+	case c.RoleArn != "":
+		return "assumed role"
+	case c.SharedCredentialsFile != "":
+		return "shared credentials file"
+	case c.Profile != "":
+		return "shared config profile"
+	default:
+		return "default chain"
+	}
+}
+
+func (c *AwsConfig) roleProvider(client *sts.Client) aws.CredentialsProvider {
+	if c.WebIdentityTokenFile != "" {
+		// web identity sessions take their tags from the token, the session name still identifies the cluster
+		return stscreds.NewWebIdentityRoleProvider(client, c.RoleArn, stscreds.IdentityTokenFile(c.WebIdentityTokenFile),
+			func(o *stscreds.WebIdentityRoleOptions) {
+				o.RoleSessionName = c.roleSessionName()
+			})
+	}
+
+	return c.assumeRoleProvider(client, c.RoleArn)
+}
+
+func (c *AwsConfig) assumeRoleProvider(client *sts.Client, roleArn string) aws.CredentialsProvider {
+	return stscreds.NewAssumeRoleProvider(client, roleArn, func(o *stscreds.AssumeRoleOptions) {
+		o.RoleSessionName = c.roleSessionName()
+		o.Tags = sessionTags(c.sessionTags())
+	})
+}
+
+// pathConfig returns a copy of the AWS client config with the credentials of the role of a path, the config itself if
+// the path has no role.
+func (c *AwsConfig) pathConfig(cfg *aws.Config, roleArn string) *aws.Config {
+	if roleArn == "" {
+		return cfg
+	}
+	pathCfg := cfg.Copy()
+	pathCfg.Credentials = &aws.CredentialsCache{Provider: c.assumeRoleProvider(c.stsClient(stsConfig(cfg)), roleArn)}
+	return &pathCfg
+}
+
+// namespaceRoleConfig returns a copy of the AWS client config which assumes the namespace roles of the request
+// contexts with the credentials of the config.
+func (c *AwsConfig) namespaceRoleConfig(cfg *aws.Config) *aws.Config {
+	if cfg.Credentials == nil {
+		return cfg
+	}
+	roleCfg := cfg.Copy()
+	roleCfg.Credentials = &namespaceRoleProvider{
+		base:   cfg.Credentials,
+		client: c.stsClient(stsConfig(cfg)),
+		config: c,
+		roles:  make(map[NamespaceRole]aws.CredentialsProvider),
+	}
+	return &roleCfg
+}
+
+// stsConfig returns a copy of the AWS client config for STS, the middlewares of the Cloud Map requests, e.g. the
+// rate limiter, don't apply to STS.
+func stsConfig(cfg *aws.Config) aws.Config {
+	stsCfg := cfg.Copy()
+	stsCfg.APIOptions = nil
+	return stsCfg
+}
+
+func (c *AwsConfig) stsClient(cfg aws.Config) *sts.Client {
+	return sts.NewFromConfig(cfg, func(o *sts.Options) {
+		if c.StsRegionalEndpoints == StsLegacyEndpoints {
+			o.Region = stsGlobalRegion
+		}
+	})
+}
+
+// roleSessionName returns the session name of assumed roles, the default session name is suffixed with the cluster
+// ID so CloudTrail identifies the cluster even without session tags.
+func (c *AwsConfig) roleSessionName() string {
+	name := c.RoleSessionName
+	if name == DefaultRoleSessionName && c.ClusterId != "" {
+		name += "-" + invalidSessionNameChars.ReplaceAllString(c.ClusterId, "-")
+	}
+	if len(name) > maxRoleSessionNameLength {
+		name = name[:maxRoleSessionNameLength]
+	}
+	return name
+}
+
+// sessionTags returns the configured session tags along with the cluster and clusterset IDs.
+func (c *AwsConfig) sessionTags() map[string]string {
+	// tags were validated before
+	tags, _ := ParseTags(c.RoleSessionTags)
+	if c.ClusterId != "" {
+		tags[ClusterIdSessionTag] = c.ClusterId
+	}
+	if c.ClusterSetId != "" {
+		tags[ClusterSetIdSessionTag] = c.ClusterSetId
+	}
+	return tags
+}
+
+// ParseTags parses comma separated key=value pairs.
+func ParseTags(s string) (map[string]string, error) {
+	tags := make(map[string]string)
+	for _, pair := range strings.Split(s, ",") {
+		if strings.TrimSpace(pair) == "" {
+			continue
+		}
+
+		kv := strings.SplitN(pair, "=", 2)
+		if len(kv) != 2 || strings.TrimSpace(kv[0]) == "" {
+			return nil, fmt.Errorf("invalid tag %q, expected key=value", pair)
+		}
+		tags[strings.TrimSpace(kv[0])] = strings.TrimSpace(kv[1])
+	}
+	return tags, nil
+}
+
+func sessionTags(tags map[string]string) []ststypes.Tag {
+	keys := make([]string, 0, len(tags))
+	for key := range tags {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	result := make([]ststypes.Tag, 0, len(keys))
+	for _, key := range keys {
+		result = append(result, ststypes.Tag{Key: aws.String(key), Value: aws.String(tags[key])})
+	}
+	return result
+}
@@ -0,0 +1,156 @@
+package cloudmap
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"github.com/aws/aws-sdk-go-v2/aws"
+	sd "github.com/aws/aws-sdk-go-v2/service/servicediscovery"
+	"github.com/stretchr/testify/assert"
+	"io/ioutil"
+	"path/filepath"
+	"testing"
+)
+
+func TestAwsConfig_Validate(t *testing.T) {
+	tests := []struct {
+		name    string
+		config  AwsConfig
+		wantErr bool
+	}{
+		{
+			name:   "default chain",
+			config: AwsConfig{},
+		},
+		{
+			name:   "assumed role with session tags",
+			config: AwsConfig{RoleArn: "arn:aws:iam::123456789012:role/mcs", RoleSessionTags: "team=platform"},
+		},
+		{
+			name:   "web identity",
+			config: AwsConfig{RoleArn: "arn:aws:iam::123456789012:role/mcs", WebIdentityTokenFile: "/var/run/token"},
+		},
+		{
+			name:    "web identity without role",
+			config:  AwsConfig{WebIdentityTokenFile: "/var/run/token"},
+			wantErr: true,
+		},
+		{
+			name:    "session tags without role",
+			config:  AwsConfig{RoleSessionTags: "team=platform"},
+			wantErr: true,
+		},
+		{
+			name: "session tags with web identity",
+			config: AwsConfig{RoleArn: "arn:aws:iam::123456789012:role/mcs", WebIdentityTokenFile: "/var/run/token",
+				RoleSessionTags: "team=platform"},
+			wantErr: true,
+		},
+		{
+			name:    "reserved session tag",
+			config:  AwsConfig{RoleArn: "arn:aws:iam::123456789012:role/mcs", RoleSessionTags: ClusterIdSessionTag + "=a"},
+			wantErr: true,
+		},
+		{
+			name:    "unsupported STS endpoints",
+			config:  AwsConfig{StsRegionalEndpoints: "global"},
+			wantErr: true,
+		},
+		{
+			name: "read and write roles",
+			config: AwsConfig{ReadRoleArn: "arn:aws:iam::123456789012:role/mcs-read",
+				WriteRoleArn: "arn:aws:iam::123456789012:role/mcs-write"},
+		},
+		{
+			name:   "read only",
+			config: AwsConfig{ReadRoleArn: "arn:aws:iam::123456789012:role/mcs-read", ReadOnly: true},
+		},
+		{
+			name:    "read only with write role",
+			config:  AwsConfig{WriteRoleArn: "arn:aws:iam::123456789012:role/mcs-write", ReadOnly: true},
+			wantErr: true,
+		},
+		{
+			name:    "malformed session tags",
+			config:  AwsConfig{RoleArn: "arn:aws:iam::123456789012:role/mcs", RoleSessionTags: "team"},
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if tt.config.StsRegionalEndpoints == "" {
+				tt.config.StsRegionalEndpoints = StsRegionalEndpoints
+			}
+			err := tt.config.Validate()
+			assert.Equal(t, tt.wantErr, err != nil, "error: %v", err)
+		})
+	}
+}
+
+func TestAwsConfig_Load_SharedCredentialsFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "credentials")
+	assert.NoError(t, ioutil.WriteFile(path, []byte(
+		"[mcs]\naws_access_key_id = AKIDEXAMPLE\naws_secret_access_key = secret\n"), 0600))
+
+	awsConfig := NewDefaultAwsConfig()
+	fs := flag.NewFlagSet("test", flag.ContinueOnError)
+	awsConfig.BindFlags(fs)
+	assert.NoError(t, fs.Parse([]string{"--aws-region=us-west-2", "--aws-profile=mcs",
+		"--aws-shared-credentials-file=" + path}))
+
+	cfg, err := awsConfig.Load(context.TODO())
+	assert.NoError(t, err)
+	assert.Equal(t, "us-west-2", cfg.Region)
+
+	credentials, err := cfg.Credentials.Retrieve(context.TODO())
+	assert.NoError(t, err)
+	assert.Equal(t, "AKIDEXAMPLE", credentials.AccessKeyID)
+	assert.Equal(t, "shared credentials file", awsConfig.CredentialSource())
+}
+
+func TestAwsConfig_SessionIdentity(t *testing.T) {
+	awsConfig := NewDefaultAwsConfig()
+	awsConfig.ClusterId = "prod/us-west-2"
+	awsConfig.ClusterSetId = "clusterset-1"
+	awsConfig.RoleSessionTags = "team=platform"
+
+	assert.Equal(t, DefaultRoleSessionName+"-prod-us-west-2", awsConfig.roleSessionName())
+	assert.Equal(t, map[string]string{
+		"team":                 "platform",
+		ClusterIdSessionTag:    "prod/us-west-2",
+		ClusterSetIdSessionTag: "clusterset-1",
+	}, awsConfig.sessionTags())
+
+	awsConfig.RoleSessionName = "custom"
+	assert.Equal(t, "custom", awsConfig.roleSessionName())
+}
+
+func TestParseTags(t *testing.T) {
+	tags, err := ParseTags("team=platform, env=prod,,")
+	assert.NoError(t, err)
+	assert.Equal(t, map[string]string{"team": "platform", "env": "prod"}, tags)
+
+	_, err = ParseTags("=prod")
+	assert.Error(t, err)
+}
+
+func TestAwsConfig_NewAwsFacade(t *testing.T) {
+	cfg := &aws.Config{Region: "us-west-2", Credentials: aws.AnonymousCredentials{}}
+	awsConfig := NewDefaultAwsConfig()
+	_, split := awsConfig.NewAwsFacade(cfg).(*splitAwsFacade)
+	assert.False(t, split, "the paths share the credentials by default")
+
+	awsConfig.ReadRoleArn = "arn:aws:iam::123456789012:role/mcs-read"
+	facade, split := awsConfig.NewAwsFacade(cfg).(*splitAwsFacade)
+	assert.True(t, split)
+	assert.NotNil(t, facade.write, "writes use the credentials of the config without a write role")
+
+	readCfg := awsConfig.pathConfig(cfg, awsConfig.ReadRoleArn)
+	assert.NotEqual(t, cfg.Credentials, readCfg.Credentials)
+	assert.Equal(t, aws.AnonymousCredentials{}, cfg.Credentials, "the config is copied")
+	assert.Equal(t, cfg, awsConfig.pathConfig(cfg, ""))
+
+	awsConfig.ReadOnly = true
+	_, err := awsConfig.NewAwsFacade(cfg).RegisterInstance(context.TODO(), &sd.RegisterInstanceInput{})
+	assert.True(t, errors.Is(err, ErrReadOnly))
+}
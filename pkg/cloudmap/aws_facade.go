@@ -24,15 +24,42 @@ type AwsFacade interface {
 	// CreateHttpNamespace provides ServiceDiscovery CreateHttpNamespace wrapper interface.
 	CreateHttpNamespace(context.Context, *sd.CreateHttpNamespaceInput, ...func(*sd.Options)) (*sd.CreateHttpNamespaceOutput, error)
 
+	// GetNamespace provides ServiceDiscovery GetNamespace wrapper interface.
+	GetNamespace(context.Context, *sd.GetNamespaceInput, ...func(*sd.Options)) (*sd.GetNamespaceOutput, error)
+
+	// DeleteNamespace provides ServiceDiscovery DeleteNamespace wrapper interface.
+	DeleteNamespace(context.Context, *sd.DeleteNamespaceInput, ...func(*sd.Options)) (*sd.DeleteNamespaceOutput, error)
+
 	// CreateService provides ServiceDiscovery CreateService wrapper interface.
 	CreateService(context.Context, *sd.CreateServiceInput, ...func(*sd.Options)) (*sd.CreateServiceOutput, error)
 
+	// GetService provides ServiceDiscovery GetService wrapper interface.
+	GetService(context.Context, *sd.GetServiceInput, ...func(*sd.Options)) (*sd.GetServiceOutput, error)
+
+	// UpdateService provides ServiceDiscovery UpdateService wrapper interface.
+	UpdateService(context.Context, *sd.UpdateServiceInput, ...func(*sd.Options)) (*sd.UpdateServiceOutput, error)
+
+	// ListTagsForResource provides ServiceDiscovery ListTagsForResource wrapper interface.
+	ListTagsForResource(context.Context, *sd.ListTagsForResourceInput, ...func(*sd.Options)) (*sd.ListTagsForResourceOutput, error)
+
+	// DeleteService provides ServiceDiscovery DeleteService wrapper interface.
+	DeleteService(context.Context, *sd.DeleteServiceInput, ...func(*sd.Options)) (*sd.DeleteServiceOutput, error)
+
+	// TagResource provides ServiceDiscovery TagResource wrapper interface.
+	TagResource(context.Context, *sd.TagResourceInput, ...func(*sd.Options)) (*sd.TagResourceOutput, error)
+
+	// UntagResource provides ServiceDiscovery UntagResource wrapper interface.
+	UntagResource(context.Context, *sd.UntagResourceInput, ...func(*sd.Options)) (*sd.UntagResourceOutput, error)
+
 	// RegisterInstance provides ServiceDiscovery RegisterInstance wrapper interface.
 	RegisterInstance(context.Context, *sd.RegisterInstanceInput, ...func(*sd.Options)) (*sd.RegisterInstanceOutput, error)
 
 	// DeregisterInstance provides ServiceDiscovery DeregisterInstance wrapper interface.
 	DeregisterInstance(context.Context, *sd.DeregisterInstanceInput, ...func(*sd.Options)) (*sd.DeregisterInstanceOutput, error)
 
+	// ListInstances provides ServiceDiscovery ListInstances wrapper interface for paginator.
+	ListInstances(context.Context, *sd.ListInstancesInput, ...func(*sd.Options)) (*sd.ListInstancesOutput, error)
+
 	// DiscoverInstances provides ServiceDiscovery DiscoverInstances wrapper interface.
 	DiscoverInstances(context.Context, *sd.DiscoverInstancesInput, ...func(*sd.Options)) (*sd.DiscoverInstancesOutput, error)
 }
@@ -41,7 +68,10 @@ type awsFacade struct {
 	*sd.Client
 }
 
-// NewAwsFacadeFromConfig creates a new AWS facade from an AWS client config.
+// NewAwsFacadeFromConfig creates a new AWS facade from an AWS client config. Throttled requests are reported to the
+// ThrottleTracker of the request context.
 func NewAwsFacadeFromConfig(cfg *aws.Config) AwsFacade {
-	return &awsFacade{sd.NewFromConfig(*cfg)}
+	return &awsFacade{sd.NewFromConfig(*cfg, func(options *sd.Options) {
+		options.Retryer = newThrottleObservingRetryer(options.Retryer)
+	})}
 }
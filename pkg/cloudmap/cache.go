@@ -2,10 +2,12 @@ package cloudmap
 
 import (
 	"errors"
+	"flag"
 	"fmt"
 	"github.com/aws/aws-cloud-map-mcs-controller-for-k8s/pkg/common"
 	"github.com/aws/aws-cloud-map-mcs-controller-for-k8s/pkg/model"
 	"k8s.io/apimachinery/pkg/util/cache"
+	"strings"
 	"time"
 )
 
@@ -13,6 +15,8 @@ const (
 	nsKeyPrefix    = "ns"
 	svcKeyPrefix   = "svc"
 	endptKeyPrefix = "endpt"
+	svcMetaPrefix  = "svcmeta"
+	svcDnsPrefix   = "svcdns"
 
 	defaultCacheSize = 1024
 	defaultNsTTL     = 2 * time.Minute
@@ -20,6 +24,8 @@ const (
 	defaultEndptTTL  = 5 * time.Second
 )
 
+// ServiceDiscoveryClientCache caches the Cloud Map namespaces, service IDs, endpoints and service metadata looked up by
+// the service discovery client, each kind with the time to live of SdCacheConfig.
 type ServiceDiscoveryClientCache interface {
 	GetNamespace(namespaceName string) (namespace *model.Namespace, found bool)
 	CacheNamespace(namespace *model.Namespace)
@@ -29,6 +35,20 @@ type ServiceDiscoveryClientCache interface {
 	GetEndpoints(namespaceName string, serviceName string) (endpoints []*model.Endpoint, found bool)
 	CacheEndpoints(namespaceName string, serviceName string, endpoints []*model.Endpoint)
 	EvictEndpoints(namespaceName string, serviceName string)
+	EvictService(namespaceName string, serviceName string)
+	GetServiceMetadata(namespaceName string, serviceName string) (metadata ServiceMetadata, found bool)
+	CacheServiceMetadata(namespaceName string, serviceName string, metadata ServiceMetadata)
+	GetServiceDnsTTL(namespaceName string, serviceName string) (ttl int64, found bool)
+	CacheServiceDnsTTL(namespaceName string, serviceName string, ttl int64)
+	Summary() CacheSummary
+}
+
+// CacheSummary counts the entries of the resource cache by kind. Expired entries are counted until they are evicted.
+type CacheSummary struct {
+	Namespaces      int `json:"namespaces"`
+	Services        int `json:"services"`
+	Endpoints       int `json:"endpoints"`
+	ServiceMetadata int `json:"serviceMetadata"`
 }
 
 type sdCache struct {
@@ -37,27 +57,45 @@ type sdCache struct {
 	config *SdCacheConfig
 }
 
+// SdCacheConfig holds the time to live of the cached namespaces, service IDs and endpoints.
 type SdCacheConfig struct {
 	NsTTL    time.Duration
 	SvcTTL   time.Duration
 	EndptTTL time.Duration
 }
 
+// NewServiceDiscoveryClientCache creates a resource cache with the given time to live settings.
 func NewServiceDiscoveryClientCache(cacheConfig *SdCacheConfig) ServiceDiscoveryClientCache {
+	return newServiceDiscoveryClientCache(common.NewLogger("cloudmap"), cacheConfig)
+}
+
+func newServiceDiscoveryClientCache(log common.Logger, cacheConfig *SdCacheConfig) *sdCache {
 	return &sdCache{
-		log:    common.NewLogger("cloudmap"),
+		log:    log,
 		cache:  cache.NewLRUExpireCache(defaultCacheSize),
 		config: cacheConfig,
 	}
 }
 
+// NewDefaultServiceDiscoveryClientCache creates a resource cache with the default time to live settings.
 func NewDefaultServiceDiscoveryClientCache() ServiceDiscoveryClientCache {
-	return NewServiceDiscoveryClientCache(
-		&SdCacheConfig{
-			NsTTL:    defaultNsTTL,
-			SvcTTL:   defaultSvcTTL,
-			EndptTTL: defaultEndptTTL,
-		})
+	return NewServiceDiscoveryClientCache(NewDefaultSdCacheConfig())
+}
+
+// NewDefaultSdCacheConfig returns the default time to live settings of the resource cache.
+func NewDefaultSdCacheConfig() *SdCacheConfig {
+	return &SdCacheConfig{
+		NsTTL:    defaultNsTTL,
+		SvcTTL:   defaultSvcTTL,
+		EndptTTL: defaultEndptTTL,
+	}
+}
+
+// BindFlags registers the cache settings as command line flags.
+func (c *SdCacheConfig) BindFlags(fs *flag.FlagSet) {
+	fs.DurationVar(&c.NsTTL, "namespace-cache-ttl", c.NsTTL, "Time to live of cached Cloud Map namespaces.")
+	fs.DurationVar(&c.SvcTTL, "service-cache-ttl", c.SvcTTL, "Time to live of cached Cloud Map services.")
+	fs.DurationVar(&c.EndptTTL, "endpoint-cache-ttl", c.EndptTTL, "Time to live of cached Cloud Map endpoints.")
 }
 
 func (sdCache *sdCache) GetNamespace(nsName string) (ns *model.Namespace, found bool) {
@@ -142,6 +180,82 @@ func (sdCache *sdCache) EvictEndpoints(nsName string, svcName string) {
 	sdCache.cache.Remove(key)
 }
 
+// EvictService removes the ID, endpoints and metadata of a deleted service.
+func (sdCache *sdCache) EvictService(nsName string, svcName string) {
+	sdCache.cache.Remove(sdCache.buildSvcKey(nsName, svcName))
+	sdCache.cache.Remove(sdCache.buildEndptsKey(nsName, svcName))
+	sdCache.cache.Remove(sdCache.buildSvcMetaKey(nsName, svcName))
+	sdCache.cache.Remove(sdCache.buildSvcDnsKey(nsName, svcName))
+}
+
+func (sdCache *sdCache) GetServiceMetadata(nsName string, svcName string) (metadata ServiceMetadata, found bool) {
+	key := sdCache.buildSvcMetaKey(nsName, svcName)
+	entry, exists := sdCache.cache.Get(key)
+	if !exists {
+		return ServiceMetadata{}, false
+	}
+
+	metadata, ok := entry.(ServiceMetadata)
+	if !ok {
+		sdCache.log.Error(errors.New("failed to retrieve service metadata from cache"), "",
+			"nsName", nsName, "svcName", svcName)
+		sdCache.cache.Remove(key)
+		return ServiceMetadata{}, false
+	}
+
+	return metadata, true
+}
+
+// CacheServiceMetadata caches the metadata last applied to a service, so unchanged metadata is not applied again.
+func (sdCache *sdCache) CacheServiceMetadata(nsName string, svcName string, metadata ServiceMetadata) {
+	key := sdCache.buildSvcMetaKey(nsName, svcName)
+	sdCache.cache.Add(key, metadata, sdCache.config.SvcTTL)
+}
+
+func (sdCache *sdCache) GetServiceDnsTTL(nsName string, svcName string) (ttl int64, found bool) {
+	key := sdCache.buildSvcDnsKey(nsName, svcName)
+	entry, exists := sdCache.cache.Get(key)
+	if !exists {
+		return 0, false
+	}
+
+	ttl, ok := entry.(int64)
+	if !ok {
+		sdCache.log.Error(errors.New("failed to retrieve service DNS TTL from cache"), "",
+			"nsName", nsName, "svcName", svcName)
+		sdCache.cache.Remove(key)
+		return 0, false
+	}
+
+	return ttl, true
+}
+
+// CacheServiceDnsTTL caches the DNS record TTL a service was last checked against, so the DNS configuration of the
+// service is only checked for drift once the entry expires or the desired TTL changes.
+func (sdCache *sdCache) CacheServiceDnsTTL(nsName string, svcName string, ttl int64) {
+	key := sdCache.buildSvcDnsKey(nsName, svcName)
+	sdCache.cache.Add(key, ttl, sdCache.config.SvcTTL)
+}
+
+// Summary counts the cached entries by the prefix of their key.
+func (sdCache *sdCache) Summary() CacheSummary {
+	summary := CacheSummary{}
+	for _, key := range sdCache.cache.Keys() {
+		prefix := strings.SplitN(fmt.Sprint(key), ":", 2)[0]
+		switch prefix {
+		case nsKeyPrefix:
+			summary.Namespaces++
+		case svcKeyPrefix:
+			summary.Services++
+		case endptKeyPrefix:
+			summary.Endpoints++
+		case svcMetaPrefix:
+			summary.ServiceMetadata++
+		}
+	}
+	return summary
+}
+
 func (sdCache *sdCache) buildNsKey(nsName string) (cacheKey string) {
 	return fmt.Sprintf("%s:%s", nsKeyPrefix, nsName)
 }
@@ -153,3 +267,11 @@ func (sdCache *sdCache) buildSvcKey(nsName string, svcName string) (cacheKey str
 func (sdCache *sdCache) buildEndptsKey(nsName string, svcName string) string {
 	return fmt.Sprintf("%s:%s:%s", endptKeyPrefix, nsName, svcName)
 }
+
+func (sdCache *sdCache) buildSvcMetaKey(nsName string, svcName string) string {
+	return fmt.Sprintf("%s:%s:%s", svcMetaPrefix, nsName, svcName)
+}
+
+func (sdCache *sdCache) buildSvcDnsKey(nsName string, svcName string) string {
+	return fmt.Sprintf("%s:%s:%s", svcDnsPrefix, nsName, svcName)
+}
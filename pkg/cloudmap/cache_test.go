@@ -124,3 +124,13 @@ func TestServiceDiscoveryClientEvictEndpoints(t *testing.T) {
 	assert.False(t, found)
 	assert.Nil(t, endpts)
 }
+
+func TestServiceDiscoveryClientCache_Summary(t *testing.T) {
+	sdc := NewDefaultServiceDiscoveryClientCache()
+	sdc.CacheNamespace(test.GetTestHttpNamespace())
+	sdc.CacheNilNamespace("other")
+	sdc.CacheServiceId(test.NsName, test.SvcName, test.SvcId)
+	sdc.CacheEndpoints(test.NsName, test.SvcName, []*model.Endpoint{test.GetTestEndpoint1()})
+
+	assert.Equal(t, CacheSummary{Namespaces: 2, Services: 1, Endpoints: 1}, sdc.Summary())
+}
@@ -0,0 +1,168 @@
+package cloudmap
+
+import (
+	"context"
+	"fmt"
+	"github.com/aws/aws-cloud-map-mcs-controller-for-k8s/pkg/common"
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/servicediscovery/types"
+	"time"
+)
+
+const (
+	// DefaultRevisionInterval is the default interval the revision change source lists Cloud Map operations
+	DefaultRevisionInterval = 10 * time.Second
+
+	// revisionOverlap is how far each listing of operations reaches back before the previous listing, so operations
+	// updated while a listing is in flight aren't missed
+	revisionOverlap = time.Minute
+)
+
+// ChangeSource notifies about changed Cloud Map services, so their imports are refreshed without waiting for the next
+// sync. A change source only speeds up the import, changes it misses are imported by the periodic sync.
+type ChangeSource interface {
+	// Start calls notify with the ID of each changed service until the context is done. notify doesn't block.
+	Start(ctx context.Context, notify func(serviceId string)) error
+}
+
+// ChangeSourceType selects the ChangeSource of the controller.
+type ChangeSourceType string
+
+const (
+	// ChangeSourcePoll doesn't notify about changes, changes are imported by the periodic sync
+	ChangeSourcePoll ChangeSourceType = "poll"
+	// ChangeSourceRevision lists the Cloud Map operations which succeeded since the previous listing, and notifies
+	// about the services of registered and de-registered instances
+	ChangeSourceRevision ChangeSourceType = "revision"
+	// ChangeSourceEvents receives the CloudTrail records of Cloud Map API calls, which an EventBridge rule delivers to
+	// an SQS queue
+	ChangeSourceEvents ChangeSourceType = "events"
+)
+
+// Validate returns an error if the change source type is unknown.
+func (t ChangeSourceType) Validate() error {
+	switch t {
+	case ChangeSourcePoll, ChangeSourceRevision, ChangeSourceEvents:
+		return nil
+	}
+	return fmt.Errorf("unknown change source %q, expected one of %s, %s, %s",
+		t, ChangeSourcePoll, ChangeSourceRevision, ChangeSourceEvents)
+}
+
+type pollChangeSource struct{}
+
+// NewPollChangeSource creates a change source which never notifies, leaving all changes to the periodic sync.
+func NewPollChangeSource() ChangeSource {
+	return pollChangeSource{}
+}
+
+func (pollChangeSource) Start(ctx context.Context, _ func(serviceId string)) error {
+	<-ctx.Done()
+	return nil
+}
+
+type revisionChangeSource struct {
+	log      common.Logger
+	sdApi    ServiceDiscoveryApi
+	interval time.Duration
+
+	// revision is the UPDATE_DATE in milliseconds up to which operations were listed
+	revision int64
+	// seen maps the IDs of the operations listed since the revision minus the overlap to their UPDATE_DATE, so
+	// operations listed again by the overlapping listing don't notify twice
+	seen map[string]int64
+}
+
+// NewRevisionChangeSource creates a change source which lists the succeeded Cloud Map operations at the given
+// interval, DefaultRevisionInterval is used if not positive. Operations are listed from the time the source starts,
+// earlier changes are imported by the first sync.
+func NewRevisionChangeSource(sdApi ServiceDiscoveryApi, interval time.Duration) ChangeSource {
+	if interval <= 0 {
+		interval = DefaultRevisionInterval
+	}
+	return &revisionChangeSource{
+		log:      common.NewLogger("cloudmap", "revisions"),
+		sdApi:    sdApi,
+		interval: interval,
+		seen:     make(map[string]int64),
+	}
+}
+
+func (s *revisionChangeSource) Start(ctx context.Context, notify func(serviceId string)) error {
+	s.revision = Now()
+	ticker := time.NewTicker(s.interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			if err := s.poll(ctx, notify); err != nil && ctx.Err() == nil {
+				// the next poll lists the operations again
+				logAwsError(s.log, err, "error listing Cloud Map operations")
+			}
+		case <-ctx.Done():
+			return nil
+		}
+	}
+}
+
+// poll notifies about the services of the operations which succeeded since the revision, and advances the revision.
+func (s *revisionChangeSource) poll(ctx context.Context, notify func(serviceId string)) error {
+	start, end := s.revision-revisionOverlap.Milliseconds(), Now()
+	ops, err := s.sdApi.ListOperations(ctx, []types.OperationFilter{
+		{
+			Name:      types.OperationFilterNameStatus,
+			Condition: types.FilterConditionEq,
+			Values:    []string{string(types.OperationStatusSuccess)},
+		},
+		{
+			Name:      types.OperationFilterNameType,
+			Condition: types.FilterConditionIn,
+			Values: []string{
+				string(types.OperationTypeRegisterInstance),
+				string(types.OperationTypeDeregisterInstance)},
+		},
+		{
+			Name:      types.OperationFilterNameUpdateDate,
+			Condition: types.FilterConditionBetween,
+			Values:    []string{itoa(start), itoa(end)},
+		},
+	})
+	if err != nil {
+		return err
+	}
+
+	notified := make(map[string]bool)
+	for opId := range ops {
+		if _, found := s.seen[opId]; found {
+			continue
+		}
+		// the operations are cached once terminal, so each operation is fetched once
+		op, err := s.sdApi.GetOperation(ctx, opId)
+		if err != nil {
+			return err
+		}
+		s.seen[opId] = updateDate(op, end)
+
+		svcId := op.Targets[string(types.OperationTargetTypeService)]
+		if svcId != "" && !notified[svcId] {
+			notify(svcId)
+			notified[svcId] = true
+		}
+	}
+
+	s.revision = end
+	for opId, updated := range s.seen {
+		if updated < end-revisionOverlap.Milliseconds() {
+			delete(s.seen, opId)
+		}
+	}
+	return nil
+}
+
+// updateDate returns the UPDATE_DATE of the operation in milliseconds, or the fallback if the operation has none.
+func updateDate(op *types.Operation, fallback int64) int64 {
+	if op.UpdateDate == nil {
+		return fallback
+	}
+	return aws.ToTime(op.UpdateDate).UnixNano() / int64(time.Millisecond)
+}
@@ -0,0 +1,87 @@
+package cloudmap
+
+import (
+	"context"
+	"errors"
+	"github.com/aws/aws-cloud-map-mcs-controller-for-k8s/mocks/pkg/cloudmap"
+	"github.com/aws/aws-cloud-map-mcs-controller-for-k8s/test"
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/servicediscovery/types"
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/assert"
+	"testing"
+	"time"
+)
+
+func TestChangeSourceType_Validate(t *testing.T) {
+	for _, sourceType := range []ChangeSourceType{ChangeSourcePoll, ChangeSourceRevision, ChangeSourceEvents} {
+		assert.NoError(t, sourceType.Validate())
+	}
+	assert.Error(t, ChangeSourceType("").Validate())
+	assert.Error(t, ChangeSourceType("webhook").Validate())
+}
+
+func TestPollChangeSource(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.TODO())
+	cancel()
+	assert.NoError(t, NewPollChangeSource().Start(ctx, func(string) {
+		t.Fatal("poll change source notified")
+	}))
+}
+
+func TestRevisionChangeSource_Poll(t *testing.T) {
+	mockController := gomock.NewController(t)
+	defer mockController.Finish()
+
+	sdApi := cloudmap.NewMockServiceDiscoveryApi(mockController)
+	source := NewRevisionChangeSource(sdApi, 0).(*revisionChangeSource)
+	source.revision = Now()
+	revision := source.revision
+
+	sdApi.EXPECT().ListOperations(gomock.Any(), gomock.Any()).
+		DoAndReturn(func(ctx context.Context, filters []types.OperationFilter) (map[string]types.OperationStatus, error) {
+			assert.Equal(t, itoa(revision-revisionOverlap.Milliseconds()), filters[2].Values[0],
+				"operations are listed from before the revision")
+			return map[string]types.OperationStatus{
+				test.OpId1: types.OperationStatusSuccess,
+				test.OpId2: types.OperationStatusSuccess,
+			}, nil
+		})
+	for _, opId := range []string{test.OpId1, test.OpId2} {
+		sdApi.EXPECT().GetOperation(gomock.Any(), opId).Return(&types.Operation{
+			Id:         aws.String(opId),
+			UpdateDate: aws.Time(time.Now()),
+			Targets:    map[string]string{string(types.OperationTargetTypeService): test.SvcId},
+		}, nil)
+	}
+
+	notified := make([]string, 0)
+	notify := func(svcId string) {
+		notified = append(notified, svcId)
+	}
+	assert.NoError(t, source.poll(context.TODO(), notify))
+	assert.Equal(t, []string{test.SvcId}, notified, "services are notified once per poll")
+	assert.GreaterOrEqual(t, source.revision, revision)
+
+	// the overlapping poll lists the operations again, which don't notify twice
+	sdApi.EXPECT().ListOperations(gomock.Any(), gomock.Any()).Return(map[string]types.OperationStatus{
+		test.OpId1: types.OperationStatusSuccess,
+		test.OpId2: types.OperationStatusSuccess,
+	}, nil)
+	assert.NoError(t, source.poll(context.TODO(), notify))
+	assert.Len(t, notified, 1)
+}
+
+func TestRevisionChangeSource_PollError(t *testing.T) {
+	mockController := gomock.NewController(t)
+	defer mockController.Finish()
+
+	sdApi := cloudmap.NewMockServiceDiscoveryApi(mockController)
+	source := NewRevisionChangeSource(sdApi, time.Second).(*revisionChangeSource)
+	source.revision = Now()
+	revision := source.revision
+
+	sdApi.EXPECT().ListOperations(gomock.Any(), gomock.Any()).Return(nil, errors.New("throttled"))
+	assert.Error(t, source.poll(context.TODO(), func(string) {}))
+	assert.Equal(t, revision, source.revision, "the revision doesn't advance")
+}
@@ -2,14 +2,41 @@ package cloudmap
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"github.com/aws/aws-cloud-map-mcs-controller-for-k8s/pkg/common"
+	"github.com/aws/aws-cloud-map-mcs-controller-for-k8s/pkg/metrics"
 	"github.com/aws/aws-cloud-map-mcs-controller-for-k8s/pkg/model"
+	"github.com/aws/aws-cloud-map-mcs-controller-for-k8s/pkg/registry"
 	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/servicediscovery/types"
+	"github.com/go-logr/logr"
+	utilerrors "k8s.io/apimachinery/pkg/util/errors"
+	"k8s.io/client-go/util/workqueue"
+	"sync"
+	"time"
+)
+
+const (
+	// DefaultDeregisterConcurrency is the default number of DeregisterInstance calls in flight per service
+	DefaultDeregisterConcurrency = 20
+	// DefaultRegisterConcurrency is the default number of RegisterInstance calls in flight per service
+	DefaultRegisterConcurrency = 20
+	// DefaultSyncChunkSize is the default number of endpoints registered or de-registered per chunk
+	DefaultSyncChunkSize = 500
+
+	// maxLoggedEndpoints is the number of endpoints above which only the endpoint count is logged
+	maxLoggedEndpoints = 20
+
+	// EmptySinceTag holds the time a Cloud Map service or namespace became empty, see
+	// ServiceDiscoveryClient.MarkServiceEmpty and ServiceDiscoveryClient.DeleteEmptyNamespaces
+	EmptySinceTag = "multicluster.k8s.aws/empty-since"
 )
 
 // ServiceDiscoveryClient provides the service endpoint management functionality required by the AWS Cloud Map
 // multi-cluster service discovery for Kubernetes controller. It maintains local caches for all AWS Cloud Map resources.
+// ServiceDiscoveryClient is the Cloud Map implementation of registry.ServiceRegistry, including all optional
+// operations.
 type ServiceDiscoveryClient interface {
 	// ListServices returns all services and their endpoints for a given namespace.
 	ListServices(ctx context.Context, namespaceName string) ([]*model.Service, error)
@@ -20,34 +47,197 @@ type ServiceDiscoveryClient interface {
 	// GetService returns a service resource fetched from AWS Cloud Map or nil if not found.
 	GetService(ctx context.Context, namespaceName string, serviceName string) (*model.Service, error)
 
+	// UpdateServiceMetadata updates the description and adds the tags of a service, unless the metadata is unchanged
+	// since it was last applied. Tags removed from the metadata are kept on the service.
+	UpdateServiceMetadata(ctx context.Context, namespaceName string, serviceName string, metadata ServiceMetadata) error
+
+	// CorrectDnsConfig updates the TTL of the DNS records of a service in a DNS namespace which differs from the given
+	// TTL, and returns the differences of its DNS configuration from the configuration of created services. The DNS
+	// configuration is only checked again once the TTL changes or the service cache entry expires.
+	CorrectDnsConfig(ctx context.Context, namespaceName string, serviceName string, ttl int64) (model.DnsConfigDrift, error)
+
 	// RegisterEndpoints registers all endpoints for given service.
 	RegisterEndpoints(ctx context.Context, namespaceName string, serviceName string, endpoints []*model.Endpoint) error
 
-	// DeleteEndpoints de-registers all endpoints for given service.
+	// DeleteEndpoints de-registers all endpoints for given service, with a bounded number of concurrent calls.
 	DeleteEndpoints(ctx context.Context, namespaceName string, serviceName string, endpoints []*model.Endpoint) error
+
+	// DeleteService deletes a Cloud Map service, which fails if the service has instances. Deleting a service which
+	// doesn't exist does nothing.
+	DeleteService(ctx context.Context, namespaceName string, serviceName string) error
+
+	// MarkServiceEmpty tags a Cloud Map service with the time it became empty, or removes the tag if not empty.
+	// Services which are already marked accordingly are left unchanged.
+	MarkServiceEmpty(ctx context.Context, namespaceName string, serviceName string, empty bool) error
+
+	// DeleteEmptyNamespaces deletes the Cloud Map namespaces created by the cluster which have had no services for the
+	// grace period, and returns their names. Empty namespaces are tagged with the time they became empty, the tag is
+	// removed once they have services again. Namespaces of other clusters and untagged namespaces are left alone.
+	DeleteEmptyNamespaces(ctx context.Context, gracePeriod time.Duration) ([]string, error)
+
+	// EvictEndpoints drops the cached endpoints of a service, so they are fetched from Cloud Map on the next lookup.
+	EvictEndpoints(namespaceName string, serviceName string)
+
+	// WarmUp populates the cache with the namespaces, service IDs and endpoints of all Cloud Map namespaces in a
+	// single enumeration pass, listing the services of up to concurrency namespaces at a time. The namespaces which
+	// can't be listed are skipped, their errors are aggregated.
+	WarmUp(ctx context.Context, concurrency int) (CacheSummary, error)
+
+	// State returns the pending operations and a summary of the resource cache of the client, for debugging.
+	State() ClientState
 }
 
+var (
+	_ registry.ServiceRegistry    = ServiceDiscoveryClient(nil)
+	_ registry.MetadataUpdater    = ServiceDiscoveryClient(nil)
+	_ registry.EmptyServiceMarker = ServiceDiscoveryClient(nil)
+	_ registry.DnsConfigCorrector = ServiceDiscoveryClient(nil)
+	_ registry.NamespaceCollector = ServiceDiscoveryClient(nil)
+	_ registry.EndpointEvicter    = ServiceDiscoveryClient(nil)
+)
+
 type serviceDiscoveryClient struct {
-	log   common.Logger
-	sdApi ServiceDiscoveryApi
-	cache ServiceDiscoveryClientCache
+	log        common.Logger
+	sdApi      ServiceDiscoveryApi
+	cache      ServiceDiscoveryClientCache
+	audit      AuditLogger
+	timeouts   *SdTimeoutConfig
+	tags       map[string]string
+	operations *operationTracker
+	// attributeHashes skips the registration of unchanged instances, all instances are registered if nil
+	attributeHashes *attributeHashes
+
+	deregisterConcurrency int
+	registerConcurrency   int
+	syncChunkSize         int
+	discoverMaxResults    int
+	instancePaging        InstancePaging
+}
+
+// SdClientConfig holds the optional settings of the service discovery client.
+type SdClientConfig struct {
+	// Log receives the log of the client and of the parts it creates: the Cloud Map API, the resource cache, the
+	// operation collectors and pollers, and the fault injection. The controller-runtime log is used if nil. Parts
+	// created on their own, e.g. with NewServiceDiscoveryApiFromConfig or NewRevisionChangeSource, log to the
+	// controller-runtime log.
+	Log logr.Logger
+
+	// Cache configures the resource cache, the default cache settings are used if nil.
+	Cache *SdCacheConfig
+
+	// AuditLogger records all Cloud Map mutations, mutations are not audited if nil.
+	AuditLogger AuditLogger
+
+	// Timeouts configures operation polling, the default poll interval and timeout are used if nil. API call
+	// timeouts are part of the AWS client config, see SdTimeoutConfig.Apply.
+	Timeouts *SdTimeoutConfig
+
+	// ClusterId and ClusterSetId identify the cluster in the ownership tags of created namespaces and services.
+	ClusterId    string
+	ClusterSetId string
+
+	// Tags are added to created namespaces and services along with the ownership tags, see ValidateResourceTags.
+	Tags map[string]string
+
+	// AwsFacade replaces the AWS SDK client created from the AWS client config, e.g. with the in-memory Cloud Map of
+	// the cloudmaptest package.
+	AwsFacade AwsFacade
+
+	// Faults are injected into the Cloud Map API calls if enabled, see FaultConfig. For testing only.
+	Faults *FaultConfig
+
+	// DeregisterConcurrency bounds the DeregisterInstance calls in flight per DeleteEndpoints call,
+	// DefaultDeregisterConcurrency is used if not positive.
+	DeregisterConcurrency int
+
+	// RegisterConcurrency bounds the RegisterInstance calls in flight per RegisterEndpoints call,
+	// DefaultRegisterConcurrency is used if not positive.
+	RegisterConcurrency int
+
+	// SyncChunkSize is the number of endpoints registered or de-registered before polling their operations, see
+	// WithSyncProgress. DefaultSyncChunkSize is used if not positive.
+	SyncChunkSize int
+
+	// DiscoverMaxResults is the MaxResults of DiscoverInstances requests, see ValidateDiscoverMaxResults.
+	// MaxDiscoverMaxResults is used if not positive.
+	DiscoverMaxResults int
+
+	// InstancePaging selects how the instances of services are listed, InstancePagingAuto is used if empty.
+	InstancePaging InstancePaging
 }
 
 // NewDefaultServiceDiscoveryClient creates a new service discovery client for AWS Cloud Map with default resource cache
 // from a given AWS client config.
 func NewDefaultServiceDiscoveryClient(cfg *aws.Config) ServiceDiscoveryClient {
-	return &serviceDiscoveryClient{
-		log:   common.NewLogger("cloudmap"),
-		sdApi: NewServiceDiscoveryApiFromConfig(cfg),
-		cache: NewDefaultServiceDiscoveryClientCache(),
-	}
+	return NewServiceDiscoveryClient(cfg, &SdClientConfig{})
 }
 
+// NewServiceDiscoveryClientWithCustomCache creates a new service discovery client for AWS Cloud Map with the given
+// resource cache settings.
+//
+// Deprecated: use NewServiceDiscoveryClient with SdClientConfig.Cache.
 func NewServiceDiscoveryClientWithCustomCache(cfg *aws.Config, cacheConfig *SdCacheConfig) ServiceDiscoveryClient {
+	return NewServiceDiscoveryClient(cfg, &SdClientConfig{Cache: cacheConfig})
+}
+
+// NewServiceDiscoveryClient creates a new service discovery client for AWS Cloud Map from a given AWS client config
+// and client settings.
+func NewServiceDiscoveryClient(cfg *aws.Config, clientConfig *SdClientConfig) ServiceDiscoveryClient {
+	log := common.NewLogger("cloudmap")
+	if clientConfig.Log != nil {
+		log = common.NewLoggerWithLogr(clientConfig.Log)
+	}
+	cacheConfig := clientConfig.Cache
+	if cacheConfig == nil {
+		cacheConfig = NewDefaultSdCacheConfig()
+	}
+	cache := newServiceDiscoveryClientCache(log, cacheConfig)
+
+	tags := ResourceTags(clientConfig.Tags, clientConfig.ClusterId, clientConfig.ClusterSetId)
+	awsFacade := clientConfig.AwsFacade
+	if awsFacade == nil {
+		awsFacade = NewAwsFacadeFromConfig(cfg)
+	}
+	discoverMaxResults := clientConfig.DiscoverMaxResults
+	if discoverMaxResults <= 0 || discoverMaxResults > MaxDiscoverMaxResults {
+		discoverMaxResults = MaxDiscoverMaxResults
+	}
+	instancePaging := clientConfig.InstancePaging
+	if instancePaging == "" {
+		instancePaging = InstancePagingAuto
+	}
+	api := newServiceDiscoveryApi(log, awsFacade, clientConfig.Timeouts, tags)
+	api.discoverMaxResults = discoverMaxResults
+	var sdApi ServiceDiscoveryApi = api
+	if clientConfig.Faults.Enabled() {
+		sdApi = newFaultInjectingApi(log, sdApi, *clientConfig.Faults)
+	}
+	deregisterConcurrency := clientConfig.DeregisterConcurrency
+	if deregisterConcurrency <= 0 {
+		deregisterConcurrency = DefaultDeregisterConcurrency
+	}
+	registerConcurrency := clientConfig.RegisterConcurrency
+	if registerConcurrency <= 0 {
+		registerConcurrency = DefaultRegisterConcurrency
+	}
+	syncChunkSize := clientConfig.SyncChunkSize
+	if syncChunkSize <= 0 {
+		syncChunkSize = DefaultSyncChunkSize
+	}
 	return &serviceDiscoveryClient{
-		log:   common.NewLogger("cloudmap"),
-		sdApi: NewServiceDiscoveryApiFromConfig(cfg),
-		cache: NewServiceDiscoveryClientCache(cacheConfig),
+		log:                   log,
+		sdApi:                 sdApi,
+		cache:                 cache,
+		audit:                 clientConfig.AuditLogger,
+		timeouts:              clientConfig.Timeouts,
+		tags:                  tags,
+		operations:            newOperationTracker(),
+		attributeHashes:       newAttributeHashes(),
+		deregisterConcurrency: deregisterConcurrency,
+		registerConcurrency:   registerConcurrency,
+		syncChunkSize:         syncChunkSize,
+		discoverMaxResults:    discoverMaxResults,
+		instancePaging:        instancePaging,
 	}
 }
 
@@ -60,6 +250,7 @@ func (sdc *serviceDiscoveryClient) ListServices(ctx context.Context, nsName stri
 	// TODO: Cache list
 	svcSums, err := sdc.sdApi.ListServices(ctx, namespace.Id)
 	if err != nil {
+		logAwsError(sdc.log, err, "failed to list services", "namespaceName", nsName, "namespaceId", namespace.Id)
 		return svcs, err
 	}
 
@@ -72,6 +263,7 @@ func (sdc *serviceDiscoveryClient) ListServices(ctx context.Context, nsName stri
 		}
 
 		svcs = append(svcs, &model.Service{
+			Id:        svcSum.Id,
 			Namespace: nsName,
 			Name:      svcSum.Name,
 			Endpoints: endpts,
@@ -98,11 +290,22 @@ func (sdc *serviceDiscoveryClient) CreateService(ctx context.Context, nsName str
 	}
 
 	svcId, err := sdc.sdApi.CreateService(ctx, *namespace, svcName)
+	sdc.recordAudit(ctx, AuditRecord{
+		Action:    AuditActionCreateService,
+		Namespace: nsName,
+		Service:   svcName,
+		ServiceId: svcId,
+		After:     map[string]string{"namespaceId": namespace.Id, "namespaceType": string(namespace.Type)},
+		Error:     errorString(err),
+	})
 	if err != nil {
+		logAwsError(sdc.log, err, "failed to create service",
+			"namespaceName", nsName, "namespaceId", namespace.Id, "serviceName", svcName)
 		return err
 	}
 
 	sdc.cache.CacheServiceId(nsName, svcName, svcId)
+	sdc.cache.CacheServiceMetadata(nsName, svcName, ServiceMetadataFromContext(ctx))
 
 	return nil
 }
@@ -112,7 +315,9 @@ func (sdc *serviceDiscoveryClient) GetService(ctx context.Context, nsName string
 	endpts, cacheHit := sdc.cache.GetEndpoints(nsName, svcName)
 
 	if cacheHit {
+		svcId, _ := sdc.cache.GetServiceId(nsName, svcName)
 		return &model.Service{
+			Id:        svcId,
 			Namespace: nsName,
 			Name:      svcName,
 			Endpoints: endpts,
@@ -136,48 +341,285 @@ func (sdc *serviceDiscoveryClient) GetService(ctx context.Context, nsName string
 	}
 
 	return &model.Service{
+		Id:        svcId,
 		Namespace: nsName,
 		Name:      svcName,
 		Endpoints: endpts,
 	}, nil
 }
 
+func (sdc *serviceDiscoveryClient) UpdateServiceMetadata(ctx context.Context, nsName string, svcName string, metadata ServiceMetadata) (err error) {
+	if applied, found := sdc.cache.GetServiceMetadata(nsName, svcName); found && applied.Equals(metadata) {
+		return nil
+	}
+
+	svcId, err := sdc.getServiceId(ctx, nsName, svcName)
+	if err != nil || svcId == "" {
+		return err
+	}
+
+	svc, err := sdc.sdApi.GetService(ctx, svcId)
+	if err != nil {
+		logAwsError(sdc.log, err, "failed to get service", "namespaceName", nsName, "serviceName", svcName)
+		return err
+	}
+
+	currentDescription := aws.ToString(svc.Description)
+	if metadata.Description != "" && metadata.Description != currentDescription {
+		sdc.log.Info("updating service description", "namespaceName", nsName, "serviceName", svcName)
+		opId, err := sdc.sdApi.UpdateServiceDescription(ctx, svc, metadata.Description)
+		sdc.recordAudit(ctx, AuditRecord{
+			Action:      AuditActionUpdateService,
+			Namespace:   nsName,
+			Service:     svcName,
+			ServiceId:   svcId,
+			OperationId: opId,
+			Before:      map[string]string{"description": currentDescription},
+			After:       map[string]string{"description": metadata.Description},
+			Error:       errorString(err),
+		})
+		if err != nil {
+			logAwsError(sdc.log, err, "failed to update service description",
+				"namespaceName", nsName, "serviceName", svcName, "serviceId", svcId)
+			return err
+		}
+	}
+
+	if err = sdc.tagService(ctx, nsName, svcName, svc, metadata.Tags); err != nil {
+		return err
+	}
+
+	sdc.cache.CacheServiceMetadata(nsName, svcName, metadata)
+	return nil
+}
+
+func (sdc *serviceDiscoveryClient) CorrectDnsConfig(ctx context.Context, nsName string, svcName string, ttl int64) (drift model.DnsConfigDrift, err error) {
+	if checkedTTL, found := sdc.cache.GetServiceDnsTTL(nsName, svcName); found && checkedTTL == ttl {
+		return drift, nil
+	}
+
+	svcId, err := sdc.getServiceId(ctx, nsName, svcName)
+	if err != nil || svcId == "" {
+		return drift, err
+	}
+
+	svc, err := sdc.sdApi.GetService(ctx, svcId)
+	if err != nil {
+		logAwsError(sdc.log, err, "failed to get service", "namespaceName", nsName, "serviceName", svcName)
+		return drift, err
+	}
+
+	// services of HTTP namespaces have no DNS configuration
+	if svc.DnsConfig != nil {
+		var records []types.DnsRecord
+		drift, records = dnsConfigDrift(svc.DnsConfig, ttl)
+		if len(drift.Corrected) > 0 {
+			sdc.log.Info("correcting service DNS configuration", "namespaceName", nsName, "serviceName", svcName,
+				"corrections", drift.Corrected)
+			opId, err := sdc.sdApi.UpdateServiceDnsRecords(ctx, svc, records)
+			sdc.recordAudit(ctx, AuditRecord{
+				Action:      AuditActionUpdateService,
+				Namespace:   nsName,
+				Service:     svcName,
+				ServiceId:   svcId,
+				OperationId: opId,
+				Before:      dnsRecordTTLs(svc.DnsConfig.DnsRecords),
+				After:       dnsRecordTTLs(records),
+				Error:       errorString(err),
+			})
+			if err != nil {
+				logAwsError(sdc.log, err, "failed to update service DNS configuration",
+					"namespaceName", nsName, "serviceName", svcName, "serviceId", svcId)
+				return model.DnsConfigDrift{}, err
+			}
+		}
+	}
+
+	sdc.cache.CacheServiceDnsTTL(nsName, svcName, ttl)
+	return drift, nil
+}
+
+// dnsConfigDrift compares the DNS configuration of a service to the configuration of created services with the given
+// TTL, and returns the differences along with the corrected DNS records. Cloud Map only updates the TTL of the records,
+// other differences are uncorrectable.
+func dnsConfigDrift(dnsConfig *types.DnsConfig, ttl int64) (drift model.DnsConfigDrift, records []types.DnsRecord) {
+	if dnsConfig.RoutingPolicy != "" && dnsConfig.RoutingPolicy != types.RoutingPolicyMultivalue {
+		drift.Uncorrectable = append(drift.Uncorrectable, fmt.Sprintf("routing policy %s instead of %s",
+			dnsConfig.RoutingPolicy, types.RoutingPolicyMultivalue))
+	}
+
+	records = make([]types.DnsRecord, 0, len(dnsConfig.DnsRecords))
+	hasSrv := false
+	for _, record := range dnsConfig.DnsRecords {
+		if record.Type == types.RecordTypeSrv {
+			hasSrv = true
+		} else {
+			drift.Uncorrectable = append(drift.Uncorrectable, fmt.Sprintf("unexpected %s record", record.Type))
+		}
+		if current := aws.ToInt64(record.TTL); current != ttl {
+			drift.Corrected = append(drift.Corrected, fmt.Sprintf("%s record TTL %d -> %d", record.Type, current, ttl))
+		}
+		records = append(records, types.DnsRecord{Type: record.Type, TTL: aws.Int64(ttl)})
+	}
+	if !hasSrv {
+		drift.Uncorrectable = append(drift.Uncorrectable, "missing SRV record")
+	}
+	return drift, records
+}
+
+// dnsRecordTTLs returns the TTL of the DNS records by record type, for the audit log.
+func dnsRecordTTLs(records []types.DnsRecord) map[string]string {
+	ttls := make(map[string]string, len(records))
+	for _, record := range records {
+		ttls[string(record.Type)+" TTL"] = fmt.Sprint(aws.ToInt64(record.TTL))
+	}
+	return ttls
+}
+
+// tagService adds the tags which are missing or differ on the service. Configured resource tags and ownership tags
+// are never changed. No tag is added if the tags of the service would exceed the Cloud Map limit, the returned error
+// wraps ErrTooManyTags.
+func (sdc *serviceDiscoveryClient) tagService(ctx context.Context, nsName string, svcName string, svc *types.Service, tags map[string]string) error {
+	if len(tags) == 0 {
+		return nil
+	}
+
+	svcArn := aws.ToString(svc.Arn)
+	current, err := sdc.sdApi.ListTagsForResource(ctx, svcArn)
+	if err != nil {
+		logAwsError(sdc.log, err, "failed to list service tags", "namespaceName", nsName, "serviceName", svcName)
+		return err
+	}
+
+	changed := make(map[string]string)
+	for key, value := range tags {
+		if _, configured := sdc.tags[key]; configured {
+			continue
+		}
+		if currentValue, exists := current[key]; !exists || currentValue != value {
+			changed[key] = value
+		}
+	}
+	if len(changed) == 0 {
+		return nil
+	}
+	if err = validateTagCount(changed, current, 0); err != nil {
+		sdc.log.Info("skipping service tags", "namespaceName", nsName, "serviceName", svcName, "reason", err.Error())
+		return err
+	}
+
+	sdc.log.Info("tagging service", "namespaceName", nsName, "serviceName", svcName, "tags", changed)
+	err = sdc.sdApi.TagResource(ctx, svcArn, changed)
+	sdc.recordAudit(ctx, AuditRecord{
+		Action:    AuditActionTagResource,
+		Namespace: nsName,
+		Service:   svcName,
+		ServiceId: aws.ToString(svc.Id),
+		After:     changed,
+		Error:     errorString(err),
+	})
+	if err != nil {
+		logAwsError(sdc.log, err, "failed to tag service", "namespaceName", nsName, "serviceName", svcName)
+	}
+	return err
+}
+
 func (sdc *serviceDiscoveryClient) RegisterEndpoints(ctx context.Context, nsName string, svcName string, endpts []*model.Endpoint) (err error) {
 	if len(endpts) == 0 {
 		sdc.log.Info("skipping endpoint registration for empty endpoint list", "serviceName", svcName)
 		return nil
 	}
 
-	sdc.log.Info("registering endpoints", "namespaceName", nsName, "serviceName", svcName, "endpoints", endpts)
+	changed := make([]*model.Endpoint, 0, len(endpts))
+	for _, endpt := range endpts {
+		if !sdc.attributeHashes.unchanged(nsName, svcName, endpt.Id, endpt.GetCloudMapAttributes()) {
+			changed = append(changed, endpt)
+		}
+	}
+	if skipped := len(endpts) - len(changed); skipped > 0 {
+		sdc.log.Info("skipping registration of unchanged endpoints", "namespaceName", nsName,
+			"serviceName", svcName, "skipped", skipped)
+		if len(changed) == 0 {
+			return nil
+		}
+	}
+
+	sdc.log.Info("registering endpoints", "namespaceName", nsName, "serviceName", svcName,
+		"endpoints", endpointsForLog(changed))
 
 	svcId, err := sdc.getServiceId(ctx, nsName, svcName)
 	if err != nil {
 		return err
 	}
 
-	opCollector := NewOperationCollector()
+	currentAttrs := sdc.getAuditedEndpointAttributes(nsName, svcName)
+	return sdc.inChunks(ctx, AuditActionRegisterInstance, changed, func(chunk []*model.Endpoint) error {
+		return sdc.registerChunk(ctx, nsName, svcName, svcId, chunk, currentAttrs)
+	})
+}
+
+// registerChunk registers a chunk of endpoints and polls the operations until they complete.
+func (sdc *serviceDiscoveryClient) registerChunk(ctx context.Context, nsName string, svcName string, svcId string, endpts []*model.Endpoint, currentAttrs map[string]map[string]string) (err error) {
+	opCollector := newOperationCollector(sdc.log, sdc.registerConcurrency)
+	registered := make(map[string]map[string]string, len(endpts))
+	var failuresMu sync.Mutex
+	failures := make([]InstanceFailure, 0)
 
 	for _, endpt := range endpts {
 		endptId := endpt.Id
 		endptAttrs := endpt.GetCloudMapAttributes()
+		registered[endptId] = endptAttrs
 		opCollector.Add(func() (opId string, err error) {
-			return sdc.sdApi.RegisterInstance(ctx, svcId, endptId, endptAttrs)
+			opId, err = sdc.sdApi.RegisterInstance(ctx, svcId, endptId, endptAttrs)
+			sdc.recordAudit(ctx, AuditRecord{
+				Action:      AuditActionRegisterInstance,
+				Namespace:   nsName,
+				Service:     svcName,
+				ServiceId:   svcId,
+				InstanceId:  endptId,
+				OperationId: opId,
+				Before:      currentAttrs[endptId],
+				After:       endptAttrs,
+				Error:       errorString(err),
+			})
+			if err != nil {
+				failuresMu.Lock()
+				failures = append(failures, InstanceFailure{InstanceId: endptId, Err: err})
+				failuresMu.Unlock()
+			}
+			return opId, err
 		})
 	}
 
-	err = NewRegisterInstancePoller(sdc.sdApi, svcId, opCollector.Collect(), opCollector.GetStartTime()).Poll(ctx)
+	timer := metrics.PhaseTimerFromContext(ctx)
+	stopRegister := timer.Start(metrics.PhaseRegister)
+	opIds := opCollector.Collect()
+	stopRegister()
+
+	stopPoll := timer.Start(metrics.PhasePoll)
+	done := sdc.operations.start(PendingOperation{Action: AuditActionRegisterInstance, Namespace: nsName,
+		Service: svcName, ServiceId: svcId, OperationIds: opIds})
+	err = newOperationPoller(sdc.log, sdc.sdApi, types.OperationTypeRegisterInstance, svcId, opIds,
+		opCollector.GetStartTime(), sdc.timeouts).Poll(ctx)
+	done()
+	stopPoll()
 
 	// Evict cache entry so next list call reflects changes
 	sdc.cache.EvictEndpoints(nsName, svcName)
 
 	if err != nil {
+		logAwsError(sdc.log, err, "failed to register endpoints",
+			"namespaceName", nsName, "serviceName", svcName, "serviceId", svcId)
 		return err
 	}
 
 	if !opCollector.IsAllOperationsCreated() {
-		return fmt.Errorf("failure while registering endpoints")
+		return &InstanceFailureError{OperationType: types.OperationTypeRegisterInstance, Failures: failures}
 	}
 
+	for endptId, endptAttrs := range registered {
+		sdc.attributeHashes.registered(nsName, svcName, endptId, endptAttrs)
+	}
 	return nil
 }
 
@@ -188,27 +630,59 @@ func (sdc *serviceDiscoveryClient) DeleteEndpoints(ctx context.Context, nsName s
 	}
 
 	sdc.log.Info("deleting endpoints", "namespaceName", nsName,
-		"serviceName", svcName, "endpoints", endpts)
+		"serviceName", svcName, "endpoints", endpointsForLog(endpts))
 
 	svcId, err := sdc.getServiceId(ctx, nsName, svcName)
 	if err != nil {
 		return err
 	}
 
-	opCollector := NewOperationCollector()
+	return sdc.inChunks(ctx, AuditActionDeregisterInstance, endpts, func(chunk []*model.Endpoint) error {
+		return sdc.deregisterChunk(ctx, nsName, svcName, svcId, chunk)
+	})
+}
+
+// deregisterChunk de-registers a chunk of endpoints and polls the operations until they complete.
+func (sdc *serviceDiscoveryClient) deregisterChunk(ctx context.Context, nsName string, svcName string, svcId string, endpts []*model.Endpoint) (err error) {
+	opCollector := newOperationCollector(sdc.log, sdc.deregisterConcurrency)
 
 	for _, endpt := range endpts {
 		endptId := endpt.Id
+		endptAttrs := endpt.GetCloudMapAttributes()
 		opCollector.Add(func() (opId string, err error) {
-			return sdc.sdApi.DeregisterInstance(ctx, svcId, endptId)
+			opId, err = sdc.sdApi.DeregisterInstance(ctx, svcId, endptId)
+			sdc.recordAudit(ctx, AuditRecord{
+				Action:      AuditActionDeregisterInstance,
+				Namespace:   nsName,
+				Service:     svcName,
+				ServiceId:   svcId,
+				InstanceId:  endptId,
+				OperationId: opId,
+				Before:      endptAttrs,
+				Error:       errorString(err),
+			})
+			return opId, err
 		})
 	}
 
-	err = NewDeregisterInstancePoller(sdc.sdApi, svcId, opCollector.Collect(), opCollector.GetStartTime()).Poll(ctx)
+	timer := metrics.PhaseTimerFromContext(ctx)
+	stopDeregister := timer.Start(metrics.PhaseDeregister)
+	opIds := opCollector.Collect()
+	stopDeregister()
+
+	stopPoll := timer.Start(metrics.PhasePoll)
+	done := sdc.operations.start(PendingOperation{Action: AuditActionDeregisterInstance, Namespace: nsName,
+		Service: svcName, ServiceId: svcId, OperationIds: opIds})
+	err = newOperationPoller(sdc.log, sdc.sdApi, types.OperationTypeDeregisterInstance, svcId, opIds,
+		opCollector.GetStartTime(), sdc.timeouts).Poll(ctx)
+	done()
+	stopPoll()
 
 	// Evict cache entry so next list call reflects changes
 	sdc.cache.EvictEndpoints(nsName, svcName)
 	if err != nil {
+		logAwsError(sdc.log, err, "failed to de-register endpoints",
+			"namespaceName", nsName, "serviceName", svcName, "serviceId", svcId)
 		return err
 	}
 
@@ -216,33 +690,309 @@ func (sdc *serviceDiscoveryClient) DeleteEndpoints(ctx context.Context, nsName s
 		return fmt.Errorf("failure while de-registering endpoints")
 	}
 
+	for _, endpt := range endpts {
+		sdc.attributeHashes.deregistered(nsName, svcName, endpt.Id)
+	}
 	return nil
 }
 
-func (sdc *serviceDiscoveryClient) listEndpoints(ctx context.Context, nsName string, svcName string) (endpts []*model.Endpoint, err error) {
-	if endpts, found := sdc.cache.GetEndpoints(nsName, svcName); found {
-		return endpts, nil
+// inChunks applies a change to the endpoints one chunk at a time, so the operations of a chunk are polled before the
+// next chunk is sent and the poll timeout applies per chunk. The change stops at the first failed chunk, the
+// completed chunks are kept: the next sync only computes the remaining changes, which resumes the change.
+func (sdc *serviceDiscoveryClient) inChunks(ctx context.Context, action string, endpts []*model.Endpoint, apply func([]*model.Endpoint) error) error {
+	endptChunks := chunks(endpts, sdc.syncChunkSize)
+	done := 0
+	for _, chunk := range endptChunks {
+		if err := apply(chunk); err != nil {
+			if done > 0 {
+				return fmt.Errorf("%s completed for %d of %d endpoints: %w", action, done, len(endpts), err)
+			}
+			return err
+		}
+		done += len(chunk)
+		if len(endptChunks) > 1 {
+			ReportSyncProgress(ctx, SyncProgress{Action: action, Done: done, Total: len(endpts)})
+		}
+	}
+	return nil
+}
+
+func (sdc *serviceDiscoveryClient) DeleteService(ctx context.Context, nsName string, svcName string) (err error) {
+	svcId, err := sdc.getServiceId(ctx, nsName, svcName)
+	if err != nil || svcId == "" {
+		return err
+	}
+
+	sdc.log.Info("deleting service", "namespaceName", nsName, "serviceName", svcName, "serviceId", svcId)
+	err = sdc.sdApi.DeleteService(ctx, svcId)
+	sdc.recordAudit(ctx, AuditRecord{
+		Action:    AuditActionDeleteService,
+		Namespace: nsName,
+		Service:   svcName,
+		ServiceId: svcId,
+		Error:     errorString(err),
+	})
+	if err != nil {
+		logAwsError(sdc.log, err, "failed to delete service",
+			"namespaceName", nsName, "serviceName", svcName, "serviceId", svcId)
+		return err
+	}
+
+	sdc.cache.EvictService(nsName, svcName)
+	sdc.attributeHashes.forget(nsName, svcName)
+	return nil
+}
+
+func (sdc *serviceDiscoveryClient) MarkServiceEmpty(ctx context.Context, nsName string, svcName string, empty bool) (err error) {
+	svcId, err := sdc.getServiceId(ctx, nsName, svcName)
+	if err != nil || svcId == "" {
+		return err
 	}
 
-	insts, err := sdc.sdApi.DiscoverInstances(ctx, nsName, svcName)
+	svc, err := sdc.sdApi.GetService(ctx, svcId)
+	if err != nil {
+		logAwsError(sdc.log, err, "failed to get service", "namespaceName", nsName, "serviceName", svcName)
+		return err
+	}
+	svcArn := aws.ToString(svc.Arn)
+	current, err := sdc.sdApi.ListTagsForResource(ctx, svcArn)
 	if err != nil {
+		logAwsError(sdc.log, err, "failed to list service tags", "namespaceName", nsName, "serviceName", svcName)
+		return err
+	}
+	emptySince, marked := current[EmptySinceTag]
+	if marked == empty {
+		return nil
+	}
+
+	record := AuditRecord{Namespace: nsName, Service: svcName, ServiceId: svcId}
+	if empty {
+		tags := map[string]string{EmptySinceTag: time.Now().UTC().Format(time.RFC3339)}
+		sdc.log.Info("marking service empty", "namespaceName", nsName, "serviceName", svcName)
+		err = sdc.sdApi.TagResource(ctx, svcArn, tags)
+		record.Action, record.After = AuditActionTagResource, tags
+	} else {
+		sdc.log.Info("unmarking empty service", "namespaceName", nsName, "serviceName", svcName)
+		err = sdc.sdApi.UntagResource(ctx, svcArn, []string{EmptySinceTag})
+		record.Action, record.Before = AuditActionUntagResource, map[string]string{EmptySinceTag: emptySince}
+	}
+	record.Error = errorString(err)
+	sdc.recordAudit(ctx, record)
+	if err != nil {
+		logAwsError(sdc.log, err, "failed to tag empty service", "namespaceName", nsName, "serviceName", svcName)
+	}
+	return err
+}
+
+func (sdc *serviceDiscoveryClient) DeleteEmptyNamespaces(ctx context.Context, gracePeriod time.Duration) (deleted []string, err error) {
+	clusterId := sdc.tags[ClusterIdTag]
+	if clusterId == "" {
+		return nil, errors.New("deleting empty namespaces requires the cluster ID of the ownership tags")
+	}
+
+	namespaces, err := sdc.sdApi.ListNamespaces(ctx)
+	if err != nil {
+		logAwsError(sdc.log, err, "failed to list namespaces")
 		return nil, err
 	}
 
-	for _, inst := range insts {
-		endpt, endptErr := model.NewEndpointFromInstance(&inst)
-		if endptErr != nil {
-			sdc.log.Error(endptErr, "skipping instance to endpoint conversion", "instanceId", *inst.InstanceId)
-			continue
+	errs := make([]error, 0)
+	for _, ns := range namespaces {
+		isDeleted, err := sdc.deleteEmptyNamespace(ctx, ns, clusterId, gracePeriod)
+		if err != nil {
+			errs = append(errs, err)
+		} else if isDeleted {
+			deleted = append(deleted, ns.Name)
 		}
-		endpts = append(endpts, endpt)
+	}
+	return deleted, utilerrors.NewAggregate(errs)
+}
+
+// deleteEmptyNamespace deletes the namespace if it was created by the cluster and has had no services for the grace
+// period, and tags or untags it as empty otherwise.
+func (sdc *serviceDiscoveryClient) deleteEmptyNamespace(ctx context.Context, ns *model.Namespace, clusterId string, gracePeriod time.Duration) (deleted bool, err error) {
+	namespace, err := sdc.sdApi.GetNamespace(ctx, ns.Id)
+	if err != nil {
+		logAwsError(sdc.log, err, "failed to get namespace", "namespaceName", ns.Name)
+		return false, err
+	}
+	nsArn := aws.ToString(namespace.Arn)
+	current, err := sdc.sdApi.ListTagsForResource(ctx, nsArn)
+	if err != nil {
+		logAwsError(sdc.log, err, "failed to list namespace tags", "namespaceName", ns.Name)
+		return false, err
+	}
+	if !IsOwned(current) || current[ClusterIdTag] != clusterId {
+		return false, nil
+	}
+
+	svcs, err := sdc.sdApi.ListServices(ctx, ns.Id)
+	if err != nil {
+		logAwsError(sdc.log, err, "failed to list services", "namespaceName", ns.Name, "namespaceId", ns.Id)
+		return false, err
+	}
+
+	emptySince, marked := current[EmptySinceTag]
+	record := AuditRecord{Namespace: ns.Name}
+	switch {
+	case len(svcs) > 0 && !marked:
+		return false, nil
+	case len(svcs) > 0:
+		sdc.log.Info("unmarking empty namespace", "namespaceName", ns.Name)
+		err = sdc.sdApi.UntagResource(ctx, nsArn, []string{EmptySinceTag})
+		record.Action, record.Before = AuditActionUntagResource, map[string]string{EmptySinceTag: emptySince}
+	default:
+		if since, parseErr := time.Parse(time.RFC3339, emptySince); marked && parseErr == nil {
+			if time.Since(since) < gracePeriod {
+				return false, nil
+			}
+			return sdc.deleteNamespace(ctx, ns)
+		}
+		// the namespace is marked with the current time if it isn't marked yet, or its tag was edited
+		tags := map[string]string{EmptySinceTag: time.Now().UTC().Format(time.RFC3339)}
+		sdc.log.Info("marking namespace empty", "namespaceName", ns.Name)
+		err = sdc.sdApi.TagResource(ctx, nsArn, tags)
+		record.Action, record.After = AuditActionTagResource, tags
+	}
+	record.Error = errorString(err)
+	sdc.recordAudit(ctx, record)
+	if err != nil {
+		logAwsError(sdc.log, err, "failed to tag empty namespace", "namespaceName", ns.Name)
+	}
+	return false, err
+}
+
+// deleteNamespace deletes the namespace and waits for the deletion, which fails if a service was created since the
+// namespace was found empty.
+func (sdc *serviceDiscoveryClient) deleteNamespace(ctx context.Context, ns *model.Namespace) (deleted bool, err error) {
+	sdc.log.Info("deleting empty namespace", "namespaceName", ns.Name, "namespaceId", ns.Id)
+	opId, err := sdc.sdApi.DeleteNamespace(ctx, ns.Id)
+	if err == nil {
+		_, err = sdc.sdApi.PollNamespaceOperation(ctx, opId)
+	}
+	sdc.recordAudit(ctx, AuditRecord{
+		Action:      AuditActionDeleteNamespace,
+		Namespace:   ns.Name,
+		OperationId: opId,
+		Error:       errorString(err),
+	})
+	if err != nil {
+		logAwsError(sdc.log, err, "failed to delete namespace", "namespaceName", ns.Name, "namespaceId", ns.Id)
+		return false, err
+	}
+
+	sdc.cache.CacheNilNamespace(ns.Name)
+	return true, nil
+}
+
+func (sdc *serviceDiscoveryClient) EvictEndpoints(nsName string, svcName string) {
+	sdc.cache.EvictEndpoints(nsName, svcName)
+}
+
+func (sdc *serviceDiscoveryClient) WarmUp(ctx context.Context, concurrency int) (summary CacheSummary, err error) {
+	namespaces, err := sdc.sdApi.ListNamespaces(ctx)
+	if err != nil {
+		logAwsError(sdc.log, err, "failed to list namespaces to warm up the cache")
+		return summary, err
+	}
+	for _, ns := range namespaces {
+		sdc.cache.CacheNamespace(ns)
+	}
+
+	if concurrency <= 0 {
+		concurrency = 1
+	}
+	errs := make([]error, len(namespaces))
+	workqueue.ParallelizeUntil(ctx, concurrency, len(namespaces), func(i int) {
+		// listing the services caches their IDs and endpoints
+		_, errs[i] = sdc.ListServices(ctx, namespaces[i].Name)
+	})
+
+	return sdc.cache.Summary(), utilerrors.NewAggregate(errs)
+}
+
+func (sdc *serviceDiscoveryClient) State() ClientState {
+	return ClientState{
+		PendingOperations: sdc.operations.list(),
+		Cache:             sdc.cache.Summary(),
+	}
+}
+
+func (sdc *serviceDiscoveryClient) listEndpoints(ctx context.Context, nsName string, svcName string) (endpts []*model.Endpoint, err error) {
+	if endpts, found := sdc.cache.GetEndpoints(nsName, svcName); found {
+		return endpts, nil
+	}
+
+	// the instances are converted page by page, the summaries of a large service aren't held along with its endpoints
+	endpts = make([]*model.Endpoint, 0)
+	instAttrs := make(map[string]map[string]string)
+	err = sdc.forEachInstancePage(ctx, nsName, svcName, func(insts []types.HttpInstanceSummary) error {
+		for i := range insts {
+			inst := &insts[i]
+			instAttrs[aws.ToString(inst.InstanceId)] = inst.Attributes
+			observeSchemaVersion(inst.Attributes)
+			endpt, endptErr := model.NewEndpointFromInstance(inst)
+			if endptErr != nil {
+				sdc.log.Error(endptErr, "skipping instance to endpoint conversion",
+					"namespaceName", nsName, "serviceName", svcName, "instanceId", *inst.InstanceId)
+				continue
+			}
+			endpts = append(endpts, endpt)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
 	}
 
 	sdc.cache.CacheEndpoints(nsName, svcName, endpts)
+	sdc.attributeHashes.discovered(nsName, svcName, instAttrs)
 
 	return endpts, nil
 }
 
+// observeSchemaVersion counts instances registered with another attribute schema version, e.g. by controllers of other
+// clusters during an upgrade.
+func observeSchemaVersion(attributes map[string]string) {
+	switch version := model.InstanceSchemaVersion(attributes); {
+	case version < model.SchemaVersion:
+		metrics.IncSchemaVersionSkew(metrics.SchemaSkewOlder)
+	case version > model.SchemaVersion:
+		metrics.IncSchemaVersionSkew(metrics.SchemaSkewNewer)
+	}
+}
+
+// forEachInstancePage passes the instances of a service to the callback according to the instance paging strategy,
+// a page at a time when listing the instances.
+func (sdc *serviceDiscoveryClient) forEachInstancePage(ctx context.Context, nsName string, svcName string, page func(insts []types.HttpInstanceSummary) error) error {
+	if sdc.instancePaging != InstancePagingList {
+		insts, err := sdc.sdApi.DiscoverInstances(ctx, nsName, svcName)
+		if err != nil {
+			logAwsError(sdc.log, err, "failed to discover instances", "namespaceName", nsName, "serviceName", svcName)
+			return err
+		}
+		maxResults := sdc.discoverMaxResults
+		if maxResults <= 0 {
+			maxResults = MaxDiscoverMaxResults
+		}
+		if sdc.instancePaging == InstancePagingNone || len(insts) < maxResults {
+			return page(insts)
+		}
+		sdc.log.Info("discovered instances may be truncated, listing all instances", "namespaceName", nsName,
+			"serviceName", svcName, "discoverMaxResults", maxResults)
+	}
+
+	svcId, err := sdc.getServiceId(ctx, nsName, svcName)
+	if err != nil || svcId == "" {
+		return err
+	}
+	if err = sdc.sdApi.ListInstancePages(ctx, nsName, svcName, svcId, page); err != nil {
+		logAwsError(sdc.log, err, "failed to list instances", "namespaceName", nsName, "serviceName", svcName)
+		return err
+	}
+	return nil
+}
+
 func (sdc *serviceDiscoveryClient) getNamespace(ctx context.Context, nsName string) (namespace *model.Namespace, err error) {
 	// We are assuming a unique namespace name per account
 	namespace, exists := sdc.cache.GetNamespace(nsName)
@@ -252,6 +1002,7 @@ func (sdc *serviceDiscoveryClient) getNamespace(ctx context.Context, nsName stri
 
 	namespaces, err := sdc.sdApi.ListNamespaces(ctx)
 	if err != nil {
+		logAwsError(sdc.log, err, "failed to list namespaces", "namespaceName", nsName)
 		return nil, err
 	}
 
@@ -285,11 +1036,13 @@ func (sdc *serviceDiscoveryClient) getServiceId(ctx context.Context, nsName stri
 
 	services, err := sdc.sdApi.ListServices(ctx, namespace.Id)
 	if err != nil {
+		logAwsError(sdc.log, err, "failed to list services",
+			"namespaceName", nsName, "namespaceId", namespace.Id, "serviceName", svcName)
 		return "", err
 	}
 
 	for _, svc := range services {
-		sdc.cache.CacheServiceId(nsName, svcName, svc.Id)
+		sdc.cache.CacheServiceId(nsName, svc.Name, svc.Id)
 		if svc.Name == svcName {
 			svcId = svc.Id
 		}
@@ -301,12 +1054,20 @@ func (sdc *serviceDiscoveryClient) getServiceId(ctx context.Context, nsName stri
 func (sdc *serviceDiscoveryClient) createNamespace(ctx context.Context, nsName string) (namespace *model.Namespace, err error) {
 	sdc.log.Info("creating a new namespace", "namespace", nsName)
 	opId, err := sdc.sdApi.CreateHttpNamespace(ctx, nsName)
+	sdc.recordAudit(ctx, AuditRecord{
+		Action:      AuditActionCreateNamespace,
+		Namespace:   nsName,
+		OperationId: opId,
+		Error:       errorString(err),
+	})
 	if err != nil {
+		logAwsError(sdc.log, err, "failed to create namespace", "namespaceName", nsName)
 		return nil, err
 	}
 
 	nsId, err := sdc.sdApi.PollNamespaceOperation(ctx, opId)
 	if err != nil {
+		logAwsError(sdc.log, err, "failed to poll namespace creation", "namespaceName", nsName, "operationId", opId)
 		return nil, err
 	}
 
@@ -322,3 +1083,32 @@ func (sdc *serviceDiscoveryClient) createNamespace(ctx context.Context, nsName s
 	sdc.cache.CacheNamespace(namespace)
 	return namespace, nil
 }
+
+func (sdc *serviceDiscoveryClient) recordAudit(ctx context.Context, record AuditRecord) {
+	if sdc.audit != nil {
+		sdc.audit.Record(ctx, record)
+	}
+}
+
+// getAuditedEndpointAttributes returns the cached attributes of currently registered endpoints by ID, to summarize
+// the state before a registration. No lookup is performed if auditing is disabled.
+func (sdc *serviceDiscoveryClient) getAuditedEndpointAttributes(nsName string, svcName string) map[string]map[string]string {
+	attrs := make(map[string]map[string]string)
+	if sdc.audit == nil {
+		return attrs
+	}
+
+	endpts, _ := sdc.cache.GetEndpoints(nsName, svcName)
+	for _, endpt := range endpts {
+		attrs[endpt.Id] = endpt.GetCloudMapAttributes()
+	}
+	return attrs
+}
+
+// endpointsForLog returns the endpoints to log, or only their count for large services.
+func endpointsForLog(endpts []*model.Endpoint) interface{} {
+	if len(endpts) > maxLoggedEndpoints {
+		return len(endpts)
+	}
+	return endpts
+}
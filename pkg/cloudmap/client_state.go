@@ -0,0 +1,82 @@
+package cloudmap
+
+import (
+	"sort"
+	"sync"
+	"time"
+)
+
+// ClientState summarizes the internal state of the service discovery client, for debugging.
+type ClientState struct {
+	// PendingOperations are the instance operations the client is waiting on
+	PendingOperations []PendingOperation `json:"pendingOperations"`
+	Cache             CacheSummary       `json:"cache"`
+}
+
+// PendingOperation is a batch of instance operations on a service, which the client polls until they complete.
+type PendingOperation struct {
+	// Action is the audit action of the operations, see AuditActionRegisterInstance and AuditActionDeregisterInstance
+	Action       string    `json:"action"`
+	Namespace    string    `json:"namespace"`
+	Service      string    `json:"service"`
+	ServiceId    string    `json:"serviceId"`
+	OperationIds []string  `json:"operationIds"`
+	StartedAt    time.Time `json:"startedAt"`
+}
+
+// operationTracker tracks the operations being polled. It is safe for concurrent use, and a nil operationTracker
+// discards all calls.
+type operationTracker struct {
+	mu      sync.Mutex
+	pending map[int]PendingOperation
+	nextId  int
+	now     func() time.Time
+}
+
+func newOperationTracker() *operationTracker {
+	return &operationTracker{
+		pending: make(map[int]PendingOperation),
+		now:     time.Now,
+	}
+}
+
+// start tracks the operations as pending until the returned function is called.
+func (t *operationTracker) start(op PendingOperation) (done func()) {
+	if t == nil {
+		return func() {}
+	}
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	id := t.nextId
+	t.nextId++
+	op.StartedAt = t.now()
+	t.pending[id] = op
+	return func() {
+		t.mu.Lock()
+		defer t.mu.Unlock()
+		delete(t.pending, id)
+	}
+}
+
+// list returns the pending operations, oldest first.
+func (t *operationTracker) list() []PendingOperation {
+	ops := make([]PendingOperation, 0)
+	if t == nil {
+		return ops
+	}
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	ids := make([]int, 0, len(t.pending))
+	for id := range t.pending {
+		ids = append(ids, id)
+	}
+	sort.Ints(ids)
+	for _, id := range ids {
+		ops = append(ops, t.pending[id])
+	}
+	return ops
+}
@@ -0,0 +1,34 @@
+package cloudmap
+
+import (
+	"github.com/stretchr/testify/assert"
+	"testing"
+	"time"
+)
+
+func TestOperationTracker(t *testing.T) {
+	startedAt := time.Date(2021, 9, 1, 0, 0, 0, 0, time.UTC)
+	tracker := newOperationTracker()
+	tracker.now = func() time.Time { return startedAt }
+
+	doneRegister := tracker.start(PendingOperation{Action: AuditActionRegisterInstance, OperationIds: []string{"op1"}})
+	doneDeregister := tracker.start(PendingOperation{Action: AuditActionDeregisterInstance, OperationIds: []string{"op2"}})
+	assert.Equal(t, []PendingOperation{
+		{Action: AuditActionRegisterInstance, OperationIds: []string{"op1"}, StartedAt: startedAt},
+		{Action: AuditActionDeregisterInstance, OperationIds: []string{"op2"}, StartedAt: startedAt},
+	}, tracker.list())
+
+	doneRegister()
+	assert.Equal(t, []PendingOperation{
+		{Action: AuditActionDeregisterInstance, OperationIds: []string{"op2"}, StartedAt: startedAt},
+	}, tracker.list())
+
+	doneDeregister()
+	assert.Empty(t, tracker.list())
+}
+
+func TestOperationTracker_Nil(t *testing.T) {
+	var tracker *operationTracker
+	tracker.start(PendingOperation{})()
+	assert.Empty(t, tracker.list())
+}
@@ -3,12 +3,14 @@ package cloudmap
 import (
 	"context"
 	"errors"
+	"fmt"
 	"github.com/aws/aws-cloud-map-mcs-controller-for-k8s/mocks/pkg/cloudmap"
 	"github.com/aws/aws-cloud-map-mcs-controller-for-k8s/pkg/common"
 	"github.com/aws/aws-cloud-map-mcs-controller-for-k8s/pkg/model"
 	"github.com/aws/aws-cloud-map-mcs-controller-for-k8s/test"
 	"github.com/aws/aws-sdk-go-v2/aws"
 	"github.com/aws/aws-sdk-go-v2/service/servicediscovery/types"
+	"github.com/aws/smithy-go"
 	testing2 "github.com/go-logr/logr/testing"
 	"github.com/golang/mock/gomock"
 	"github.com/stretchr/testify/assert"
@@ -27,6 +29,20 @@ func TestNewServiceDiscoveryClient(t *testing.T) {
 	assert.NotNil(t, sdc)
 }
 
+func TestNewServiceDiscoveryClient_Log(t *testing.T) {
+	sdc := NewServiceDiscoveryClient(&aws.Config{}, &SdClientConfig{
+		Log:    testing2.TestLogger{T: t},
+		Faults: &FaultConfig{ErrorRate: 0.5, Seed: 1},
+	}).(*serviceDiscoveryClient)
+
+	log := common.NewLoggerWithLogr(testing2.TestLogger{T: t})
+	assert.Equal(t, log, sdc.log)
+	assert.Equal(t, log, sdc.cache.(*sdCache).log)
+	faultInjectingApi := sdc.sdApi.(*faultInjectingApi)
+	assert.Equal(t, log, faultInjectingApi.log)
+	assert.Equal(t, log, faultInjectingApi.api.(*serviceDiscoveryApi).log)
+}
+
 func TestServiceDiscoveryClient_ListServices_HappyCase(t *testing.T) {
 	tc := getTestSdClient(t)
 	defer tc.close()
@@ -168,6 +184,7 @@ func TestServiceDiscoveryClient_CreateService_HappyCase(t *testing.T) {
 	tc.mockApi.EXPECT().CreateService(context.TODO(), *test.GetTestHttpNamespace(), test.SvcName).
 		Return(test.SvcId, nil)
 	tc.mockCache.EXPECT().CacheServiceId(test.NsName, test.SvcName, test.SvcId)
+	tc.mockCache.EXPECT().CacheServiceMetadata(test.NsName, test.SvcName, ServiceMetadata{})
 
 	err := tc.client.CreateService(context.TODO(), test.NsName, test.SvcName)
 	assert.Nil(t, err, "No error for happy case")
@@ -182,6 +199,7 @@ func TestServiceDiscoveryClient_CreateService_HappyCaseForDNSNamespace(t *testin
 	tc.mockApi.EXPECT().CreateService(context.TODO(), *test.GetTestDnsNamespace(), test.SvcName).
 		Return(test.SvcId, nil)
 	tc.mockCache.EXPECT().CacheServiceId(test.NsName, test.SvcName, test.SvcId)
+	tc.mockCache.EXPECT().CacheServiceMetadata(test.NsName, test.SvcName, ServiceMetadata{})
 
 	err := tc.client.CreateService(context.TODO(), test.NsName, test.SvcName)
 	assert.Nil(t, err, "No error for happy case")
@@ -232,6 +250,7 @@ func TestServiceDiscoveryClient_CreateService_CreatesNamespace_HappyCase(t *test
 	tc.mockApi.EXPECT().CreateService(context.TODO(), *test.GetTestHttpNamespace(), test.SvcName).
 		Return(test.SvcId, nil)
 	tc.mockCache.EXPECT().CacheServiceId(test.NsName, test.SvcName, test.SvcId)
+	tc.mockCache.EXPECT().CacheServiceMetadata(test.NsName, test.SvcName, ServiceMetadata{})
 
 	err := tc.client.CreateService(context.TODO(), test.NsName, test.SvcName)
 	assert.Nil(t, err, "No error for happy case")
@@ -334,12 +353,137 @@ func TestServiceDiscoveryClient_GetService_CachedValues(t *testing.T) {
 
 	tc.mockCache.EXPECT().GetEndpoints(test.NsName, test.SvcName).
 		Return([]*model.Endpoint{test.GetTestEndpoint1(), test.GetTestEndpoint2()}, true)
+	tc.mockCache.EXPECT().GetServiceId(test.NsName, test.SvcName).Return(test.SvcId, true)
 
 	svc, err := tc.client.GetService(context.TODO(), test.NsName, test.SvcName)
 	assert.Nil(t, err)
 	assert.Equal(t, test.GetTestService(), svc)
 }
 
+func TestServiceDiscoveryClient_UpdateServiceMetadata_Unchanged(t *testing.T) {
+	tc := getTestSdClient(t)
+	defer tc.close()
+
+	metadata := ServiceMetadata{Description: "checkout", Tags: map[string]string{"team": "payments"}}
+	tc.mockCache.EXPECT().GetServiceMetadata(test.NsName, test.SvcName).Return(metadata, true)
+
+	err := tc.client.UpdateServiceMetadata(context.TODO(), test.NsName, test.SvcName, metadata)
+	assert.Nil(t, err)
+}
+
+func TestServiceDiscoveryClient_UpdateServiceMetadata_HappyCase(t *testing.T) {
+	tc := getTestSdClient(t)
+	defer tc.close()
+	tc.client.tags = map[string]string{"env": "prod"}
+
+	svcArn := "arn:aws:servicediscovery:us-west-2:123456789012:service/" + test.SvcId
+	metadata := ServiceMetadata{
+		Description: "checkout",
+		Tags:        map[string]string{"team": "payments", "owner": "alice", "env": "dev"},
+	}
+	tc.mockCache.EXPECT().GetServiceMetadata(test.NsName, test.SvcName).Return(ServiceMetadata{}, false)
+	tc.mockCache.EXPECT().GetServiceId(test.NsName, test.SvcName).Return(test.SvcId, true)
+	svc := &types.Service{Id: aws.String(test.SvcId), Arn: aws.String(svcArn)}
+	tc.mockApi.EXPECT().GetService(context.TODO(), test.SvcId).Return(svc, nil)
+	tc.mockApi.EXPECT().UpdateServiceDescription(context.TODO(), svc, "checkout").Return(test.OpId1, nil)
+	tc.mockApi.EXPECT().ListTagsForResource(context.TODO(), svcArn).
+		Return(map[string]string{"env": "prod", "owner": "alice"}, nil)
+	// the configured env tag is kept, and the unchanged owner tag is not applied again
+	tc.mockApi.EXPECT().TagResource(context.TODO(), svcArn, map[string]string{"team": "payments"}).Return(nil)
+	tc.mockCache.EXPECT().CacheServiceMetadata(test.NsName, test.SvcName, metadata)
+
+	err := tc.client.UpdateServiceMetadata(context.TODO(), test.NsName, test.SvcName, metadata)
+	assert.Nil(t, err)
+}
+
+func TestServiceDiscoveryClient_UpdateServiceMetadata_TagError(t *testing.T) {
+	tc := getTestSdClient(t)
+	defer tc.close()
+
+	svcArn := "arn:aws:servicediscovery:us-west-2:123456789012:service/" + test.SvcId
+	metadata := ServiceMetadata{Tags: map[string]string{"team": "payments"}}
+	tagErr := errors.New("error tagging service")
+	tc.mockCache.EXPECT().GetServiceMetadata(test.NsName, test.SvcName).Return(ServiceMetadata{}, false)
+	tc.mockCache.EXPECT().GetServiceId(test.NsName, test.SvcName).Return(test.SvcId, true)
+	tc.mockApi.EXPECT().GetService(context.TODO(), test.SvcId).
+		Return(&types.Service{Id: aws.String(test.SvcId), Arn: aws.String(svcArn)}, nil)
+	tc.mockApi.EXPECT().ListTagsForResource(context.TODO(), svcArn).Return(map[string]string{}, nil)
+	tc.mockApi.EXPECT().TagResource(context.TODO(), svcArn, metadata.Tags).Return(tagErr)
+
+	err := tc.client.UpdateServiceMetadata(context.TODO(), test.NsName, test.SvcName, metadata)
+	assert.Equal(t, tagErr, err)
+}
+
+func TestServiceDiscoveryClient_UpdateServiceMetadata_TooManyTags(t *testing.T) {
+	tc := getTestSdClient(t)
+	defer tc.close()
+
+	svcArn := "arn:aws:servicediscovery:us-west-2:123456789012:service/" + test.SvcId
+	current := make(map[string]string, maxResourceTags)
+	for i := 0; i < maxResourceTags; i++ {
+		current[fmt.Sprintf("tag%d", i)] = "value"
+	}
+	metadata := ServiceMetadata{Tags: map[string]string{"team": "payments"}}
+	tc.mockCache.EXPECT().GetServiceMetadata(test.NsName, test.SvcName).Return(ServiceMetadata{}, false)
+	tc.mockCache.EXPECT().GetServiceId(test.NsName, test.SvcName).Return(test.SvcId, true)
+	tc.mockApi.EXPECT().GetService(context.TODO(), test.SvcId).
+		Return(&types.Service{Id: aws.String(test.SvcId), Arn: aws.String(svcArn)}, nil)
+	tc.mockApi.EXPECT().ListTagsForResource(context.TODO(), svcArn).Return(current, nil)
+
+	// no tag is added beyond the limit
+	err := tc.client.UpdateServiceMetadata(context.TODO(), test.NsName, test.SvcName, metadata)
+	assert.True(t, errors.Is(err, ErrTooManyTags))
+}
+
+func TestServiceDiscoveryClient_CorrectDnsConfig_Checked(t *testing.T) {
+	tc := getTestSdClient(t)
+	defer tc.close()
+
+	tc.mockCache.EXPECT().GetServiceDnsTTL(test.NsName, test.SvcName).Return(int64(60), true)
+
+	drift, err := tc.client.CorrectDnsConfig(context.TODO(), test.NsName, test.SvcName, 60)
+	assert.Nil(t, err)
+	assert.True(t, drift.IsEmpty())
+}
+
+func TestServiceDiscoveryClient_CorrectDnsConfig_HappyCase(t *testing.T) {
+	tc := getTestSdClient(t)
+	defer tc.close()
+
+	svc := &types.Service{
+		Id: aws.String(test.SvcId),
+		DnsConfig: &types.DnsConfig{
+			RoutingPolicy: types.RoutingPolicyWeighted,
+			DnsRecords:    []types.DnsRecord{{Type: types.RecordTypeSrv, TTL: aws.Int64(300)}},
+		},
+	}
+	tc.mockCache.EXPECT().GetServiceDnsTTL(test.NsName, test.SvcName).Return(int64(0), false)
+	tc.mockCache.EXPECT().GetServiceId(test.NsName, test.SvcName).Return(test.SvcId, true)
+	tc.mockApi.EXPECT().GetService(context.TODO(), test.SvcId).Return(svc, nil)
+	tc.mockApi.EXPECT().UpdateServiceDnsRecords(context.TODO(), svc,
+		[]types.DnsRecord{{Type: types.RecordTypeSrv, TTL: aws.Int64(60)}}).Return(test.OpId1, nil)
+	tc.mockCache.EXPECT().CacheServiceDnsTTL(test.NsName, test.SvcName, int64(60))
+
+	drift, err := tc.client.CorrectDnsConfig(context.TODO(), test.NsName, test.SvcName, 60)
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"SRV record TTL 300 -> 60"}, drift.Corrected)
+	assert.Equal(t, []string{"routing policy WEIGHTED instead of MULTIVALUE"}, drift.Uncorrectable)
+}
+
+func TestServiceDiscoveryClient_CorrectDnsConfig_HttpNamespace(t *testing.T) {
+	tc := getTestSdClient(t)
+	defer tc.close()
+
+	tc.mockCache.EXPECT().GetServiceDnsTTL(test.NsName, test.SvcName).Return(int64(0), false)
+	tc.mockCache.EXPECT().GetServiceId(test.NsName, test.SvcName).Return(test.SvcId, true)
+	tc.mockApi.EXPECT().GetService(context.TODO(), test.SvcId).Return(&types.Service{Id: aws.String(test.SvcId)}, nil)
+	tc.mockCache.EXPECT().CacheServiceDnsTTL(test.NsName, test.SvcName, int64(60))
+
+	drift, err := tc.client.CorrectDnsConfig(context.TODO(), test.NsName, test.SvcName, 60)
+	assert.Nil(t, err)
+	assert.True(t, drift.IsEmpty())
+}
+
 func TestServiceDiscoveryClient_RegisterEndpoints(t *testing.T) {
 	tc := getTestSdClient(t)
 	defer tc.close()
@@ -355,6 +499,7 @@ func TestServiceDiscoveryClient_RegisterEndpoints(t *testing.T) {
 		model.ServicePortAttr:       test.ServicePortStr1,
 		model.ServiceProtocolAttr:   test.Protocol1,
 		model.ServiceTargetPortAttr: test.PortStr1,
+		model.SchemaVersionAttr:     "3",
 	}
 	attrs2 := map[string]string{
 		model.EndpointIpv4Attr:      test.EndptIp2,
@@ -365,6 +510,7 @@ func TestServiceDiscoveryClient_RegisterEndpoints(t *testing.T) {
 		model.ServicePortAttr:       test.ServicePortStr2,
 		model.ServiceProtocolAttr:   test.Protocol2,
 		model.ServiceTargetPortAttr: test.PortStr2,
+		model.SchemaVersionAttr:     "3",
 	}
 
 	tc.mockApi.EXPECT().RegisterInstance(context.TODO(), test.SvcId, test.EndptId1, attrs1).
@@ -384,6 +530,32 @@ func TestServiceDiscoveryClient_RegisterEndpoints(t *testing.T) {
 	assert.Nil(t, err)
 }
 
+func TestServiceDiscoveryClient_RegisterEndpoints_InstanceFailure(t *testing.T) {
+	tc := getTestSdClient(t)
+	defer tc.close()
+
+	tc.mockCache.EXPECT().GetServiceId(test.NsName, test.SvcName).Return(test.SvcId, true)
+
+	throttled := &smithy.GenericAPIError{Code: "ThrottlingException", Message: "Rate exceeded"}
+	tc.mockApi.EXPECT().RegisterInstance(context.TODO(), test.SvcId, test.EndptId1, gomock.Any()).
+		Return(test.OpId1, nil)
+	tc.mockApi.EXPECT().RegisterInstance(context.TODO(), test.SvcId, test.EndptId2, gomock.Any()).
+		Return("", throttled)
+	tc.mockApi.EXPECT().ListOperations(context.TODO(), gomock.Any()).
+		Return(map[string]types.OperationStatus{test.OpId1: types.OperationStatusSuccess}, nil)
+
+	tc.mockCache.EXPECT().EvictEndpoints(test.NsName, test.SvcName)
+
+	err := tc.client.RegisterEndpoints(context.TODO(), test.NsName, test.SvcName,
+		[]*model.Endpoint{test.GetTestEndpoint1(), test.GetTestEndpoint2()})
+
+	var instanceErr *InstanceFailureError
+	assert.True(t, errors.As(err, &instanceErr))
+	assert.Equal(t, []InstanceFailure{{InstanceId: test.EndptId2, Err: throttled}}, instanceErr.Failures)
+	assert.True(t, IsThrottlingError(err), "the failures can be classified")
+	assert.Equal(t, "ThrottlingException", ErrorCode(instanceErr.Failures[0].Err))
+}
+
 func TestServiceDiscoveryClient_DeleteEndpoints(t *testing.T) {
 	tc := getTestSdClient(t)
 	defer tc.close()
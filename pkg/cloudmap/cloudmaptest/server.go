@@ -0,0 +1,874 @@
+// Package cloudmaptest provides an in-memory Cloud Map for testing code using the Cloud Map client, without an AWS
+// account and without mocking individual API calls.
+package cloudmaptest
+
+import (
+	"context"
+	"fmt"
+	"github.com/aws/aws-cloud-map-mcs-controller-for-k8s/pkg/cloudmap"
+	"github.com/aws/aws-sdk-go-v2/aws"
+	sd "github.com/aws/aws-sdk-go-v2/service/servicediscovery"
+	"github.com/aws/aws-sdk-go-v2/service/servicediscovery/types"
+	"k8s.io/apimachinery/pkg/util/sets"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	// DefaultAccountId and DefaultRegion build the ARNs of resources unless configured otherwise
+	DefaultAccountId = "123456789012"
+	DefaultRegion    = "us-west-2"
+
+	defaultPageSize = 100
+)
+
+// Options configure the behavior of the in-memory Cloud Map.
+type Options struct {
+	// Latency is added to every API call
+	Latency time.Duration
+	// OperationDelay is the time until asynchronous operations complete, operations complete with the next API call
+	// if 0
+	OperationDelay time.Duration
+	// PageSize is the default number of results of list calls, 100 if 0
+	PageSize int
+	// AccountId and Region build the ARNs of resources, DefaultAccountId and DefaultRegion are used if empty
+	AccountId string
+	Region    string
+}
+
+// Server is an in-memory implementation of the Cloud Map API used by the Cloud Map client. It keeps namespaces,
+// services, instances, tags and operations, and completes operations asynchronously like Cloud Map: namespaces are
+// created, services updated and instances registered or de-registered when their operation succeeds. Server is safe
+// for concurrent use.
+type Server struct {
+	opts Options
+
+	mu         sync.Mutex
+	nextId     int
+	namespaces map[string]*types.Namespace
+	services   map[string]*types.Service
+	instances  map[string]map[string]map[string]string
+	tags       map[string]map[string]string
+	operations map[string]*operation
+	failures   map[types.OperationType]string
+	calls      map[string]int
+}
+
+// operation is a Cloud Map operation, which applies its change when it succeeds.
+type operation struct {
+	types.Operation
+	due   time.Time
+	apply func()
+}
+
+var _ cloudmap.AwsFacade = &Server{}
+
+// NewServer creates an empty in-memory Cloud Map.
+func NewServer(opts Options) *Server {
+	if opts.PageSize <= 0 {
+		opts.PageSize = defaultPageSize
+	}
+	if opts.AccountId == "" {
+		opts.AccountId = DefaultAccountId
+	}
+	if opts.Region == "" {
+		opts.Region = DefaultRegion
+	}
+	return &Server{
+		opts:       opts,
+		namespaces: make(map[string]*types.Namespace),
+		services:   make(map[string]*types.Service),
+		instances:  make(map[string]map[string]map[string]string),
+		tags:       make(map[string]map[string]string),
+		operations: make(map[string]*operation),
+		failures:   make(map[types.OperationType]string),
+		calls:      make(map[string]int),
+	}
+}
+
+// NewServiceDiscoveryClient creates a Cloud Map client backed by the server. Operations are polled every 10ms unless
+// the client config sets the timeouts.
+func (s *Server) NewServiceDiscoveryClient(clientConfig cloudmap.SdClientConfig) cloudmap.ServiceDiscoveryClient {
+	clientConfig.AwsFacade = s
+	if clientConfig.Timeouts == nil {
+		clientConfig.Timeouts = cloudmap.NewDefaultSdTimeoutConfig()
+		clientConfig.Timeouts.OperationPollInterval = 10 * time.Millisecond
+	}
+	return cloudmap.NewServiceDiscoveryClient(&aws.Config{}, &clientConfig)
+}
+
+// AddNamespace creates a namespace without operation, and returns its ID. An existing namespace of the same name is
+// returned instead.
+func (s *Server) AddNamespace(name string, namespaceType types.NamespaceType) string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if ns := s.namespaceByName(name); ns != nil {
+		return aws.ToString(ns.Id)
+	}
+	nsId := s.newId("ns")
+	s.namespaces[nsId] = s.newNamespace(nsId, name, namespaceType)
+	s.tags[aws.ToString(s.namespaces[nsId].Arn)] = make(map[string]string)
+	return nsId
+}
+
+// AddService creates a service in the namespace of the given name, and returns its ID. The namespace is created as
+// HTTP namespace if it doesn't exist. An existing service of the same name is returned instead.
+func (s *Server) AddService(namespaceName string, serviceName string) string {
+	nsId := s.AddNamespace(namespaceName, types.NamespaceTypeHttp)
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if svc := s.serviceByName(nsId, serviceName); svc != nil {
+		return aws.ToString(svc.Id)
+	}
+	return s.addService(nsId, serviceName, nil, nil, nil)
+}
+
+// AddInstance registers an instance without operation, creating its namespace and service if needed.
+func (s *Server) AddInstance(namespaceName string, serviceName string, instanceId string, attrs map[string]string) {
+	svcId := s.AddService(namespaceName, serviceName)
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.instances[svcId][instanceId] = copyMap(attrs)
+}
+
+// Instances returns the attributes of the instances of a service by instance ID, or nil if the service doesn't exist.
+func (s *Server) Instances(namespaceName string, serviceName string) map[string]map[string]string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.completeOperations()
+
+	ns := s.namespaceByName(namespaceName)
+	if ns == nil {
+		return nil
+	}
+	svc := s.serviceByName(aws.ToString(ns.Id), serviceName)
+	if svc == nil {
+		return nil
+	}
+
+	instances := make(map[string]map[string]string)
+	for id, attrs := range s.instances[aws.ToString(svc.Id)] {
+		instances[id] = copyMap(attrs)
+	}
+	return instances
+}
+
+// Tags returns the tags of the resource with the given ARN.
+func (s *Server) Tags(arn string) map[string]string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return copyMap(s.tags[arn])
+}
+
+// FailOperations makes the following operations of the given type fail with the error code instead of applying their
+// change. An empty error code lets them succeed again.
+func (s *Server) FailOperations(operationType types.OperationType, errorCode string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if errorCode == "" {
+		delete(s.failures, operationType)
+	} else {
+		s.failures[operationType] = errorCode
+	}
+}
+
+// CompleteOperations completes all pending operations, regardless of the operation delay.
+func (s *Server) CompleteOperations() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for _, op := range s.operations {
+		op.due = time.Time{}
+	}
+	s.completeOperations()
+}
+
+// Calls returns the number of calls of an API action, e.g. "RegisterInstance".
+func (s *Server) Calls(action string) int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.calls[action]
+}
+
+func (s *Server) ListNamespaces(ctx context.Context, input *sd.ListNamespacesInput, _ ...func(*sd.Options)) (*sd.ListNamespacesOutput, error) {
+	unlock, err := s.call(ctx, "ListNamespaces")
+	if err != nil {
+		return nil, err
+	}
+	defer unlock()
+
+	summaries := make([]types.NamespaceSummary, 0, len(s.namespaces))
+	for _, ns := range s.namespaces {
+		if !namespaceMatches(ns, input.Filters) {
+			continue
+		}
+		summaries = append(summaries, types.NamespaceSummary{
+			Arn:        ns.Arn,
+			Id:         ns.Id,
+			Name:       ns.Name,
+			Type:       ns.Type,
+			CreateDate: ns.CreateDate,
+		})
+	}
+	sort.Slice(summaries, func(i, j int) bool {
+		return aws.ToString(summaries[i].Id) < aws.ToString(summaries[j].Id)
+	})
+
+	start, end, next, err := s.page(len(summaries), input.MaxResults, input.NextToken)
+	if err != nil {
+		return nil, err
+	}
+	return &sd.ListNamespacesOutput{Namespaces: summaries[start:end], NextToken: next}, nil
+}
+
+func (s *Server) ListServices(ctx context.Context, input *sd.ListServicesInput, _ ...func(options *sd.Options)) (*sd.ListServicesOutput, error) {
+	unlock, err := s.call(ctx, "ListServices")
+	if err != nil {
+		return nil, err
+	}
+	defer unlock()
+
+	summaries := make([]types.ServiceSummary, 0, len(s.services))
+	for _, svc := range s.services {
+		if !serviceMatches(svc, input.Filters) {
+			continue
+		}
+		summaries = append(summaries, types.ServiceSummary{
+			Arn:           svc.Arn,
+			Id:            svc.Id,
+			Name:          svc.Name,
+			Description:   svc.Description,
+			DnsConfig:     svc.DnsConfig,
+			InstanceCount: aws.Int32(int32(len(s.instances[aws.ToString(svc.Id)]))),
+			CreateDate:    svc.CreateDate,
+		})
+	}
+	sort.Slice(summaries, func(i, j int) bool {
+		return aws.ToString(summaries[i].Id) < aws.ToString(summaries[j].Id)
+	})
+
+	start, end, next, err := s.page(len(summaries), input.MaxResults, input.NextToken)
+	if err != nil {
+		return nil, err
+	}
+	return &sd.ListServicesOutput{Services: summaries[start:end], NextToken: next}, nil
+}
+
+func (s *Server) ListOperations(ctx context.Context, input *sd.ListOperationsInput, _ ...func(*sd.Options)) (*sd.ListOperationsOutput, error) {
+	unlock, err := s.call(ctx, "ListOperations")
+	if err != nil {
+		return nil, err
+	}
+	defer unlock()
+
+	summaries := make([]types.OperationSummary, 0, len(s.operations))
+	for _, op := range s.operations {
+		matches, err := operationMatches(&op.Operation, input.Filters)
+		if err != nil {
+			return nil, err
+		}
+		if matches {
+			summaries = append(summaries, types.OperationSummary{Id: op.Id, Status: op.Status})
+		}
+	}
+	sort.Slice(summaries, func(i, j int) bool {
+		return aws.ToString(summaries[i].Id) < aws.ToString(summaries[j].Id)
+	})
+
+	start, end, next, err := s.page(len(summaries), input.MaxResults, input.NextToken)
+	if err != nil {
+		return nil, err
+	}
+	return &sd.ListOperationsOutput{Operations: summaries[start:end], NextToken: next}, nil
+}
+
+func (s *Server) GetOperation(ctx context.Context, input *sd.GetOperationInput, _ ...func(*sd.Options)) (*sd.GetOperationOutput, error) {
+	unlock, err := s.call(ctx, "GetOperation")
+	if err != nil {
+		return nil, err
+	}
+	defer unlock()
+
+	op, found := s.operations[aws.ToString(input.OperationId)]
+	if !found {
+		return nil, &types.OperationNotFound{Message: aws.String("No operation found with ID " +
+			aws.ToString(input.OperationId))}
+	}
+	operation := op.Operation
+	operation.Targets = copyMap(op.Targets)
+	return &sd.GetOperationOutput{Operation: &operation}, nil
+}
+
+func (s *Server) CreateHttpNamespace(ctx context.Context, input *sd.CreateHttpNamespaceInput, _ ...func(*sd.Options)) (*sd.CreateHttpNamespaceOutput, error) {
+	unlock, err := s.call(ctx, "CreateHttpNamespace")
+	if err != nil {
+		return nil, err
+	}
+	defer unlock()
+
+	name := aws.ToString(input.Name)
+	if strings.TrimSpace(name) == "" {
+		return nil, &types.InvalidInput{Message: aws.String("Namespace name is invalid")}
+	}
+	if ns := s.namespaceByName(name); ns != nil {
+		return nil, &types.NamespaceAlreadyExists{
+			Message:     aws.String("Namespace " + name + " already exists"),
+			NamespaceId: ns.Id,
+		}
+	}
+
+	// the namespace ID is known when the operation succeeds
+	nsId := s.newId("ns")
+	tags := fromSdTags(input.Tags)
+	opId := s.addOperation(types.OperationTypeCreateNamespace,
+		map[string]string{string(types.OperationTargetTypeNamespace): nsId}, func() {
+			s.namespaces[nsId] = s.newNamespace(nsId, name, types.NamespaceTypeHttp)
+			s.tags[aws.ToString(s.namespaces[nsId].Arn)] = tags
+		})
+	return &sd.CreateHttpNamespaceOutput{OperationId: aws.String(opId)}, nil
+}
+
+func (s *Server) GetNamespace(ctx context.Context, input *sd.GetNamespaceInput, _ ...func(*sd.Options)) (*sd.GetNamespaceOutput, error) {
+	unlock, err := s.call(ctx, "GetNamespace")
+	if err != nil {
+		return nil, err
+	}
+	defer unlock()
+
+	ns, found := s.namespaces[aws.ToString(input.Id)]
+	if !found {
+		return nil, &types.NamespaceNotFound{Message: aws.String("No namespace found with ID " + aws.ToString(input.Id))}
+	}
+	result := *ns
+	return &sd.GetNamespaceOutput{Namespace: &result}, nil
+}
+
+func (s *Server) DeleteNamespace(ctx context.Context, input *sd.DeleteNamespaceInput, _ ...func(*sd.Options)) (*sd.DeleteNamespaceOutput, error) {
+	unlock, err := s.call(ctx, "DeleteNamespace")
+	if err != nil {
+		return nil, err
+	}
+	defer unlock()
+
+	nsId := aws.ToString(input.Id)
+	ns, found := s.namespaces[nsId]
+	if !found {
+		return nil, &types.NamespaceNotFound{Message: aws.String("No namespace found with ID " + nsId)}
+	}
+	for _, svc := range s.services {
+		if aws.ToString(svc.NamespaceId) == nsId {
+			return nil, &types.ResourceInUse{Message: aws.String("Namespace " + nsId + " has services")}
+		}
+	}
+
+	opId := s.addOperation(types.OperationTypeDeleteNamespace,
+		map[string]string{string(types.OperationTargetTypeNamespace): nsId}, func() {
+			delete(s.namespaces, nsId)
+			delete(s.tags, aws.ToString(ns.Arn))
+		})
+	return &sd.DeleteNamespaceOutput{OperationId: aws.String(opId)}, nil
+}
+
+func (s *Server) CreateService(ctx context.Context, input *sd.CreateServiceInput, _ ...func(*sd.Options)) (*sd.CreateServiceOutput, error) {
+	unlock, err := s.call(ctx, "CreateService")
+	if err != nil {
+		return nil, err
+	}
+	defer unlock()
+
+	nsId := aws.ToString(input.NamespaceId)
+	if _, found := s.namespaces[nsId]; !found {
+		return nil, &types.NamespaceNotFound{Message: aws.String("No namespace found with ID " + nsId)}
+	}
+	name := aws.ToString(input.Name)
+	if existing := s.serviceByName(nsId, name); existing != nil {
+		return nil, &types.ServiceAlreadyExists{
+			Message:   aws.String("Service " + name + " already exists"),
+			ServiceId: existing.Id,
+		}
+	}
+
+	svcId := s.addService(nsId, name, input.Description, input.DnsConfig, fromSdTags(input.Tags))
+	svc := *s.services[svcId]
+	return &sd.CreateServiceOutput{Service: &svc}, nil
+}
+
+func (s *Server) GetService(ctx context.Context, input *sd.GetServiceInput, _ ...func(*sd.Options)) (*sd.GetServiceOutput, error) {
+	unlock, err := s.call(ctx, "GetService")
+	if err != nil {
+		return nil, err
+	}
+	defer unlock()
+
+	svc, err := s.getService(aws.ToString(input.Id))
+	if err != nil {
+		return nil, err
+	}
+	result := *svc
+	result.InstanceCount = aws.Int32(int32(len(s.instances[aws.ToString(svc.Id)])))
+	return &sd.GetServiceOutput{Service: &result}, nil
+}
+
+func (s *Server) UpdateService(ctx context.Context, input *sd.UpdateServiceInput, _ ...func(*sd.Options)) (*sd.UpdateServiceOutput, error) {
+	unlock, err := s.call(ctx, "UpdateService")
+	if err != nil {
+		return nil, err
+	}
+	defer unlock()
+
+	svc, err := s.getService(aws.ToString(input.Id))
+	if err != nil {
+		return nil, err
+	}
+	if input.Service == nil {
+		return nil, &types.InvalidInput{Message: aws.String("Service change is required")}
+	}
+
+	change := *input.Service
+	opId := s.addOperation(types.OperationTypeUpdateService,
+		map[string]string{string(types.OperationTargetTypeService): aws.ToString(svc.Id)}, func() {
+			svc.Description = change.Description
+			if change.DnsConfig != nil && svc.DnsConfig != nil {
+				dnsConfig := *svc.DnsConfig
+				dnsConfig.DnsRecords = change.DnsConfig.DnsRecords
+				svc.DnsConfig = &dnsConfig
+			}
+		})
+	return &sd.UpdateServiceOutput{OperationId: aws.String(opId)}, nil
+}
+
+func (s *Server) ListTagsForResource(ctx context.Context, input *sd.ListTagsForResourceInput, _ ...func(*sd.Options)) (*sd.ListTagsForResourceOutput, error) {
+	unlock, err := s.call(ctx, "ListTagsForResource")
+	if err != nil {
+		return nil, err
+	}
+	defer unlock()
+
+	tags, found := s.tags[aws.ToString(input.ResourceARN)]
+	if !found {
+		return nil, resourceNotFound(input.ResourceARN)
+	}
+	return &sd.ListTagsForResourceOutput{Tags: toSdTags(tags)}, nil
+}
+
+func (s *Server) TagResource(ctx context.Context, input *sd.TagResourceInput, _ ...func(*sd.Options)) (*sd.TagResourceOutput, error) {
+	unlock, err := s.call(ctx, "TagResource")
+	if err != nil {
+		return nil, err
+	}
+	defer unlock()
+
+	tags, found := s.tags[aws.ToString(input.ResourceARN)]
+	if !found {
+		return nil, resourceNotFound(input.ResourceARN)
+	}
+	for key, value := range fromSdTags(input.Tags) {
+		tags[key] = value
+	}
+	return &sd.TagResourceOutput{}, nil
+}
+
+func (s *Server) UntagResource(ctx context.Context, input *sd.UntagResourceInput, _ ...func(*sd.Options)) (*sd.UntagResourceOutput, error) {
+	unlock, err := s.call(ctx, "UntagResource")
+	if err != nil {
+		return nil, err
+	}
+	defer unlock()
+
+	tags, found := s.tags[aws.ToString(input.ResourceARN)]
+	if !found {
+		return nil, resourceNotFound(input.ResourceARN)
+	}
+	for _, key := range input.TagKeys {
+		delete(tags, key)
+	}
+	return &sd.UntagResourceOutput{}, nil
+}
+
+func (s *Server) DeleteService(ctx context.Context, input *sd.DeleteServiceInput, _ ...func(*sd.Options)) (*sd.DeleteServiceOutput, error) {
+	unlock, err := s.call(ctx, "DeleteService")
+	if err != nil {
+		return nil, err
+	}
+	defer unlock()
+
+	svc, err := s.getService(aws.ToString(input.Id))
+	if err != nil {
+		return nil, err
+	}
+	svcId := aws.ToString(svc.Id)
+	if len(s.instances[svcId]) > 0 {
+		return nil, &types.ResourceInUse{Message: aws.String("Service " + svcId + " has registered instances")}
+	}
+
+	delete(s.services, svcId)
+	delete(s.instances, svcId)
+	delete(s.tags, aws.ToString(svc.Arn))
+	return &sd.DeleteServiceOutput{}, nil
+}
+
+func (s *Server) RegisterInstance(ctx context.Context, input *sd.RegisterInstanceInput, _ ...func(*sd.Options)) (*sd.RegisterInstanceOutput, error) {
+	unlock, err := s.call(ctx, "RegisterInstance")
+	if err != nil {
+		return nil, err
+	}
+	defer unlock()
+
+	svc, err := s.getService(aws.ToString(input.ServiceId))
+	if err != nil {
+		return nil, err
+	}
+
+	svcId, instId, attrs := aws.ToString(svc.Id), aws.ToString(input.InstanceId), copyMap(input.Attributes)
+	opId := s.addOperation(types.OperationTypeRegisterInstance, map[string]string{
+		string(types.OperationTargetTypeNamespace): aws.ToString(svc.NamespaceId),
+		string(types.OperationTargetTypeService):   svcId,
+		string(types.OperationTargetTypeInstance):  instId,
+	}, func() {
+		if instances, found := s.instances[svcId]; found {
+			instances[instId] = attrs
+		}
+	})
+	return &sd.RegisterInstanceOutput{OperationId: aws.String(opId)}, nil
+}
+
+func (s *Server) DeregisterInstance(ctx context.Context, input *sd.DeregisterInstanceInput, _ ...func(*sd.Options)) (*sd.DeregisterInstanceOutput, error) {
+	unlock, err := s.call(ctx, "DeregisterInstance")
+	if err != nil {
+		return nil, err
+	}
+	defer unlock()
+
+	svc, err := s.getService(aws.ToString(input.ServiceId))
+	if err != nil {
+		return nil, err
+	}
+	svcId, instId := aws.ToString(svc.Id), aws.ToString(input.InstanceId)
+	if _, found := s.instances[svcId][instId]; !found {
+		return nil, &types.InstanceNotFound{Message: aws.String("No instance found with ID " + instId)}
+	}
+
+	opId := s.addOperation(types.OperationTypeDeregisterInstance, map[string]string{
+		string(types.OperationTargetTypeNamespace): aws.ToString(svc.NamespaceId),
+		string(types.OperationTargetTypeService):   svcId,
+		string(types.OperationTargetTypeInstance):  instId,
+	}, func() {
+		delete(s.instances[svcId], instId)
+	})
+	return &sd.DeregisterInstanceOutput{OperationId: aws.String(opId)}, nil
+}
+
+func (s *Server) ListInstances(ctx context.Context, input *sd.ListInstancesInput, _ ...func(*sd.Options)) (*sd.ListInstancesOutput, error) {
+	unlock, err := s.call(ctx, "ListInstances")
+	if err != nil {
+		return nil, err
+	}
+	defer unlock()
+
+	svcId := aws.ToString(input.ServiceId)
+	if _, err = s.getService(svcId); err != nil {
+		return nil, err
+	}
+
+	instances := s.instances[svcId]
+	ids := make([]string, 0, len(instances))
+	for id := range instances {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+
+	start, end, next, err := s.page(len(ids), input.MaxResults, input.NextToken)
+	if err != nil {
+		return nil, err
+	}
+	summaries := make([]types.InstanceSummary, 0, end-start)
+	for _, id := range ids[start:end] {
+		summaries = append(summaries, types.InstanceSummary{
+			Id:         aws.String(id),
+			Attributes: copyMap(instances[id]),
+		})
+	}
+	return &sd.ListInstancesOutput{Instances: summaries, NextToken: next}, nil
+}
+
+func (s *Server) DiscoverInstances(ctx context.Context, input *sd.DiscoverInstancesInput, _ ...func(*sd.Options)) (*sd.DiscoverInstancesOutput, error) {
+	unlock, err := s.call(ctx, "DiscoverInstances")
+	if err != nil {
+		return nil, err
+	}
+	defer unlock()
+
+	nsName, svcName := aws.ToString(input.NamespaceName), aws.ToString(input.ServiceName)
+	ns := s.namespaceByName(nsName)
+	if ns == nil {
+		return nil, &types.NamespaceNotFound{Message: aws.String("No namespace found with name " + nsName)}
+	}
+	svc := s.serviceByName(aws.ToString(ns.Id), svcName)
+	if svc == nil {
+		return nil, &types.ServiceNotFound{Message: aws.String("No service found with name " + svcName)}
+	}
+
+	instances := s.instances[aws.ToString(svc.Id)]
+	ids := make([]string, 0, len(instances))
+	for id := range instances {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+	if input.MaxResults != nil && int(*input.MaxResults) < len(ids) {
+		ids = ids[:*input.MaxResults]
+	}
+
+	summaries := make([]types.HttpInstanceSummary, 0, len(ids))
+	for _, id := range ids {
+		summaries = append(summaries, types.HttpInstanceSummary{
+			InstanceId:    aws.String(id),
+			NamespaceName: aws.String(nsName),
+			ServiceName:   aws.String(svcName),
+			HealthStatus:  types.HealthStatusHealthy,
+			Attributes:    copyMap(instances[id]),
+		})
+	}
+	return &sd.DiscoverInstancesOutput{Instances: summaries}, nil
+}
+
+// call waits for the configured latency, then locks the server and completes the due operations. The returned
+// function unlocks the server.
+func (s *Server) call(ctx context.Context, action string) (unlock func(), err error) {
+	if s.opts.Latency > 0 {
+		timer := time.NewTimer(s.opts.Latency)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return nil, ctx.Err()
+		case <-timer.C:
+		}
+	}
+
+	s.mu.Lock()
+	s.calls[action]++
+	s.completeOperations()
+	return s.mu.Unlock, nil
+}
+
+// page returns the range of list results of a page, and the token of the next page.
+func (s *Server) page(total int, maxResults *int32, nextToken *string) (start int, end int, next *string, err error) {
+	if nextToken != nil {
+		if start, err = strconv.Atoi(*nextToken); err != nil || start < 0 || start > total {
+			return 0, 0, nil, &types.InvalidInput{Message: aws.String("Invalid next token")}
+		}
+	}
+
+	size := s.opts.PageSize
+	if maxResults != nil && *maxResults > 0 {
+		size = int(*maxResults)
+	}
+	end = start + size
+	if end >= total {
+		return start, total, nil, nil
+	}
+	return start, end, aws.String(strconv.Itoa(end)), nil
+}
+
+// completeOperations completes the operations which are due, in the order they were submitted.
+func (s *Server) completeOperations() {
+	now := time.Now()
+	due := make([]*operation, 0)
+	for _, op := range s.operations {
+		if op.apply != nil && !op.due.After(now) {
+			due = append(due, op)
+		}
+	}
+	sort.Slice(due, func(i, j int) bool {
+		return aws.ToTime(due[i].CreateDate).Before(aws.ToTime(due[j].CreateDate)) ||
+			aws.ToTime(due[i].CreateDate).Equal(aws.ToTime(due[j].CreateDate)) &&
+				aws.ToString(due[i].Id) < aws.ToString(due[j].Id)
+	})
+
+	for _, op := range due {
+		if errorCode, fail := s.failures[op.Type]; fail {
+			op.Status = types.OperationStatusFail
+			op.ErrorCode = aws.String(errorCode)
+			op.ErrorMessage = aws.String("injected operation failure")
+		} else {
+			op.apply()
+			op.Status = types.OperationStatusSuccess
+		}
+		op.UpdateDate = aws.Time(now)
+		op.apply = nil
+	}
+}
+
+func (s *Server) addOperation(operationType types.OperationType, targets map[string]string, apply func()) string {
+	now := time.Now()
+	opId := s.newId("op")
+	s.operations[opId] = &operation{
+		Operation: types.Operation{
+			Id:         aws.String(opId),
+			Type:       operationType,
+			Status:     types.OperationStatusSubmitted,
+			CreateDate: aws.Time(now),
+			UpdateDate: aws.Time(now),
+			Targets:    targets,
+		},
+		due:   now.Add(s.opts.OperationDelay),
+		apply: apply,
+	}
+	return opId
+}
+
+func (s *Server) newNamespace(nsId string, name string, namespaceType types.NamespaceType) *types.Namespace {
+	return &types.Namespace{
+		Arn:        aws.String(s.arn("namespace", nsId)),
+		Id:         aws.String(nsId),
+		Name:       aws.String(name),
+		Type:       namespaceType,
+		CreateDate: aws.Time(time.Now()),
+	}
+}
+
+func (s *Server) addService(nsId string, name string, description *string, dnsConfig *types.DnsConfig, tags map[string]string) string {
+	svcId := s.newId("srv")
+	s.services[svcId] = &types.Service{
+		Arn:         aws.String(s.arn("service", svcId)),
+		Id:          aws.String(svcId),
+		Name:        aws.String(name),
+		NamespaceId: aws.String(nsId),
+		Description: description,
+		DnsConfig:   dnsConfig,
+		CreateDate:  aws.Time(time.Now()),
+	}
+	s.instances[svcId] = make(map[string]map[string]string)
+	s.tags[aws.ToString(s.services[svcId].Arn)] = copyMap(tags)
+	return svcId
+}
+
+func (s *Server) getService(svcId string) (*types.Service, error) {
+	svc, found := s.services[svcId]
+	if !found {
+		return nil, &types.ServiceNotFound{Message: aws.String("No service found with ID " + svcId)}
+	}
+	return svc, nil
+}
+
+func (s *Server) namespaceByName(name string) *types.Namespace {
+	for _, ns := range s.namespaces {
+		if aws.ToString(ns.Name) == name {
+			return ns
+		}
+	}
+	return nil
+}
+
+func (s *Server) serviceByName(nsId string, name string) *types.Service {
+	for _, svc := range s.services {
+		if aws.ToString(svc.NamespaceId) == nsId && aws.ToString(svc.Name) == name {
+			return svc
+		}
+	}
+	return nil
+}
+
+func (s *Server) newId(prefix string) string {
+	s.nextId++
+	return fmt.Sprintf("%s-%017d", prefix, s.nextId)
+}
+
+func (s *Server) arn(resourceType string, id string) string {
+	return fmt.Sprintf("arn:aws:servicediscovery:%s:%s:%s/%s", s.opts.Region, s.opts.AccountId, resourceType, id)
+}
+
+func namespaceMatches(ns *types.Namespace, filters []types.NamespaceFilter) bool {
+	for _, filter := range filters {
+		if filter.Name == types.NamespaceFilterNameType && !sets.NewString(filter.Values...).Has(string(ns.Type)) {
+			return false
+		}
+	}
+	return true
+}
+
+func serviceMatches(svc *types.Service, filters []types.ServiceFilter) bool {
+	for _, filter := range filters {
+		if filter.Name == types.ServiceFilterNameNamespaceId &&
+			!sets.NewString(filter.Values...).Has(aws.ToString(svc.NamespaceId)) {
+			return false
+		}
+	}
+	return true
+}
+
+// operationMatches evaluates the operation filters, UPDATE_DATE filters take epoch milliseconds.
+func operationMatches(op *types.Operation, filters []types.OperationFilter) (bool, error) {
+	for _, filter := range filters {
+		var value string
+		switch filter.Name {
+		case types.OperationFilterNameNamespaceId:
+			value = op.Targets[string(types.OperationTargetTypeNamespace)]
+		case types.OperationFilterNameServiceId:
+			value = op.Targets[string(types.OperationTargetTypeService)]
+		case types.OperationFilterNameStatus:
+			value = string(op.Status)
+		case types.OperationFilterNameType:
+			value = string(op.Type)
+		case types.OperationFilterNameUpdateDate:
+			if len(filter.Values) != 2 {
+				return false, &types.InvalidInput{Message: aws.String("UPDATE_DATE requires a range")}
+			}
+			from, fromErr := strconv.ParseInt(filter.Values[0], 10, 64)
+			to, toErr := strconv.ParseInt(filter.Values[1], 10, 64)
+			if fromErr != nil || toErr != nil {
+				return false, &types.InvalidInput{Message: aws.String("UPDATE_DATE range is invalid")}
+			}
+			updated := aws.ToTime(op.UpdateDate).UnixNano() / int64(time.Millisecond)
+			if updated < from || updated > to {
+				return false, nil
+			}
+			continue
+		}
+		if !sets.NewString(filter.Values...).Has(value) {
+			return false, nil
+		}
+	}
+	return true, nil
+}
+
+func resourceNotFound(arn *string) error {
+	return &types.ResourceNotFoundException{Message: aws.String("No resource found with ARN " + aws.ToString(arn))}
+}
+
+func fromSdTags(sdTags []types.Tag) map[string]string {
+	tags := make(map[string]string, len(sdTags))
+	for _, tag := range sdTags {
+		tags[aws.ToString(tag.Key)] = aws.ToString(tag.Value)
+	}
+	return tags
+}
+
+func toSdTags(tags map[string]string) []types.Tag {
+	keys := make([]string, 0, len(tags))
+	for key := range tags {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	sdTags := make([]types.Tag, 0, len(keys))
+	for _, key := range keys {
+		sdTags = append(sdTags, types.Tag{Key: aws.String(key), Value: aws.String(tags[key])})
+	}
+	return sdTags
+}
+
+func copyMap(m map[string]string) map[string]string {
+	result := make(map[string]string, len(m))
+	for key, value := range m {
+		result[key] = value
+	}
+	return result
+}
@@ -0,0 +1,332 @@
+package cloudmaptest
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/aws/aws-cloud-map-mcs-controller-for-k8s/pkg/cloudmap"
+	"github.com/aws/aws-cloud-map-mcs-controller-for-k8s/pkg/model"
+	"github.com/aws/aws-cloud-map-mcs-controller-for-k8s/test"
+	"github.com/aws/aws-sdk-go-v2/aws"
+	sd "github.com/aws/aws-sdk-go-v2/service/servicediscovery"
+	"github.com/aws/aws-sdk-go-v2/service/servicediscovery/types"
+	"github.com/stretchr/testify/assert"
+	"testing"
+	"time"
+)
+
+func TestServer_Client(t *testing.T) {
+	server := NewServer(Options{})
+	sdClient := server.NewServiceDiscoveryClient(cloudmap.SdClientConfig{})
+
+	// the namespace is created along with the service
+	assert.NoError(t, sdClient.CreateService(context.TODO(), test.NsName, test.SvcName))
+	endpoints := []*model.Endpoint{test.GetTestEndpoint1(), test.GetTestEndpoint2()}
+	assert.NoError(t, sdClient.RegisterEndpoints(context.TODO(), test.NsName, test.SvcName, endpoints))
+
+	instances := server.Instances(test.NsName, test.SvcName)
+	assert.Len(t, instances, 2)
+	assert.Equal(t, test.EndptIp1, instances[test.EndptId1][model.EndpointIpv4Attr])
+
+	svc, err := sdClient.GetService(context.TODO(), test.NsName, test.SvcName)
+	assert.NoError(t, err)
+	if assert.NotNil(t, svc) {
+		assert.Len(t, svc.Endpoints, 2)
+	}
+
+	assert.NoError(t, sdClient.DeleteEndpoints(context.TODO(), test.NsName, test.SvcName, endpoints[:1]))
+	instances = server.Instances(test.NsName, test.SvcName)
+	assert.Len(t, instances, 1)
+	assert.Contains(t, instances, test.EndptId2)
+}
+
+func TestServer_ClientServiceIds(t *testing.T) {
+	server := NewServer(Options{})
+	server.AddInstance(test.NsName, "first", test.EndptId1, test.GetTestEndpoint1().GetCloudMapAttributes())
+	server.AddInstance(test.NsName, "second", test.EndptId2, test.GetTestEndpoint2().GetCloudMapAttributes())
+	sdClient := server.NewServiceDiscoveryClient(cloudmap.SdClientConfig{})
+
+	// each service is cached with its own ID
+	first, err := sdClient.GetService(context.TODO(), test.NsName, "first")
+	assert.NoError(t, err)
+	second, err := sdClient.GetService(context.TODO(), test.NsName, "second")
+	assert.NoError(t, err)
+	if assert.NotNil(t, first) && assert.NotNil(t, second) {
+		assert.NotEqual(t, first.Id, second.Id)
+		assert.Equal(t, test.EndptId1, first.Endpoints[0].Id)
+		assert.Equal(t, test.EndptId2, second.Endpoints[0].Id)
+	}
+
+	services, err := sdClient.ListServices(context.TODO(), test.NsName)
+	assert.NoError(t, err)
+	assert.Len(t, services, 2)
+}
+
+func TestServer_ClientSkipsUnchangedEndpoints(t *testing.T) {
+	server := NewServer(Options{})
+	server.AddService(test.NsName, test.SvcName)
+	sdClient := server.NewServiceDiscoveryClient(cloudmap.SdClientConfig{})
+
+	endpoints := []*model.Endpoint{test.GetTestEndpoint1()}
+	assert.NoError(t, sdClient.RegisterEndpoints(context.TODO(), test.NsName, test.SvcName, endpoints))
+	assert.NoError(t, sdClient.RegisterEndpoints(context.TODO(), test.NsName, test.SvcName, endpoints))
+	assert.Equal(t, 1, server.Calls("RegisterInstance"), "unchanged endpoint registered once")
+
+	changed := test.GetTestEndpoint1()
+	changed.Attributes["key"] = "value"
+	assert.NoError(t, sdClient.RegisterEndpoints(context.TODO(), test.NsName, test.SvcName, []*model.Endpoint{changed}))
+	assert.Equal(t, 2, server.Calls("RegisterInstance"))
+	assert.Equal(t, "value", server.Instances(test.NsName, test.SvcName)[test.EndptId1]["key"])
+}
+
+func TestServer_ClientEmptyService(t *testing.T) {
+	server := NewServer(Options{})
+	svcId := server.AddService(test.NsName, test.SvcName)
+	server.AddInstance(test.NsName, test.SvcName, test.EndptId1, test.GetTestEndpoint1().GetCloudMapAttributes())
+	sdClient := server.NewServiceDiscoveryClient(cloudmap.SdClientConfig{})
+	arn := "arn:aws:servicediscovery:" + DefaultRegion + ":" + DefaultAccountId + ":service/" + svcId
+
+	assert.NoError(t, sdClient.MarkServiceEmpty(context.TODO(), test.NsName, test.SvcName, true))
+	assert.Contains(t, server.Tags(arn), cloudmap.EmptySinceTag)
+	assert.NoError(t, sdClient.MarkServiceEmpty(context.TODO(), test.NsName, test.SvcName, false))
+	assert.NotContains(t, server.Tags(arn), cloudmap.EmptySinceTag)
+
+	// services with instances are not deleted
+	var inUse *types.ResourceInUse
+	assert.True(t, errors.As(sdClient.DeleteService(context.TODO(), test.NsName, test.SvcName), &inUse))
+
+	assert.NoError(t, sdClient.DeleteEndpoints(context.TODO(), test.NsName, test.SvcName,
+		[]*model.Endpoint{test.GetTestEndpoint1()}))
+	assert.NoError(t, sdClient.DeleteService(context.TODO(), test.NsName, test.SvcName))
+	svc, err := sdClient.GetService(context.TODO(), test.NsName, test.SvcName)
+	assert.NoError(t, err)
+	assert.Nil(t, svc)
+}
+
+func TestServer_ClientEmptyNamespaces(t *testing.T) {
+	server := NewServer(Options{})
+	// the namespace of another tool is never deleted
+	server.AddNamespace("untagged", types.NamespaceTypeHttp)
+	sdClient := server.NewServiceDiscoveryClient(cloudmap.SdClientConfig{ClusterId: test.ClusterId})
+
+	assert.NoError(t, sdClient.CreateService(context.TODO(), test.NsName, test.SvcName))
+	nsId := server.AddNamespace(test.NsName, types.NamespaceTypeHttp)
+	arn := "arn:aws:servicediscovery:" + DefaultRegion + ":" + DefaultAccountId + ":namespace/" + nsId
+
+	// namespaces with services are not marked
+	deleted, err := sdClient.DeleteEmptyNamespaces(context.TODO(), 0)
+	assert.NoError(t, err)
+	assert.Empty(t, deleted)
+	assert.NotContains(t, server.Tags(arn), cloudmap.EmptySinceTag)
+
+	// the empty namespace is marked, and deleted once the grace period elapsed
+	assert.NoError(t, sdClient.DeleteService(context.TODO(), test.NsName, test.SvcName))
+	deleted, err = sdClient.DeleteEmptyNamespaces(context.TODO(), time.Hour)
+	assert.NoError(t, err)
+	assert.Empty(t, deleted)
+	assert.Contains(t, server.Tags(arn), cloudmap.EmptySinceTag)
+	deleted, err = sdClient.DeleteEmptyNamespaces(context.TODO(), time.Hour)
+	assert.NoError(t, err)
+	assert.Empty(t, deleted, "the grace period hasn't elapsed")
+	deleted, err = sdClient.DeleteEmptyNamespaces(context.TODO(), 0)
+	assert.NoError(t, err)
+	assert.Equal(t, []string{test.NsName}, deleted)
+	assert.Equal(t, 1, server.Calls("DeleteNamespace"))
+
+	// the namespace is created again along with a service
+	assert.NoError(t, sdClient.CreateService(context.TODO(), test.NsName, test.SvcName))
+	assert.NotEqual(t, nsId, server.AddNamespace(test.NsName, types.NamespaceTypeHttp))
+}
+
+func TestServer_OperationDelay(t *testing.T) {
+	server := NewServer(Options{OperationDelay: time.Hour})
+	svcId := server.AddService(test.NsName, test.SvcName)
+
+	output, err := server.RegisterInstance(context.TODO(), &sd.RegisterInstanceInput{
+		ServiceId:  aws.String(svcId),
+		InstanceId: aws.String(test.EndptId1),
+		Attributes: map[string]string{model.EndpointIpv4Attr: test.EndptIp1},
+	})
+	assert.NoError(t, err)
+	assert.Empty(t, server.Instances(test.NsName, test.SvcName), "operation pending")
+
+	op, err := server.GetOperation(context.TODO(), &sd.GetOperationInput{OperationId: output.OperationId})
+	assert.NoError(t, err)
+	assert.Equal(t, types.OperationStatusSubmitted, op.Operation.Status)
+	assert.Equal(t, svcId, op.Operation.Targets[string(types.OperationTargetTypeService)])
+
+	server.CompleteOperations()
+	assert.Contains(t, server.Instances(test.NsName, test.SvcName), test.EndptId1)
+	op, err = server.GetOperation(context.TODO(), &sd.GetOperationInput{OperationId: output.OperationId})
+	assert.NoError(t, err)
+	assert.Equal(t, types.OperationStatusSuccess, op.Operation.Status)
+}
+
+func TestServer_FailOperations(t *testing.T) {
+	server := NewServer(Options{})
+	server.AddService(test.NsName, test.SvcName)
+	server.FailOperations(types.OperationTypeRegisterInstance, "InstanceLimitExceeded")
+	sdClient := server.NewServiceDiscoveryClient(cloudmap.SdClientConfig{})
+
+	err := sdClient.RegisterEndpoints(context.TODO(), test.NsName, test.SvcName,
+		[]*model.Endpoint{test.GetTestEndpoint1()})
+	var opErr *cloudmap.OperationFailureError
+	if assert.True(t, errors.As(err, &opErr)) {
+		assert.Equal(t, "InstanceLimitExceeded", opErr.Failures[0].ErrorCode)
+	}
+	assert.Empty(t, server.Instances(test.NsName, test.SvcName))
+
+	server.FailOperations(types.OperationTypeRegisterInstance, "")
+	assert.NoError(t, sdClient.RegisterEndpoints(context.TODO(), test.NsName, test.SvcName,
+		[]*model.Endpoint{test.GetTestEndpoint1()}))
+	assert.Len(t, server.Instances(test.NsName, test.SvcName), 1)
+}
+
+func TestServer_Pagination(t *testing.T) {
+	server := NewServer(Options{PageSize: 1})
+	server.AddNamespace("first", types.NamespaceTypeHttp)
+	server.AddNamespace("second", types.NamespaceTypeDnsPrivate)
+
+	names := make([]string, 0)
+	pages := sd.NewListNamespacesPaginator(server, &sd.ListNamespacesInput{})
+	for pages.HasMorePages() {
+		output, err := pages.NextPage(context.TODO())
+		if !assert.NoError(t, err) {
+			return
+		}
+		for _, ns := range output.Namespaces {
+			names = append(names, aws.ToString(ns.Name))
+		}
+	}
+	assert.Equal(t, []string{"first", "second"}, names)
+	assert.Equal(t, 2, server.Calls("ListNamespaces"))
+
+	output, err := server.ListNamespaces(context.TODO(), &sd.ListNamespacesInput{Filters: []types.NamespaceFilter{
+		{Name: types.NamespaceFilterNameType, Values: []string{string(types.NamespaceTypeDnsPrivate)}},
+	}})
+	assert.NoError(t, err)
+	if assert.Len(t, output.Namespaces, 1) {
+		assert.Equal(t, "second", aws.ToString(output.Namespaces[0].Name))
+	}
+}
+
+func TestServer_Errors(t *testing.T) {
+	server := NewServer(Options{})
+	svcId := server.AddService(test.NsName, test.SvcName)
+
+	_, err := server.GetService(context.TODO(), &sd.GetServiceInput{Id: aws.String("srv-unknown")})
+	var svcNotFound *types.ServiceNotFound
+	assert.True(t, errors.As(err, &svcNotFound))
+
+	_, err = server.DeregisterInstance(context.TODO(), &sd.DeregisterInstanceInput{
+		ServiceId:  aws.String(svcId),
+		InstanceId: aws.String(test.EndptId1),
+	})
+	var instNotFound *types.InstanceNotFound
+	assert.True(t, errors.As(err, &instNotFound))
+
+	_, err = server.CreateHttpNamespace(context.TODO(), &sd.CreateHttpNamespaceInput{Name: aws.String(test.NsName)})
+	var nsExists *types.NamespaceAlreadyExists
+	assert.True(t, errors.As(err, &nsExists))
+
+	_, err = server.ListTagsForResource(context.TODO(), &sd.ListTagsForResourceInput{ResourceARN: aws.String("arn")})
+	var resourceNotFound *types.ResourceNotFoundException
+	assert.True(t, errors.As(err, &resourceNotFound))
+}
+
+func TestServer_Latency(t *testing.T) {
+	server := NewServer(Options{Latency: time.Minute})
+
+	ctx, cancel := context.WithTimeout(context.TODO(), time.Millisecond)
+	defer cancel()
+	_, err := server.ListNamespaces(ctx, &sd.ListNamespacesInput{})
+	assert.Equal(t, context.DeadlineExceeded, err)
+	assert.Zero(t, server.Calls("ListNamespaces"))
+}
+
+func TestServer_ClientSyncsInChunks(t *testing.T) {
+	server := NewServer(Options{})
+	server.AddService(test.NsName, test.SvcName)
+	sdClient := server.NewServiceDiscoveryClient(cloudmap.SdClientConfig{SyncChunkSize: 2})
+
+	endpoint3 := test.GetTestEndpoint2()
+	endpoint3.Id, endpoint3.IP = "tcp-192_168_0_3-1", "192.168.0.3"
+	endpoints := []*model.Endpoint{test.GetTestEndpoint1(), test.GetTestEndpoint2(), endpoint3}
+	progress := make([]cloudmap.SyncProgress, 0)
+	ctx := cloudmap.WithSyncProgress(context.TODO(), func(p cloudmap.SyncProgress) {
+		progress = append(progress, p)
+	})
+
+	assert.NoError(t, sdClient.RegisterEndpoints(ctx, test.NsName, test.SvcName, endpoints))
+	assert.Len(t, server.Instances(test.NsName, test.SvcName), 3)
+	assert.NoError(t, sdClient.DeleteEndpoints(ctx, test.NsName, test.SvcName, endpoints))
+	assert.Empty(t, server.Instances(test.NsName, test.SvcName))
+	assert.Equal(t, []cloudmap.SyncProgress{
+		{Action: cloudmap.AuditActionRegisterInstance, Done: 2, Total: 3},
+		{Action: cloudmap.AuditActionRegisterInstance, Done: 3, Total: 3},
+		{Action: cloudmap.AuditActionDeregisterInstance, Done: 2, Total: 3},
+		{Action: cloudmap.AuditActionDeregisterInstance, Done: 3, Total: 3},
+	}, progress)
+}
+
+func TestServer_ClientInstancePaging(t *testing.T) {
+	tests := []struct {
+		name       string
+		paging     cloudmap.InstancePaging
+		maxResults int
+		instances  int
+		discover   int
+		list       int
+	}{
+		{name: "auto pages through truncated results", paging: cloudmap.InstancePagingAuto, maxResults: 2,
+			instances: 3, discover: 1, list: 2},
+		{name: "auto keeps complete results", paging: cloudmap.InstancePagingAuto, maxResults: 5,
+			instances: 3, discover: 1},
+		{name: "list", paging: cloudmap.InstancePagingList, maxResults: 2, instances: 3, list: 2},
+		{name: "none truncates", paging: cloudmap.InstancePagingNone, maxResults: 2, instances: 2, discover: 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := NewServer(Options{PageSize: 2})
+			server.AddService(test.NsName, test.SvcName)
+			for i := 0; i < 3; i++ {
+				endpoint := test.GetTestEndpoint1()
+				endpoint.IP = fmt.Sprintf("192.168.0.%d", i+1)
+				endpoint.Id = model.EndpointIdFromIPAddressAndPort(endpoint.IP, endpoint.EndpointPort)
+				server.AddInstance(test.NsName, test.SvcName, endpoint.Id, endpoint.GetCloudMapAttributes())
+			}
+			sdClient := server.NewServiceDiscoveryClient(cloudmap.SdClientConfig{
+				DiscoverMaxResults: tt.maxResults,
+				InstancePaging:     tt.paging,
+			})
+
+			svc, err := sdClient.GetService(context.TODO(), test.NsName, test.SvcName)
+			assert.NoError(t, err)
+			assert.Len(t, svc.Endpoints, tt.instances)
+			assert.Equal(t, tt.discover, server.Calls("DiscoverInstances"))
+			assert.Equal(t, tt.list, server.Calls("ListInstances"))
+		})
+	}
+}
+
+func TestServer_ClientWarmUp(t *testing.T) {
+	server := NewServer(Options{})
+	server.AddService(test.NsName, test.SvcName)
+	server.AddInstance(test.NsName, test.SvcName, test.EndptId1, test.GetTestEndpoint1().GetCloudMapAttributes())
+	server.AddService("other-namespace", test.SvcName)
+	sdClient := server.NewServiceDiscoveryClient(cloudmap.SdClientConfig{})
+
+	summary, err := sdClient.WarmUp(context.TODO(), 2)
+	assert.NoError(t, err)
+	assert.Equal(t, 2, summary.Namespaces)
+	assert.Equal(t, 2, summary.Services)
+	assert.Equal(t, 2, summary.Endpoints)
+
+	// the reconciles are served from the cache
+	calls := server.Calls("ListServices") + server.Calls("DiscoverInstances")
+	svc, err := sdClient.GetService(context.TODO(), test.NsName, test.SvcName)
+	assert.NoError(t, err)
+	assert.Len(t, svc.Endpoints, 1)
+	assert.Equal(t, 1, server.Calls("ListNamespaces"))
+	assert.Equal(t, calls, server.Calls("ListServices")+server.Calls("DiscoverInstances"))
+}
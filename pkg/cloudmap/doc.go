@@ -0,0 +1,16 @@
+// Package cloudmap syncs Kubernetes services with AWS Cloud Map. It is the Cloud Map client of the controller, and is
+// supported as a Go library for tooling reusing the sync logic, e.g. custom operators and CLIs.
+//
+// ServiceDiscoveryClient, created by NewServiceDiscoveryClient, looks up and registers the services and endpoints of
+// the model package. It caches the Cloud Map resources, polls the operations of registrations and de-registrations,
+// tags the resources it creates with their owning cluster, and records the mutations to an optional AuditLogger. The
+// in-memory Cloud Map of the cloudmaptest package replaces the AWS API in tests, see SdClientConfig.AwsFacade.
+//
+//	client := cloudmap.NewServiceDiscoveryClient(&awsCfg, &cloudmap.SdClientConfig{ClusterId: "cluster-1"})
+//	svc, err := client.GetService(ctx, "namespace", "service")
+//
+// The exported API follows the semantic versioning of the module: ServiceDiscoveryClient, ServiceDiscoveryApi,
+// ServiceDiscoveryClientCache, the client settings and the errors only change compatibly within a major version.
+// FaultConfig is meant for testing, and the operation collectors and pollers are building blocks of the client whose
+// API may change in minor versions.
+package cloudmap
@@ -0,0 +1,69 @@
+package cloudmap
+
+import (
+	"errors"
+	"fmt"
+	"github.com/aws/aws-cloud-map-mcs-controller-for-k8s/pkg/common"
+	awshttp "github.com/aws/aws-sdk-go-v2/aws/transport/http"
+	"github.com/aws/aws-sdk-go-v2/service/servicediscovery/types"
+	"github.com/aws/smithy-go"
+)
+
+// InstanceFailure describes an instance whose operation could not be created, e.g. because the request was throttled.
+type InstanceFailure struct {
+	InstanceId string
+	Err        error
+}
+
+// InstanceFailureError is returned when the operations of one or more instances could not be created.
+type InstanceFailureError struct {
+	OperationType types.OperationType
+	Failures      []InstanceFailure
+}
+
+func (e *InstanceFailureError) Error() string {
+	return fmt.Sprintf("failed to create %s operations of %d instances", e.OperationType, len(e.Failures))
+}
+
+// Unwrap returns the error of the first failure, so the class of the failures, e.g. throttling, can be tested.
+func (e *InstanceFailureError) Unwrap() error {
+	if len(e.Failures) == 0 {
+		return nil
+	}
+	return e.Failures[0].Err
+}
+
+// ErrorCode returns the AWS error code of the error, Unknown if it is not an AWS error.
+func ErrorCode(err error) string {
+	var apiErr smithy.APIError
+	if errors.As(err, &apiErr) {
+		return apiErr.ErrorCode()
+	}
+	return unknownOperationErrorCode
+}
+
+// logAwsError logs an error with the AWS request ID and error code of the failed request as structured fields,
+// so failures can be correlated with CloudTrail and AWS support cases.
+func logAwsError(log common.Logger, err error, msg string, keysAndValues ...interface{}) {
+	log.Error(err, msg, append(keysAndValues, awsErrorFields(err)...)...)
+}
+
+// awsErrorFields extracts structured log fields identifying a failed AWS request from an SDK error.
+func awsErrorFields(err error) (fields []interface{}) {
+	var respErr *awshttp.ResponseError
+	if errors.As(err, &respErr) {
+		fields = append(fields, "awsRequestId", respErr.ServiceRequestID())
+	}
+
+	var apiErr smithy.APIError
+	if errors.As(err, &apiErr) {
+		fields = append(fields, "awsErrorCode", apiErr.ErrorCode())
+	}
+
+	var opErr *smithy.OperationError
+	if errors.As(err, &opErr) {
+		fields = append(fields, "awsOperation", opErr.Operation())
+	}
+
+	return fields
+}
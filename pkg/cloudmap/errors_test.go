@@ -0,0 +1,31 @@
+package cloudmap
+
+import (
+	"errors"
+	awshttp "github.com/aws/aws-sdk-go-v2/aws/transport/http"
+	"github.com/aws/smithy-go"
+	smithyhttp "github.com/aws/smithy-go/transport/http"
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func TestAwsErrorFields(t *testing.T) {
+	err := &smithy.OperationError{
+		ServiceID:     "ServiceDiscovery",
+		OperationName: "RegisterInstance",
+		Err: &awshttp.ResponseError{
+			ResponseError: &smithyhttp.ResponseError{
+				Err: &smithy.GenericAPIError{Code: "ThrottlingException", Message: "Rate exceeded"},
+			},
+			RequestID: "request-id",
+		},
+	}
+
+	assert.Equal(t,
+		[]interface{}{"awsRequestId", "request-id", "awsErrorCode", "ThrottlingException", "awsOperation", "RegisterInstance"},
+		awsErrorFields(err))
+}
+
+func TestAwsErrorFields_NonAwsError(t *testing.T) {
+	assert.Empty(t, awsErrorFields(errors.New("error")))
+}
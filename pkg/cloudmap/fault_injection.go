@@ -0,0 +1,275 @@
+package cloudmap
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"github.com/aws/aws-cloud-map-mcs-controller-for-k8s/pkg/common"
+	"github.com/aws/aws-cloud-map-mcs-controller-for-k8s/pkg/model"
+	"github.com/aws/aws-sdk-go-v2/service/servicediscovery/types"
+	"github.com/aws/smithy-go"
+	"math/rand"
+	"sync"
+	"time"
+)
+
+// Error codes of injected faults
+const (
+	InjectedThrottlingCode = "ThrottlingException"
+	InjectedErrorCode      = "InternalFailure"
+)
+
+// FaultConfig configures the faults a fault injecting Cloud Map API injects into calls, to test how the controller
+// copes with a slow, throttling or failing Cloud Map. No faults are injected with the zero value.
+type FaultConfig struct {
+	// Latency is added to every call
+	Latency time.Duration
+	// LatencyJitter is the maximum random latency added to every call on top of Latency
+	LatencyJitter time.Duration
+	// ThrottleRate is the fraction of calls failing with a throttling error, between 0 and 1
+	ThrottleRate float64
+	// ErrorRate is the fraction of calls failing with a server error, between 0 and 1
+	ErrorRate float64
+	// Seed seeds the random faults, a time based seed is used if 0
+	Seed int64
+}
+
+// BindFlags registers the fault settings as command line flags.
+func (c *FaultConfig) BindFlags(fs *flag.FlagSet) {
+	fs.DurationVar(&c.Latency, "chaos-latency", c.Latency,
+		"Chaos mode: latency added to every Cloud Map API call. For testing only.")
+	fs.DurationVar(&c.LatencyJitter, "chaos-latency-jitter", c.LatencyJitter,
+		"Chaos mode: maximum random latency added to every Cloud Map API call on top of --chaos-latency. "+
+			"For testing only.")
+	fs.Float64Var(&c.ThrottleRate, "chaos-throttle-rate", c.ThrottleRate,
+		"Chaos mode: fraction of Cloud Map API calls failing with a throttling error, between 0 and 1. "+
+			"For testing only.")
+	fs.Float64Var(&c.ErrorRate, "chaos-error-rate", c.ErrorRate,
+		"Chaos mode: fraction of Cloud Map API calls failing with a server error, between 0 and 1. For testing only.")
+	fs.Int64Var(&c.Seed, "chaos-seed", c.Seed,
+		"Chaos mode: seed of the random faults, a time based seed is used if 0.")
+}
+
+// Validate returns an error if a fault setting is out of range.
+func (c *FaultConfig) Validate() error {
+	if c.Latency < 0 || c.LatencyJitter < 0 {
+		return errors.New("injected latency must not be negative")
+	}
+	if c.ThrottleRate < 0 || c.ErrorRate < 0 || c.ThrottleRate+c.ErrorRate > 1 {
+		return errors.New("injected throttle and error rates must be between 0 and 1, and must not add up to more than 1")
+	}
+	return nil
+}
+
+// Enabled returns true if faults are injected.
+func (c *FaultConfig) Enabled() bool {
+	return c != nil && (c.Latency > 0 || c.LatencyJitter > 0 || c.ThrottleRate > 0 || c.ErrorRate > 0)
+}
+
+// faultInjectingApi decorates a ServiceDiscoveryApi, delaying calls and failing a fraction of them before they reach
+// the decorated API.
+type faultInjectingApi struct {
+	api    ServiceDiscoveryApi
+	config FaultConfig
+	log    common.Logger
+
+	mu   sync.Mutex
+	rand *rand.Rand
+}
+
+// NewFaultInjectingApi returns a ServiceDiscoveryApi injecting the configured faults into the calls of the given API.
+// Failed calls are not passed on, so they never modify Cloud Map.
+func NewFaultInjectingApi(api ServiceDiscoveryApi, config FaultConfig) ServiceDiscoveryApi {
+	return newFaultInjectingApi(common.NewLogger("cloudmap", "chaos"), api, config)
+}
+
+func newFaultInjectingApi(log common.Logger, api ServiceDiscoveryApi, config FaultConfig) *faultInjectingApi {
+	seed := config.Seed
+	if seed == 0 {
+		seed = time.Now().UnixNano()
+	}
+	return &faultInjectingApi{
+		api:    api,
+		config: config,
+		log:    log,
+		rand:   rand.New(rand.NewSource(seed)),
+	}
+}
+
+// inject delays the call and returns the injected error, if any.
+func (f *faultInjectingApi) inject(ctx context.Context, action string) error {
+	f.mu.Lock()
+	delay := f.config.Latency
+	if f.config.LatencyJitter > 0 {
+		delay += time.Duration(f.rand.Int63n(int64(f.config.LatencyJitter) + 1))
+	}
+	roll := f.rand.Float64()
+	f.mu.Unlock()
+
+	if delay > 0 {
+		timer := time.NewTimer(delay)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return ctx.Err()
+		case <-timer.C:
+		}
+	}
+
+	switch {
+	case roll < f.config.ThrottleRate:
+		f.log.Debug("injecting throttling error", "action", action)
+		return &smithy.GenericAPIError{Code: InjectedThrottlingCode, Message: "Rate exceeded (injected)"}
+	case roll < f.config.ThrottleRate+f.config.ErrorRate:
+		f.log.Debug("injecting server error", "action", action)
+		return &smithy.GenericAPIError{Code: InjectedErrorCode, Message: "injected failure", Fault: smithy.FaultServer}
+	}
+	return nil
+}
+
+func (f *faultInjectingApi) ListNamespaces(ctx context.Context) ([]*model.Namespace, error) {
+	if err := f.inject(ctx, "ListNamespaces"); err != nil {
+		return nil, err
+	}
+	return f.api.ListNamespaces(ctx)
+}
+
+func (f *faultInjectingApi) ListServices(ctx context.Context, namespaceId string) ([]*model.Resource, error) {
+	if err := f.inject(ctx, "ListServices"); err != nil {
+		return nil, err
+	}
+	return f.api.ListServices(ctx, namespaceId)
+}
+
+func (f *faultInjectingApi) DiscoverInstances(ctx context.Context, nsName string, svcName string) ([]types.HttpInstanceSummary, error) {
+	if err := f.inject(ctx, "DiscoverInstances"); err != nil {
+		return nil, err
+	}
+	return f.api.DiscoverInstances(ctx, nsName, svcName)
+}
+
+func (f *faultInjectingApi) ListInstances(ctx context.Context, nsName string, svcName string, svcId string) ([]types.HttpInstanceSummary, error) {
+	if err := f.inject(ctx, "ListInstances"); err != nil {
+		return nil, err
+	}
+	return f.api.ListInstances(ctx, nsName, svcName, svcId)
+}
+
+func (f *faultInjectingApi) ListInstancePages(ctx context.Context, nsName string, svcName string, svcId string, page func(insts []types.HttpInstanceSummary) error) error {
+	if err := f.inject(ctx, "ListInstances"); err != nil {
+		return err
+	}
+	return f.api.ListInstancePages(ctx, nsName, svcName, svcId, page)
+}
+
+func (f *faultInjectingApi) ListOperations(ctx context.Context, opFilters []types.OperationFilter) (map[string]types.OperationStatus, error) {
+	if err := f.inject(ctx, "ListOperations"); err != nil {
+		return nil, err
+	}
+	return f.api.ListOperations(ctx, opFilters)
+}
+
+func (f *faultInjectingApi) GetOperation(ctx context.Context, operationId string) (*types.Operation, error) {
+	if err := f.inject(ctx, "GetOperation"); err != nil {
+		return nil, err
+	}
+	return f.api.GetOperation(ctx, operationId)
+}
+
+func (f *faultInjectingApi) CreateHttpNamespace(ctx context.Context, namespaceName string) (string, error) {
+	if err := f.inject(ctx, "CreateHttpNamespace"); err != nil {
+		return "", err
+	}
+	return f.api.CreateHttpNamespace(ctx, namespaceName)
+}
+
+func (f *faultInjectingApi) CreateService(ctx context.Context, namespace model.Namespace, serviceName string) (string, error) {
+	if err := f.inject(ctx, "CreateService"); err != nil {
+		return "", err
+	}
+	return f.api.CreateService(ctx, namespace, serviceName)
+}
+
+func (f *faultInjectingApi) GetNamespace(ctx context.Context, namespaceId string) (*types.Namespace, error) {
+	if err := f.inject(ctx, "GetNamespace"); err != nil {
+		return nil, err
+	}
+	return f.api.GetNamespace(ctx, namespaceId)
+}
+
+func (f *faultInjectingApi) DeleteNamespace(ctx context.Context, namespaceId string) (string, error) {
+	if err := f.inject(ctx, "DeleteNamespace"); err != nil {
+		return "", err
+	}
+	return f.api.DeleteNamespace(ctx, namespaceId)
+}
+
+func (f *faultInjectingApi) GetService(ctx context.Context, serviceId string) (*types.Service, error) {
+	if err := f.inject(ctx, "GetService"); err != nil {
+		return nil, err
+	}
+	return f.api.GetService(ctx, serviceId)
+}
+
+func (f *faultInjectingApi) UpdateServiceDescription(ctx context.Context, service *types.Service, description string) (string, error) {
+	if err := f.inject(ctx, "UpdateService"); err != nil {
+		return "", err
+	}
+	return f.api.UpdateServiceDescription(ctx, service, description)
+}
+
+func (f *faultInjectingApi) UpdateServiceDnsRecords(ctx context.Context, service *types.Service, dnsRecords []types.DnsRecord) (string, error) {
+	if err := f.inject(ctx, "UpdateService"); err != nil {
+		return "", err
+	}
+	return f.api.UpdateServiceDnsRecords(ctx, service, dnsRecords)
+}
+
+func (f *faultInjectingApi) ListTagsForResource(ctx context.Context, resourceArn string) (map[string]string, error) {
+	if err := f.inject(ctx, "ListTagsForResource"); err != nil {
+		return nil, err
+	}
+	return f.api.ListTagsForResource(ctx, resourceArn)
+}
+
+func (f *faultInjectingApi) TagResource(ctx context.Context, resourceArn string, tags map[string]string) error {
+	if err := f.inject(ctx, "TagResource"); err != nil {
+		return err
+	}
+	return f.api.TagResource(ctx, resourceArn, tags)
+}
+
+func (f *faultInjectingApi) UntagResource(ctx context.Context, resourceArn string, tagKeys []string) error {
+	if err := f.inject(ctx, "UntagResource"); err != nil {
+		return err
+	}
+	return f.api.UntagResource(ctx, resourceArn, tagKeys)
+}
+
+func (f *faultInjectingApi) DeleteService(ctx context.Context, serviceId string) error {
+	if err := f.inject(ctx, "DeleteService"); err != nil {
+		return err
+	}
+	return f.api.DeleteService(ctx, serviceId)
+}
+
+func (f *faultInjectingApi) RegisterInstance(ctx context.Context, serviceId string, instanceId string, instanceAttrs map[string]string) (string, error) {
+	if err := f.inject(ctx, "RegisterInstance"); err != nil {
+		return "", err
+	}
+	return f.api.RegisterInstance(ctx, serviceId, instanceId, instanceAttrs)
+}
+
+func (f *faultInjectingApi) DeregisterInstance(ctx context.Context, serviceId string, instanceId string) (string, error) {
+	if err := f.inject(ctx, "DeregisterInstance"); err != nil {
+		return "", err
+	}
+	return f.api.DeregisterInstance(ctx, serviceId, instanceId)
+}
+
+func (f *faultInjectingApi) PollNamespaceOperation(ctx context.Context, operationId string) (string, error) {
+	if err := f.inject(ctx, "GetOperation"); err != nil {
+		return "", err
+	}
+	return f.api.PollNamespaceOperation(ctx, operationId)
+}
@@ -0,0 +1,109 @@
+package cloudmap
+
+import (
+	"context"
+	"errors"
+	"github.com/aws/aws-cloud-map-mcs-controller-for-k8s/mocks/pkg/cloudmap"
+	"github.com/aws/aws-cloud-map-mcs-controller-for-k8s/test"
+	"github.com/aws/smithy-go"
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/assert"
+	"testing"
+	"time"
+)
+
+func TestFaultConfig_Validate(t *testing.T) {
+	assert.NoError(t, (&FaultConfig{}).Validate())
+	assert.NoError(t, (&FaultConfig{Latency: time.Second, ThrottleRate: 0.5, ErrorRate: 0.5}).Validate())
+	assert.Error(t, (&FaultConfig{LatencyJitter: -time.Second}).Validate())
+	assert.Error(t, (&FaultConfig{ThrottleRate: -0.1}).Validate())
+	assert.Error(t, (&FaultConfig{ThrottleRate: 0.6, ErrorRate: 0.6}).Validate())
+}
+
+func TestFaultConfig_Enabled(t *testing.T) {
+	var config *FaultConfig
+	assert.False(t, config.Enabled(), "nil")
+	assert.False(t, (&FaultConfig{Seed: 1}).Enabled(), "no faults")
+	assert.True(t, (&FaultConfig{Latency: time.Millisecond}).Enabled(), "latency")
+	assert.True(t, (&FaultConfig{ErrorRate: 0.1}).Enabled(), "errors")
+}
+
+func TestFaultInjectingApi_NoFaults(t *testing.T) {
+	mockController := gomock.NewController(t)
+	defer mockController.Finish()
+
+	mockApi := cloudmap.NewMockServiceDiscoveryApi(mockController)
+	mockApi.EXPECT().RegisterInstance(context.TODO(), test.SvcId, test.EndptId1, map[string]string{}).
+		Return(test.OpId1, nil)
+
+	api := NewFaultInjectingApi(mockApi, FaultConfig{Seed: 1})
+	opId, err := api.RegisterInstance(context.TODO(), test.SvcId, test.EndptId1, map[string]string{})
+	assert.NoError(t, err)
+	assert.Equal(t, test.OpId1, opId)
+}
+
+func TestFaultInjectingApi_Throttling(t *testing.T) {
+	mockController := gomock.NewController(t)
+	defer mockController.Finish()
+
+	// throttled calls are not passed on
+	mockApi := cloudmap.NewMockServiceDiscoveryApi(mockController)
+
+	api := NewFaultInjectingApi(mockApi, FaultConfig{ThrottleRate: 1, Seed: 1})
+	_, err := api.CreateService(context.TODO(), *test.GetTestHttpNamespace(), test.SvcName)
+	assert.True(t, IsThrottlingError(err))
+}
+
+func TestFaultInjectingApi_Errors(t *testing.T) {
+	mockController := gomock.NewController(t)
+	defer mockController.Finish()
+
+	mockApi := cloudmap.NewMockServiceDiscoveryApi(mockController)
+
+	api := NewFaultInjectingApi(mockApi, FaultConfig{ErrorRate: 1, Seed: 1})
+	err := api.TagResource(context.TODO(), "arn", map[string]string{})
+	var apiErr smithy.APIError
+	if assert.True(t, errors.As(err, &apiErr)) {
+		assert.Equal(t, InjectedErrorCode, apiErr.ErrorCode())
+		assert.Equal(t, smithy.FaultServer, apiErr.ErrorFault())
+	}
+	assert.False(t, IsThrottlingError(err))
+}
+
+func TestFaultInjectingApi_ErrorRate(t *testing.T) {
+	mockController := gomock.NewController(t)
+	defer mockController.Finish()
+
+	mockApi := cloudmap.NewMockServiceDiscoveryApi(mockController)
+	mockApi.EXPECT().ListNamespaces(gomock.Any()).Return(nil, nil).AnyTimes()
+
+	api := NewFaultInjectingApi(mockApi, FaultConfig{ErrorRate: 0.25, Seed: 1})
+	failed := 0
+	for i := 0; i < 1000; i++ {
+		if _, err := api.ListNamespaces(context.TODO()); err != nil {
+			failed++
+		}
+	}
+	assert.InDelta(t, 250, failed, 50)
+}
+
+func TestFaultInjectingApi_Latency(t *testing.T) {
+	mockController := gomock.NewController(t)
+	defer mockController.Finish()
+
+	mockApi := cloudmap.NewMockServiceDiscoveryApi(mockController)
+	mockApi.EXPECT().GetService(gomock.Any(), test.SvcId).Return(nil, nil)
+
+	api := NewFaultInjectingApi(mockApi, FaultConfig{Latency: 20 * time.Millisecond, Seed: 1})
+	start := time.Now()
+	_, err := api.GetService(context.TODO(), test.SvcId)
+	assert.NoError(t, err)
+	assert.GreaterOrEqual(t, int64(time.Since(start)), int64(20*time.Millisecond))
+
+	// the latency is abandoned with the context, and the call is not passed on
+	ctx, cancel := context.WithTimeout(context.TODO(), time.Millisecond)
+	defer cancel()
+	api = NewFaultInjectingApi(mockApi, FaultConfig{Latency: time.Minute, Seed: 1})
+	_, err = api.GetService(ctx, test.SvcId)
+	assert.Equal(t, context.DeadlineExceeded, err)
+}
@@ -0,0 +1,46 @@
+package cloudmap
+
+import (
+	"fmt"
+)
+
+const (
+	// MaxDiscoverMaxResults is the largest MaxResults accepted by DiscoverInstances, which has no further pages
+	MaxDiscoverMaxResults = 1000
+)
+
+// InstancePaging selects how the instances of a service are listed. DiscoverInstances returns at most
+// MaxDiscoverMaxResults instances without a token for further pages, ListInstances pages through all instances of a
+// service at the cost of one request per page.
+type InstancePaging string
+
+const (
+	// InstancePagingAuto lists the instances with DiscoverInstances, and pages through them with ListInstances if
+	// DiscoverInstances returned as many instances as requested, as the response may be truncated
+	InstancePagingAuto InstancePaging = "auto"
+	// InstancePagingList always pages through the instances with ListInstances
+	InstancePagingList InstancePaging = "list"
+	// InstancePagingNone only lists the instances with DiscoverInstances, the instances of larger services are
+	// truncated
+	InstancePagingNone InstancePaging = "none"
+)
+
+// Validate returns an error if the paging strategy is unknown, the empty strategy is InstancePagingAuto.
+func (p InstancePaging) Validate() error {
+	switch p {
+	case "", InstancePagingAuto, InstancePagingList, InstancePagingNone:
+		return nil
+	}
+	return fmt.Errorf("unknown instance paging %q, expected one of %s, %s, %s",
+		p, InstancePagingAuto, InstancePagingList, InstancePagingNone)
+}
+
+// ValidateDiscoverMaxResults returns an error if DiscoverInstances doesn't accept the MaxResults, 0 selects
+// MaxDiscoverMaxResults.
+func ValidateDiscoverMaxResults(maxResults int) error {
+	if maxResults < 0 || maxResults > MaxDiscoverMaxResults {
+		return fmt.Errorf("invalid DiscoverInstances max results %d, expected at most %d",
+			maxResults, MaxDiscoverMaxResults)
+	}
+	return nil
+}
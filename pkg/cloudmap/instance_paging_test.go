@@ -0,0 +1,20 @@
+package cloudmap
+
+import (
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func TestInstancePaging_Validate(t *testing.T) {
+	for _, paging := range []InstancePaging{"", InstancePagingAuto, InstancePagingList, InstancePagingNone} {
+		assert.NoError(t, paging.Validate(), paging)
+	}
+	assert.Error(t, InstancePaging("all").Validate())
+}
+
+func TestValidateDiscoverMaxResults(t *testing.T) {
+	assert.NoError(t, ValidateDiscoverMaxResults(0))
+	assert.NoError(t, ValidateDiscoverMaxResults(MaxDiscoverMaxResults))
+	assert.Error(t, ValidateDiscoverMaxResults(-1))
+	assert.Error(t, ValidateDiscoverMaxResults(MaxDiscoverMaxResults+1))
+}
@@ -0,0 +1,71 @@
+package cloudmap
+
+import (
+	"context"
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/credentials/stscreds"
+	"github.com/aws/aws-sdk-go-v2/service/sts"
+	"sync"
+)
+
+// NamespaceSessionTag is the session tag identifying the Kubernetes namespace of assumed namespace roles, which the
+// trust policies of the roles may check
+const NamespaceSessionTag = reservedTagPrefix + "namespace"
+
+// NamespaceRole is the IAM role assumed for the Cloud Map operations of a Kubernetes namespace.
+type NamespaceRole struct {
+	Namespace string
+	RoleArn   string
+}
+
+type namespaceRoleKey struct{}
+
+// WithNamespaceRole returns a context which calls Cloud Map with the credentials of the IAM role of the namespace,
+// instead of the credentials of the controller.
+func WithNamespaceRole(ctx context.Context, namespace string, roleArn string) context.Context {
+	return context.WithValue(ctx, namespaceRoleKey{}, NamespaceRole{Namespace: namespace, RoleArn: roleArn})
+}
+
+// NamespaceRoleFromContext returns the namespace role of the context, and false if not set.
+func NamespaceRoleFromContext(ctx context.Context) (NamespaceRole, bool) {
+	role, ok := ctx.Value(namespaceRoleKey{}).(NamespaceRole)
+	return role, ok && role.RoleArn != ""
+}
+
+// namespaceRoleProvider retrieves the credentials of the namespace role of the request context, assumed with the
+// credentials of the controller, and the credentials of the controller for requests without a namespace role. The
+// credentials of each role are cached until they expire.
+type namespaceRoleProvider struct {
+	base   aws.CredentialsProvider
+	client *sts.Client
+	config *AwsConfig
+
+	mu    sync.Mutex
+	roles map[NamespaceRole]aws.CredentialsProvider
+}
+
+func (p *namespaceRoleProvider) Retrieve(ctx context.Context) (aws.Credentials, error) {
+	role, ok := NamespaceRoleFromContext(ctx)
+	if !ok {
+		return p.base.Retrieve(ctx)
+	}
+	return p.provider(role).Retrieve(ctx)
+}
+
+func (p *namespaceRoleProvider) provider(role NamespaceRole) aws.CredentialsProvider {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if provider, found := p.roles[role]; found {
+		return provider
+	}
+
+	tags := p.config.sessionTags()
+	tags[NamespaceSessionTag] = role.Namespace
+	provider := &aws.CredentialsCache{Provider: stscreds.NewAssumeRoleProvider(p.client, role.RoleArn,
+		func(o *stscreds.AssumeRoleOptions) {
+			o.RoleSessionName = p.config.roleSessionName()
+			o.Tags = sessionTags(tags)
+		})}
+	p.roles[role] = provider
+	return provider
+}
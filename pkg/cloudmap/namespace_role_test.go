@@ -0,0 +1,58 @@
+package cloudmap
+
+import (
+	"context"
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/sts"
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func TestNamespaceRoleFromContext(t *testing.T) {
+	_, found := NamespaceRoleFromContext(context.TODO())
+	assert.False(t, found)
+
+	_, found = NamespaceRoleFromContext(WithNamespaceRole(context.TODO(), "tenant-a", ""))
+	assert.False(t, found, "an empty role ARN is no role")
+
+	role, found := NamespaceRoleFromContext(WithNamespaceRole(context.TODO(), "tenant-a",
+		"arn:aws:iam::123456789012:role/tenant-a"))
+	assert.True(t, found)
+	assert.Equal(t, NamespaceRole{Namespace: "tenant-a", RoleArn: "arn:aws:iam::123456789012:role/tenant-a"}, role)
+}
+
+func TestNamespaceRoleProvider(t *testing.T) {
+	base := aws.CredentialsProviderFunc(func(context.Context) (aws.Credentials, error) {
+		return aws.Credentials{AccessKeyID: "AKIDCONTROLLER"}, nil
+	})
+	provider := &namespaceRoleProvider{
+		base:   base,
+		client: sts.New(sts.Options{Region: "us-west-2"}),
+		config: NewDefaultAwsConfig(),
+		roles:  make(map[NamespaceRole]aws.CredentialsProvider),
+	}
+
+	credentials, err := provider.Retrieve(context.TODO())
+	assert.NoError(t, err)
+	assert.Equal(t, "AKIDCONTROLLER", credentials.AccessKeyID, "requests without a role use the base credentials")
+
+	roleA := NamespaceRole{Namespace: "tenant-a", RoleArn: "arn:aws:iam::123456789012:role/tenant"}
+	roleB := NamespaceRole{Namespace: "tenant-b", RoleArn: "arn:aws:iam::123456789012:role/tenant"}
+	assert.Same(t, provider.provider(roleA), provider.provider(roleA), "the credentials of a role are cached")
+	assert.NotSame(t, provider.provider(roleA), provider.provider(roleB),
+		"namespaces sharing a role have sessions of their own")
+	assert.Len(t, provider.roles, 2)
+}
+
+func TestAwsConfig_NamespaceRoleConfig(t *testing.T) {
+	awsConfig := NewDefaultAwsConfig()
+	cfg := &aws.Config{Region: "us-west-2", Credentials: aws.AnonymousCredentials{}}
+
+	roleCfg := awsConfig.namespaceRoleConfig(cfg)
+	provider, ok := roleCfg.Credentials.(*namespaceRoleProvider)
+	assert.True(t, ok)
+	assert.Equal(t, aws.AnonymousCredentials{}, provider.base)
+	assert.Equal(t, aws.AnonymousCredentials{}, cfg.Credentials, "the config is copied")
+
+	assert.Equal(t, &aws.Config{}, awsConfig.namespaceRoleConfig(&aws.Config{}), "no credentials to assume roles with")
+}
@@ -0,0 +1,154 @@
+package cloudmap
+
+import (
+	"context"
+	"github.com/aws/aws-sdk-go-v2/service/servicediscovery/types"
+	"sync"
+	"time"
+)
+
+// Time terminal operations are cached, operations never change once they are terminal
+const defaultOperationCacheTTL = time.Minute
+
+// operationCache shares GetOperation calls and namespace operation polls between concurrent callers waiting on the
+// same operation, and caches terminal operations briefly, saving calls against the low Cloud Map operation API quota.
+// Pending operations are never cached. A nil operationCache calls through.
+type operationCache struct {
+	ttl time.Duration
+	now func() time.Time
+
+	gets  callGroup
+	polls callGroup
+
+	mu       sync.Mutex
+	terminal map[string]cachedOperation
+}
+
+type cachedOperation struct {
+	operation *types.Operation
+	expires   time.Time
+}
+
+func newOperationCache() *operationCache {
+	return &operationCache{
+		ttl:      defaultOperationCacheTTL,
+		now:      time.Now,
+		terminal: make(map[string]cachedOperation),
+	}
+}
+
+// get returns the cached terminal operation, or joins the call of a concurrent caller getting the operation, or gets
+// the operation. Shared calls run on a context detached from the callers, bounded by timeout, so a caller giving up
+// doesn't fail the callers which joined its call. Each caller stops waiting once its own context is done.
+func (c *operationCache) get(ctx context.Context, opId string, timeout time.Duration,
+	getOperation func(ctx context.Context) (*types.Operation, error)) (*types.Operation, error) {
+	if c == nil {
+		return getOperation(ctx)
+	}
+
+	if op, found := c.cached(opId); found {
+		return op, nil
+	}
+
+	result, err := c.gets.do(ctx, opId, timeout, func(ctx context.Context) (interface{}, error) {
+		op, err := getOperation(ctx)
+		if err == nil && isTerminal(op) {
+			c.store(opId, op)
+		}
+		return op, err
+	})
+	op, _ := result.(*types.Operation)
+	return op, err
+}
+
+// poll joins the poll of a concurrent caller polling the namespace operation, or polls the operation. Shared polls
+// run on a detached context like the calls of get.
+func (c *operationCache) poll(ctx context.Context, opId string, timeout time.Duration,
+	pollOperation func(ctx context.Context) (string, error)) (string, error) {
+	if c == nil {
+		return pollOperation(ctx)
+	}
+
+	result, err := c.polls.do(ctx, opId, timeout, func(ctx context.Context) (interface{}, error) {
+		return pollOperation(ctx)
+	})
+	nsId, _ := result.(string)
+	return nsId, err
+}
+
+func (c *operationCache) cached(opId string) (*types.Operation, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	entry, found := c.terminal[opId]
+	if !found || c.now().After(entry.expires) {
+		return nil, false
+	}
+	return entry.operation, true
+}
+
+func (c *operationCache) store(opId string, op *types.Operation) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	now := c.now()
+	for id, entry := range c.terminal {
+		if now.After(entry.expires) {
+			delete(c.terminal, id)
+		}
+	}
+	c.terminal[opId] = cachedOperation{operation: op, expires: now.Add(c.ttl)}
+}
+
+func isTerminal(op *types.Operation) bool {
+	return op != nil && (op.Status == types.OperationStatusSuccess || op.Status == types.OperationStatusFail)
+}
+
+// callGroup shares the result of a call between concurrent callers with the same key. Callers arriving after the call
+// returned start a new call.
+type callGroup struct {
+	mu    sync.Mutex
+	calls map[string]*groupCall
+}
+
+type groupCall struct {
+	done   chan struct{}
+	result interface{}
+	err    error
+}
+
+// do joins the call in flight with the key, or starts the call on a context detached from ctx with the given timeout.
+// It returns the error of ctx if ctx is done before the call returns, the call goes on for the other callers.
+func (g *callGroup) do(ctx context.Context, key string, timeout time.Duration,
+	fn func(ctx context.Context) (interface{}, error)) (interface{}, error) {
+	g.mu.Lock()
+	if g.calls == nil {
+		g.calls = make(map[string]*groupCall)
+	}
+	call, found := g.calls[key]
+	if !found {
+		call = &groupCall{done: make(chan struct{})}
+		g.calls[key] = call
+		go g.run(key, call, timeout, fn)
+	}
+	g.mu.Unlock()
+
+	select {
+	case <-call.done:
+		return call.result, call.err
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
+
+func (g *callGroup) run(key string, call *groupCall, timeout time.Duration,
+	fn func(ctx context.Context) (interface{}, error)) {
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+	call.result, call.err = fn(ctx)
+
+	g.mu.Lock()
+	delete(g.calls, key)
+	g.mu.Unlock()
+	close(call.done)
+}
@@ -0,0 +1,170 @@
+package cloudmap
+
+import (
+	"context"
+	"errors"
+	"github.com/aws/aws-cloud-map-mcs-controller-for-k8s/test"
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/servicediscovery/types"
+	"github.com/stretchr/testify/assert"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestOperationCache_Get(t *testing.T) {
+	now := time.Now()
+	cache := newOperationCache()
+	cache.now = func() time.Time { return now }
+
+	calls := 0
+	status := types.OperationStatusPending
+	getOperation := func(context.Context) (*types.Operation, error) {
+		calls++
+		return &types.Operation{Id: aws.String(test.OpId1), Status: status}, nil
+	}
+
+	// pending operations are not cached
+	op, err := cache.get(context.TODO(), test.OpId1, time.Minute, getOperation)
+	assert.NoError(t, err)
+	assert.Equal(t, types.OperationStatusPending, op.Status)
+	_, _ = cache.get(context.TODO(), test.OpId1, time.Minute, getOperation)
+	assert.Equal(t, 2, calls)
+
+	status = types.OperationStatusSuccess
+	_, _ = cache.get(context.TODO(), test.OpId1, time.Minute, getOperation)
+	op, err = cache.get(context.TODO(), test.OpId1, time.Minute, getOperation)
+	assert.NoError(t, err)
+	assert.Equal(t, types.OperationStatusSuccess, op.Status)
+	assert.Equal(t, 3, calls, "terminal operation cached")
+
+	now = now.Add(defaultOperationCacheTTL + time.Second)
+	_, _ = cache.get(context.TODO(), test.OpId1, time.Minute, getOperation)
+	assert.Equal(t, 4, calls, "cache entry expired")
+}
+
+func TestOperationCache_Get_Error(t *testing.T) {
+	cache := newOperationCache()
+	calls := 0
+	getOperation := func(context.Context) (*types.Operation, error) {
+		calls++
+		return nil, errors.New("error")
+	}
+
+	_, err := cache.get(context.TODO(), test.OpId1, time.Minute, getOperation)
+	assert.Error(t, err)
+	_, err = cache.get(context.TODO(), test.OpId1, time.Minute, getOperation)
+	assert.Error(t, err)
+	assert.Equal(t, 2, calls, "errors are not cached")
+}
+
+func TestOperationCache_Get_Concurrent(t *testing.T) {
+	cache := newOperationCache()
+	started := make(chan struct{})
+	release := make(chan struct{})
+	var calls int32
+	getOperation := func(context.Context) (*types.Operation, error) {
+		if atomic.AddInt32(&calls, 1) == 1 {
+			close(started)
+		}
+		<-release
+		return &types.Operation{Id: aws.String(test.OpId1), Status: types.OperationStatusPending}, nil
+	}
+
+	wg := sync.WaitGroup{}
+	results := make([]*types.Operation, 5)
+	for i := range results {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			results[i], _ = cache.get(context.TODO(), test.OpId1, time.Minute, getOperation)
+		}(i)
+		if i == 0 {
+			<-started
+		}
+	}
+
+	// let the other callers join the call in flight
+	time.Sleep(20 * time.Millisecond)
+	close(release)
+	wg.Wait()
+
+	assert.Equal(t, int32(1), atomic.LoadInt32(&calls))
+	for _, op := range results {
+		assert.Equal(t, results[0], op)
+	}
+}
+
+func TestOperationCache_Poll(t *testing.T) {
+	cache := newOperationCache()
+	nsId, err := cache.poll(context.TODO(), test.OpId1, time.Minute, func(context.Context) (string, error) {
+		return test.NsId, nil
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, test.NsId, nsId)
+}
+
+func TestOperationCache_Nil(t *testing.T) {
+	var cache *operationCache
+	op, err := cache.get(context.TODO(), test.OpId1, time.Minute, func(context.Context) (*types.Operation, error) {
+		return &types.Operation{Status: types.OperationStatusSuccess}, nil
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, types.OperationStatusSuccess, op.Status)
+
+	nsId, err := cache.poll(context.TODO(), test.OpId1, time.Minute, func(context.Context) (string, error) {
+		return test.NsId, nil
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, test.NsId, nsId)
+}
+
+func TestOperationCache_Get_CallerGivesUp(t *testing.T) {
+	cache := newOperationCache()
+	started := make(chan struct{})
+	release := make(chan struct{})
+	getOperation := func(ctx context.Context) (*types.Operation, error) {
+		close(started)
+		select {
+		case <-release:
+			return &types.Operation{Id: aws.String(test.OpId1), Status: types.OperationStatusPending}, nil
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+	}
+
+	firstCtx, cancel := context.WithCancel(context.TODO())
+	firstErr := make(chan error)
+	go func() {
+		_, err := cache.get(firstCtx, test.OpId1, time.Minute, getOperation)
+		firstErr <- err
+	}()
+	<-started
+
+	joined := make(chan *types.Operation)
+	go func() {
+		op, _ := cache.get(context.TODO(), test.OpId1, time.Minute, getOperation)
+		joined <- op
+	}()
+	// let the second caller join the call in flight
+	time.Sleep(20 * time.Millisecond)
+
+	// the caller which started the call gives up, the call goes on for the caller which joined it
+	cancel()
+	assert.Equal(t, context.Canceled, <-firstErr)
+	close(release)
+	op := <-joined
+	if assert.NotNil(t, op) {
+		assert.Equal(t, types.OperationStatusPending, op.Status)
+	}
+}
+
+func TestOperationCache_Poll_Timeout(t *testing.T) {
+	cache := newOperationCache()
+	_, err := cache.poll(context.TODO(), test.OpId1, time.Millisecond, func(ctx context.Context) (string, error) {
+		<-ctx.Done()
+		return "", ctx.Err()
+	})
+	assert.Equal(t, context.DeadlineExceeded, err)
+}
@@ -23,6 +23,7 @@ type OperationCollector interface {
 type opCollector struct {
 	log              common.Logger
 	opChan           chan opResult
+	slots            chan struct{}
 	wg               sync.WaitGroup
 	startTime        int64
 	createOpsSuccess bool
@@ -34,12 +35,26 @@ type opResult struct {
 }
 
 func NewOperationCollector() OperationCollector {
-	return &opCollector{
-		log:              common.NewLogger("cloudmap"),
+	return newOperationCollector(common.NewLogger("cloudmap"), 0)
+}
+
+// NewBoundedOperationCollector returns an operation collector calling at most maxConcurrent operation providers at
+// a time, the providers are not bounded if maxConcurrent is not positive.
+func NewBoundedOperationCollector(maxConcurrent int) OperationCollector {
+	return newOperationCollector(common.NewLogger("cloudmap"), maxConcurrent)
+}
+
+func newOperationCollector(log common.Logger, maxConcurrent int) *opCollector {
+	opColl := &opCollector{
+		log:              log,
 		opChan:           make(chan opResult),
 		startTime:        Now(),
 		createOpsSuccess: true,
 	}
+	if maxConcurrent > 0 {
+		opColl.slots = make(chan struct{}, maxConcurrent)
+	}
+	return opColl
 }
 
 func (opColl *opCollector) Add(opProvider func() (opId string, err error)) {
@@ -47,7 +62,13 @@ func (opColl *opCollector) Add(opProvider func() (opId string, err error)) {
 	go func() {
 		defer opColl.wg.Done()
 
+		if opColl.slots != nil {
+			opColl.slots <- struct{}{}
+		}
 		opId, opErr := opProvider()
+		if opColl.slots != nil {
+			<-opColl.slots
+		}
 		opColl.opChan <- opResult{opId, opErr}
 	}()
 }
@@ -63,7 +84,7 @@ func (opColl *opCollector) Collect() []string {
 
 	for op := range opColl.opChan {
 		if op.err != nil {
-			opColl.log.Info("could not create operation", "error", op.err)
+			logAwsError(opColl.log, op.err, "could not create operation")
 			opColl.createOpsSuccess = false
 			continue
 		}
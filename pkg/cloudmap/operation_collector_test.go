@@ -2,7 +2,9 @@ package cloudmap
 
 import (
 	"errors"
+	"fmt"
 	"github.com/stretchr/testify/assert"
+	"sync/atomic"
 	"testing"
 	"time"
 )
@@ -39,6 +41,31 @@ func TestOpCollector_MixedSuccess(t *testing.T) {
 	assert.Equal(t, []string{"two"}, result)
 }
 
+func TestOpCollector_Bounded(t *testing.T) {
+	oc := NewBoundedOperationCollector(2)
+	var inFlight, maxInFlight int32
+	for i := 0; i < 10; i++ {
+		opId := fmt.Sprintf("op-%d", i)
+		oc.Add(func() (string, error) {
+			current := atomic.AddInt32(&inFlight, 1)
+			for {
+				max := atomic.LoadInt32(&maxInFlight)
+				if current <= max || atomic.CompareAndSwapInt32(&maxInFlight, max, current) {
+					break
+				}
+			}
+			time.Sleep(10 * time.Millisecond)
+			atomic.AddInt32(&inFlight, -1)
+			return opId, nil
+		})
+	}
+
+	result := oc.Collect()
+	assert.True(t, oc.IsAllOperationsCreated())
+	assert.Len(t, result, 10)
+	assert.LessOrEqual(t, atomic.LoadInt32(&maxInFlight), int32(2))
+}
+
 func TestOpCollector_GetStartTime(t *testing.T) {
 	oc1 := NewOperationCollector()
 	time.Sleep(time.Second)
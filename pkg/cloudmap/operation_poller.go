@@ -4,6 +4,7 @@ import (
 	"context"
 	"errors"
 	"github.com/aws/aws-cloud-map-mcs-controller-for-k8s/pkg/common"
+	"github.com/aws/aws-cloud-map-mcs-controller-for-k8s/pkg/metrics"
 	"github.com/aws/aws-sdk-go-v2/aws"
 	"github.com/aws/aws-sdk-go-v2/service/servicediscovery/types"
 	"k8s.io/apimachinery/pkg/util/wait"
@@ -19,8 +20,31 @@ const (
 	defaultOperationPollTimeout = 5 * time.Minute
 
 	operationPollTimoutErrorMessage = "timed out while polling operations"
+
+	operationFailureErrorMessage = "operation failure"
+
+	unknownOperationErrorCode = "Unknown"
 )
 
+// OperationFailure describes a Cloud Map operation that reached FAIL status.
+type OperationFailure struct {
+	OperationId string
+	// InstanceId is the target instance of the operation, empty if unknown
+	InstanceId   string
+	ErrorCode    string
+	ErrorMessage string
+}
+
+// OperationFailureError is returned by an OperationPoller when one or more polled operations reached FAIL status.
+type OperationFailureError struct {
+	OperationType types.OperationType
+	Failures      []OperationFailure
+}
+
+func (e *OperationFailureError) Error() string {
+	return operationFailureErrorMessage
+}
+
 // OperationPoller polls a list operations for a terminal status.
 type OperationPoller interface {
 	// Poll monitors operations until they reach terminal state.
@@ -28,9 +52,10 @@ type OperationPoller interface {
 }
 
 type operationPoller struct {
-	log     common.Logger
-	sdApi   ServiceDiscoveryApi
-	timeout time.Duration
+	log      common.Logger
+	sdApi    ServiceDiscoveryApi
+	interval time.Duration
+	timeout  time.Duration
 
 	opIds  []string
 	svcId  string
@@ -38,30 +63,35 @@ type operationPoller struct {
 	start  int64
 }
 
-func newOperationPoller(sdApi ServiceDiscoveryApi, svcId string, opIds []string, startTime int64) operationPoller {
-	return operationPoller{
-		log:     common.NewLogger("cloudmap"),
-		sdApi:   sdApi,
-		timeout: defaultOperationPollTimeout,
-
-		opIds: opIds,
-		svcId: svcId,
-		start: startTime,
+func newOperationPoller(log common.Logger, sdApi ServiceDiscoveryApi, opType types.OperationType, svcId string,
+	opIds []string, startTime int64, timeouts *SdTimeoutConfig) *operationPoller {
+	return &operationPoller{
+		log:      log,
+		sdApi:    sdApi,
+		interval: timeouts.pollInterval(),
+		timeout:  timeouts.pollTimeout(),
+
+		opIds:  opIds,
+		svcId:  svcId,
+		opType: opType,
+		start:  startTime,
 	}
 }
 
-// NewRegisterInstancePoller creates a new operation poller for register instance operations.
-func NewRegisterInstancePoller(sdApi ServiceDiscoveryApi, serviceId string, opIds []string, startTime int64) OperationPoller {
-	poller := newOperationPoller(sdApi, serviceId, opIds, startTime)
-	poller.opType = types.OperationTypeRegisterInstance
-	return &poller
+// NewRegisterInstancePoller creates a new operation poller for register instance operations, the default poll
+// interval and timeout are used if timeouts is nil.
+func NewRegisterInstancePoller(sdApi ServiceDiscoveryApi, serviceId string, opIds []string, startTime int64,
+	timeouts *SdTimeoutConfig) OperationPoller {
+	return newOperationPoller(common.NewLogger("cloudmap", "poller"), sdApi, types.OperationTypeRegisterInstance,
+		serviceId, opIds, startTime, timeouts)
 }
 
-// NewDeregisterInstancePoller creates a new operation poller for de-register instance operations.
-func NewDeregisterInstancePoller(sdApi ServiceDiscoveryApi, serviceId string, opIds []string, startTime int64) OperationPoller {
-	poller := newOperationPoller(sdApi, serviceId, opIds, startTime)
-	poller.opType = types.OperationTypeDeregisterInstance
-	return &poller
+// NewDeregisterInstancePoller creates a new operation poller for de-register instance operations, the default poll
+// interval and timeout are used if timeouts is nil.
+func NewDeregisterInstancePoller(sdApi ServiceDiscoveryApi, serviceId string, opIds []string, startTime int64,
+	timeouts *SdTimeoutConfig) OperationPoller {
+	return newOperationPoller(common.NewLogger("cloudmap", "poller"), sdApi, types.OperationTypeDeregisterInstance,
+		serviceId, opIds, startTime, timeouts)
 }
 
 func (opPoller *operationPoller) Poll(ctx context.Context) (err error) {
@@ -70,12 +100,14 @@ func (opPoller *operationPoller) Poll(ctx context.Context) (err error) {
 		return nil
 	}
 
-	err = wait.Poll(defaultOperationPollInterval, opPoller.timeout, func() (done bool, err error) {
+	err = wait.Poll(opPoller.interval, opPoller.timeout, func() (done bool, err error) {
 		opPoller.log.Info("polling operations", "operations", opPoller.opIds)
 
 		sdOps, err := opPoller.sdApi.ListOperations(ctx, opPoller.buildFilters())
 
 		if err != nil {
+			logAwsError(opPoller.log, err, "failed to list operations",
+				"serviceId", opPoller.svcId, "operations", opPoller.opIds)
 			return true, err
 		}
 
@@ -94,10 +126,15 @@ func (opPoller *operationPoller) Poll(ctx context.Context) (err error) {
 		}
 
 		if len(failedOps) != 0 {
+			opErr := &OperationFailureError{OperationType: opPoller.opType}
 			for _, failedOp := range failedOps {
-				opPoller.log.Info("operation failed", "failedOp", failedOp, "reason", opPoller.getFailedOpReason(ctx, failedOp))
+				failure := opPoller.getFailedOp(ctx, failedOp)
+				opPoller.log.Error(opErr, "operation failed", "serviceId", opPoller.svcId, "operationId", failedOp,
+					"operationType", opPoller.opType, "errorCode", failure.ErrorCode, "reason", failure.ErrorMessage)
+				metrics.IncOperationFailures(string(opPoller.opType), failure.ErrorCode)
+				opErr.Failures = append(opErr.Failures, failure)
 			}
-			return true, errors.New("operation failure")
+			return true, opErr
 		}
 
 		opPoller.log.Info("operations completed successfully")
@@ -133,26 +170,41 @@ func (opPoller *operationPoller) buildFilters() []types.OperationFilter {
 		Name:      types.OperationFilterNameUpdateDate,
 		Condition: types.FilterConditionBetween,
 		Values: []string{
-			Itoa(opPoller.start),
+			itoa(opPoller.start),
 			// Add one minute to end range in case op updates while list request is in flight
-			Itoa(Now() + 60000),
+			itoa(Now() + 60000),
 		},
 	}
 
 	return []types.OperationFilter{svcFilter, statusFilter, typeFilter, timeFilter}
 }
 
-// getFailedOpReason returns operation error message, which is not available in ListOperations response
-func (opPoller *operationPoller) getFailedOpReason(ctx context.Context, opId string) string {
-	op, err := opPoller.sdApi.GetOperation(ctx, opId)
+// getFailedOp returns operation error code and message, which are not available in ListOperations response
+func (opPoller *operationPoller) getFailedOp(ctx context.Context, opId string) OperationFailure {
+	failure := OperationFailure{
+		OperationId: opId,
+		ErrorCode:   unknownOperationErrorCode,
+	}
 
+	op, err := opPoller.sdApi.GetOperation(ctx, opId)
 	if err != nil {
-		return "failed to retrieve operation failure reason"
+		logAwsError(opPoller.log, err, "failed to retrieve operation failure reason",
+			"serviceId", opPoller.svcId, "operationId", opId)
+		failure.ErrorMessage = "failed to retrieve operation failure reason"
+		return failure
 	}
 
-	return aws.ToString(op.ErrorMessage)
+	if op.ErrorCode != nil {
+		failure.ErrorCode = aws.ToString(op.ErrorCode)
+	}
+	failure.ErrorMessage = aws.ToString(op.ErrorMessage)
+	failure.InstanceId = op.Targets[string(types.OperationTargetTypeInstance)]
+
+	return failure
 }
-func Itoa(i int64) string {
+
+// itoa formats an int64, e.g. the millisecond timestamps of operation filters.
+func itoa(i int64) string {
 	return strconv.FormatInt(i, 10)
 }
 
@@ -27,13 +27,13 @@ func TestOperationPoller_HappyCases(t *testing.T) {
 	}{
 		{
 			constructor: func() OperationPoller {
-				return NewRegisterInstancePoller(sdApi, test.SvcId, []string{test.OpId1, test.OpId2}, test.OpStart)
+				return NewRegisterInstancePoller(sdApi, test.SvcId, []string{test.OpId1, test.OpId2}, test.OpStart, nil)
 			},
 			expectedOpType: types.OperationTypeRegisterInstance,
 		},
 		{
 			constructor: func() OperationPoller {
-				return NewDeregisterInstancePoller(sdApi, test.SvcId, []string{test.OpId1, test.OpId2}, test.OpStart)
+				return NewDeregisterInstancePoller(sdApi, test.SvcId, []string{test.OpId1, test.OpId2}, test.OpStart, nil)
 			},
 			expectedOpType: types.OperationTypeDeregisterInstance,
 		},
@@ -106,7 +106,7 @@ func TestOperationPoller_PollEmpty(t *testing.T) {
 
 	sdApi := cloudmap.NewMockServiceDiscoveryApi(mockController)
 
-	p := NewRegisterInstancePoller(sdApi, test.SvcId, []string{}, test.OpStart)
+	p := NewRegisterInstancePoller(sdApi, test.SvcId, []string{}, test.OpStart, nil)
 	err := p.Poll(context.TODO())
 	assert.Nil(t, err)
 }
@@ -117,7 +117,7 @@ func TestOperationPoller_PollFailure(t *testing.T) {
 
 	sdApi := cloudmap.NewMockServiceDiscoveryApi(mockController)
 
-	p := NewRegisterInstancePoller(sdApi, test.SvcId, []string{test.OpId1, test.OpId2}, test.OpStart)
+	p := NewRegisterInstancePoller(sdApi, test.SvcId, []string{test.OpId1, test.OpId2}, test.OpStart, nil)
 
 	pollErr := errors.New("error polling operations")
 
@@ -135,7 +135,7 @@ func TestOperationPoller_PollOpFailure(t *testing.T) {
 
 	sdApi := cloudmap.NewMockServiceDiscoveryApi(mockController)
 
-	p := NewRegisterInstancePoller(sdApi, test.SvcId, []string{test.OpId1, test.OpId2}, test.OpStart)
+	p := NewRegisterInstancePoller(sdApi, test.SvcId, []string{test.OpId1, test.OpId2}, test.OpStart, nil)
 
 	sdApi.EXPECT().
 		ListOperations(gomock.Any(), gomock.Any()).
@@ -146,13 +146,20 @@ func TestOperationPoller_PollOpFailure(t *testing.T) {
 			}, nil)
 
 	opErr := "operation failure message"
+	opErrCode := "ERROR_CODE"
 
 	sdApi.EXPECT().
 		GetOperation(gomock.Any(), test.OpId2).
-		Return(&types.Operation{ErrorMessage: &opErr}, nil)
+		Return(&types.Operation{ErrorCode: &opErrCode, ErrorMessage: &opErr}, nil)
 
 	err := p.Poll(context.TODO())
 	assert.Equal(t, "operation failure", err.Error())
+
+	var failureErr *OperationFailureError
+	assert.True(t, errors.As(err, &failureErr))
+	assert.Equal(t, types.OperationTypeRegisterInstance, failureErr.OperationType)
+	assert.Equal(t, []OperationFailure{{OperationId: test.OpId2, ErrorCode: opErrCode, ErrorMessage: opErr}},
+		failureErr.Failures)
 }
 
 func TestOperationPoller_PollOpFailureAndMessageFailure(t *testing.T) {
@@ -161,7 +168,7 @@ func TestOperationPoller_PollOpFailureAndMessageFailure(t *testing.T) {
 
 	sdApi := cloudmap.NewMockServiceDiscoveryApi(mockController)
 
-	p := NewRegisterInstancePoller(sdApi, test.SvcId, []string{test.OpId1, test.OpId2}, test.OpStart)
+	p := NewRegisterInstancePoller(sdApi, test.SvcId, []string{test.OpId1, test.OpId2}, test.OpStart, nil)
 
 	sdApi.EXPECT().
 		ListOperations(gomock.Any(), gomock.Any()).
@@ -177,6 +184,10 @@ func TestOperationPoller_PollOpFailureAndMessageFailure(t *testing.T) {
 
 	err := p.Poll(context.TODO())
 	assert.Equal(t, "operation failure", err.Error())
+
+	var failureErr *OperationFailureError
+	assert.True(t, errors.As(err, &failureErr))
+	assert.Equal(t, unknownOperationErrorCode, failureErr.Failures[0].ErrorCode)
 }
 
 func TestOperationPoller_PollTimeout(t *testing.T) {
@@ -186,10 +197,11 @@ func TestOperationPoller_PollTimeout(t *testing.T) {
 	sdApi := cloudmap.NewMockServiceDiscoveryApi(mockController)
 
 	p := operationPoller{
-		log:     common.NewLoggerWithLogr(testing2.TestLogger{T: t}),
-		sdApi:   sdApi,
-		timeout: 2 * time.Millisecond,
-		opIds:   []string{test.OpId1, test.OpId2},
+		log:      common.NewLoggerWithLogr(testing2.TestLogger{T: t}),
+		sdApi:    sdApi,
+		interval: defaultOperationPollInterval,
+		timeout:  2 * time.Millisecond,
+		opIds:    []string{test.OpId1, test.OpId2},
 	}
 
 	sdApi.EXPECT().
@@ -202,7 +214,7 @@ func TestOperationPoller_PollTimeout(t *testing.T) {
 }
 
 func TestItoa(t *testing.T) {
-	assert.Equal(t, "7", Itoa(7))
+	assert.Equal(t, "7", itoa(7))
 }
 
 func TestNow(t *testing.T) {
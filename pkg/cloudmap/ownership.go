@@ -0,0 +1,120 @@
+package cloudmap
+
+import (
+	"errors"
+	"fmt"
+	"github.com/aws/aws-cloud-map-mcs-controller-for-k8s/pkg/version"
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/servicediscovery/types"
+	"sort"
+	"strings"
+)
+
+const (
+	// ManagedByTag identifies the controller as creator of a Cloud Map namespace or service
+	ManagedByTag = "multicluster.k8s.aws/managed-by"
+	// ClusterIdTag identifies the cluster which created a Cloud Map namespace or service
+	ClusterIdTag = "multicluster.k8s.aws/cluster-id"
+	// ClusterSetIdTag identifies the clusterset of the cluster which created a Cloud Map namespace or service
+	ClusterSetIdTag = "multicluster.k8s.aws/clusterset-id"
+
+	// awsTagPrefix is the prefix of tags reserved for AWS use
+	awsTagPrefix = "aws:"
+	// Cloud Map tag limits, the ownership tags count against the maximum number of tags per resource
+	maxResourceTags     = 50
+	maxTagKeyLength     = 128
+	maxTagValueLength   = 256
+	ownershipTagsLength = 3
+)
+
+// OwnershipTags returns the tags identifying the controller, clusterset and cluster as creator of Cloud Map resources.
+// Empty cluster and clusterset IDs are omitted.
+func OwnershipTags(clusterId string, clusterSetId string) map[string]string {
+	tags := map[string]string{ManagedByTag: version.PackageName}
+	if clusterId != "" {
+		tags[ClusterIdTag] = clusterId
+	}
+	if clusterSetId != "" {
+		tags[ClusterSetIdTag] = clusterSetId
+	}
+	return tags
+}
+
+// ResourceTags returns the additional tags along with the ownership tags of created Cloud Map resources.
+func ResourceTags(tags map[string]string, clusterId string, clusterSetId string) map[string]string {
+	result := make(map[string]string, len(tags)+ownershipTagsLength)
+	for key, value := range tags {
+		result[key] = value
+	}
+	for key, value := range OwnershipTags(clusterId, clusterSetId) {
+		result[key] = value
+	}
+	return result
+}
+
+// ValidateResourceTags returns an error if additional tags of created Cloud Map resources exceed the Cloud Map tag
+// limits, or use a prefix reserved for AWS or the ownership tags.
+func ValidateResourceTags(tags map[string]string) error {
+	if len(tags) > maxResourceTags-ownershipTagsLength {
+		return fmt.Errorf("%d resource tags exceed the maximum of %d", len(tags), maxResourceTags-ownershipTagsLength)
+	}
+	for key, value := range tags {
+		if strings.HasPrefix(strings.ToLower(key), awsTagPrefix) || strings.HasPrefix(key, reservedTagPrefix) {
+			return fmt.Errorf("resource tag %s uses a reserved prefix", key)
+		}
+		if len(key) > maxTagKeyLength {
+			return fmt.Errorf("resource tag key %s exceeds %d characters", key, maxTagKeyLength)
+		}
+		if len(value) > maxTagValueLength {
+			return fmt.Errorf("value of resource tag %s exceeds %d characters", key, maxTagValueLength)
+		}
+	}
+	return nil
+}
+
+// ErrTooManyTags is returned when the tags of a Cloud Map service would exceed the maximum number of tags per resource.
+var ErrTooManyTags = errors.New("too many Cloud Map resource tags")
+
+// ValidateServiceTags returns an error wrapping ErrTooManyTags if the service tags merged with the additional tags of
+// created Cloud Map resources and the ownership tags exceed the maximum number of tags per resource.
+func ValidateServiceTags(svcTags map[string]string, resourceTags map[string]string) error {
+	return validateTagCount(svcTags, resourceTags, ownershipTagsLength)
+}
+
+// validateTagCount counts the distinct keys of the merged tags along with the reserved number of other tags.
+func validateTagCount(tags map[string]string, other map[string]string, reserved int) error {
+	count := reserved + len(other)
+	for key := range tags {
+		if _, found := other[key]; !found {
+			count++
+		}
+	}
+	if count > maxResourceTags {
+		return fmt.Errorf("%d service tags exceed the maximum of %d: %w", count, maxResourceTags, ErrTooManyTags)
+	}
+	return nil
+}
+
+// IsOwned returns true if the tags identify a Cloud Map resource created by the controller.
+func IsOwned(tags map[string]string) bool {
+	return tags[ManagedByTag] == version.PackageName
+}
+
+// sdTags converts tags to Cloud Map tags sorted by key, or nil if there are no tags.
+func sdTags(tags map[string]string) []types.Tag {
+	if len(tags) == 0 {
+		return nil
+	}
+
+	keys := make([]string, 0, len(tags))
+	for key := range tags {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	result := make([]types.Tag, 0, len(keys))
+	for _, key := range keys {
+		result = append(result, types.Tag{Key: aws.String(key), Value: aws.String(tags[key])})
+	}
+	return result
+}
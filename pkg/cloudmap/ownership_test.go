@@ -0,0 +1,59 @@
+package cloudmap
+
+import (
+	"fmt"
+	"github.com/aws/aws-cloud-map-mcs-controller-for-k8s/pkg/version"
+	"github.com/aws/aws-cloud-map-mcs-controller-for-k8s/test"
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/servicediscovery/types"
+	"github.com/stretchr/testify/assert"
+	"strings"
+	"testing"
+)
+
+func TestOwnershipTags(t *testing.T) {
+	tags := OwnershipTags(test.ClusterId, test.ClusterSetId)
+	assert.Equal(t, map[string]string{
+		ManagedByTag:    version.PackageName,
+		ClusterIdTag:    test.ClusterId,
+		ClusterSetIdTag: test.ClusterSetId,
+	}, tags)
+	assert.True(t, IsOwned(tags))
+
+	assert.Equal(t, map[string]string{ManagedByTag: version.PackageName}, OwnershipTags("", ""),
+		"empty IDs are omitted")
+	assert.False(t, IsOwned(map[string]string{ClusterIdTag: test.ClusterId}))
+	assert.False(t, IsOwned(nil))
+}
+
+func TestResourceTags(t *testing.T) {
+	tags := ResourceTags(map[string]string{"team": "platform", ClusterIdTag: "spoofed"}, test.ClusterId, "")
+	assert.Equal(t, map[string]string{
+		"team":       "platform",
+		ManagedByTag: version.PackageName,
+		ClusterIdTag: test.ClusterId,
+	}, tags)
+}
+
+func TestValidateResourceTags(t *testing.T) {
+	assert.NoError(t, ValidateResourceTags(nil))
+	assert.NoError(t, ValidateResourceTags(map[string]string{"cost-center": "1234", "env": "prod"}))
+	assert.Error(t, ValidateResourceTags(map[string]string{"aws:cloudformation:stack-name": "a"}), "AWS prefix")
+	assert.Error(t, ValidateResourceTags(map[string]string{ManagedByTag: "a"}), "ownership prefix")
+	assert.Error(t, ValidateResourceTags(map[string]string{strings.Repeat("k", 129): "a"}), "key length")
+	assert.Error(t, ValidateResourceTags(map[string]string{"k": strings.Repeat("v", 257)}), "value length")
+
+	tooMany := make(map[string]string)
+	for i := 0; i < maxResourceTags; i++ {
+		tooMany[fmt.Sprintf("tag-%d", i)] = "v"
+	}
+	assert.Error(t, ValidateResourceTags(tooMany), "tag count")
+}
+
+func TestSdTags(t *testing.T) {
+	assert.Nil(t, sdTags(nil))
+	assert.Equal(t, []types.Tag{
+		{Key: aws.String("a"), Value: aws.String("1")},
+		{Key: aws.String("b"), Value: aws.String("2")},
+	}, sdTags(map[string]string{"b": "2", "a": "1"}))
+}
@@ -0,0 +1,175 @@
+package cloudmap
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/aws/aws-cloud-map-mcs-controller-for-k8s/pkg/model"
+	"github.com/aws/aws-sdk-go-v2/aws"
+	sd "github.com/aws/aws-sdk-go-v2/service/servicediscovery"
+	"github.com/aws/smithy-go"
+	"k8s.io/apimachinery/pkg/util/sets"
+)
+
+// Outcomes of a permission check
+const (
+	// PermissionGranted means the request was authorized
+	PermissionGranted = "PASS"
+	// PermissionDenied means the request was rejected as not authorized
+	PermissionDenied = "FAIL"
+	// PermissionUnknown means the request failed for another reason, which hides whether it is authorized
+	PermissionUnknown = "UNKNOWN"
+	// PermissionNotChecked means the action wasn't checked
+	PermissionNotChecked = "NOT CHECKED"
+)
+
+const (
+	// probeId is a syntactically valid ID of Cloud Map resources which don't exist
+	probeId   = "00000000000000000"
+	probeName = "cloudmap-mcs-permission-check"
+)
+
+var accessDeniedCodes = sets.NewString("AccessDeniedException", "AccessDenied", "UnauthorizedOperation")
+
+// PermissionCheck is the outcome of checking whether the credentials are authorized for an action.
+type PermissionCheck struct {
+	Action string
+	Result string
+	// Detail is the error code of failed requests, or the reason an action is not checked
+	Detail string
+}
+
+// permissionProbe calls an action in a way which can't modify Cloud Map, by referencing resources which don't exist or
+// passing invalid input. Authorized requests fail with one of the expected error codes.
+type permissionProbe struct {
+	action   string
+	expected []string
+	call     func(ctx context.Context, facade AwsFacade) error
+}
+
+// CheckPermissions checks whether the credentials of the AWS facade are authorized for each Cloud Map action the
+// controller requires, without modifying Cloud Map. The account ID and region build the ARNs of resources which don't
+// exist, checking the tagging actions. The Route 53 actions of DNS namespaces are checked by route53.CheckPermissions.
+func CheckPermissions(ctx context.Context, facade AwsFacade, region string, accountId string) []PermissionCheck {
+	serviceId := "srv-" + probeId
+	serviceArn := fmt.Sprintf("arn:aws:servicediscovery:%s:%s:service/%s", region, accountId, serviceId)
+	probes := []permissionProbe{
+		{action: "ListNamespaces", call: func(ctx context.Context, facade AwsFacade) error {
+			_, err := facade.ListNamespaces(ctx, &sd.ListNamespacesInput{MaxResults: aws.Int32(1)})
+			return err
+		}},
+		{action: "ListServices", call: func(ctx context.Context, facade AwsFacade) error {
+			_, err := facade.ListServices(ctx, &sd.ListServicesInput{MaxResults: aws.Int32(1)})
+			return err
+		}},
+		{action: "ListOperations", call: func(ctx context.Context, facade AwsFacade) error {
+			_, err := facade.ListOperations(ctx, &sd.ListOperationsInput{MaxResults: aws.Int32(1)})
+			return err
+		}},
+		{action: "GetOperation", expected: []string{"OperationNotFound"},
+			call: func(ctx context.Context, facade AwsFacade) error {
+				_, err := facade.GetOperation(ctx, &sd.GetOperationInput{OperationId: aws.String(probeId)})
+				return err
+			}},
+		{action: "CreateHttpNamespace", expected: []string{"InvalidInput"},
+			call: func(ctx context.Context, facade AwsFacade) error {
+				// the name is invalid, the namespace is never created
+				_, err := facade.CreateHttpNamespace(ctx, &sd.CreateHttpNamespaceInput{Name: aws.String(" ")})
+				return err
+			}},
+		{action: "CreateService", expected: []string{"NamespaceNotFound"},
+			call: func(ctx context.Context, facade AwsFacade) error {
+				_, err := facade.CreateService(ctx, &sd.CreateServiceInput{
+					Name:        aws.String(probeName),
+					NamespaceId: aws.String("ns-" + probeId),
+				})
+				return err
+			}},
+		{action: "GetService", expected: []string{"ServiceNotFound"},
+			call: func(ctx context.Context, facade AwsFacade) error {
+				_, err := facade.GetService(ctx, &sd.GetServiceInput{Id: aws.String(serviceId)})
+				return err
+			}},
+		{action: "UpdateService", expected: []string{"ServiceNotFound"},
+			call: func(ctx context.Context, facade AwsFacade) error {
+				_, err := facade.UpdateService(ctx, &sd.UpdateServiceInput{Id: aws.String(serviceId)})
+				return err
+			}},
+		{action: "ListTagsForResource", expected: []string{"ResourceNotFoundException"},
+			call: func(ctx context.Context, facade AwsFacade) error {
+				_, err := facade.ListTagsForResource(ctx,
+					&sd.ListTagsForResourceInput{ResourceARN: aws.String(serviceArn)})
+				return err
+			}},
+		{action: "TagResource", expected: []string{"ResourceNotFoundException"},
+			call: func(ctx context.Context, facade AwsFacade) error {
+				_, err := facade.TagResource(ctx, &sd.TagResourceInput{ResourceARN: aws.String(serviceArn)})
+				return err
+			}},
+		{action: "RegisterInstance", expected: []string{"ServiceNotFound"},
+			call: func(ctx context.Context, facade AwsFacade) error {
+				_, err := facade.RegisterInstance(ctx, &sd.RegisterInstanceInput{
+					ServiceId:  aws.String(serviceId),
+					InstanceId: aws.String(probeName),
+					Attributes: map[string]string{model.EndpointIpv4Attr: "192.0.2.1"},
+				})
+				return err
+			}},
+		{action: "DeregisterInstance", expected: []string{"ServiceNotFound", "InstanceNotFound"},
+			call: func(ctx context.Context, facade AwsFacade) error {
+				_, err := facade.DeregisterInstance(ctx, &sd.DeregisterInstanceInput{
+					ServiceId:  aws.String(serviceId),
+					InstanceId: aws.String(probeName),
+				})
+				return err
+			}},
+		{action: "ListInstances", expected: []string{"ServiceNotFound"},
+			call: func(ctx context.Context, facade AwsFacade) error {
+				_, err := facade.ListInstances(ctx, &sd.ListInstancesInput{ServiceId: aws.String(serviceId)})
+				return err
+			}},
+		{action: "DiscoverInstances", expected: []string{"NamespaceNotFound", "ServiceNotFound"},
+			call: func(ctx context.Context, facade AwsFacade) error {
+				_, err := facade.DiscoverInstances(ctx, &sd.DiscoverInstancesInput{
+					NamespaceName: aws.String(probeName),
+					ServiceName:   aws.String(probeName),
+				})
+				return err
+			}},
+	}
+
+	checks := make([]PermissionCheck, 0, len(probes))
+	for _, probe := range probes {
+		checks = append(checks, probe.check(ctx, facade))
+	}
+	return checks
+}
+
+func (p *permissionProbe) check(ctx context.Context, facade AwsFacade) PermissionCheck {
+	return NewPermissionCheck("servicediscovery:"+p.action, p.call(ctx, facade), p.expected...)
+}
+
+// NewPermissionCheck returns the outcome of a request probing an action, which is authorized if it succeeded or
+// failed with one of the expected error codes.
+func NewPermissionCheck(action string, err error, expected ...string) PermissionCheck {
+	check := PermissionCheck{Action: action, Result: PermissionGranted}
+	if err == nil {
+		return check
+	}
+
+	var apiErr smithy.APIError
+	if !errors.As(err, &apiErr) {
+		check.Result = PermissionUnknown
+		check.Detail = err.Error()
+		return check
+	}
+
+	check.Detail = apiErr.ErrorCode()
+	switch {
+	case accessDeniedCodes.Has(apiErr.ErrorCode()):
+		check.Result = PermissionDenied
+	case !sets.NewString(expected...).Has(apiErr.ErrorCode()):
+		check.Result = PermissionUnknown
+	}
+	return check
+}
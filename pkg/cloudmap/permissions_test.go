@@ -0,0 +1,90 @@
+package cloudmap
+
+import (
+	"context"
+	"errors"
+	"github.com/aws/aws-cloud-map-mcs-controller-for-k8s/mocks/pkg/cloudmap"
+	sd "github.com/aws/aws-sdk-go-v2/service/servicediscovery"
+	"github.com/aws/smithy-go"
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func TestCheckPermissions_Authorized(t *testing.T) {
+	mockController := gomock.NewController(t)
+	defer mockController.Finish()
+
+	awsFacade := cloudmap.NewMockAwsFacade(mockController)
+	expectProbes(awsFacade, nil)
+
+	checks := CheckPermissions(context.TODO(), awsFacade, "us-west-2", "123456789012")
+	assert.Len(t, checks, 15)
+	for _, check := range checks {
+		assert.Equal(t, PermissionGranted, check.Result, check.Action)
+	}
+}
+
+func TestCheckPermissions_Denied(t *testing.T) {
+	mockController := gomock.NewController(t)
+	defer mockController.Finish()
+
+	awsFacade := cloudmap.NewMockAwsFacade(mockController)
+	expectProbes(awsFacade, &smithy.GenericAPIError{Code: "AccessDeniedException"})
+
+	checks := CheckPermissions(context.TODO(), awsFacade, "us-west-2", "123456789012")
+	for _, check := range checks {
+		assert.Equal(t, PermissionDenied, check.Result, check.Action)
+		assert.Equal(t, "AccessDeniedException", check.Detail)
+	}
+}
+
+func TestPermissionProbe_Check(t *testing.T) {
+	tests := []struct {
+		name   string
+		err    error
+		result string
+	}{
+		{name: "success", err: nil, result: PermissionGranted},
+		{name: "expected error", err: &smithy.GenericAPIError{Code: "ServiceNotFound"}, result: PermissionGranted},
+		{name: "access denied", err: &smithy.GenericAPIError{Code: "AccessDenied"}, result: PermissionDenied},
+		{name: "unexpected error", err: &smithy.GenericAPIError{Code: "InvalidInput"}, result: PermissionUnknown},
+		{name: "network error", err: errors.New("connection refused"), result: PermissionUnknown},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			probe := &permissionProbe{action: "GetService", expected: []string{"ServiceNotFound"},
+				call: func(ctx context.Context, facade AwsFacade) error {
+					return tt.err
+				}}
+			check := probe.check(context.TODO(), nil)
+			assert.Equal(t, "servicediscovery:GetService", check.Action)
+			assert.Equal(t, tt.result, check.Result)
+		})
+	}
+}
+
+// expectProbes expects each probe to be called once, failing with err or the error of an authorized request if nil.
+func expectProbes(awsFacade *cloudmap.MockAwsFacade, err error) {
+	errOr := func(code string) error {
+		if err != nil {
+			return err
+		}
+		return &smithy.GenericAPIError{Code: code}
+	}
+
+	awsFacade.EXPECT().ListNamespaces(gomock.Any(), gomock.Any()).Return(&sd.ListNamespacesOutput{}, err)
+	awsFacade.EXPECT().ListServices(gomock.Any(), gomock.Any()).Return(&sd.ListServicesOutput{}, err)
+	awsFacade.EXPECT().ListOperations(gomock.Any(), gomock.Any()).Return(&sd.ListOperationsOutput{}, err)
+	awsFacade.EXPECT().GetOperation(gomock.Any(), gomock.Any()).Return(nil, errOr("OperationNotFound"))
+	awsFacade.EXPECT().CreateHttpNamespace(gomock.Any(), gomock.Any()).Return(nil, errOr("InvalidInput"))
+	awsFacade.EXPECT().CreateService(gomock.Any(), gomock.Any()).Return(nil, errOr("NamespaceNotFound"))
+	awsFacade.EXPECT().GetService(gomock.Any(), gomock.Any()).Return(nil, errOr("ServiceNotFound"))
+	awsFacade.EXPECT().UpdateService(gomock.Any(), gomock.Any()).Return(nil, errOr("ServiceNotFound"))
+	awsFacade.EXPECT().ListTagsForResource(gomock.Any(), gomock.Any()).Return(nil, errOr("ResourceNotFoundException"))
+	awsFacade.EXPECT().TagResource(gomock.Any(), gomock.Any()).Return(nil, errOr("ResourceNotFoundException"))
+	awsFacade.EXPECT().RegisterInstance(gomock.Any(), gomock.Any()).Return(nil, errOr("ServiceNotFound"))
+	awsFacade.EXPECT().DeregisterInstance(gomock.Any(), gomock.Any()).Return(nil, errOr("InstanceNotFound"))
+	awsFacade.EXPECT().ListInstances(gomock.Any(), gomock.Any()).Return(nil, errOr("ServiceNotFound"))
+	awsFacade.EXPECT().DiscoverInstances(gomock.Any(), gomock.Any()).Return(nil, errOr("NamespaceNotFound"))
+}
@@ -0,0 +1,61 @@
+package cloudmap
+
+import (
+	"context"
+	"github.com/aws/smithy-go/middleware"
+	"k8s.io/client-go/util/flowcontrol"
+	"sync"
+)
+
+// RateLimiter limits the rate of Cloud Map API requests. The limit can be changed at runtime, and a RateLimiter
+// without limit accepts all requests. It is safe for concurrent use.
+type RateLimiter struct {
+	mu      sync.RWMutex
+	limiter flowcontrol.RateLimiter
+}
+
+// NewRateLimiter creates a rate limiter without limit.
+func NewRateLimiter() *RateLimiter {
+	return &RateLimiter{}
+}
+
+// SetLimit limits requests to a sustained rate of qps requests per second with the given burst, a qps of 0 removes
+// the limit.
+func (l *RateLimiter) SetLimit(qps float32, burst int) {
+	var limiter flowcontrol.RateLimiter
+	if qps > 0 {
+		limiter = flowcontrol.NewTokenBucketRateLimiter(qps, burst)
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if l.limiter != nil {
+		l.limiter.Stop()
+	}
+	l.limiter = limiter
+}
+
+// Wait blocks until a request is permitted or the context is done.
+func (l *RateLimiter) Wait(ctx context.Context) error {
+	l.mu.RLock()
+	limiter := l.limiter
+	l.mu.RUnlock()
+
+	if limiter == nil {
+		return nil
+	}
+	return limiter.Wait(ctx)
+}
+
+// AddMiddleware adds the rate limiter to the middleware stack of an AWS SDK client, for use in aws.Config APIOptions.
+// Retries of a request are not rate limited again, they are subject to the SDK retry backoff.
+func (l *RateLimiter) AddMiddleware(stack *middleware.Stack) error {
+	return stack.Initialize.Add(middleware.InitializeMiddlewareFunc("CloudMapRateLimiter",
+		func(ctx context.Context, in middleware.InitializeInput, next middleware.InitializeHandler) (
+			out middleware.InitializeOutput, metadata middleware.Metadata, err error) {
+			if err = l.Wait(ctx); err != nil {
+				return out, metadata, err
+			}
+			return next.HandleInitialize(ctx, in)
+		}), middleware.Before)
+}
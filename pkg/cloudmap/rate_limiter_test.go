@@ -0,0 +1,23 @@
+package cloudmap
+
+import (
+	"context"
+	"github.com/stretchr/testify/assert"
+	"testing"
+	"time"
+)
+
+func TestRateLimiter_Wait(t *testing.T) {
+	limiter := NewRateLimiter()
+	assert.NoError(t, limiter.Wait(context.TODO()), "no limit")
+
+	limiter.SetLimit(1, 1)
+	assert.NoError(t, limiter.Wait(context.TODO()), "burst")
+
+	ctx, cancel := context.WithTimeout(context.TODO(), 10*time.Millisecond)
+	defer cancel()
+	assert.Error(t, limiter.Wait(ctx), "limit exceeded")
+
+	limiter.SetLimit(0, 0)
+	assert.NoError(t, limiter.Wait(context.TODO()), "limit removed")
+}
@@ -0,0 +1,24 @@
+package cloudmap
+
+import (
+	"context"
+	"github.com/aws/aws-cloud-map-mcs-controller-for-k8s/pkg/model"
+)
+
+// ServiceMetadata holds the description and the additional tags of a Cloud Map service.
+type ServiceMetadata = model.ServiceMetadata
+
+type serviceMetadataKey struct{}
+
+// WithServiceMetadata returns a context which creates Cloud Map services with the given description and tags.
+func WithServiceMetadata(ctx context.Context, metadata ServiceMetadata) context.Context {
+	return context.WithValue(ctx, serviceMetadataKey{}, metadata)
+}
+
+// ServiceMetadataFromContext returns the service metadata of the context or empty metadata if not set.
+func ServiceMetadataFromContext(ctx context.Context) ServiceMetadata {
+	if metadata, ok := ctx.Value(serviceMetadataKey{}).(ServiceMetadata); ok {
+		return metadata
+	}
+	return ServiceMetadata{}
+}
@@ -0,0 +1,140 @@
+package cloudmap
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	sd "github.com/aws/aws-sdk-go-v2/service/servicediscovery"
+)
+
+// ErrReadOnly is returned by read only AWS facades for the Cloud Map actions modifying Cloud Map.
+var ErrReadOnly = errors.New("modifying Cloud Map is not permitted, the credentials are read only")
+
+// splitAwsFacade sends the reads of the import path and the writes of the export path to facades with credentials of
+// their own, so each set of credentials holds only the permissions of its path.
+type splitAwsFacade struct {
+	read  AwsFacade
+	write AwsFacade
+}
+
+// NewSplitAwsFacade creates an AWS facade which calls the actions reading Cloud Map (List*, Get*, DiscoverInstances)
+// with the read facade, and the actions modifying it (Create*, Update*, Delete*, Register*, Deregister*, tagging) with
+// the write facade. The facade is read only if the write facade is nil, the writes fail with ErrReadOnly.
+func NewSplitAwsFacade(read AwsFacade, write AwsFacade) AwsFacade {
+	return &splitAwsFacade{read: read, write: write}
+}
+
+func (f *splitAwsFacade) writer(action string) (AwsFacade, error) {
+	if f.write == nil {
+		return nil, fmt.Errorf("%s: %w", action, ErrReadOnly)
+	}
+	return f.write, nil
+}
+
+func (f *splitAwsFacade) ListNamespaces(ctx context.Context, input *sd.ListNamespacesInput, optFns ...func(*sd.Options)) (*sd.ListNamespacesOutput, error) {
+	return f.read.ListNamespaces(ctx, input, optFns...)
+}
+
+func (f *splitAwsFacade) ListServices(ctx context.Context, input *sd.ListServicesInput, optFns ...func(*sd.Options)) (*sd.ListServicesOutput, error) {
+	return f.read.ListServices(ctx, input, optFns...)
+}
+
+func (f *splitAwsFacade) ListOperations(ctx context.Context, input *sd.ListOperationsInput, optFns ...func(*sd.Options)) (*sd.ListOperationsOutput, error) {
+	return f.read.ListOperations(ctx, input, optFns...)
+}
+
+func (f *splitAwsFacade) GetOperation(ctx context.Context, input *sd.GetOperationInput, optFns ...func(*sd.Options)) (*sd.GetOperationOutput, error) {
+	return f.read.GetOperation(ctx, input, optFns...)
+}
+
+func (f *splitAwsFacade) CreateHttpNamespace(ctx context.Context, input *sd.CreateHttpNamespaceInput, optFns ...func(*sd.Options)) (*sd.CreateHttpNamespaceOutput, error) {
+	write, err := f.writer("CreateHttpNamespace")
+	if err != nil {
+		return nil, err
+	}
+	return write.CreateHttpNamespace(ctx, input, optFns...)
+}
+
+func (f *splitAwsFacade) GetNamespace(ctx context.Context, input *sd.GetNamespaceInput, optFns ...func(*sd.Options)) (*sd.GetNamespaceOutput, error) {
+	return f.read.GetNamespace(ctx, input, optFns...)
+}
+
+func (f *splitAwsFacade) DeleteNamespace(ctx context.Context, input *sd.DeleteNamespaceInput, optFns ...func(*sd.Options)) (*sd.DeleteNamespaceOutput, error) {
+	write, err := f.writer("DeleteNamespace")
+	if err != nil {
+		return nil, err
+	}
+	return write.DeleteNamespace(ctx, input, optFns...)
+}
+
+func (f *splitAwsFacade) CreateService(ctx context.Context, input *sd.CreateServiceInput, optFns ...func(*sd.Options)) (*sd.CreateServiceOutput, error) {
+	write, err := f.writer("CreateService")
+	if err != nil {
+		return nil, err
+	}
+	return write.CreateService(ctx, input, optFns...)
+}
+
+func (f *splitAwsFacade) GetService(ctx context.Context, input *sd.GetServiceInput, optFns ...func(*sd.Options)) (*sd.GetServiceOutput, error) {
+	return f.read.GetService(ctx, input, optFns...)
+}
+
+func (f *splitAwsFacade) UpdateService(ctx context.Context, input *sd.UpdateServiceInput, optFns ...func(*sd.Options)) (*sd.UpdateServiceOutput, error) {
+	write, err := f.writer("UpdateService")
+	if err != nil {
+		return nil, err
+	}
+	return write.UpdateService(ctx, input, optFns...)
+}
+
+func (f *splitAwsFacade) ListTagsForResource(ctx context.Context, input *sd.ListTagsForResourceInput, optFns ...func(*sd.Options)) (*sd.ListTagsForResourceOutput, error) {
+	return f.read.ListTagsForResource(ctx, input, optFns...)
+}
+
+func (f *splitAwsFacade) DeleteService(ctx context.Context, input *sd.DeleteServiceInput, optFns ...func(*sd.Options)) (*sd.DeleteServiceOutput, error) {
+	write, err := f.writer("DeleteService")
+	if err != nil {
+		return nil, err
+	}
+	return write.DeleteService(ctx, input, optFns...)
+}
+
+func (f *splitAwsFacade) TagResource(ctx context.Context, input *sd.TagResourceInput, optFns ...func(*sd.Options)) (*sd.TagResourceOutput, error) {
+	write, err := f.writer("TagResource")
+	if err != nil {
+		return nil, err
+	}
+	return write.TagResource(ctx, input, optFns...)
+}
+
+func (f *splitAwsFacade) UntagResource(ctx context.Context, input *sd.UntagResourceInput, optFns ...func(*sd.Options)) (*sd.UntagResourceOutput, error) {
+	write, err := f.writer("UntagResource")
+	if err != nil {
+		return nil, err
+	}
+	return write.UntagResource(ctx, input, optFns...)
+}
+
+func (f *splitAwsFacade) RegisterInstance(ctx context.Context, input *sd.RegisterInstanceInput, optFns ...func(*sd.Options)) (*sd.RegisterInstanceOutput, error) {
+	write, err := f.writer("RegisterInstance")
+	if err != nil {
+		return nil, err
+	}
+	return write.RegisterInstance(ctx, input, optFns...)
+}
+
+func (f *splitAwsFacade) DeregisterInstance(ctx context.Context, input *sd.DeregisterInstanceInput, optFns ...func(*sd.Options)) (*sd.DeregisterInstanceOutput, error) {
+	write, err := f.writer("DeregisterInstance")
+	if err != nil {
+		return nil, err
+	}
+	return write.DeregisterInstance(ctx, input, optFns...)
+}
+
+func (f *splitAwsFacade) ListInstances(ctx context.Context, input *sd.ListInstancesInput, optFns ...func(*sd.Options)) (*sd.ListInstancesOutput, error) {
+	return f.read.ListInstances(ctx, input, optFns...)
+}
+
+func (f *splitAwsFacade) DiscoverInstances(ctx context.Context, input *sd.DiscoverInstancesInput, optFns ...func(*sd.Options)) (*sd.DiscoverInstancesOutput, error) {
+	return f.read.DiscoverInstances(ctx, input, optFns...)
+}
@@ -0,0 +1,52 @@
+package cloudmap
+
+import (
+	"context"
+	"errors"
+	"github.com/aws/aws-cloud-map-mcs-controller-for-k8s/mocks/pkg/cloudmap"
+	sd "github.com/aws/aws-sdk-go-v2/service/servicediscovery"
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func TestSplitAwsFacade(t *testing.T) {
+	mockController := gomock.NewController(t)
+	defer mockController.Finish()
+
+	read := cloudmap.NewMockAwsFacade(mockController)
+	write := cloudmap.NewMockAwsFacade(mockController)
+	facade := NewSplitAwsFacade(read, write)
+
+	read.EXPECT().DiscoverInstances(context.TODO(), gomock.Any()).Return(&sd.DiscoverInstancesOutput{}, nil)
+	read.EXPECT().GetService(context.TODO(), gomock.Any()).Return(&sd.GetServiceOutput{}, nil)
+	write.EXPECT().RegisterInstance(context.TODO(), gomock.Any()).Return(&sd.RegisterInstanceOutput{}, nil)
+	write.EXPECT().CreateService(context.TODO(), gomock.Any()).Return(&sd.CreateServiceOutput{}, nil)
+
+	_, err := facade.DiscoverInstances(context.TODO(), &sd.DiscoverInstancesInput{})
+	assert.NoError(t, err)
+	_, err = facade.GetService(context.TODO(), &sd.GetServiceInput{})
+	assert.NoError(t, err)
+	_, err = facade.RegisterInstance(context.TODO(), &sd.RegisterInstanceInput{})
+	assert.NoError(t, err)
+	_, err = facade.CreateService(context.TODO(), &sd.CreateServiceInput{})
+	assert.NoError(t, err)
+}
+
+func TestSplitAwsFacade_ReadOnly(t *testing.T) {
+	mockController := gomock.NewController(t)
+	defer mockController.Finish()
+
+	read := cloudmap.NewMockAwsFacade(mockController)
+	facade := NewSplitAwsFacade(read, nil)
+
+	read.EXPECT().ListNamespaces(context.TODO(), gomock.Any()).Return(&sd.ListNamespacesOutput{}, nil)
+	_, err := facade.ListNamespaces(context.TODO(), &sd.ListNamespacesInput{})
+	assert.NoError(t, err)
+
+	_, err = facade.DeregisterInstance(context.TODO(), &sd.DeregisterInstanceInput{})
+	assert.True(t, errors.Is(err, ErrReadOnly))
+	assert.Contains(t, err.Error(), "DeregisterInstance")
+	_, err = facade.CreateHttpNamespace(context.TODO(), &sd.CreateHttpNamespaceInput{})
+	assert.True(t, errors.Is(err, ErrReadOnly))
+}
@@ -0,0 +1,48 @@
+package cloudmap
+
+import (
+	"context"
+	"github.com/aws/aws-cloud-map-mcs-controller-for-k8s/pkg/model"
+)
+
+type syncProgressKey struct{}
+
+// SyncProgress is the progress of registering or de-registering the endpoints of a service, which are processed in
+// chunks of SdClientConfig.SyncChunkSize endpoints.
+type SyncProgress struct {
+	// Action is AuditActionRegisterInstance or AuditActionDeregisterInstance
+	Action string
+	// Done is the number of endpoints processed by the completed chunks, out of Total
+	Done  int
+	Total int
+}
+
+// WithSyncProgress returns a context reporting the progress of the endpoint changes made while serving it, after each
+// completed chunk of a change spanning several chunks. Changes fitting a single chunk are not reported.
+func WithSyncProgress(ctx context.Context, report func(SyncProgress)) context.Context {
+	return context.WithValue(ctx, syncProgressKey{}, report)
+}
+
+// ReportSyncProgress reports the progress to the context, if it was created by WithSyncProgress.
+func ReportSyncProgress(ctx context.Context, progress SyncProgress) {
+	if report, ok := ctx.Value(syncProgressKey{}).(func(SyncProgress)); ok {
+		report(progress)
+	}
+}
+
+// chunks splits the endpoints into consecutive chunks of at most size endpoints.
+func chunks(endpts []*model.Endpoint, size int) [][]*model.Endpoint {
+	if size <= 0 || len(endpts) <= size {
+		return [][]*model.Endpoint{endpts}
+	}
+
+	result := make([][]*model.Endpoint, 0, (len(endpts)+size-1)/size)
+	for start := 0; start < len(endpts); start += size {
+		end := start + size
+		if end > len(endpts) {
+			end = len(endpts)
+		}
+		result = append(result, endpts[start:end])
+	}
+	return result
+}
@@ -0,0 +1,18 @@
+package cloudmap
+
+import (
+	"github.com/aws/aws-cloud-map-mcs-controller-for-k8s/pkg/model"
+	"github.com/aws/aws-cloud-map-mcs-controller-for-k8s/test"
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func TestChunks(t *testing.T) {
+	endpt1, endpt2, endpt3 := test.GetTestEndpoint1(), test.GetTestEndpoint2(), test.GetTestEndpoint1()
+
+	assert.Equal(t, [][]*model.Endpoint{{endpt1, endpt2}, {endpt3}},
+		chunks([]*model.Endpoint{endpt1, endpt2, endpt3}, 2))
+	assert.Equal(t, [][]*model.Endpoint{{endpt1, endpt2}}, chunks([]*model.Endpoint{endpt1, endpt2}, 2))
+	assert.Equal(t, [][]*model.Endpoint{{endpt1, endpt2}}, chunks([]*model.Endpoint{endpt1, endpt2}, 0),
+		"not chunked if the size is not positive")
+}
@@ -0,0 +1,83 @@
+package cloudmap
+
+import (
+	"context"
+	"errors"
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/aws/retry"
+	"sync"
+)
+
+type throttleTrackerKey struct{}
+
+// ThrottleTracker counts the Cloud Map requests which were throttled while serving a context. It is safe for
+// concurrent use. A nil ThrottleTracker discards all calls.
+type ThrottleTracker struct {
+	mu    sync.Mutex
+	count int
+}
+
+// WithThrottleTracker returns a context carrying a new throttle tracker, and the tracker.
+func WithThrottleTracker(ctx context.Context) (context.Context, *ThrottleTracker) {
+	tracker := &ThrottleTracker{}
+	return context.WithValue(ctx, throttleTrackerKey{}, tracker), tracker
+}
+
+// ThrottleTrackerFromContext returns the throttle tracker of the context or nil if not set.
+func ThrottleTrackerFromContext(ctx context.Context) *ThrottleTracker {
+	tracker, _ := ctx.Value(throttleTrackerKey{}).(*ThrottleTracker)
+	return tracker
+}
+
+// Observe records a throttled request.
+func (t *ThrottleTracker) Observe() {
+	if t == nil {
+		return
+	}
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.count++
+}
+
+// Count returns the number of throttled requests observed.
+func (t *ThrottleTracker) Count() int {
+	if t == nil {
+		return 0
+	}
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return t.count
+}
+
+// IsThrottlingError returns true if the error was caused by Cloud Map throttling the request.
+func IsThrottlingError(err error) bool {
+	if err == nil {
+		return false
+	}
+
+	var maxAttemptsErr *retry.MaxAttemptsError
+	if errors.As(err, &maxAttemptsErr) {
+		err = maxAttemptsErr.Err
+	}
+
+	return retry.IsErrorThrottles(retry.DefaultThrottles).IsErrorThrottle(err) == aws.TrueTernary
+}
+
+// throttleObservingRetryer reports throttled requests to the throttle tracker of the request context before the SDK
+// retries them, so the delay is not silently absorbed by the retries.
+type throttleObservingRetryer struct {
+	aws.Retryer
+}
+
+func newThrottleObservingRetryer(retryer aws.Retryer) aws.Retryer {
+	return &throttleObservingRetryer{Retryer: retryer}
+}
+
+func (r *throttleObservingRetryer) GetRetryToken(ctx context.Context, opErr error) (func(error) error, error) {
+	if IsThrottlingError(opErr) {
+		ThrottleTrackerFromContext(ctx).Observe()
+	}
+	return r.Retryer.GetRetryToken(ctx, opErr)
+}
@@ -0,0 +1,41 @@
+package cloudmap
+
+import (
+	"context"
+	"errors"
+	"github.com/aws/aws-sdk-go-v2/aws/retry"
+	"github.com/aws/smithy-go"
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func TestIsThrottlingError(t *testing.T) {
+	throttleErr := &smithy.GenericAPIError{Code: "ThrottlingException", Message: "Rate exceeded"}
+
+	assert.True(t, IsThrottlingError(throttleErr))
+	assert.True(t, IsThrottlingError(&smithy.OperationError{ServiceID: "ServiceDiscovery", Err: throttleErr}))
+	assert.True(t, IsThrottlingError(&retry.MaxAttemptsError{Attempt: 3, Err: throttleErr}))
+	assert.False(t, IsThrottlingError(&smithy.GenericAPIError{Code: "InvalidInput"}))
+	assert.False(t, IsThrottlingError(errors.New("error")))
+	assert.False(t, IsThrottlingError(nil))
+}
+
+func TestThrottleObservingRetryer_GetRetryToken(t *testing.T) {
+	ctx, tracker := WithThrottleTracker(context.TODO())
+	retryer := newThrottleObservingRetryer(retry.NewStandard())
+
+	_, err := retryer.GetRetryToken(ctx, &smithy.GenericAPIError{Code: "ThrottlingException"})
+	assert.NoError(t, err)
+	_, err = retryer.GetRetryToken(ctx, &smithy.GenericAPIError{Code: "InternalFailure"})
+	assert.NoError(t, err)
+
+	assert.Equal(t, 1, tracker.Count())
+}
+
+func TestThrottleTracker_NilSafe(t *testing.T) {
+	tracker := ThrottleTrackerFromContext(context.TODO())
+	assert.Nil(t, tracker)
+
+	tracker.Observe()
+	assert.Equal(t, 0, tracker.Count())
+}
@@ -0,0 +1,104 @@
+package cloudmap
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"github.com/aws/aws-sdk-go-v2/aws"
+	awshttp "github.com/aws/aws-sdk-go-v2/aws/transport/http"
+	"github.com/aws/smithy-go/middleware"
+	"time"
+)
+
+const (
+	// Time until a Cloud Map API call including its retries is abandoned
+	defaultApiCallTimeout = time.Minute
+
+	// Time until a single HTTP attempt of a Cloud Map API call is abandoned
+	defaultApiAttemptTimeout = 15 * time.Second
+)
+
+// SdTimeoutConfig bounds Cloud Map API calls and operation polling, so a hung connection to the AWS endpoint cannot
+// stall a reconcile indefinitely. The timeouts are independent of the reconcile context, which still cancels calls.
+type SdTimeoutConfig struct {
+	// ApiCallTimeout bounds an API call including its retries, 0 disables the timeout
+	ApiCallTimeout time.Duration
+	// ApiAttemptTimeout bounds a single HTTP attempt of an API call, 0 disables the timeout
+	ApiAttemptTimeout time.Duration
+	// OperationPollInterval is the interval between polls of asynchronous Cloud Map operations
+	OperationPollInterval time.Duration
+	// OperationPollTimeout is the time until polling asynchronous Cloud Map operations is abandoned
+	OperationPollTimeout time.Duration
+}
+
+// NewDefaultSdTimeoutConfig returns the default timeout settings of Cloud Map API calls and operation polling.
+func NewDefaultSdTimeoutConfig() *SdTimeoutConfig {
+	return &SdTimeoutConfig{
+		ApiCallTimeout:        defaultApiCallTimeout,
+		ApiAttemptTimeout:     defaultApiAttemptTimeout,
+		OperationPollInterval: defaultOperationPollInterval,
+		OperationPollTimeout:  defaultOperationPollTimeout,
+	}
+}
+
+// BindFlags registers the timeout settings as command line flags.
+func (c *SdTimeoutConfig) BindFlags(fs *flag.FlagSet) {
+	fs.DurationVar(&c.ApiCallTimeout, "aws-api-call-timeout", c.ApiCallTimeout,
+		"Time until a Cloud Map API call including its retries is abandoned, 0 disables the timeout.")
+	fs.DurationVar(&c.ApiAttemptTimeout, "aws-api-attempt-timeout", c.ApiAttemptTimeout,
+		"Time until a single HTTP attempt of a Cloud Map API call is abandoned and retried, 0 disables the timeout.")
+	fs.DurationVar(&c.OperationPollInterval, "operation-poll-interval", c.OperationPollInterval,
+		"Interval between polls of asynchronous Cloud Map operations.")
+	fs.DurationVar(&c.OperationPollTimeout, "operation-poll-timeout", c.OperationPollTimeout,
+		"Time until polling asynchronous Cloud Map operations is abandoned.")
+}
+
+// Validate returns an error if a timeout setting is out of range.
+func (c *SdTimeoutConfig) Validate() error {
+	if c.ApiCallTimeout < 0 || c.ApiAttemptTimeout < 0 {
+		return errors.New("API call timeouts must not be negative")
+	}
+	if c.OperationPollInterval <= 0 || c.OperationPollTimeout <= 0 {
+		return errors.New("operation poll interval and timeout must be positive")
+	}
+	return nil
+}
+
+// Apply configures the API call timeouts on an AWS SDK config. The call timeout is added to the middleware stack
+// after the rate limiter, so time spent waiting for the rate limiter does not count against it.
+func (c *SdTimeoutConfig) Apply(cfg *aws.Config) {
+	if c.ApiAttemptTimeout > 0 {
+		httpClient, ok := cfg.HTTPClient.(*awshttp.BuildableClient)
+		if !ok {
+			httpClient = awshttp.NewBuildableClient()
+		}
+		cfg.HTTPClient = httpClient.WithTimeout(c.ApiAttemptTimeout)
+	}
+	if c.ApiCallTimeout > 0 {
+		cfg.APIOptions = append(cfg.APIOptions, c.addMiddleware)
+	}
+}
+
+func (c *SdTimeoutConfig) addMiddleware(stack *middleware.Stack) error {
+	return stack.Initialize.Add(middleware.InitializeMiddlewareFunc("CloudMapCallTimeout",
+		func(ctx context.Context, in middleware.InitializeInput, next middleware.InitializeHandler) (
+			out middleware.InitializeOutput, metadata middleware.Metadata, err error) {
+			ctx, cancel := context.WithTimeout(ctx, c.ApiCallTimeout)
+			defer cancel()
+			return next.HandleInitialize(ctx, in)
+		}), middleware.After)
+}
+
+func (c *SdTimeoutConfig) pollInterval() time.Duration {
+	if c == nil || c.OperationPollInterval <= 0 {
+		return defaultOperationPollInterval
+	}
+	return c.OperationPollInterval
+}
+
+func (c *SdTimeoutConfig) pollTimeout() time.Duration {
+	if c == nil || c.OperationPollTimeout <= 0 {
+		return defaultOperationPollTimeout
+	}
+	return c.OperationPollTimeout
+}
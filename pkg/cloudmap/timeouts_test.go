@@ -0,0 +1,82 @@
+package cloudmap
+
+import (
+	"context"
+	"flag"
+	"github.com/aws/aws-sdk-go-v2/aws"
+	awshttp "github.com/aws/aws-sdk-go-v2/aws/transport/http"
+	"github.com/aws/smithy-go/middleware"
+	"github.com/stretchr/testify/assert"
+	"testing"
+	"time"
+)
+
+func TestSdTimeoutConfig_BindFlags(t *testing.T) {
+	config := NewDefaultSdTimeoutConfig()
+	fs := flag.NewFlagSet("test", flag.ContinueOnError)
+	config.BindFlags(fs)
+
+	assert.NoError(t, fs.Parse([]string{"--aws-api-call-timeout=30s", "--operation-poll-timeout=2m"}))
+	assert.Equal(t, 30*time.Second, config.ApiCallTimeout)
+	assert.Equal(t, defaultApiAttemptTimeout, config.ApiAttemptTimeout)
+	assert.Equal(t, defaultOperationPollInterval, config.OperationPollInterval)
+	assert.Equal(t, 2*time.Minute, config.OperationPollTimeout)
+	assert.NoError(t, config.Validate())
+}
+
+func TestSdTimeoutConfig_Validate(t *testing.T) {
+	disabled := NewDefaultSdTimeoutConfig()
+	disabled.ApiCallTimeout = 0
+	disabled.ApiAttemptTimeout = 0
+	assert.NoError(t, disabled.Validate(), "disabled call timeouts")
+
+	negative := NewDefaultSdTimeoutConfig()
+	negative.ApiCallTimeout = -time.Second
+	assert.Error(t, negative.Validate(), "negative call timeout")
+
+	noInterval := NewDefaultSdTimeoutConfig()
+	noInterval.OperationPollInterval = 0
+	assert.Error(t, noInterval.Validate(), "zero poll interval")
+}
+
+func TestSdTimeoutConfig_Apply(t *testing.T) {
+	cfg := aws.Config{HTTPClient: awshttp.NewBuildableClient()}
+	NewDefaultSdTimeoutConfig().Apply(&cfg)
+
+	httpClient, ok := cfg.HTTPClient.(*awshttp.BuildableClient)
+	assert.True(t, ok)
+	assert.Equal(t, defaultApiAttemptTimeout, httpClient.GetTimeout())
+	assert.Len(t, cfg.APIOptions, 1)
+
+	disabled := aws.Config{}
+	(&SdTimeoutConfig{}).Apply(&disabled)
+	assert.Nil(t, disabled.HTTPClient)
+	assert.Empty(t, disabled.APIOptions)
+}
+
+func TestSdTimeoutConfig_CallDeadline(t *testing.T) {
+	config := &SdTimeoutConfig{ApiCallTimeout: time.Minute}
+	stack := middleware.NewStack("test", func() interface{} { return nil })
+	assert.NoError(t, config.addMiddleware(stack))
+
+	var deadline time.Time
+	handler := middleware.DecorateHandler(middleware.HandlerFunc(
+		func(ctx context.Context, input interface{}) (interface{}, middleware.Metadata, error) {
+			deadline, _ = ctx.Deadline()
+			return nil, middleware.Metadata{}, nil
+		}), stack)
+
+	_, _, err := handler.Handle(context.TODO(), nil)
+	assert.NoError(t, err)
+	assert.WithinDuration(t, time.Now().Add(time.Minute), deadline, 5*time.Second)
+}
+
+func TestSdTimeoutConfig_PollDefaults(t *testing.T) {
+	var config *SdTimeoutConfig
+	assert.Equal(t, defaultOperationPollInterval, config.pollInterval())
+	assert.Equal(t, defaultOperationPollTimeout, config.pollTimeout())
+
+	config = &SdTimeoutConfig{OperationPollInterval: time.Second, OperationPollTimeout: time.Minute}
+	assert.Equal(t, time.Second, config.pollInterval())
+	assert.Equal(t, time.Minute, config.pollTimeout())
+}
@@ -0,0 +1,381 @@
+package clusterset
+
+import (
+	"context"
+	"fmt"
+	"github.com/aws/aws-cloud-map-mcs-controller-for-k8s/pkg/api/v1alpha1"
+	"github.com/aws/aws-cloud-map-mcs-controller-for-k8s/pkg/cloudmap"
+	"github.com/aws/aws-cloud-map-mcs-controller-for-k8s/pkg/controllers"
+	"github.com/aws/aws-cloud-map-mcs-controller-for-k8s/pkg/model"
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/util/sets"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+const (
+	// SeverityError marks violations which break multi-cluster services
+	SeverityError = "ERROR"
+	// SeverityWarning marks violations which are allowed, but likely unintended
+	SeverityWarning = "WARNING"
+)
+
+// Member is a cluster of the clusterset.
+type Member struct {
+	// Name identifies the cluster in violations, such as its kubeconfig context
+	Name   string
+	Client client.Client
+}
+
+// Violation is a breach of the namespace sameness assumption of multi-cluster services: namespaces of the same name
+// are the same namespace in every cluster, and services of the same name in the same namespace are the same service.
+type Violation struct {
+	Severity  string
+	Namespace string
+	// Name is the name of the service, empty for violations of the whole namespace
+	Name    string
+	Message string
+}
+
+// Options configure the validation.
+type Options struct {
+	// ClusterSetId restricts the Cloud Map instances to those registered by clusters of the clusterset
+	ClusterSetId string
+}
+
+// memberState holds the resources of a member read for the validation.
+type memberState struct {
+	name string
+	// namespaces are the names of the Kubernetes namespaces of the member
+	namespaces sets.String
+	// cloudMapNamespaces maps the Kubernetes namespaces with exports in any member to their Cloud Map namespace
+	cloudMapNamespaces map[string]string
+	// exports are the exported services, by namespaced name
+	exports map[types.NamespacedName]*v1.Service
+	// services are the services exported by other members, by namespaced name, if the member has them
+	services map[types.NamespacedName]*v1.Service
+}
+
+// Validate reads the exported services of each member, and the Cloud Map services they are exported to, and reports
+// the violations of namespace sameness: namespaces which don't exist in every member or map to different Cloud Map
+// namespaces, services which are exported by some members only, and exported services or Cloud Map instances which
+// disagree on the service definition. Violations are sorted by namespace and service.
+func Validate(ctx context.Context, members []*Member, sdClient cloudmap.ServiceDiscoveryClient, opts Options) ([]Violation, error) {
+	states := make([]*memberState, 0, len(members))
+	exported := make(map[types.NamespacedName]bool)
+	for _, member := range members {
+		state, err := readExports(ctx, member)
+		if err != nil {
+			return nil, fmt.Errorf("unable to read the exports of cluster %s: %w", member.Name, err)
+		}
+		states = append(states, state)
+		for name := range state.exports {
+			exported[name] = true
+		}
+	}
+
+	exportedNamespaces := sets.NewString()
+	for name := range exported {
+		exportedNamespaces.Insert(name.Namespace)
+	}
+	for i, state := range states {
+		if err := readSharedResources(ctx, members[i], state, exported, exportedNamespaces); err != nil {
+			return nil, fmt.Errorf("unable to read the services of cluster %s: %w", state.name, err)
+		}
+	}
+
+	v := &validation{states: states}
+	for _, namespace := range exportedNamespaces.List() {
+		v.checkNamespace(namespace)
+	}
+	for _, name := range sortedNames(exported) {
+		v.checkService(name)
+	}
+	if err := v.checkCloudMap(ctx, sdClient, exported, opts); err != nil {
+		return nil, err
+	}
+
+	sort.SliceStable(v.violations, func(i, j int) bool {
+		a, b := v.violations[i], v.violations[j]
+		if a.Namespace != b.Namespace {
+			return a.Namespace < b.Namespace
+		}
+		return a.Name < b.Name
+	})
+	return v.violations, nil
+}
+
+func readExports(ctx context.Context, member *Member) (*memberState, error) {
+	state := &memberState{
+		name:               member.Name,
+		namespaces:         sets.NewString(),
+		cloudMapNamespaces: make(map[string]string),
+		exports:            make(map[types.NamespacedName]*v1.Service),
+		services:           make(map[types.NamespacedName]*v1.Service),
+	}
+
+	namespaces := v1.NamespaceList{}
+	if err := member.Client.List(ctx, &namespaces); err != nil {
+		return nil, err
+	}
+	for _, namespace := range namespaces.Items {
+		state.namespaces.Insert(namespace.Name)
+	}
+
+	serviceExports := v1alpha1.ServiceExportList{}
+	if err := member.Client.List(ctx, &serviceExports); err != nil {
+		return nil, err
+	}
+	for _, serviceExport := range serviceExports.Items {
+		if serviceExport.DeletionTimestamp != nil {
+			continue
+		}
+		name := types.NamespacedName{Namespace: serviceExport.Namespace, Name: serviceExport.Name}
+		svc := &v1.Service{}
+		if err := member.Client.Get(ctx, name, svc); err != nil {
+			if errors.IsNotFound(err) {
+				// the controller exports nothing until the service is created
+				continue
+			}
+			return nil, err
+		}
+		state.exports[name] = svc
+	}
+	return state, nil
+}
+
+// readSharedResources reads the Cloud Map namespace of the exported namespaces, and the services exported by other
+// members, which the member has without exporting them.
+func readSharedResources(ctx context.Context, member *Member, state *memberState,
+	exported map[types.NamespacedName]bool, exportedNamespaces sets.String) error {
+	clusterConfig, err := controllers.LoadClusterConfig(ctx, member.Client)
+	if err != nil {
+		return err
+	}
+	for _, namespace := range exportedNamespaces.List() {
+		if !state.namespaces.Has(namespace) {
+			continue
+		}
+		settings, err := controllers.ResolveSyncSettings(ctx, member.Client, clusterConfig, namespace)
+		if err != nil {
+			return err
+		}
+		state.cloudMapNamespaces[namespace] = settings.CloudMapNamespace
+	}
+
+	for name := range exported {
+		if _, ok := state.exports[name]; ok || !state.namespaces.Has(name.Namespace) {
+			continue
+		}
+		svc := &v1.Service{}
+		if err := member.Client.Get(ctx, name, svc); err != nil {
+			if errors.IsNotFound(err) {
+				continue
+			}
+			return err
+		}
+		state.services[name] = svc
+	}
+	return nil
+}
+
+type validation struct {
+	states     []*memberState
+	violations []Violation
+}
+
+func (v *validation) report(severity string, namespace string, name string, format string, args ...interface{}) {
+	v.violations = append(v.violations, Violation{
+		Severity:  severity,
+		Namespace: namespace,
+		Name:      name,
+		Message:   fmt.Sprintf(format, args...),
+	})
+}
+
+// checkNamespace checks a namespace with exported services exists in every member, and is mapped to the same Cloud
+// Map namespace.
+func (v *validation) checkNamespace(namespace string) {
+	missing := make([]string, 0)
+	mappings := make(map[string][]string)
+	for _, state := range v.states {
+		if !state.namespaces.Has(namespace) {
+			missing = append(missing, state.name)
+			continue
+		}
+		cmNamespace := state.cloudMapNamespaces[namespace]
+		mappings[cmNamespace] = append(mappings[cmNamespace], state.name)
+	}
+
+	if len(missing) > 0 {
+		v.report(SeverityWarning, namespace, "",
+			"namespace does not exist in %s, the services exported to it can't be imported there",
+			strings.Join(missing, ", "))
+	}
+	if len(mappings) > 1 {
+		v.report(SeverityError, namespace, "",
+			"namespace is mapped to different Cloud Map namespaces: %s", describeGroups(mappings))
+	}
+}
+
+// checkService checks every member which has an exported service exports it, and that the exported services agree
+// on their type and ports.
+func (v *validation) checkService(name types.NamespacedName) {
+	notExported := make([]string, 0)
+	definitions := make(map[string][]string)
+	for _, state := range v.states {
+		if _, ok := state.services[name]; ok {
+			notExported = append(notExported, state.name)
+		}
+		if svc, ok := state.exports[name]; ok {
+			definition := serviceDefinition(svc)
+			definitions[definition] = append(definitions[definition], state.name)
+		}
+	}
+
+	if len(notExported) > 0 {
+		v.report(SeverityWarning, name.Namespace, name.Name,
+			"service is exported by other clusters, but not by %s, its local endpoints are not part of the "+
+				"multi-cluster service", strings.Join(notExported, ", "))
+	}
+	if len(definitions) > 1 {
+		v.report(SeverityError, name.Namespace, name.Name,
+			"exported services have different definitions: %s", describeGroups(definitions))
+	}
+}
+
+// checkCloudMap checks the instances of the Cloud Map services of the exported namespaces agree on the service ports,
+// and that each Cloud Map service with instances is exported by a member.
+func (v *validation) checkCloudMap(ctx context.Context, sdClient cloudmap.ServiceDiscoveryClient,
+	exported map[types.NamespacedName]bool, opts Options) error {
+	// the Kubernetes namespaces of each Cloud Map namespace
+	namespaces := make(map[string]sets.String)
+	for _, state := range v.states {
+		for namespace, cmNamespace := range state.cloudMapNamespaces {
+			if namespaces[cmNamespace] == nil {
+				namespaces[cmNamespace] = sets.NewString()
+			}
+			namespaces[cmNamespace].Insert(namespace)
+		}
+	}
+
+	cmNamespaces := make([]string, 0, len(namespaces))
+	for cmNamespace := range namespaces {
+		cmNamespaces = append(cmNamespaces, cmNamespace)
+	}
+	sort.Strings(cmNamespaces)
+	for _, cmNamespace := range cmNamespaces {
+		services, err := sdClient.ListServices(ctx, cmNamespace)
+		if err != nil {
+			return fmt.Errorf("unable to list the services of Cloud Map namespace %s: %w", cmNamespace, err)
+		}
+		for _, svc := range services {
+			v.checkCloudMapService(svc, namespaces[cmNamespace].List(), exported, opts)
+		}
+	}
+	return nil
+}
+
+func (v *validation) checkCloudMapService(svc *model.Service, namespaces []string,
+	exported map[types.NamespacedName]bool, opts Options) {
+	// the service ports of the instances of each cluster
+	ports := make(map[string]sets.String)
+	for _, endpoint := range model.ExpandEndpoints(svc.Endpoints) {
+		if opts.ClusterSetId != "" && endpoint.Attributes[controllers.ClusterSetIdAttr] != opts.ClusterSetId {
+			continue
+		}
+		clusterId := endpoint.Attributes[controllers.ClusterIdAttr]
+		if ports[clusterId] == nil {
+			ports[clusterId] = sets.NewString()
+		}
+		ports[clusterId].Insert(describePort(endpoint.ServicePort))
+	}
+	if len(ports) == 0 {
+		return
+	}
+	clusterIds := make([]string, 0, len(ports))
+	for clusterId := range ports {
+		clusterIds = append(clusterIds, clusterId)
+	}
+	sort.Strings(clusterIds)
+	definitions := make(map[string][]string)
+	for _, clusterId := range clusterIds {
+		definition := strings.Join(ports[clusterId].List(), ",")
+		definitions[definition] = append(definitions[definition], "cluster "+clusterId)
+	}
+
+	// the violations of a Cloud Map service are reported for each Kubernetes namespace it is imported into
+	for _, namespace := range namespaces {
+		name := types.NamespacedName{Namespace: namespace, Name: svc.Name}
+		if !exported[name] {
+			v.report(SeverityWarning, namespace, svc.Name,
+				"Cloud Map service %s/%s has instances of clusters %s, but no member cluster exports it",
+				svc.Namespace, svc.Name, strings.Join(clusterIds, ", "))
+		}
+		if len(definitions) > 1 {
+			v.report(SeverityError, namespace, svc.Name,
+				"Cloud Map service %s/%s has instances with different service ports: %s",
+				svc.Namespace, svc.Name, describeGroups(definitions))
+		}
+	}
+}
+
+// serviceDefinition describes the type and ports of a service, which exporting clusters must agree on.
+func serviceDefinition(svc *v1.Service) string {
+	serviceType := string(svc.Spec.Type)
+	if serviceType == "" {
+		serviceType = string(v1.ServiceTypeClusterIP)
+	}
+	if svc.Spec.ClusterIP == v1.ClusterIPNone {
+		serviceType = "Headless"
+	}
+
+	ports := make([]string, 0, len(svc.Spec.Ports))
+	for _, port := range svc.Spec.Ports {
+		ports = append(ports, describePort(controllers.ServicePortToPort(port)))
+	}
+	sort.Strings(ports)
+	return serviceType + " " + strings.Join(ports, ",")
+}
+
+func describePort(port model.Port) string {
+	protocol := port.Protocol
+	if protocol == "" {
+		protocol = model.TCPProtocol
+	}
+	return strconv.Itoa(int(port.Port)) + "/" + protocol
+}
+
+// describeGroups describes which clusters share each value, such as "80/TCP (a, b), 8080/TCP (c)".
+func describeGroups(groups map[string][]string) string {
+	values := sortedKeys(groups)
+	descriptions := make([]string, 0, len(values))
+	for _, value := range values {
+		descriptions = append(descriptions, fmt.Sprintf("%s (%s)", value, strings.Join(groups[value], ", ")))
+	}
+	return strings.Join(descriptions, ", ")
+}
+
+func sortedKeys(m map[string][]string) []string {
+	keys := make([]string, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+func sortedNames(names map[types.NamespacedName]bool) []types.NamespacedName {
+	sorted := make([]types.NamespacedName, 0, len(names))
+	for name := range names {
+		sorted = append(sorted, name)
+	}
+	sort.Slice(sorted, func(i, j int) bool {
+		return sorted[i].String() < sorted[j].String()
+	})
+	return sorted
+}
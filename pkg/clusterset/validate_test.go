@@ -0,0 +1,123 @@
+package clusterset
+
+import (
+	"context"
+	"github.com/aws/aws-cloud-map-mcs-controller-for-k8s/mocks/pkg/cloudmap"
+	cloudmapv1alpha1 "github.com/aws/aws-cloud-map-mcs-controller-for-k8s/pkg/api/cloudmap/v1alpha1"
+	"github.com/aws/aws-cloud-map-mcs-controller-for-k8s/pkg/api/v1alpha1"
+	"github.com/aws/aws-cloud-map-mcs-controller-for-k8s/pkg/controllers"
+	"github.com/aws/aws-cloud-map-mcs-controller-for-k8s/pkg/model"
+	"github.com/aws/aws-cloud-map-mcs-controller-for-k8s/test"
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/assert"
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	"testing"
+)
+
+func TestValidate(t *testing.T) {
+	// the service is exported by both clusters with different ports, web is only exported by a, and the namespace
+	// only-a does not exist in b
+	memberA := newMember(t, "a",
+		namespace(test.NsName), namespace("only-a"),
+		service(test.NsName, test.SvcName, test.ServicePort1), serviceExport(test.NsName, test.SvcName),
+		service(test.NsName, "web", 80), serviceExport(test.NsName, "web"),
+		service("only-a", "x", 80), serviceExport("only-a", "x"))
+	memberB := newMember(t, "b",
+		namespace(test.NsName),
+		service(test.NsName, test.SvcName, test.ServicePort2), serviceExport(test.NsName, test.SvcName),
+		service(test.NsName, "web", 80))
+
+	mockController := gomock.NewController(t)
+	defer mockController.Finish()
+
+	endpoint1, endpoint2 := test.GetTestEndpoint1(), test.GetTestEndpoint2()
+	endpoint1.Attributes[controllers.ClusterIdAttr] = "cluster-1"
+	endpoint2.Attributes[controllers.ClusterIdAttr] = "cluster-2"
+	stale := test.GetTestEndpoint1()
+	stale.Attributes[controllers.ClusterIdAttr] = "cluster-3"
+
+	sdClient := cloudmap.NewMockServiceDiscoveryClient(mockController)
+	sdClient.EXPECT().ListServices(gomock.Any(), test.NsName).Return([]*model.Service{
+		test.GetTestServiceWithEndpoint([]*model.Endpoint{endpoint1, endpoint2}),
+		{Namespace: test.NsName, Name: "stale", Endpoints: []*model.Endpoint{stale}},
+		{Namespace: test.NsName, Name: "empty"},
+	}, nil)
+	sdClient.EXPECT().ListServices(gomock.Any(), "only-a").Return(nil, nil)
+
+	violations, err := Validate(context.TODO(), []*Member{memberA, memberB}, sdClient, Options{})
+	assert.NoError(t, err)
+	assert.Equal(t, []Violation{
+		{Severity: SeverityWarning, Namespace: test.NsName, Name: "stale",
+			Message: "Cloud Map service ns-name/stale has instances of clusters cluster-3, but no member cluster exports it"},
+		{Severity: SeverityError, Namespace: test.NsName, Name: test.SvcName,
+			Message: "exported services have different definitions: ClusterIP 11/TCP (a), ClusterIP 22/TCP (b)"},
+		{Severity: SeverityError, Namespace: test.NsName, Name: test.SvcName,
+			Message: "Cloud Map service ns-name/svc-name has instances with different service ports: " +
+				"11/TCP (cluster cluster-1), 22/TCP (cluster cluster-2)"},
+		{Severity: SeverityWarning, Namespace: test.NsName, Name: "web",
+			Message: "service is exported by other clusters, but not by b, its local endpoints are not part of the " +
+				"multi-cluster service"},
+		{Severity: SeverityWarning, Namespace: "only-a",
+			Message: "namespace does not exist in b, the services exported to it can't be imported there"},
+	}, violations)
+}
+
+func TestValidate_NamespaceMapping(t *testing.T) {
+	config := &cloudmapv1alpha1.ClusterCloudMapConfig{
+		ObjectMeta: metav1.ObjectMeta{Name: cloudmapv1alpha1.ClusterCloudMapConfigName},
+		Spec: cloudmapv1alpha1.ClusterCloudMapConfigSpec{
+			NamespaceMapping: cloudmapv1alpha1.NamespaceMapping{Prefix: "prod-"},
+		},
+	}
+	memberA := newMember(t, "a", namespace(test.NsName),
+		service(test.NsName, test.SvcName, test.ServicePort1), serviceExport(test.NsName, test.SvcName))
+	memberB := newMember(t, "b", namespace(test.NsName), config,
+		service(test.NsName, test.SvcName, test.ServicePort1), serviceExport(test.NsName, test.SvcName))
+
+	mockController := gomock.NewController(t)
+	defer mockController.Finish()
+
+	sdClient := cloudmap.NewMockServiceDiscoveryClient(mockController)
+	sdClient.EXPECT().ListServices(gomock.Any(), test.NsName).Return(nil, nil)
+	sdClient.EXPECT().ListServices(gomock.Any(), "prod-"+test.NsName).Return(nil, nil)
+
+	violations, err := Validate(context.TODO(), []*Member{memberA, memberB}, sdClient, Options{})
+	assert.NoError(t, err)
+	assert.Equal(t, []Violation{
+		{Severity: SeverityError, Namespace: test.NsName,
+			Message: "namespace is mapped to different Cloud Map namespaces: ns-name (a), prod-ns-name (b)"},
+	}, violations)
+}
+
+func newMember(t *testing.T, name string, objects ...runtime.Object) *Member {
+	scheme := runtime.NewScheme()
+	assert.NoError(t, clientgoscheme.AddToScheme(scheme))
+	assert.NoError(t, v1alpha1.AddToScheme(scheme))
+	assert.NoError(t, cloudmapv1alpha1.AddToScheme(scheme))
+	return &Member{
+		Name:   name,
+		Client: fake.NewClientBuilder().WithScheme(scheme).WithRuntimeObjects(objects...).Build(),
+	}
+}
+
+func namespace(name string) *v1.Namespace {
+	return &v1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: name}}
+}
+
+func service(namespace string, name string, port int32) *v1.Service {
+	return &v1.Service{
+		ObjectMeta: metav1.ObjectMeta{Namespace: namespace, Name: name},
+		Spec: v1.ServiceSpec{
+			Type:  v1.ServiceTypeClusterIP,
+			Ports: []v1.ServicePort{{Port: port, Protocol: v1.ProtocolTCP}},
+		},
+	}
+}
+
+func serviceExport(namespace string, name string) *v1alpha1.ServiceExport {
+	return &v1alpha1.ServiceExport{ObjectMeta: metav1.ObjectMeta{Namespace: namespace, Name: name}}
+}
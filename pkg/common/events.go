@@ -0,0 +1,64 @@
+package common
+
+import (
+	"flag"
+	"k8s.io/client-go/tools/record"
+	"time"
+)
+
+const (
+	defaultEventBurst               = 10
+	defaultEventQPS                 = 1.0 / 300.0
+	defaultEventMaxSimilar          = 5
+	defaultEventAggregationInterval = 10 * time.Minute
+)
+
+// EventConfig controls correlation and rate limiting of Kubernetes events emitted by the controller. Identical events
+// are always de-duplicated into a single event with an increasing count. Similar events (same object, type and reason)
+// are combined into a single aggregated event once MaxSimilarEvents is exceeded within the AggregationInterval.
+type EventConfig struct {
+	// Burst is the number of events per object which are accepted before rate limiting applies.
+	Burst int
+	// QPS is the sustained rate of events per object accepted once the burst is exhausted.
+	QPS float64
+	// MaxSimilarEvents is the number of similar events within the AggregationInterval before they are aggregated.
+	MaxSimilarEvents int
+	// AggregationInterval is the time window in which similar events are aggregated.
+	AggregationInterval time.Duration
+}
+
+// NewDefaultEventConfig returns the default event correlation and rate limiting settings.
+func NewDefaultEventConfig() *EventConfig {
+	return &EventConfig{
+		Burst:               defaultEventBurst,
+		QPS:                 defaultEventQPS,
+		MaxSimilarEvents:    defaultEventMaxSimilar,
+		AggregationInterval: defaultEventAggregationInterval,
+	}
+}
+
+// BindFlags registers the event settings as command line flags.
+func (c *EventConfig) BindFlags(fs *flag.FlagSet) {
+	fs.IntVar(&c.Burst, "event-burst", c.Burst,
+		"Number of events per object accepted before event rate limiting applies.")
+	fs.Float64Var(&c.QPS, "event-qps", c.QPS,
+		"Sustained rate of events per object accepted once the event burst is exhausted.")
+	fs.IntVar(&c.MaxSimilarEvents, "event-max-similar", c.MaxSimilarEvents,
+		"Number of similar events for an object within the aggregation interval before they are combined.")
+	fs.DurationVar(&c.AggregationInterval, "event-aggregation-interval", c.AggregationInterval,
+		"Time window in which similar events for an object are combined.")
+}
+
+// NewEventBroadcaster creates an event broadcaster which correlates and rate limits events according to the config.
+func NewEventBroadcaster(c *EventConfig) record.EventBroadcaster {
+	return record.NewBroadcasterWithCorrelatorOptions(c.correlatorOptions())
+}
+
+func (c *EventConfig) correlatorOptions() record.CorrelatorOptions {
+	return record.CorrelatorOptions{
+		BurstSize:            c.Burst,
+		QPS:                  float32(c.QPS),
+		MaxEvents:            c.MaxSimilarEvents,
+		MaxIntervalInSeconds: int(c.AggregationInterval.Seconds()),
+	}
+}
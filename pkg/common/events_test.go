@@ -0,0 +1,42 @@
+package common
+
+import (
+	"flag"
+	"github.com/stretchr/testify/assert"
+	"k8s.io/client-go/tools/record"
+	"testing"
+	"time"
+)
+
+func TestEventConfig_BindFlags(t *testing.T) {
+	c := NewDefaultEventConfig()
+	fs := flag.NewFlagSet("test", flag.ContinueOnError)
+	c.BindFlags(fs)
+
+	err := fs.Parse([]string{
+		"--event-burst=3",
+		"--event-qps=0.5",
+		"--event-max-similar=2",
+		"--event-aggregation-interval=1m",
+	})
+	assert.NoError(t, err)
+
+	assert.Equal(t, &EventConfig{
+		Burst:               3,
+		QPS:                 0.5,
+		MaxSimilarEvents:    2,
+		AggregationInterval: time.Minute,
+	}, c)
+}
+
+func TestEventConfig_CorrelatorOptions(t *testing.T) {
+	c := NewDefaultEventConfig()
+
+	assert.Equal(t, record.CorrelatorOptions{
+		BurstSize:            defaultEventBurst,
+		QPS:                  float32(defaultEventQPS),
+		MaxEvents:            defaultEventMaxSimilar,
+		MaxIntervalInSeconds: 600,
+	}, c.correlatorOptions())
+	assert.NotNil(t, NewEventBroadcaster(c))
+}
@@ -0,0 +1,248 @@
+package common
+
+import (
+	"flag"
+	"fmt"
+	"github.com/go-logr/logr"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+	ctrlzap "sigs.k8s.io/controller-runtime/pkg/log/zap"
+	"strings"
+	"sync"
+)
+
+// LogLevel is the verbosity of a logger. Errors are always logged.
+type LogLevel int
+
+const (
+	ErrorLevel LogLevel = iota
+	InfoLevel
+	DebugLevel
+)
+
+const (
+	JsonLogFormat    = "json"
+	ConsoleLogFormat = "console"
+)
+
+var (
+	logLevelNames = map[string]LogLevel{
+		"error": ErrorLevel,
+		"info":  InfoLevel,
+		"debug": DebugLevel,
+	}
+
+	// sinkLevel is the level of the underlying zap logger, it is kept at the most verbose configured level, and the
+	// root logger filters each component at its own level
+	sinkLevel = zap.NewAtomicLevelAt(zapcore.InfoLevel)
+
+	levelsMu sync.RWMutex
+	// defaultLevel applies to all components without a specific level, loggers pass everything to the sink until
+	// logging is configured.
+	defaultLevel    = DebugLevel
+	componentLevels = map[string]LogLevel{}
+)
+
+// LogConfig configures the log output of the controller.
+type LogConfig struct {
+	// Level is the default log level: error, info or debug.
+	Level string
+	// Format is the log output format: json or console.
+	Format string
+	// ComponentLevels overrides the log level per component, as comma separated component=level pairs,
+	// e.g. "cloudmap=debug,controllers.ServiceExport=error". Components match by dotted logger name prefix.
+	ComponentLevels string
+}
+
+// NewDefaultLogConfig returns the default log settings.
+func NewDefaultLogConfig() *LogConfig {
+	return &LogConfig{
+		Level:  "info",
+		Format: JsonLogFormat,
+	}
+}
+
+// BindFlags registers the log settings as command line flags.
+func (c *LogConfig) BindFlags(fs *flag.FlagSet) {
+	fs.StringVar(&c.Level, "log-level", c.Level, "Default log level: error, info or debug.")
+	fs.StringVar(&c.Format, "log-format", c.Format, "Log output format: json or console.")
+	fs.StringVar(&c.ComponentLevels, "log-component-levels", c.ComponentLevels,
+		"Comma separated component=level overrides of the log level, e.g. "+
+			"\"cloudmap=debug,cloudmap.poller=info,controllers.ServiceExport=debug\".")
+}
+
+// NewLogr creates the root logger for the config and applies the configured log levels.
+func (c *LogConfig) NewLogr() (logr.Logger, error) {
+	opts := []ctrlzap.Opts{ctrlzap.Level(sinkLevel)}
+
+	switch c.Format {
+	case JsonLogFormat:
+		opts = append(opts, ctrlzap.JSONEncoder())
+	case ConsoleLogFormat:
+		opts = append(opts, ctrlzap.ConsoleEncoder())
+	default:
+		return nil, fmt.Errorf("unsupported log format %q", c.Format)
+	}
+
+	if err := c.Apply(); err != nil {
+		return nil, err
+	}
+
+	return levelFilter{log: ctrlzap.New(opts...)}, nil
+}
+
+// Apply updates the log levels of all loggers at runtime.
+func (c *LogConfig) Apply() error {
+	level, err := ParseLogLevel(c.Level)
+	if err != nil {
+		return err
+	}
+
+	components, err := ParseComponentLevels(c.ComponentLevels)
+	if err != nil {
+		return err
+	}
+
+	SetLogLevels(level, components)
+	return nil
+}
+
+// SetLogLevels sets the default log level and the per component overrides.
+func SetLogLevels(level LogLevel, components map[string]LogLevel) {
+	levelsMu.Lock()
+	defer levelsMu.Unlock()
+
+	defaultLevel = level
+	componentLevels = make(map[string]LogLevel, len(components))
+	maxLevel := level
+	for component, componentLevel := range components {
+		componentLevels[component] = componentLevel
+		if componentLevel > maxLevel {
+			maxLevel = componentLevel
+		}
+	}
+
+	sinkLevel.SetLevel(maxLevel.zapLevel())
+}
+
+// ParseLogLevel converts a level name to a LogLevel.
+func ParseLogLevel(name string) (LogLevel, error) {
+	level, ok := logLevelNames[strings.ToLower(strings.TrimSpace(name))]
+	if !ok {
+		return ErrorLevel, fmt.Errorf("unsupported log level %q", name)
+	}
+	return level, nil
+}
+
+// ParseComponentLevels converts comma separated component=level pairs to a map of component log levels.
+func ParseComponentLevels(s string) (map[string]LogLevel, error) {
+	components := make(map[string]LogLevel)
+	for _, pair := range strings.Split(s, ",") {
+		if strings.TrimSpace(pair) == "" {
+			continue
+		}
+
+		kv := strings.SplitN(pair, "=", 2)
+		if len(kv) != 2 || strings.TrimSpace(kv[0]) == "" {
+			return nil, fmt.Errorf("invalid component log level %q, expected component=level", pair)
+		}
+
+		level, err := ParseLogLevel(kv[1])
+		if err != nil {
+			return nil, err
+		}
+		components[strings.TrimSpace(kv[0])] = level
+	}
+	return components, nil
+}
+
+// String returns the name of the log level.
+func (level LogLevel) String() string {
+	switch level {
+	case DebugLevel:
+		return "debug"
+	case InfoLevel:
+		return "info"
+	default:
+		return "error"
+	}
+}
+
+func (level LogLevel) zapLevel() zapcore.Level {
+	switch level {
+	case DebugLevel:
+		return zapcore.DebugLevel
+	case InfoLevel:
+		return zapcore.InfoLevel
+	default:
+		return zapcore.ErrorLevel
+	}
+}
+
+// levelFilter applies the component log levels to the loggers derived from the root logger, including the loggers of
+// controller-runtime which don't go through Logger. The component is the dotted name of the logger, and V(1) and
+// above log at the debug level.
+type levelFilter struct {
+	log       logr.Logger
+	component string
+	debug     bool
+}
+
+func (f levelFilter) level() LogLevel {
+	if f.debug {
+		return DebugLevel
+	}
+	return InfoLevel
+}
+
+func (f levelFilter) Enabled() bool {
+	return isLevelEnabled(f.component, f.level()) && f.log.Enabled()
+}
+
+func (f levelFilter) Info(msg string, keysAndValues ...interface{}) {
+	if isLevelEnabled(f.component, f.level()) {
+		f.log.Info(msg, keysAndValues...)
+	}
+}
+
+func (f levelFilter) Error(err error, msg string, keysAndValues ...interface{}) {
+	f.log.Error(err, msg, keysAndValues...)
+}
+
+func (f levelFilter) V(level int) logr.Logger {
+	return levelFilter{log: f.log.V(level), component: f.component, debug: f.debug || level > 0}
+}
+
+func (f levelFilter) WithValues(keysAndValues ...interface{}) logr.Logger {
+	return levelFilter{log: f.log.WithValues(keysAndValues...), component: f.component, debug: f.debug}
+}
+
+func (f levelFilter) WithName(name string) logr.Logger {
+	component := name
+	if f.component != "" {
+		component = f.component + "." + name
+	}
+	return levelFilter{log: f.log.WithName(name), component: component, debug: f.debug}
+}
+
+// isLevelEnabled checks the level against the most specific override matching the component, e.g. a
+// "controllers.ServiceExport" logger uses the "controllers.ServiceExport" level, then the "controllers" level,
+// then the default level.
+func isLevelEnabled(component string, level LogLevel) bool {
+	levelsMu.RLock()
+	defer levelsMu.RUnlock()
+
+	for c := component; c != ""; {
+		if componentLevel, found := componentLevels[c]; found {
+			return level <= componentLevel
+		}
+
+		i := strings.LastIndex(c, ".")
+		if i < 0 {
+			break
+		}
+		c = c[:i]
+	}
+
+	return level <= defaultLevel
+}
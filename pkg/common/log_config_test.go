@@ -0,0 +1,101 @@
+package common
+
+import (
+	"flag"
+	"github.com/go-logr/logr"
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/zap/zapcore"
+	"testing"
+)
+
+func TestLogConfig_BindFlags(t *testing.T) {
+	c := NewDefaultLogConfig()
+	fs := flag.NewFlagSet("test", flag.ContinueOnError)
+	c.BindFlags(fs)
+
+	err := fs.Parse([]string{"--log-level=error", "--log-format=console", "--log-component-levels=cloudmap=debug"})
+	assert.NoError(t, err)
+	assert.Equal(t, &LogConfig{Level: "error", Format: ConsoleLogFormat, ComponentLevels: "cloudmap=debug"}, c)
+}
+
+func TestLogConfig_NewLogr(t *testing.T) {
+	defer SetLogLevels(DebugLevel, nil)
+
+	c := &LogConfig{Level: "info", Format: ConsoleLogFormat}
+	l, err := c.NewLogr()
+	assert.NoError(t, err)
+	assert.NotNil(t, l)
+
+	c.Format = "xml"
+	_, err = c.NewLogr()
+	assert.Error(t, err)
+}
+
+func TestParseComponentLevels(t *testing.T) {
+	levels, err := ParseComponentLevels("cloudmap=debug, controllers.ServiceExport=ERROR,")
+	assert.NoError(t, err)
+	assert.Equal(t, map[string]LogLevel{"cloudmap": DebugLevel, "controllers.ServiceExport": ErrorLevel}, levels)
+
+	_, err = ParseComponentLevels("cloudmap")
+	assert.Error(t, err)
+
+	_, err = ParseComponentLevels("cloudmap=verbose")
+	assert.Error(t, err)
+}
+
+func TestIsLevelEnabled(t *testing.T) {
+	defer SetLogLevels(DebugLevel, nil)
+
+	SetLogLevels(InfoLevel, map[string]LogLevel{
+		"cloudmap":        DebugLevel,
+		"cloudmap.poller": ErrorLevel,
+	})
+
+	assert.Equal(t, zapcore.DebugLevel, sinkLevel.Level(), "sink allows the most verbose component")
+
+	assert.True(t, isLevelEnabled("controllers.ServiceExport", InfoLevel))
+	assert.False(t, isLevelEnabled("controllers.ServiceExport", DebugLevel))
+	assert.True(t, isLevelEnabled("cloudmap", DebugLevel))
+	assert.True(t, isLevelEnabled("cloudmap.cache", DebugLevel), "inherits parent component level")
+	assert.False(t, isLevelEnabled("cloudmap.poller", InfoLevel))
+	assert.True(t, isLevelEnabled("cloudmap.poller", ErrorLevel))
+	assert.False(t, isLevelEnabled("", DebugLevel), "default level applies without component")
+}
+
+// recordingLogr records the messages which reach the sink.
+type recordingLogr struct {
+	messages *[]string
+}
+
+func (r recordingLogr) Enabled() bool                     { return true }
+func (r recordingLogr) Info(msg string, _ ...interface{}) { *r.messages = append(*r.messages, msg) }
+func (r recordingLogr) Error(_ error, msg string, _ ...interface{}) {
+	*r.messages = append(*r.messages, msg)
+}
+func (r recordingLogr) V(int) logr.Logger                     { return r }
+func (r recordingLogr) WithValues(...interface{}) logr.Logger { return r }
+func (r recordingLogr) WithName(string) logr.Logger           { return r }
+
+func TestLevelFilter(t *testing.T) {
+	defer SetLogLevels(DebugLevel, nil)
+
+	SetLogLevels(InfoLevel, map[string]LogLevel{"cloudmap": DebugLevel})
+
+	messages := make([]string, 0)
+	root := levelFilter{log: recordingLogr{messages: &messages}}
+	// controller-runtime loggers log debug messages with V(1), only the debug component passes them
+	root.WithName("controller-runtime").WithName("manager").V(1).Info("runtime debug")
+	root.WithName("controller-runtime").Info("runtime info")
+	root.WithName("cloudmap").WithName("cache").V(1).Info("cloudmap debug")
+	assert.False(t, root.WithName("controllers").V(1).Enabled())
+	assert.True(t, root.WithName("cloudmap").V(1).Enabled())
+	root.WithName("controllers").V(1).Error(nil, "error")
+
+	assert.Equal(t, []string{"runtime info", "cloudmap debug", "error"}, messages)
+}
+
+func TestLogLevel_String(t *testing.T) {
+	assert.Equal(t, "debug", DebugLevel.String())
+	assert.Equal(t, "info", InfoLevel.String())
+	assert.Equal(t, "error", ErrorLevel.String())
+}
@@ -3,6 +3,7 @@ package common
 import (
 	"github.com/go-logr/logr"
 	ctrl "sigs.k8s.io/controller-runtime"
+	"strings"
 )
 
 type Logger interface {
@@ -13,6 +14,8 @@ type Logger interface {
 
 type logger struct {
 	log logr.Logger
+	// component is the dotted logger name used to look up the component log level
+	component string
 }
 
 func NewLogger(name string, names ...string) Logger {
@@ -20,7 +23,7 @@ func NewLogger(name string, names ...string) Logger {
 	for _, n := range names {
 		l = l.WithName(n)
 	}
-	return logger{log: l}
+	return logger{log: l, component: strings.Join(append([]string{name}, names...), ".")}
 }
 
 func NewLoggerWithLogr(l logr.Logger) Logger {
@@ -28,11 +31,15 @@ func NewLoggerWithLogr(l logr.Logger) Logger {
 }
 
 func (l logger) Info(msg string, keysAndValues ...interface{}) {
-	l.log.Info(msg, keysAndValues...)
+	if isLevelEnabled(l.component, InfoLevel) {
+		l.log.Info(msg, keysAndValues...)
+	}
 }
 
 func (l logger) Debug(msg string, keysAndValues ...interface{}) {
-	l.log.V(1).Info(msg, keysAndValues...)
+	if isLevelEnabled(l.component, DebugLevel) {
+		l.log.V(1).Info(msg, keysAndValues...)
+	}
 }
 
 func (l logger) Error(err error, msg string, keysAndValues ...interface{}) {
@@ -40,5 +47,5 @@ func (l logger) Error(err error, msg string, keysAndValues ...interface{}) {
 }
 
 func (l logger) WithValues(keysAndValues ...interface{}) Logger {
-	return logger{log: l.log.WithValues(keysAndValues...)}
+	return logger{log: l.log.WithValues(keysAndValues...), component: l.component}
 }
@@ -0,0 +1,14 @@
+package common
+
+import "strings"
+
+// SplitNamespaces returns the namespaces of a comma separated list, ignoring blanks.
+func SplitNamespaces(value string) []string {
+	namespaces := make([]string, 0)
+	for _, ns := range strings.Split(value, ",") {
+		if ns = strings.TrimSpace(ns); ns != "" {
+			namespaces = append(namespaces, ns)
+		}
+	}
+	return namespaces
+}
@@ -0,0 +1,11 @@
+package common
+
+import (
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func TestSplitNamespaces(t *testing.T) {
+	assert.Equal(t, []string{"ns1", "ns2"}, SplitNamespaces(" ns1, ,ns2,"))
+	assert.Empty(t, SplitNamespaces(""))
+}
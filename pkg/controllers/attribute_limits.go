@@ -0,0 +1,220 @@
+package controllers
+
+import (
+	"errors"
+	"fmt"
+	"github.com/aws/aws-cloud-map-mcs-controller-for-k8s/pkg/api/v1alpha1"
+	"github.com/aws/aws-cloud-map-mcs-controller-for-k8s/pkg/model"
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/util/sets"
+	"sort"
+	"strings"
+	"unicode/utf8"
+)
+
+// AttributeLimitPolicy is the handling of endpoints whose Cloud Map instance attributes exceed the Cloud Map limits.
+type AttributeLimitPolicy string
+
+const (
+	// AttributeLimitFail fails the export of the service, reported by the Synced condition of the ServiceExport
+	AttributeLimitFail AttributeLimitPolicy = "fail"
+	// AttributeLimitDrop drops the attributes exceeding the limits
+	AttributeLimitDrop AttributeLimitPolicy = "drop"
+	// AttributeLimitTruncate truncates the values exceeding the limits, and drops the attributes which can't be
+	// truncated
+	AttributeLimitTruncate AttributeLimitPolicy = "truncate"
+
+	// AttributeLimitExceededReason is the condition and event reason for instance attributes exceeding the Cloud Map
+	// limits
+	AttributeLimitExceededReason = "CloudMapAttributeLimitExceeded"
+
+	// maxAttributesLength is the maximum total length of the keys and values of the attributes of a Cloud Map instance
+	maxAttributesLength = 5000
+)
+
+// ErrAttributeLimitExceeded is returned for endpoints whose attributes exceed the Cloud Map limits under the fail
+// policy, or can't be brought within the limits.
+var ErrAttributeLimitExceeded = errors.New("instance attributes exceed the Cloud Map limits")
+
+// attributeLimitError is returned for an endpoint whose attributes exceed the Cloud Map limits, it wraps
+// ErrAttributeLimitExceeded.
+type attributeLimitError struct {
+	endpointId string
+	violations []string
+}
+
+func (e *attributeLimitError) Error() string {
+	return fmt.Sprintf("%s: endpoint %s: %s", ErrAttributeLimitExceeded, e.endpointId, strings.Join(e.violations, ", "))
+}
+
+func (e *attributeLimitError) Unwrap() error {
+	return ErrAttributeLimitExceeded
+}
+
+// protectedAttributes identify the cluster of the endpoint, and are neither dropped nor truncated.
+var protectedAttributes = sets.NewString(ClusterIdAttr, ClusterSetIdAttr)
+
+// structuredAttributes hold encoded values, and are dropped rather than truncated.
+var structuredAttributes = sets.NewString(ExportedLabelsAttr, ExportedAnnotationsAttr, ExportCreationTimestampAttr)
+
+// Validate returns an error if the policy is unknown. The empty policy is the fail policy.
+func (p AttributeLimitPolicy) Validate() error {
+	switch p {
+	case "", AttributeLimitFail, AttributeLimitDrop, AttributeLimitTruncate:
+		return nil
+	default:
+		return fmt.Errorf("unknown attribute limit policy %q, expected one of %s, %s or %s", p,
+			AttributeLimitFail, AttributeLimitDrop, AttributeLimitTruncate)
+	}
+}
+
+// enforceAttributeLimits applies the attribute limit policy to the endpoints before they're registered, and emits a
+// warning event for attributes dropped or truncated.
+func (r *ServiceExportReconciler) enforceAttributeLimits(serviceExport *v1alpha1.ServiceExport, endpoints []*model.Endpoint) error {
+	adjusted := sets.NewString()
+	for _, endpoint := range endpoints {
+		violations, err := applyAttributeLimits(endpoint, r.AttributeLimitPolicy)
+		if err != nil {
+			r.Recorder.Event(serviceExport, v1.EventTypeWarning, AttributeLimitExceededReason, err.Error())
+			return err
+		}
+		adjusted.Insert(violations...)
+	}
+
+	if adjusted.Len() > 0 {
+		message := fmt.Sprintf("applied the %s policy to instance attributes exceeding the Cloud Map limits: %s",
+			r.AttributeLimitPolicy, strings.Join(adjusted.List(), ", "))
+		r.Log.Info(message, "namespace", serviceExport.Namespace, "name", serviceExport.Name)
+		r.Recorder.Event(serviceExport, v1.EventTypeWarning, AttributeLimitExceededReason, message)
+	}
+	return nil
+}
+
+// applyAttributeLimits drops or truncates the custom attributes of the endpoint according to the policy if its
+// attributes exceed the Cloud Map limits, and returns the violated limits. An error is returned under the fail policy,
+// or if the endpoint still exceeds the limits.
+func applyAttributeLimits(endpoint *model.Endpoint, policy AttributeLimitPolicy) ([]string, error) {
+	violations := attributeLimitViolations(endpoint.GetCloudMapAttributes())
+	if len(violations) == 0 {
+		return nil, nil
+	}
+
+	if policy == AttributeLimitDrop || policy == AttributeLimitTruncate {
+		reduceAttributes(endpoint, policy)
+	}
+	if remaining := attributeLimitViolations(endpoint.GetCloudMapAttributes()); len(remaining) > 0 {
+		return violations, &attributeLimitError{endpointId: endpoint.Id, violations: remaining}
+	}
+	return violations, nil
+}
+
+// attributeLimitViolations returns the Cloud Map limits the instance attributes violate.
+func attributeLimitViolations(attributes map[string]string) []string {
+	violations := make([]string, 0)
+	for _, key := range sortedKeys(attributes) {
+		switch {
+		case !attributeKeyPattern.MatchString(key):
+			violations = append(violations, fmt.Sprintf("invalid attribute key %q", key))
+		case len(attributes[key]) > maxAttributeValueLength:
+			violations = append(violations, fmt.Sprintf("value of attribute %s exceeds %d characters", key,
+				maxAttributeValueLength))
+		}
+	}
+	if count := customAttributeCount(attributes); count > maxCustomAttributes {
+		violations = append(violations, fmt.Sprintf("%d attributes exceed the limit of %d", count, maxCustomAttributes))
+	}
+	if length := attributesLength(attributes); length > maxAttributesLength {
+		violations = append(violations, fmt.Sprintf("attributes of %d characters exceed the limit of %d", length,
+			maxAttributesLength))
+	}
+	return violations
+}
+
+// reduceAttributes drops the custom attributes of the endpoint with invalid keys, drops or truncates the values
+// exceeding the length limit, and then drops or truncates the largest custom attributes until the count and total
+// length are within the limits.
+func reduceAttributes(endpoint *model.Endpoint, policy AttributeLimitPolicy) {
+	for _, key := range sortedKeys(endpoint.Attributes) {
+		value := endpoint.Attributes[key]
+		switch {
+		case protectedAttributes.Has(key):
+		case !attributeKeyPattern.MatchString(key):
+			delete(endpoint.Attributes, key)
+		case len(value) > maxAttributeValueLength && policy == AttributeLimitTruncate && !structuredAttributes.Has(key):
+			endpoint.Attributes[key] = truncateString(value, maxAttributeValueLength)
+		case len(value) > maxAttributeValueLength:
+			delete(endpoint.Attributes, key)
+		}
+	}
+
+	for {
+		attributes := endpoint.GetCloudMapAttributes()
+		excessCount := customAttributeCount(attributes) - maxCustomAttributes
+		excessLength := attributesLength(attributes) - maxAttributesLength
+		if excessCount <= 0 && excessLength <= 0 {
+			return
+		}
+
+		key := largestAttribute(endpoint.Attributes)
+		if key == "" {
+			return
+		}
+		value := endpoint.Attributes[key]
+		if excessCount <= 0 && policy == AttributeLimitTruncate && !structuredAttributes.Has(key) && len(value) > excessLength {
+			endpoint.Attributes[key] = truncateString(value, len(value)-excessLength)
+		} else {
+			delete(endpoint.Attributes, key)
+		}
+	}
+}
+
+// largestAttribute returns the key of the largest custom attribute which may be dropped or truncated, the highest key
+// of equally large attributes, or an empty key if there is none.
+func largestAttribute(attributes map[string]string) (largest string) {
+	for _, key := range sortedKeys(attributes) {
+		if protectedAttributes.Has(key) {
+			continue
+		}
+		if largest == "" || len(key)+len(attributes[key]) >= len(largest)+len(attributes[largest]) {
+			largest = key
+		}
+	}
+	return largest
+}
+
+// customAttributeCount returns the number of attributes counting towards the Cloud Map custom attribute limit.
+func customAttributeCount(attributes map[string]string) (count int) {
+	for key := range attributes {
+		if !strings.HasPrefix(key, "AWS_") {
+			count++
+		}
+	}
+	return count
+}
+
+func attributesLength(attributes map[string]string) (length int) {
+	for key, value := range attributes {
+		length += len(key) + len(value)
+	}
+	return length
+}
+
+// truncateString truncates the string to at most maxLength bytes, without splitting a multi-byte character.
+func truncateString(s string, maxLength int) string {
+	if len(s) <= maxLength {
+		return s
+	}
+	for maxLength > 0 && !utf8.RuneStart(s[maxLength]) {
+		maxLength--
+	}
+	return s[:maxLength]
+}
+
+func sortedKeys(attributes map[string]string) []string {
+	keys := make([]string, 0, len(attributes))
+	for key := range attributes {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
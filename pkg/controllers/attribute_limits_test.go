@@ -0,0 +1,121 @@
+package controllers
+
+import (
+	"errors"
+	"fmt"
+	"github.com/aws/aws-cloud-map-mcs-controller-for-k8s/test"
+	"github.com/stretchr/testify/assert"
+	"strings"
+	"testing"
+)
+
+func TestAttributeLimitPolicy_Validate(t *testing.T) {
+	for _, policy := range []AttributeLimitPolicy{"", AttributeLimitFail, AttributeLimitDrop, AttributeLimitTruncate} {
+		assert.NoError(t, policy.Validate(), policy)
+	}
+	assert.Error(t, AttributeLimitPolicy("ignore").Validate())
+}
+
+func TestApplyAttributeLimits_WithinLimits(t *testing.T) {
+	endpoint := test.GetTestEndpoint1()
+	endpoint.Attributes["custom"] = "value"
+
+	violations, err := applyAttributeLimits(endpoint, AttributeLimitFail)
+	assert.NoError(t, err)
+	assert.Empty(t, violations)
+	assert.Equal(t, "value", endpoint.Attributes["custom"])
+}
+
+func TestApplyAttributeLimits_ValueLength(t *testing.T) {
+	long := strings.Repeat("a", maxAttributeValueLength+1)
+	tests := []struct {
+		name    string
+		policy  AttributeLimitPolicy
+		want    map[string]string
+		wantErr bool
+	}{
+		{
+			name:    "fail",
+			policy:  AttributeLimitFail,
+			want:    map[string]string{"custom": long, ExportedLabelsAttr: long, ClusterIdAttr: test.ClusterId},
+			wantErr: true,
+		},
+		{
+			name:   "drop",
+			policy: AttributeLimitDrop,
+			want:   map[string]string{ClusterIdAttr: test.ClusterId},
+		},
+		{
+			name:   "truncate",
+			policy: AttributeLimitTruncate,
+			want:   map[string]string{"custom": long[:maxAttributeValueLength], ClusterIdAttr: test.ClusterId},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			endpoint := test.GetTestEndpoint1()
+			endpoint.Attributes = map[string]string{"custom": long, ExportedLabelsAttr: long, ClusterIdAttr: test.ClusterId}
+
+			violations, err := applyAttributeLimits(endpoint, tt.policy)
+			assert.Equal(t, tt.wantErr, errors.Is(err, ErrAttributeLimitExceeded))
+			assert.Len(t, violations, 2)
+			assert.Equal(t, tt.want, endpoint.Attributes)
+		})
+	}
+}
+
+func TestApplyAttributeLimits_Count(t *testing.T) {
+	endpoint := test.GetTestEndpoint1()
+	endpoint.Attributes[ClusterIdAttr] = test.ClusterId
+	for i := 0; i < maxCustomAttributes; i++ {
+		endpoint.Attributes[fmt.Sprintf("custom-%02d", i)] = "value"
+	}
+
+	_, err := applyAttributeLimits(endpoint, AttributeLimitFail)
+	assert.True(t, errors.Is(err, ErrAttributeLimitExceeded))
+
+	violations, err := applyAttributeLimits(endpoint, AttributeLimitTruncate)
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"38 attributes exceed the limit of 30"}, violations)
+	assert.Equal(t, maxCustomAttributes, customAttributeCount(endpoint.GetCloudMapAttributes()))
+	assert.Equal(t, test.ClusterId, endpoint.Attributes[ClusterIdAttr], "cluster attributes are kept")
+	assert.Contains(t, endpoint.Attributes, "custom-00", "the lowest keys of equally large attributes are kept")
+	assert.NotContains(t, endpoint.Attributes, "custom-29")
+}
+
+func TestApplyAttributeLimits_TotalLength(t *testing.T) {
+	value := strings.Repeat("a", 1000)
+	newAttributes := func() map[string]string {
+		return map[string]string{"a": value, "b": value, "c": value, "d": value, "e": value}
+	}
+
+	endpoint := test.GetTestEndpoint1()
+	endpoint.Attributes = newAttributes()
+	_, err := applyAttributeLimits(endpoint, AttributeLimitDrop)
+	assert.NoError(t, err)
+	assert.Len(t, endpoint.Attributes, 4, "the largest attribute is dropped")
+	assert.NotContains(t, endpoint.Attributes, "e")
+
+	endpoint.Attributes = newAttributes()
+	_, err = applyAttributeLimits(endpoint, AttributeLimitTruncate)
+	assert.NoError(t, err)
+	assert.Len(t, endpoint.Attributes, 5)
+	assert.Less(t, len(endpoint.Attributes["e"]), len(value), "the largest attribute is truncated")
+	assert.Equal(t, maxAttributesLength, attributesLength(endpoint.GetCloudMapAttributes()))
+}
+
+func TestApplyAttributeLimits_InvalidKey(t *testing.T) {
+	endpoint := test.GetTestEndpoint1()
+	endpoint.Attributes["invalid key"] = "value"
+
+	violations, err := applyAttributeLimits(endpoint, AttributeLimitTruncate)
+	assert.NoError(t, err)
+	assert.Equal(t, []string{`invalid attribute key "invalid key"`}, violations)
+	assert.NotContains(t, endpoint.Attributes, "invalid key")
+}
+
+func TestTruncateString(t *testing.T) {
+	assert.Equal(t, "abc", truncateString("abc", 5))
+	assert.Equal(t, "ab", truncateString("abc", 2))
+	assert.Equal(t, "a", truncateString("aé", 2), "multi-byte characters aren't split")
+}
@@ -0,0 +1,192 @@
+package controllers
+
+import (
+	"fmt"
+	"github.com/aws/aws-cloud-map-mcs-controller-for-k8s/pkg/api/v1alpha1"
+	"github.com/aws/aws-cloud-map-mcs-controller-for-k8s/pkg/model"
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	// WeightAttr is the attribute of the Cloud Map instances holding the weight of the endpoints of the cluster, from 0
+	// to 100, while a canary is annotated. Instances without the attribute have the full weight. Importing clusters
+	// only honour the weight 0, see weightedEndpoints.
+	WeightAttr = "WEIGHT"
+
+	// CanaryWeightStepAnnotation starts a canary shifting traffic to the endpoints of the cluster: the weight of the
+	// endpoints starts at 0 and increases by the step, in percentage points, every step interval until it reaches 100.
+	// The weight is rolled back to 0 if the ready endpoints fall below the minimum ready percentage, and stays there
+	// until the annotation is removed. Removing the annotation restores the full weight.
+	CanaryWeightStepAnnotation = "multicluster.k8s.aws/canary-weight-step"
+	// CanaryStepIntervalAnnotation sets the interval between the steps of the canary, e.g. "10m"
+	CanaryStepIntervalAnnotation = "multicluster.k8s.aws/canary-step-interval"
+	// CanaryMinReadyAnnotation sets the minimum percentage of ready endpoints below which the canary is rolled back
+	CanaryMinReadyAnnotation = "multicluster.k8s.aws/canary-min-ready-percent"
+
+	// CanaryRolledBackReason is the event reason for canaries rolled back after a readiness regression
+	CanaryRolledBackReason = "CanaryRolledBack"
+	// InvalidCanaryReason is the event reason for invalid canary annotations
+	InvalidCanaryReason = "InvalidCanary"
+
+	// defaultCanaryStepInterval is the interval between the steps of a canary if not annotated
+	defaultCanaryStepInterval = 5 * time.Minute
+	// defaultCanaryMinReady is the minimum percentage of ready endpoints of a canary if not annotated
+	defaultCanaryMinReady = 90
+	// fullWeight is the weight of endpoints receiving their full share of traffic
+	fullWeight = 100
+)
+
+// canarySettings are the settings of the canary of a ServiceExport.
+type canarySettings struct {
+	step     int32
+	interval time.Duration
+	minReady int32
+}
+
+// parseCanarySettings returns the canary settings from the annotations of the ServiceExport, nil if no canary is
+// annotated.
+func parseCanarySettings(serviceExport *v1alpha1.ServiceExport) (*canarySettings, error) {
+	value, found := serviceExport.Annotations[CanaryWeightStepAnnotation]
+	if !found {
+		return nil, nil
+	}
+
+	settings := &canarySettings{interval: defaultCanaryStepInterval, minReady: defaultCanaryMinReady}
+	step, err := strconv.ParseInt(strings.TrimSpace(value), 10, 32)
+	if err != nil || step < 1 || step > fullWeight {
+		return nil, fmt.Errorf("invalid annotation %s: %q is not a percentage between 1 and 100",
+			CanaryWeightStepAnnotation, value)
+	}
+	settings.step = int32(step)
+
+	if value, found = serviceExport.Annotations[CanaryStepIntervalAnnotation]; found {
+		interval, err := time.ParseDuration(strings.TrimSpace(value))
+		if err != nil || interval <= 0 {
+			return nil, fmt.Errorf("invalid annotation %s: %q is not a positive duration",
+				CanaryStepIntervalAnnotation, value)
+		}
+		settings.interval = interval
+	}
+
+	if value, found = serviceExport.Annotations[CanaryMinReadyAnnotation]; found {
+		minReady, err := strconv.ParseInt(strings.TrimSpace(value), 10, 32)
+		if err != nil || minReady < 0 || minReady > 100 {
+			return nil, fmt.Errorf("invalid annotation %s: %q is not a percentage between 0 and 100",
+				CanaryMinReadyAnnotation, value)
+		}
+		settings.minReady = int32(minReady)
+	}
+
+	return settings, nil
+}
+
+// advanceCanary updates the canary status of the ServiceExport from the readiness of its endpoints, and returns the
+// time until the next step of the canary, zero if the canary isn't progressing. Invalid annotations hold the weight.
+func (r *ServiceExportReconciler) advanceCanary(serviceExport *v1alpha1.ServiceExport, endpoints []*model.Endpoint, now time.Time) time.Duration {
+	settings, err := parseCanarySettings(serviceExport)
+	if err != nil {
+		r.Log.Info("holding the weight of an invalid canary", "namespace", serviceExport.Namespace,
+			"name", serviceExport.Name, "reason", err.Error())
+		r.Recorder.Event(serviceExport, v1.EventTypeWarning, InvalidCanaryReason, err.Error())
+		if serviceExport.Status.Canary == nil {
+			serviceExport.Status.Canary = &v1alpha1.CanaryStatus{
+				Phase:              v1alpha1.CanaryProgressing,
+				LastTransitionTime: metav1.NewTime(now),
+			}
+		}
+		return 0
+	}
+	if settings == nil {
+		serviceExport.Status.Canary = nil
+		return 0
+	}
+
+	canary := serviceExport.Status.Canary
+	if canary == nil {
+		r.Log.Info("starting canary", "namespace", serviceExport.Namespace, "name", serviceExport.Name)
+		serviceExport.Status.Canary = &v1alpha1.CanaryStatus{
+			Phase:              v1alpha1.CanaryProgressing,
+			LastTransitionTime: metav1.NewTime(now),
+		}
+		return settings.interval
+	}
+	if canary.Phase != v1alpha1.CanaryProgressing {
+		return 0
+	}
+
+	ready, total := readyEndpointCount(endpoints)
+	if total == 0 {
+		// the weight is held until the endpoints of the cluster are known
+		return settings.interval
+	}
+	if ready*100 < int(settings.minReady)*total {
+		message := fmt.Sprintf("%d of %d endpoints are ready, below %d%%, the weight is rolled back from %d to 0",
+			ready, total, settings.minReady, canary.Weight)
+		r.Log.Info("rolling back canary", "namespace", serviceExport.Namespace, "name", serviceExport.Name,
+			"ready", ready, "total", total, "weight", canary.Weight)
+		r.Recorder.Event(serviceExport, v1.EventTypeWarning, CanaryRolledBackReason, message)
+		canary.Weight = 0
+		canary.Phase = v1alpha1.CanaryRolledBack
+		canary.LastTransitionTime = metav1.NewTime(now)
+		return 0
+	}
+
+	next := canary.LastTransitionTime.Add(settings.interval)
+	if now.Before(next) {
+		return next.Sub(now)
+	}
+	canary.Weight += settings.step
+	canary.LastTransitionTime = metav1.NewTime(now)
+	if canary.Weight >= fullWeight {
+		canary.Weight = fullWeight
+		canary.Phase = v1alpha1.CanaryPromoted
+	}
+	r.Log.Info("shifting canary weight", "namespace", serviceExport.Namespace, "name", serviceExport.Name,
+		"weight", canary.Weight, "phase", canary.Phase)
+	if canary.Phase == v1alpha1.CanaryPromoted {
+		return 0
+	}
+	return settings.interval
+}
+
+// setCanaryWeight sets the weight attribute of the endpoints from the canary status of the ServiceExport.
+func setCanaryWeight(serviceExport *v1alpha1.ServiceExport, endpoints []*model.Endpoint) {
+	if serviceExport.Status.Canary == nil {
+		return
+	}
+	weight := strconv.Itoa(int(serviceExport.Status.Canary.Weight))
+	for _, endpoint := range endpoints {
+		endpoint.Attributes[WeightAttr] = weight
+	}
+}
+
+// weightedEndpoints drops the endpoints with the weight 0, unless no endpoint with a weight above 0 remains so the
+// service keeps being served. The derived Service balances the connections evenly across its endpoints, so importing
+// clusters serve the endpoints with a weight between 1 and 100 like endpoints with the full weight: partial weights
+// are only honoured by clients discovering the instances from Cloud Map with their WEIGHT attribute.
+func weightedEndpoints(endpoints []*model.Endpoint) []*model.Endpoint {
+	result := make([]*model.Endpoint, 0, len(endpoints))
+	for _, endpoint := range endpoints {
+		if endpoint.Attributes[WeightAttr] != "0" {
+			result = append(result, endpoint)
+		}
+	}
+	if len(result) == 0 {
+		return endpoints
+	}
+	return result
+}
+
+// readyEndpointCount returns the number of ready endpoints, and the total number of endpoints.
+func readyEndpointCount(endpoints []*model.Endpoint) (ready int, total int) {
+	for _, endpoint := range endpoints {
+		if endpoint.IsReady() {
+			ready++
+		}
+	}
+	return ready, len(endpoints)
+}
@@ -0,0 +1,100 @@
+package controllers
+
+import (
+	"github.com/aws/aws-cloud-map-mcs-controller-for-k8s/pkg/api/v1alpha1"
+	"github.com/aws/aws-cloud-map-mcs-controller-for-k8s/pkg/common"
+	"github.com/aws/aws-cloud-map-mcs-controller-for-k8s/pkg/model"
+	"github.com/aws/aws-cloud-map-mcs-controller-for-k8s/test"
+	testingLogger "github.com/go-logr/logr/testing"
+	"github.com/stretchr/testify/assert"
+	"k8s.io/client-go/tools/record"
+	"testing"
+	"time"
+)
+
+func TestAdvanceCanary(t *testing.T) {
+	recorder := record.NewFakeRecorder(10)
+	reconciler := &ServiceExportReconciler{Log: common.NewLoggerWithLogr(testingLogger.TestLogger{T: t}), Recorder: recorder}
+	serviceExport := testServiceExportObj()
+	serviceExport.Annotations = map[string]string{
+		CanaryWeightStepAnnotation:   "40",
+		CanaryStepIntervalAnnotation: "1m",
+	}
+	endpoints := []*model.Endpoint{test.GetTestEndpoint1(), test.GetTestEndpoint2()}
+	start := time.Now()
+
+	// the canary starts at weight 0
+	assert.Equal(t, time.Minute, reconciler.advanceCanary(serviceExport, endpoints, start))
+	assert.Equal(t, int32(0), serviceExport.Status.Canary.Weight)
+	assert.Equal(t, v1alpha1.CanaryProgressing, serviceExport.Status.Canary.Phase)
+
+	// the weight is held until the interval elapsed
+	assert.Equal(t, 30*time.Second, reconciler.advanceCanary(serviceExport, endpoints, start.Add(30*time.Second)))
+	assert.Equal(t, int32(0), serviceExport.Status.Canary.Weight)
+
+	assert.Equal(t, time.Minute, reconciler.advanceCanary(serviceExport, endpoints, start.Add(time.Minute)))
+	assert.Equal(t, int32(40), serviceExport.Status.Canary.Weight)
+	assert.Equal(t, time.Minute, reconciler.advanceCanary(serviceExport, endpoints, start.Add(2*time.Minute)))
+	assert.Equal(t, int32(80), serviceExport.Status.Canary.Weight)
+
+	// the weight is capped at 100
+	assert.Zero(t, reconciler.advanceCanary(serviceExport, endpoints, start.Add(3*time.Minute)))
+	assert.Equal(t, int32(100), serviceExport.Status.Canary.Weight)
+	assert.Equal(t, v1alpha1.CanaryPromoted, serviceExport.Status.Canary.Phase)
+
+	setCanaryWeight(serviceExport, endpoints)
+	assert.Equal(t, "100", endpoints[0].Attributes[WeightAttr])
+
+	// removing the annotation ends the canary
+	serviceExport.Annotations = nil
+	assert.Zero(t, reconciler.advanceCanary(serviceExport, endpoints, start.Add(4*time.Minute)))
+	assert.Nil(t, serviceExport.Status.Canary)
+}
+
+func TestAdvanceCanary_RollBack(t *testing.T) {
+	recorder := record.NewFakeRecorder(10)
+	reconciler := &ServiceExportReconciler{Log: common.NewLoggerWithLogr(testingLogger.TestLogger{T: t}), Recorder: recorder}
+	serviceExport := testServiceExportObj()
+	serviceExport.Annotations = map[string]string{CanaryWeightStepAnnotation: "25"}
+	serviceExport.Status.Canary = &v1alpha1.CanaryStatus{Weight: 50, Phase: v1alpha1.CanaryProgressing}
+
+	notReady := false
+	endpoint2 := test.GetTestEndpoint2()
+	endpoint2.Ready = &notReady
+	endpoints := []*model.Endpoint{test.GetTestEndpoint1(), endpoint2}
+
+	assert.Zero(t, reconciler.advanceCanary(serviceExport, endpoints, time.Now()))
+	assert.Equal(t, int32(0), serviceExport.Status.Canary.Weight)
+	assert.Equal(t, v1alpha1.CanaryRolledBack, serviceExport.Status.Canary.Phase)
+	assert.Contains(t, <-recorder.Events, CanaryRolledBackReason)
+
+	// the weight stays rolled back while the canary is annotated
+	assert.Zero(t, reconciler.advanceCanary(serviceExport, []*model.Endpoint{test.GetTestEndpoint1()},
+		time.Now().Add(time.Hour)))
+	assert.Equal(t, int32(0), serviceExport.Status.Canary.Weight)
+}
+
+func TestParseCanarySettings_Invalid(t *testing.T) {
+	serviceExport := testServiceExportObj()
+	for _, annotations := range []map[string]string{
+		{CanaryWeightStepAnnotation: "0"},
+		{CanaryWeightStepAnnotation: "ten"},
+		{CanaryWeightStepAnnotation: "10", CanaryStepIntervalAnnotation: "-1m"},
+		{CanaryWeightStepAnnotation: "10", CanaryMinReadyAnnotation: "101"},
+	} {
+		serviceExport.Annotations = annotations
+		_, err := parseCanarySettings(serviceExport)
+		assert.Error(t, err, "annotations %v", annotations)
+	}
+}
+
+func TestWeightedEndpoints(t *testing.T) {
+	canary := test.GetTestEndpoint1()
+	canary.Attributes[WeightAttr] = "0"
+	partial := test.GetTestEndpoint2()
+	partial.Attributes[WeightAttr] = "40"
+
+	assert.Equal(t, []*model.Endpoint{partial}, weightedEndpoints([]*model.Endpoint{canary, partial}))
+	assert.Equal(t, []*model.Endpoint{canary}, weightedEndpoints([]*model.Endpoint{canary}),
+		"endpoints with the weight 0 are served if no other endpoint remains")
+}
@@ -4,26 +4,45 @@ import (
 	"context"
 	"crypto/sha256"
 	"encoding/base32"
+	goerrors "errors"
 	"fmt"
 	"github.com/aws/aws-cloud-map-mcs-controller-for-k8s/pkg/api/v1alpha1"
 	"github.com/aws/aws-cloud-map-mcs-controller-for-k8s/pkg/cloudmap"
 	"github.com/aws/aws-cloud-map-mcs-controller-for-k8s/pkg/common"
+	"github.com/aws/aws-cloud-map-mcs-controller-for-k8s/pkg/events"
+	"github.com/aws/aws-cloud-map-mcs-controller-for-k8s/pkg/metrics"
 	"github.com/aws/aws-cloud-map-mcs-controller-for-k8s/pkg/model"
+	"github.com/aws/aws-cloud-map-mcs-controller-for-k8s/pkg/registry"
 	v1 "k8s.io/api/core/v1"
 	discovery "k8s.io/api/discovery/v1beta1"
 	"k8s.io/apimachinery/pkg/api/errors"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
 	"k8s.io/apimachinery/pkg/runtime/schema"
 	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/util/sets"
 	"reflect"
 	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sort"
+	"strconv"
 	"strings"
+	"sync"
+	"sync/atomic"
 	"time"
 )
 
 const (
-	// TODO move to configuration
-	syncPeriod = 2 * time.Second
+	// DefaultSyncPeriod is the default interval Cloud Map services are imported into the cluster
+	DefaultSyncPeriod = 2 * time.Second
+
+	// DefaultStartupConcurrency is the default number of Cloud Map namespaces listed concurrently on startup
+	DefaultStartupConcurrency = 8
+
+	// CloudMapControllerName labels the reconcile metrics of the Cloud Map controller
+	CloudMapControllerName = "cloudmap"
+
+	// refreshQueueSize is the number of changed Cloud Map services queued for refresh
+	refreshQueueSize = 100
 
 	maxEndpointsPerSlice = 100
 
@@ -37,60 +56,367 @@ const (
 // CloudMapReconciler reconciles state of Cloud Map services with local ServiceImport objects
 type CloudMapReconciler struct {
 	Client   client.Client
-	Cloudmap cloudmap.ServiceDiscoveryClient
+	Registry registry.ServiceRegistry
 	Log      common.Logger
+	// Namespaces restricts the reconciliation to the given namespaces, all namespaces are reconciled if empty.
+	Namespaces []string
+	// SyncPeriod is the interval between reconciliations, DefaultSyncPeriod is used if 0.
+	SyncPeriod time.Duration
+	// ClusterConfig provides the cluster wide settings, the defaults apply if nil.
+	ClusterConfig *ClusterConfig
+	// StartupConcurrency bounds the Cloud Map namespaces listed concurrently on startup, DefaultStartupConcurrency is
+	// used if 0.
+	StartupConcurrency int
+	// ChangeSource notifies about changed Cloud Map services, which are refreshed right away, changes are only
+	// imported by the periodic sync if nil.
+	ChangeSource cloudmap.ChangeSource
+	// Notifier sends changes of the ServiceImports and their endpoints to webhooks, nothing is sent if nil.
+	Notifier *events.WebhookNotifier
+	// ExternalDns publishes the imported services through external-dns, nothing is published if nil.
+	ExternalDns *ExternalDnsConfig
+	// Istio creates ServiceEntries for the imported services, no ServiceEntries are created if nil.
+	Istio *IstioConfig
+	// GatewayBackends configures the derived Services as Gateway API backends, they are left as is if nil.
+	GatewayBackends *GatewayBackendsConfig
+	// SlowReconcileThreshold is the import time of a service above which the per-phase timings are logged, 0 disables
+	// it
+	SlowReconcileThreshold time.Duration
+
+	// syncLag tracks Cloud Map endpoint changes that are pending import to the cluster, since the sync or refresh
+	// reading them from Cloud Map
+	syncLag *metrics.LagTracker
+	// syncPeriodOverride is the sync period set at runtime, in nanoseconds, accessed atomically
+	syncPeriodOverride int64
+
+	// refreshes queues the changed Cloud Map services, see Refresh
+	refreshes     chan serviceRefresh
+	refreshesOnce sync.Once
+	// repairs queues the ServiceImports whose resources were deleted outside the reconciler, see Repair
+	repairs     chan types.NamespacedName
+	repairsOnce sync.Once
+	// importedServices maps the IDs of the Cloud Map services imported by the last sync to their import, it is only
+	// accessed by the reconciliation loop
+	importedServices map[string]*importedService
+}
+
+// serviceRefresh is a change of a Cloud Map service, and the time the change was observed.
+type serviceRefresh struct {
+	serviceId string
+	observed  time.Time
+}
+
+// importedService is a Cloud Map service and the cluster namespaces it is imported into.
+type importedService struct {
+	cmNamespace string
+	name        string
+	namespaces  sets.String
 }
 
 // +kubebuilder:rbac:groups="",resources=namespaces,verbs=list;watch
 // +kubebuilder:rbac:groups="",resources=services,verbs=create;get;list;watch;update;delete
 // +kubebuilder:rbac:groups="discovery.k8s.io",resources=endpointslices,verbs=list;get;create;watch;update;delete
 // +kubebuilder:rbac:groups=multicluster.x-k8s.io,resources=serviceimports,verbs=create;get;list;watch;update;patch;delete
+// +kubebuilder:rbac:groups=multicluster.x-k8s.io,resources=serviceimports/status,verbs=get;update;patch
 
 // Start implements manager.Runnable
 func (r *CloudMapReconciler) Start(ctx context.Context) error {
-	ticker := time.NewTicker(syncPeriod)
+	if r.syncLag == nil {
+		r.syncLag = metrics.NewLagTracker()
+	}
+
+	r.prefetch(ctx)
+
+	if r.ChangeSource != nil {
+		go func() {
+			if err := r.ChangeSource.Start(ctx, r.Refresh); err != nil {
+				r.Log.Error(err, "Cloud Map change source failed, changes are imported by the periodic sync")
+			}
+		}()
+	}
+
+	period := r.syncPeriod()
+	ticker := time.NewTicker(period)
 	defer ticker.Stop()
 	for {
 		if err := r.Reconcile(ctx); err != nil {
 			// just log the error and continue running
 			r.Log.Error(err, "Cloud Map reconciliation error")
 		}
+		if current := r.syncPeriod(); current != period {
+			r.Log.Info("changing sync period", "syncPeriod", current.String())
+			period = current
+			ticker.Reset(period)
+		}
+		if !r.waitForSync(ctx, ticker) {
+			r.Log.Info("terminating CloudMapReconciler")
+			return nil
+		}
+	}
+}
+
+// waitForSync refreshes changed services until the next sync is due, and returns false once the context is done.
+func (r *CloudMapReconciler) waitForSync(ctx context.Context, ticker *time.Ticker) bool {
+	for {
 		select {
 		case <-ticker.C:
+			return true
+		case refresh := <-r.refreshQueue():
+			if !r.refreshService(ctx, refresh.serviceId, refresh.observed) {
+				return true
+			}
+		case key := <-r.repairQueue():
+			if !r.repairService(ctx, key) {
+				return true
+			}
 		case <-ctx.Done():
-			r.Log.Info("terminating CloudMapReconciler")
-			return nil
+			return false
+		}
+	}
+}
+
+// Refresh imports a changed Cloud Map service outside of the periodic sync. Services which weren't imported by the
+// last sync, and services without endpoints, are handled by an immediate sync of all namespaces. Refresh doesn't block,
+// changes are dropped while the refresh queue is full, and imported by the next periodic sync.
+func (r *CloudMapReconciler) Refresh(serviceId string) {
+	select {
+	case r.refreshQueue() <- serviceRefresh{serviceId: serviceId, observed: time.Now()}:
+	default:
+		r.Log.Debug("refresh queue full, dropping Cloud Map service refresh", "serviceId", serviceId)
+	}
+}
+
+func (r *CloudMapReconciler) refreshQueue() chan serviceRefresh {
+	r.refreshesOnce.Do(func() {
+		r.refreshes = make(chan serviceRefresh, refreshQueueSize)
+	})
+	return r.refreshes
+}
+
+// Repair re-imports the ServiceImport with the given namespace and name outside of the periodic sync, once it, its
+// derived Service or its EndpointSlices were deleted outside the reconciler. ServiceImports which weren't imported by
+// the last sync are ignored. Repair doesn't block, repairs are dropped while the repair queue is full, and done by the
+// next periodic sync.
+func (r *CloudMapReconciler) Repair(namespace string, name string) {
+	key := types.NamespacedName{Namespace: namespace, Name: name}
+	select {
+	case r.repairQueue() <- key:
+	default:
+		r.Log.Debug("repair queue full, dropping ServiceImport repair", "namespace", namespace, "name", name)
+	}
+}
+
+func (r *CloudMapReconciler) repairQueue() chan types.NamespacedName {
+	r.repairsOnce.Do(func() {
+		r.repairs = make(chan types.NamespacedName, refreshQueueSize)
+	})
+	return r.repairs
+}
+
+// refreshService imports a single Cloud Map service, changed at the observed time, into the namespaces it was
+// imported into by the last sync, and returns false if a sync of all namespaces is needed instead.
+func (r *CloudMapReconciler) refreshService(ctx context.Context, svcId string, observed time.Time) bool {
+	imported, found := r.importedServices[svcId]
+	if !found {
+		return false
+	}
+
+	registry.EvictEndpoints(r.Registry, imported.cmNamespace, imported.name)
+	for _, namespaceName := range imported.namespaces.List() {
+		r.syncLag.ObserveAt(types.NamespacedName{Namespace: namespaceName, Name: imported.name}.String(), observed)
+	}
+	return r.importService(ctx, imported, imported.namespaces.List())
+}
+
+// repairService re-imports the service of a ServiceImport into its namespace from the cached Cloud Map service, as
+// Cloud Map didn't change, and returns false if a sync of all namespaces is needed instead.
+func (r *CloudMapReconciler) repairService(ctx context.Context, key types.NamespacedName) bool {
+	for _, imported := range r.importedServices {
+		if imported.name == key.Name && imported.namespaces.Has(key.Namespace) {
+			r.Log.Info("repairing ServiceImport", "namespace", key.Namespace, "name", key.Name)
+			return r.importService(ctx, imported, []string{key.Namespace})
 		}
 	}
+	// not imported by the last sync, e.g. a ServiceImport deleted by the sync itself
+	return true
+}
+
+// importService imports the Cloud Map service of an import into the given namespaces, and returns false if a sync of
+// all namespaces is needed instead.
+func (r *CloudMapReconciler) importService(ctx context.Context, imported *importedService, namespaceNames []string) bool {
+	svc, err := r.Registry.GetService(ctx, imported.cmNamespace, imported.name)
+	if err != nil {
+		r.Log.Error(err, "error refreshing Cloud Map service", "cloudMapNamespace", imported.cmNamespace,
+			"name", imported.name)
+		return true
+	}
+	if svc == nil || len(svc.Endpoints) == 0 {
+		// the ServiceImports of services without endpoints are deleted by the sync
+		return false
+	}
+
+	for _, namespaceName := range namespaceNames {
+		nsSvc := *svc
+		// import into the Kubernetes namespace mapped to the Cloud Map namespace
+		nsSvc.Namespace = namespaceName
+		if err := r.reconcileService(ctx, &nsSvc); err != nil {
+			r.Log.Error(err, "error when syncing service", "namespace", nsSvc.Namespace, "name", nsSvc.Name)
+		}
+	}
+	return true
+}
+
+// SetSyncPeriod changes the interval between reconciliations at runtime, taking effect after the next reconciliation.
+func (r *CloudMapReconciler) SetSyncPeriod(period time.Duration) {
+	atomic.StoreInt64(&r.syncPeriodOverride, int64(period))
+}
+
+func (r *CloudMapReconciler) syncPeriod() time.Duration {
+	if period := time.Duration(atomic.LoadInt64(&r.syncPeriodOverride)); period > 0 {
+		return period
+	}
+	if r.SyncPeriod > 0 {
+		return r.SyncPeriod
+	}
+	return DefaultSyncPeriod
 }
 
 // Reconcile triggers a single reconciliation round
 func (r *CloudMapReconciler) Reconcile(ctx context.Context) error {
-
-	namespaces := v1.NamespaceList{}
-	if err := r.Client.List(ctx, &namespaces); err != nil {
+	namespaceNames, err := r.listNamespaces(ctx)
+	if err != nil {
 		r.Log.Error(err, "unable to list namespaces")
 		return err
 	}
 
 	//TODO: Fetch list of namespaces from Cloudmap and only reconcile the intersection
 
-	for _, ns := range namespaces.Items {
-		if err := r.reconcileNamespace(ctx, ns.Name); err != nil {
+	importedServices := make(map[string]*importedService)
+	for _, namespaceName := range namespaceNames {
+		if err := r.reconcileNamespace(ctx, namespaceName, importedServices); err != nil {
+			if goerrors.Is(err, ErrInvalidSyncConfig) {
+				// the imports of the namespace are kept until its CloudMapSyncConfig is corrected
+				r.Log.Info("skipping namespace with invalid CloudMapSyncConfig", "namespace", namespaceName,
+					"reason", err.Error())
+				continue
+			}
 			return err
 		}
 	}
+	r.importedServices = importedServices
 
 	return nil
 }
 
-func (r *CloudMapReconciler) reconcileNamespace(ctx context.Context, namespaceName string) error {
+// prefetch lists the services of the Cloud Map namespaces mapped to the cluster namespaces concurrently, which
+// populates the cache of the Cloud Map client, so the first reconciliation doesn't list each namespace serially.
+// Failures are logged, the reconciliation lists the namespace again.
+func (r *CloudMapReconciler) prefetch(ctx context.Context) {
+	start := time.Now()
+	namespaceNames, err := r.listNamespaces(ctx)
+	if err != nil {
+		r.Log.Error(err, "unable to list namespaces to prefetch")
+		return
+	}
+
+	// credentials are the contexts calling each Cloud Map namespace with the IAM role of its first namespace
+	credentials := make(map[string]context.Context)
+	for _, namespaceName := range namespaceNames {
+		settings, err := ResolveSyncSettings(ctx, r.Client, r.ClusterConfig, namespaceName)
+		if err != nil {
+			r.Log.Error(err, "unable to resolve sync settings to prefetch", "namespace", namespaceName)
+			continue
+		}
+		if _, ok := credentials[settings.CloudMapNamespace]; !ok {
+			credentials[settings.CloudMapNamespace] = settings.WithCredentials(ctx, namespaceName)
+		}
+	}
+	if len(credentials) == 0 {
+		return
+	}
+
+	listServices := func(cmNamespace string) {
+		if _, err := r.Registry.ListServices(credentials[cmNamespace], cmNamespace); err != nil {
+			r.Log.Error(err, "unable to prefetch Cloud Map services", "cloudMapNamespace", cmNamespace)
+		}
+	}
+
+	// the first listing caches all Cloud Map namespaces, the remaining listings don't list them again
+	names := sets.StringKeySet(credentials).List()
+	listServices(names[0])
+
+	concurrency := r.StartupConcurrency
+	if concurrency <= 0 {
+		concurrency = DefaultStartupConcurrency
+	}
+	work := make(chan string)
+	wg := sync.WaitGroup{}
+	for i := 0; i < concurrency && i < len(names)-1; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for cmNamespace := range work {
+				listServices(cmNamespace)
+			}
+		}()
+	}
+
+	for _, cmNamespace := range names[1:] {
+		select {
+		case work <- cmNamespace:
+		case <-ctx.Done():
+		}
+	}
+	close(work)
+	wg.Wait()
+
+	r.Log.Info("prefetched Cloud Map services", "cloudMapNamespaces", len(names),
+		"duration", time.Since(start).String())
+}
+
+// listNamespaces returns the configured namespaces, or all namespaces of the cluster if none are configured.
+func (r *CloudMapReconciler) listNamespaces(ctx context.Context) ([]string, error) {
+	return listNamespaceNames(ctx, r.Client, r.Namespaces)
+}
+
+// listNamespaceNames returns the given namespaces, or all namespaces of the cluster if none are given.
+func listNamespaceNames(ctx context.Context, c client.Client, configured []string) ([]string, error) {
+	if len(configured) > 0 {
+		return configured, nil
+	}
+
+	namespaces := v1.NamespaceList{}
+	if err := c.List(ctx, &namespaces); err != nil {
+		return nil, err
+	}
+
+	namespaceNames := make([]string, 0, len(namespaces.Items))
+	for _, ns := range namespaces.Items {
+		namespaceNames = append(namespaceNames, ns.Name)
+	}
+	return namespaceNames, nil
+}
+
+// reconcileNamespace imports the services of the Cloud Map namespace mapped to the namespace, and adds them to the
+// imported services.
+func (r *CloudMapReconciler) reconcileNamespace(ctx context.Context, namespaceName string, importedServices map[string]*importedService) error {
 	r.Log.Debug("syncing namespace", "namespace", namespaceName)
 
-	desiredServices, err := r.Cloudmap.ListServices(ctx, namespaceName)
+	settings, err := ResolveSyncSettings(ctx, r.Client, r.ClusterConfig, namespaceName)
 	if err != nil {
 		return err
 	}
+	ctx = settings.WithCredentials(ctx, namespaceName)
+
+	// changes of the services are observed when they are read from Cloud Map
+	observed := time.Now()
+	desiredServices, err := r.Registry.ListServices(ctx, settings.CloudMapNamespace)
+	if err != nil {
+		return err
+	}
+	for _, svc := range desiredServices {
+		// import into the Kubernetes namespace mapped to the Cloud Map namespace
+		svc.Namespace = namespaceName
+	}
 
 	serviceImports := v1alpha1.ServiceImportList{}
 	if err := r.Client.List(ctx, &serviceImports, client.InNamespace(namespaceName)); err != nil {
@@ -109,10 +435,17 @@ func (r *CloudMapReconciler) reconcileNamespace(ctx context.Context, namespaceNa
 			continue
 		}
 
+		r.syncLag.ObserveAt(types.NamespacedName{Namespace: svc.Namespace, Name: svc.Name}.String(), observed)
 		if err := r.reconcileService(ctx, svc); err != nil {
 			r.Log.Error(err, "error when syncing service", "namespace", svc.Namespace, "name", svc.Name)
 		}
 		delete(existingImportsMap, svc.Namespace+"/"+svc.Name)
+
+		if importedServices[svc.Id] == nil {
+			importedServices[svc.Id] = &importedService{cmNamespace: settings.CloudMapNamespace, name: svc.Name,
+				namespaces: sets.NewString()}
+		}
+		importedServices[svc.Id].namespaces.Insert(namespaceName)
 	}
 
 	// delete remaining imports that have not been matched
@@ -122,6 +455,7 @@ func (r *CloudMapReconciler) reconcileNamespace(ctx context.Context, namespaceNa
 			continue
 		}
 		r.Log.Info("delete ServiceImport", "namespace", i.Namespace, "name", i.Name)
+		r.Notifier.Notify(events.ImportChange{Type: events.ServiceImportDeleted, Namespace: i.Namespace, Name: i.Name})
 	}
 
 	return nil
@@ -129,7 +463,15 @@ func (r *CloudMapReconciler) reconcileNamespace(ctx context.Context, namespaceNa
 
 func (r *CloudMapReconciler) reconcileService(ctx context.Context, svc *model.Service) error {
 	r.Log.Info("syncing service", "namespace", svc.Namespace, "service", svc.Name)
+	svc.Endpoints = servedEndpoints(dedupeMigratedEndpoints(unpackEndpoints(svc.Endpoints)))
+
+	// the lag of the changes pending since they were read from Cloud Map is reported once they are imported
+	syncLagKey := types.NamespacedName{Namespace: svc.Namespace, Name: svc.Name}.String()
 
+	timer := metrics.NewPhaseTimer()
+	defer r.observeImportPhases(timer, svc)
+
+	stopImport := timer.Start(metrics.PhaseImport)
 	svcImport, err := r.getServiceImport(ctx, svc.Namespace, svc.Name)
 	if err != nil {
 		if !errors.IsNotFound(err) {
@@ -154,19 +496,72 @@ func (r *CloudMapReconciler) reconcileService(ctx context.Context, svc *model.Se
 		}
 	}
 
+	// update the derived service before the ServiceImport, which mirrors its ports
+	if err = r.updateDerivedServicePorts(ctx, derivedService, svc.Endpoints); err != nil {
+		return err
+	}
+	if err = r.updateGatewayBackend(ctx, svcImport, derivedService, svc.Endpoints); err != nil {
+		return err
+	}
+
 	// update ServiceImport to match IP and port of previously created service
 	if err = r.updateServiceImport(ctx, svcImport, derivedService); err != nil {
 		return err
 	}
 
-	err = r.updateEndpointSlices(ctx, svcImport, svc.Endpoints, derivedService)
+	if err = r.updateServiceImportMetadata(ctx, svcImport, svc.Endpoints); err != nil {
+		return err
+	}
+	stopImport()
+
+	stopStatusUpdate := timer.Start(metrics.PhaseStatusUpdate)
+	if err = r.updateServiceImportClusters(ctx, svcImport, svc.Endpoints); err != nil {
+		return err
+	}
+	stopStatusUpdate()
+
+	stopEndpointSlices := timer.Start(metrics.PhaseEndpointSlices)
+	updated, err := r.updateEndpointSlices(ctx, svcImport, svc.Endpoints, derivedService)
 	if err != nil {
 		return err
 	}
+	stopEndpointSlices()
+
+	stopImport = timer.Start(metrics.PhaseImport)
+	if err = r.updateExternalDns(ctx, svcImport, derivedService, svc.Endpoints); err != nil {
+		return err
+	}
+
+	if err = r.updateServiceEntry(ctx, svcImport, svc.Endpoints); err != nil {
+		return err
+	}
+	stopImport()
+
+	if !updated {
+		r.syncLag.Forget(syncLagKey)
+	} else {
+		if lag, found := r.syncLag.Complete(syncLagKey); found {
+			metrics.ObserveImportSyncLag(lag)
+		}
+		r.Notifier.Notify(events.ImportChange{Type: events.ImportEndpointsChanged, Namespace: svc.Namespace,
+			Name: svc.Name, Endpoints: eventEndpoints(svc.Endpoints)})
+	}
 
 	return nil
 }
 
+// observeImportPhases records the import phase timings of a service, and logs them if the import exceeded the slow
+// reconcile threshold.
+func (r *CloudMapReconciler) observeImportPhases(timer *metrics.PhaseTimer, svc *model.Service) {
+	timer.Observe(CloudMapControllerName)
+
+	total := timer.Total()
+	if r.SlowReconcileThreshold > 0 && total > r.SlowReconcileThreshold {
+		keysAndValues := []interface{}{"namespace", svc.Namespace, "name", svc.Name, "total", total.String()}
+		r.Log.Info("slow Cloud Map service import", append(keysAndValues, timer.KeysAndValues()...)...)
+	}
+}
+
 func (r *CloudMapReconciler) getServiceImport(ctx context.Context, namespace string, name string) (*v1alpha1.ServiceImport, error) {
 	existingServiceImport := &v1alpha1.ServiceImport{}
 	err := r.Client.Get(ctx, types.NamespacedName{Namespace: namespace, Name: name}, existingServiceImport)
@@ -174,23 +569,12 @@ func (r *CloudMapReconciler) getServiceImport(ctx context.Context, namespace str
 }
 
 func (r *CloudMapReconciler) createAndGetServiceImport(ctx context.Context, namespace string, name string) (*v1alpha1.ServiceImport, error) {
-	imp := &v1alpha1.ServiceImport{
-		ObjectMeta: metav1.ObjectMeta{
-			Namespace:   namespace,
-			Name:        name,
-			Annotations: map[string]string{DerivedServiceAnnotation: DerivedName(namespace, name)},
-		},
-		Spec: v1alpha1.ServiceImportSpec{
-			IPs:   []string{},
-			Type:  v1alpha1.ClusterSetIP,
-			Ports: []v1alpha1.ServicePort{},
-		},
-	}
-
+	imp := createServiceImportStruct(namespace, name)
 	if err := r.Client.Create(ctx, imp); err != nil {
 		return nil, err
 	}
 	r.Log.Info("created ServiceImport", "namespace", imp.Namespace, "name", imp.Name)
+	r.Notifier.Notify(events.ImportChange{Type: events.ServiceImportCreated, Namespace: imp.Namespace, Name: imp.Name})
 
 	return r.getServiceImport(ctx, namespace, name)
 }
@@ -211,57 +595,79 @@ func (r *CloudMapReconciler) createAndGetDerivedService(ctx context.Context, svc
 	return r.getDerivedService(ctx, svc.Namespace, svcImport.Annotations[DerivedServiceAnnotation])
 }
 
-func (r *CloudMapReconciler) updateEndpointSlices(ctx context.Context, svcImport *v1alpha1.ServiceImport, desiredEndpoints []*model.Endpoint, svc *v1.Service) error {
+// updateEndpointSlices reconciles the EndpointSlices of the derived service with the desired endpoints, and returns
+// true if any EndpointSlice was created, updated or deleted. All endpoints of an EndpointSlice share its ports, so
+// endpoints exported with different target ports, e.g. by clusters deploying different versions of the service, are
+// held by different EndpointSlices.
+func (r *CloudMapReconciler) updateEndpointSlices(ctx context.Context, svcImport *v1alpha1.ServiceImport, desiredEndpoints []*model.Endpoint, svc *v1.Service) (updated bool, err error) {
 	existingSlicesList := discovery.EndpointSliceList{}
 	if err := r.Client.List(ctx, &existingSlicesList,
 		client.InNamespace(svc.Namespace), client.MatchingLabels{discovery.LabelServiceName: svc.Name}); err != nil {
-		return err
+		return false, err
 	}
 
-	desiredPorts := extractEndpointPorts(desiredEndpoints)
-	matchedEndpoints := make(map[string]*discovery.Endpoint)
-	endpointsToCreate := make([]discovery.Endpoint, 0)
+	desired := createSliceEndpoints(svc, desiredEndpoints)
+	desiredMap := make(map[string]*sliceEndpoint, len(desired))
+	for _, endpoint := range desired {
+		desiredMap[endpoint.ip()] = endpoint
+	}
 
-	// populate map of existing endpoints in slices for lookup efficiency
-	existingEndpointMap := make(map[string]*discovery.Endpoint)
+	// desired endpoints stay in the first existing endpoint slice of their ports
+	keptEndpoints := make(map[string]string)
 	for _, existingSlice := range existingSlicesList.Items {
+		portsKey := endpointPortsKey(existingSlice.Ports)
 		for _, existingEndpoint := range existingSlice.Endpoints {
-			ref := existingEndpoint
-			existingEndpointMap[ref.Addresses[0]] = &ref
+			ip := existingEndpoint.Addresses[0]
+			if endpoint, found := desiredMap[ip]; found && endpoint.portsKey == portsKey && keptEndpoints[ip] == "" {
+				keptEndpoints[ip] = existingSlice.Name
+			}
 		}
 	}
-
-	// check if all desired endpoints are in an endpoint slice already
-	for _, desiredEndpoint := range desiredEndpoints {
-		match, exists := existingEndpointMap[desiredEndpoint.IP]
-		if exists {
-			matchedEndpoints[desiredEndpoint.IP] = match
-		} else {
-			endpointsToCreate = append(endpointsToCreate, createEndpointForSlice(svc, desiredEndpoint.IP))
+	endpointsToCreate := make([]*sliceEndpoint, 0)
+	for _, endpoint := range desired {
+		if keptEndpoints[endpoint.ip()] == "" {
+			endpointsToCreate = append(endpointsToCreate, endpoint)
 		}
 	}
 
-	// check if all endpoints in slices match a desired endpoint,
 	for _, existingSlice := range existingSlicesList.Items {
+		portsKey := endpointPortsKey(existingSlice.Ports)
 		updatedEndpointList := make([]discovery.Endpoint, 0)
+		endpointSliceNeedsUpdate := false
 		for _, existingEndpoint := range existingSlice.Endpoints {
-			keep, found := matchedEndpoints[existingEndpoint.Addresses[0]]
-			if found {
-				updatedEndpointList = append(updatedEndpointList, *keep)
+			ip := existingEndpoint.Addresses[0]
+			if keptEndpoints[ip] != existingSlice.Name {
+				continue
 			}
+			// only keep the first occurrence of the endpoint
+			delete(keptEndpoints, ip)
+
+			// the readiness, hostname or zone of the endpoint changed
+			desiredEndpoint := desiredMap[ip].endpoint
+			if !reflect.DeepEqual(existingEndpoint.Conditions, desiredEndpoint.Conditions) ||
+				!reflect.DeepEqual(existingEndpoint.Hostname, desiredEndpoint.Hostname) ||
+				!reflect.DeepEqual(existingEndpoint.Topology, desiredEndpoint.Topology) {
+				existingEndpoint.Conditions = desiredEndpoint.Conditions
+				existingEndpoint.Hostname = desiredEndpoint.Hostname
+				existingEndpoint.Topology = desiredEndpoint.Topology
+				endpointSliceNeedsUpdate = true
+			}
+			updatedEndpointList = append(updatedEndpointList, existingEndpoint)
 		}
 
-		endpointSliceNeedsUpdate := len(existingSlice.Endpoints) != len(updatedEndpointList)
+		endpointSliceNeedsUpdate = endpointSliceNeedsUpdate || len(existingSlice.Endpoints) != len(updatedEndpointList)
 
-		// fill endpoint slice with endpoints to create if necessary and there is sufficient room
+		// fill endpoint slice with endpoints of the same ports to create if there is sufficient room
+		remaining := make([]*sliceEndpoint, 0, len(endpointsToCreate))
 		for _, endpointToCreate := range endpointsToCreate {
-			if len(updatedEndpointList) >= maxEndpointsPerSlice {
-				break
+			if endpointToCreate.portsKey != portsKey || len(updatedEndpointList) >= maxEndpointsPerSlice {
+				remaining = append(remaining, endpointToCreate)
+				continue
 			}
 			endpointSliceNeedsUpdate = true
-			updatedEndpointList = append(updatedEndpointList, endpointToCreate)
-			endpointsToCreate = endpointsToCreate[1:]
+			updatedEndpointList = append(updatedEndpointList, endpointToCreate.endpoint)
 		}
+		endpointsToCreate = remaining
 
 		sliceToUpdate := existingSlice
 		sliceToUpdate.Endpoints = updatedEndpointList
@@ -270,43 +676,45 @@ func (r *CloudMapReconciler) updateEndpointSlices(ctx context.Context, svcImport
 		if len(updatedEndpointList) == 0 {
 			r.Log.Info("deleting EndpointSlice", "namespace", sliceToUpdate.Namespace, "name", sliceToUpdate.Name)
 			if err := r.Client.Delete(ctx, &sliceToUpdate); err != nil {
-				return fmt.Errorf("failed to delete EndpointSlice: %w", err)
+				return updated, fmt.Errorf("failed to delete EndpointSlice: %w", err)
 			}
+			updated = true
 			continue
 		}
 
-		// needsUpdate = true if ports don't match
-		if !EndpointPortsAreEqualIgnoreOrder(desiredPorts, sliceToUpdate.Ports) {
-			sliceToUpdate.Ports = desiredPorts
-			endpointSliceNeedsUpdate = true
-		}
-
 		if endpointSliceNeedsUpdate {
 			r.Log.Info("updating EndpointSlice", "namespace", sliceToUpdate.Namespace, "name", sliceToUpdate.Name)
 			if err := r.Client.Update(ctx, &sliceToUpdate); err != nil {
-				return fmt.Errorf("failed to update EndpointSlice: %w", err)
+				return updated, fmt.Errorf("failed to update EndpointSlice: %w", err)
 			}
+			updated = true
 		}
 	}
 
-	slicesToCreate := make([]*discovery.EndpointSlice, 0)
-	for len(endpointsToCreate) > maxEndpointsPerSlice {
-		slicesToCreate = append(slicesToCreate, createEndpointSliceStruct(svcImport, svc, endpointsToCreate[0:maxEndpointsPerSlice], desiredPorts))
-		endpointsToCreate = endpointsToCreate[maxEndpointsPerSlice:]
-	}
-
-	if len(endpointsToCreate) != 0 {
-		slicesToCreate = append(slicesToCreate, createEndpointSliceStruct(svcImport, svc, endpointsToCreate, desiredPorts))
-	}
-
-	for _, newSlice := range slicesToCreate {
+	for _, newSlice := range createEndpointSliceStructs(svcImport, svc, endpointsToCreate) {
 		r.Log.Info("creating EndpointSlice", "namespace", newSlice.Namespace)
 		if err := r.Client.Create(ctx, newSlice); err != nil {
-			return fmt.Errorf("failed to create EndpointSlice: %w", err)
+			return updated, fmt.Errorf("failed to create EndpointSlice: %w", err)
 		}
+		updated = true
 	}
 
-	return nil
+	return updated, nil
+}
+
+func createServiceImportStruct(namespace string, name string) *v1alpha1.ServiceImport {
+	return &v1alpha1.ServiceImport{
+		ObjectMeta: metav1.ObjectMeta{
+			Namespace:   namespace,
+			Name:        name,
+			Annotations: map[string]string{DerivedServiceAnnotation: DerivedName(namespace, name)},
+		},
+		Spec: v1alpha1.ServiceImportSpec{
+			IPs:   []string{},
+			Type:  v1alpha1.ClusterSetIP,
+			Ports: []v1alpha1.ServicePort{},
+		},
+	}
 }
 
 // DerivedName computes the "placeholder" name for the imported service
@@ -335,14 +743,26 @@ func createDerivedServiceStruct(endpoints []*model.Endpoint, svcImport *v1alpha1
 	}
 }
 
-func createEndpointForSlice(svc *v1.Service, ip string) discovery.Endpoint {
-	t := true
+// createEndpointForSlice returns the EndpointSlice endpoint of an imported endpoint, with its readiness, hostname and
+// zone. The node name is omitted, as the node belongs to the exporting cluster.
+func createEndpointForSlice(svc *v1.Service, endpoint *model.Endpoint) discovery.Endpoint {
+	ready := endpoint.IsReady()
+	var hostname *string
+	if endpoint.Hostname != "" {
+		hostname = &endpoint.Hostname
+	}
+	var topology map[string]string
+	if endpoint.Zone != "" {
+		topology = map[string]string{v1.LabelTopologyZone: endpoint.Zone}
+	}
 
 	return discovery.Endpoint{
-		Addresses: []string{ip},
+		Addresses: []string{endpoint.IP},
 		Conditions: discovery.EndpointConditions{
-			Ready: &t,
+			Ready: &ready,
 		},
+		Hostname: hostname,
+		Topology: topology,
 		TargetRef: &v1.ObjectReference{
 			Kind:            "Service",
 			Namespace:       svc.Namespace,
@@ -373,32 +793,227 @@ func createEndpointSliceStruct(svcImport *v1alpha1.ServiceImport, svc *v1.Servic
 	}
 }
 
+// sliceEndpoint is an endpoint of the EndpointSlices of a derived Service, with the ports of the EndpointSlice holding
+// it.
+type sliceEndpoint struct {
+	endpoint discovery.Endpoint
+	ports    []discovery.EndpointPort
+	portsKey string
+}
+
+func (e *sliceEndpoint) ip() string {
+	return e.endpoint.Addresses[0]
+}
+
+// createSliceEndpoints returns an EndpointSlice endpoint per IP of the imported endpoints, in order of the endpoints,
+// with the ports exported for the IP. The ports are named after the derived Service ports of their service ports, as
+// the names of the EndpointSlice ports select the Service ports they serve.
+func createSliceEndpoints(svc *v1.Service, endpoints []*model.Endpoint) []*sliceEndpoint {
+	portNames := servicePortNames(svc)
+	result := make([]*sliceEndpoint, 0)
+	endpointsByIP := make(map[string]*sliceEndpoint)
+	for _, endpoint := range endpoints {
+		sliceEndpt, found := endpointsByIP[endpoint.IP]
+		if !found {
+			sliceEndpt = &sliceEndpoint{endpoint: createEndpointForSlice(svc, endpoint)}
+			endpointsByIP[endpoint.IP] = sliceEndpt
+			result = append(result, sliceEndpt)
+		}
+		endpointPort := endpoint.EndpointPort
+		if name, found := portNames[endpoint.ServicePort.GetID()]; found {
+			endpointPort.Name = name
+		}
+		port := PortToEndpointPort(endpointPort)
+		if !containsEndpointPort(sliceEndpt.ports, port) {
+			sliceEndpt.ports = append(sliceEndpt.ports, port)
+		}
+	}
+
+	for _, sliceEndpt := range result {
+		sort.Slice(sliceEndpt.ports, func(i, j int) bool {
+			return endpointPortKey(sliceEndpt.ports[i]) < endpointPortKey(sliceEndpt.ports[j])
+		})
+		sliceEndpt.portsKey = endpointPortsKey(sliceEndpt.ports)
+	}
+	return result
+}
+
+// createEndpointSliceStructs returns the EndpointSlices holding the endpoints, grouped by their ports in order of the
+// endpoints.
+func createEndpointSliceStructs(svcImport *v1alpha1.ServiceImport, svc *v1.Service, endpoints []*sliceEndpoint) []*discovery.EndpointSlice {
+	portsKeys := make([]string, 0)
+	groups := make(map[string][]*sliceEndpoint)
+	for _, endpoint := range endpoints {
+		if _, found := groups[endpoint.portsKey]; !found {
+			portsKeys = append(portsKeys, endpoint.portsKey)
+		}
+		groups[endpoint.portsKey] = append(groups[endpoint.portsKey], endpoint)
+	}
+
+	slices := make([]*discovery.EndpointSlice, 0)
+	for _, portsKey := range portsKeys {
+		group := groups[portsKey]
+		for len(group) > 0 {
+			size := len(group)
+			if size > maxEndpointsPerSlice {
+				size = maxEndpointsPerSlice
+			}
+			sliceEndpoints := make([]discovery.Endpoint, 0, size)
+			for _, endpoint := range group[:size] {
+				sliceEndpoints = append(sliceEndpoints, endpoint.endpoint)
+			}
+			slices = append(slices, createEndpointSliceStruct(svcImport, svc, sliceEndpoints, group[0].ports))
+			group = group[size:]
+		}
+	}
+	return slices
+}
+
+// endpointPortsKey identifies the ports of an EndpointSlice regardless of their order.
+func endpointPortsKey(ports []discovery.EndpointPort) string {
+	keys := make([]string, 0, len(ports))
+	for _, port := range ports {
+		keys = append(keys, endpointPortKey(port))
+	}
+	sort.Strings(keys)
+	return strings.Join(keys, ",")
+}
+
+func endpointPortKey(port discovery.EndpointPort) string {
+	var name string
+	var protocol v1.Protocol
+	var number int32
+	if port.Name != nil {
+		name = *port.Name
+	}
+	if port.Protocol != nil {
+		protocol = *port.Protocol
+	}
+	if port.Port != nil {
+		number = *port.Port
+	}
+	return fmt.Sprintf("%s/%s/%d", name, protocol, number)
+}
+
+func containsEndpointPort(ports []discovery.EndpointPort, port discovery.EndpointPort) bool {
+	for _, p := range ports {
+		if endpointPortKey(p) == endpointPortKey(port) {
+			return true
+		}
+	}
+	return false
+}
+
+// extractServicePorts returns the ports of the derived Service, sorted by port and protocol. The target port is the
+// container port of the endpoints of all clusters if they agree, or the service port otherwise, as the EndpointSlices
+// hold the container port of each endpoint. Ports are named as described by nameServicePorts.
 func extractServicePorts(endpoints []*model.Endpoint) []v1.ServicePort {
 	uniquePorts := make(map[string]model.Port)
+	targetPorts := make(map[string]sets.String)
 	for _, ep := range endpoints {
-		uniquePorts[ep.ServicePort.GetID()] = ep.ServicePort
+		id := ep.ServicePort.GetID()
+		existing, found := uniquePorts[id]
+		if !found || (ep.ServicePort.Name != "" && (existing.Name == "" || ep.ServicePort.Name < existing.Name)) {
+			uniquePorts[id] = ep.ServicePort
+		}
+		if targetPorts[id] == nil {
+			targetPorts[id] = sets.NewString()
+		}
+		targetPorts[id].Insert(ep.ServicePort.TargetPort)
 	}
 
 	servicePorts := make([]v1.ServicePort, 0, len(uniquePorts))
-	for _, servicePort := range uniquePorts {
+	for id, servicePort := range uniquePorts {
+		if targetPorts[id].Len() != 1 {
+			servicePort.TargetPort = strconv.Itoa(int(servicePort.Port))
+		}
 		servicePorts = append(servicePorts, PortToServicePort(servicePort))
 	}
+	sort.Slice(servicePorts, func(i, j int) bool {
+		if servicePorts[i].Port != servicePorts[j].Port {
+			return servicePorts[i].Port < servicePorts[j].Port
+		}
+		return servicePorts[i].Protocol < servicePorts[j].Protocol
+	})
+	nameServicePorts(servicePorts)
 
 	return servicePorts
 }
 
-func extractEndpointPorts(endpoints []*model.Endpoint) []discovery.EndpointPort {
-	uniquePorts := make(map[string]model.Port)
-	for _, ep := range endpoints {
-		uniquePorts[ep.EndpointPort.GetID()] = ep.EndpointPort
+// nameServicePorts makes the names of the sorted ports unique, as Service port names must be. Each port keeps the name
+// exported by the clusters, the lowest name if the clusters export different names. Unnamed ports of services with
+// multiple ports, and ports whose name is taken by a lower port, are named after their protocol and port, e.g.
+// "tcp-8080".
+func nameServicePorts(ports []v1.ServicePort) {
+	taken := sets.NewString()
+	unnamed := make([]int, 0)
+	for i, port := range ports {
+		if port.Name == "" || taken.Has(port.Name) {
+			unnamed = append(unnamed, i)
+			continue
+		}
+		taken.Insert(port.Name)
+	}
+	if len(ports) == 1 {
+		return
 	}
 
-	endpointPorts := make([]discovery.EndpointPort, 0, len(uniquePorts))
-	for _, endpointPort := range uniquePorts {
-		endpointPorts = append(endpointPorts, PortToEndpointPort(endpointPort))
+	for _, i := range unnamed {
+		base := fmt.Sprintf("%s-%d", strings.ToLower(string(ports[i].Protocol)), ports[i].Port)
+		name := base
+		for n := 2; taken.Has(name); n++ {
+			name = fmt.Sprintf("%s-%d", base, n)
+		}
+		ports[i].Name = name
+		taken.Insert(name)
 	}
+}
 
-	return endpointPorts
+// servicePortNames returns the names of the ports of the derived Service by the ID of the exported service port.
+func servicePortNames(svc *v1.Service) map[string]string {
+	names := make(map[string]string, len(svc.Spec.Ports))
+	for _, port := range svc.Spec.Ports {
+		modelPort := ServicePortToPort(port)
+		names[modelPort.GetID()] = port.Name
+	}
+	return names
+}
+
+// updateDerivedServicePorts updates the ports of the derived Service to the ports exported by the clusters, keeping
+// their application protocols.
+func (r *CloudMapReconciler) updateDerivedServicePorts(ctx context.Context, svc *v1.Service, endpoints []*model.Endpoint) error {
+	desired := extractServicePorts(endpoints)
+	if len(desired) == 0 || servicePortsEqual(svc.Spec.Ports, desired) {
+		return nil
+	}
+
+	appProtocols := make(map[string]*string)
+	for _, port := range svc.Spec.Ports {
+		appProtocols[port.Name] = port.AppProtocol
+	}
+	for i := range desired {
+		desired[i].AppProtocol = appProtocols[desired[i].Name]
+	}
+	svc.Spec.Ports = desired
+	if err := r.Client.Update(ctx, svc); err != nil {
+		return err
+	}
+	r.Log.Info("updated derived Service ports", "namespace", svc.Namespace, "name", svc.Name, "ports", svc.Spec.Ports)
+	return nil
+}
+
+// servicePortsEqual returns true if the ports have the same names, protocols, ports and target ports in the same order.
+func servicePortsEqual(a, b []v1.ServicePort) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i].Name != b[i].Name || a[i].Protocol != b[i].Protocol || a[i].Port != b[i].Port ||
+			a[i].TargetPort != b[i].TargetPort {
+			return false
+		}
+	}
+	return true
 }
 
 func (r *CloudMapReconciler) updateServiceImport(ctx context.Context, svcImport *v1alpha1.ServiceImport, svc *v1.Service) error {
@@ -415,11 +1030,73 @@ func (r *CloudMapReconciler) updateServiceImport(ctx context.Context, svcImport
 		r.Log.Info("updated ServiceImport",
 			"namespace", svcImport.Namespace, "name", svcImport.Name,
 			"IP", svcImport.Spec.IPs, "ports", svcImport.Spec.Ports)
+		r.Notifier.Notify(events.ImportChange{Type: events.ServiceImportUpdated, Namespace: svcImport.Namespace,
+			Name: svcImport.Name})
 	}
 
 	return nil
 }
 
+// updateServiceImportMetadata applies the labels and annotations exported by all exporting clusters to the ServiceImport.
+func (r *CloudMapReconciler) updateServiceImportMetadata(ctx context.Context, svcImport *v1alpha1.ServiceImport, endpoints []*model.Endpoint) error {
+	exported := mergeExportedMetadata(endpoints)
+	if len(exported.Conflicts) > 0 {
+		r.Log.Info("exporting clusters specify conflicting metadata, using values of the oldest export",
+			"namespace", svcImport.Namespace, "name", svcImport.Name, "conflicts", exported.Conflicts)
+	}
+
+	if !applyExportedMetadata(&svcImport.ObjectMeta, exported) {
+		return nil
+	}
+
+	if err := r.Client.Update(ctx, svcImport); err != nil {
+		return err
+	}
+	r.Log.Info("updated ServiceImport exported metadata",
+		"namespace", svcImport.Namespace, "name", svcImport.Name,
+		"labels", exported.Labels, "annotations", exported.Annotations)
+	r.Notifier.Notify(events.ImportChange{Type: events.ServiceImportUpdated, Namespace: svcImport.Namespace,
+		Name: svcImport.Name})
+
+	return nil
+}
+
+// updateServiceImportClusters lists the clusters exporting the endpoints in the status of the ServiceImport.
+func (r *CloudMapReconciler) updateServiceImportClusters(ctx context.Context, svcImport *v1alpha1.ServiceImport, endpoints []*model.Endpoint) error {
+	clusters := exportingClusters(endpoints)
+	if reflect.DeepEqual(svcImport.Status.Clusters, clusters) {
+		return nil
+	}
+
+	svcImport.Status.Clusters = clusters
+	if err := r.Client.Status().Update(ctx, svcImport); err != nil {
+		return err
+	}
+	r.Log.Debug("updated ServiceImport clusters", "namespace", svcImport.Namespace, "name", svcImport.Name,
+		"clusters", clusters)
+	return nil
+}
+
+// exportingClusters returns the sorted clusters of the endpoints, endpoints without cluster ID are not attributed to
+// a cluster.
+func exportingClusters(endpoints []*model.Endpoint) []v1alpha1.ClusterStatus {
+	clusterIds := sets.NewString()
+	for _, endpoint := range endpoints {
+		if clusterId := endpoint.Attributes[ClusterIdAttr]; clusterId != "" {
+			clusterIds.Insert(clusterId)
+		}
+	}
+	if clusterIds.Len() == 0 {
+		return nil
+	}
+
+	clusters := make([]v1alpha1.ClusterStatus, 0, clusterIds.Len())
+	for _, clusterId := range clusterIds.List() {
+		clusters = append(clusters, v1alpha1.ClusterStatus{Cluster: clusterId})
+	}
+	return clusters
+}
+
 func portsEqual(svcImport *v1alpha1.ServiceImport, svc *v1.Service) bool {
 	impPorts := svcImport.Spec.Ports
 	svcPorts := make([]v1alpha1.ServicePort, 0)
@@ -439,3 +1116,34 @@ func servicePortToServiceImport(port v1.ServicePort) v1alpha1.ServicePort {
 		Port:        port.Port,
 	}
 }
+
+// applyImportResource creates the custom resource of an integration, or updates its spec if changed. The resource is
+// unstructured, so the controller doesn't depend on the API packages of the integrations.
+func (r *CloudMapReconciler) applyImportResource(ctx context.Context, desired *unstructured.Unstructured, keysAndValues ...interface{}) error {
+	kind := desired.GetKind()
+	logValues := append([]interface{}{"namespace", desired.GetNamespace(), "name", desired.GetName()}, keysAndValues...)
+
+	existing := &unstructured.Unstructured{}
+	existing.SetGroupVersionKind(desired.GroupVersionKind())
+	err := r.Client.Get(ctx, types.NamespacedName{Namespace: desired.GetNamespace(), Name: desired.GetName()}, existing)
+	if errors.IsNotFound(err) {
+		if err = r.Client.Create(ctx, desired); err != nil {
+			return err
+		}
+		r.Log.Info("created "+kind, logValues...)
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	if reflect.DeepEqual(existing.Object["spec"], desired.Object["spec"]) {
+		return nil
+	}
+	existing.Object["spec"] = desired.Object["spec"]
+	if err = r.Client.Update(ctx, existing); err != nil {
+		return err
+	}
+	r.Log.Info("updated "+kind, logValues...)
+	return nil
+}
@@ -2,7 +2,9 @@ package controllers
 
 import (
 	"context"
+	"fmt"
 	"github.com/aws/aws-cloud-map-mcs-controller-for-k8s/mocks/pkg/cloudmap"
+	cloudmapv1alpha1 "github.com/aws/aws-cloud-map-mcs-controller-for-k8s/pkg/api/cloudmap/v1alpha1"
 	"github.com/aws/aws-cloud-map-mcs-controller-for-k8s/pkg/api/v1alpha1"
 	"github.com/aws/aws-cloud-map-mcs-controller-for-k8s/pkg/common"
 	"github.com/aws/aws-cloud-map-mcs-controller-for-k8s/pkg/model"
@@ -19,7 +21,9 @@ import (
 	"sigs.k8s.io/controller-runtime/pkg/client"
 	"sigs.k8s.io/controller-runtime/pkg/client/fake"
 	"strings"
+	"sync/atomic"
 	"testing"
+	"time"
 )
 
 func TestCloudMapReconciler_Reconcile(t *testing.T) {
@@ -28,6 +32,7 @@ func TestCloudMapReconciler_Reconcile(t *testing.T) {
 
 	s := scheme.Scheme
 	s.AddKnownTypes(v1alpha1.GroupVersion, &v1alpha1.ServiceImportList{}, &v1alpha1.ServiceImport{})
+	s.AddKnownTypes(cloudmapv1alpha1.GroupVersion, &cloudmapv1alpha1.CloudMapSyncConfig{})
 
 	fakeClient := fake.NewClientBuilder().WithRuntimeObjects(objs...).Build()
 
@@ -72,6 +77,248 @@ func TestCloudMapReconciler_Reconcile(t *testing.T) {
 	assert.Equal(t, test.EndptIp1, endpointSlice.Endpoints[0].Addresses[0])
 }
 
+func TestExportingClusters(t *testing.T) {
+	endpoint1 := test.GetTestEndpoint1()
+	endpoint1.Attributes[ClusterIdAttr] = "cluster-b"
+	endpoint2 := test.GetTestEndpoint2()
+	endpoint2.Attributes[ClusterIdAttr] = "cluster-a"
+	endpoint3 := test.GetTestEndpoint1()
+	endpoint3.Attributes[ClusterIdAttr] = "cluster-b"
+
+	assert.Equal(t, []v1alpha1.ClusterStatus{{Cluster: "cluster-a"}, {Cluster: "cluster-b"}},
+		exportingClusters([]*model.Endpoint{endpoint1, endpoint2, endpoint3, test.GetTestEndpoint2()}))
+	assert.Nil(t, exportingClusters([]*model.Endpoint{test.GetTestEndpoint1()}), "endpoints without cluster ID")
+}
+
+func TestCloudMapReconciler_Reconcile_EndpointDetails(t *testing.T) {
+	fakeClient := fake.NewClientBuilder().WithRuntimeObjects(testNamespace()).Build()
+
+	mockController := gomock.NewController(t)
+	defer mockController.Finish()
+
+	notReady := false
+	endpoint := test.GetTestEndpoint1()
+	endpoint.Ready = &notReady
+	endpoint.Hostname = "pod-0"
+	endpoint.Zone = "us-west-2a"
+	mockSDClient := cloudmap.NewMockServiceDiscoveryClient(mockController)
+	gomock.InOrder(
+		mockSDClient.EXPECT().ListServices(context.TODO(), test.NsName).
+			Return([]*model.Service{test.GetTestServiceWithEndpoint([]*model.Endpoint{endpoint})}, nil),
+		mockSDClient.EXPECT().ListServices(context.TODO(), test.NsName).
+			Return([]*model.Service{test.GetTestServiceWithEndpoint([]*model.Endpoint{test.GetTestEndpoint1()})}, nil),
+	)
+
+	reconciler := getReconciler(t, mockSDClient, fakeClient)
+
+	assert.NoError(t, reconciler.Reconcile(context.TODO()))
+	endpointSliceList := &v1beta1.EndpointSliceList{}
+	assert.NoError(t, fakeClient.List(context.TODO(), endpointSliceList, client.InNamespace(test.NsName)))
+	sliceEndpoint := endpointSliceList.Items[0].Endpoints[0]
+	assert.False(t, *sliceEndpoint.Conditions.Ready)
+	assert.Equal(t, "pod-0", *sliceEndpoint.Hostname)
+	assert.Equal(t, map[string]string{v1.LabelTopologyZone: "us-west-2a"}, sliceEndpoint.Topology)
+	assert.Nil(t, sliceEndpoint.NodeName, "the node belongs to the exporting cluster")
+
+	assert.NoError(t, reconciler.Reconcile(context.TODO()))
+	assert.NoError(t, fakeClient.List(context.TODO(), endpointSliceList, client.InNamespace(test.NsName)))
+	assert.Len(t, endpointSliceList.Items, 1)
+	sliceEndpoint = endpointSliceList.Items[0].Endpoints[0]
+	assert.True(t, *sliceEndpoint.Conditions.Ready, "the existing endpoint is updated")
+	assert.Nil(t, sliceEndpoint.Hostname)
+	assert.Nil(t, sliceEndpoint.Topology)
+}
+
+func TestCloudMapReconciler_Reconcile_DifferentTargetPorts(t *testing.T) {
+	fakeClient := fake.NewClientBuilder().WithRuntimeObjects(testNamespace()).Build()
+
+	mockController := gomock.NewController(t)
+	defer mockController.Finish()
+
+	// the clusters export the same service port with different target ports
+	endpoint2 := test.GetTestEndpoint2()
+	endpoint2.ServicePort = test.GetTestEndpoint1().ServicePort
+	endpoint2.ServicePort.TargetPort = test.PortStr2
+	mockSDClient := cloudmap.NewMockServiceDiscoveryClient(mockController)
+	mockSDClient.EXPECT().ListServices(context.TODO(), test.NsName).
+		Return([]*model.Service{test.GetTestServiceWithEndpoint([]*model.Endpoint{test.GetTestEndpoint1(), endpoint2})}, nil)
+
+	reconciler := getReconciler(t, mockSDClient, fakeClient)
+	assert.NoError(t, reconciler.Reconcile(context.TODO()))
+
+	derivedServiceList := &v1.ServiceList{}
+	assert.NoError(t, fakeClient.List(context.TODO(), derivedServiceList, client.InNamespace(test.NsName)))
+	derivedPorts := derivedServiceList.Items[0].Spec.Ports
+	if assert.Len(t, derivedPorts, 1) {
+		assert.Equal(t, int32(test.ServicePort1), derivedPorts[0].Port)
+		assert.Equal(t, int32(test.ServicePort1), derivedPorts[0].TargetPort.IntVal, "conflicting target ports")
+	}
+
+	endpointSliceList := &v1beta1.EndpointSliceList{}
+	assert.NoError(t, fakeClient.List(context.TODO(), endpointSliceList, client.InNamespace(test.NsName)))
+	assert.Len(t, endpointSliceList.Items, 2, "endpoints of different ports are held by different slices")
+	for _, slice := range endpointSliceList.Items {
+		if assert.Len(t, slice.Endpoints, 1) && assert.Len(t, slice.Ports, 1) {
+			expected := int32(test.Port1)
+			if slice.Endpoints[0].Addresses[0] == test.EndptIp2 {
+				expected = test.Port2
+			}
+			assert.Equal(t, expected, *slice.Ports[0].Port)
+		}
+	}
+}
+
+func TestExtractServicePorts(t *testing.T) {
+	endpoint2 := test.GetTestEndpoint2()
+	endpoint3 := test.GetTestEndpoint1()
+	endpoint3.IP = test.EndptIp2
+
+	ports := extractServicePorts([]*model.Endpoint{endpoint2, test.GetTestEndpoint1(), endpoint3})
+	if assert.Len(t, ports, 2) {
+		assert.Equal(t, int32(test.ServicePort1), ports[0].Port, "ports are sorted")
+		assert.Equal(t, int32(test.Port1), ports[0].TargetPort.IntVal, "the target port the endpoints agree on")
+		assert.Equal(t, int32(test.ServicePort2), ports[1].Port)
+	}
+}
+
+func TestCloudMapReconciler_Reconcile_PortNames(t *testing.T) {
+	fakeClient := fake.NewClientBuilder().WithRuntimeObjects(testNamespace()).Build()
+
+	mockController := gomock.NewController(t)
+	defer mockController.Finish()
+
+	// another cluster exports the service port unnamed
+	endpoint2 := test.GetTestEndpoint2()
+	endpoint2.ServicePort = test.GetTestEndpoint1().ServicePort
+	endpoint2.ServicePort.Name = ""
+	endpoint2.EndpointPort.Name = ""
+	mockSDClient := cloudmap.NewMockServiceDiscoveryClient(mockController)
+	mockSDClient.EXPECT().ListServices(context.TODO(), test.NsName).
+		Return([]*model.Service{test.GetTestServiceWithEndpoint([]*model.Endpoint{test.GetTestEndpoint1(), endpoint2})}, nil)
+
+	reconciler := getReconciler(t, mockSDClient, fakeClient)
+	assert.NoError(t, reconciler.Reconcile(context.TODO()))
+
+	serviceImport := &v1alpha1.ServiceImport{}
+	assert.NoError(t, fakeClient.Get(context.TODO(), types.NamespacedName{Namespace: test.NsName, Name: test.SvcName}, serviceImport))
+	if assert.Len(t, serviceImport.Spec.Ports, 1) {
+		assert.Equal(t, test.PortName1, serviceImport.Spec.Ports[0].Name)
+	}
+
+	endpointSliceList := &v1beta1.EndpointSliceList{}
+	assert.NoError(t, fakeClient.List(context.TODO(), endpointSliceList, client.InNamespace(test.NsName)))
+	for _, slice := range endpointSliceList.Items {
+		assert.Equal(t, test.PortName1, *slice.Ports[0].Name, "the slice ports are named after the service port")
+	}
+}
+
+func TestNameServicePorts(t *testing.T) {
+	ports := []v1.ServicePort{
+		{Name: "http", Protocol: v1.ProtocolTCP, Port: 80},
+		{Name: "http", Protocol: v1.ProtocolTCP, Port: 8080},
+		{Protocol: v1.ProtocolUDP, Port: 53},
+		{Name: "udp-53", Protocol: v1.ProtocolTCP, Port: 9090},
+	}
+	nameServicePorts(ports)
+	assert.Equal(t, "http", ports[0].Name)
+	assert.Equal(t, "tcp-8080", ports[1].Name, "names are unique")
+	assert.Equal(t, "udp-53-2", ports[2].Name, "unnamed ports of multi-port services are named")
+	assert.Equal(t, "udp-53", ports[3].Name)
+
+	single := []v1.ServicePort{{Protocol: v1.ProtocolTCP, Port: 80}}
+	nameServicePorts(single)
+	assert.Equal(t, "", single[0].Name, "a single port may be unnamed")
+}
+
+func TestCloudMapReconciler_Reconcile_RestrictedNamespaces(t *testing.T) {
+	// no namespace objects, the reconciler must not list namespaces
+	scheme.Scheme.AddKnownTypes(cloudmapv1alpha1.GroupVersion, &cloudmapv1alpha1.CloudMapSyncConfig{})
+	fakeClient := fake.NewClientBuilder().Build()
+
+	mockController := gomock.NewController(t)
+	defer mockController.Finish()
+
+	mockSDClient := cloudmap.NewMockServiceDiscoveryClient(mockController)
+	mockSDClient.EXPECT().ListServices(context.TODO(), test.NsName).Return([]*model.Service{}, nil)
+
+	reconciler := getReconciler(t, mockSDClient, fakeClient)
+	reconciler.Namespaces = []string{test.NsName}
+
+	assert.NoError(t, reconciler.Reconcile(context.TODO()))
+}
+
+func TestCloudMapReconciler_Prefetch(t *testing.T) {
+	scheme.Scheme.AddKnownTypes(cloudmapv1alpha1.GroupVersion, &cloudmapv1alpha1.CloudMapSyncConfig{})
+	objs := make([]runtime.Object, 0)
+	for i := 0; i < 5; i++ {
+		objs = append(objs, &v1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: fmt.Sprintf("ns-%d", i)}})
+	}
+	fakeClient := fake.NewClientBuilder().WithRuntimeObjects(objs...).Build()
+
+	mockController := gomock.NewController(t)
+	defer mockController.Finish()
+
+	var inFlight, maxInFlight int32
+	mockSDClient := cloudmap.NewMockServiceDiscoveryClient(mockController)
+	mockSDClient.EXPECT().ListServices(gomock.Any(), gomock.Any()).Times(5).
+		DoAndReturn(func(ctx context.Context, nsName string) ([]*model.Service, error) {
+			current := atomic.AddInt32(&inFlight, 1)
+			defer atomic.AddInt32(&inFlight, -1)
+			for {
+				max := atomic.LoadInt32(&maxInFlight)
+				if current <= max || atomic.CompareAndSwapInt32(&maxInFlight, max, current) {
+					break
+				}
+			}
+			time.Sleep(20 * time.Millisecond)
+			return []*model.Service{}, nil
+		})
+
+	reconciler := getReconciler(t, mockSDClient, fakeClient)
+	reconciler.StartupConcurrency = 2
+	reconciler.prefetch(context.TODO())
+	assert.LessOrEqual(t, maxInFlight, int32(2))
+}
+
+func TestCloudMapReconciler_RefreshService(t *testing.T) {
+	s := scheme.Scheme
+	s.AddKnownTypes(v1alpha1.GroupVersion, &v1alpha1.ServiceImportList{}, &v1alpha1.ServiceImport{})
+	s.AddKnownTypes(cloudmapv1alpha1.GroupVersion, &cloudmapv1alpha1.CloudMapSyncConfig{})
+	fakeClient := fake.NewClientBuilder().WithRuntimeObjects(testNamespace()).Build()
+
+	mockController := gomock.NewController(t)
+	defer mockController.Finish()
+
+	mockSDClient := cloudmap.NewMockServiceDiscoveryClient(mockController)
+	mockSDClient.EXPECT().ListServices(context.TODO(), test.NsName).
+		Return([]*model.Service{test.GetTestServiceWithEndpoint([]*model.Endpoint{test.GetTestEndpoint1()})}, nil)
+	gomock.InOrder(
+		mockSDClient.EXPECT().EvictEndpoints(test.NsName, test.SvcName),
+		mockSDClient.EXPECT().GetService(context.TODO(), test.NsName, test.SvcName).
+			Return(test.GetTestServiceWithEndpoint([]*model.Endpoint{test.GetTestEndpoint1(), test.GetTestEndpoint2()}), nil),
+	)
+
+	reconciler := getReconciler(t, mockSDClient, fakeClient)
+	assert.NoError(t, reconciler.Reconcile(context.TODO()))
+
+	assert.False(t, reconciler.refreshService(context.TODO(), "unknown-service", time.Now()),
+		"unknown services need a sync")
+	assert.True(t, reconciler.refreshService(context.TODO(), test.SvcId, time.Now()))
+
+	endpointSliceList := &v1beta1.EndpointSliceList{}
+	assert.NoError(t, fakeClient.List(context.TODO(), endpointSliceList, client.InNamespace(test.NsName)))
+	assert.Len(t, endpointSliceList.Items[0].Endpoints, 2, "refreshed endpoints imported")
+}
+
+func TestCloudMapReconciler_Refresh_QueueFull(t *testing.T) {
+	reconciler := getReconciler(t, nil, nil)
+	for i := 0; i < refreshQueueSize+1; i++ {
+		// never blocks
+		reconciler.Refresh(fmt.Sprintf("srv-%d", i))
+	}
+	assert.Len(t, reconciler.refreshQueue(), refreshQueueSize)
+}
+
 func testNamespace() *v1.Namespace {
 	return &v1.Namespace{
 		ObjectMeta: metav1.ObjectMeta{
@@ -84,7 +331,7 @@ func testNamespace() *v1.Namespace {
 func getReconciler(t *testing.T, mockSDClient *cloudmap.MockServiceDiscoveryClient, client client.Client) *CloudMapReconciler {
 	return &CloudMapReconciler{
 		Client:   client,
-		Cloudmap: mockSDClient,
+		Registry: mockSDClient,
 		Log:      common.NewLoggerWithLogr(testingLogger.TestLogger{T: t}),
 	}
 }
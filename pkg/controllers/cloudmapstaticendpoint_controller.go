@@ -0,0 +1,375 @@
+package controllers
+
+import (
+	"context"
+	goerrors "errors"
+	"fmt"
+	cloudmapv1alpha1 "github.com/aws/aws-cloud-map-mcs-controller-for-k8s/pkg/api/cloudmap/v1alpha1"
+	"github.com/aws/aws-cloud-map-mcs-controller-for-k8s/pkg/common"
+	"github.com/aws/aws-cloud-map-mcs-controller-for-k8s/pkg/model"
+	"github.com/aws/aws-cloud-map-mcs-controller-for-k8s/pkg/registry"
+	"github.com/aws/aws-cloud-map-mcs-controller-for-k8s/pkg/tenancy"
+	"github.com/aws/aws-cloud-map-mcs-controller-for-k8s/pkg/version"
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/equality"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/tools/record"
+	"net"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/builder"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+	"sigs.k8s.io/controller-runtime/pkg/handler"
+	"sigs.k8s.io/controller-runtime/pkg/predicate"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+	"sigs.k8s.io/controller-runtime/pkg/source"
+	"strconv"
+)
+
+const (
+	// StaticEndpointFinalizer de-registers the endpoints of a deleted CloudMapStaticEndpoint
+	StaticEndpointFinalizer = "multicluster.k8s.aws/static-endpoint-finalizer"
+
+	// StaticEndpointAttr is the Cloud Map instance attribute naming the CloudMapStaticEndpoint "<namespace>/<name>"
+	// which registered the instance. ServiceExports of the same service leave these instances alone.
+	StaticEndpointAttr = "STATIC_ENDPOINT"
+
+	// InvalidStaticEndpointReason is the condition reason for CloudMapStaticEndpoints which can't be exported
+	InvalidStaticEndpointReason = "InvalidStaticEndpoint"
+)
+
+// CloudMapStaticEndpointReconciler exports the endpoints of CloudMapStaticEndpoints to Cloud Map, with the ownership,
+// version and custom attributes, sync settings and cleanup policy of the endpoints of ServiceExports.
+type CloudMapStaticEndpointReconciler struct {
+	Client   client.Client
+	Log      common.Logger
+	Registry registry.ServiceRegistry
+	Recorder record.EventRecorder
+
+	// TenancyPolicy restricts the Cloud Map namespaces the endpoints may be exported to, nil permits all
+	TenancyPolicy *tenancy.Policy
+	// NamespaceReader reads the namespaces checked by the tenancy policy bypassing the cache of the manager, the
+	// client is used if nil
+	NamespaceReader client.Reader
+	// ClusterConfig provides the cluster wide settings, the defaults apply if nil
+	ClusterConfig *ClusterConfig
+	// ClusterId and ClusterSetId identify the cluster in the attributes of registered instances, omitted if empty
+	ClusterId    string
+	ClusterSetId string
+	// AttributeLimitPolicy handles instance attributes exceeding the Cloud Map limits, the fail policy applies if empty
+	AttributeLimitPolicy AttributeLimitPolicy
+}
+
+// +kubebuilder:rbac:groups=cloudmap.multicluster.k8s.aws,resources=cloudmapstaticendpoints,verbs=get;list;watch;update;patch
+// +kubebuilder:rbac:groups=cloudmap.multicluster.k8s.aws,resources=cloudmapstaticendpoints/status,verbs=get;update;patch
+
+func (r *CloudMapStaticEndpointReconciler) Reconcile(ctx context.Context, req ctrl.Request) (ctrl.Result, error) {
+	staticEndpoint := &cloudmapv1alpha1.CloudMapStaticEndpoint{}
+	if err := r.Client.Get(ctx, req.NamespacedName, staticEndpoint); err != nil {
+		return ctrl.Result{}, client.IgnoreNotFound(err)
+	}
+
+	settings, err := ResolveSyncSettings(ctx, r.Client, r.ClusterConfig, staticEndpoint.Namespace)
+	if err != nil {
+		r.Log.Error(err, "error resolving sync settings", "namespace", staticEndpoint.Namespace)
+		return ctrl.Result{}, err
+	}
+	ctx = settings.WithCredentials(ctx, staticEndpoint.Namespace)
+
+	if staticEndpoint.GetDeletionTimestamp() != nil {
+		return ctrl.Result{}, r.handleDelete(ctx, staticEndpoint, settings)
+	}
+
+	if !controllerutil.ContainsFinalizer(staticEndpoint, StaticEndpointFinalizer) {
+		controllerutil.AddFinalizer(staticEndpoint, StaticEndpointFinalizer)
+		if err = r.Client.Update(ctx, staticEndpoint); err != nil {
+			r.Log.Error(err, "error adding finalizer", "namespace", staticEndpoint.Namespace,
+				"name", staticEndpoint.Name)
+			return ctrl.Result{}, err
+		}
+	}
+
+	originalStatus := staticEndpoint.Status.DeepCopy()
+	exported, err := r.export(ctx, staticEndpoint, settings)
+	r.setSyncedCondition(staticEndpoint, err)
+	if err == nil {
+		staticEndpoint.Status.Endpoints = int32(exported)
+	}
+	staticEndpoint.Status.ObservedGeneration = staticEndpoint.Generation
+	if !equality.Semantic.DeepEqual(originalStatus, &staticEndpoint.Status) {
+		if statusErr := r.Client.Status().Update(ctx, staticEndpoint); statusErr != nil {
+			r.Log.Error(statusErr, "error updating CloudMapStaticEndpoint status",
+				"namespace", staticEndpoint.Namespace, "name", staticEndpoint.Name)
+			return ctrl.Result{}, statusErr
+		}
+	}
+
+	// invalid endpoints and denied namespaces are reported by the Synced condition, retrying won't help
+	if goerrors.Is(err, tenancy.ErrNotPermitted) || goerrors.Is(err, errInvalidStaticEndpoint) ||
+		goerrors.Is(err, ErrAttributeLimitExceeded) {
+		return ctrl.Result{}, nil
+	}
+	return ctrl.Result{}, err
+}
+
+// errInvalidStaticEndpoint is wrapped by the errors of CloudMapStaticEndpoints which can't be exported.
+var errInvalidStaticEndpoint = goerrors.New("invalid static endpoint")
+
+// export registers the endpoints of the CloudMapStaticEndpoint and de-registers the instances it no longer defines,
+// and returns the number of exported endpoints.
+func (r *CloudMapStaticEndpointReconciler) export(ctx context.Context, staticEndpoint *cloudmapv1alpha1.CloudMapStaticEndpoint, settings SyncSettings) (int, error) {
+	if err := checkNamespaceTenancy(ctx, namespaceReader(r.Client, r.NamespaceReader), r.Log, r.TenancyPolicy,
+		staticEndpoint.Namespace, settings.CloudMapNamespace); err != nil {
+		if goerrors.Is(err, tenancy.ErrNotPermitted) {
+			r.Recorder.Event(staticEndpoint, v1.EventTypeWarning, TenancyDeniedReason, err.Error())
+		}
+		return 0, err
+	}
+
+	desired, err := r.staticEndpoints(staticEndpoint, settings)
+	if err != nil {
+		return 0, err
+	}
+	for _, endpoint := range desired {
+		if _, err = applyAttributeLimits(endpoint, r.AttributeLimitPolicy); err != nil {
+			r.Recorder.Event(staticEndpoint, v1.EventTypeWarning, AttributeLimitExceededReason, err.Error())
+			return 0, err
+		}
+	}
+
+	cmNamespace, svcName := settings.CloudMapNamespace, staticEndpoint.Spec.ServiceName
+	cmService, err := r.Registry.GetService(ctx, cmNamespace, svcName)
+	if err != nil {
+		return 0, err
+	}
+	if cmService == nil {
+		if err = r.Registry.CreateService(ctx, cmNamespace, svcName); err != nil {
+			r.Log.Error(err, "error creating a new service in Cloud Map", "namespace", cmNamespace, "name", svcName)
+			return 0, err
+		}
+		cmService = &model.Service{Namespace: cmNamespace, Name: svcName}
+	}
+
+	changes := (&model.Plan{
+		Current: r.registeredEndpoints(staticEndpoint, cmService.Endpoints),
+		Desired: desired,
+	}).CalculateChanges()
+	if changes.HasUpdates() {
+		upserts := append(changes.Create, changes.Update...)
+		if err = r.Registry.RegisterEndpoints(ctx, cmNamespace, svcName, upserts); err != nil {
+			r.Log.Error(err, "error registering static endpoints to Cloud Map", "namespace", staticEndpoint.Namespace,
+				"name", staticEndpoint.Name)
+			return 0, err
+		}
+	}
+	if changes.HasDeletes() {
+		if err = r.Registry.DeleteEndpoints(ctx, cmNamespace, svcName, changes.Delete); err != nil {
+			r.Log.Error(err, "error deleting static endpoints from Cloud Map", "namespace", staticEndpoint.Namespace,
+				"name", staticEndpoint.Name)
+			return 0, err
+		}
+	}
+	return len(desired), nil
+}
+
+// staticEndpoints returns the Cloud Map endpoints of each address and port of the CloudMapStaticEndpoint.
+func (r *CloudMapStaticEndpointReconciler) staticEndpoints(staticEndpoint *cloudmapv1alpha1.CloudMapStaticEndpoint, settings SyncSettings) ([]*model.Endpoint, error) {
+	if err := validateInstanceAttributes(staticEndpoint.Spec.Attributes); err != nil {
+		return nil, fmt.Errorf("%w: %s", errInvalidStaticEndpoint, err.Error())
+	}
+
+	endpoints := make([]*model.Endpoint, 0, len(staticEndpoint.Spec.Addresses)*len(staticEndpoint.Spec.Ports))
+	for _, address := range staticEndpoint.Spec.Addresses {
+		if ip := net.ParseIP(address); ip == nil || ip.To4() == nil {
+			return nil, fmt.Errorf("%w: address %q is not an IPv4 address", errInvalidStaticEndpoint, address)
+		}
+		for _, staticPort := range staticEndpoint.Spec.Ports {
+			servicePort, endpointPort := staticEndpointPorts(staticPort)
+
+			attributes := make(map[string]string)
+			for key, value := range staticEndpoint.Spec.Attributes {
+				attributes[key] = value
+			}
+			if version.GetVersion() != "" {
+				attributes[K8sVersionAttr] = version.PackageName + " " + version.GetVersion()
+			}
+			if staticPort.AppProtocol != "" {
+				attributes[AppProtocolAttr] = staticPort.AppProtocol
+			}
+			settings.FilterAttributes(attributes)
+			if r.ClusterId != "" {
+				attributes[ClusterIdAttr] = r.ClusterId
+			}
+			if r.ClusterSetId != "" {
+				attributes[ClusterSetIdAttr] = r.ClusterSetId
+			}
+			attributes[StaticEndpointAttr] = staticEndpointKey(staticEndpoint)
+
+			endpoints = append(endpoints, &model.Endpoint{
+				Id:           model.EndpointId(r.ClusterId, address, endpointPort),
+				IP:           address,
+				EndpointPort: endpointPort,
+				ServicePort:  servicePort,
+				Zone:         staticEndpoint.Spec.Zone,
+				Attributes:   attributes,
+			})
+		}
+	}
+	return endpoints, nil
+}
+
+// staticEndpointPorts returns the service port and the endpoint port of a port of a CloudMapStaticEndpoint.
+func staticEndpointPorts(staticPort cloudmapv1alpha1.StaticEndpointPort) (servicePort model.Port, endpointPort model.Port) {
+	protocol := staticPort.Protocol
+	if protocol == "" {
+		protocol = model.TCPProtocol
+	}
+	targetPort := staticPort.TargetPort
+	if targetPort == 0 {
+		targetPort = staticPort.Port
+	}
+	servicePort = model.Port{
+		Name:       staticPort.Name,
+		Port:       staticPort.Port,
+		TargetPort: strconv.Itoa(int(targetPort)),
+		Protocol:   protocol,
+	}
+	endpointPort = model.Port{Name: staticPort.Name, Port: targetPort, Protocol: protocol}
+	return servicePort, endpointPort
+}
+
+// registeredEndpoints returns the instances registered by this cluster for the CloudMapStaticEndpoint.
+func (r *CloudMapStaticEndpointReconciler) registeredEndpoints(staticEndpoint *cloudmapv1alpha1.CloudMapStaticEndpoint, endpoints []*model.Endpoint) []*model.Endpoint {
+	key := staticEndpointKey(staticEndpoint)
+	registered := make([]*model.Endpoint, 0)
+	for _, endpoint := range endpoints {
+		if endpoint.Attributes[StaticEndpointAttr] == key && endpoint.Attributes[ClusterIdAttr] == r.ClusterId {
+			registered = append(registered, endpoint)
+		}
+	}
+	return registered
+}
+
+// handleDelete de-registers the endpoints of a deleted CloudMapStaticEndpoint unless the cleanup policy retains them,
+// and removes the finalizer.
+func (r *CloudMapStaticEndpointReconciler) handleDelete(ctx context.Context, staticEndpoint *cloudmapv1alpha1.CloudMapStaticEndpoint, settings SyncSettings) error {
+	if !controllerutil.ContainsFinalizer(staticEndpoint, StaticEndpointFinalizer) {
+		return nil
+	}
+
+	deregister := settings.CleanupPolicy != cloudmapv1alpha1.CleanupPolicyRetain
+	if err := checkNamespaceTenancy(ctx, namespaceReader(r.Client, r.NamespaceReader), r.Log, r.TenancyPolicy,
+		staticEndpoint.Namespace, settings.CloudMapNamespace); err != nil {
+		if !goerrors.Is(err, tenancy.ErrNotPermitted) {
+			return err
+		}
+		// never delete from Cloud Map namespaces of other tenants, but do not block the deletion
+		deregister = false
+	}
+
+	if deregister {
+		cmService, err := r.Registry.GetService(ctx, settings.CloudMapNamespace, staticEndpoint.Spec.ServiceName)
+		if err != nil {
+			return err
+		}
+		if cmService != nil {
+			registered := r.registeredEndpoints(staticEndpoint, cmService.Endpoints)
+			if err = r.Registry.DeleteEndpoints(ctx, cmService.Namespace, cmService.Name, registered); err != nil {
+				r.Log.Error(err, "error deleting static endpoints from Cloud Map",
+					"namespace", staticEndpoint.Namespace, "name", staticEndpoint.Name)
+				return err
+			}
+			if len(registered) > 0 {
+				r.Recorder.Eventf(staticEndpoint, v1.EventTypeNormal, DeregisteredReason,
+					"de-registered %d instances from Cloud Map service %s/%s", len(registered), cmService.Namespace,
+					cmService.Name)
+			}
+		}
+	} else {
+		r.Log.Info("retaining static endpoints in Cloud Map", "namespace", staticEndpoint.Namespace,
+			"name", staticEndpoint.Name)
+	}
+
+	controllerutil.RemoveFinalizer(staticEndpoint, StaticEndpointFinalizer)
+	return r.Client.Update(ctx, staticEndpoint)
+}
+
+func (r *CloudMapStaticEndpointReconciler) setSyncedCondition(staticEndpoint *cloudmapv1alpha1.CloudMapStaticEndpoint, err error) {
+	condition := metav1.Condition{
+		Type:               SyncedCondition,
+		Status:             metav1.ConditionTrue,
+		ObservedGeneration: staticEndpoint.Generation,
+		Reason:             SyncedReason,
+		Message:            "endpoints are exported to Cloud Map",
+	}
+	if err != nil {
+		condition.Status = metav1.ConditionFalse
+		condition.Message = err.Error()
+	}
+	switch {
+	case err == nil:
+	case goerrors.Is(err, tenancy.ErrNotPermitted):
+		condition.Reason = TenancyDeniedReason
+	case goerrors.Is(err, ErrAttributeLimitExceeded):
+		condition.Reason = AttributeLimitExceededReason
+	case goerrors.Is(err, errInvalidStaticEndpoint):
+		condition.Reason = InvalidStaticEndpointReason
+	default:
+		condition.Reason = SyncFailedReason
+	}
+	meta.SetStatusCondition(&staticEndpoint.Status.Conditions, condition)
+}
+
+func (r *CloudMapStaticEndpointReconciler) SetupWithManager(mgr ctrl.Manager) error {
+	return ctrl.NewControllerManagedBy(mgr).
+		For(&cloudmapv1alpha1.CloudMapStaticEndpoint{}).
+		// Re-export the static endpoints of a namespace once its sync settings change
+		Watches(
+			&source.Kind{Type: &cloudmapv1alpha1.CloudMapSyncConfig{}},
+			handler.EnqueueRequestsFromMapFunc(r.syncConfigEventHandler()),
+			// the status updates of the CloudMapSyncConfig don't change the sync settings
+			builder.WithPredicates(predicate.Funcs{UpdateFunc: generationChanged}),
+		).
+		Complete(r)
+}
+
+// syncConfigEventHandler enqueues all CloudMapStaticEndpoints in the namespace of a CloudMapSyncConfig.
+func (r *CloudMapStaticEndpointReconciler) syncConfigEventHandler() handler.MapFunc {
+	return func(object client.Object) []reconcile.Request {
+		if object.GetName() != cloudmapv1alpha1.CloudMapSyncConfigName {
+			return nil
+		}
+
+		staticEndpoints := &cloudmapv1alpha1.CloudMapStaticEndpointList{}
+		if err := r.Client.List(context.TODO(), staticEndpoints, client.InNamespace(object.GetNamespace())); err != nil {
+			r.Log.Error(err, "error listing CloudMapStaticEndpoints", "namespace", object.GetNamespace())
+			return nil
+		}
+
+		requests := make([]reconcile.Request, 0, len(staticEndpoints.Items))
+		for _, staticEndpoint := range staticEndpoints.Items {
+			requests = append(requests, reconcile.Request{
+				NamespacedName: types.NamespacedName{Namespace: staticEndpoint.Namespace, Name: staticEndpoint.Name},
+			})
+		}
+		return requests
+	}
+}
+
+func staticEndpointKey(staticEndpoint *cloudmapv1alpha1.CloudMapStaticEndpoint) string {
+	return staticEndpoint.Namespace + "/" + staticEndpoint.Name
+}
+
+// withoutStaticEndpoints returns the endpoints not registered for CloudMapStaticEndpoints, which ServiceExports of
+// the same service must not de-register.
+func withoutStaticEndpoints(endpoints []*model.Endpoint) []*model.Endpoint {
+	result := make([]*model.Endpoint, 0, len(endpoints))
+	for _, endpoint := range endpoints {
+		if _, static := endpoint.Attributes[StaticEndpointAttr]; !static {
+			result = append(result, endpoint)
+		}
+	}
+	return result
+}
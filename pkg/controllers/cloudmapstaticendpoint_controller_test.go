@@ -0,0 +1,150 @@
+package controllers
+
+import (
+	"context"
+	"github.com/aws/aws-cloud-map-mcs-controller-for-k8s/mocks/pkg/cloudmap"
+	cloudmapv1alpha1 "github.com/aws/aws-cloud-map-mcs-controller-for-k8s/pkg/api/cloudmap/v1alpha1"
+	"github.com/aws/aws-cloud-map-mcs-controller-for-k8s/pkg/common"
+	"github.com/aws/aws-cloud-map-mcs-controller-for-k8s/pkg/model"
+	"github.com/aws/aws-cloud-map-mcs-controller-for-k8s/test"
+	testing2 "github.com/go-logr/logr/testing"
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/assert"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/tools/record"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	"testing"
+)
+
+func TestCloudMapStaticEndpointReconciler_Reconcile(t *testing.T) {
+	mockController := gomock.NewController(t)
+	defer mockController.Finish()
+	mock := cloudmap.NewMockServiceDiscoveryClient(mockController)
+
+	staticEndpoint := testStaticEndpointObj()
+	fakeClient := fake.NewClientBuilder().WithScheme(getStaticEndpointScheme()).WithObjects(staticEndpoint).Build()
+
+	exported := test.GetTestEndpoint1()
+	exported.Attributes[ClusterIdAttr] = test.ClusterId
+	stale := test.GetTestEndpoint2()
+	stale.Attributes[ClusterIdAttr] = test.ClusterId
+	stale.Attributes[StaticEndpointAttr] = test.NsName + "/" + staticEndpoint.Name
+	mock.EXPECT().GetService(gomock.Any(), test.NsName, test.SvcName).
+		Return(test.GetTestServiceWithEndpoint([]*model.Endpoint{exported, stale}), nil)
+	mock.EXPECT().RegisterEndpoints(gomock.Any(), test.NsName, test.SvcName, gomock.Any()).
+		DoAndReturn(func(_ context.Context, _ string, _ string, endpoints []*model.Endpoint) error {
+			assert.Len(t, endpoints, 1)
+			assert.Equal(t, "10.0.1.10", endpoints[0].IP)
+			assert.Equal(t, model.Port{Name: "http", Port: 80, TargetPort: "8080", Protocol: model.TCPProtocol},
+				endpoints[0].ServicePort)
+			assert.Equal(t, model.Port{Name: "http", Port: 8080, Protocol: model.TCPProtocol}, endpoints[0].EndpointPort)
+			assert.Equal(t, test.NsName+"/"+staticEndpoint.Name, endpoints[0].Attributes[StaticEndpointAttr])
+			assert.Equal(t, test.ClusterId, endpoints[0].Attributes[ClusterIdAttr])
+			assert.Equal(t, "vm", endpoints[0].Attributes["backend"])
+			return nil
+		})
+	// the endpoints of the ServiceExport of the service are kept
+	mock.EXPECT().DeleteEndpoints(gomock.Any(), test.NsName, test.SvcName, []*model.Endpoint{stale}).Return(nil)
+
+	reconciler := getStaticEndpointReconciler(t, mock, fakeClient)
+	_, err := reconciler.Reconcile(context.TODO(), ctrl.Request{NamespacedName: client.ObjectKeyFromObject(staticEndpoint)})
+	assert.NoError(t, err)
+
+	updated := &cloudmapv1alpha1.CloudMapStaticEndpoint{}
+	assert.NoError(t, fakeClient.Get(context.TODO(), client.ObjectKeyFromObject(staticEndpoint), updated))
+	assert.Contains(t, updated.Finalizers, StaticEndpointFinalizer)
+	assert.Equal(t, int32(1), updated.Status.Endpoints)
+	synced := meta.FindStatusCondition(updated.Status.Conditions, SyncedCondition)
+	assert.NotNil(t, synced)
+	assert.Equal(t, metav1.ConditionTrue, synced.Status)
+}
+
+func TestCloudMapStaticEndpointReconciler_Reconcile_Invalid(t *testing.T) {
+	mockController := gomock.NewController(t)
+	defer mockController.Finish()
+	mock := cloudmap.NewMockServiceDiscoveryClient(mockController)
+
+	staticEndpoint := testStaticEndpointObj()
+	staticEndpoint.Spec.Addresses = []string{"vm.example.com"}
+	fakeClient := fake.NewClientBuilder().WithScheme(getStaticEndpointScheme()).WithObjects(staticEndpoint).Build()
+
+	reconciler := getStaticEndpointReconciler(t, mock, fakeClient)
+	_, err := reconciler.Reconcile(context.TODO(), ctrl.Request{NamespacedName: client.ObjectKeyFromObject(staticEndpoint)})
+	assert.NoError(t, err, "invalid endpoints aren't retried")
+
+	updated := &cloudmapv1alpha1.CloudMapStaticEndpoint{}
+	assert.NoError(t, fakeClient.Get(context.TODO(), client.ObjectKeyFromObject(staticEndpoint), updated))
+	synced := meta.FindStatusCondition(updated.Status.Conditions, SyncedCondition)
+	assert.NotNil(t, synced)
+	assert.Equal(t, metav1.ConditionFalse, synced.Status)
+	assert.Equal(t, InvalidStaticEndpointReason, synced.Reason)
+}
+
+func TestCloudMapStaticEndpointReconciler_Reconcile_Delete(t *testing.T) {
+	mockController := gomock.NewController(t)
+	defer mockController.Finish()
+	mock := cloudmap.NewMockServiceDiscoveryClient(mockController)
+
+	staticEndpoint := testStaticEndpointObj()
+	staticEndpoint.Finalizers = []string{StaticEndpointFinalizer}
+	staticEndpoint.DeletionTimestamp = &metav1.Time{}
+	fakeClient := fake.NewClientBuilder().WithScheme(getStaticEndpointScheme()).WithObjects(staticEndpoint).Build()
+
+	exported := test.GetTestEndpoint1()
+	exported.Attributes[ClusterIdAttr] = test.ClusterId
+	registered := test.GetTestEndpoint2()
+	registered.Attributes[ClusterIdAttr] = test.ClusterId
+	registered.Attributes[StaticEndpointAttr] = test.NsName + "/" + staticEndpoint.Name
+	mock.EXPECT().GetService(gomock.Any(), test.NsName, test.SvcName).
+		Return(test.GetTestServiceWithEndpoint([]*model.Endpoint{exported, registered}), nil)
+	mock.EXPECT().DeleteEndpoints(gomock.Any(), test.NsName, test.SvcName, []*model.Endpoint{registered}).Return(nil)
+
+	reconciler := getStaticEndpointReconciler(t, mock, fakeClient)
+	_, err := reconciler.Reconcile(context.TODO(), ctrl.Request{NamespacedName: client.ObjectKeyFromObject(staticEndpoint)})
+	assert.NoError(t, err)
+
+	updated := &cloudmapv1alpha1.CloudMapStaticEndpoint{}
+	assert.NoError(t, fakeClient.Get(context.TODO(), client.ObjectKeyFromObject(staticEndpoint), updated))
+	assert.NotContains(t, updated.Finalizers, StaticEndpointFinalizer)
+}
+
+func TestWithoutStaticEndpoints(t *testing.T) {
+	exported := test.GetTestEndpoint1()
+	static := test.GetTestEndpoint2()
+	static.Attributes[StaticEndpointAttr] = test.NsName + "/vms"
+
+	assert.Equal(t, []*model.Endpoint{exported}, withoutStaticEndpoints([]*model.Endpoint{exported, static}))
+}
+
+func testStaticEndpointObj() *cloudmapv1alpha1.CloudMapStaticEndpoint {
+	return &cloudmapv1alpha1.CloudMapStaticEndpoint{
+		ObjectMeta: metav1.ObjectMeta{Namespace: test.NsName, Name: "vms"},
+		Spec: cloudmapv1alpha1.CloudMapStaticEndpointSpec{
+			ServiceName: test.SvcName,
+			Addresses:   []string{"10.0.1.10"},
+			Ports:       []cloudmapv1alpha1.StaticEndpointPort{{Name: "http", Port: 80, TargetPort: 8080}},
+			Attributes:  map[string]string{"backend": "vm"},
+		},
+	}
+}
+
+func getStaticEndpointScheme() *runtime.Scheme {
+	scheme := getSyncConfigScheme()
+	scheme.AddKnownTypes(cloudmapv1alpha1.GroupVersion,
+		&cloudmapv1alpha1.CloudMapStaticEndpoint{}, &cloudmapv1alpha1.CloudMapStaticEndpointList{})
+	return scheme
+}
+
+func getStaticEndpointReconciler(t *testing.T, mockClient *cloudmap.MockServiceDiscoveryClient, client client.Client) *CloudMapStaticEndpointReconciler {
+	return &CloudMapStaticEndpointReconciler{
+		Client:    client,
+		Log:       common.NewLoggerWithLogr(testing2.TestLogger{T: t}),
+		Registry:  mockClient,
+		Recorder:  record.NewFakeRecorder(10),
+		ClusterId: test.ClusterId,
+	}
+}
@@ -0,0 +1,101 @@
+package controllers
+
+import (
+	"context"
+	"fmt"
+	cloudmapv1alpha1 "github.com/aws/aws-cloud-map-mcs-controller-for-k8s/pkg/api/cloudmap/v1alpha1"
+	"github.com/aws/aws-cloud-map-mcs-controller-for-k8s/pkg/common"
+	"k8s.io/apimachinery/pkg/api/equality"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/handler"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+	"sigs.k8s.io/controller-runtime/pkg/source"
+	"strings"
+)
+
+// CloudMapSyncConfigReconciler validates CloudMapSyncConfigs against the limits of the cluster config and reports
+// the result in their status. The settings themselves are resolved when syncing each namespace.
+type CloudMapSyncConfigReconciler struct {
+	Client        client.Client
+	Log           common.Logger
+	ClusterConfig *ClusterConfig
+}
+
+// +kubebuilder:rbac:groups=cloudmap.multicluster.k8s.aws,resources=cloudmapsyncconfigs,verbs=get;list;watch
+// +kubebuilder:rbac:groups=cloudmap.multicluster.k8s.aws,resources=cloudmapsyncconfigs/status,verbs=get;update;patch
+
+func (r *CloudMapSyncConfigReconciler) Reconcile(ctx context.Context, req ctrl.Request) (ctrl.Result, error) {
+	config := &cloudmapv1alpha1.CloudMapSyncConfig{}
+	if err := r.Client.Get(ctx, req.NamespacedName, config); err != nil {
+		return ctrl.Result{}, client.IgnoreNotFound(err)
+	}
+
+	originalStatus := config.Status.DeepCopy()
+	valid := metav1.Condition{
+		Type:               cloudmapv1alpha1.ConfigValidCondition,
+		Status:             metav1.ConditionTrue,
+		ObservedGeneration: config.Generation,
+		Reason:             ConfigValidReason,
+		Message:            "the configuration is valid and applied to the namespace",
+	}
+
+	limits := r.ClusterConfig.SyncConfigLimits()
+	if errs := ValidateCloudMapSyncConfig(&config.Spec, &limits); len(errs) > 0 {
+		valid.Status = metav1.ConditionFalse
+		valid.Reason = ConfigInvalidReason
+		valid.Message = strings.Join(errs, "; ") + ", the cluster settings apply to the namespace"
+	} else if config.Name != cloudmapv1alpha1.CloudMapSyncConfigName {
+		valid.Status = metav1.ConditionFalse
+		valid.Reason = ConfigIgnoredReason
+		valid.Message = fmt.Sprintf("only the CloudMapSyncConfig named %s is applied",
+			cloudmapv1alpha1.CloudMapSyncConfigName)
+	}
+
+	meta.SetStatusCondition(&config.Status.Conditions, valid)
+	config.Status.ObservedGeneration = config.Generation
+	if equality.Semantic.DeepEqual(originalStatus, &config.Status) {
+		return ctrl.Result{}, nil
+	}
+
+	if err := r.Client.Status().Update(ctx, config); err != nil {
+		r.Log.Error(err, "error updating CloudMapSyncConfig status", "namespace", config.Namespace, "name", config.Name)
+		return ctrl.Result{}, err
+	}
+	return ctrl.Result{}, nil
+}
+
+func (r *CloudMapSyncConfigReconciler) SetupWithManager(mgr ctrl.Manager) error {
+	builder := ctrl.NewControllerManagedBy(mgr).
+		For(&cloudmapv1alpha1.CloudMapSyncConfig{})
+	if r.ClusterConfig != nil {
+		builder = builder.Watches(
+			&source.Channel{Source: r.ClusterConfig.Subscribe()},
+			handler.EnqueueRequestsFromMapFunc(r.clusterConfigEventHandler()),
+		)
+	}
+	return builder.Complete(r)
+}
+
+// clusterConfigEventHandler enqueues all CloudMapSyncConfigs once the cluster config changed, as the limits they are
+// validated against may have changed.
+func (r *CloudMapSyncConfigReconciler) clusterConfigEventHandler() handler.MapFunc {
+	return func(object client.Object) []reconcile.Request {
+		configs := &cloudmapv1alpha1.CloudMapSyncConfigList{}
+		if err := r.Client.List(context.TODO(), configs); err != nil {
+			r.Log.Error(err, "error listing CloudMapSyncConfigs")
+			return nil
+		}
+
+		requests := make([]reconcile.Request, 0, len(configs.Items))
+		for _, config := range configs.Items {
+			requests = append(requests, reconcile.Request{
+				NamespacedName: types.NamespacedName{Namespace: config.Namespace, Name: config.Name},
+			})
+		}
+		return requests
+	}
+}
@@ -0,0 +1,73 @@
+package controllers
+
+import (
+	"context"
+	cloudmapv1alpha1 "github.com/aws/aws-cloud-map-mcs-controller-for-k8s/pkg/api/cloudmap/v1alpha1"
+	"github.com/aws/aws-cloud-map-mcs-controller-for-k8s/pkg/common"
+	testing2 "github.com/go-logr/logr/testing"
+	"github.com/stretchr/testify/assert"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	"testing"
+)
+
+func TestCloudMapSyncConfigReconciler_Reconcile(t *testing.T) {
+	tests := []struct {
+		name       string
+		limits     cloudmapv1alpha1.SyncConfigLimits
+		wantValid  metav1.ConditionStatus
+		wantReason string
+	}{
+		{
+			name:       "override within limits is valid",
+			limits:     cloudmapv1alpha1.SyncConfigLimits{AllowCloudMapNamespaceOverride: true},
+			wantValid:  metav1.ConditionTrue,
+			wantReason: ConfigValidReason,
+		},
+		{
+			name:       "override exceeding limits is invalid",
+			wantValid:  metav1.ConditionFalse,
+			wantReason: ConfigInvalidReason,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			syncConfig := testSyncConfig(cloudmapv1alpha1.CloudMapSyncConfigName,
+				cloudmapv1alpha1.CloudMapSyncConfigSpec{CloudMapNamespace: "shared"})
+			fakeClient := fake.NewClientBuilder().WithScheme(getSyncConfigScheme()).WithObjects(syncConfig).Build()
+			clusterConfig := NewClusterConfig()
+			clusterConfig.set(&cloudmapv1alpha1.ClusterCloudMapConfigSpec{SyncConfigLimits: tt.limits})
+			reconciler := &CloudMapSyncConfigReconciler{
+				Client:        fakeClient,
+				Log:           common.NewLoggerWithLogr(testing2.TestLogger{T: t}),
+				ClusterConfig: clusterConfig,
+			}
+
+			_, err := reconciler.Reconcile(context.TODO(), ctrl.Request{NamespacedName: client.ObjectKeyFromObject(syncConfig)})
+			assert.NoError(t, err)
+
+			updated := &cloudmapv1alpha1.CloudMapSyncConfig{}
+			assert.NoError(t, fakeClient.Get(context.TODO(), client.ObjectKeyFromObject(syncConfig), updated))
+			valid := meta.FindStatusCondition(updated.Status.Conditions, cloudmapv1alpha1.ConfigValidCondition)
+			assert.NotNil(t, valid)
+			assert.Equal(t, tt.wantValid, valid.Status)
+			assert.Equal(t, tt.wantReason, valid.Reason)
+		})
+	}
+}
+
+func TestClusterConfig_Subscribe(t *testing.T) {
+	clusterConfig := NewClusterConfig()
+	changes := clusterConfig.Subscribe()
+
+	clusterConfig.set(&cloudmapv1alpha1.ClusterCloudMapConfigSpec{CleanupPolicy: cloudmapv1alpha1.CleanupPolicyRetain})
+	clusterConfig.set(&cloudmapv1alpha1.ClusterCloudMapConfigSpec{CleanupPolicy: cloudmapv1alpha1.CleanupPolicyDelete})
+
+	// changes are coalesced into a single pending event
+	assert.Len(t, changes, 1)
+	event := <-changes
+	assert.Equal(t, cloudmapv1alpha1.ClusterCloudMapConfigName, event.Object.GetName())
+}
@@ -0,0 +1,131 @@
+package controllers
+
+import (
+	"context"
+	cloudmapv1alpha1 "github.com/aws/aws-cloud-map-mcs-controller-for-k8s/pkg/api/cloudmap/v1alpha1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/event"
+	"sync"
+)
+
+// ClusterConfig holds the cluster wide settings applied from the ClusterCloudMapConfig, shared by the controllers.
+// It is safe for concurrent use, and a nil ClusterConfig provides the default settings.
+type ClusterConfig struct {
+	mu          sync.RWMutex
+	spec        cloudmapv1alpha1.ClusterCloudMapConfigSpec
+	subscribers []chan event.GenericEvent
+}
+
+// NewClusterConfig creates a cluster config with the default settings.
+func NewClusterConfig() *ClusterConfig {
+	return &ClusterConfig{}
+}
+
+// LoadClusterConfig reads the settings of the ClusterCloudMapConfig of the cluster once, for commands running outside
+// of the controller manager, or before the manager starts. The defaults apply if the config does not exist or is
+// invalid.
+func LoadClusterConfig(ctx context.Context, c client.Reader) (*ClusterConfig, error) {
+	clusterConfig := NewClusterConfig()
+	config := &cloudmapv1alpha1.ClusterCloudMapConfig{}
+	if err := c.Get(ctx, types.NamespacedName{Name: cloudmapv1alpha1.ClusterCloudMapConfigName}, config); err != nil {
+		if errors.IsNotFound(err) {
+			return clusterConfig, nil
+		}
+		return nil, err
+	}
+
+	if errs := ValidateClusterCloudMapConfig(&config.Spec); len(errs) == 0 {
+		clusterConfig.set(&config.Spec)
+	}
+	return clusterConfig, nil
+}
+
+// CloudMapNamespace returns the name of the Cloud Map namespace of a Kubernetes namespace.
+func (c *ClusterConfig) CloudMapNamespace(namespace string) string {
+	if c == nil {
+		return namespace
+	}
+
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return c.spec.NamespaceMapping.Prefix + namespace + c.spec.NamespaceMapping.Suffix
+}
+
+// CleanupPolicy returns the cleanup policy of deleted ServiceExports.
+func (c *ClusterConfig) CleanupPolicy() cloudmapv1alpha1.CleanupPolicy {
+	if c == nil {
+		return cloudmapv1alpha1.CleanupPolicyDelete
+	}
+
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	if c.spec.CleanupPolicy == "" {
+		return cloudmapv1alpha1.CleanupPolicyDelete
+	}
+	return c.spec.CleanupPolicy
+}
+
+// EmptyServicePolicy returns the policy for Cloud Map services without instances.
+func (c *ClusterConfig) EmptyServicePolicy() cloudmapv1alpha1.EmptyServicePolicy {
+	if c == nil {
+		return cloudmapv1alpha1.EmptyServicePolicyKeep
+	}
+
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	if c.spec.EmptyServicePolicy == "" {
+		return cloudmapv1alpha1.EmptyServicePolicyKeep
+	}
+	return c.spec.EmptyServicePolicy
+}
+
+// SyncConfigLimits returns the limits of the CloudMapSyncConfig overrides.
+func (c *ClusterConfig) SyncConfigLimits() cloudmapv1alpha1.SyncConfigLimits {
+	if c == nil {
+		return cloudmapv1alpha1.SyncConfigLimits{}
+	}
+
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return *c.spec.SyncConfigLimits.DeepCopy()
+}
+
+// Spec returns a copy of the applied settings.
+func (c *ClusterConfig) Spec() cloudmapv1alpha1.ClusterCloudMapConfigSpec {
+	if c == nil {
+		return cloudmapv1alpha1.ClusterCloudMapConfigSpec{}
+	}
+
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return *c.spec.DeepCopy()
+}
+
+// Subscribe returns a channel receiving an event after each change of the settings, for use as a controller watch
+// source. Changes are coalesced while the subscriber has not received the previous event.
+func (c *ClusterConfig) Subscribe() <-chan event.GenericEvent {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	subscriber := make(chan event.GenericEvent, 1)
+	c.subscribers = append(c.subscribers, subscriber)
+	return subscriber
+}
+
+func (c *ClusterConfig) set(spec *cloudmapv1alpha1.ClusterCloudMapConfigSpec) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.spec = *spec.DeepCopy()
+
+	changed := event.GenericEvent{Object: &cloudmapv1alpha1.ClusterCloudMapConfig{
+		ObjectMeta: metav1.ObjectMeta{Name: cloudmapv1alpha1.ClusterCloudMapConfigName},
+	}}
+	for _, subscriber := range c.subscribers {
+		select {
+		case subscriber <- changed:
+		default:
+		}
+	}
+}
@@ -0,0 +1,237 @@
+package controllers
+
+import (
+	"fmt"
+	"github.com/aws/aws-cloud-map-mcs-controller-for-k8s/pkg/api/v1alpha1"
+	"github.com/aws/aws-cloud-map-mcs-controller-for-k8s/pkg/model"
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/sets"
+	"strings"
+	"time"
+)
+
+// A blue/green migration moves the traffic of a service from a source cluster to the target cluster annotated with
+// MigrateFromClusterAnnotation. The target cluster drives the migration, the phase of its endpoints is registered in
+// the MigrationPhaseAttr attribute, and importing clusters serve the endpoints according to the phase:
+//   Standby    the endpoints of the target cluster are registered but not served
+//   Promoted   the endpoints of both clusters are served
+//   Draining   the endpoints of the source cluster aren't served anymore
+//   Completed  the source cluster can stop exporting the service
+// Each phase lasts at least the phase period, and progresses once the endpoints of the target cluster are ready. The
+// migration returns to Standby if they aren't ready before it completes. The source cluster reports the drain in its
+// Drained condition.
+
+const (
+	// MigrateFromClusterAnnotation starts the migration of the service from the cluster with the given ID to this
+	// cluster. Removing the annotation serves the endpoints of both clusters again.
+	MigrateFromClusterAnnotation = "multicluster.k8s.aws/migrate-from-cluster"
+	// MigrationPhasePeriodAnnotation sets the time each phase of a migration lasts, e.g. "5m"
+	MigrationPhasePeriodAnnotation = "multicluster.k8s.aws/migration-phase-period"
+
+	// MigrationSourceAttr is the Cloud Map instance attribute holding the ID of the cluster a service migrates from
+	MigrationSourceAttr = "MIGRATION_SOURCE"
+	// MigrationPhaseAttr is the Cloud Map instance attribute holding the phase of the migration, in lower case
+	MigrationPhaseAttr = "MIGRATION_PHASE"
+
+	// DrainedCondition is the ServiceExport condition type set while the endpoints of the cluster are drained by the
+	// migration of the service to another cluster
+	DrainedCondition = "Drained"
+	// MigratedReason is the Drained condition reason for services migrated to another cluster
+	MigratedReason = "MigratedToCluster"
+	// NotDrainedReason is the Drained condition reason once the endpoints of the cluster are served again
+	NotDrainedReason = "NotDrained"
+
+	// MigrationPhaseReason is the event reason for the phase changes of a migration
+	MigrationPhaseReason = "MigrationPhaseChanged"
+	// InvalidMigrationReason is the event reason for invalid migration annotations
+	InvalidMigrationReason = "InvalidMigration"
+
+	// defaultMigrationPhasePeriod is the time each phase of a migration lasts if not annotated
+	defaultMigrationPhasePeriod = 2 * time.Minute
+	// migrationMinReady is the minimum percentage of ready endpoints for a migration to progress
+	migrationMinReady = 90
+)
+
+// migrationSettings are the settings of the migration of a ServiceExport.
+type migrationSettings struct {
+	sourceCluster string
+	period        time.Duration
+}
+
+// parseMigrationSettings returns the migration settings from the annotations of the ServiceExport, nil if no migration
+// is annotated.
+func parseMigrationSettings(serviceExport *v1alpha1.ServiceExport, clusterId string) (*migrationSettings, error) {
+	sourceCluster := strings.TrimSpace(serviceExport.Annotations[MigrateFromClusterAnnotation])
+	if sourceCluster == "" {
+		return nil, nil
+	}
+	if sourceCluster == clusterId {
+		return nil, fmt.Errorf("invalid annotation %s: the service can't migrate from its own cluster",
+			MigrateFromClusterAnnotation)
+	}
+
+	settings := &migrationSettings{sourceCluster: sourceCluster, period: defaultMigrationPhasePeriod}
+	if value, found := serviceExport.Annotations[MigrationPhasePeriodAnnotation]; found {
+		period, err := time.ParseDuration(strings.TrimSpace(value))
+		if err != nil || period <= 0 {
+			return nil, fmt.Errorf("invalid annotation %s: %q is not a positive duration",
+				MigrationPhasePeriodAnnotation, value)
+		}
+		settings.period = period
+	}
+	return settings, nil
+}
+
+// advanceMigration updates the migration status of the ServiceExport from the readiness of its endpoints, and returns
+// the time until the next phase of the migration, zero if the migration is completed. Invalid annotations hold the
+// phase.
+func (r *ServiceExportReconciler) advanceMigration(serviceExport *v1alpha1.ServiceExport, endpoints []*model.Endpoint, now time.Time) time.Duration {
+	settings, err := parseMigrationSettings(serviceExport, r.ClusterId)
+	if err != nil {
+		r.Log.Info("holding the phase of an invalid migration", "namespace", serviceExport.Namespace,
+			"name", serviceExport.Name, "reason", err.Error())
+		r.Recorder.Event(serviceExport, v1.EventTypeWarning, InvalidMigrationReason, err.Error())
+		return 0
+	}
+	migration := serviceExport.Status.Migration
+	if settings == nil {
+		serviceExport.Status.Migration = nil
+		return 0
+	}
+	if migration == nil || migration.SourceCluster != settings.sourceCluster {
+		r.setMigrationPhase(serviceExport, settings.sourceCluster, v1alpha1.MigrationStandby, now)
+		return settings.period
+	}
+	if migration.Phase == v1alpha1.MigrationCompleted {
+		return 0
+	}
+
+	ready, total := readyEndpointCount(endpoints)
+	if total == 0 || ready*100 < migrationMinReady*total {
+		if migration.Phase != v1alpha1.MigrationStandby {
+			r.Recorder.Event(serviceExport, v1.EventTypeWarning, MigrationPhaseReason,
+				fmt.Sprintf("%d of %d endpoints are ready, the migration from cluster %s returns to %s",
+					ready, total, settings.sourceCluster, v1alpha1.MigrationStandby))
+			r.setMigrationPhase(serviceExport, settings.sourceCluster, v1alpha1.MigrationStandby, now)
+		}
+		// the phase is held until the endpoints are ready
+		return settings.period
+	}
+
+	next := migration.LastTransitionTime.Add(settings.period)
+	if now.Before(next) {
+		return next.Sub(now)
+	}
+	switch migration.Phase {
+	case v1alpha1.MigrationStandby:
+		r.setMigrationPhase(serviceExport, settings.sourceCluster, v1alpha1.MigrationPromoted, now)
+	case v1alpha1.MigrationPromoted:
+		r.setMigrationPhase(serviceExport, settings.sourceCluster, v1alpha1.MigrationDraining, now)
+	default:
+		r.setMigrationPhase(serviceExport, settings.sourceCluster, v1alpha1.MigrationCompleted, now)
+		return 0
+	}
+	return settings.period
+}
+
+func (r *ServiceExportReconciler) setMigrationPhase(serviceExport *v1alpha1.ServiceExport, sourceCluster string, phase v1alpha1.MigrationPhase, now time.Time) {
+	serviceExport.Status.Migration = &v1alpha1.MigrationStatus{
+		SourceCluster:      sourceCluster,
+		Phase:              phase,
+		LastTransitionTime: metav1.NewTime(now),
+	}
+	r.Log.Info("migration phase changed", "namespace", serviceExport.Namespace, "name", serviceExport.Name,
+		"sourceCluster", sourceCluster, "phase", phase)
+	r.Recorder.Event(serviceExport, v1.EventTypeNormal, MigrationPhaseReason,
+		fmt.Sprintf("migration from cluster %s is %s", sourceCluster, phase))
+}
+
+// setMigrationAttributes sets the migration attributes of the endpoints from the migration status of the
+// ServiceExport.
+func setMigrationAttributes(serviceExport *v1alpha1.ServiceExport, endpoints []*model.Endpoint) {
+	migration := serviceExport.Status.Migration
+	if migration == nil {
+		return
+	}
+	for _, endpoint := range endpoints {
+		endpoint.Attributes[MigrationSourceAttr] = migration.SourceCluster
+		endpoint.Attributes[MigrationPhaseAttr] = strings.ToLower(string(migration.Phase))
+	}
+}
+
+// drainedClusters returns the clusters whose endpoints are drained by the migration of the service to the cluster of
+// a ready endpoint, by the target cluster.
+func drainedClusters(endpoints []*model.Endpoint) map[string]string {
+	drained := make(map[string]string)
+	for _, endpoint := range endpoints {
+		switch endpoint.Attributes[MigrationPhaseAttr] {
+		case strings.ToLower(string(v1alpha1.MigrationDraining)), strings.ToLower(string(v1alpha1.MigrationCompleted)):
+			if endpoint.IsReady() {
+				drained[endpoint.Attributes[MigrationSourceAttr]] = endpoint.Attributes[ClusterIdAttr]
+			}
+		}
+	}
+	return drained
+}
+
+// servedEndpoints returns the endpoints importing clusters serve: endpoints on standby, the endpoints of clusters
+// drained by a migration and the endpoints with the weight 0 are dropped.
+func servedEndpoints(endpoints []*model.Endpoint) []*model.Endpoint {
+	drained := drainedClusters(endpoints)
+	result := make([]*model.Endpoint, 0, len(endpoints))
+	for _, endpoint := range endpoints {
+		if endpoint.Attributes[MigrationPhaseAttr] == strings.ToLower(string(v1alpha1.MigrationStandby)) {
+			continue
+		}
+		if _, found := drained[endpoint.Attributes[ClusterIdAttr]]; found {
+			continue
+		}
+		result = append(result, endpoint)
+	}
+	return weightedEndpoints(result)
+}
+
+// setDrainedCondition sets the Drained condition of the ServiceExport while the endpoints of the cluster are drained
+// by a migration to another cluster, and clears it once they are served again.
+func (r *ServiceExportReconciler) setDrainedCondition(serviceExport *v1alpha1.ServiceExport, cmEndpoints []*model.Endpoint) {
+	condition := metav1.Condition{
+		Type:               DrainedCondition,
+		Status:             metav1.ConditionFalse,
+		ObservedGeneration: serviceExport.Generation,
+		Reason:             NotDrainedReason,
+		Message:            "endpoints are served to importing clusters",
+	}
+	targets := sets.NewString()
+	if r.ClusterId != "" {
+		for source, target := range drainedClusters(cmEndpoints) {
+			if source == r.ClusterId {
+				targets.Insert(target)
+			}
+		}
+	}
+	if targets.Len() > 0 {
+		condition.Status = metav1.ConditionTrue
+		condition.Reason = MigratedReason
+		condition.Message = fmt.Sprintf("endpoints are drained by the migration of the service to cluster %s, "+
+			"the ServiceExport can be deleted once the migration is completed", strings.Join(targets.List(), ", "))
+	}
+
+	if meta.FindStatusCondition(serviceExport.Status.Conditions, condition.Type) == nil && targets.Len() == 0 {
+		return
+	}
+
+	meta.SetStatusCondition(&serviceExport.Status.Conditions, condition)
+}
+
+// nextRequeue returns the shortest positive duration, zero if none.
+func nextRequeue(durations ...time.Duration) time.Duration {
+	var next time.Duration
+	for _, duration := range durations {
+		if duration > 0 && (next == 0 || duration < next) {
+			next = duration
+		}
+	}
+	return next
+}
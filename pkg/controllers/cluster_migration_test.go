@@ -0,0 +1,102 @@
+package controllers
+
+import (
+	"github.com/aws/aws-cloud-map-mcs-controller-for-k8s/pkg/api/v1alpha1"
+	"github.com/aws/aws-cloud-map-mcs-controller-for-k8s/pkg/common"
+	"github.com/aws/aws-cloud-map-mcs-controller-for-k8s/pkg/model"
+	"github.com/aws/aws-cloud-map-mcs-controller-for-k8s/test"
+	testingLogger "github.com/go-logr/logr/testing"
+	"github.com/stretchr/testify/assert"
+	"k8s.io/apimachinery/pkg/api/meta"
+	"k8s.io/client-go/tools/record"
+	"testing"
+	"time"
+)
+
+func TestAdvanceMigration(t *testing.T) {
+	reconciler := &ServiceExportReconciler{
+		Log:       common.NewLoggerWithLogr(testingLogger.TestLogger{T: t}),
+		Recorder:  record.NewFakeRecorder(10),
+		ClusterId: test.ClusterId,
+	}
+	serviceExport := testServiceExportObj()
+	serviceExport.Annotations = map[string]string{
+		MigrateFromClusterAnnotation:   "cluster-a",
+		MigrationPhasePeriodAnnotation: "1m",
+	}
+	notReady := false
+	unready := test.GetTestEndpoint1()
+	unready.Ready = &notReady
+	ready := []*model.Endpoint{test.GetTestEndpoint1(), test.GetTestEndpoint2()}
+	start := time.Now()
+
+	assert.Equal(t, time.Minute, reconciler.advanceMigration(serviceExport, ready, start))
+	assert.Equal(t, v1alpha1.MigrationStandby, serviceExport.Status.Migration.Phase)
+	assert.Equal(t, "cluster-a", serviceExport.Status.Migration.SourceCluster)
+
+	// the phase is held while the endpoints aren't ready
+	reconciler.advanceMigration(serviceExport, []*model.Endpoint{unready}, start.Add(time.Minute))
+	assert.Equal(t, v1alpha1.MigrationStandby, serviceExport.Status.Migration.Phase)
+
+	reconciler.advanceMigration(serviceExport, ready, start.Add(time.Minute))
+	assert.Equal(t, v1alpha1.MigrationPromoted, serviceExport.Status.Migration.Phase)
+
+	// the migration returns to standby once the endpoints aren't ready
+	reconciler.advanceMigration(serviceExport, []*model.Endpoint{unready}, start.Add(90*time.Second))
+	assert.Equal(t, v1alpha1.MigrationStandby, serviceExport.Status.Migration.Phase)
+
+	reconciler.advanceMigration(serviceExport, ready, start.Add(3*time.Minute))
+	reconciler.advanceMigration(serviceExport, ready, start.Add(4*time.Minute))
+	assert.Equal(t, v1alpha1.MigrationDraining, serviceExport.Status.Migration.Phase)
+	setMigrationAttributes(serviceExport, ready)
+	assert.Equal(t, "draining", ready[0].Attributes[MigrationPhaseAttr])
+	assert.Equal(t, "cluster-a", ready[0].Attributes[MigrationSourceAttr])
+
+	assert.Zero(t, reconciler.advanceMigration(serviceExport, ready, start.Add(5*time.Minute)))
+	assert.Equal(t, v1alpha1.MigrationCompleted, serviceExport.Status.Migration.Phase)
+
+	// removing the annotation ends the migration
+	serviceExport.Annotations = nil
+	reconciler.advanceMigration(serviceExport, ready, start.Add(6*time.Minute))
+	assert.Nil(t, serviceExport.Status.Migration)
+}
+
+func TestServedEndpoints(t *testing.T) {
+	source := test.GetTestEndpoint1()
+	source.Attributes[ClusterIdAttr] = "cluster-a"
+	target := test.GetTestEndpoint2()
+	target.Attributes[ClusterIdAttr] = "cluster-b"
+	target.Attributes[MigrationSourceAttr] = "cluster-a"
+
+	target.Attributes[MigrationPhaseAttr] = "standby"
+	assert.Equal(t, []*model.Endpoint{source}, servedEndpoints([]*model.Endpoint{source, target}))
+
+	target.Attributes[MigrationPhaseAttr] = "promoted"
+	assert.Equal(t, []*model.Endpoint{source, target}, servedEndpoints([]*model.Endpoint{source, target}))
+
+	target.Attributes[MigrationPhaseAttr] = "draining"
+	assert.Equal(t, []*model.Endpoint{target}, servedEndpoints([]*model.Endpoint{source, target}))
+
+	// the source cluster is only drained while the target cluster has ready endpoints
+	notReady := false
+	target.Ready = &notReady
+	assert.Equal(t, []*model.Endpoint{source, target}, servedEndpoints([]*model.Endpoint{source, target}))
+}
+
+func TestSetDrainedCondition(t *testing.T) {
+	reconciler := &ServiceExportReconciler{ClusterId: "cluster-a"}
+	serviceExport := testServiceExportObj()
+	target := test.GetTestEndpoint2()
+	target.Attributes[ClusterIdAttr] = "cluster-b"
+	target.Attributes[MigrationSourceAttr] = "cluster-a"
+	target.Attributes[MigrationPhaseAttr] = "completed"
+
+	reconciler.setDrainedCondition(serviceExport, []*model.Endpoint{target})
+	condition := meta.FindStatusCondition(serviceExport.Status.Conditions, DrainedCondition)
+	assert.NotNil(t, condition)
+	assert.Equal(t, MigratedReason, condition.Reason)
+	assert.Contains(t, condition.Message, "cluster-b")
+
+	reconciler.setDrainedCondition(serviceExport, nil)
+	assert.True(t, meta.IsStatusConditionFalse(serviceExport.Status.Conditions, DrainedCondition))
+}
@@ -0,0 +1,187 @@
+package controllers
+
+import (
+	"context"
+	"fmt"
+	cloudmapv1alpha1 "github.com/aws/aws-cloud-map-mcs-controller-for-k8s/pkg/api/cloudmap/v1alpha1"
+	"github.com/aws/aws-cloud-map-mcs-controller-for-k8s/pkg/cloudmap"
+	"github.com/aws/aws-cloud-map-mcs-controller-for-k8s/pkg/common"
+	"k8s.io/apimachinery/pkg/api/equality"
+	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/validation"
+	"regexp"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"strings"
+)
+
+const (
+	// ConfigValidReason is the Valid condition reason of configurations which passed validation
+	ConfigValidReason = "ValidSpec"
+	// ConfigInvalidReason is the condition reason of configurations which failed validation
+	ConfigInvalidReason = "InvalidSpec"
+	// ConfigIgnoredReason is the condition reason of configurations which are not applied because of their name
+	ConfigIgnoredReason = "Ignored"
+	// ConfigAppliedReason is the Applied condition reason of configurations which are in effect
+	ConfigAppliedReason = "Applied"
+	// ConfigRestartRequiredReason is the Applied condition reason of configurations which require a restart
+	ConfigRestartRequiredReason = "RestartRequired"
+)
+
+var regionPattern = regexp.MustCompile(`^[a-z]{2}(-[a-z]+)+-[0-9]+$`)
+
+// ClusterCloudMapConfigReconciler validates the ClusterCloudMapConfig, applies it to the shared ClusterConfig and
+// reports the result in its status.
+type ClusterCloudMapConfigReconciler struct {
+	Client        client.Client
+	Log           common.Logger
+	ClusterConfig *ClusterConfig
+	// RateLimiter limits the Cloud Map API requests, the rate limit of the config is not applied if nil
+	RateLimiter *cloudmap.RateLimiter
+	// Region is the AWS region the controller was started with
+	Region string
+}
+
+// +kubebuilder:rbac:groups=cloudmap.multicluster.k8s.aws,resources=clustercloudmapconfigs,verbs=get;list;watch
+// +kubebuilder:rbac:groups=cloudmap.multicluster.k8s.aws,resources=clustercloudmapconfigs/status,verbs=get;update;patch
+
+func (r *ClusterCloudMapConfigReconciler) Reconcile(ctx context.Context, req ctrl.Request) (ctrl.Result, error) {
+	config := &cloudmapv1alpha1.ClusterCloudMapConfig{}
+	if err := r.Client.Get(ctx, req.NamespacedName, config); err != nil {
+		if errors.IsNotFound(err) && req.Name == cloudmapv1alpha1.ClusterCloudMapConfigName {
+			r.Log.Info("ClusterCloudMapConfig deleted, reverting to the default settings")
+			r.apply(&cloudmapv1alpha1.ClusterCloudMapConfigSpec{})
+		}
+		return ctrl.Result{}, client.IgnoreNotFound(err)
+	}
+
+	originalStatus := config.Status.DeepCopy()
+	valid := metav1.Condition{
+		Type:               cloudmapv1alpha1.ConfigValidCondition,
+		Status:             metav1.ConditionTrue,
+		ObservedGeneration: config.Generation,
+		Reason:             ConfigValidReason,
+		Message:            "the configuration is valid",
+	}
+	applied := metav1.Condition{
+		Type:               cloudmapv1alpha1.ConfigAppliedCondition,
+		Status:             metav1.ConditionTrue,
+		ObservedGeneration: config.Generation,
+		Reason:             ConfigAppliedReason,
+		Message:            "the configuration is in effect",
+	}
+
+	if errs := ValidateClusterCloudMapConfig(&config.Spec); len(errs) > 0 {
+		valid.Status = metav1.ConditionFalse
+		valid.Reason = ConfigInvalidReason
+		valid.Message = strings.Join(errs, "; ")
+		applied.Status = metav1.ConditionFalse
+		applied.Reason = ConfigInvalidReason
+		applied.Message = "the last valid configuration remains in effect"
+	} else if config.Name != cloudmapv1alpha1.ClusterCloudMapConfigName {
+		applied.Status = metav1.ConditionFalse
+		applied.Reason = ConfigIgnoredReason
+		applied.Message = fmt.Sprintf("only the ClusterCloudMapConfig named %s is applied",
+			cloudmapv1alpha1.ClusterCloudMapConfigName)
+	} else {
+		r.apply(&config.Spec)
+		if config.Spec.Region != "" && config.Spec.Region != r.Region {
+			applied.Status = metav1.ConditionFalse
+			applied.Reason = ConfigRestartRequiredReason
+			applied.Message = fmt.Sprintf("the controller runs in region %s, changing the region to %s requires a restart",
+				r.Region, config.Spec.Region)
+		}
+	}
+
+	meta.SetStatusCondition(&config.Status.Conditions, valid)
+	meta.SetStatusCondition(&config.Status.Conditions, applied)
+	config.Status.ObservedGeneration = config.Generation
+	if equality.Semantic.DeepEqual(originalStatus, &config.Status) {
+		return ctrl.Result{}, nil
+	}
+
+	if err := r.Client.Status().Update(ctx, config); err != nil {
+		r.Log.Error(err, "error updating ClusterCloudMapConfig status", "name", config.Name)
+		return ctrl.Result{}, err
+	}
+	return ctrl.Result{}, nil
+}
+
+// apply makes the settings effective for all controllers.
+func (r *ClusterCloudMapConfigReconciler) apply(spec *cloudmapv1alpha1.ClusterCloudMapConfigSpec) {
+	current := r.ClusterConfig.Spec()
+	if equality.Semantic.DeepEqual(&current, spec) {
+		return
+	}
+
+	r.Log.Info("applying cluster configuration", "namespacePrefix", spec.NamespaceMapping.Prefix,
+		"namespaceSuffix", spec.NamespaceMapping.Suffix, "cleanupPolicy", spec.CleanupPolicy,
+		"emptyServicePolicy", spec.EmptyServicePolicy)
+	if r.RateLimiter != nil && !equality.Semantic.DeepEqual(current.RateLimit, spec.RateLimit) {
+		if spec.RateLimit == nil {
+			r.RateLimiter.SetLimit(0, 0)
+		} else {
+			r.RateLimiter.SetLimit(float32(spec.RateLimit.QPS), int(spec.RateLimit.Burst))
+		}
+	}
+	r.ClusterConfig.set(spec)
+}
+
+// ValidateClusterCloudMapConfig returns the validation errors of the cluster configuration.
+func ValidateClusterCloudMapConfig(spec *cloudmapv1alpha1.ClusterCloudMapConfigSpec) []string {
+	errs := make([]string, 0)
+
+	mapping := spec.NamespaceMapping
+	if mapping.Prefix != "" || mapping.Suffix != "" {
+		if msgs := validation.IsDNS1123Subdomain(mapping.Prefix + "namespace" + mapping.Suffix); len(msgs) > 0 {
+			errs = append(errs, fmt.Sprintf("namespaceMapping does not produce valid Cloud Map namespace names: %s",
+				strings.Join(msgs, ", ")))
+		}
+	}
+
+	if spec.Region != "" && !regionPattern.MatchString(spec.Region) {
+		errs = append(errs, fmt.Sprintf("region %s is not a valid AWS region", spec.Region))
+	}
+
+	switch spec.CleanupPolicy {
+	case "", cloudmapv1alpha1.CleanupPolicyDelete, cloudmapv1alpha1.CleanupPolicyRetain:
+	default:
+		errs = append(errs, fmt.Sprintf("unsupported cleanupPolicy %s", spec.CleanupPolicy))
+	}
+
+	switch spec.EmptyServicePolicy {
+	case "", cloudmapv1alpha1.EmptyServicePolicyKeep, cloudmapv1alpha1.EmptyServicePolicyTag,
+		cloudmapv1alpha1.EmptyServicePolicyDelete:
+	default:
+		errs = append(errs, fmt.Sprintf("unsupported emptyServicePolicy %s", spec.EmptyServicePolicy))
+	}
+
+	if spec.RateLimit != nil && (spec.RateLimit.QPS < 1 || spec.RateLimit.Burst < 1) {
+		errs = append(errs, "rateLimit qps and burst must be at least 1")
+	}
+
+	limits := spec.SyncConfigLimits
+	if limits.MinDNSTTL != nil && limits.MaxDNSTTL != nil && *limits.MinDNSTTL > *limits.MaxDNSTTL {
+		errs = append(errs, "syncConfigLimits minDNSTTL must not exceed maxDNSTTL")
+	}
+	for _, attr := range limits.AllowedAttributes {
+		if !OptionalAttributes.Has(attr) {
+			errs = append(errs, fmt.Sprintf("unknown attribute %s in syncConfigLimits allowedAttributes", attr))
+		}
+	}
+	for _, policy := range limits.AllowedCleanupPolicies {
+		if policy != cloudmapv1alpha1.CleanupPolicyDelete && policy != cloudmapv1alpha1.CleanupPolicyRetain {
+			errs = append(errs, fmt.Sprintf("unsupported cleanupPolicy %s in syncConfigLimits", policy))
+		}
+	}
+
+	return errs
+}
+
+func (r *ClusterCloudMapConfigReconciler) SetupWithManager(mgr ctrl.Manager) error {
+	return ctrl.NewControllerManagedBy(mgr).
+		For(&cloudmapv1alpha1.ClusterCloudMapConfig{}).
+		Complete(r)
+}
@@ -0,0 +1,164 @@
+package controllers
+
+import (
+	"context"
+	cloudmapv1alpha1 "github.com/aws/aws-cloud-map-mcs-controller-for-k8s/pkg/api/cloudmap/v1alpha1"
+	"github.com/aws/aws-cloud-map-mcs-controller-for-k8s/pkg/common"
+	"github.com/aws/aws-cloud-map-mcs-controller-for-k8s/test"
+	testing2 "github.com/go-logr/logr/testing"
+	"github.com/stretchr/testify/assert"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	"testing"
+)
+
+func TestClusterCloudMapConfigReconciler_Reconcile(t *testing.T) {
+	tests := []struct {
+		name          string
+		configName    string
+		spec          cloudmapv1alpha1.ClusterCloudMapConfigSpec
+		wantValid     metav1.ConditionStatus
+		wantApplied   string
+		wantNamespace string
+		wantCleanup   cloudmapv1alpha1.CleanupPolicy
+		wantEmpty     cloudmapv1alpha1.EmptyServicePolicy
+	}{
+		{
+			name:       "valid config is applied",
+			configName: cloudmapv1alpha1.ClusterCloudMapConfigName,
+			spec: cloudmapv1alpha1.ClusterCloudMapConfigSpec{
+				NamespaceMapping:   cloudmapv1alpha1.NamespaceMapping{Prefix: "prod-"},
+				CleanupPolicy:      cloudmapv1alpha1.CleanupPolicyRetain,
+				EmptyServicePolicy: cloudmapv1alpha1.EmptyServicePolicyTag,
+				RateLimit:          &cloudmapv1alpha1.RateLimit{QPS: 5, Burst: 10},
+			},
+			wantValid:     metav1.ConditionTrue,
+			wantApplied:   ConfigAppliedReason,
+			wantNamespace: "prod-" + test.NsName,
+			wantCleanup:   cloudmapv1alpha1.CleanupPolicyRetain,
+			wantEmpty:     cloudmapv1alpha1.EmptyServicePolicyTag,
+		},
+		{
+			name:       "region change requires restart",
+			configName: cloudmapv1alpha1.ClusterCloudMapConfigName,
+			spec: cloudmapv1alpha1.ClusterCloudMapConfigSpec{
+				NamespaceMapping: cloudmapv1alpha1.NamespaceMapping{Suffix: "-eu"},
+				Region:           "eu-west-1",
+			},
+			wantValid:     metav1.ConditionTrue,
+			wantApplied:   ConfigRestartRequiredReason,
+			wantNamespace: test.NsName + "-eu",
+			wantCleanup:   cloudmapv1alpha1.CleanupPolicyDelete,
+			wantEmpty:     cloudmapv1alpha1.EmptyServicePolicyKeep,
+		},
+		{
+			name:       "invalid config keeps defaults",
+			configName: cloudmapv1alpha1.ClusterCloudMapConfigName,
+			spec: cloudmapv1alpha1.ClusterCloudMapConfigSpec{
+				NamespaceMapping: cloudmapv1alpha1.NamespaceMapping{Prefix: "Prod_"},
+				RateLimit:        &cloudmapv1alpha1.RateLimit{QPS: 0, Burst: 1},
+			},
+			wantValid:     metav1.ConditionFalse,
+			wantApplied:   ConfigInvalidReason,
+			wantNamespace: test.NsName,
+			wantCleanup:   cloudmapv1alpha1.CleanupPolicyDelete,
+			wantEmpty:     cloudmapv1alpha1.EmptyServicePolicyKeep,
+		},
+		{
+			name:       "config with other name is ignored",
+			configName: "other",
+			spec: cloudmapv1alpha1.ClusterCloudMapConfigSpec{
+				NamespaceMapping: cloudmapv1alpha1.NamespaceMapping{Prefix: "prod-"},
+			},
+			wantValid:     metav1.ConditionTrue,
+			wantApplied:   ConfigIgnoredReason,
+			wantNamespace: test.NsName,
+			wantCleanup:   cloudmapv1alpha1.CleanupPolicyDelete,
+			wantEmpty:     cloudmapv1alpha1.EmptyServicePolicyKeep,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			config := &cloudmapv1alpha1.ClusterCloudMapConfig{
+				ObjectMeta: metav1.ObjectMeta{Name: tt.configName, Generation: 2},
+				Spec:       tt.spec,
+			}
+			fakeClient := fake.NewClientBuilder().WithScheme(getClusterCloudMapConfigScheme()).
+				WithObjects(config).Build()
+			reconciler := getClusterCloudMapConfigReconciler(t, fakeClient)
+
+			_, err := reconciler.Reconcile(context.TODO(), ctrl.Request{
+				NamespacedName: types.NamespacedName{Name: tt.configName},
+			})
+			assert.NoError(t, err)
+
+			assert.Equal(t, tt.wantNamespace, reconciler.ClusterConfig.CloudMapNamespace(test.NsName))
+			assert.Equal(t, tt.wantCleanup, reconciler.ClusterConfig.CleanupPolicy())
+			assert.Equal(t, tt.wantEmpty, reconciler.ClusterConfig.EmptyServicePolicy())
+
+			updated := &cloudmapv1alpha1.ClusterCloudMapConfig{}
+			assert.NoError(t, fakeClient.Get(context.TODO(), client.ObjectKey{Name: tt.configName}, updated))
+			assert.Equal(t, int64(2), updated.Status.ObservedGeneration)
+			assert.True(t, meta.IsStatusConditionPresentAndEqual(updated.Status.Conditions,
+				cloudmapv1alpha1.ConfigValidCondition, tt.wantValid))
+			applied := meta.FindStatusCondition(updated.Status.Conditions, cloudmapv1alpha1.ConfigAppliedCondition)
+			assert.NotNil(t, applied)
+			assert.Equal(t, tt.wantApplied, applied.Reason)
+		})
+	}
+}
+
+func TestClusterCloudMapConfigReconciler_Reconcile_Deleted(t *testing.T) {
+	fakeClient := fake.NewClientBuilder().WithScheme(getClusterCloudMapConfigScheme()).Build()
+	reconciler := getClusterCloudMapConfigReconciler(t, fakeClient)
+	reconciler.ClusterConfig.set(&cloudmapv1alpha1.ClusterCloudMapConfigSpec{
+		NamespaceMapping: cloudmapv1alpha1.NamespaceMapping{Prefix: "prod-"},
+	})
+
+	_, err := reconciler.Reconcile(context.TODO(), ctrl.Request{
+		NamespacedName: types.NamespacedName{Name: cloudmapv1alpha1.ClusterCloudMapConfigName},
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, test.NsName, reconciler.ClusterConfig.CloudMapNamespace(test.NsName))
+}
+
+func TestLoadClusterConfig(t *testing.T) {
+	fakeClient := fake.NewClientBuilder().WithScheme(getClusterCloudMapConfigScheme()).Build()
+	clusterConfig, err := LoadClusterConfig(context.TODO(), fakeClient)
+	assert.NoError(t, err)
+	assert.Equal(t, test.NsName, clusterConfig.CloudMapNamespace(test.NsName), "the defaults apply without config")
+
+	fakeClient = fake.NewClientBuilder().WithScheme(getClusterCloudMapConfigScheme()).
+		WithObjects(&cloudmapv1alpha1.ClusterCloudMapConfig{
+			ObjectMeta: metav1.ObjectMeta{Name: cloudmapv1alpha1.ClusterCloudMapConfigName},
+			Spec: cloudmapv1alpha1.ClusterCloudMapConfigSpec{
+				NamespaceMapping: cloudmapv1alpha1.NamespaceMapping{Prefix: "prod-"},
+				CleanupPolicy:    cloudmapv1alpha1.CleanupPolicyRetain,
+			},
+		}).Build()
+	clusterConfig, err = LoadClusterConfig(context.TODO(), fakeClient)
+	assert.NoError(t, err)
+	assert.Equal(t, "prod-"+test.NsName, clusterConfig.CloudMapNamespace(test.NsName))
+	assert.Equal(t, cloudmapv1alpha1.CleanupPolicyRetain, clusterConfig.CleanupPolicy())
+}
+
+func getClusterCloudMapConfigScheme() *runtime.Scheme {
+	scheme := runtime.NewScheme()
+	scheme.AddKnownTypes(cloudmapv1alpha1.GroupVersion,
+		&cloudmapv1alpha1.ClusterCloudMapConfig{}, &cloudmapv1alpha1.ClusterCloudMapConfigList{})
+	return scheme
+}
+
+func getClusterCloudMapConfigReconciler(t *testing.T, client client.Client) *ClusterCloudMapConfigReconciler {
+	return &ClusterCloudMapConfigReconciler{
+		Client:        client,
+		Log:           common.NewLoggerWithLogr(testing2.TestLogger{T: t}),
+		ClusterConfig: NewClusterConfig(),
+		Region:        "us-west-2",
+	}
+}
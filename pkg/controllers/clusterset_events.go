@@ -0,0 +1,43 @@
+package controllers
+
+import (
+	"context"
+	"github.com/aws/aws-cloud-map-mcs-controller-for-k8s/pkg/events"
+	"github.com/aws/aws-cloud-map-mcs-controller-for-k8s/pkg/model"
+)
+
+// publishRegistered publishes the endpoints created by an export, and whether the cluster joined the service by
+// registering its first endpoints.
+func (r *ServiceExportReconciler) publishRegistered(ctx context.Context, cmNamespace string, name string, created []*model.Endpoint, joined bool) {
+	published := make([]events.ClusterSetEvent, 0, 2)
+	if joined {
+		published = append(published, events.ClusterSetEvent{Type: events.ClusterJoined, Namespace: cmNamespace, Service: name})
+	}
+	if len(created) > 0 {
+		published = append(published, events.ClusterSetEvent{Type: events.EndpointsAdded, Namespace: cmNamespace,
+			Service: name, Endpoints: eventEndpoints(created)})
+	}
+	r.Publisher.Publish(ctx, published...)
+}
+
+// publishDeregistered publishes the endpoints deleted by an export, and whether the cluster left the service by
+// de-registering its last endpoints.
+func (r *ServiceExportReconciler) publishDeregistered(ctx context.Context, cmNamespace string, name string, deleted []*model.Endpoint, left bool) {
+	published := make([]events.ClusterSetEvent, 0, 2)
+	if len(deleted) > 0 {
+		published = append(published, events.ClusterSetEvent{Type: events.EndpointsRemoved, Namespace: cmNamespace,
+			Service: name, Endpoints: eventEndpoints(deleted)})
+	}
+	if left {
+		published = append(published, events.ClusterSetEvent{Type: events.ClusterLeft, Namespace: cmNamespace, Service: name})
+	}
+	r.Publisher.Publish(ctx, published...)
+}
+
+func eventEndpoints(endpoints []*model.Endpoint) []events.Endpoint {
+	result := make([]events.Endpoint, 0, len(endpoints))
+	for _, endpoint := range endpoints {
+		result = append(result, events.Endpoint{IP: endpoint.IP, Port: endpoint.EndpointPort.Port})
+	}
+	return result
+}
@@ -0,0 +1,67 @@
+package controllers
+
+import (
+	"context"
+	"encoding/json"
+	"github.com/aws/aws-cloud-map-mcs-controller-for-k8s/mocks/pkg/cloudmap"
+	mockevents "github.com/aws/aws-cloud-map-mcs-controller-for-k8s/mocks/pkg/events"
+	"github.com/aws/aws-cloud-map-mcs-controller-for-k8s/pkg/events"
+	"github.com/aws/aws-cloud-map-mcs-controller-for-k8s/pkg/model"
+	"github.com/aws/aws-cloud-map-mcs-controller-for-k8s/test"
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/sns"
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/assert"
+	"k8s.io/apimachinery/pkg/types"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	"testing"
+)
+
+func TestServiceExportReconciler_Reconcile_PublishesClusterSetEvents(t *testing.T) {
+	fakeClient := fake.NewClientBuilder().
+		WithScheme(getServiceExportScheme()).
+		WithObjects(testServiceObj(), testServiceExportObj()).
+		WithLists(testEndpointSliceObj()).
+		Build()
+
+	mockController := gomock.NewController(t)
+	defer mockController.Finish()
+
+	mock := cloudmap.NewMockServiceDiscoveryClient(mockController)
+	gomock.InOrder(
+		mock.EXPECT().GetService(gomock.Any(), test.NsName, test.SvcName).Return(nil, nil),
+		mock.EXPECT().GetService(gomock.Any(), test.NsName, test.SvcName).
+			Return(&model.Service{Namespace: test.NsName, Name: test.SvcName}, nil),
+	)
+	mock.EXPECT().CreateService(gomock.Any(), test.NsName, test.SvcName).Return(nil)
+	mock.EXPECT().RegisterEndpoints(gomock.Any(), test.NsName, test.SvcName,
+		[]*model.Endpoint{test.GetTestEndpoint1()}).Return(nil)
+
+	published := make([]events.ClusterSetEvent, 0)
+	snsFacade := mockevents.NewMockSnsFacade(mockController)
+	snsFacade.EXPECT().Publish(gomock.Any(), gomock.Any()).Times(3).
+		DoAndReturn(func(ctx context.Context, input *sns.PublishInput, _ ...func(*sns.Options)) (*sns.PublishOutput, error) {
+			event := events.ClusterSetEvent{}
+			assert.NoError(t, json.Unmarshal([]byte(aws.ToString(input.Message)), &event))
+			published = append(published, event)
+			return &sns.PublishOutput{}, nil
+		})
+
+	reconciler := getServiceExportReconciler(t, mock, fakeClient)
+	reconciler.Publisher = events.NewSnsPublisher(snsFacade, "arn:aws:sns:us-west-2:123456789012:clusterset",
+		test.ClusterId, "")
+
+	_, err := reconciler.Reconcile(context.Background(), ctrl.Request{
+		NamespacedName: types.NamespacedName{Namespace: test.NsName, Name: test.SvcName},
+	})
+	assert.NoError(t, err)
+
+	if assert.Len(t, published, 3) {
+		assert.Equal(t, events.ServiceExported, published[0].Type)
+		assert.Equal(t, events.ClusterJoined, published[1].Type)
+		assert.Equal(t, events.EndpointsAdded, published[2].Type)
+		assert.Equal(t, []events.Endpoint{{IP: test.EndptIp1, Port: test.Port1}}, published[2].Endpoints)
+		assert.Equal(t, test.ClusterId, published[2].ClusterId)
+	}
+}
@@ -0,0 +1,88 @@
+package controllers
+
+import (
+	"context"
+	"github.com/aws/aws-cloud-map-mcs-controller-for-k8s/pkg/api/v1alpha1"
+	"github.com/aws/aws-cloud-map-mcs-controller-for-k8s/pkg/common"
+	v1 "k8s.io/api/core/v1"
+	discovery "k8s.io/api/discovery/v1beta1"
+	"k8s.io/apimachinery/pkg/types"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/builder"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/event"
+	"sigs.k8s.io/controller-runtime/pkg/handler"
+	"sigs.k8s.io/controller-runtime/pkg/predicate"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+	"sigs.k8s.io/controller-runtime/pkg/source"
+)
+
+// ImportRepairer re-imports a ServiceImport whose resources were deleted, it is implemented by CloudMapReconciler.
+type ImportRepairer interface {
+	Repair(namespace string, name string)
+}
+
+// DerivedResourceReconciler watches the deletions of the ServiceImports, derived Services and EndpointSlices, and has
+// the ServiceImport they belong to re-imported right away, instead of waiting for the next sync. Resources deleted by
+// the import itself, e.g. the ServiceImports of services which aren't imported anymore, aren't repaired.
+type DerivedResourceReconciler struct {
+	Log      common.Logger
+	Repairer ImportRepairer
+}
+
+// Reconcile has the ServiceImport of the request re-imported.
+func (r *DerivedResourceReconciler) Reconcile(_ context.Context, req ctrl.Request) (ctrl.Result, error) {
+	r.Log.Debug("derived resource deleted, repairing ServiceImport", "namespace", req.Namespace, "name", req.Name)
+	r.Repairer.Repair(req.Namespace, req.Name)
+	return ctrl.Result{}, nil
+}
+
+func (r *DerivedResourceReconciler) SetupWithManager(mgr ctrl.Manager) error {
+	return ctrl.NewControllerManagedBy(mgr).
+		Named("derived-resources").
+		For(&v1alpha1.ServiceImport{}, builder.WithPredicates(deletionFilter())).
+		Watches(
+			&source.Kind{Type: &v1.Service{}},
+			handler.EnqueueRequestsFromMapFunc(derivedServiceEventHandler),
+			builder.WithPredicates(deletionFilter()),
+		).
+		Watches(
+			&source.Kind{Type: &discovery.EndpointSlice{}},
+			handler.EnqueueRequestsFromMapFunc(importedEndpointSliceEventHandler),
+			builder.WithPredicates(deletionFilter()),
+		).
+		Complete(r)
+}
+
+// deletionFilter only passes the deletion events.
+func deletionFilter() predicate.Funcs {
+	return predicate.Funcs{
+		CreateFunc:  func(event.CreateEvent) bool { return false },
+		UpdateFunc:  func(event.UpdateEvent) bool { return false },
+		DeleteFunc:  func(event.DeleteEvent) bool { return true },
+		GenericFunc: func(event.GenericEvent) bool { return false },
+	}
+}
+
+// derivedServiceEventHandler enqueues the ServiceImport controlling a derived Service, which is named after it.
+func derivedServiceEventHandler(object client.Object) []reconcile.Request {
+	for _, ref := range object.GetOwnerReferences() {
+		if ref.Controller != nil && *ref.Controller && object.GetName() == DerivedName(object.GetNamespace(), ref.Name) {
+			return []reconcile.Request{
+				{NamespacedName: types.NamespacedName{Namespace: object.GetNamespace(), Name: ref.Name}},
+			}
+		}
+	}
+	return nil
+}
+
+// importedEndpointSliceEventHandler enqueues the ServiceImport of an EndpointSlice of a derived Service.
+func importedEndpointSliceEventHandler(object client.Object) []reconcile.Request {
+	name, found := object.GetLabels()[LabelServiceImportName]
+	if !found || name == "" {
+		return nil
+	}
+	return []reconcile.Request{
+		{NamespacedName: types.NamespacedName{Namespace: object.GetNamespace(), Name: name}},
+	}
+}
@@ -0,0 +1,98 @@
+package controllers
+
+import (
+	"context"
+	"github.com/aws/aws-cloud-map-mcs-controller-for-k8s/mocks/pkg/cloudmap"
+	cloudmapv1alpha1 "github.com/aws/aws-cloud-map-mcs-controller-for-k8s/pkg/api/cloudmap/v1alpha1"
+	"github.com/aws/aws-cloud-map-mcs-controller-for-k8s/pkg/api/v1alpha1"
+	"github.com/aws/aws-cloud-map-mcs-controller-for-k8s/pkg/model"
+	"github.com/aws/aws-cloud-map-mcs-controller-for-k8s/test"
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/assert"
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/api/discovery/v1beta1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/kubernetes/scheme"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+	"testing"
+)
+
+func TestCloudMapReconciler_RepairService(t *testing.T) {
+	s := scheme.Scheme
+	s.AddKnownTypes(v1alpha1.GroupVersion, &v1alpha1.ServiceImportList{}, &v1alpha1.ServiceImport{})
+	s.AddKnownTypes(cloudmapv1alpha1.GroupVersion, &cloudmapv1alpha1.CloudMapSyncConfig{})
+	fakeClient := fake.NewClientBuilder().WithRuntimeObjects(testNamespace()).Build()
+
+	mockController := gomock.NewController(t)
+	defer mockController.Finish()
+
+	mockSDClient := cloudmap.NewMockServiceDiscoveryClient(mockController)
+	mockSDClient.EXPECT().ListServices(context.TODO(), test.NsName).
+		Return([]*model.Service{test.GetTestServiceWithEndpoint([]*model.Endpoint{test.GetTestEndpoint1()})}, nil)
+	// the repair imports the cached service, Cloud Map didn't change
+	mockSDClient.EXPECT().GetService(context.TODO(), test.NsName, test.SvcName).
+		Return(test.GetTestServiceWithEndpoint([]*model.Endpoint{test.GetTestEndpoint1()}), nil)
+
+	reconciler := getReconciler(t, mockSDClient, fakeClient)
+	assert.NoError(t, reconciler.Reconcile(context.TODO()))
+
+	importName := types.NamespacedName{Namespace: test.NsName, Name: test.SvcName}
+	derivedName := types.NamespacedName{Namespace: test.NsName, Name: DerivedName(test.NsName, test.SvcName)}
+	svcImport := &v1alpha1.ServiceImport{}
+	assert.NoError(t, fakeClient.Get(context.TODO(), importName, svcImport))
+	derivedService := &v1.Service{}
+	assert.NoError(t, fakeClient.Get(context.TODO(), derivedName, derivedService))
+	assert.NoError(t, fakeClient.Delete(context.TODO(), svcImport))
+	assert.NoError(t, fakeClient.Delete(context.TODO(), derivedService))
+	assert.NoError(t, fakeClient.DeleteAllOf(context.TODO(), &v1beta1.EndpointSlice{}, client.InNamespace(test.NsName)))
+
+	assert.True(t, reconciler.repairService(context.TODO(), types.NamespacedName{Namespace: test.NsName, Name: "unknown"}),
+		"ServiceImports which aren't imported aren't repaired")
+	assert.True(t, reconciler.repairService(context.TODO(), importName))
+
+	assert.NoError(t, fakeClient.Get(context.TODO(), importName, &v1alpha1.ServiceImport{}), "ServiceImport re-derived")
+	assert.NoError(t, fakeClient.Get(context.TODO(), derivedName, &v1.Service{}), "derived Service recreated")
+	endpointSliceList := &v1beta1.EndpointSliceList{}
+	assert.NoError(t, fakeClient.List(context.TODO(), endpointSliceList, client.InNamespace(test.NsName)))
+	assert.Len(t, endpointSliceList.Items, 1, "EndpointSlice recreated")
+}
+
+func TestCloudMapReconciler_Repair_QueueFull(t *testing.T) {
+	reconciler := getReconciler(t, nil, nil)
+	for i := 0; i < refreshQueueSize+1; i++ {
+		// never blocks
+		reconciler.Repair(test.NsName, test.SvcName)
+	}
+	assert.Len(t, reconciler.repairQueue(), refreshQueueSize)
+}
+
+func TestDerivedResourceEventHandlers(t *testing.T) {
+	svcImport := &v1alpha1.ServiceImport{ObjectMeta: metav1.ObjectMeta{Namespace: test.NsName, Name: test.SvcName}}
+	expected := []reconcile.Request{{NamespacedName: types.NamespacedName{Namespace: test.NsName, Name: test.SvcName}}}
+
+	derivedService := &v1.Service{ObjectMeta: metav1.ObjectMeta{
+		Namespace: test.NsName,
+		Name:      DerivedName(test.NsName, test.SvcName),
+		OwnerReferences: []metav1.OwnerReference{
+			*metav1.NewControllerRef(svcImport, v1alpha1.GroupVersion.WithKind("ServiceImport")),
+		},
+	}}
+	assert.Equal(t, expected, derivedServiceEventHandler(derivedService))
+
+	otherService := derivedService.DeepCopy()
+	otherService.Name = "other"
+	assert.Empty(t, derivedServiceEventHandler(otherService), "Services not named after their owner aren't derived")
+
+	endpointSlice := &v1beta1.EndpointSlice{ObjectMeta: metav1.ObjectMeta{
+		Namespace: test.NsName,
+		Name:      "slice",
+		Labels:    map[string]string{LabelServiceImportName: test.SvcName},
+	}}
+	assert.Equal(t, expected, importedEndpointSliceEventHandler(endpointSlice))
+
+	endpointSlice.Labels = map[string]string{v1beta1.LabelServiceName: test.SvcName}
+	assert.Empty(t, importedEndpointSliceEventHandler(endpointSlice), "EndpointSlices which aren't imported are ignored")
+}
@@ -0,0 +1,42 @@
+package controllers
+
+import (
+	"context"
+	"fmt"
+	"github.com/aws/aws-cloud-map-mcs-controller-for-k8s/pkg/api/v1alpha1"
+	"github.com/aws/aws-cloud-map-mcs-controller-for-k8s/pkg/cloudmap"
+	"github.com/aws/aws-cloud-map-mcs-controller-for-k8s/pkg/registry"
+	v1 "k8s.io/api/core/v1"
+	"strings"
+)
+
+const (
+	// DnsConfigCorrectedReason is the event reason for Cloud Map services whose drifted DNS configuration was corrected
+	DnsConfigCorrectedReason = "DnsConfigCorrected"
+	// DnsConfigDriftReason is the event reason for drifts of the DNS configuration which can't be corrected
+	DnsConfigDriftReason = "DnsConfigDrift"
+)
+
+// correctDnsConfig corrects the DNS configuration of the Cloud Map service if it drifted from the DNS record TTL of the
+// context, e.g. after the TTL of the CloudMapSyncConfig changed or the service was edited in the console, and reports
+// the drift in events of the ServiceExport.
+func (r *ServiceExportReconciler) correctDnsConfig(ctx context.Context, serviceExport *v1alpha1.ServiceExport, cmNamespace string, name string) error {
+	drift, err := registry.CorrectDnsConfig(ctx, r.Registry, cmNamespace, name, cloudmap.DnsTTLFromContext(ctx))
+	if err != nil {
+		return err
+	}
+
+	if len(drift.Corrected) > 0 {
+		r.Log.Info("corrected the DNS configuration of the Cloud Map service", "namespace", serviceExport.Namespace,
+			"name", name, "cloudMapNamespace", cmNamespace, "corrections", drift.Corrected)
+		r.Recorder.Event(serviceExport, v1.EventTypeNormal, DnsConfigCorrectedReason,
+			fmt.Sprintf("corrected the DNS configuration of Cloud Map service %s/%s: %s", cmNamespace, name,
+				strings.Join(drift.Corrected, ", ")))
+	}
+	if len(drift.Uncorrectable) > 0 {
+		r.Recorder.Event(serviceExport, v1.EventTypeWarning, DnsConfigDriftReason,
+			fmt.Sprintf("the DNS configuration of Cloud Map service %s/%s can't be corrected, "+
+				"the service must be recreated: %s", cmNamespace, name, strings.Join(drift.Uncorrectable, ", ")))
+	}
+	return nil
+}
@@ -0,0 +1,62 @@
+package controllers
+
+import (
+	"context"
+	"github.com/aws/aws-cloud-map-mcs-controller-for-k8s/mocks/pkg/cloudmap"
+	cmclient "github.com/aws/aws-cloud-map-mcs-controller-for-k8s/pkg/cloudmap"
+	"github.com/aws/aws-cloud-map-mcs-controller-for-k8s/pkg/common"
+	"github.com/aws/aws-cloud-map-mcs-controller-for-k8s/pkg/model"
+	"github.com/aws/aws-cloud-map-mcs-controller-for-k8s/test"
+	testingLogger "github.com/go-logr/logr/testing"
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/assert"
+	"k8s.io/client-go/tools/record"
+	"testing"
+)
+
+func TestServiceExportReconciler_CorrectDnsConfig(t *testing.T) {
+	mockController := gomock.NewController(t)
+	defer mockController.Finish()
+
+	mock := cloudmap.NewMockServiceDiscoveryClient(mockController)
+	mock.EXPECT().CorrectDnsConfig(gomock.Any(), test.NsName, test.SvcName, int64(30)).
+		Return(model.DnsConfigDrift{
+			Corrected:     []string{"SRV record TTL 300 -> 30"},
+			Uncorrectable: []string{"unexpected A record"},
+		}, nil)
+
+	recorder := record.NewFakeRecorder(10)
+	reconciler := &ServiceExportReconciler{
+		Log:      common.NewLoggerWithLogr(testingLogger.TestLogger{T: t}),
+		Registry: mock,
+		Recorder: recorder,
+	}
+
+	ctx := cmclient.WithDnsTTL(context.TODO(), 30)
+	err := reconciler.correctDnsConfig(ctx, testServiceExportObj(), test.NsName, test.SvcName)
+	assert.NoError(t, err)
+	assert.Contains(t, <-recorder.Events, "Normal DnsConfigCorrected corrected the DNS configuration of Cloud Map "+
+		"service "+test.NsName+"/"+test.SvcName+": SRV record TTL 300 -> 30")
+	assert.Contains(t, <-recorder.Events, "Warning DnsConfigDrift")
+}
+
+func TestServiceExportReconciler_CorrectDnsConfig_NoDrift(t *testing.T) {
+	mockController := gomock.NewController(t)
+	defer mockController.Finish()
+
+	// the default TTL is desired
+	mock := cloudmap.NewMockServiceDiscoveryClient(mockController)
+	mock.EXPECT().CorrectDnsConfig(gomock.Any(), test.NsName, test.SvcName, cmclient.DnsTTLFromContext(context.TODO())).
+		Return(model.DnsConfigDrift{}, nil)
+
+	recorder := record.NewFakeRecorder(10)
+	reconciler := &ServiceExportReconciler{
+		Log:      common.NewLoggerWithLogr(testingLogger.TestLogger{T: t}),
+		Registry: mock,
+		Recorder: recorder,
+	}
+
+	err := reconciler.correctDnsConfig(context.TODO(), testServiceExportObj(), test.NsName, test.SvcName)
+	assert.NoError(t, err)
+	assert.Empty(t, recorder.Events)
+}
@@ -0,0 +1,56 @@
+package controllers
+
+import (
+	"context"
+	"github.com/aws/aws-cloud-map-mcs-controller-for-k8s/pkg/common"
+	"github.com/aws/aws-cloud-map-mcs-controller-for-k8s/pkg/registry"
+	"time"
+)
+
+const (
+	// DefaultEmptyNamespaceGracePeriod is the default time a Cloud Map namespace stays empty before it is deleted
+	DefaultEmptyNamespaceGracePeriod = time.Hour
+	// defaultEmptyNamespaceInterval is the interval of the empty namespace collections if not configured
+	defaultEmptyNamespaceInterval = 5 * time.Minute
+)
+
+// EmptyNamespaceCollector deletes the Cloud Map namespaces created by the cluster once the last service in them is
+// removed and the grace period elapsed, so namespaces of ephemeral environments don't accumulate. Namespaces created
+// by other clusters or tools are left alone.
+type EmptyNamespaceCollector struct {
+	Log      common.Logger
+	Registry registry.ServiceRegistry
+	// GracePeriod is the time a namespace stays empty before it is deleted
+	GracePeriod time.Duration
+	// Interval is the interval of the collections, defaultEmptyNamespaceInterval is used if not positive
+	Interval time.Duration
+}
+
+// Start collects the empty namespaces every interval, until the context is done.
+func (c *EmptyNamespaceCollector) Start(ctx context.Context) error {
+	interval := c.Interval
+	if interval <= 0 {
+		interval = defaultEmptyNamespaceInterval
+	}
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		if err := c.Collect(ctx); err != nil {
+			c.Log.Error(err, "unable to delete empty Cloud Map namespaces")
+		}
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+		}
+	}
+}
+
+// Collect deletes the namespaces which have been empty for the grace period.
+func (c *EmptyNamespaceCollector) Collect(ctx context.Context) error {
+	deleted, err := registry.DeleteEmptyNamespaces(ctx, c.Registry, c.GracePeriod)
+	for _, name := range deleted {
+		c.Log.Info("deleted empty Cloud Map namespace", "cloudMapNamespace", name)
+	}
+	return err
+}
@@ -0,0 +1,31 @@
+package controllers
+
+import (
+	"context"
+	"errors"
+	"github.com/aws/aws-cloud-map-mcs-controller-for-k8s/mocks/pkg/cloudmap"
+	"github.com/aws/aws-cloud-map-mcs-controller-for-k8s/pkg/common"
+	"github.com/aws/aws-cloud-map-mcs-controller-for-k8s/test"
+	testingLogger "github.com/go-logr/logr/testing"
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/assert"
+	"testing"
+	"time"
+)
+
+func TestEmptyNamespaceCollector_Collect(t *testing.T) {
+	mockController := gomock.NewController(t)
+	defer mockController.Finish()
+
+	deleteErr := errors.New("namespace has services")
+	mock := cloudmap.NewMockServiceDiscoveryClient(mockController)
+	// the namespaces which were deleted are reported along with the failures
+	mock.EXPECT().DeleteEmptyNamespaces(gomock.Any(), time.Hour).Return([]string{test.NsName}, deleteErr)
+
+	collector := &EmptyNamespaceCollector{
+		Log:         common.NewLoggerWithLogr(testingLogger.TestLogger{T: t}),
+		Registry:    mock,
+		GracePeriod: time.Hour,
+	}
+	assert.Equal(t, deleteErr, collector.Collect(context.TODO()))
+}
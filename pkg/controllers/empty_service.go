@@ -0,0 +1,87 @@
+package controllers
+
+import (
+	"context"
+	"fmt"
+	cloudmapv1alpha1 "github.com/aws/aws-cloud-map-mcs-controller-for-k8s/pkg/api/cloudmap/v1alpha1"
+	"github.com/aws/aws-cloud-map-mcs-controller-for-k8s/pkg/api/v1alpha1"
+	"github.com/aws/aws-cloud-map-mcs-controller-for-k8s/pkg/registry"
+	v1 "k8s.io/api/core/v1"
+)
+
+const (
+	// EmptyServiceDeletedReason is the event reason for Cloud Map services deleted by the empty service policy
+	EmptyServiceDeletedReason = "CloudMapServiceDeleted"
+	// EmptyServiceMarkedReason is the event reason for Cloud Map services tagged as empty by the empty service policy
+	EmptyServiceMarkedReason = "CloudMapServiceMarkedEmpty"
+	// EmptyServicePolicyFailedReason is the event reason for failures applying the empty service policy
+	EmptyServicePolicyFailedReason = "EmptyServicePolicyFailed"
+)
+
+// applyEmptyServicePolicy deletes or tags the Cloud Map service according to the empty service policy of the cluster
+// config, once no cluster has instances registered. The service is tidied up on a best effort basis: failures are
+// reported in an event but don't fail the reconciliation, a service which is deleted while another cluster registers
+// instances is kept by Cloud Map.
+func (r *ServiceExportReconciler) applyEmptyServicePolicy(ctx context.Context, serviceExport *v1alpha1.ServiceExport, cmNamespace string, name string) {
+	applied, err := tidyEmptyService(ctx, r.Registry, r.ClusterConfig.EmptyServicePolicy(), cmNamespace, name)
+	if err != nil {
+		r.emptyServicePolicyFailed(serviceExport, cmNamespace, name, err)
+		return
+	}
+
+	switch applied {
+	case cloudmapv1alpha1.EmptyServicePolicyDelete:
+		r.Log.Info("deleted empty Cloud Map service", "namespace", cmNamespace, "name", name)
+		r.Recorder.Eventf(serviceExport, v1.EventTypeNormal, EmptyServiceDeletedReason,
+			"deleted Cloud Map service %s/%s without instances", cmNamespace, name)
+	case cloudmapv1alpha1.EmptyServicePolicyTag:
+		r.Log.Info("marked Cloud Map service empty", "namespace", cmNamespace, "name", name)
+		r.Recorder.Eventf(serviceExport, v1.EventTypeNormal, EmptyServiceMarkedReason,
+			"tagged Cloud Map service %s/%s without instances as empty", cmNamespace, name)
+	}
+}
+
+// tidyEmptyService deletes or tags the Cloud Map service according to the empty service policy if no cluster has
+// instances registered, and returns the policy applied, EmptyServicePolicyKeep if the service was left as is.
+func tidyEmptyService(ctx context.Context, reg registry.ServiceRegistry, policy cloudmapv1alpha1.EmptyServicePolicy, cmNamespace string, name string) (cloudmapv1alpha1.EmptyServicePolicy, error) {
+	if policy == cloudmapv1alpha1.EmptyServicePolicyKeep {
+		return cloudmapv1alpha1.EmptyServicePolicyKeep, nil
+	}
+
+	cmService, err := reg.GetService(ctx, cmNamespace, name)
+	if err != nil {
+		return cloudmapv1alpha1.EmptyServicePolicyKeep, err
+	}
+	if cmService == nil || len(cmService.Endpoints) > 0 {
+		return cloudmapv1alpha1.EmptyServicePolicyKeep, nil
+	}
+
+	switch policy {
+	case cloudmapv1alpha1.EmptyServicePolicyDelete:
+		err = reg.DeleteService(ctx, cmNamespace, name)
+	case cloudmapv1alpha1.EmptyServicePolicyTag:
+		err = registry.MarkServiceEmpty(ctx, reg, cmNamespace, name, true)
+	default:
+		return cloudmapv1alpha1.EmptyServicePolicyKeep, nil
+	}
+	if err != nil {
+		return cloudmapv1alpha1.EmptyServicePolicyKeep, err
+	}
+	return policy, nil
+}
+
+// unmarkEmptyService removes the empty tag of a Cloud Map service once instances are registered again.
+func (r *ServiceExportReconciler) unmarkEmptyService(ctx context.Context, serviceExport *v1alpha1.ServiceExport, cmNamespace string, name string) {
+	if r.ClusterConfig.EmptyServicePolicy() != cloudmapv1alpha1.EmptyServicePolicyTag {
+		return
+	}
+	if err := registry.MarkServiceEmpty(ctx, r.Registry, cmNamespace, name, false); err != nil {
+		r.emptyServicePolicyFailed(serviceExport, cmNamespace, name, err)
+	}
+}
+
+func (r *ServiceExportReconciler) emptyServicePolicyFailed(serviceExport *v1alpha1.ServiceExport, cmNamespace string, name string, err error) {
+	r.Log.Error(err, "error applying the empty service policy", "namespace", cmNamespace, "name", name)
+	r.Recorder.Event(serviceExport, v1.EventTypeWarning, EmptyServicePolicyFailedReason,
+		fmt.Sprintf("failed to apply the empty service policy to Cloud Map service %s/%s: %s", cmNamespace, name, err))
+}
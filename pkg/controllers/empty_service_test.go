@@ -0,0 +1,121 @@
+package controllers
+
+import (
+	"context"
+	"github.com/aws/aws-cloud-map-mcs-controller-for-k8s/mocks/pkg/cloudmap"
+	cloudmapv1alpha1 "github.com/aws/aws-cloud-map-mcs-controller-for-k8s/pkg/api/cloudmap/v1alpha1"
+	"github.com/aws/aws-cloud-map-mcs-controller-for-k8s/pkg/model"
+	"github.com/aws/aws-cloud-map-mcs-controller-for-k8s/test"
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/assert"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/tools/record"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	"testing"
+)
+
+func TestServiceExportReconciler_Reconcile_DeleteEmptyService(t *testing.T) {
+	serviceExportObj := testServiceExportObj()
+	serviceExportObj.Finalizers = []string{ServiceExportFinalizer}
+	fakeClient := fake.NewClientBuilder().
+		WithScheme(getServiceExportScheme()).
+		WithObjects(serviceExportObj).
+		Build()
+
+	mockController := gomock.NewController(t)
+	defer mockController.Finish()
+
+	mock := cloudmap.NewMockServiceDiscoveryClient(mockController)
+	gomock.InOrder(
+		mock.EXPECT().GetService(gomock.Any(), test.NsName, test.SvcName).
+			Return(test.GetTestServiceWithEndpoint([]*model.Endpoint{test.GetTestEndpoint1()}), nil),
+		mock.EXPECT().DeleteEndpoints(gomock.Any(), test.NsName, test.SvcName,
+			[]*model.Endpoint{test.GetTestEndpoint1()}).Return(nil),
+		mock.EXPECT().GetService(gomock.Any(), test.NsName, test.SvcName).
+			Return(test.GetTestServiceWithEndpoint([]*model.Endpoint{}), nil),
+		mock.EXPECT().DeleteService(gomock.Any(), test.NsName, test.SvcName).Return(nil),
+	)
+
+	reconciler := getServiceExportReconciler(t, mock, fakeClient)
+	reconciler.ClusterConfig = NewClusterConfig()
+	reconciler.ClusterConfig.set(&cloudmapv1alpha1.ClusterCloudMapConfigSpec{
+		EmptyServicePolicy: cloudmapv1alpha1.EmptyServicePolicyDelete,
+	})
+	recorder := reconciler.Recorder.(*record.FakeRecorder)
+
+	_, err := reconciler.Reconcile(context.Background(), ctrl.Request{
+		NamespacedName: types.NamespacedName{Namespace: test.NsName, Name: test.SvcName},
+	})
+	assert.NoError(t, err)
+
+	assert.Len(t, recorder.Events, 2)
+	assert.Contains(t, <-recorder.Events, DeregisteredReason)
+	assert.Contains(t, <-recorder.Events, EmptyServiceDeletedReason)
+}
+
+func TestServiceExportReconciler_Reconcile_KeepServiceOfOtherClusters(t *testing.T) {
+	serviceExportObj := testServiceExportObj()
+	serviceExportObj.Finalizers = []string{ServiceExportFinalizer}
+	fakeClient := fake.NewClientBuilder().
+		WithScheme(getServiceExportScheme()).
+		WithObjects(serviceExportObj).
+		Build()
+
+	mockController := gomock.NewController(t)
+	defer mockController.Finish()
+
+	other := test.GetTestEndpoint2()
+	other.Attributes[ClusterIdAttr] = "other-cluster"
+	mock := cloudmap.NewMockServiceDiscoveryClient(mockController)
+	gomock.InOrder(
+		mock.EXPECT().GetService(gomock.Any(), test.NsName, test.SvcName).
+			Return(test.GetTestServiceWithEndpoint([]*model.Endpoint{test.GetTestEndpoint1(), other}), nil),
+		mock.EXPECT().DeleteEndpoints(gomock.Any(), test.NsName, test.SvcName,
+			[]*model.Endpoint{test.GetTestEndpoint1()}).Return(nil),
+		// the instance of the other cluster keeps the service
+		mock.EXPECT().GetService(gomock.Any(), test.NsName, test.SvcName).
+			Return(test.GetTestServiceWithEndpoint([]*model.Endpoint{other}), nil),
+	)
+
+	reconciler := getServiceExportReconciler(t, mock, fakeClient)
+	reconciler.ClusterId = test.ClusterId
+	reconciler.ClusterConfig = NewClusterConfig()
+	reconciler.ClusterConfig.set(&cloudmapv1alpha1.ClusterCloudMapConfigSpec{
+		EmptyServicePolicy: cloudmapv1alpha1.EmptyServicePolicyTag,
+	})
+
+	_, err := reconciler.Reconcile(context.Background(), ctrl.Request{
+		NamespacedName: types.NamespacedName{Namespace: test.NsName, Name: test.SvcName},
+	})
+	assert.NoError(t, err)
+}
+
+func TestServiceExportReconciler_Reconcile_UnmarkEmptyService(t *testing.T) {
+	fakeClient := fake.NewClientBuilder().
+		WithScheme(getServiceExportScheme()).
+		WithObjects(testServiceObj(), testServiceExportObj()).
+		WithLists(testEndpointSliceObj()).
+		Build()
+
+	mockController := gomock.NewController(t)
+	defer mockController.Finish()
+
+	mock := cloudmap.NewMockServiceDiscoveryClient(mockController)
+	mock.EXPECT().GetService(gomock.Any(), test.NsName, test.SvcName).
+		Return(test.GetTestServiceWithEndpoint([]*model.Endpoint{}), nil)
+	mock.EXPECT().RegisterEndpoints(gomock.Any(), test.NsName, test.SvcName,
+		[]*model.Endpoint{test.GetTestEndpoint1()}).Return(nil)
+	mock.EXPECT().MarkServiceEmpty(gomock.Any(), test.NsName, test.SvcName, false).Return(nil)
+
+	reconciler := getServiceExportReconciler(t, mock, fakeClient)
+	reconciler.ClusterConfig = NewClusterConfig()
+	reconciler.ClusterConfig.set(&cloudmapv1alpha1.ClusterCloudMapConfigSpec{
+		EmptyServicePolicy: cloudmapv1alpha1.EmptyServicePolicyTag,
+	})
+
+	_, err := reconciler.Reconcile(context.Background(), ctrl.Request{
+		NamespacedName: types.NamespacedName{Namespace: test.NsName, Name: test.SvcName},
+	})
+	assert.NoError(t, err)
+}
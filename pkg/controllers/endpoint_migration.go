@@ -0,0 +1,40 @@
+package controllers
+
+import (
+	"github.com/aws/aws-cloud-map-mcs-controller-for-k8s/pkg/model"
+)
+
+// Instances registered before instance IDs included the exporting cluster have the ID of
+// model.EndpointIdFromIPAddressAndPort. During an upgrade, the exporting cluster registers the endpoints under their
+// new ID, and de-registers the legacy instances as endpoints which are not desired anymore once the registrations
+// succeeded, so both instances of an endpoint can briefly exist in Cloud Map.
+
+// dedupeMigratedEndpoints drops the legacy instances of endpoints which are also registered under their new ID by
+// the same cluster, so importing clusters don't see an endpoint twice while the exporting cluster migrates it.
+// Endpoints of other clusters sharing the address and port are kept.
+func dedupeMigratedEndpoints(endpoints []*model.Endpoint) []*model.Endpoint {
+	migrated := make(map[string]bool)
+	for _, endpoint := range endpoints {
+		if !hasLegacyId(endpoint) {
+			migrated[clusterEndpointKey(endpoint)] = true
+		}
+	}
+
+	result := make([]*model.Endpoint, 0, len(endpoints))
+	for _, endpoint := range endpoints {
+		if hasLegacyId(endpoint) && migrated[clusterEndpointKey(endpoint)] {
+			continue
+		}
+		result = append(result, endpoint)
+	}
+	return result
+}
+
+func hasLegacyId(endpoint *model.Endpoint) bool {
+	return endpoint.Id == model.EndpointIdFromIPAddressAndPort(endpoint.IP, endpoint.EndpointPort)
+}
+
+// endpointKey identifies an endpoint by its address and port, independent of the ID scheme.
+func endpointKey(endpoint *model.Endpoint) string {
+	return endpoint.IP + "/" + endpoint.EndpointPort.GetID()
+}
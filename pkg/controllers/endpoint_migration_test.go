@@ -0,0 +1,23 @@
+package controllers
+
+import (
+	"github.com/aws/aws-cloud-map-mcs-controller-for-k8s/pkg/model"
+	"github.com/aws/aws-cloud-map-mcs-controller-for-k8s/test"
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func TestDedupeMigratedEndpoints(t *testing.T) {
+	legacy := test.GetTestEndpoint1()
+	legacy.Attributes[ClusterIdAttr] = test.ClusterId
+	migrated := test.GetTestEndpoint1()
+	migrated.Id = model.EndpointId(test.ClusterId, migrated.IP, migrated.EndpointPort)
+	migrated.Attributes[ClusterIdAttr] = test.ClusterId
+	notMigrated := test.GetTestEndpoint2()
+	// the legacy instance of another cluster with an overlapping pod network is not a duplicate
+	otherCluster := test.GetTestEndpoint1()
+	otherCluster.Attributes[ClusterIdAttr] = "other-cluster"
+
+	assert.Equal(t, []*model.Endpoint{migrated, notMigrated, otherCluster},
+		dedupeMigratedEndpoints([]*model.Endpoint{legacy, migrated, notMigrated, otherCluster}))
+}
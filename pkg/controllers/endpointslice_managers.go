@@ -0,0 +1,43 @@
+package controllers
+
+import (
+	discovery "k8s.io/api/discovery/v1beta1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"strings"
+)
+
+const (
+	// EndpointSliceControllerManager manages the EndpointSlices of the endpoints selected by the selector of a Service
+	EndpointSliceControllerManager = "endpointslice-controller.k8s.io"
+	// EndpointSliceMirroringManager manages the EndpointSlices mirroring the Endpoints of a Service without selector
+	EndpointSliceMirroringManager = "endpointslice-mirroring-controller.k8s.io"
+	// AllEndpointSliceManagers exports the EndpointSlices of a Service regardless of their manager
+	AllEndpointSliceManagers = "*"
+)
+
+// ParseEndpointSliceManagers parses a comma separated list of EndpointSlice managers.
+func ParseEndpointSliceManagers(value string) []string {
+	managers := make([]string, 0)
+	for _, manager := range strings.Split(value, ",") {
+		if manager = strings.TrimSpace(manager); manager != "" {
+			managers = append(managers, manager)
+		}
+	}
+	return managers
+}
+
+// exportsEndpointSlice returns whether the endpoints of the EndpointSlice are exported, according to the manager
+// label of the EndpointSlice. The EndpointSlices of the EndpointSlice controller, and those without manager, are always
+// exported, the EndpointSlices of other managers if listed in EndpointSliceManagers.
+func (r *ServiceExportReconciler) exportsEndpointSlice(slice metav1.Object) bool {
+	manager := slice.GetLabels()[discovery.LabelManagedBy]
+	if manager == "" || manager == EndpointSliceControllerManager {
+		return true
+	}
+	for _, exported := range r.EndpointSliceManagers {
+		if exported == AllEndpointSliceManagers || exported == manager {
+			return true
+		}
+	}
+	return false
+}
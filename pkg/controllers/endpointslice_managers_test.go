@@ -0,0 +1,84 @@
+package controllers
+
+import (
+	"context"
+	"github.com/aws/aws-cloud-map-mcs-controller-for-k8s/mocks/pkg/cloudmap"
+	"github.com/aws/aws-cloud-map-mcs-controller-for-k8s/pkg/model"
+	"github.com/aws/aws-cloud-map-mcs-controller-for-k8s/test"
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/assert"
+	discovery "k8s.io/api/discovery/v1beta1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	"testing"
+)
+
+func TestServiceExportReconciler_Reconcile_MirroredEndpointSlice(t *testing.T) {
+	service := testServiceObj()
+	service.Spec.Selector = nil
+	slices := testEndpointSliceObj()
+	slices.Items[0].Labels[discovery.LabelManagedBy] = EndpointSliceMirroringManager
+	fakeClient := fake.NewClientBuilder().
+		WithScheme(getServiceExportScheme()).
+		WithObjects(service, testServiceExportObj()).
+		WithLists(slices).
+		Build()
+
+	mockController := gomock.NewController(t)
+	defer mockController.Finish()
+
+	mock := cloudmap.NewMockServiceDiscoveryClient(mockController)
+	mock.EXPECT().GetService(gomock.Any(), test.NsName, test.SvcName).
+		Return(&model.Service{Namespace: test.NsName, Name: test.SvcName}, nil)
+	mock.EXPECT().RegisterEndpoints(gomock.Any(), test.NsName, test.SvcName,
+		[]*model.Endpoint{test.GetTestEndpoint1()}).Return(nil).Times(1)
+
+	reconciler := getServiceExportReconciler(t, mock, fakeClient)
+	reconciler.EndpointSliceManagers = []string{EndpointSliceMirroringManager}
+
+	request := ctrl.Request{
+		NamespacedName: types.NamespacedName{
+			Namespace: test.NsName,
+			Name:      test.SvcName,
+		},
+	}
+
+	_, err := reconciler.Reconcile(context.Background(), request)
+	assert.NoError(t, err)
+}
+
+func TestServiceExportReconciler_ExportsEndpointSlice(t *testing.T) {
+	tests := []struct {
+		name     string
+		managers []string
+		managed  string
+		want     bool
+	}{
+		{name: "no manager", managed: "", want: true},
+		{name: "endpointslice controller", managed: EndpointSliceControllerManager, want: true},
+		{name: "mirroring not exported", managed: EndpointSliceMirroringManager, want: false},
+		{name: "mirroring exported", managers: []string{EndpointSliceMirroringManager},
+			managed: EndpointSliceMirroringManager, want: true},
+		{name: "custom not listed", managers: []string{EndpointSliceMirroringManager},
+			managed: "custom-controller", want: false},
+		{name: "all managers", managers: []string{AllEndpointSliceManagers}, managed: "custom-controller", want: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			reconciler := &ServiceExportReconciler{EndpointSliceManagers: tt.managers}
+			slice := &discovery.EndpointSlice{ObjectMeta: metav1.ObjectMeta{Labels: map[string]string{}}}
+			if tt.managed != "" {
+				slice.Labels[discovery.LabelManagedBy] = tt.managed
+			}
+			assert.Equal(t, tt.want, reconciler.exportsEndpointSlice(slice))
+		})
+	}
+}
+
+func TestParseEndpointSliceManagers(t *testing.T) {
+	assert.Empty(t, ParseEndpointSliceManagers(""))
+	assert.Equal(t, []string{EndpointSliceMirroringManager, "custom-controller"},
+		ParseEndpointSliceManagers(EndpointSliceMirroringManager+", custom-controller,"))
+}
@@ -0,0 +1,74 @@
+package controllers
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	v1 "k8s.io/api/core/v1"
+	discovery "k8s.io/api/discovery/v1beta1"
+	"k8s.io/apimachinery/pkg/api/equality"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/event"
+	"sigs.k8s.io/controller-runtime/pkg/predicate"
+)
+
+// serviceExportFilter suppresses the updates of ServiceExports which don't change what is exported, e.g. the status
+// updates of the reconciler itself. Changes of the spec, labels and annotations, and deletions, pass.
+func serviceExportFilter() predicate.Funcs {
+	return predicate.Funcs{
+		UpdateFunc: func(e event.UpdateEvent) bool {
+			oldObj, newObj := e.ObjectOld, e.ObjectNew
+			return oldObj.GetGeneration() != newObj.GetGeneration() ||
+				!equality.Semantic.DeepEqual(oldObj.GetLabels(), newObj.GetLabels()) ||
+				!equality.Semantic.DeepEqual(oldObj.GetAnnotations(), newObj.GetAnnotations()) ||
+				!equality.Semantic.DeepEqual(oldObj.GetFinalizers(), newObj.GetFinalizers()) ||
+				(oldObj.GetDeletionTimestamp() == nil) != (newObj.GetDeletionTimestamp() == nil)
+		},
+	}
+}
+
+// generationChanged returns true if the spec of the object changed, status updates leave the generation as is.
+func generationChanged(e event.UpdateEvent) bool {
+	return e.ObjectOld.GetGeneration() != e.ObjectNew.GetGeneration()
+}
+
+// serviceChanged returns true if the spec, labels or annotations of the Service changed, Services don't maintain
+// their generation and their status, e.g. the load balancer ingress, isn't exported.
+func serviceChanged(e event.UpdateEvent) bool {
+	oldService, oldOk := e.ObjectOld.(*v1.Service)
+	newService, newOk := e.ObjectNew.(*v1.Service)
+	if !oldOk || !newOk {
+		return true
+	}
+	return !equality.Semantic.DeepEqual(oldService.Spec, newService.Spec) ||
+		!equality.Semantic.DeepEqual(oldService.Labels, newService.Labels) ||
+		!equality.Semantic.DeepEqual(oldService.Annotations, newService.Annotations)
+}
+
+// endpointSliceChanged returns true if the endpoints of the EndpointSlice changed, by comparing their hashes. Updates
+// of the other metadata of the EndpointSlice, e.g. the trigger time annotation of the EndpointSlice controller, don't
+// change the exported endpoints.
+func endpointSliceChanged(e event.UpdateEvent) bool {
+	oldHash, newHash := endpointSliceHash(e.ObjectOld), endpointSliceHash(e.ObjectNew)
+	return oldHash == "" || oldHash != newHash
+}
+
+// endpointSliceHash returns a hash of the exported content of the EndpointSlice: its labels, address type, endpoints
+// and ports. Objects which aren't EndpointSlices hash to an empty string.
+func endpointSliceHash(object client.Object) string {
+	slice, ok := object.(*discovery.EndpointSlice)
+	if !ok {
+		return ""
+	}
+	content, err := json.Marshal(struct {
+		Labels      map[string]string
+		AddressType discovery.AddressType
+		Endpoints   []discovery.Endpoint
+		Ports       []discovery.EndpointPort
+	}{slice.Labels, slice.AddressType, slice.Endpoints, slice.Ports})
+	if err != nil {
+		return ""
+	}
+	hash := sha256.Sum256(content)
+	return hex.EncodeToString(hash[:])
+}
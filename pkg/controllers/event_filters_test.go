@@ -0,0 +1,67 @@
+package controllers
+
+import (
+	"github.com/aws/aws-cloud-map-mcs-controller-for-k8s/test"
+	"github.com/stretchr/testify/assert"
+	v1 "k8s.io/api/core/v1"
+	discovery "k8s.io/api/discovery/v1beta1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/event"
+	"testing"
+)
+
+func TestServiceExportFilter(t *testing.T) {
+	filter := serviceExportFilter()
+	serviceExport := testServiceExportObj()
+	serviceExport.Generation = 1
+
+	statusUpdate := serviceExport.DeepCopy()
+	statusUpdate.ResourceVersion = "2"
+	statusUpdate.Status.Conditions = []metav1.Condition{{Type: "Ready", Status: metav1.ConditionTrue}}
+	assert.False(t, filter.Update(event.UpdateEvent{ObjectOld: serviceExport, ObjectNew: statusUpdate}),
+		"status updates are suppressed")
+
+	annotated := serviceExport.DeepCopy()
+	annotated.Annotations = map[string]string{CanaryWeightStepAnnotation: "10"}
+	assert.True(t, filter.Update(event.UpdateEvent{ObjectOld: serviceExport, ObjectNew: annotated}))
+
+	deleted := serviceExport.DeepCopy()
+	now := metav1.Now()
+	deleted.DeletionTimestamp = &now
+	assert.True(t, filter.Update(event.UpdateEvent{ObjectOld: serviceExport, ObjectNew: deleted}))
+
+	assert.True(t, filter.Create(event.CreateEvent{Object: serviceExport}))
+	assert.True(t, filter.Delete(event.DeleteEvent{Object: serviceExport}))
+}
+
+func TestServiceChanged(t *testing.T) {
+	service := testServiceObj()
+
+	statusUpdate := service.DeepCopy()
+	statusUpdate.Status.LoadBalancer.Ingress = []v1.LoadBalancerIngress{{IP: "10.0.0.1"}}
+	assert.False(t, serviceChanged(event.UpdateEvent{ObjectOld: service, ObjectNew: statusUpdate}))
+
+	portChange := service.DeepCopy()
+	portChange.Spec.Ports[0].Port = test.ServicePort2
+	assert.True(t, serviceChanged(event.UpdateEvent{ObjectOld: service, ObjectNew: portChange}))
+}
+
+func TestEndpointSliceChanged(t *testing.T) {
+	slice := &testEndpointSliceObj().Items[0]
+
+	triggered := slice.DeepCopy()
+	triggered.ResourceVersion = "2"
+	triggered.Annotations = map[string]string{"endpoints.kubernetes.io/last-change-trigger-time": "2021-01-01T00:00:00Z"}
+	assert.False(t, endpointSliceChanged(event.UpdateEvent{ObjectOld: slice, ObjectNew: triggered}),
+		"metadata updates are suppressed")
+
+	notReady := slice.DeepCopy()
+	ready := false
+	notReady.Endpoints[0].Conditions = discovery.EndpointConditions{Ready: &ready}
+	assert.True(t, endpointSliceChanged(event.UpdateEvent{ObjectOld: slice, ObjectNew: notReady}))
+
+	managed := slice.DeepCopy()
+	managed.Labels[discovery.LabelManagedBy] = "other-controller"
+	assert.True(t, endpointSliceChanged(event.UpdateEvent{ObjectOld: slice, ObjectNew: managed}),
+		"a change of manager changes the exported endpoints")
+}
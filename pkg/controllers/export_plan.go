@@ -0,0 +1,100 @@
+package controllers
+
+import (
+	"context"
+	goerrors "errors"
+	cloudmapv1alpha1 "github.com/aws/aws-cloud-map-mcs-controller-for-k8s/pkg/api/cloudmap/v1alpha1"
+	"github.com/aws/aws-cloud-map-mcs-controller-for-k8s/pkg/api/v1alpha1"
+	"github.com/aws/aws-cloud-map-mcs-controller-for-k8s/pkg/model"
+	"github.com/aws/aws-cloud-map-mcs-controller-for-k8s/pkg/tenancy"
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+)
+
+// ExportPlan lists the Cloud Map changes the ServiceExport controller would perform to reconcile a ServiceExport.
+type ExportPlan struct {
+	Namespace         string
+	Name              string
+	CloudMapNamespace string
+	// CreateService is set if the Cloud Map service does not exist yet
+	CreateService bool
+	// Changes are the endpoints to register, update and de-register
+	Changes model.Changes
+	// Withheld is the number of changes the sync policy of the ServiceExport withholds
+	Withheld int
+	// Skipped explains why the ServiceExport is not reconciled with Cloud Map, empty if it is
+	Skipped string
+}
+
+// PlanExport computes the changes reconciling the ServiceExport would perform in Cloud Map, without modifying the
+// cluster or Cloud Map. Instance attributes exceeding the Cloud Map limits are reported with the Recorder.
+func (r *ServiceExportReconciler) PlanExport(ctx context.Context, serviceExport *v1alpha1.ServiceExport) (*ExportPlan, error) {
+	settings, err := ResolveSyncSettings(ctx, r.Client, r.ClusterConfig, serviceExport.Namespace)
+	if err != nil {
+		return nil, err
+	}
+	ctx = settings.WithCredentials(ctx, serviceExport.Namespace)
+	plan := &ExportPlan{
+		Namespace:         serviceExport.Namespace,
+		Name:              serviceExport.Name,
+		CloudMapNamespace: settings.CloudMapNamespace,
+	}
+
+	isDelete := serviceExport.GetDeletionTimestamp() != nil
+	service := &v1.Service{}
+	namespacedName := types.NamespacedName{Namespace: serviceExport.Namespace, Name: serviceExport.Name}
+	if err = r.Client.Get(ctx, namespacedName, service); err != nil {
+		if !errors.IsNotFound(err) {
+			return nil, err
+		}
+		isDelete = true
+	}
+
+	if isDelete && !controllerutil.ContainsFinalizer(serviceExport, ServiceExportFinalizer) {
+		plan.Skipped = "the ServiceExport is deleted"
+		return plan, nil
+	}
+	if err = r.checkTenancy(ctx, serviceExport, settings.CloudMapNamespace); err != nil {
+		if !goerrors.Is(err, tenancy.ErrNotPermitted) {
+			return nil, err
+		}
+		plan.Skipped = err.Error()
+		return plan, nil
+	}
+	if isDelete && settings.CleanupPolicy == cloudmapv1alpha1.CleanupPolicyRetain {
+		plan.Skipped = "the endpoints of the deleted ServiceExport are retained in Cloud Map"
+		return plan, nil
+	}
+
+	cmService, err := r.Registry.GetService(ctx, settings.CloudMapNamespace, serviceExport.Name)
+	if err != nil {
+		return nil, err
+	}
+	current := make([]*model.Endpoint, 0)
+	if cmService != nil {
+		current = serviceExportEndpoints(cmService.Endpoints)
+	}
+
+	if isDelete {
+		plan.Changes = model.Changes{Delete: r.ownedEndpoints(current)}
+		return plan, nil
+	}
+
+	plan.CreateService = cmService == nil
+	desired, err := r.extractEndpoints(ctx, serviceExport, service, settings)
+	if err != nil {
+		return nil, err
+	}
+	// the canary and the migration are not advanced, the plan follows their current status
+	if _, plan.Changes, plan.Withheld, err = r.exportChanges(serviceExport, current, desired); err != nil {
+		return nil, err
+	}
+	return plan, nil
+}
+
+// IsNone returns true if reconciling the ServiceExport changes nothing in Cloud Map.
+func (p *ExportPlan) IsNone() bool {
+	return !p.CreateService && p.Changes.IsNone()
+}
@@ -0,0 +1,122 @@
+package controllers
+
+import (
+	"context"
+	"github.com/aws/aws-cloud-map-mcs-controller-for-k8s/mocks/pkg/cloudmap"
+	"github.com/aws/aws-cloud-map-mcs-controller-for-k8s/pkg/api/v1alpha1"
+	"github.com/aws/aws-cloud-map-mcs-controller-for-k8s/pkg/model"
+	"github.com/aws/aws-cloud-map-mcs-controller-for-k8s/pkg/tenancy"
+	"github.com/aws/aws-cloud-map-mcs-controller-for-k8s/test"
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/assert"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	"testing"
+)
+
+func TestServiceExportReconciler_PlanExport_NewService(t *testing.T) {
+	fakeClient := fake.NewClientBuilder().
+		WithScheme(getServiceExportScheme()).
+		WithObjects(testServiceObj(), testServiceExportObj()).
+		WithLists(testEndpointSliceObj()).
+		Build()
+
+	mockController := gomock.NewController(t)
+	defer mockController.Finish()
+
+	// only reads are expected
+	mock := cloudmap.NewMockServiceDiscoveryClient(mockController)
+	mock.EXPECT().GetService(gomock.Any(), test.NsName, test.SvcName).Return(nil, nil)
+
+	reconciler := getServiceExportReconciler(t, mock, fakeClient)
+	plan, err := reconciler.PlanExport(context.Background(), testServiceExportObj())
+	assert.NoError(t, err)
+	assert.True(t, plan.CreateService)
+	assert.Equal(t, test.NsName, plan.CloudMapNamespace)
+	assert.Equal(t, []*model.Endpoint{test.GetTestEndpoint1()}, plan.Changes.Create)
+	assert.Empty(t, plan.Changes.Delete)
+	assert.False(t, plan.IsNone())
+}
+
+func TestServiceExportReconciler_PlanExport_ExistingService(t *testing.T) {
+	fakeClient := fake.NewClientBuilder().
+		WithScheme(getServiceExportScheme()).
+		WithObjects(testServiceObj(), testServiceExportObj()).
+		WithLists(testEndpointSliceObj()).
+		Build()
+
+	mockController := gomock.NewController(t)
+	defer mockController.Finish()
+
+	mock := cloudmap.NewMockServiceDiscoveryClient(mockController)
+	mock.EXPECT().GetService(gomock.Any(), test.NsName, test.SvcName).Return(test.GetTestService(), nil)
+
+	reconciler := getServiceExportReconciler(t, mock, fakeClient)
+	plan, err := reconciler.PlanExport(context.Background(), testServiceExportObj())
+	assert.NoError(t, err)
+	assert.False(t, plan.CreateService)
+	assert.Empty(t, plan.Changes.Create)
+	assert.Equal(t, []*model.Endpoint{test.GetTestEndpoint2()}, plan.Changes.Delete)
+}
+
+func TestServiceExportReconciler_PlanExport_SyncPolicy(t *testing.T) {
+	serviceExport := testServiceExportObj()
+	serviceExport.Spec.SyncPolicy = v1alpha1.SyncPolicyUpsertOnly
+	fakeClient := fake.NewClientBuilder().
+		WithScheme(getServiceExportScheme()).
+		WithObjects(testServiceObj(), serviceExport).
+		WithLists(testEndpointSliceObj()).
+		Build()
+
+	mockController := gomock.NewController(t)
+	defer mockController.Finish()
+
+	mock := cloudmap.NewMockServiceDiscoveryClient(mockController)
+	mock.EXPECT().GetService(gomock.Any(), test.NsName, test.SvcName).Return(test.GetTestService(), nil)
+
+	// the plan withholds the de-registrations like the export
+	reconciler := getServiceExportReconciler(t, mock, fakeClient)
+	plan, err := reconciler.PlanExport(context.Background(), serviceExport)
+	assert.NoError(t, err)
+	assert.Empty(t, plan.Changes.Delete)
+	assert.Equal(t, 1, plan.Withheld)
+}
+
+func TestServiceExportReconciler_PlanExport_DeletedService(t *testing.T) {
+	serviceExport := testServiceExportObj()
+	serviceExport.Finalizers = []string{ServiceExportFinalizer}
+	fakeClient := fake.NewClientBuilder().
+		WithScheme(getServiceExportScheme()).
+		WithObjects(serviceExport).
+		Build()
+
+	mockController := gomock.NewController(t)
+	defer mockController.Finish()
+
+	mock := cloudmap.NewMockServiceDiscoveryClient(mockController)
+	mock.EXPECT().GetService(gomock.Any(), test.NsName, test.SvcName).Return(test.GetTestService(), nil)
+
+	reconciler := getServiceExportReconciler(t, mock, fakeClient)
+	plan, err := reconciler.PlanExport(context.Background(), serviceExport)
+	assert.NoError(t, err)
+	assert.ElementsMatch(t, []*model.Endpoint{test.GetTestEndpoint1(), test.GetTestEndpoint2()}, plan.Changes.Delete)
+}
+
+func TestServiceExportReconciler_PlanExport_TenancyDenied(t *testing.T) {
+	fakeClient := fake.NewClientBuilder().
+		WithScheme(getServiceExportScheme()).
+		WithObjects(testNamespace(), testServiceObj(), testServiceExportObj()).
+		Build()
+
+	mockController := gomock.NewController(t)
+	defer mockController.Finish()
+
+	// no Cloud Map calls are expected
+	mock := cloudmap.NewMockServiceDiscoveryClient(mockController)
+
+	reconciler := getServiceExportReconciler(t, mock, fakeClient)
+	reconciler.TenancyPolicy = &tenancy.Policy{DenyUnlisted: true}
+	plan, err := reconciler.PlanExport(context.Background(), testServiceExportObj())
+	assert.NoError(t, err)
+	assert.NotEmpty(t, plan.Skipped)
+	assert.True(t, plan.IsNone())
+}
@@ -0,0 +1,108 @@
+package controllers
+
+import (
+	"github.com/aws/aws-cloud-map-mcs-controller-for-k8s/pkg/model"
+	"k8s.io/apimachinery/pkg/types"
+	"sort"
+	"sync"
+	"time"
+)
+
+// ExportState is the outcome of the last export of a service, comparing the endpoints desired from the cluster to
+// the endpoints in Cloud Map.
+type ExportState struct {
+	Namespace         string `json:"namespace"`
+	Name              string `json:"name"`
+	CloudMapNamespace string `json:"cloudMapNamespace"`
+	DesiredEndpoints  int    `json:"desiredEndpoints"`
+	CurrentEndpoints  int    `json:"currentEndpoints"`
+	// Create, Update and Delete are the changes computed by the last export
+	Create     []*model.Endpoint `json:"create"`
+	Update     []*model.Endpoint `json:"update"`
+	Delete     []*model.Endpoint `json:"delete"`
+	ExportedAt time.Time         `json:"exportedAt"`
+	// Error is the error applying the changes, empty if they were applied
+	Error string `json:"error,omitempty"`
+}
+
+// ExportStates records the last ExportState of each exported service. It is safe for concurrent use, and a nil
+// ExportStates discards all calls.
+type ExportStates struct {
+	mu     sync.RWMutex
+	states map[types.NamespacedName]ExportState
+	now    func() time.Time
+}
+
+// NewExportStates creates an empty export state record.
+func NewExportStates() *ExportStates {
+	return &ExportStates{
+		states: make(map[types.NamespacedName]ExportState),
+		now:    time.Now,
+	}
+}
+
+// Record replaces the state of the service, setting the export time.
+func (s *ExportStates) Record(state ExportState) {
+	if s == nil {
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	state.ExportedAt = s.now()
+	s.states[types.NamespacedName{Namespace: state.Namespace, Name: state.Name}] = state
+}
+
+// Forget removes the state of a service which is no longer exported.
+func (s *ExportStates) Forget(name types.NamespacedName) {
+	if s == nil {
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	delete(s.states, name)
+}
+
+// List returns the states of all exported services, sorted by namespace and name.
+func (s *ExportStates) List() []ExportState {
+	states := make([]ExportState, 0)
+	if s == nil {
+		return states
+	}
+
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	for _, state := range s.states {
+		states = append(states, state)
+	}
+	sort.Slice(states, func(i, j int) bool {
+		if states[i].Namespace != states[j].Namespace {
+			return states[i].Namespace < states[j].Namespace
+		}
+		return states[i].Name < states[j].Name
+	})
+	return states
+}
+
+func newExportState(service types.NamespacedName, cmNamespace string, plan model.Plan, changes model.Changes) ExportState {
+	return ExportState{
+		Namespace:         service.Namespace,
+		Name:              service.Name,
+		CloudMapNamespace: cmNamespace,
+		DesiredEndpoints:  len(plan.Desired),
+		CurrentEndpoints:  len(plan.Current),
+		Create:            changes.Create,
+		Update:            changes.Update,
+		Delete:            changes.Delete,
+	}
+}
+
+// failed returns the state recording the error applying the changes.
+func (s ExportState) failed(err error) ExportState {
+	s.Error = err.Error()
+	return s
+}
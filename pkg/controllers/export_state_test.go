@@ -0,0 +1,46 @@
+package controllers
+
+import (
+	"errors"
+	"github.com/aws/aws-cloud-map-mcs-controller-for-k8s/pkg/model"
+	"github.com/aws/aws-cloud-map-mcs-controller-for-k8s/test"
+	"github.com/stretchr/testify/assert"
+	"k8s.io/apimachinery/pkg/types"
+	"testing"
+	"time"
+)
+
+func TestExportStates(t *testing.T) {
+	exportedAt := time.Date(2021, 9, 1, 0, 0, 0, 0, time.UTC)
+	states := NewExportStates()
+	states.now = func() time.Time { return exportedAt }
+
+	plan := model.Plan{
+		Current: []*model.Endpoint{test.GetTestEndpoint2()},
+		Desired: []*model.Endpoint{test.GetTestEndpoint1()},
+	}
+	changes := plan.CalculateChanges()
+	name := types.NamespacedName{Namespace: test.NsName, Name: test.SvcName}
+	states.Record(newExportState(name, test.NsName, plan, changes).failed(errors.New("throttled")))
+	states.Record(newExportState(types.NamespacedName{Namespace: test.NsName, Name: "a"}, test.NsName,
+		model.Plan{}, model.Changes{}))
+
+	list := states.List()
+	if assert.Len(t, list, 2) {
+		assert.Equal(t, "a", list[0].Name)
+		assert.Equal(t, ExportState{
+			Namespace:         test.NsName,
+			Name:              test.SvcName,
+			CloudMapNamespace: test.NsName,
+			DesiredEndpoints:  1,
+			CurrentEndpoints:  1,
+			Create:            []*model.Endpoint{test.GetTestEndpoint1()},
+			Delete:            []*model.Endpoint{test.GetTestEndpoint2()},
+			ExportedAt:        exportedAt,
+			Error:             "throttled",
+		}, list[1])
+	}
+
+	states.Forget(name)
+	assert.Len(t, states.List(), 1)
+}
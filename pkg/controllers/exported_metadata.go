@@ -0,0 +1,203 @@
+package controllers
+
+import (
+	"encoding/json"
+	"github.com/aws/aws-cloud-map-mcs-controller-for-k8s/pkg/api/v1alpha1"
+	"github.com/aws/aws-cloud-map-mcs-controller-for-k8s/pkg/model"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sort"
+	"strings"
+	"time"
+)
+
+const (
+	// ExportedLabelsAttr is the Cloud Map instance attribute carrying the JSON encoded exported labels of a ServiceExport
+	ExportedLabelsAttr = "EXPORTED_LABELS"
+	// ExportedAnnotationsAttr is the Cloud Map instance attribute carrying the JSON encoded exported annotations
+	ExportedAnnotationsAttr = "EXPORTED_ANNOTATIONS"
+	// ExportCreationTimestampAttr is the Cloud Map instance attribute carrying the creation time of the ServiceExport,
+	// used to resolve conflicting exported metadata in favour of the oldest export
+	ExportCreationTimestampAttr = "EXPORT_CREATION_TIMESTAMP"
+
+	// ExportedLabelKeysAnnotation lists the ServiceImport labels applied from exported labels
+	ExportedLabelKeysAnnotation = "multicluster.k8s.aws/exported-label-keys"
+	// ExportedAnnotationKeysAnnotation lists the ServiceImport annotations applied from exported annotations
+	ExportedAnnotationKeysAnnotation = "multicluster.k8s.aws/exported-annotation-keys"
+)
+
+// exportedMetadata holds the labels and annotations exported for a service across all exporting clusters.
+type exportedMetadata struct {
+	Labels      map[string]string
+	Annotations map[string]string
+	// Conflicts lists the label and annotation keys exporting clusters disagree on
+	Conflicts []string
+}
+
+// exportedMetadataAttributes returns the Cloud Map instance attributes encoding the exported metadata of a
+// ServiceExport, or no attributes if it doesn't export any metadata.
+func exportedMetadataAttributes(serviceExport *v1alpha1.ServiceExport) (map[string]string, error) {
+	attrs := make(map[string]string)
+	spec := serviceExport.Spec
+	if len(spec.ExportedLabels) == 0 && len(spec.ExportedAnnotations) == 0 {
+		return attrs, nil
+	}
+
+	if len(spec.ExportedLabels) > 0 {
+		labels, err := json.Marshal(spec.ExportedLabels)
+		if err != nil {
+			return nil, err
+		}
+		attrs[ExportedLabelsAttr] = string(labels)
+	}
+
+	if len(spec.ExportedAnnotations) > 0 {
+		annotations, err := json.Marshal(spec.ExportedAnnotations)
+		if err != nil {
+			return nil, err
+		}
+		attrs[ExportedAnnotationsAttr] = string(annotations)
+	}
+
+	attrs[ExportCreationTimestampAttr] = serviceExport.CreationTimestamp.UTC().Format(time.RFC3339)
+
+	return attrs, nil
+}
+
+type export struct {
+	created     string
+	labels      map[string]string
+	annotations map[string]string
+	key         string
+}
+
+// mergeExportedMetadata merges the metadata exported by all clusters from the attributes of the service endpoints.
+// Conflicting values are resolved in favour of the oldest export.
+func mergeExportedMetadata(endpoints []*model.Endpoint) exportedMetadata {
+	exports := make(map[string]export)
+	for _, endpt := range endpoints {
+		labelsAttr, hasLabels := endpt.Attributes[ExportedLabelsAttr]
+		annotationsAttr, hasAnnotations := endpt.Attributes[ExportedAnnotationsAttr]
+		if !hasLabels && !hasAnnotations {
+			continue
+		}
+
+		created := endpt.Attributes[ExportCreationTimestampAttr]
+		key := strings.Join([]string{created, labelsAttr, annotationsAttr}, "\n")
+		if _, found := exports[key]; found {
+			continue
+		}
+
+		exp := export{created: created, key: key}
+		// malformed attributes are ignored, they can only be written by a faulty exporter
+		_ = json.Unmarshal([]byte(labelsAttr), &exp.labels)
+		_ = json.Unmarshal([]byte(annotationsAttr), &exp.annotations)
+		exports[key] = exp
+	}
+
+	sorted := make([]export, 0, len(exports))
+	for _, exp := range exports {
+		sorted = append(sorted, exp)
+	}
+	sort.Slice(sorted, func(i, j int) bool {
+		if sorted[i].created != sorted[j].created {
+			return sorted[i].created < sorted[j].created
+		}
+		return sorted[i].key < sorted[j].key
+	})
+
+	merged := exportedMetadata{
+		Labels:      make(map[string]string),
+		Annotations: make(map[string]string),
+	}
+	conflicts := make(map[string]bool)
+	for _, exp := range sorted {
+		mergeInto(merged.Labels, exp.labels, "label/", conflicts)
+		mergeInto(merged.Annotations, exp.annotations, "annotation/", conflicts)
+	}
+
+	for conflict := range conflicts {
+		merged.Conflicts = append(merged.Conflicts, conflict)
+	}
+	sort.Strings(merged.Conflicts)
+
+	return merged
+}
+
+// exportedMetadataConflicts returns the exported label and annotation keys the local export conflicts on with other
+// exporting clusters, given the endpoints currently registered in Cloud Map and the desired local endpoints.
+func exportedMetadataConflicts(current []*model.Endpoint, desired []*model.Endpoint) []string {
+	desiredIds := make(map[string]bool)
+	for _, endpt := range desired {
+		desiredIds[endpt.Id] = true
+	}
+
+	endpoints := append([]*model.Endpoint{}, desired...)
+	for _, endpt := range current {
+		if !desiredIds[endpt.Id] {
+			endpoints = append(endpoints, endpt)
+		}
+	}
+
+	return mergeExportedMetadata(endpoints).Conflicts
+}
+
+func mergeInto(merged map[string]string, values map[string]string, conflictPrefix string, conflicts map[string]bool) {
+	for key, value := range values {
+		existing, found := merged[key]
+		if !found {
+			merged[key] = value
+		} else if existing != value {
+			conflicts[conflictPrefix+key] = true
+		}
+	}
+}
+
+// applyExportedMetadata applies the exported labels and annotations to the object metadata, removing previously
+// exported keys which are no longer exported. Returns true if the metadata changed.
+func applyExportedMetadata(meta *metav1.ObjectMeta, exported exportedMetadata) bool {
+	if meta.Annotations == nil {
+		meta.Annotations = make(map[string]string)
+	}
+	if meta.Labels == nil {
+		meta.Labels = make(map[string]string)
+	}
+
+	labelsChanged := applyExportedKeys(meta.Labels, exported.Labels, meta.Annotations, ExportedLabelKeysAnnotation)
+	annotationsChanged := applyExportedKeys(meta.Annotations, exported.Annotations, meta.Annotations, ExportedAnnotationKeysAnnotation)
+
+	return labelsChanged || annotationsChanged
+}
+
+func applyExportedKeys(target map[string]string, exported map[string]string, annotations map[string]string, keysAnnotation string) (changed bool) {
+	for _, key := range strings.Split(annotations[keysAnnotation], ",") {
+		if _, stillExported := exported[key]; stillExported {
+			continue
+		}
+		if _, found := target[key]; found {
+			delete(target, key)
+			changed = true
+		}
+	}
+
+	keys := make([]string, 0, len(exported))
+	for key, value := range exported {
+		if current, found := target[key]; !found || current != value {
+			target[key] = value
+			changed = true
+		}
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	if len(keys) == 0 {
+		if _, found := annotations[keysAnnotation]; found {
+			delete(annotations, keysAnnotation)
+			changed = true
+		}
+	} else if joined := strings.Join(keys, ","); annotations[keysAnnotation] != joined {
+		annotations[keysAnnotation] = joined
+		changed = true
+	}
+
+	return changed
+}
@@ -0,0 +1,96 @@
+package controllers
+
+import (
+	"github.com/aws/aws-cloud-map-mcs-controller-for-k8s/pkg/api/v1alpha1"
+	"github.com/aws/aws-cloud-map-mcs-controller-for-k8s/pkg/model"
+	"github.com/stretchr/testify/assert"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"testing"
+	"time"
+)
+
+func TestExportedMetadataAttributes(t *testing.T) {
+	serviceExport := &v1alpha1.ServiceExport{
+		ObjectMeta: metav1.ObjectMeta{CreationTimestamp: metav1.NewTime(time.Unix(100, 0))},
+		Spec: v1alpha1.ServiceExportSpec{
+			ExportedLabels: map[string]string{"team": "a"},
+		},
+	}
+
+	attrs, err := exportedMetadataAttributes(serviceExport)
+	assert.NoError(t, err)
+	assert.Equal(t, map[string]string{
+		ExportedLabelsAttr:          `{"team":"a"}`,
+		ExportCreationTimestampAttr: "1970-01-01T00:01:40Z",
+	}, attrs)
+
+	attrs, err = exportedMetadataAttributes(&v1alpha1.ServiceExport{})
+	assert.NoError(t, err)
+	assert.Empty(t, attrs, "no attributes without exported metadata")
+}
+
+func TestMergeExportedMetadata_OldestExportWins(t *testing.T) {
+	endpoints := []*model.Endpoint{
+		exportedEndpoint("1", "2021-01-02T00:00:00Z", `{"team":"b","tier":"web"}`, ""),
+		exportedEndpoint("2", "2021-01-01T00:00:00Z", `{"team":"a"}`, `{"example.com/owner":"a"}`),
+		exportedEndpoint("3", "2021-01-01T00:00:00Z", `{"team":"a"}`, `{"example.com/owner":"a"}`),
+		{Id: "4", Attributes: map[string]string{}},
+	}
+
+	merged := mergeExportedMetadata(endpoints)
+
+	assert.Equal(t, map[string]string{"team": "a", "tier": "web"}, merged.Labels)
+	assert.Equal(t, map[string]string{"example.com/owner": "a"}, merged.Annotations)
+	assert.Equal(t, []string{"label/team"}, merged.Conflicts)
+}
+
+func TestExportedMetadataConflicts_IgnoresReplacedLocalEndpoints(t *testing.T) {
+	current := []*model.Endpoint{exportedEndpoint("1", "2021-01-01T00:00:00Z", `{"team":"a"}`, "")}
+	desired := []*model.Endpoint{exportedEndpoint("1", "2021-01-01T00:00:00Z", `{"team":"b"}`, "")}
+
+	assert.Empty(t, exportedMetadataConflicts(current, desired))
+
+	other := exportedEndpoint("2", "2021-01-02T00:00:00Z", `{"team":"c"}`, "")
+	assert.Equal(t, []string{"label/team"}, exportedMetadataConflicts(append(current, other), desired))
+}
+
+func TestApplyExportedMetadata(t *testing.T) {
+	meta := &metav1.ObjectMeta{
+		Labels: map[string]string{"team": "a", "stale": "x", "local": "y"},
+		Annotations: map[string]string{
+			DerivedServiceAnnotation:    "imported-svc",
+			ExportedLabelKeysAnnotation: "stale,team",
+		},
+	}
+
+	changed := applyExportedMetadata(meta, exportedMetadata{
+		Labels:      map[string]string{"team": "b"},
+		Annotations: map[string]string{"example.com/owner": "a"},
+	})
+
+	assert.True(t, changed)
+	assert.Equal(t, map[string]string{"team": "b", "local": "y"}, meta.Labels)
+	assert.Equal(t, map[string]string{
+		DerivedServiceAnnotation:         "imported-svc",
+		ExportedLabelKeysAnnotation:      "team",
+		ExportedAnnotationKeysAnnotation: "example.com/owner",
+		"example.com/owner":              "a",
+	}, meta.Annotations)
+
+	changed = applyExportedMetadata(meta, exportedMetadata{
+		Labels:      map[string]string{"team": "b"},
+		Annotations: map[string]string{"example.com/owner": "a"},
+	})
+	assert.False(t, changed, "no change when already applied")
+}
+
+func exportedEndpoint(id string, created string, labels string, annotations string) *model.Endpoint {
+	attrs := map[string]string{ExportCreationTimestampAttr: created}
+	if labels != "" {
+		attrs[ExportedLabelsAttr] = labels
+	}
+	if annotations != "" {
+		attrs[ExportedAnnotationsAttr] = annotations
+	}
+	return &model.Endpoint{Id: id, Attributes: attrs}
+}
@@ -0,0 +1,176 @@
+package controllers
+
+import (
+	"context"
+	"fmt"
+	"github.com/aws/aws-cloud-map-mcs-controller-for-k8s/pkg/api/v1alpha1"
+	"github.com/aws/aws-cloud-map-mcs-controller-for-k8s/pkg/model"
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"net"
+	"sort"
+	"strings"
+)
+
+const (
+	// ExternalDnsHostnameAnnotation is the annotation external-dns publishes the IP of a Service under
+	ExternalDnsHostnameAnnotation = "external-dns.alpha.kubernetes.io/hostname"
+	// ExternalDnsTTLAnnotation is the annotation setting the TTL of the records external-dns publishes for a Service
+	ExternalDnsTTLAnnotation = "external-dns.alpha.kubernetes.io/ttl"
+)
+
+// DNSEndpointGVK is the kind of the external-dns custom resource listing DNS records to publish.
+var DNSEndpointGVK = schema.GroupVersionKind{Group: "externaldns.k8s.io", Version: "v1alpha1", Kind: "DNSEndpoint"}
+
+// ExternalDnsMode selects how imported services are published through external-dns.
+type ExternalDnsMode string
+
+const (
+	// ExternalDnsDisabled doesn't publish imported services
+	ExternalDnsDisabled ExternalDnsMode = ""
+	// ExternalDnsEndpoint creates a DNSEndpoint per ServiceImport, with A and AAAA records of the endpoint IPs of all
+	// clusters. external-dns must run with the crd source.
+	ExternalDnsEndpoint ExternalDnsMode = "dnsendpoint"
+	// ExternalDnsAnnotation annotates the derived Service of each ServiceImport with its hostname, publishing the
+	// clusterset IP. external-dns must run with the service source and --publish-internal-services.
+	ExternalDnsAnnotation ExternalDnsMode = "annotation"
+)
+
+// ExternalDnsConfig configures publishing imported services into the DNS zones managed by external-dns, under the
+// hostname "<service>.<namespace>.<domain>".
+type ExternalDnsConfig struct {
+	Mode   ExternalDnsMode
+	Domain string
+	// TTL is the TTL of the published records in seconds, the external-dns default is used if 0
+	TTL int64
+}
+
+// Validate returns an error if the mode is unknown, or no domain is set for an enabled mode.
+func (c *ExternalDnsConfig) Validate() error {
+	switch c.Mode {
+	case ExternalDnsDisabled:
+		return nil
+	case ExternalDnsEndpoint, ExternalDnsAnnotation:
+	default:
+		return fmt.Errorf("unknown external-dns mode %q, expected one of %s, %s", c.Mode, ExternalDnsEndpoint,
+			ExternalDnsAnnotation)
+	}
+	if strings.Trim(c.Domain, ".") == "" {
+		return fmt.Errorf("the %s external-dns mode requires a domain", c.Mode)
+	}
+	return nil
+}
+
+// Hostname returns the hostname an imported service is published under.
+func (c *ExternalDnsConfig) Hostname(namespace string, name string) string {
+	return name + "." + namespace + "." + strings.Trim(c.Domain, ".")
+}
+
+// +kubebuilder:rbac:groups=externaldns.k8s.io,resources=dnsendpoints,verbs=create;get;update
+
+// updateExternalDns publishes the imported service according to the external-dns mode. The DNSEndpoint and the
+// annotation are removed with the ServiceImport and its derived Service, which own them.
+func (r *CloudMapReconciler) updateExternalDns(ctx context.Context, svcImport *v1alpha1.ServiceImport, svc *v1.Service, endpoints []*model.Endpoint) error {
+	if r.ExternalDns == nil {
+		return nil
+	}
+
+	switch r.ExternalDns.Mode {
+	case ExternalDnsEndpoint:
+		return r.updateDNSEndpoint(ctx, svcImport, endpoints)
+	case ExternalDnsAnnotation:
+		return r.updateExternalDnsAnnotations(ctx, svcImport, svc)
+	}
+	return nil
+}
+
+func (r *CloudMapReconciler) updateDNSEndpoint(ctx context.Context, svcImport *v1alpha1.ServiceImport, endpoints []*model.Endpoint) error {
+	return r.applyImportResource(ctx, createDNSEndpointStruct(svcImport, r.ExternalDns, endpoints),
+		"hostname", r.ExternalDns.Hostname(svcImport.Namespace, svcImport.Name))
+}
+
+func (r *CloudMapReconciler) updateExternalDnsAnnotations(ctx context.Context, svcImport *v1alpha1.ServiceImport, svc *v1.Service) error {
+	desired := map[string]string{ExternalDnsHostnameAnnotation: r.ExternalDns.Hostname(svcImport.Namespace, svcImport.Name)}
+	if r.ExternalDns.TTL > 0 {
+		desired[ExternalDnsTTLAnnotation] = fmt.Sprint(r.ExternalDns.TTL)
+	}
+
+	changed := false
+	for key, value := range desired {
+		if svc.Annotations[key] != value {
+			if svc.Annotations == nil {
+				svc.Annotations = make(map[string]string)
+			}
+			svc.Annotations[key] = value
+			changed = true
+		}
+	}
+	if !changed {
+		return nil
+	}
+	if err := r.Client.Update(ctx, svc); err != nil {
+		return err
+	}
+	r.Log.Info("annotated derived Service for external-dns", "namespace", svc.Namespace, "name", svc.Name,
+		"hostname", desired[ExternalDnsHostnameAnnotation])
+	return nil
+}
+
+// createDNSEndpointStruct returns the DNSEndpoint of the imported service, owned by the ServiceImport. The fields of
+// the spec have the types an unstructured object read from the API server has, so specs compare equal.
+func createDNSEndpointStruct(svcImport *v1alpha1.ServiceImport, config *ExternalDnsConfig, endpoints []*model.Endpoint) *unstructured.Unstructured {
+	ipv4, ipv6 := make(map[string]bool), make(map[string]bool)
+	for _, endpoint := range endpoints {
+		ip := net.ParseIP(endpoint.IP)
+		if ip == nil {
+			continue
+		}
+		if ip.To4() != nil {
+			ipv4[endpoint.IP] = true
+		} else {
+			ipv6[endpoint.IP] = true
+		}
+	}
+
+	hostname := config.Hostname(svcImport.Namespace, svcImport.Name)
+	records := make([]interface{}, 0, 2)
+	for _, record := range []struct {
+		recordType string
+		ips        map[string]bool
+	}{{"A", ipv4}, {"AAAA", ipv6}} {
+		if len(record.ips) == 0 {
+			continue
+		}
+		targets := make([]string, 0, len(record.ips))
+		for ip := range record.ips {
+			targets = append(targets, ip)
+		}
+		sort.Strings(targets)
+		values := make([]interface{}, 0, len(targets))
+		for _, target := range targets {
+			values = append(values, target)
+		}
+
+		dnsRecord := map[string]interface{}{
+			"dnsName":    hostname,
+			"recordType": record.recordType,
+			"targets":    values,
+		}
+		if config.TTL > 0 {
+			dnsRecord["recordTTL"] = config.TTL
+		}
+		records = append(records, dnsRecord)
+	}
+
+	dnsEndpoint := &unstructured.Unstructured{Object: map[string]interface{}{
+		"spec": map[string]interface{}{"endpoints": records},
+	}}
+	dnsEndpoint.SetGroupVersionKind(DNSEndpointGVK)
+	dnsEndpoint.SetNamespace(svcImport.Namespace)
+	dnsEndpoint.SetName(svcImport.Name)
+	dnsEndpoint.SetOwnerReferences([]metav1.OwnerReference{*metav1.NewControllerRef(svcImport,
+		v1alpha1.GroupVersion.WithKind("ServiceImport"))})
+	return dnsEndpoint
+}
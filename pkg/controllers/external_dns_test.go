@@ -0,0 +1,86 @@
+package controllers
+
+import (
+	"context"
+	"github.com/aws/aws-cloud-map-mcs-controller-for-k8s/mocks/pkg/cloudmap"
+	cloudmapv1alpha1 "github.com/aws/aws-cloud-map-mcs-controller-for-k8s/pkg/api/cloudmap/v1alpha1"
+	"github.com/aws/aws-cloud-map-mcs-controller-for-k8s/pkg/api/v1alpha1"
+	"github.com/aws/aws-cloud-map-mcs-controller-for-k8s/pkg/model"
+	"github.com/aws/aws-cloud-map-mcs-controller-for-k8s/test"
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/assert"
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/kubernetes/scheme"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	"testing"
+)
+
+func TestExternalDnsConfig_Validate(t *testing.T) {
+	assert.NoError(t, (&ExternalDnsConfig{}).Validate())
+	assert.NoError(t, (&ExternalDnsConfig{Mode: ExternalDnsEndpoint, Domain: "clusterset.example"}).Validate())
+	assert.NoError(t, (&ExternalDnsConfig{Mode: ExternalDnsAnnotation, Domain: "clusterset.example."}).Validate())
+	assert.Error(t, (&ExternalDnsConfig{Mode: ExternalDnsEndpoint}).Validate(), "a domain is required")
+	assert.Error(t, (&ExternalDnsConfig{Mode: "ingress", Domain: "clusterset.example"}).Validate())
+}
+
+func TestCreateDNSEndpointStruct(t *testing.T) {
+	svcImport := createServiceImportStruct(test.NsName, test.SvcName)
+	ipv6 := test.GetTestEndpoint2()
+	ipv6.IP = "fd00::1"
+	endpoints := []*model.Endpoint{test.GetTestEndpoint1(), test.GetTestEndpoint1(), ipv6}
+
+	dnsEndpoint := createDNSEndpointStruct(svcImport, &ExternalDnsConfig{Domain: "clusterset.example.", TTL: 30}, endpoints)
+	assert.Equal(t, DNSEndpointGVK, dnsEndpoint.GroupVersionKind())
+	assert.Equal(t, test.SvcName, dnsEndpoint.GetName())
+	assert.Equal(t, "ServiceImport", dnsEndpoint.GetOwnerReferences()[0].Kind)
+	hostname := test.SvcName + "." + test.NsName + ".clusterset.example"
+	assert.Equal(t, []interface{}{
+		map[string]interface{}{"dnsName": hostname, "recordType": "A", "recordTTL": int64(30),
+			"targets": []interface{}{test.EndptIp1}},
+		map[string]interface{}{"dnsName": hostname, "recordType": "AAAA", "recordTTL": int64(30),
+			"targets": []interface{}{"fd00::1"}},
+	}, dnsEndpoint.Object["spec"].(map[string]interface{})["endpoints"])
+}
+
+func TestCloudMapReconciler_Reconcile_ExternalDns(t *testing.T) {
+	s := scheme.Scheme
+	s.AddKnownTypes(v1alpha1.GroupVersion, &v1alpha1.ServiceImportList{}, &v1alpha1.ServiceImport{})
+	s.AddKnownTypes(cloudmapv1alpha1.GroupVersion, &cloudmapv1alpha1.CloudMapSyncConfig{})
+
+	for _, mode := range []ExternalDnsMode{ExternalDnsEndpoint, ExternalDnsAnnotation} {
+		t.Run(string(mode), func(t *testing.T) {
+			fakeClient := fake.NewClientBuilder().WithRuntimeObjects([]runtime.Object{testNamespace()}...).Build()
+
+			mockController := gomock.NewController(t)
+			defer mockController.Finish()
+
+			mockSDClient := cloudmap.NewMockServiceDiscoveryClient(mockController)
+			mockSDClient.EXPECT().ListServices(context.TODO(), test.NsName).
+				Return([]*model.Service{test.GetTestServiceWithEndpoint([]*model.Endpoint{test.GetTestEndpoint1()})}, nil)
+
+			reconciler := getReconciler(t, mockSDClient, fakeClient)
+			reconciler.ExternalDns = &ExternalDnsConfig{Mode: mode, Domain: "clusterset.example"}
+			assert.NoError(t, reconciler.Reconcile(context.TODO()))
+
+			hostname := test.SvcName + "." + test.NsName + ".clusterset.example"
+			switch mode {
+			case ExternalDnsEndpoint:
+				dnsEndpoint := &unstructured.Unstructured{}
+				dnsEndpoint.SetGroupVersionKind(DNSEndpointGVK)
+				assert.NoError(t, fakeClient.Get(context.TODO(),
+					types.NamespacedName{Namespace: test.NsName, Name: test.SvcName}, dnsEndpoint))
+				records, _, _ := unstructured.NestedSlice(dnsEndpoint.Object, "spec", "endpoints")
+				assert.Len(t, records, 1)
+				assert.Equal(t, hostname, records[0].(map[string]interface{})["dnsName"])
+			case ExternalDnsAnnotation:
+				derived := &v1.Service{}
+				assert.NoError(t, fakeClient.Get(context.TODO(),
+					types.NamespacedName{Namespace: test.NsName, Name: DerivedName(test.NsName, test.SvcName)}, derived))
+				assert.Equal(t, hostname, derived.Annotations[ExternalDnsHostnameAnnotation])
+			}
+		})
+	}
+}
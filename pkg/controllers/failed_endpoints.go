@@ -0,0 +1,74 @@
+package controllers
+
+import (
+	goerrors "errors"
+	"github.com/aws/aws-cloud-map-mcs-controller-for-k8s/pkg/api/v1alpha1"
+	"github.com/aws/aws-cloud-map-mcs-controller-for-k8s/pkg/cloudmap"
+	"github.com/aws/aws-cloud-map-mcs-controller-for-k8s/pkg/model"
+	"sort"
+	"strings"
+)
+
+// maxFailedEndpoints bounds the failed endpoints listed in the status of a ServiceExport, their count is always
+// reported
+const maxFailedEndpoints = 10
+
+// failedEndpoints returns the endpoints which failed to be registered with the error, with the reason of each
+// failure, for attribute limits exceeded, operations which could not be created, e.g. when throttled, and operations
+// which reached FAIL status.
+func failedEndpoints(endpoints []*model.Endpoint, err error) []v1alpha1.FailedEndpoint {
+	failed := make([]v1alpha1.FailedEndpoint, 0)
+	var limitErr *attributeLimitError
+	var instanceErr *cloudmap.InstanceFailureError
+	var opErr *cloudmap.OperationFailureError
+	switch {
+	case goerrors.As(err, &limitErr):
+		failed = append(failed, v1alpha1.FailedEndpoint{
+			InstanceId: limitErr.endpointId,
+			Reason:     AttributeLimitExceededReason,
+			Message:    strings.Join(limitErr.violations, ", "),
+		})
+	case goerrors.As(err, &instanceErr):
+		for _, failure := range instanceErr.Failures {
+			failed = append(failed, v1alpha1.FailedEndpoint{
+				InstanceId: failure.InstanceId,
+				Reason:     cloudmap.ErrorCode(failure.Err),
+				Message:    failure.Err.Error(),
+			})
+		}
+	case goerrors.As(err, &opErr):
+		for _, failure := range opErr.Failures {
+			failed = append(failed, v1alpha1.FailedEndpoint{
+				InstanceId: failure.InstanceId,
+				Reason:     failure.ErrorCode,
+				Message:    failure.ErrorMessage,
+			})
+		}
+	}
+
+	ips := make(map[string]string, len(endpoints))
+	for _, endpoint := range endpoints {
+		ips[endpoint.Id] = endpoint.IP
+	}
+	for i := range failed {
+		failed[i].IP = ips[failed[i].InstanceId]
+	}
+	sort.SliceStable(failed, func(i, j int) bool {
+		return failed[i].InstanceId < failed[j].InstanceId
+	})
+	return failed
+}
+
+// setFailedEndpoints records the endpoints which failed to be registered with the error, nil once the registration
+// succeeded, in the status of the ServiceExport: their count, and at most maxFailedEndpoints of them.
+func setFailedEndpoints(serviceExport *v1alpha1.ServiceExport, endpoints []*model.Endpoint, err error) {
+	failed := failedEndpoints(endpoints, err)
+	serviceExport.Status.FailedEndpointCount = int32(len(failed))
+	if len(failed) > maxFailedEndpoints {
+		failed = failed[:maxFailedEndpoints]
+	}
+	if len(failed) == 0 {
+		failed = nil
+	}
+	serviceExport.Status.FailedEndpoints = failed
+}
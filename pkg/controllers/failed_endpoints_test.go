@@ -0,0 +1,79 @@
+package controllers
+
+import (
+	"errors"
+	"fmt"
+	"github.com/aws/aws-cloud-map-mcs-controller-for-k8s/pkg/api/v1alpha1"
+	"github.com/aws/aws-cloud-map-mcs-controller-for-k8s/pkg/cloudmap"
+	"github.com/aws/aws-cloud-map-mcs-controller-for-k8s/pkg/model"
+	"github.com/aws/aws-cloud-map-mcs-controller-for-k8s/test"
+	sdtypes "github.com/aws/aws-sdk-go-v2/service/servicediscovery/types"
+	"github.com/aws/smithy-go"
+	"github.com/stretchr/testify/assert"
+	"strconv"
+	"testing"
+)
+
+func TestFailedEndpoints(t *testing.T) {
+	endpoints := []*model.Endpoint{test.GetTestEndpoint1(), test.GetTestEndpoint2()}
+	throttled := &smithy.GenericAPIError{Code: "ThrottlingException", Message: "Rate exceeded"}
+	tests := []struct {
+		name string
+		err  error
+		want []v1alpha1.FailedEndpoint
+	}{
+		{name: "no error", want: []v1alpha1.FailedEndpoint{}},
+		{name: "other error", err: errors.New("connection reset"), want: []v1alpha1.FailedEndpoint{}},
+		{
+			name: "attribute limit",
+			err:  &attributeLimitError{endpointId: test.EndptId1, violations: []string{"invalid attribute key \"a b\""}},
+			want: []v1alpha1.FailedEndpoint{{InstanceId: test.EndptId1, IP: test.EndptIp1,
+				Reason: AttributeLimitExceededReason, Message: "invalid attribute key \"a b\""}},
+		},
+		{
+			name: "throttled",
+			err: fmt.Errorf("RegisterInstance completed for 1 of 2 endpoints: %w", &cloudmap.InstanceFailureError{
+				OperationType: sdtypes.OperationTypeRegisterInstance,
+				Failures:      []cloudmap.InstanceFailure{{InstanceId: test.EndptId2, Err: throttled}},
+			}),
+			want: []v1alpha1.FailedEndpoint{{InstanceId: test.EndptId2, IP: test.EndptIp2,
+				Reason: "ThrottlingException", Message: throttled.Error()}},
+		},
+		{
+			name: "operations failed",
+			err: &cloudmap.OperationFailureError{
+				OperationType: sdtypes.OperationTypeRegisterInstance,
+				Failures: []cloudmap.OperationFailure{
+					{OperationId: test.OpId2, InstanceId: test.EndptId2, ErrorCode: "ERROR_CODE", ErrorMessage: "error"},
+					{OperationId: test.OpId1, InstanceId: test.EndptId1, ErrorCode: "ERROR_CODE", ErrorMessage: "error"},
+				},
+			},
+			want: []v1alpha1.FailedEndpoint{
+				{InstanceId: test.EndptId1, IP: test.EndptIp1, Reason: "ERROR_CODE", Message: "error"},
+				{InstanceId: test.EndptId2, IP: test.EndptIp2, Reason: "ERROR_CODE", Message: "error"},
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, failedEndpoints(endpoints, tt.err))
+		})
+	}
+}
+
+func TestSetFailedEndpoints(t *testing.T) {
+	opErr := &cloudmap.OperationFailureError{OperationType: sdtypes.OperationTypeRegisterInstance}
+	for i := 0; i < 25; i++ {
+		opErr.Failures = append(opErr.Failures, cloudmap.OperationFailure{
+			InstanceId: "instance-" + strconv.Itoa(i), ErrorCode: "ERROR_CODE"})
+	}
+	serviceExport := testServiceExportObj()
+
+	setFailedEndpoints(serviceExport, nil, opErr)
+	assert.Equal(t, int32(25), serviceExport.Status.FailedEndpointCount)
+	assert.Len(t, serviceExport.Status.FailedEndpoints, maxFailedEndpoints)
+
+	setFailedEndpoints(serviceExport, nil, nil)
+	assert.Zero(t, serviceExport.Status.FailedEndpointCount)
+	assert.Nil(t, serviceExport.Status.FailedEndpoints, "cleared once the registration succeeds")
+}
@@ -0,0 +1,191 @@
+package controllers
+
+import (
+	"context"
+	goerrors "errors"
+	"github.com/aws/aws-cloud-map-mcs-controller-for-k8s/pkg/api/v1alpha1"
+	"github.com/aws/aws-cloud-map-mcs-controller-for-k8s/pkg/cloudmap"
+	"github.com/aws/aws-cloud-map-mcs-controller-for-k8s/pkg/model"
+	"github.com/aws/aws-cloud-map-mcs-controller-for-k8s/pkg/registry"
+	"github.com/aws/aws-cloud-map-mcs-controller-for-k8s/pkg/tenancy"
+	v1 "k8s.io/api/core/v1"
+	utilerrors "k8s.io/apimachinery/pkg/util/errors"
+	"sort"
+	"strings"
+)
+
+// AdditionalNamespacesAnnotation sets comma separated Cloud Map namespaces the endpoints of the ServiceExport are
+// exported to, besides the Cloud Map namespace of its namespace, e.g. to publish a shared platform service into the
+// namespaces of several consumers.
+const AdditionalNamespacesAnnotation = "multicluster.k8s.aws/additional-cloudmap-namespaces"
+
+// additionalNamespaces returns the sorted additional Cloud Map namespaces of the ServiceExport, without the Cloud Map
+// namespace of its namespace.
+func additionalNamespaces(serviceExport *v1alpha1.ServiceExport, cmNamespace string) []string {
+	found := make(map[string]bool)
+	namespaces := make([]string, 0)
+	for _, ns := range strings.Split(serviceExport.Annotations[AdditionalNamespacesAnnotation], ",") {
+		if ns = strings.TrimSpace(ns); ns != "" && ns != cmNamespace && !found[ns] {
+			found[ns] = true
+			namespaces = append(namespaces, ns)
+		}
+	}
+	sort.Strings(namespaces)
+	return namespaces
+}
+
+// exportAdditionalNamespaces exports the endpoints of the service to the additional Cloud Map namespaces of the
+// ServiceExport, and de-registers them from the namespaces no longer listed. The namespaces exported to are recorded
+// in the status of the ServiceExport, so the endpoints are de-registered once a namespace is removed from the
+// annotation or the ServiceExport is deleted. Namespaces failing the export are retried, the failures are aggregated.
+func (r *ServiceExportReconciler) exportAdditionalNamespaces(ctx context.Context, serviceExport *v1alpha1.ServiceExport, service *v1.Service, settings SyncSettings) error {
+	targets := additionalNamespaces(serviceExport, settings.CloudMapNamespace)
+	if len(targets) == 0 && len(serviceExport.Status.AdditionalNamespaces) == 0 {
+		return nil
+	}
+
+	if settings.DNSTTL != nil {
+		ctx = cloudmap.WithDnsTTL(ctx, *settings.DNSTTL)
+	}
+	// invalid metadata is reported by exportService
+	metadata, _ := serviceMetadata(serviceExport, service, r.ResourceTags)
+	ctx = cloudmap.WithServiceMetadata(ctx, metadata)
+
+	var endpoints []*model.Endpoint
+	if len(targets) > 0 {
+		var err error
+		if endpoints, err = r.extractEndpoints(ctx, serviceExport, service, settings); err != nil {
+			return err
+		}
+		setCanaryWeight(serviceExport, endpoints)
+		setMigrationAttributes(serviceExport, endpoints)
+	}
+
+	errs := make([]error, 0)
+	exported := make([]string, 0, len(targets))
+	for _, cmNamespace := range targets {
+		if err := r.checkTenancy(ctx, serviceExport, cmNamespace); err != nil {
+			if goerrors.Is(err, tenancy.ErrNotPermitted) {
+				r.Recorder.Event(serviceExport, v1.EventTypeWarning, TenancyDeniedReason, err.Error())
+			}
+			errs = append(errs, err)
+			continue
+		}
+		err := r.syncNamespace(ctx, cmNamespace, service.Name, endpoints, metadata, serviceExport.Spec.SyncPolicy)
+		if err == nil {
+			err = r.correctDnsConfig(ctx, serviceExport, cmNamespace, service.Name)
+		}
+		if err != nil {
+			r.Log.Error(err, "error exporting to an additional Cloud Map namespace", "namespace", service.Namespace,
+				"name", service.Name, "cloudMapNamespace", cmNamespace)
+			errs = append(errs, err)
+		}
+		// the namespace is recorded even if the export failed, so its endpoints are de-registered on removal
+		exported = append(exported, cmNamespace)
+	}
+
+	for _, cmNamespace := range serviceExport.Status.AdditionalNamespaces {
+		if containsString(targets, cmNamespace) {
+			continue
+		}
+		if err := r.unexportNamespace(ctx, serviceExport, cmNamespace); err != nil {
+			errs = append(errs, err)
+			// the endpoints are de-registered on retry
+			exported = append(exported, cmNamespace)
+		}
+	}
+	sort.Strings(exported)
+	serviceExport.Status.AdditionalNamespaces = exported
+	if len(exported) == 0 {
+		serviceExport.Status.AdditionalNamespaces = nil
+	}
+
+	return utilerrors.NewAggregate(errs)
+}
+
+// syncNamespace registers the endpoints in the service of the Cloud Map namespace, and de-registers the endpoints
+// registered by this cluster which are not desired anymore, as far as the sync policy permits.
+func (r *ServiceExportReconciler) syncNamespace(ctx context.Context, cmNamespace string, name string, endpoints []*model.Endpoint, metadata cloudmap.ServiceMetadata, policy v1alpha1.SyncPolicy) error {
+	cmService, err := r.createOrGetCloudMapService(ctx, cmNamespace, name)
+	if err != nil {
+		return err
+	}
+	if !metadata.IsEmpty() {
+		err = registry.UpdateServiceMetadata(ctx, r.Registry, cmNamespace, name, metadata)
+		if goerrors.Is(err, cloudmap.ErrTooManyTags) {
+			// reported by exportService, the description is still applied
+			metadata.Tags = nil
+			err = registry.UpdateServiceMetadata(ctx, r.Registry, cmNamespace, name, metadata)
+		}
+		if err != nil {
+			return err
+		}
+	}
+
+	changes, _ := allowedChanges(policy, (&model.Plan{
+		Current: r.ownedEndpoints(cmService.Endpoints),
+		Desired: endpoints,
+	}).CalculateChanges())
+	if changes.HasUpdates() {
+		upserts := append(changes.Create, changes.Update...)
+		if err = r.Registry.RegisterEndpoints(ctx, cmNamespace, name, upserts); err != nil {
+			return err
+		}
+		r.publishRegistered(ctx, cmNamespace, name, changes.Create, len(r.ownedEndpoints(cmService.Endpoints)) == 0)
+	}
+	if changes.HasDeletes() {
+		if err = r.Registry.DeleteEndpoints(ctx, cmNamespace, name, changes.Delete); err != nil {
+			return err
+		}
+		r.publishDeregistered(ctx, cmNamespace, name, changes.Delete, len(endpoints) == 0)
+	}
+	return nil
+}
+
+// unexportNamespace de-registers the endpoints registered by this cluster from the service of an additional Cloud Map
+// namespace, unless the tenancy policy denies the namespace or the sync policy withholds de-registrations.
+func (r *ServiceExportReconciler) unexportNamespace(ctx context.Context, serviceExport *v1alpha1.ServiceExport, cmNamespace string) error {
+	if !allowsDeregistration(serviceExport.Spec.SyncPolicy) {
+		r.Log.Info("retaining endpoints in Cloud Map under the sync policy", "namespace", serviceExport.Namespace,
+			"name", serviceExport.Name, "cloudMapNamespace", cmNamespace, "syncPolicy", serviceExport.Spec.SyncPolicy)
+		return nil
+	}
+	if err := r.checkTenancy(ctx, serviceExport, cmNamespace); err != nil {
+		if goerrors.Is(err, tenancy.ErrNotPermitted) {
+			// never delete from Cloud Map namespaces of other tenants
+			r.Log.Info("skipping Cloud Map deregistration", "namespace", serviceExport.Namespace,
+				"name", serviceExport.Name, "cloudMapNamespace", cmNamespace, "reason", err.Error())
+			return nil
+		}
+		return err
+	}
+
+	cmService, err := r.Registry.GetService(ctx, cmNamespace, serviceExport.Name)
+	if err != nil || cmService == nil {
+		return err
+	}
+	if err = r.deregisterEndpoints(ctx, serviceExport, cmService); err != nil {
+		return err
+	}
+	r.applyEmptyServicePolicy(ctx, serviceExport, cmNamespace, serviceExport.Name)
+	return nil
+}
+
+// exportedAdditionalNamespaces returns the additional Cloud Map namespaces of the ServiceExport along with the
+// namespaces recorded in its status, which may have been exported to before the annotation changed.
+func exportedAdditionalNamespaces(serviceExport *v1alpha1.ServiceExport, cmNamespace string) []string {
+	namespaces := additionalNamespaces(serviceExport, cmNamespace)
+	for _, ns := range serviceExport.Status.AdditionalNamespaces {
+		if ns != cmNamespace && !containsString(namespaces, ns) {
+			namespaces = append(namespaces, ns)
+			sort.Strings(namespaces)
+		}
+	}
+	return namespaces
+}
+
+// containsString returns whether the sorted strings contain the value.
+func containsString(sorted []string, value string) bool {
+	i := sort.SearchStrings(sorted, value)
+	return i < len(sorted) && sorted[i] == value
+}
@@ -0,0 +1,113 @@
+package controllers
+
+import (
+	"context"
+	"github.com/aws/aws-cloud-map-mcs-controller-for-k8s/mocks/pkg/cloudmap"
+	"github.com/aws/aws-cloud-map-mcs-controller-for-k8s/pkg/api/v1alpha1"
+	"github.com/aws/aws-cloud-map-mcs-controller-for-k8s/pkg/model"
+	"github.com/aws/aws-cloud-map-mcs-controller-for-k8s/test"
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/assert"
+	"k8s.io/apimachinery/pkg/types"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	"testing"
+)
+
+func TestServiceExportReconciler_Reconcile_AdditionalNamespaces(t *testing.T) {
+	serviceExportObj := testServiceExportObj()
+	serviceExportObj.Annotations = map[string]string{AdditionalNamespacesAnnotation: "consumers"}
+	fakeClient := fake.NewClientBuilder().
+		WithScheme(getServiceExportScheme()).
+		WithObjects(testServiceObj(), serviceExportObj).
+		WithLists(testEndpointSliceObj()).
+		Build()
+
+	mockController := gomock.NewController(t)
+	defer mockController.Finish()
+
+	mock := cloudmap.NewMockServiceDiscoveryClient(mockController)
+	// the endpoint is already registered in the Cloud Map namespace of the namespace
+	mock.EXPECT().GetService(gomock.Any(), test.NsName, test.SvcName).
+		Return(&model.Service{Namespace: test.NsName, Name: test.SvcName,
+			Endpoints: []*model.Endpoint{test.GetTestEndpoint1()}}, nil)
+	// the service is created in the additional namespace
+	first := mock.EXPECT().GetService(gomock.Any(), "consumers", test.SvcName).Return(nil, nil)
+	second := mock.EXPECT().GetService(gomock.Any(), "consumers", test.SvcName).
+		Return(&model.Service{Namespace: "consumers", Name: test.SvcName}, nil)
+	gomock.InOrder(first, second)
+	mock.EXPECT().CreateService(gomock.Any(), "consumers", test.SvcName).Return(nil).Times(1)
+	mock.EXPECT().RegisterEndpoints(gomock.Any(), "consumers", test.SvcName,
+		[]*model.Endpoint{test.GetTestEndpoint1()}).Return(nil).Times(1)
+
+	reconciler := getServiceExportReconciler(t, mock, fakeClient)
+
+	request := ctrl.Request{
+		NamespacedName: types.NamespacedName{
+			Namespace: test.NsName,
+			Name:      test.SvcName,
+		},
+	}
+
+	got, err := reconciler.Reconcile(context.Background(), request)
+	assert.NoError(t, err)
+	assert.Equal(t, ctrl.Result{}, got, "Result should be empty")
+
+	serviceExport := &v1alpha1.ServiceExport{}
+	err = fakeClient.Get(context.TODO(), types.NamespacedName{Namespace: test.NsName, Name: test.SvcName}, serviceExport)
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"consumers"}, serviceExport.Status.AdditionalNamespaces)
+}
+
+func TestServiceExportReconciler_Reconcile_RemovedAdditionalNamespace(t *testing.T) {
+	// the ServiceExport was exported to a namespace no longer listed in the annotation
+	serviceExportObj := testServiceExportObj()
+	serviceExportObj.Status.AdditionalNamespaces = []string{"retired"}
+	fakeClient := fake.NewClientBuilder().
+		WithScheme(getServiceExportScheme()).
+		WithObjects(testServiceObj(), serviceExportObj).
+		WithLists(testEndpointSliceObj()).
+		Build()
+
+	mockController := gomock.NewController(t)
+	defer mockController.Finish()
+
+	mock := cloudmap.NewMockServiceDiscoveryClient(mockController)
+	mock.EXPECT().GetService(gomock.Any(), test.NsName, test.SvcName).
+		Return(&model.Service{Namespace: test.NsName, Name: test.SvcName,
+			Endpoints: []*model.Endpoint{test.GetTestEndpoint1()}}, nil)
+	mock.EXPECT().GetService(gomock.Any(), "retired", test.SvcName).
+		Return(&model.Service{Namespace: "retired", Name: test.SvcName,
+			Endpoints: []*model.Endpoint{test.GetTestEndpoint1()}}, nil)
+	mock.EXPECT().DeleteEndpoints(gomock.Any(), "retired", test.SvcName,
+		[]*model.Endpoint{test.GetTestEndpoint1()}).Return(nil).Times(1)
+
+	reconciler := getServiceExportReconciler(t, mock, fakeClient)
+
+	request := ctrl.Request{
+		NamespacedName: types.NamespacedName{
+			Namespace: test.NsName,
+			Name:      test.SvcName,
+		},
+	}
+
+	got, err := reconciler.Reconcile(context.Background(), request)
+	assert.NoError(t, err)
+	assert.Equal(t, ctrl.Result{}, got, "Result should be empty")
+
+	serviceExport := &v1alpha1.ServiceExport{}
+	err = fakeClient.Get(context.TODO(), types.NamespacedName{Namespace: test.NsName, Name: test.SvcName}, serviceExport)
+	assert.NoError(t, err)
+	assert.Empty(t, serviceExport.Status.AdditionalNamespaces)
+}
+
+func TestAdditionalNamespaces(t *testing.T) {
+	serviceExport := testServiceExportObj()
+	serviceExport.Annotations = map[string]string{
+		AdditionalNamespacesAnnotation: " team-b,team-a, ,team-b," + test.NsName,
+	}
+	assert.Equal(t, []string{"team-a", "team-b"}, additionalNamespaces(serviceExport, test.NsName))
+
+	serviceExport.Status.AdditionalNamespaces = []string{"team-c", test.NsName}
+	assert.Equal(t, []string{"team-a", "team-b", "team-c"}, exportedAdditionalNamespaces(serviceExport, test.NsName))
+}
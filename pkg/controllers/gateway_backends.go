@@ -0,0 +1,144 @@
+package controllers
+
+import (
+	"context"
+	"github.com/aws/aws-cloud-map-mcs-controller-for-k8s/pkg/api/v1alpha1"
+	"github.com/aws/aws-cloud-map-mcs-controller-for-k8s/pkg/model"
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/util/sets"
+	"strings"
+)
+
+const (
+	// ServiceImportAnnotation annotates a derived Service with the name of its ServiceImport, so Gateway API routes
+	// can find the backend of an imported service
+	ServiceImportAnnotation = "multicluster.k8s.aws/service-import"
+	// ZonesAnnotation annotates a derived Service with the comma separated zones of the endpoints of all clusters
+	ZonesAnnotation = "multicluster.k8s.aws/zones"
+
+	// gatewayAPIGroup is the API group of the Gateway API
+	gatewayAPIGroup = "gateway.networking.k8s.io"
+)
+
+// ReferenceGrantGVK is the kind of the Gateway API resource permitting routes of other namespaces to reference a
+// backend.
+var ReferenceGrantGVK = schema.GroupVersionKind{Group: gatewayAPIGroup, Version: "v1beta1", Kind: "ReferenceGrant"}
+
+// gatewayRouteKinds are the kinds of the Gateway API routes a ReferenceGrant permits to reference a derived Service.
+var gatewayRouteKinds = []string{"HTTPRoute", "GRPCRoute", "TCPRoute", "TLSRoute"}
+
+// GatewayBackendsConfig configures the derived Services of imported services as Gateway API backends, so east-west
+// gateways can route to the endpoints of other clusters.
+type GatewayBackendsConfig struct {
+	// RouteNamespaces are the namespaces of routes which may reference the derived Services, through a
+	// ReferenceGrant per ServiceImport. Routes can only reference derived Services of their namespace if empty.
+	RouteNamespaces []string
+}
+
+// +kubebuilder:rbac:groups=gateway.networking.k8s.io,resources=referencegrants,verbs=create;get;update
+
+// updateGatewayBackend annotates the derived Service with its ServiceImport and the zones of its endpoints, sets the
+// application protocol of its ports exported by the clusters, and grants routes of the configured namespaces access
+// to it.
+func (r *CloudMapReconciler) updateGatewayBackend(ctx context.Context, svcImport *v1alpha1.ServiceImport, svc *v1.Service, endpoints []*model.Endpoint) error {
+	if r.GatewayBackends == nil {
+		return nil
+	}
+
+	if applyGatewayBackend(svcImport, svc, endpoints) {
+		if err := r.Client.Update(ctx, svc); err != nil {
+			return err
+		}
+		r.Log.Info("updated derived Service as Gateway API backend", "namespace", svc.Namespace, "name", svc.Name,
+			"serviceImport", svcImport.Name)
+	}
+
+	if len(r.GatewayBackends.RouteNamespaces) == 0 {
+		return nil
+	}
+	return r.applyImportResource(ctx, createReferenceGrantStruct(svcImport, svc, r.GatewayBackends.RouteNamespaces),
+		"service", svc.Name)
+}
+
+// applyGatewayBackend updates the annotations and port application protocols of the derived Service, and returns true
+// if changed. Application protocols conflicting between clusters are resolved in favour of the lowest value.
+func applyGatewayBackend(svcImport *v1alpha1.ServiceImport, svc *v1.Service, endpoints []*model.Endpoint) bool {
+	zones := sets.NewString()
+	appProtocols := make(map[string]string)
+	for _, endpoint := range endpoints {
+		if endpoint.Zone != "" {
+			zones.Insert(endpoint.Zone)
+		}
+		appProtocol := endpoint.Attributes[AppProtocolAttr]
+		id := endpoint.ServicePort.GetID()
+		if current, found := appProtocols[id]; appProtocol != "" && (!found || appProtocol < current) {
+			appProtocols[id] = appProtocol
+		}
+	}
+
+	changed := false
+	desired := map[string]string{ServiceImportAnnotation: svcImport.Name, ZonesAnnotation: strings.Join(zones.List(), ",")}
+	for key, value := range desired {
+		if svc.Annotations[key] == value {
+			continue
+		}
+		if svc.Annotations == nil {
+			svc.Annotations = make(map[string]string)
+		}
+		if value == "" {
+			delete(svc.Annotations, key)
+		} else {
+			svc.Annotations[key] = value
+		}
+		changed = true
+	}
+
+	for i := range svc.Spec.Ports {
+		port := &svc.Spec.Ports[i]
+		modelPort := ServicePortToPort(*port)
+		appProtocol, found := appProtocols[modelPort.GetID()]
+		switch {
+		case found && (port.AppProtocol == nil || *port.AppProtocol != appProtocol):
+			port.AppProtocol = &appProtocol
+			changed = true
+		case !found && port.AppProtocol != nil:
+			port.AppProtocol = nil
+			changed = true
+		}
+	}
+	return changed
+}
+
+// createReferenceGrantStruct returns the ReferenceGrant permitting the routes of the namespaces to reference the
+// derived Service, owned by the ServiceImport.
+func createReferenceGrantStruct(svcImport *v1alpha1.ServiceImport, svc *v1.Service, routeNamespaces []string) *unstructured.Unstructured {
+	namespaces := sets.NewString(routeNamespaces...).List()
+	from := make([]interface{}, 0, len(namespaces)*len(gatewayRouteKinds))
+	for _, namespace := range namespaces {
+		for _, kind := range gatewayRouteKinds {
+			from = append(from, map[string]interface{}{
+				"group":     gatewayAPIGroup,
+				"kind":      kind,
+				"namespace": namespace,
+			})
+		}
+	}
+
+	referenceGrant := &unstructured.Unstructured{Object: map[string]interface{}{
+		"spec": map[string]interface{}{
+			"from": from,
+			"to": []interface{}{
+				map[string]interface{}{"group": "", "kind": "Service", "name": svc.Name},
+			},
+		},
+	}}
+	referenceGrant.SetGroupVersionKind(ReferenceGrantGVK)
+	referenceGrant.SetNamespace(svcImport.Namespace)
+	referenceGrant.SetName(svcImport.Name)
+	referenceGrant.SetOwnerReferences([]metav1.OwnerReference{*metav1.NewControllerRef(svcImport,
+		v1alpha1.GroupVersion.WithKind("ServiceImport"))})
+	return referenceGrant
+}
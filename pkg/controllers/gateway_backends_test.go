@@ -0,0 +1,45 @@
+package controllers
+
+import (
+	"github.com/aws/aws-cloud-map-mcs-controller-for-k8s/pkg/model"
+	"github.com/aws/aws-cloud-map-mcs-controller-for-k8s/test"
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func TestApplyGatewayBackend(t *testing.T) {
+	svcImport := createServiceImportStruct(test.NsName, test.SvcName)
+	endpoint1 := test.GetTestEndpoint1()
+	endpoint1.Zone = "us-west-2b"
+	endpoint1.Attributes[AppProtocolAttr] = "kubernetes.io/h2c"
+	endpoint2 := test.GetTestEndpoint1()
+	endpoint2.IP = test.EndptIp2
+	endpoint2.Zone = "us-west-2a"
+	endpoint2.Attributes[AppProtocolAttr] = "http"
+	endpoints := []*model.Endpoint{endpoint1, endpoint2}
+	svc := createDerivedServiceStruct(endpoints, svcImport)
+
+	assert.True(t, applyGatewayBackend(svcImport, svc, endpoints))
+	assert.Equal(t, map[string]string{
+		ServiceImportAnnotation: test.SvcName,
+		ZonesAnnotation:         "us-west-2a,us-west-2b",
+	}, svc.Annotations)
+	assert.Equal(t, "http", *svc.Spec.Ports[0].AppProtocol, "conflicting protocols resolve to the lowest")
+	assert.False(t, applyGatewayBackend(svcImport, svc, endpoints), "unchanged")
+
+	assert.True(t, applyGatewayBackend(svcImport, svc, []*model.Endpoint{test.GetTestEndpoint1()}))
+	assert.Equal(t, map[string]string{ServiceImportAnnotation: test.SvcName}, svc.Annotations)
+	assert.Nil(t, svc.Spec.Ports[0].AppProtocol)
+}
+
+func TestCreateReferenceGrantStruct(t *testing.T) {
+	svcImport := createServiceImportStruct(test.NsName, test.SvcName)
+	svc := createDerivedServiceStruct([]*model.Endpoint{test.GetTestEndpoint1()}, svcImport)
+
+	referenceGrant := createReferenceGrantStruct(svcImport, svc, []string{"gateways", "gateways"})
+	assert.Equal(t, ReferenceGrantGVK, referenceGrant.GroupVersionKind())
+	assert.Equal(t, test.SvcName, referenceGrant.GetName())
+	spec := referenceGrant.Object["spec"].(map[string]interface{})
+	assert.Len(t, spec["from"], len(gatewayRouteKinds), "namespaces are deduplicated")
+	assert.Equal(t, []interface{}{map[string]interface{}{"group": "", "kind": "Service", "name": svc.Name}}, spec["to"])
+}
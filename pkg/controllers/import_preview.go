@@ -0,0 +1,138 @@
+package controllers
+
+import (
+	"context"
+	"github.com/aws/aws-cloud-map-mcs-controller-for-k8s/pkg/api/v1alpha1"
+	"github.com/aws/aws-cloud-map-mcs-controller-for-k8s/pkg/model"
+	v1 "k8s.io/api/core/v1"
+	discovery "k8s.io/api/discovery/v1beta1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sort"
+)
+
+// NamespaceImportPreview lists the services the CloudMapReconciler would import into a Kubernetes namespace.
+type NamespaceImportPreview struct {
+	Namespace         string
+	CloudMapNamespace string
+	Imports           []*ImportPreview
+	// DeletedImports are the names of the existing ServiceImports without Cloud Map service, which would be deleted
+	DeletedImports []string
+}
+
+// ImportPreview holds the ServiceImport, derived Service and EndpointSlices importing a Cloud Map service, as the
+// CloudMapReconciler would create them.
+type ImportPreview struct {
+	ServiceImport  *v1alpha1.ServiceImport
+	DerivedService *v1.Service
+	EndpointSlices []*discovery.EndpointSlice
+	// ServiceImportExists and DerivedServiceExists are set if the resource exists in the cluster already
+	ServiceImportExists  bool
+	DerivedServiceExists bool
+}
+
+// PreviewImports computes the resources the CloudMapReconciler would create to import the services of a Cloud Map
+// namespace, for each Kubernetes namespace mapped to the Cloud Map namespace, without modifying the cluster.
+func (r *CloudMapReconciler) PreviewImports(ctx context.Context, cmNamespace string) ([]*NamespaceImportPreview, error) {
+	namespaceNames, err := r.listNamespaces(ctx)
+	if err != nil {
+		return nil, err
+	}
+	sort.Strings(namespaceNames)
+
+	var services []*model.Service
+	previews := make([]*NamespaceImportPreview, 0)
+	for _, namespaceName := range namespaceNames {
+		settings, err := ResolveSyncSettings(ctx, r.Client, r.ClusterConfig, namespaceName)
+		if err != nil {
+			return nil, err
+		}
+		if settings.CloudMapNamespace != cmNamespace {
+			continue
+		}
+
+		if services == nil {
+			if services, err = r.Registry.ListServices(ctx, cmNamespace); err != nil {
+				return nil, err
+			}
+			sort.Slice(services, func(i, j int) bool {
+				return services[i].Name < services[j].Name
+			})
+		}
+
+		preview, err := r.previewNamespace(ctx, namespaceName, cmNamespace, services)
+		if err != nil {
+			return nil, err
+		}
+		previews = append(previews, preview)
+	}
+	return previews, nil
+}
+
+func (r *CloudMapReconciler) previewNamespace(ctx context.Context, namespaceName string, cmNamespace string, services []*model.Service) (*NamespaceImportPreview, error) {
+	serviceImports := v1alpha1.ServiceImportList{}
+	if err := r.Client.List(ctx, &serviceImports, client.InNamespace(namespaceName)); err != nil {
+		return nil, err
+	}
+	existingImports := make(map[string]bool)
+	for _, svcImport := range serviceImports.Items {
+		existingImports[svcImport.Name] = true
+	}
+
+	preview := &NamespaceImportPreview{
+		Namespace:         namespaceName,
+		CloudMapNamespace: cmNamespace,
+		Imports:           make([]*ImportPreview, 0),
+		DeletedImports:    make([]string, 0),
+	}
+	for _, svc := range services {
+		if len(svc.Endpoints) == 0 {
+			// empty services are not imported
+			continue
+		}
+
+		imp, err := r.previewService(ctx, namespaceName, svc)
+		if err != nil {
+			return nil, err
+		}
+		preview.Imports = append(preview.Imports, imp)
+		delete(existingImports, svc.Name)
+	}
+
+	for name := range existingImports {
+		preview.DeletedImports = append(preview.DeletedImports, name)
+	}
+	sort.Strings(preview.DeletedImports)
+	return preview, nil
+}
+
+func (r *CloudMapReconciler) previewService(ctx context.Context, namespaceName string, svc *model.Service) (*ImportPreview, error) {
+	preview := &ImportPreview{ServiceImport: createServiceImportStruct(namespaceName, svc.Name)}
+	endpoints := servedEndpoints(dedupeMigratedEndpoints(unpackEndpoints(svc.Endpoints)))
+
+	existingImport, err := r.getServiceImport(ctx, namespaceName, svc.Name)
+	switch {
+	case err == nil:
+		preview.ServiceImportExists = true
+		preview.ServiceImport.Annotations[DerivedServiceAnnotation] = existingImport.Annotations[DerivedServiceAnnotation]
+	case !errors.IsNotFound(err):
+		return nil, err
+	}
+
+	preview.DerivedService = createDerivedServiceStruct(endpoints, preview.ServiceImport)
+	if _, err = r.getDerivedService(ctx, namespaceName, preview.DerivedService.Name); err == nil {
+		preview.DerivedServiceExists = true
+	} else if !errors.IsNotFound(err) {
+		return nil, err
+	}
+
+	for _, port := range preview.DerivedService.Spec.Ports {
+		preview.ServiceImport.Spec.Ports = append(preview.ServiceImport.Spec.Ports, servicePortToServiceImport(port))
+	}
+	applyExportedMetadata(&preview.ServiceImport.ObjectMeta, mergeExportedMetadata(endpoints))
+
+	preview.EndpointSlices = createEndpointSliceStructs(preview.ServiceImport, preview.DerivedService,
+		createSliceEndpoints(preview.DerivedService, endpoints))
+
+	return preview, nil
+}
@@ -0,0 +1,75 @@
+package controllers
+
+import (
+	"context"
+	"github.com/aws/aws-cloud-map-mcs-controller-for-k8s/mocks/pkg/cloudmap"
+	cloudmapv1alpha1 "github.com/aws/aws-cloud-map-mcs-controller-for-k8s/pkg/api/cloudmap/v1alpha1"
+	"github.com/aws/aws-cloud-map-mcs-controller-for-k8s/pkg/api/v1alpha1"
+	"github.com/aws/aws-cloud-map-mcs-controller-for-k8s/pkg/model"
+	"github.com/aws/aws-cloud-map-mcs-controller-for-k8s/test"
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/assert"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/scheme"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	"testing"
+)
+
+func TestCloudMapReconciler_PreviewImports(t *testing.T) {
+	scheme.Scheme.AddKnownTypes(v1alpha1.GroupVersion, &v1alpha1.ServiceImportList{}, &v1alpha1.ServiceImport{})
+	scheme.Scheme.AddKnownTypes(cloudmapv1alpha1.GroupVersion, &cloudmapv1alpha1.CloudMapSyncConfig{})
+
+	stale := &v1alpha1.ServiceImport{ObjectMeta: metav1.ObjectMeta{Namespace: test.NsName, Name: "stale"}}
+	fakeClient := fake.NewClientBuilder().WithRuntimeObjects(testNamespace(), stale).Build()
+
+	mockController := gomock.NewController(t)
+	defer mockController.Finish()
+
+	// only reads are expected
+	mockSDClient := cloudmap.NewMockServiceDiscoveryClient(mockController)
+	mockSDClient.EXPECT().ListServices(gomock.Any(), test.NsName).Return([]*model.Service{
+		test.GetTestServiceWithEndpoint([]*model.Endpoint{test.GetTestEndpoint1()}),
+		{Namespace: test.NsName, Name: "empty"},
+	}, nil)
+
+	reconciler := getReconciler(t, mockSDClient, fakeClient)
+	previews, err := reconciler.PreviewImports(context.TODO(), test.NsName)
+	assert.NoError(t, err)
+	if !assert.Len(t, previews, 1) {
+		return
+	}
+
+	preview := previews[0]
+	assert.Equal(t, test.NsName, preview.Namespace)
+	assert.Equal(t, []string{"stale"}, preview.DeletedImports)
+	if !assert.Len(t, preview.Imports, 1) {
+		return
+	}
+
+	imp := preview.Imports[0]
+	assert.False(t, imp.ServiceImportExists)
+	assert.False(t, imp.DerivedServiceExists)
+	assert.Equal(t, test.SvcName, imp.ServiceImport.Name)
+	assert.Equal(t, int32(test.ServicePort1), imp.ServiceImport.Spec.Ports[0].Port)
+	assert.Equal(t, DerivedName(test.NsName, test.SvcName), imp.DerivedService.Name)
+	if assert.Len(t, imp.EndpointSlices, 1) {
+		assert.Equal(t, test.EndptIp1, imp.EndpointSlices[0].Endpoints[0].Addresses[0])
+		assert.Equal(t, test.SvcName, imp.EndpointSlices[0].Labels[LabelServiceImportName])
+	}
+}
+
+func TestCloudMapReconciler_PreviewImports_UnmappedNamespace(t *testing.T) {
+	scheme.Scheme.AddKnownTypes(cloudmapv1alpha1.GroupVersion, &cloudmapv1alpha1.CloudMapSyncConfig{})
+	fakeClient := fake.NewClientBuilder().WithRuntimeObjects(testNamespace()).Build()
+
+	mockController := gomock.NewController(t)
+	defer mockController.Finish()
+
+	// the Cloud Map namespace is not read if no Kubernetes namespace imports it
+	mockSDClient := cloudmap.NewMockServiceDiscoveryClient(mockController)
+
+	reconciler := getReconciler(t, mockSDClient, fakeClient)
+	previews, err := reconciler.PreviewImports(context.TODO(), "other")
+	assert.NoError(t, err)
+	assert.Empty(t, previews)
+}
@@ -0,0 +1,70 @@
+package controllers
+
+import (
+	"context"
+	"encoding/json"
+	"github.com/aws/aws-cloud-map-mcs-controller-for-k8s/mocks/pkg/cloudmap"
+	cloudmapv1alpha1 "github.com/aws/aws-cloud-map-mcs-controller-for-k8s/pkg/api/cloudmap/v1alpha1"
+	"github.com/aws/aws-cloud-map-mcs-controller-for-k8s/pkg/api/v1alpha1"
+	"github.com/aws/aws-cloud-map-mcs-controller-for-k8s/pkg/events"
+	"github.com/aws/aws-cloud-map-mcs-controller-for-k8s/pkg/model"
+	"github.com/aws/aws-cloud-map-mcs-controller-for-k8s/test"
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/assert"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/kubernetes/scheme"
+	"net/http"
+	"net/http/httptest"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	"testing"
+	"time"
+)
+
+func TestCloudMapReconciler_Reconcile_NotifiesWebhooks(t *testing.T) {
+	s := scheme.Scheme
+	s.AddKnownTypes(v1alpha1.GroupVersion, &v1alpha1.ServiceImportList{}, &v1alpha1.ServiceImport{})
+	s.AddKnownTypes(cloudmapv1alpha1.GroupVersion, &cloudmapv1alpha1.CloudMapSyncConfig{})
+	fakeClient := fake.NewClientBuilder().WithRuntimeObjects([]runtime.Object{testNamespace()}...).Build()
+
+	mockController := gomock.NewController(t)
+	defer mockController.Finish()
+
+	mockSDClient := cloudmap.NewMockServiceDiscoveryClient(mockController)
+	mockSDClient.EXPECT().ListServices(context.TODO(), test.NsName).
+		Return([]*model.Service{test.GetTestServiceWithEndpoint([]*model.Endpoint{test.GetTestEndpoint1()})}, nil)
+
+	received := make(chan events.ImportChange, 10)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		change := events.ImportChange{}
+		assert.NoError(t, json.NewDecoder(r.Body).Decode(&change))
+		received <- change
+	}))
+	defer server.Close()
+
+	reconciler := getReconciler(t, mockSDClient, fakeClient)
+	reconciler.Notifier = events.NewWebhookNotifier([]string{server.URL}, test.ClusterId)
+	ctx, cancel := context.WithCancel(context.TODO())
+	defer cancel()
+	go func() {
+		_ = reconciler.Notifier.Start(ctx)
+	}()
+
+	assert.NoError(t, reconciler.Reconcile(context.TODO()))
+
+	changes := make([]events.ImportChange, 0)
+	for len(changes) == 0 || changes[len(changes)-1].Type != events.ImportEndpointsChanged {
+		select {
+		case change := <-received:
+			changes = append(changes, change)
+		case <-time.After(5 * time.Second):
+			t.Fatalf("webhook not notified of the endpoints, notified of %v", changes)
+		}
+	}
+
+	assert.Equal(t, events.ServiceImportCreated, changes[0].Type)
+	endpoints := changes[len(changes)-1]
+	assert.Equal(t, test.NsName, endpoints.Namespace)
+	assert.Equal(t, test.SvcName, endpoints.Name)
+	assert.Equal(t, test.ClusterId, endpoints.ClusterId)
+	assert.Equal(t, []events.Endpoint{{IP: test.EndptIp1, Port: test.Port1}}, endpoints.Endpoints)
+}
@@ -0,0 +1,70 @@
+package controllers
+
+import (
+	"fmt"
+	"github.com/aws/aws-cloud-map-mcs-controller-for-k8s/pkg/api/v1alpha1"
+	"github.com/aws/aws-cloud-map-mcs-controller-for-k8s/pkg/cloudmap"
+	"github.com/aws/aws-cloud-map-mcs-controller-for-k8s/pkg/model"
+	"github.com/aws/aws-cloud-map-mcs-controller-for-k8s/pkg/replication"
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/util/sets"
+	"regexp"
+	"strings"
+)
+
+const (
+	// InstanceAttributesAnnotation sets comma separated key=value custom attributes of the Cloud Map instances, on the
+	// ServiceExport or Service. App Mesh virtual nodes using Cloud Map service discovery select instances by such
+	// attributes, e.g. "virtual-node=orders-v1,ECS_TASK_DEFINITION_FAMILY=orders".
+	InstanceAttributesAnnotation = "multicluster.k8s.aws/cloudmap-instance-attributes"
+
+	// InvalidInstanceAttributesReason is the event reason for invalid Cloud Map instance attributes annotations
+	InvalidInstanceAttributesReason = "InvalidInstanceAttributes"
+
+	// maxCustomAttributes is the maximum number of custom attributes of a Cloud Map instance
+	maxCustomAttributes = 30
+	// maxAttributeValueLength is the maximum length of Cloud Map attribute values
+	maxAttributeValueLength = 1024
+)
+
+// attributeKeyPattern matches the Cloud Map attribute keys, which AWS_ prefixed keys are reserved from
+var attributeKeyPattern = regexp.MustCompile(`^[a-zA-Z0-9!-~]{1,255}$`)
+
+// reservedAttributes are the attributes the controller registers, which custom attributes can't override.
+var reservedAttributes = sets.NewString(
+	model.EndpointPortNameAttr, model.EndpointProtocolAttr, model.ServicePortNameAttr, model.ServicePortAttr,
+	model.ServiceTargetPortAttr, model.ServiceProtocolAttr, model.EndpointReadyAttr, model.EndpointHostnameAttr,
+	model.EndpointNodenameAttr, model.EndpointZoneAttr, model.EndpointPortsAttr,
+	K8sVersionAttr, ClusterIdAttr, ClusterSetIdAttr, RegionAttr, AppProtocolAttr, NodeProviderIdAttr, Ec2InstanceIdAttr,
+	StaticEndpointAttr, replication.SourceRegionAttr, WeightAttr, MigrationSourceAttr, MigrationPhaseAttr,
+	ExportedLabelsAttr, ExportedAnnotationsAttr, ExportCreationTimestampAttr)
+
+// instanceAttributes returns the custom Cloud Map instance attributes from the annotation of the ServiceExport, or
+// from the annotation of the Service if the ServiceExport doesn't set it.
+func instanceAttributes(serviceExport *v1alpha1.ServiceExport, service *v1.Service) (map[string]string, error) {
+	attributes, err := cloudmap.ParseTags(metadataAnnotation(serviceExport, service, InstanceAttributesAnnotation))
+	if err == nil {
+		err = validateInstanceAttributes(attributes)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("invalid annotation %s: %w", InstanceAttributesAnnotation, err)
+	}
+	return attributes, nil
+}
+
+func validateInstanceAttributes(attributes map[string]string) error {
+	if len(attributes) > maxCustomAttributes {
+		return fmt.Errorf("%d attributes exceed the limit of %d", len(attributes), maxCustomAttributes)
+	}
+	for key, value := range attributes {
+		switch {
+		case !attributeKeyPattern.MatchString(key):
+			return fmt.Errorf("invalid attribute key %q", key)
+		case strings.HasPrefix(key, "AWS_"), reservedAttributes.Has(key):
+			return fmt.Errorf("attribute %s is reserved", key)
+		case len(value) > maxAttributeValueLength:
+			return fmt.Errorf("value of attribute %s exceeds %d characters", key, maxAttributeValueLength)
+		}
+	}
+	return nil
+}
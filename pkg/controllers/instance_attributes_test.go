@@ -0,0 +1,47 @@
+package controllers
+
+import (
+	"fmt"
+	"github.com/stretchr/testify/assert"
+	"strings"
+	"testing"
+)
+
+func TestInstanceAttributes(t *testing.T) {
+	serviceExport := testServiceExportObj()
+	service := testServiceObj()
+	attributes, err := instanceAttributes(serviceExport, service)
+	assert.NoError(t, err)
+	assert.Empty(t, attributes, "no annotations")
+
+	service.Annotations = map[string]string{InstanceAttributesAnnotation: "virtual-node=orders-v0"}
+	serviceExport.Annotations = map[string]string{
+		InstanceAttributesAnnotation: "virtual-node=orders-v1, ECS_TASK_DEFINITION_FAMILY=orders",
+	}
+	attributes, err = instanceAttributes(serviceExport, service)
+	assert.NoError(t, err)
+	assert.Equal(t, map[string]string{"virtual-node": "orders-v1", "ECS_TASK_DEFINITION_FAMILY": "orders"}, attributes,
+		"ServiceExport annotations take precedence")
+}
+
+func TestInstanceAttributes_Invalid(t *testing.T) {
+	service := testServiceObj()
+	tooMany := make([]string, 0)
+	for i := 0; i <= maxCustomAttributes; i++ {
+		tooMany = append(tooMany, fmt.Sprintf("key%d=value", i))
+	}
+
+	for name, annotation := range map[string]string{
+		"malformed":       "virtual-node",
+		"AWS prefix":      "AWS_INSTANCE_IPV4=10.0.0.1",
+		"reserved":        ClusterIdAttr + "=other-cluster",
+		"key characters":  "virtual node=orders",
+		"value length":    "virtual-node=" + strings.Repeat("v", 1025),
+		"attribute count": strings.Join(tooMany, ","),
+	} {
+		serviceExport := testServiceExportObj()
+		serviceExport.Annotations = map[string]string{InstanceAttributesAnnotation: annotation}
+		_, err := instanceAttributes(serviceExport, service)
+		assert.Error(t, err, name)
+	}
+}
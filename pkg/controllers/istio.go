@@ -0,0 +1,154 @@
+package controllers
+
+import (
+	"context"
+	"fmt"
+	"github.com/aws/aws-cloud-map-mcs-controller-for-k8s/pkg/api/v1alpha1"
+	"github.com/aws/aws-cloud-map-mcs-controller-for-k8s/pkg/model"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"sort"
+	"strings"
+)
+
+const (
+	// DefaultIstioHostSuffix is the default suffix of the ServiceEntry hosts, the clusterset domain of the MCS API
+	DefaultIstioHostSuffix = "svc.clusterset.local"
+
+	// IstioClusterLabel is the label of ServiceEntry endpoints naming the cluster of the endpoint
+	IstioClusterLabel = "topology.istio.io/cluster"
+)
+
+// ServiceEntryGVK is the kind of the Istio custom resource adding services to the mesh service registry.
+var ServiceEntryGVK = schema.GroupVersionKind{Group: "networking.istio.io", Version: "v1beta1", Kind: "ServiceEntry"}
+
+// IstioConfig configures the ServiceEntries created for imported services, which make the endpoints of all clusters
+// discoverable by the mesh without Istio multicluster.
+type IstioConfig struct {
+	// HostSuffix is appended to "<service>.<namespace>." to form the ServiceEntry host, DefaultIstioHostSuffix is
+	// used if empty
+	HostSuffix string
+}
+
+// Host returns the ServiceEntry host of an imported service.
+func (c *IstioConfig) Host(namespace string, name string) string {
+	suffix := strings.Trim(c.HostSuffix, ".")
+	if suffix == "" {
+		suffix = DefaultIstioHostSuffix
+	}
+	return name + "." + namespace + "." + suffix
+}
+
+// +kubebuilder:rbac:groups=networking.istio.io,resources=serviceentries,verbs=create;get;update
+
+// updateServiceEntry creates or updates the ServiceEntry of the imported service, which is deleted with the
+// ServiceImport owning it.
+func (r *CloudMapReconciler) updateServiceEntry(ctx context.Context, svcImport *v1alpha1.ServiceImport, endpoints []*model.Endpoint) error {
+	if r.Istio == nil {
+		return nil
+	}
+
+	return r.applyImportResource(ctx, createServiceEntryStruct(svcImport, r.Istio, endpoints),
+		"host", r.Istio.Host(svcImport.Namespace, svcImport.Name))
+}
+
+// createServiceEntryStruct returns the ServiceEntry of the imported service, owned by the ServiceImport. Each endpoint
+// IP is a workload entry of the ServiceEntry, with the locality of its region and zone attributes. Ports are named like
+// the ports of the derived Service, and ports of protocols Istio doesn't support are skipped.
+func createServiceEntryStruct(svcImport *v1alpha1.ServiceImport, config *IstioConfig, endpoints []*model.Endpoint) *unstructured.Unstructured {
+	derivedNames := make(map[string]string)
+	for _, port := range extractServicePorts(endpoints) {
+		modelPort := ServicePortToPort(port)
+		derivedNames[modelPort.GetID()] = port.Name
+	}
+	ports := make(map[string]int64)
+	workloads := make(map[string]map[string]interface{})
+	for _, endpoint := range endpoints {
+		if endpoint.ServicePort.Protocol != model.TCPProtocol {
+			continue
+		}
+		portName := derivedNames[endpoint.ServicePort.GetID()]
+		if portName == "" {
+			portName = istioPortName(endpoint.ServicePort)
+		}
+		ports[portName] = int64(endpoint.ServicePort.Port)
+
+		workload := workloads[endpoint.IP]
+		if workload == nil {
+			workload = map[string]interface{}{
+				"address": endpoint.IP,
+				"ports":   map[string]interface{}{},
+			}
+			if locality := istioLocality(endpoint); locality != "" {
+				workload["locality"] = locality
+			}
+			if clusterId := endpoint.Attributes[ClusterIdAttr]; clusterId != "" {
+				workload["labels"] = map[string]interface{}{IstioClusterLabel: clusterId}
+			}
+			workloads[endpoint.IP] = workload
+		}
+		workload["ports"].(map[string]interface{})[portName] = int64(endpoint.EndpointPort.Port)
+	}
+
+	portNames := make([]string, 0, len(ports))
+	for portName := range ports {
+		portNames = append(portNames, portName)
+	}
+	sort.Strings(portNames)
+	specPorts := make([]interface{}, 0, len(portNames))
+	for _, portName := range portNames {
+		specPorts = append(specPorts, map[string]interface{}{
+			"name":     portName,
+			"number":   ports[portName],
+			"protocol": model.TCPProtocol,
+		})
+	}
+
+	ips := make([]string, 0, len(workloads))
+	for ip := range workloads {
+		ips = append(ips, ip)
+	}
+	sort.Strings(ips)
+	specEndpoints := make([]interface{}, 0, len(ips))
+	for _, ip := range ips {
+		specEndpoints = append(specEndpoints, workloads[ip])
+	}
+
+	serviceEntry := &unstructured.Unstructured{Object: map[string]interface{}{
+		"spec": map[string]interface{}{
+			"hosts":      []interface{}{config.Host(svcImport.Namespace, svcImport.Name)},
+			"location":   "MESH_INTERNAL",
+			"resolution": "STATIC",
+			"ports":      specPorts,
+			"endpoints":  specEndpoints,
+		},
+	}}
+	serviceEntry.SetGroupVersionKind(ServiceEntryGVK)
+	serviceEntry.SetNamespace(svcImport.Namespace)
+	serviceEntry.SetName(svcImport.Name)
+	serviceEntry.SetOwnerReferences([]metav1.OwnerReference{*metav1.NewControllerRef(svcImport,
+		v1alpha1.GroupVersion.WithKind("ServiceImport"))})
+	return serviceEntry
+}
+
+// istioPortName returns the name of the service port, or a name derived from its number if unnamed, as Istio requires
+// named ports.
+func istioPortName(port model.Port) string {
+	if port.Name != "" {
+		return port.Name
+	}
+	return fmt.Sprintf("%s-%d", strings.ToLower(port.Protocol), port.Port)
+}
+
+// istioLocality returns the "<region>/<zone>" locality of the endpoint, or an empty locality if the region is unknown.
+func istioLocality(endpoint *model.Endpoint) string {
+	region, zone := endpoint.Attributes[RegionAttr], endpoint.Zone
+	if region == "" {
+		return ""
+	}
+	if zone == "" {
+		return region
+	}
+	return region + "/" + zone
+}
@@ -0,0 +1,55 @@
+package controllers
+
+import (
+	"github.com/aws/aws-cloud-map-mcs-controller-for-k8s/pkg/model"
+	"github.com/aws/aws-cloud-map-mcs-controller-for-k8s/test"
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func TestIstioConfig_Host(t *testing.T) {
+	assert.Equal(t, test.SvcName+"."+test.NsName+".svc.clusterset.local", (&IstioConfig{}).Host(test.NsName, test.SvcName))
+	assert.Equal(t, test.SvcName+"."+test.NsName+".mesh.example",
+		(&IstioConfig{HostSuffix: ".mesh.example."}).Host(test.NsName, test.SvcName))
+}
+
+func TestCreateServiceEntryStruct(t *testing.T) {
+	svcImport := createServiceImportStruct(test.NsName, test.SvcName)
+	endpoint := test.GetTestEndpoint1()
+	endpoint.Attributes[ClusterIdAttr] = test.ClusterId
+	endpoint.Attributes[RegionAttr] = "us-west-2"
+	endpoint.Zone = "us-west-2a"
+	udp := test.GetTestEndpoint2()
+	udp.ServicePort.Protocol = model.UDPProtocol
+
+	serviceEntry := createServiceEntryStruct(svcImport, &IstioConfig{}, []*model.Endpoint{endpoint, udp})
+	assert.Equal(t, ServiceEntryGVK, serviceEntry.GroupVersionKind())
+	assert.Equal(t, test.SvcName, serviceEntry.GetName())
+	assert.Equal(t, "ServiceImport", serviceEntry.GetOwnerReferences()[0].Kind)
+	assert.Equal(t, map[string]interface{}{
+		"hosts":      []interface{}{test.SvcName + "." + test.NsName + ".svc.clusterset.local"},
+		"location":   "MESH_INTERNAL",
+		"resolution": "STATIC",
+		"ports": []interface{}{
+			map[string]interface{}{"name": test.PortName1, "number": int64(test.ServicePort1), "protocol": "TCP"},
+		},
+		"endpoints": []interface{}{
+			map[string]interface{}{
+				"address":  test.EndptIp1,
+				"ports":    map[string]interface{}{test.PortName1: int64(test.Port1)},
+				"locality": "us-west-2/us-west-2a",
+				"labels":   map[string]interface{}{IstioClusterLabel: test.ClusterId},
+			},
+		},
+	}, serviceEntry.Object["spec"], "UDP ports are skipped")
+}
+
+func TestIstioLocality(t *testing.T) {
+	endpoint := test.GetTestEndpoint1()
+	endpoint.Zone = "us-west-2a"
+	assert.Equal(t, "", istioLocality(endpoint), "a zone requires a region")
+	endpoint.Attributes[RegionAttr] = "us-west-2"
+	assert.Equal(t, "us-west-2/us-west-2a", istioLocality(endpoint))
+	endpoint.Zone = ""
+	assert.Equal(t, "us-west-2", istioLocality(endpoint))
+}
@@ -0,0 +1,99 @@
+package controllers
+
+import (
+	"github.com/aws/aws-cloud-map-mcs-controller-for-k8s/pkg/model"
+	"sort"
+)
+
+// packEndpoints combines the endpoints of each address into multi-port endpoints, which are registered as a single
+// Cloud Map instance with the additional ports encoded in its attributes, instead of an instance per port. Only
+// endpoints differing in nothing but their port are combined, e.g. endpoints of ports with different app protocols are
+// not. The encoded ports are limited to the length of an attribute value, further ports start another instance.
+func packEndpoints(endpoints []*model.Endpoint) []*model.Endpoint {
+	groups := make(map[string][]*model.Endpoint)
+	keys := make([]string, 0)
+	result := make([]*model.Endpoint, 0, len(endpoints))
+	for _, endpoint := range endpoints {
+		if endpoint.EndpointPort.Protocol != endpoint.ServicePort.Protocol {
+			// the encoded ports share the protocol
+			result = append(result, endpoint)
+			continue
+		}
+		key := packingKey(endpoint)
+		if _, found := groups[key]; !found {
+			keys = append(keys, key)
+		}
+		groups[key] = append(groups[key], endpoint)
+	}
+
+	for _, key := range keys {
+		group := groups[key]
+		sort.SliceStable(group, func(i, j int) bool {
+			if group[i].ServicePort.Protocol != group[j].ServicePort.Protocol {
+				return group[i].ServicePort.Protocol < group[j].ServicePort.Protocol
+			}
+			return group[i].ServicePort.Port < group[j].ServicePort.Port
+		})
+
+		var packed *model.Endpoint
+		for _, endpoint := range group {
+			mapping := model.PortMapping{ServicePort: endpoint.ServicePort, EndpointPort: endpoint.EndpointPort}
+			if packed != nil {
+				mappings := append(append([]model.PortMapping{}, packed.AdditionalPorts...), mapping)
+				if len(model.EncodePortMappings(mappings)) <= maxAttributeValueLength {
+					packed.AdditionalPorts = mappings
+					continue
+				}
+			}
+			// the endpoint of the first port is the instance, keeping its ID
+			packed = endpoint
+			result = append(result, packed)
+		}
+	}
+	return result
+}
+
+// packingKey identifies the endpoints which only differ in their port.
+func packingKey(endpoint *model.Endpoint) string {
+	portless := *endpoint
+	portless.Id = ""
+	portless.EndpointPort = model.Port{}
+	portless.ServicePort = model.Port{}
+	return portless.String()
+}
+
+// unpackEndpoints expands multi-port endpoints to an endpoint per port. While a cluster switches to multi-port
+// instances, the single-port instances of the ports of a multi-port instance are dropped.
+func unpackEndpoints(endpoints []*model.Endpoint) []*model.Endpoint {
+	packed := make(map[string]bool)
+	for _, endpoint := range endpoints {
+		if len(endpoint.AdditionalPorts) == 0 {
+			continue
+		}
+		for _, expanded := range endpoint.Expand() {
+			packed[clusterEndpointKey(expanded)] = true
+		}
+	}
+
+	result := make([]*model.Endpoint, 0, len(endpoints))
+	for _, endpoint := range endpoints {
+		if len(endpoint.AdditionalPorts) == 0 && packed[clusterEndpointKey(endpoint)] {
+			continue
+		}
+		result = append(result, endpoint.Expand()...)
+	}
+	return result
+}
+
+// clusterEndpointKey identifies an endpoint by its cluster, address and port.
+func clusterEndpointKey(endpoint *model.Endpoint) string {
+	return endpoint.Attributes[ClusterIdAttr] + "/" + endpointKey(endpoint)
+}
+
+// exportedEndpointCount returns the number of endpoints of the instances, counting each port of a multi-port instance.
+func exportedEndpointCount(endpoints []*model.Endpoint) (count int) {
+	for _, endpoint := range endpoints {
+		count += len(endpoint.AdditionalPorts) + 1
+	}
+	return count
+}
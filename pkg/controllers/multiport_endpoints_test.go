@@ -0,0 +1,67 @@
+package controllers
+
+import (
+	"fmt"
+	"github.com/aws/aws-cloud-map-mcs-controller-for-k8s/pkg/model"
+	"github.com/aws/aws-cloud-map-mcs-controller-for-k8s/test"
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func multiPortTestEndpoints(ip string, ports ...int32) []*model.Endpoint {
+	endpoints := make([]*model.Endpoint, 0, len(ports))
+	for _, port := range ports {
+		endpointPort := model.Port{Name: fmt.Sprintf("port-%d", port), Port: port + 1000, Protocol: model.TCPProtocol}
+		endpoints = append(endpoints, &model.Endpoint{
+			Id:           model.EndpointId(test.ClusterId, ip, endpointPort),
+			IP:           ip,
+			EndpointPort: endpointPort,
+			ServicePort: model.Port{Name: endpointPort.Name, Port: port, TargetPort: fmt.Sprint(port + 1000),
+				Protocol: model.TCPProtocol},
+			Attributes: map[string]string{ClusterIdAttr: test.ClusterId},
+		})
+	}
+	return endpoints
+}
+
+func TestPackEndpoints(t *testing.T) {
+	endpoints := append(multiPortTestEndpoints(test.EndptIp1, 81, 80, 82), multiPortTestEndpoints(test.EndptIp2, 80)...)
+	endpoints[2].Attributes[AppProtocolAttr] = "http"
+
+	packed := packEndpoints(endpoints)
+	assert.Len(t, packed, 3)
+	assert.Equal(t, endpoints[1], packed[0], "the lowest port is the instance")
+	assert.Equal(t, []model.PortMapping{{ServicePort: endpoints[0].ServicePort, EndpointPort: endpoints[0].EndpointPort}},
+		packed[0].AdditionalPorts)
+	assert.Equal(t, endpoints[2], packed[1], "endpoints with different attributes aren't combined")
+	assert.Empty(t, packed[1].AdditionalPorts)
+	assert.Equal(t, endpoints[3], packed[2])
+	assert.Equal(t, 4, exportedEndpointCount(packed))
+}
+
+func TestPackEndpoints_AttributeValueLength(t *testing.T) {
+	ports := make([]int32, 0)
+	for port := int32(1); port <= 100; port++ {
+		ports = append(ports, port)
+	}
+
+	packed := packEndpoints(multiPortTestEndpoints(test.EndptIp1, ports...))
+	assert.Greater(t, len(packed), 1)
+	assert.Equal(t, len(ports), exportedEndpointCount(packed))
+	for _, endpoint := range packed {
+		assert.LessOrEqual(t, len(endpoint.GetCloudMapAttributes()[model.EndpointPortsAttr]), maxAttributeValueLength)
+	}
+}
+
+func TestUnpackEndpoints(t *testing.T) {
+	single := multiPortTestEndpoints(test.EndptIp1, 80, 81)
+	multi := packEndpoints(multiPortTestEndpoints(test.EndptIp1, 80, 81))
+	multi[0].Id = "multi-port-instance"
+	other := multiPortTestEndpoints(test.EndptIp2, 80)
+
+	unpacked := unpackEndpoints(append(append(single[1:], multi...), other...))
+	assert.Len(t, unpacked, 3, "single-port instances of the ports of multi-port instances are dropped")
+	assert.Equal(t, "multi-port-instance", unpacked[0].Id)
+	assert.Equal(t, single[1].EndpointPort, unpacked[1].EndpointPort)
+	assert.Equal(t, other[0], unpacked[2])
+}
@@ -0,0 +1,67 @@
+package controllers
+
+import (
+	"context"
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"strings"
+)
+
+const (
+	// NodeProviderIdAttr is the Cloud Map instance attribute holding the provider ID of the node of the endpoint
+	NodeProviderIdAttr = "NODE_PROVIDER_ID"
+	// Ec2InstanceIdAttr is the Cloud Map instance attribute holding the EC2 instance ID of the node of the endpoint
+	Ec2InstanceIdAttr = "EC2_INSTANCE_ID"
+
+	// awsProviderIdPrefix prefixes the provider IDs of EC2 nodes, e.g. "aws:///us-west-2a/i-0123456789abcdef0"
+	awsProviderIdPrefix = "aws://"
+)
+
+// +kubebuilder:rbac:groups="",resources=nodes,verbs=get;list;watch
+
+// nodeAttributes returns the instance attributes of the node of an endpoint, which are empty if node attributes are
+// disabled, or the node is unknown. The attributes of each node are looked up once per export in the cache, with the
+// node reader since nodes aren't in the cache of the manager when it watches specific namespaces.
+func (r *ServiceExportReconciler) nodeAttributes(ctx context.Context, nodename string, cache map[string]map[string]string) map[string]string {
+	if !r.NodeAttributes || nodename == "" {
+		return nil
+	}
+	if attributes, found := cache[nodename]; found {
+		return attributes
+	}
+
+	attributes := make(map[string]string)
+	var reader client.Reader = r.Client
+	if r.NodeReader != nil {
+		reader = r.NodeReader
+	}
+	node := &v1.Node{}
+	if err := reader.Get(ctx, types.NamespacedName{Name: nodename}, node); err != nil {
+		// the endpoints are exported without node attributes
+		if !errors.IsNotFound(err) {
+			r.Log.Error(err, "error getting node of endpoints", "node", nodename)
+		}
+	} else if providerId := node.Spec.ProviderID; providerId != "" {
+		attributes[NodeProviderIdAttr] = providerId
+		if instanceId := ec2InstanceId(providerId); instanceId != "" {
+			attributes[Ec2InstanceIdAttr] = instanceId
+		}
+	}
+	cache[nodename] = attributes
+	return attributes
+}
+
+// ec2InstanceId returns the EC2 instance ID of the provider ID of a node, or an empty ID if the node isn't an EC2
+// instance, e.g. a Fargate node.
+func ec2InstanceId(providerId string) string {
+	if !strings.HasPrefix(providerId, awsProviderIdPrefix) {
+		return ""
+	}
+	instanceId := providerId[strings.LastIndex(providerId, "/")+1:]
+	if !strings.HasPrefix(instanceId, "i-") {
+		return ""
+	}
+	return instanceId
+}
@@ -0,0 +1,57 @@
+package controllers
+
+import (
+	"context"
+	"github.com/aws/aws-cloud-map-mcs-controller-for-k8s/mocks/pkg/cloudmap"
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/assert"
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	"testing"
+)
+
+func TestServiceExportReconciler_NodeAttributes(t *testing.T) {
+	mockController := gomock.NewController(t)
+	defer mockController.Finish()
+
+	node := &v1.Node{
+		ObjectMeta: metav1.ObjectMeta{Name: "node-1"},
+		Spec:       v1.NodeSpec{ProviderID: "aws:///us-west-2a/i-0123456789abcdef0"},
+	}
+	fakeClient := fake.NewClientBuilder().WithScheme(getServiceExportScheme()).WithObjects(node).Build()
+	reconciler := getServiceExportReconciler(t, cloudmap.NewMockServiceDiscoveryClient(mockController), fakeClient)
+	cache := make(map[string]map[string]string)
+
+	assert.Empty(t, reconciler.nodeAttributes(context.TODO(), "node-1", cache), "node attributes are disabled")
+
+	reconciler.NodeAttributes = true
+	assert.Equal(t, map[string]string{
+		NodeProviderIdAttr: "aws:///us-west-2a/i-0123456789abcdef0",
+		Ec2InstanceIdAttr:  "i-0123456789abcdef0",
+	}, reconciler.nodeAttributes(context.TODO(), "node-1", cache))
+	assert.Empty(t, reconciler.nodeAttributes(context.TODO(), "unknown-node", cache))
+	assert.Empty(t, reconciler.nodeAttributes(context.TODO(), "", cache))
+
+	// nodes are read with the node reader when set
+	reconciler.NodeReader = fake.NewClientBuilder().WithScheme(getServiceExportScheme()).WithObjects(&v1.Node{
+		ObjectMeta: metav1.ObjectMeta{Name: "node-2"},
+		Spec:       v1.NodeSpec{ProviderID: "aws:///us-west-2b/i-0fedcba9876543210"},
+	}).Build()
+	assert.Equal(t, "i-0fedcba9876543210", reconciler.nodeAttributes(context.TODO(), "node-2", cache)[Ec2InstanceIdAttr])
+}
+
+func TestEc2InstanceId(t *testing.T) {
+	tests := []struct {
+		providerId string
+		want       string
+	}{
+		{providerId: "aws:///us-west-2a/i-0123456789abcdef0", want: "i-0123456789abcdef0"},
+		{providerId: "aws:///us-west-2a/fargate-ip-192-168-1-1.us-west-2.compute.internal", want: ""},
+		{providerId: "gce://project/us-central1-a/instance-1", want: ""},
+		{providerId: "", want: ""},
+	}
+	for _, tt := range tests {
+		assert.Equal(t, tt.want, ec2InstanceId(tt.providerId), tt.providerId)
+	}
+}
@@ -0,0 +1,153 @@
+package controllers
+
+import (
+	"context"
+	goerrors "errors"
+	cloudmapv1alpha1 "github.com/aws/aws-cloud-map-mcs-controller-for-k8s/pkg/api/cloudmap/v1alpha1"
+	"github.com/aws/aws-cloud-map-mcs-controller-for-k8s/pkg/api/v1alpha1"
+	"github.com/aws/aws-cloud-map-mcs-controller-for-k8s/pkg/common"
+	"github.com/aws/aws-cloud-map-mcs-controller-for-k8s/pkg/model"
+	"github.com/aws/aws-cloud-map-mcs-controller-for-k8s/pkg/registry"
+	"github.com/aws/aws-cloud-map-mcs-controller-for-k8s/pkg/tenancy"
+	"k8s.io/apimachinery/pkg/util/sets"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"time"
+)
+
+// OrphanCollector de-registers the instances registered by the cluster for ServiceExports which don't exist anymore,
+// e.g. ServiceExports deleted while the controller was down, whose finalizer was removed by hand. The cleanup policy
+// of the namespaces applies: Cloud Map namespaces of namespaces retaining their endpoints are left alone, and the
+// services left without instances are tidied up by the empty service policy.
+type OrphanCollector struct {
+	Client   client.Client
+	Log      common.Logger
+	Registry registry.ServiceRegistry
+	// TenancyPolicy restricts the Cloud Map namespaces the collector deletes from, nil permits all
+	TenancyPolicy *tenancy.Policy
+	// NamespaceReader reads the namespaces checked by the tenancy policy bypassing the cache of the manager, the
+	// client is used if nil
+	NamespaceReader client.Reader
+	ClusterConfig   *ClusterConfig
+	// Namespaces restricts the collection to the Cloud Map namespaces of the namespaces, all namespaces if empty
+	Namespaces []string
+	// ClusterId identifies the instances registered by the cluster, instances without cluster ID are never collected
+	ClusterId string
+	// Interval is the interval of the collections
+	Interval time.Duration
+}
+
+// Start collects the orphaned instances every interval, until the context is done.
+func (c *OrphanCollector) Start(ctx context.Context) error {
+	ticker := time.NewTicker(c.Interval)
+	defer ticker.Stop()
+	for {
+		if err := c.Collect(ctx); err != nil {
+			c.Log.Error(err, "unable to collect orphaned Cloud Map instances")
+		}
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+		}
+	}
+}
+
+// Collect de-registers the instances of the cluster from the services of the Cloud Map namespaces of the namespaces of
+// the cluster which no ServiceExport exports.
+func (c *OrphanCollector) Collect(ctx context.Context) error {
+	namespaceNames, err := listNamespaceNames(ctx, c.Client, c.Namespaces)
+	if err != nil {
+		return err
+	}
+
+	cmNamespaces := sets.NewString()
+	// skipped are the Cloud Map namespaces retaining the endpoints of a namespace, or denied to a namespace
+	skipped := sets.NewString()
+	// exported are the Cloud Map services of the ServiceExports, by Cloud Map namespace
+	exported := make(map[string]sets.String)
+	// credentials are the contexts calling each Cloud Map namespace with the IAM role of its first namespace
+	credentials := make(map[string]context.Context)
+	for _, namespaceName := range namespaceNames {
+		settings, err := ResolveSyncSettings(ctx, c.Client, c.ClusterConfig, namespaceName)
+		if err != nil {
+			return err
+		}
+		cmNamespaces.Insert(settings.CloudMapNamespace)
+		if _, ok := credentials[settings.CloudMapNamespace]; !ok {
+			credentials[settings.CloudMapNamespace] = settings.WithCredentials(ctx, namespaceName)
+		}
+		if settings.CleanupPolicy == cloudmapv1alpha1.CleanupPolicyRetain {
+			skipped.Insert(settings.CloudMapNamespace)
+		}
+		err = checkNamespaceTenancy(ctx, namespaceReader(c.Client, c.NamespaceReader), c.Log, c.TenancyPolicy,
+			namespaceName, settings.CloudMapNamespace)
+		if goerrors.Is(err, tenancy.ErrNotPermitted) {
+			skipped.Insert(settings.CloudMapNamespace)
+		} else if err != nil {
+			return err
+		}
+
+		serviceExports := v1alpha1.ServiceExportList{}
+		if err = c.Client.List(ctx, &serviceExports, client.InNamespace(namespaceName)); err != nil {
+			return err
+		}
+		for i := range serviceExports.Items {
+			serviceExport := &serviceExports.Items[i]
+			cmNamespacesOfExport := append(exportedAdditionalNamespaces(serviceExport, settings.CloudMapNamespace),
+				settings.CloudMapNamespace)
+			for _, cmNamespace := range cmNamespacesOfExport {
+				if exported[cmNamespace] == nil {
+					exported[cmNamespace] = sets.NewString()
+				}
+				exported[cmNamespace].Insert(serviceExport.Name)
+			}
+		}
+	}
+
+	for _, cmNamespace := range cmNamespaces.Difference(skipped).List() {
+		if err = c.collectNamespace(credentials[cmNamespace], cmNamespace, exported[cmNamespace]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// collectNamespace de-registers the instances of the cluster from the services of the Cloud Map namespace which
+// aren't exported.
+func (c *OrphanCollector) collectNamespace(ctx context.Context, cmNamespace string, exported sets.String) error {
+	services, err := c.Registry.ListServices(ctx, cmNamespace)
+	if err != nil {
+		return err
+	}
+	for _, svc := range services {
+		if exported.Has(svc.Name) {
+			continue
+		}
+		orphaned := make([]*model.Endpoint, 0)
+		for _, endpoint := range serviceExportEndpoints(svc.Endpoints) {
+			if endpoint.Attributes[ClusterIdAttr] == c.ClusterId {
+				orphaned = append(orphaned, endpoint)
+			}
+		}
+		if len(orphaned) == 0 {
+			continue
+		}
+
+		if err = c.Registry.DeleteEndpoints(ctx, cmNamespace, svc.Name, orphaned); err != nil {
+			return err
+		}
+		c.Log.Info("de-registered orphaned instances from Cloud Map", "cloudMapNamespace", cmNamespace,
+			"name", svc.Name, "instances", len(orphaned))
+
+		applied, err := tidyEmptyService(ctx, c.Registry, c.ClusterConfig.EmptyServicePolicy(), cmNamespace, svc.Name)
+		if err != nil {
+			// the service is tidied up on a best effort basis
+			c.Log.Error(err, "error applying the empty service policy", "cloudMapNamespace", cmNamespace,
+				"name", svc.Name)
+		} else if applied != cloudmapv1alpha1.EmptyServicePolicyKeep {
+			c.Log.Info("applied the empty service policy to the orphaned Cloud Map service",
+				"cloudMapNamespace", cmNamespace, "name", svc.Name, "policy", applied)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,116 @@
+package controllers
+
+import (
+	"context"
+	"github.com/aws/aws-cloud-map-mcs-controller-for-k8s/mocks/pkg/cloudmap"
+	cloudmapv1alpha1 "github.com/aws/aws-cloud-map-mcs-controller-for-k8s/pkg/api/cloudmap/v1alpha1"
+	"github.com/aws/aws-cloud-map-mcs-controller-for-k8s/pkg/api/v1alpha1"
+	cmclient "github.com/aws/aws-cloud-map-mcs-controller-for-k8s/pkg/cloudmap"
+	"github.com/aws/aws-cloud-map-mcs-controller-for-k8s/pkg/common"
+	"github.com/aws/aws-cloud-map-mcs-controller-for-k8s/pkg/model"
+	"github.com/aws/aws-cloud-map-mcs-controller-for-k8s/test"
+	testingLogger "github.com/go-logr/logr/testing"
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/assert"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	"testing"
+)
+
+func TestOrphanCollector_Collect(t *testing.T) {
+	fakeClient := fake.NewClientBuilder().
+		WithScheme(getOrphanScheme()).
+		WithObjects(testServiceExportObj()).
+		Build()
+
+	owned := test.GetTestEndpoint1()
+	owned.Attributes[ClusterIdAttr] = test.ClusterId
+	other := test.GetTestEndpoint2()
+	other.Attributes[ClusterIdAttr] = "other-cluster"
+	exported := test.GetTestEndpoint1()
+	exported.Attributes[ClusterIdAttr] = test.ClusterId
+
+	mockController := gomock.NewController(t)
+	defer mockController.Finish()
+
+	// the instances of the cluster are only de-registered from the service without ServiceExport
+	mock := cloudmap.NewMockServiceDiscoveryClient(mockController)
+	mock.EXPECT().ListServices(gomock.Any(), test.NsName).Return([]*model.Service{
+		{Namespace: test.NsName, Name: test.SvcName, Endpoints: []*model.Endpoint{exported}},
+		{Namespace: test.NsName, Name: "orphan", Endpoints: []*model.Endpoint{owned, other}},
+	}, nil)
+	mock.EXPECT().DeleteEndpoints(gomock.Any(), test.NsName, "orphan", []*model.Endpoint{owned}).Return(nil)
+
+	collector := getOrphanCollector(t, mock, fakeClient)
+	assert.NoError(t, collector.Collect(context.TODO()))
+}
+
+func TestOrphanCollector_RetainPolicy(t *testing.T) {
+	syncConfig := &cloudmapv1alpha1.CloudMapSyncConfig{
+		ObjectMeta: metav1.ObjectMeta{Namespace: test.NsName, Name: cloudmapv1alpha1.CloudMapSyncConfigName},
+		Spec:       cloudmapv1alpha1.CloudMapSyncConfigSpec{CleanupPolicy: cloudmapv1alpha1.CleanupPolicyRetain},
+	}
+	fakeClient := fake.NewClientBuilder().
+		WithScheme(getOrphanScheme()).
+		WithObjects(syncConfig).
+		Build()
+
+	mockController := gomock.NewController(t)
+	defer mockController.Finish()
+
+	// the Cloud Map namespace isn't listed
+	mock := cloudmap.NewMockServiceDiscoveryClient(mockController)
+
+	collector := getOrphanCollector(t, mock, fakeClient)
+	assert.NoError(t, collector.Collect(context.TODO()))
+}
+
+func TestOrphanCollector_NamespaceRole(t *testing.T) {
+	roleArn := "arn:aws:iam::123456789012:role/tenant"
+	syncConfig := &cloudmapv1alpha1.CloudMapSyncConfig{
+		ObjectMeta: metav1.ObjectMeta{Namespace: test.NsName, Name: cloudmapv1alpha1.CloudMapSyncConfigName},
+		Spec:       cloudmapv1alpha1.CloudMapSyncConfigSpec{RoleArn: roleArn},
+	}
+	fakeClient := fake.NewClientBuilder().
+		WithScheme(getOrphanScheme()).
+		WithObjects(syncConfig).
+		Build()
+
+	mockController := gomock.NewController(t)
+	defer mockController.Finish()
+
+	// the Cloud Map namespace is listed with the IAM role of the namespace
+	mock := cloudmap.NewMockServiceDiscoveryClient(mockController)
+	mock.EXPECT().ListServices(gomock.Any(), test.NsName).DoAndReturn(
+		func(ctx context.Context, _ string) ([]*model.Service, error) {
+			role, found := cmclient.NamespaceRoleFromContext(ctx)
+			assert.True(t, found)
+			assert.Equal(t, cmclient.NamespaceRole{Namespace: test.NsName, RoleArn: roleArn}, role)
+			return []*model.Service{}, nil
+		})
+
+	collector := getOrphanCollector(t, mock, fakeClient)
+	collector.ClusterConfig = NewClusterConfig()
+	collector.ClusterConfig.set(&cloudmapv1alpha1.ClusterCloudMapConfigSpec{
+		SyncConfigLimits: cloudmapv1alpha1.SyncConfigLimits{AllowRoleArnOverride: true},
+	})
+	assert.NoError(t, collector.Collect(context.TODO()))
+}
+
+func getOrphanCollector(t *testing.T, mock *cloudmap.MockServiceDiscoveryClient, fakeClient client.Client) *OrphanCollector {
+	return &OrphanCollector{
+		Client:     fakeClient,
+		Log:        common.NewLoggerWithLogr(testingLogger.TestLogger{T: t}),
+		Registry:   mock,
+		Namespaces: []string{test.NsName},
+		ClusterId:  test.ClusterId,
+	}
+}
+
+func getOrphanScheme() *runtime.Scheme {
+	scheme := getServiceExportScheme()
+	scheme.AddKnownTypes(v1alpha1.GroupVersion, &v1alpha1.ServiceExportList{})
+	return scheme
+}
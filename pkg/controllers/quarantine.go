@@ -0,0 +1,137 @@
+package controllers
+
+import (
+	"fmt"
+	"github.com/aws/aws-cloud-map-mcs-controller-for-k8s/pkg/api/v1alpha1"
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"sync"
+	"time"
+)
+
+const (
+	// QuarantinedCondition is the ServiceExport condition type set while the export is quarantined after failing
+	// repeatedly, and retried at the quarantine retry interval only
+	QuarantinedCondition = "Quarantined"
+	// QuarantinedReason is the condition and event reason for exports quarantined after failing repeatedly
+	QuarantinedReason = "RepeatedFailures"
+	// NotQuarantinedReason is the condition reason once a quarantined export succeeded
+	NotQuarantinedReason = "NotQuarantined"
+
+	// DefaultQuarantineThreshold is the default number of consecutive failures after which an export is quarantined
+	DefaultQuarantineThreshold = 10
+	// DefaultQuarantineRetryInterval is the default interval quarantined exports are retried at
+	DefaultQuarantineRetryInterval = 30 * time.Minute
+)
+
+// Quarantine tracks the consecutive failures of each ServiceExport, and quarantines the exports failing past a
+// threshold: they are retried at a slow interval instead of the backoff of the controller, so a pathological export
+// can't consume the workqueue and the Cloud Map quota indefinitely. Changes of a quarantined ServiceExport or its
+// Service reset its failures, and are reconciled right away. Permanent failures aren't counted, they aren't retried.
+// It is safe for concurrent use, and a nil Quarantine quarantines no export.
+type Quarantine struct {
+	threshold     int
+	retryInterval time.Duration
+	mu            sync.Mutex
+	failures      map[types.NamespacedName]exportFailures
+}
+
+// exportFailures are the consecutive failures of a revision of a ServiceExport and its Service.
+type exportFailures struct {
+	revision string
+	count    int
+}
+
+// NewQuarantine creates a quarantine for the exports failing threshold consecutive times, retried at retryInterval.
+func NewQuarantine(threshold int, retryInterval time.Duration) *Quarantine {
+	return &Quarantine{
+		threshold:     threshold,
+		retryInterval: retryInterval,
+		failures:      make(map[types.NamespacedName]exportFailures),
+	}
+}
+
+// Observe records the outcome of an export of the revision of the ServiceExport, failed with the error or succeeded
+// if nil, and returns its consecutive failures and whether it is quarantined. The failures of previous revisions are
+// reset, the changed ServiceExport or Service may export successfully.
+func (q *Quarantine) Observe(name types.NamespacedName, revision string, err error) (int, bool) {
+	if q == nil {
+		return 0, false
+	}
+
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	failures := q.failures[name]
+	if failures.revision != revision {
+		failures = exportFailures{revision: revision}
+	}
+	switch {
+	case err == nil:
+		delete(q.failures, name)
+		return 0, false
+	case classifyError(err) != permanentError:
+		failures.count++
+	}
+	q.failures[name] = failures
+	return failures.count, failures.count >= q.threshold
+}
+
+// exportRevision returns the revision of a ServiceExport and its Service, which changes with the spec of the
+// ServiceExport or any change of the Service.
+func exportRevision(serviceExport *v1alpha1.ServiceExport, service *v1.Service) string {
+	return fmt.Sprintf("%d/%s", serviceExport.Generation, service.ResourceVersion)
+}
+
+// RetryInterval returns the interval quarantined exports are retried at.
+func (q *Quarantine) RetryInterval() time.Duration {
+	if q == nil {
+		return 0
+	}
+	return q.retryInterval
+}
+
+// Forget removes the ServiceExport, which was deleted.
+func (q *Quarantine) Forget(name types.NamespacedName) {
+	if q == nil {
+		return
+	}
+
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	delete(q.failures, name)
+}
+
+// setQuarantinedCondition sets the Quarantined condition of the ServiceExport and emits a warning event once the
+// export is quarantined, and clears the condition once the export succeeds.
+func (r *ServiceExportReconciler) setQuarantinedCondition(serviceExport *v1alpha1.ServiceExport, failures int, quarantined bool) {
+	condition := metav1.Condition{
+		Type:               QuarantinedCondition,
+		Status:             metav1.ConditionFalse,
+		ObservedGeneration: serviceExport.Generation,
+		Reason:             NotQuarantinedReason,
+		Message:            "the export is retried with the backoff of the controller",
+	}
+	if quarantined {
+		condition.Status = metav1.ConditionTrue
+		condition.Reason = QuarantinedReason
+		condition.Message = fmt.Sprintf("the export failed %d consecutive times and is retried every %s until it "+
+			"succeeds, changes of the ServiceExport or its Service are exported right away", failures,
+			r.Quarantine.RetryInterval())
+	}
+
+	current := meta.FindStatusCondition(serviceExport.Status.Conditions, QuarantinedCondition)
+	if current == nil && !quarantined {
+		return
+	}
+	if quarantined && (current == nil || current.Status != metav1.ConditionTrue) {
+		r.Log.Info("quarantining ServiceExport", "namespace", serviceExport.Namespace, "name", serviceExport.Name,
+			"failures", failures)
+		r.Recorder.Event(serviceExport, v1.EventTypeWarning, QuarantinedReason, condition.Message)
+	}
+
+	meta.SetStatusCondition(&serviceExport.Status.Conditions, condition)
+}
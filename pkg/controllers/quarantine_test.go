@@ -0,0 +1,72 @@
+package controllers
+
+import (
+	"errors"
+	"github.com/aws/aws-cloud-map-mcs-controller-for-k8s/pkg/common"
+	"github.com/aws/aws-cloud-map-mcs-controller-for-k8s/test"
+	sdtypes "github.com/aws/aws-sdk-go-v2/service/servicediscovery/types"
+	testingLogger "github.com/go-logr/logr/testing"
+	"github.com/stretchr/testify/assert"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/tools/record"
+	"testing"
+	"time"
+)
+
+func TestQuarantine_Observe(t *testing.T) {
+	quarantine := NewQuarantine(3, time.Hour)
+	name := types.NamespacedName{Namespace: test.NsName, Name: test.SvcName}
+	transient := errors.New("connection reset")
+
+	for want := 1; want < 3; want++ {
+		failures, quarantined := quarantine.Observe(name, "1/100", transient)
+		assert.Equal(t, want, failures)
+		assert.False(t, quarantined)
+	}
+	failures, quarantined := quarantine.Observe(name, "1/100", &sdtypes.InvalidInput{})
+	assert.Equal(t, 2, failures, "permanent failures aren't retried, and not counted")
+	assert.False(t, quarantined)
+
+	failures, quarantined = quarantine.Observe(name, "1/100", transient)
+	assert.Equal(t, 3, failures)
+	assert.True(t, quarantined)
+	assert.Equal(t, time.Hour, quarantine.RetryInterval())
+
+	failures, quarantined = quarantine.Observe(name, "2/100", transient)
+	assert.Equal(t, 1, failures, "changes of the ServiceExport or its Service reset the failures")
+	assert.False(t, quarantined)
+
+	_, quarantined = quarantine.Observe(name, "2/100", nil)
+	assert.False(t, quarantined, "successes release the export")
+
+	var disabled *Quarantine
+	_, quarantined = disabled.Observe(name, "1/100", transient)
+	assert.False(t, quarantined)
+}
+
+func TestServiceExportReconciler_SetQuarantinedCondition(t *testing.T) {
+	recorder := record.NewFakeRecorder(5)
+	reconciler := &ServiceExportReconciler{
+		Log:        common.NewLoggerWithLogr(testingLogger.TestLogger{T: t}),
+		Recorder:   recorder,
+		Quarantine: NewQuarantine(3, time.Hour),
+	}
+	serviceExport := testServiceExportObj()
+
+	reconciler.setQuarantinedCondition(serviceExport, 2, false)
+	assert.Nil(t, meta.FindStatusCondition(serviceExport.Status.Conditions, QuarantinedCondition),
+		"no condition until an export is quarantined")
+
+	reconciler.setQuarantinedCondition(serviceExport, 3, true)
+	reconciler.setQuarantinedCondition(serviceExport, 4, true)
+	condition := meta.FindStatusCondition(serviceExport.Status.Conditions, QuarantinedCondition)
+	assert.Equal(t, metav1.ConditionTrue, condition.Status)
+	assert.Equal(t, QuarantinedReason, condition.Reason)
+	assert.Len(t, recorder.Events, 1, "a single event once the export is quarantined")
+
+	reconciler.setQuarantinedCondition(serviceExport, 0, false)
+	condition = meta.FindStatusCondition(serviceExport.Status.Conditions, QuarantinedCondition)
+	assert.Equal(t, metav1.ConditionFalse, condition.Status)
+}
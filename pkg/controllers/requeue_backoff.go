@@ -0,0 +1,154 @@
+package controllers
+
+import (
+	goerrors "errors"
+	"fmt"
+	"github.com/aws/aws-cloud-map-mcs-controller-for-k8s/pkg/api/v1alpha1"
+	"github.com/aws/aws-cloud-map-mcs-controller-for-k8s/pkg/cloudmap"
+	"github.com/aws/aws-cloud-map-mcs-controller-for-k8s/pkg/tenancy"
+	sdtypes "github.com/aws/aws-sdk-go-v2/service/servicediscovery/types"
+	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sync"
+	"time"
+)
+
+const (
+	// StalledCondition is the ServiceExport condition type set while the export failed permanently, e.g. with invalid
+	// input, and isn't retried until the ServiceExport, its Service or its settings change
+	StalledCondition = "Stalled"
+	// PermanentFailureReason is the Stalled condition reason for exports which failed permanently
+	PermanentFailureReason = "PermanentFailure"
+	// NotStalledReason is the Stalled condition reason once an export didn't fail permanently
+	NotStalledReason = "NotStalled"
+
+	// throttledBackoffBase and throttledBackoffMax bound the backoff of exports throttled by Cloud Map, which needs
+	// time to recover
+	throttledBackoffBase = 30 * time.Second
+	throttledBackoffMax  = 10 * time.Minute
+	// notFoundBackoffBase and notFoundBackoffMax bound the backoff of exports failing on missing resources, e.g. a
+	// Cloud Map namespace still being created, which usually appear shortly
+	notFoundBackoffBase = time.Second
+	notFoundBackoffMax  = 30 * time.Second
+)
+
+// errorClass classifies the errors of an export by how they are retried.
+type errorClass int
+
+const (
+	// transientError is retried with the backoff of the controller
+	transientError errorClass = iota
+	// throttlingError is retried with a long exponential backoff
+	throttlingError
+	// notFoundError is retried with a short exponential backoff
+	notFoundError
+	// permanentError isn't retried, the export is stalled until its inputs change
+	permanentError
+)
+
+// classifyError returns the class of an export error.
+func classifyError(err error) errorClass {
+	var invalidInput *sdtypes.InvalidInput
+	var namespaceNotFound *sdtypes.NamespaceNotFound
+	var serviceNotFound *sdtypes.ServiceNotFound
+	var instanceNotFound *sdtypes.InstanceNotFound
+	switch {
+	case cloudmap.IsThrottlingError(err):
+		return throttlingError
+	case goerrors.Is(err, tenancy.ErrNotPermitted), goerrors.Is(err, ErrAttributeLimitExceeded),
+		goerrors.Is(err, cloudmap.ErrReadOnly), goerrors.Is(err, ErrInvalidSyncConfig), goerrors.As(err, &invalidInput):
+		return permanentError
+	case errors.IsNotFound(err), goerrors.As(err, &namespaceNotFound), goerrors.As(err, &serviceNotFound),
+		goerrors.As(err, &instanceNotFound):
+		return notFoundError
+	default:
+		return transientError
+	}
+}
+
+// requeueBackoff tracks the consecutive throttling and not found failures of each ServiceExport, to requeue them with
+// an exponential backoff of their own instead of the uniform backoff of the controller. It is safe for concurrent use.
+type requeueBackoff struct {
+	mu       sync.Mutex
+	failures map[types.NamespacedName]int
+}
+
+func newRequeueBackoff() *requeueBackoff {
+	return &requeueBackoff{failures: make(map[types.NamespacedName]int)}
+}
+
+// result returns the result of a reconcile which failed with the error, nil if it succeeded. Transient errors are
+// returned to be retried by the controller, permanent errors aren't retried, and throttling and not found errors are
+// requeued after their backoff. A nil requeueBackoff returns all errors but permanent errors.
+func (b *requeueBackoff) result(name types.NamespacedName, result ctrl.Result, err error) (ctrl.Result, error) {
+	class := classifyError(err)
+	if b != nil && (err == nil || class == transientError || class == permanentError) {
+		b.forget(name)
+	}
+	switch {
+	case err == nil:
+		return result, nil
+	case class == permanentError:
+		// retrying cannot succeed until the ServiceExport, its Service or its settings change
+		return result, nil
+	case b == nil || class == transientError:
+		return result, err
+	case class == throttlingError:
+		return ctrl.Result{RequeueAfter: b.next(name, throttledBackoffBase, throttledBackoffMax)}, nil
+	default:
+		return ctrl.Result{RequeueAfter: b.next(name, notFoundBackoffBase, notFoundBackoffMax)}, nil
+	}
+}
+
+// next counts a failure of the ServiceExport, and returns the backoff doubling from base with each consecutive
+// failure up to max.
+func (b *requeueBackoff) next(name types.NamespacedName, base time.Duration, max time.Duration) time.Duration {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	failures := b.failures[name]
+	b.failures[name] = failures + 1
+	backoff := base
+	for i := 0; i < failures && backoff < max; i++ {
+		backoff *= 2
+	}
+	if backoff > max {
+		backoff = max
+	}
+	return backoff
+}
+
+func (b *requeueBackoff) forget(name types.NamespacedName) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	delete(b.failures, name)
+}
+
+// setStalledCondition sets the Stalled condition of the ServiceExport if the export failed permanently, and clears the
+// condition once an export didn't.
+func setStalledCondition(serviceExport *v1alpha1.ServiceExport, err error) {
+	condition := metav1.Condition{
+		Type:               StalledCondition,
+		Status:             metav1.ConditionFalse,
+		ObservedGeneration: serviceExport.Generation,
+		Reason:             NotStalledReason,
+		Message:            "the export is retried until it succeeds",
+	}
+	stalled := err != nil && classifyError(err) == permanentError
+	if stalled {
+		condition.Status = metav1.ConditionTrue
+		condition.Reason = PermanentFailureReason
+		condition.Message = fmt.Sprintf("the export failed permanently and isn't retried until the ServiceExport, "+
+			"its Service or its settings change: %s", err.Error())
+	}
+
+	if meta.FindStatusCondition(serviceExport.Status.Conditions, StalledCondition) == nil && !stalled {
+		return
+	}
+
+	meta.SetStatusCondition(&serviceExport.Status.Conditions, condition)
+}
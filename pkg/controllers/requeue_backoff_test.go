@@ -0,0 +1,96 @@
+package controllers
+
+import (
+	"errors"
+	"fmt"
+	"github.com/aws/aws-cloud-map-mcs-controller-for-k8s/pkg/cloudmap"
+	"github.com/aws/aws-cloud-map-mcs-controller-for-k8s/pkg/tenancy"
+	"github.com/aws/aws-cloud-map-mcs-controller-for-k8s/test"
+	"github.com/aws/aws-sdk-go-v2/aws"
+	sdtypes "github.com/aws/aws-sdk-go-v2/service/servicediscovery/types"
+	"github.com/aws/smithy-go"
+	"github.com/stretchr/testify/assert"
+	k8serrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/types"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"testing"
+	"time"
+)
+
+func TestClassifyError(t *testing.T) {
+	tests := []struct {
+		name string
+		err  error
+		want errorClass
+	}{
+		{name: "transient", err: errors.New("connection reset"), want: transientError},
+		{name: "tenancy", err: fmt.Errorf("denied: %w", tenancy.ErrNotPermitted), want: permanentError},
+		{name: "attribute limit", err: fmt.Errorf("%w: too long", ErrAttributeLimitExceeded), want: permanentError},
+		{name: "read only", err: fmt.Errorf("register: %w", cloudmap.ErrReadOnly), want: permanentError},
+		{name: "invalid input", err: &sdtypes.InvalidInput{Message: aws.String("invalid")}, want: permanentError},
+		{name: "namespace not found", err: fmt.Errorf("create: %w", &sdtypes.NamespaceNotFound{}), want: notFoundError},
+		{name: "k8s not found", err: k8serrors.NewNotFound(schema.GroupResource{Resource: "services"}, test.SvcName),
+			want: notFoundError},
+		{name: "throttled", err: &smithy.GenericAPIError{Code: "ThrottlingException", Message: "Rate exceeded"},
+			want: throttlingError},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, classifyError(tt.err))
+		})
+	}
+}
+
+func TestRequeueBackoff_Result(t *testing.T) {
+	backoff := newRequeueBackoff()
+	name := types.NamespacedName{Namespace: test.NsName, Name: test.SvcName}
+	notFound := &sdtypes.NamespaceNotFound{}
+
+	for _, want := range []time.Duration{time.Second, 2 * time.Second, 4 * time.Second} {
+		result, err := backoff.result(name, ctrl.Result{}, notFound)
+		assert.NoError(t, err)
+		assert.Equal(t, want, result.RequeueAfter, "not found errors back off exponentially")
+	}
+	for i := 0; i < 10; i++ {
+		_, _ = backoff.result(name, ctrl.Result{}, notFound)
+	}
+	result, _ := backoff.result(name, ctrl.Result{}, notFound)
+	assert.Equal(t, notFoundBackoffMax, result.RequeueAfter)
+
+	result, err := backoff.result(name, ctrl.Result{RequeueAfter: time.Minute}, nil)
+	assert.NoError(t, err)
+	assert.Equal(t, time.Minute, result.RequeueAfter)
+	result, _ = backoff.result(name, ctrl.Result{}, notFound)
+	assert.Equal(t, time.Second, result.RequeueAfter, "successes reset the backoff")
+
+	transient := errors.New("connection reset")
+	_, err = backoff.result(name, ctrl.Result{}, transient)
+	assert.Equal(t, transient, err, "transient errors are retried by the controller")
+
+	result, err = backoff.result(name, ctrl.Result{}, &sdtypes.InvalidInput{})
+	assert.NoError(t, err)
+	assert.Equal(t, ctrl.Result{}, result, "permanent errors aren't retried")
+
+	var disabled *requeueBackoff
+	_, err = disabled.result(name, ctrl.Result{}, notFound)
+	assert.Equal(t, notFound, err)
+}
+
+func TestSetStalledCondition(t *testing.T) {
+	serviceExport := testServiceExportObj()
+	setStalledCondition(serviceExport, errors.New("connection reset"))
+	assert.Nil(t, meta.FindStatusCondition(serviceExport.Status.Conditions, StalledCondition),
+		"no condition until an export stalls")
+
+	setStalledCondition(serviceExport, &sdtypes.InvalidInput{Message: aws.String("invalid")})
+	condition := meta.FindStatusCondition(serviceExport.Status.Conditions, StalledCondition)
+	assert.Equal(t, metav1.ConditionTrue, condition.Status)
+	assert.Equal(t, PermanentFailureReason, condition.Reason)
+
+	setStalledCondition(serviceExport, nil)
+	condition = meta.FindStatusCondition(serviceExport.Status.Conditions, StalledCondition)
+	assert.Equal(t, metav1.ConditionFalse, condition.Status)
+}
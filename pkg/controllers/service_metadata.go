@@ -0,0 +1,58 @@
+package controllers
+
+import (
+	"fmt"
+	"github.com/aws/aws-cloud-map-mcs-controller-for-k8s/pkg/api/v1alpha1"
+	"github.com/aws/aws-cloud-map-mcs-controller-for-k8s/pkg/cloudmap"
+	v1 "k8s.io/api/core/v1"
+)
+
+const (
+	// ServiceDescriptionAnnotation sets the description of the Cloud Map service, on the ServiceExport or Service
+	ServiceDescriptionAnnotation = "multicluster.k8s.aws/cloudmap-service-description"
+	// ServiceTagsAnnotation sets comma separated key=value tags of the Cloud Map service, on the ServiceExport or
+	// Service
+	ServiceTagsAnnotation = "multicluster.k8s.aws/cloudmap-service-tags"
+
+	// InvalidServiceMetadataReason is the event reason for invalid Cloud Map service description or tags annotations
+	InvalidServiceMetadataReason = "InvalidServiceMetadata"
+
+	// maxServiceDescriptionLength is the maximum length of Cloud Map service descriptions
+	maxServiceDescriptionLength = 1024
+)
+
+// serviceMetadata returns the Cloud Map service description and tags from the annotations of the ServiceExport, or
+// from the annotations of the Service if the ServiceExport doesn't set them. The tags are rejected if they would
+// exceed the Cloud Map limit of tags per service along with the resource tags configured for the controller.
+func serviceMetadata(serviceExport *v1alpha1.ServiceExport, service *v1.Service, resourceTags map[string]string) (cloudmap.ServiceMetadata, error) {
+	metadata := cloudmap.ServiceMetadata{
+		Description: metadataAnnotation(serviceExport, service, ServiceDescriptionAnnotation),
+	}
+	if len(metadata.Description) > maxServiceDescriptionLength {
+		return cloudmap.ServiceMetadata{}, fmt.Errorf("annotation %s exceeds %d characters",
+			ServiceDescriptionAnnotation, maxServiceDescriptionLength)
+	}
+
+	tags, err := cloudmap.ParseTags(metadataAnnotation(serviceExport, service, ServiceTagsAnnotation))
+	if err == nil {
+		err = cloudmap.ValidateResourceTags(tags)
+	}
+	if err == nil {
+		err = cloudmap.ValidateServiceTags(tags, resourceTags)
+	}
+	if err != nil {
+		return cloudmap.ServiceMetadata{}, fmt.Errorf("invalid annotation %s: %w", ServiceTagsAnnotation, err)
+	}
+	if len(tags) > 0 {
+		metadata.Tags = tags
+	}
+
+	return metadata, nil
+}
+
+func metadataAnnotation(serviceExport *v1alpha1.ServiceExport, service *v1.Service, key string) string {
+	if value, found := serviceExport.Annotations[key]; found {
+		return value
+	}
+	return service.Annotations[key]
+}
@@ -0,0 +1,62 @@
+package controllers
+
+import (
+	"errors"
+	"fmt"
+	"github.com/aws/aws-cloud-map-mcs-controller-for-k8s/pkg/cloudmap"
+	"github.com/stretchr/testify/assert"
+	"strings"
+	"testing"
+)
+
+func TestServiceMetadata(t *testing.T) {
+	serviceExport := testServiceExportObj()
+	service := testServiceObj()
+	metadata, err := serviceMetadata(serviceExport, service, nil)
+	assert.NoError(t, err)
+	assert.True(t, metadata.IsEmpty(), "no annotations")
+
+	service.Annotations = map[string]string{
+		ServiceDescriptionAnnotation: "service description",
+		ServiceTagsAnnotation:        "team=payments",
+	}
+	serviceExport.Annotations = map[string]string{ServiceDescriptionAnnotation: "export description"}
+	metadata, err = serviceMetadata(serviceExport, service, nil)
+	assert.NoError(t, err)
+	assert.Equal(t, cloudmap.ServiceMetadata{
+		Description: "export description",
+		Tags:        map[string]string{"team": "payments"},
+	}, metadata, "ServiceExport annotations take precedence")
+}
+
+func TestServiceMetadata_Invalid(t *testing.T) {
+	service := testServiceObj()
+
+	serviceExport := testServiceExportObj()
+	serviceExport.Annotations = map[string]string{ServiceTagsAnnotation: "team"}
+	_, err := serviceMetadata(serviceExport, service, nil)
+	assert.Error(t, err, "malformed tags")
+
+	serviceExport.Annotations = map[string]string{ServiceTagsAnnotation: cloudmap.ManagedByTag + "=other"}
+	_, err = serviceMetadata(serviceExport, service, nil)
+	assert.Error(t, err, "reserved tag")
+
+	// the annotation tags fit within the limit, but not along with the configured tags
+	tags := make([]string, 0, 40)
+	resourceTags := make(map[string]string, 10)
+	for i := 0; i < 40; i++ {
+		tags = append(tags, fmt.Sprintf("tag%d=value", i))
+		if i < 10 {
+			resourceTags[fmt.Sprintf("resource%d", i)] = "value"
+		}
+	}
+	serviceExport.Annotations = map[string]string{ServiceTagsAnnotation: strings.Join(tags, ",")}
+	_, err = serviceMetadata(serviceExport, service, nil)
+	assert.NoError(t, err)
+	_, err = serviceMetadata(serviceExport, service, resourceTags)
+	assert.True(t, errors.Is(err, cloudmap.ErrTooManyTags), "tag limit along with the resource tags")
+
+	serviceExport.Annotations = map[string]string{ServiceDescriptionAnnotation: strings.Repeat("d", 1025)}
+	_, err = serviceMetadata(serviceExport, service, nil)
+	assert.Error(t, err, "description length")
+}
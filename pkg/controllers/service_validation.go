@@ -0,0 +1,107 @@
+package controllers
+
+import (
+	"context"
+	"fmt"
+	"github.com/aws/aws-cloud-map-mcs-controller-for-k8s/pkg/api/v1alpha1"
+	v1 "k8s.io/api/core/v1"
+	discovery "k8s.io/api/discovery/v1beta1"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+)
+
+const (
+	// ServiceValidReason is the Valid condition reason once the Service of the ServiceExport can be exported
+	ServiceValidReason = "ServiceValid"
+	// ServiceNotFoundReason is the Valid condition reason for ServiceExports without Service
+	ServiceNotFoundReason = "ServiceNotFound"
+	// ServiceWithoutSelectorReason is the Valid condition reason for Services without selector, whose EndpointSlices
+	// aren't exported
+	ServiceWithoutSelectorReason = "ServiceWithoutSelector"
+	// UnsupportedServiceTypeReason is the Valid condition reason for Services of a type which cannot be exported
+	UnsupportedServiceTypeReason = "UnsupportedServiceType"
+)
+
+// validateService returns the Valid condition reason and message if the Service, nil if not found, cannot be exported,
+// and an empty reason if it can. A Service without selector is valid once it has an exported EndpointSlice, e.g. a
+// mirrored EndpointSlice or one managed by hand.
+func (r *ServiceExportReconciler) validateService(ctx context.Context, service *v1.Service) (string, string, error) {
+	if service == nil {
+		return ServiceNotFoundReason, "no Service found for the ServiceExport", nil
+	}
+	if service.Spec.Type == v1.ServiceTypeExternalName {
+		return UnsupportedServiceTypeReason, fmt.Sprintf("Services of type %s cannot be exported",
+			service.Spec.Type), nil
+	}
+	if len(service.Spec.Selector) > 0 {
+		return "", "", nil
+	}
+
+	endpointSlices := discovery.EndpointSliceList{}
+	err := r.Client.List(ctx, &endpointSlices,
+		client.InNamespace(service.Namespace), client.MatchingLabels{discovery.LabelServiceName: service.Name})
+	if err != nil {
+		return "", "", err
+	}
+	for i := range endpointSlices.Items {
+		if r.exportsEndpointSlice(&endpointSlices.Items[i]) {
+			return "", "", nil
+		}
+	}
+	return ServiceWithoutSelectorReason, fmt.Sprintf("the Service has no selector and no exported EndpointSlices, "+
+		"the EndpointSlices of other managers, e.g. %s, are exported if listed as exported EndpointSlice managers",
+		EndpointSliceMirroringManager), nil
+}
+
+// handleInvalid withdraws the endpoints of a ServiceExport whose Service cannot be exported, and reports why in its
+// Valid condition. The ServiceExport is kept, and re-evaluated once its Service is created or changed, and periodically.
+func (r *ServiceExportReconciler) handleInvalid(ctx context.Context, serviceExport *v1alpha1.ServiceExport, reason string, message string) (ctrl.Result, error) {
+	r.Log.Info("ServiceExport is invalid", "namespace", serviceExport.Namespace, "name", serviceExport.Name,
+		"reason", reason, "message", message)
+
+	name := types.NamespacedName{Namespace: serviceExport.Namespace, Name: serviceExport.Name}
+	var err error
+	if controllerutil.ContainsFinalizer(serviceExport, ServiceExportFinalizer) {
+		if err = r.withdrawEndpoints(ctx, serviceExport); err == nil {
+			r.ExportStates.Forget(name)
+			r.syncLag.Forget(name.String())
+		}
+	}
+
+	originalStatus := serviceExport.Status.DeepCopy()
+	setValidCondition(serviceExport, reason, message)
+	deferred, statusErr := r.updateStatus(ctx, serviceExport, originalStatus)
+	if statusErr != nil && err == nil {
+		return ctrl.Result{}, statusErr
+	}
+
+	result := ctrl.Result{RequeueAfter: nextRequeue(deferred, r.syncInterval(serviceExport))}
+	return r.backoff.result(name, result, err)
+}
+
+// setValidCondition sets the Valid condition of the ServiceExport to false with the reason and message if its Service
+// cannot be exported, and to true once an empty reason reports that it can.
+func setValidCondition(serviceExport *v1alpha1.ServiceExport, reason string, message string) {
+	condition := metav1.Condition{
+		Type:               string(v1alpha1.ServiceExportValid),
+		Status:             metav1.ConditionTrue,
+		ObservedGeneration: serviceExport.Generation,
+		Reason:             ServiceValidReason,
+		Message:            "the Service can be exported",
+	}
+	if reason != "" {
+		condition.Status = metav1.ConditionFalse
+		condition.Reason = reason
+		condition.Message = message
+	}
+
+	if meta.FindStatusCondition(serviceExport.Status.Conditions, condition.Type) == nil && reason == "" {
+		return
+	}
+
+	meta.SetStatusCondition(&serviceExport.Status.Conditions, condition)
+}
@@ -0,0 +1,115 @@
+package controllers
+
+import (
+	"context"
+	"github.com/aws/aws-cloud-map-mcs-controller-for-k8s/mocks/pkg/cloudmap"
+	"github.com/aws/aws-cloud-map-mcs-controller-for-k8s/pkg/api/v1alpha1"
+	"github.com/aws/aws-cloud-map-mcs-controller-for-k8s/pkg/model"
+	"github.com/aws/aws-cloud-map-mcs-controller-for-k8s/test"
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/assert"
+	v1 "k8s.io/api/core/v1"
+	discovery "k8s.io/api/discovery/v1beta1"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	"testing"
+	"time"
+)
+
+func TestServiceExportReconciler_ValidateService(t *testing.T) {
+	withSelector := testServiceObj()
+	withSelector.Spec.Selector = map[string]string{"app": test.SvcName}
+	externalName := testServiceObj()
+	externalName.Spec.Type = v1.ServiceTypeExternalName
+	mirrored := testEndpointSliceObj()
+	mirrored.Items[0].Labels[discovery.LabelManagedBy] = EndpointSliceMirroringManager
+
+	tests := []struct {
+		name       string
+		service    *v1.Service
+		slices     *discovery.EndpointSliceList
+		managers   []string
+		wantReason string
+	}{
+		{name: "not found", wantReason: ServiceNotFoundReason},
+		{name: "external name", service: externalName, wantReason: UnsupportedServiceTypeReason},
+		{name: "selector", service: withSelector, slices: &discovery.EndpointSliceList{}},
+		{name: "no selector and no slices", service: testServiceObj(), slices: &discovery.EndpointSliceList{},
+			wantReason: ServiceWithoutSelectorReason},
+		{name: "no selector and slices managed by hand", service: testServiceObj(), slices: testEndpointSliceObj()},
+		{name: "no selector and mirrored slices", service: testServiceObj(), slices: mirrored,
+			wantReason: ServiceWithoutSelectorReason},
+		{name: "no selector and exported mirrored slices", service: testServiceObj(), slices: mirrored,
+			managers: []string{EndpointSliceMirroringManager}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			builder := fake.NewClientBuilder().WithScheme(getServiceExportScheme())
+			if tt.slices != nil {
+				builder = builder.WithLists(tt.slices)
+			}
+			reconciler := getServiceExportReconciler(t, cloudmap.NewMockServiceDiscoveryClient(gomock.NewController(t)),
+				builder.Build())
+			reconciler.EndpointSliceManagers = tt.managers
+
+			reason, _, err := reconciler.validateService(context.TODO(), tt.service)
+			assert.NoError(t, err)
+			assert.Equal(t, tt.wantReason, reason)
+		})
+	}
+}
+
+func TestServiceExportReconciler_Reconcile_UnsupportedServiceType(t *testing.T) {
+	service := testServiceObj()
+	service.Spec.Type = v1.ServiceTypeExternalName
+	serviceExportObj := testServiceExportObj()
+	serviceExportObj.Finalizers = []string{ServiceExportFinalizer}
+	fakeClient := fake.NewClientBuilder().
+		WithScheme(getServiceExportScheme()).
+		WithObjects(service, serviceExportObj).
+		Build()
+
+	mockController := gomock.NewController(t)
+	defer mockController.Finish()
+
+	mock := cloudmap.NewMockServiceDiscoveryClient(mockController)
+	// the endpoints exported before the Service type changed are withdrawn
+	mock.EXPECT().GetService(gomock.Any(), test.NsName, test.SvcName).
+		Return(test.GetTestService(), nil)
+	mock.EXPECT().DeleteEndpoints(gomock.Any(), test.NsName, test.SvcName,
+		[]*model.Endpoint{test.GetTestEndpoint1(), test.GetTestEndpoint2()}).Return(nil).Times(1)
+
+	reconciler := getServiceExportReconciler(t, mock, fakeClient)
+	reconciler.ResyncPeriod = time.Hour
+
+	name := types.NamespacedName{Namespace: test.NsName, Name: test.SvcName}
+	got, err := reconciler.Reconcile(context.Background(), ctrl.Request{NamespacedName: name})
+	assert.NoError(t, err)
+	assert.Equal(t, time.Hour, got.RequeueAfter, "invalid exports are re-evaluated periodically")
+
+	serviceExport := &v1alpha1.ServiceExport{}
+	assert.NoError(t, fakeClient.Get(context.TODO(), name, serviceExport))
+	assert.Equal(t, []string{ServiceExportFinalizer}, serviceExport.Finalizers)
+	condition := meta.FindStatusCondition(serviceExport.Status.Conditions, string(v1alpha1.ServiceExportValid))
+	assert.Equal(t, metav1.ConditionFalse, condition.Status)
+	assert.Equal(t, UnsupportedServiceTypeReason, condition.Reason)
+}
+
+func TestSetValidCondition(t *testing.T) {
+	serviceExport := testServiceExportObj()
+	setValidCondition(serviceExport, "", "")
+	assert.Empty(t, serviceExport.Status.Conditions, "no condition until a ServiceExport is invalid")
+
+	setValidCondition(serviceExport, ServiceNotFoundReason, "no Service found for the ServiceExport")
+	condition := meta.FindStatusCondition(serviceExport.Status.Conditions, string(v1alpha1.ServiceExportValid))
+	assert.Equal(t, metav1.ConditionFalse, condition.Status)
+	assert.Equal(t, ServiceNotFoundReason, condition.Reason)
+
+	setValidCondition(serviceExport, "", "")
+	condition = meta.FindStatusCondition(serviceExport.Status.Conditions, string(v1alpha1.ServiceExportValid))
+	assert.Equal(t, metav1.ConditionTrue, condition.Status)
+	assert.Equal(t, ServiceValidReason, condition.Reason)
+}
@@ -18,15 +18,26 @@ package controllers
 
 import (
 	"context"
+	goerrors "errors"
 	"fmt"
+	cloudmapv1alpha1 "github.com/aws/aws-cloud-map-mcs-controller-for-k8s/pkg/api/cloudmap/v1alpha1"
 	"github.com/aws/aws-cloud-map-mcs-controller-for-k8s/pkg/cloudmap"
 	"github.com/aws/aws-cloud-map-mcs-controller-for-k8s/pkg/common"
+	"github.com/aws/aws-cloud-map-mcs-controller-for-k8s/pkg/events"
+	"github.com/aws/aws-cloud-map-mcs-controller-for-k8s/pkg/metrics"
 	"github.com/aws/aws-cloud-map-mcs-controller-for-k8s/pkg/model"
+	"github.com/aws/aws-cloud-map-mcs-controller-for-k8s/pkg/registry"
+	"github.com/aws/aws-cloud-map-mcs-controller-for-k8s/pkg/replication"
+	"github.com/aws/aws-cloud-map-mcs-controller-for-k8s/pkg/tenancy"
 	"github.com/aws/aws-cloud-map-mcs-controller-for-k8s/pkg/version"
 	v1 "k8s.io/api/core/v1"
 	discovery "k8s.io/api/discovery/v1beta1"
+	"k8s.io/apimachinery/pkg/api/equality"
 	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/tools/record"
 	"sigs.k8s.io/controller-runtime/pkg/builder"
 	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
 	"sigs.k8s.io/controller-runtime/pkg/event"
@@ -34,6 +45,10 @@ import (
 	"sigs.k8s.io/controller-runtime/pkg/predicate"
 	"sigs.k8s.io/controller-runtime/pkg/reconcile"
 	"sigs.k8s.io/controller-runtime/pkg/source"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
 
 	"k8s.io/apimachinery/pkg/runtime"
 	ctrl "sigs.k8s.io/controller-runtime"
@@ -46,6 +61,46 @@ const (
 	K8sVersionAttr            = "K8S_CONTROLLER"
 	ServiceExportFinalizer    = "multicluster.k8s.aws/service-export-finalizer"
 	EndpointSliceServiceLabel = "kubernetes.io/service-name"
+
+	// ClusterIdAttr is the Cloud Map instance attribute identifying the cluster which registered the instance
+	ClusterIdAttr = "CLUSTER_ID"
+	// ClusterSetIdAttr is the Cloud Map instance attribute identifying the clusterset of the registering cluster
+	ClusterSetIdAttr = "CLUSTERSET_ID"
+	// RegionAttr is the Cloud Map instance attribute holding the topology region of the endpoint
+	RegionAttr = "REGION"
+	// AppProtocolAttr is the Cloud Map instance attribute holding the application protocol of the service port
+	AppProtocolAttr = "SERVICE_APP_PROTOCOL"
+
+	// ServiceExportControllerName labels the reconcile metrics of the ServiceExport controller
+	ServiceExportControllerName = "serviceexport"
+
+	// OperationFailedReason is the event reason for Cloud Map operations which reached FAIL status
+	OperationFailedReason = "CloudMapOperationFailed"
+
+	// SyncedCondition is the ServiceExport condition type reporting whether the endpoints are exported to Cloud Map
+	SyncedCondition = "Synced"
+	// SyncedReason is the condition reason for exports which completed successfully
+	SyncedReason = "CloudMapSynced"
+	// SyncFailedReason is the condition reason for exports which failed
+	SyncFailedReason = "CloudMapSyncFailed"
+
+	// ExportedMetadataConflictReason is the Conflict condition reason for conflicting exported labels or annotations
+	ExportedMetadataConflictReason = "ExportedMetadataConflict"
+	// NoConflictReason is the Conflict condition reason once exported labels and annotations are consistent
+	NoConflictReason = "NoConflict"
+
+	// ThrottledCondition is the ServiceExport condition type set while Cloud Map throttling delays the export
+	ThrottledCondition = "Throttled"
+	// ThrottledReason is the condition and event reason for exports delayed by Cloud Map throttling
+	ThrottledReason = "CloudMapThrottled"
+	// NotThrottledReason is the condition reason once an export completed without Cloud Map throttling
+	NotThrottledReason = "CloudMapNotThrottled"
+
+	// TenancyDeniedReason is the condition and event reason for exports denied by the tenancy policy
+	TenancyDeniedReason = "TenancyPolicyDenied"
+
+	// DeregisteredReason is the event reason for the de-registration of the endpoints of a deleted ServiceExport
+	DeregisteredReason = "CloudMapDeregistered"
 )
 
 // ServiceExportReconciler reconciles a ServiceExport object
@@ -53,13 +108,63 @@ type ServiceExportReconciler struct {
 	Client   client.Client
 	Log      common.Logger
 	Scheme   *runtime.Scheme
-	CloudMap cloudmap.ServiceDiscoveryClient
+	Registry registry.ServiceRegistry
+	Recorder record.EventRecorder
+
+	// TenancyPolicy restricts the Cloud Map namespaces exports may publish into and delete from, nil permits all
+	TenancyPolicy *tenancy.Policy
+	// NamespaceReader reads the namespaces checked by the tenancy policy bypassing the cache of the manager, which
+	// can't read cluster scoped objects when restricted to the watched namespaces, the client is used if nil
+	NamespaceReader client.Reader
+	// ClusterConfig provides the cluster wide settings, the defaults apply if nil
+	ClusterConfig *ClusterConfig
+	// ClusterId and ClusterSetId identify the cluster in the attributes of registered instances, omitted if empty
+	ClusterId    string
+	ClusterSetId string
+	// ResourceTags are the additional tags of the created Cloud Map services, which count against the Cloud Map limit
+	// of tags per service along with the service tags annotations
+	ResourceTags map[string]string
+
+	// SlowReconcileThreshold is the total reconcile time above which the per-phase timings are logged, 0 disables it
+	SlowReconcileThreshold time.Duration
+	// ExportStates records the outcome of the last export of each service for debugging, nothing is recorded if nil
+	ExportStates *ExportStates
+	// Publisher publishes clusterset events for external automation, nothing is published if nil
+	Publisher *events.Publisher
+	// AttributeLimitPolicy handles instance attributes exceeding the Cloud Map limits, the fail policy applies if empty
+	AttributeLimitPolicy AttributeLimitPolicy
+	// NodeAttributes adds the provider ID and EC2 instance ID of the node of each endpoint to its instance attributes
+	NodeAttributes bool
+	// NodeReader reads the nodes of the endpoints bypassing the cache of the manager, which only holds the watched
+	// namespaces, the client is used if nil
+	NodeReader client.Reader
+	// MultiPortInstances registers the ports of an endpoint as a single Cloud Map instance instead of an instance
+	// per port
+	MultiPortInstances bool
+	// EndpointSliceManagers are the managers of EndpointSlices exported besides the EndpointSlice controller, e.g.
+	// EndpointSliceMirroringManager for Services without selector, AllEndpointSliceManagers exports all EndpointSlices
+	EndpointSliceManagers []string
+	// StatusBatcher coalesces minor status changes into periodic writes, every change is written if nil
+	StatusBatcher *StatusBatcher
+	// ResyncPeriod is the interval the ServiceExports are periodically re-exported at, overridden by the
+	// SyncIntervalAnnotation, 0 disables the periodic re-export
+	ResyncPeriod time.Duration
+	// Quarantine retries the exports failing repeatedly at a slow interval, no export is quarantined if nil
+	Quarantine *Quarantine
+
+	// syncLag tracks endpoint changes observed in the cluster that are pending export to Cloud Map
+	syncLag *metrics.LagTracker
+	// backoff requeues the exports failing with throttling and not found errors
+	backoff *requeueBackoff
 }
 
 // +kubebuilder:rbac:groups="",resources=services,verbs=get
 // +kubebuilder:rbac:groups="discovery.k8s.io",resources=endpointslices,verbs=list;watch;create
 // +kubebuilder:rbac:groups=multicluster.x-k8s.io,resources=serviceexports,verbs=get;list;watch;update;patch
 // +kubebuilder:rbac:groups=multicluster.x-k8s.io,resources=serviceexports/finalizers,verbs=get;update
+// +kubebuilder:rbac:groups=multicluster.x-k8s.io,resources=serviceexports/status,verbs=get;update;patch
+// +kubebuilder:rbac:groups="",resources=events,verbs=create;patch
+// +kubebuilder:rbac:groups="",resources=namespaces,verbs=get
 
 func (r *ServiceExportReconciler) Reconcile(ctx context.Context, req ctrl.Request) (ctrl.Result, error) {
 	ctx, cancel := context.WithCancel(ctx)
@@ -74,30 +179,43 @@ func (r *ServiceExportReconciler) Reconcile(ctx context.Context, req ctrl.Reques
 		return ctrl.Result{}, client.IgnoreNotFound(err)
 	}
 
+	ctx = cloudmap.WithAuditTrigger(ctx, "ServiceExport/"+req.NamespacedName.String())
+
+	timer := metrics.NewPhaseTimer()
+	ctx = metrics.WithPhaseTimer(ctx, timer)
+	defer r.observeReconcilePhases(timer, req)
+
 	// Mark ServiceExport to be deleted, which is indicated by the deletion timestamp being set.
 	isServiceExportMarkedForDelete := serviceExport.GetDeletionTimestamp() != nil
 
-	service := v1.Service{}
+	// Check if the service export is marked to be deleted
+	if isServiceExportMarkedForDelete {
+		return r.handleDelete(ctx, &serviceExport)
+	}
+
+	service := &v1.Service{}
 	namespacedName := types.NamespacedName{Namespace: serviceExport.Namespace, Name: serviceExport.Name}
-	if err := r.Client.Get(ctx, namespacedName, &service); err != nil {
-		if errors.IsNotFound(err) {
-			r.Log.Error(err, "no Service found for ServiceExport",
-				"Namespace", serviceExport.Namespace, "Name", serviceExport.Name)
-			// Mark ServiceExport to be deleted, if the corresponding Service is not found
-			isServiceExportMarkedForDelete = true
-		} else {
+	if err := r.Client.Get(ctx, namespacedName, service); err != nil {
+		if !errors.IsNotFound(err) {
 			r.Log.Error(err, "error fetching service",
 				"Namespace", serviceExport.Namespace, "Name", serviceExport.Name)
 			return ctrl.Result{}, err
 		}
+		service = nil
 	}
 
-	// Check if the service export is marked to be deleted
-	if isServiceExportMarkedForDelete {
-		return r.handleDelete(ctx, &serviceExport)
+	// Keep invalid ServiceExports, re-evaluated once their Service is created or changed
+	reason, message, err := r.validateService(ctx, service)
+	if err != nil {
+		r.Log.Error(err, "error validating service",
+			"Namespace", serviceExport.Namespace, "Name", serviceExport.Name)
+		return ctrl.Result{}, err
+	}
+	if reason != "" {
+		return r.handleInvalid(ctx, &serviceExport, reason, message)
 	}
 
-	return r.handleUpdate(ctx, &serviceExport, &service)
+	return r.handleUpdate(ctx, &serviceExport, service)
 }
 
 func (r *ServiceExportReconciler) handleUpdate(ctx context.Context, serviceExport *v1alpha1.ServiceExport, service *v1.Service) (ctrl.Result, error) {
@@ -105,75 +223,381 @@ func (r *ServiceExportReconciler) handleUpdate(ctx context.Context, serviceExpor
 	// Add the finalizer to the service export if not present, ensures the ServiceExport won't be deleted
 	if !controllerutil.ContainsFinalizer(serviceExport, ServiceExportFinalizer) {
 		controllerutil.AddFinalizer(serviceExport, ServiceExportFinalizer)
-		if err := r.Client.Update(ctx, serviceExport); err != nil {
+		stopStatusUpdate := metrics.PhaseTimerFromContext(ctx).Start(metrics.PhaseStatusUpdate)
+		err := r.Client.Update(ctx, serviceExport)
+		stopStatusUpdate()
+		if err != nil {
 			r.Log.Error(err, "error adding finalizer",
 				"Namespace", serviceExport.Namespace, "Name", serviceExport.Name)
 			return ctrl.Result{}, err
 		}
 	}
 
-	r.Log.Info("updating Cloud Map service", "namespace", service.Namespace, "name", service.Name)
-	cmService, err := r.createOrGetCloudMapService(ctx, service)
+	settings, err := ResolveSyncSettings(ctx, r.Client, r.ClusterConfig, serviceExport.Namespace)
 	if err != nil {
+		r.Log.Error(err, "error resolving sync settings", "namespace", serviceExport.Namespace)
+		if goerrors.Is(err, ErrInvalidSyncConfig) {
+			// exported once the CloudMapSyncConfig is corrected, which enqueues the ServiceExports of its namespace
+			r.Recorder.Event(serviceExport, v1.EventTypeWarning, InvalidSyncConfigReason, err.Error())
+		}
+		name := types.NamespacedName{Namespace: serviceExport.Namespace, Name: serviceExport.Name}
+		return r.backoff.result(name, ctrl.Result{}, err)
+	}
+	ctx = settings.WithCredentials(ctx, serviceExport.Namespace)
+
+	originalStatus := serviceExport.Status.DeepCopy()
+	if err := r.checkTenancy(ctx, serviceExport, settings.CloudMapNamespace); err != nil {
+		if !goerrors.Is(err, tenancy.ErrNotPermitted) {
+			return ctrl.Result{}, err
+		}
+		// retrying cannot succeed until the policy or the namespace changes
+		r.Recorder.Event(serviceExport, v1.EventTypeWarning, TenancyDeniedReason, err.Error())
+		r.setSyncedCondition(serviceExport, err)
+		setStalledCondition(serviceExport, err)
+		deferred, statusErr := r.updateStatus(ctx, serviceExport, originalStatus)
+		return ctrl.Result{RequeueAfter: deferred}, statusErr
+	}
+
+	ctx, throttle := cloudmap.WithThrottleTracker(ctx)
+	ctx = r.withSyncProgress(ctx, serviceExport, originalStatus)
+	result, err := r.exportService(ctx, serviceExport, service, settings)
+	if err == nil {
+		err = r.exportAdditionalNamespaces(ctx, serviceExport, service, settings)
+	}
+
+	throttled := throttle.Count()
+	if cloudmap.IsThrottlingError(err) {
+		throttled++
+	}
+	setValidCondition(serviceExport, "", "")
+	r.setSyncedCondition(serviceExport, err)
+	r.setThrottledCondition(serviceExport, throttled)
+	r.completeSyncingCondition(serviceExport, err)
+	setStalledCondition(serviceExport, err)
+	name := types.NamespacedName{Namespace: serviceExport.Namespace, Name: serviceExport.Name}
+	failures, quarantined := r.Quarantine.Observe(name, exportRevision(serviceExport, service), err)
+	r.setQuarantinedCondition(serviceExport, failures, quarantined)
+	deferred, statusErr := r.updateStatus(ctx, serviceExport, originalStatus)
+	if statusErr != nil && err == nil {
+		return ctrl.Result{}, statusErr
+	}
+	if quarantined {
+		// events of the ServiceExport and its Service are still reconciled right away
+		return ctrl.Result{RequeueAfter: r.Quarantine.RetryInterval()}, nil
+	}
+	// the deferred status is written by the requeued reconcile, which also re-exports the service periodically
+	result.RequeueAfter = nextRequeue(result.RequeueAfter, deferred, r.syncInterval(serviceExport))
+
+	return r.backoff.result(name, result, err)
+}
+
+// exportChanges applies the canary weight, the migration attributes and the attribute limit policy to the endpoints
+// extracted for the ServiceExport, and returns the plan reconciling the current endpoints in Cloud Map with them, the
+// changes of the plan the sync policy permits, and the number of changes it withholds.
+func (r *ServiceExportReconciler) exportChanges(serviceExport *v1alpha1.ServiceExport, current []*model.Endpoint, desired []*model.Endpoint) (plan model.Plan, changes model.Changes, withheld int, err error) {
+	setCanaryWeight(serviceExport, desired)
+	setMigrationAttributes(serviceExport, desired)
+	if err = r.enforceAttributeLimits(serviceExport, desired); err != nil {
+		return plan, changes, 0, err
+	}
+
+	plan = model.Plan{Current: current, Desired: desired}
+	changes, withheld = allowedChanges(serviceExport.Spec.SyncPolicy, plan.CalculateChanges())
+	return plan, changes, withheld, nil
+}
+
+// exportService synchronizes the endpoints of the service to Cloud Map.
+func (r *ServiceExportReconciler) exportService(ctx context.Context, serviceExport *v1alpha1.ServiceExport, service *v1.Service, settings SyncSettings) (ctrl.Result, error) {
+	timer := metrics.PhaseTimerFromContext(ctx)
+	if settings.DNSTTL != nil {
+		ctx = cloudmap.WithDnsTTL(ctx, *settings.DNSTTL)
+	}
+
+	metadata, err := serviceMetadata(serviceExport, service, r.ResourceTags)
+	if err != nil {
+		// the service is exported without description and tags
+		r.Log.Info("ignoring invalid Cloud Map service metadata", "namespace", service.Namespace,
+			"name", service.Name, "reason", err.Error())
+		r.Recorder.Event(serviceExport, v1.EventTypeWarning, InvalidServiceMetadataReason, err.Error())
+	}
+	ctx = cloudmap.WithServiceMetadata(ctx, metadata)
+
+	if _, err = instanceAttributes(serviceExport, service); err != nil {
+		// the endpoints are registered without custom attributes
+		r.Log.Info("ignoring invalid Cloud Map instance attributes", "namespace", service.Namespace,
+			"name", service.Name, "reason", err.Error())
+		r.Recorder.Event(serviceExport, v1.EventTypeWarning, InvalidInstanceAttributesReason, err.Error())
+	}
+
+	cmNamespace := settings.CloudMapNamespace
+	r.Log.Info("updating Cloud Map service", "namespace", service.Namespace, "name", service.Name,
+		"cloudMapNamespace", cmNamespace)
+	stopFetch := timer.Start(metrics.PhaseFetchEndpoints)
+	cmService, err := r.createOrGetCloudMapService(ctx, cmNamespace, service.Name)
+	if err != nil {
+		stopFetch()
 		r.Log.Error(err, "error fetching service from Cloud Map",
 			"namespace", service.Namespace, "name", service.Name)
 		return ctrl.Result{}, err
 	}
 
-	endpoints, err := r.extractEndpoints(ctx, service)
+	if !metadata.IsEmpty() {
+		err = registry.UpdateServiceMetadata(ctx, r.Registry, cmNamespace, service.Name, metadata)
+		if goerrors.Is(err, cloudmap.ErrTooManyTags) {
+			// the tags already on the service leave no room for the service tags, the description is still applied
+			r.Recorder.Event(serviceExport, v1.EventTypeWarning, InvalidServiceMetadataReason, err.Error())
+			metadata.Tags = nil
+			err = registry.UpdateServiceMetadata(ctx, r.Registry, cmNamespace, service.Name, metadata)
+		}
+		if err != nil {
+			stopFetch()
+			r.Log.Error(err, "error updating Cloud Map service metadata",
+				"namespace", service.Namespace, "name", service.Name)
+			return ctrl.Result{}, err
+		}
+	}
+
+	if err = r.correctDnsConfig(ctx, serviceExport, cmNamespace, service.Name); err != nil {
+		stopFetch()
+		r.Log.Error(err, "error correcting Cloud Map service DNS configuration",
+			"namespace", service.Namespace, "name", service.Name)
+		return ctrl.Result{}, err
+	}
+
+	endpoints, err := r.extractEndpoints(ctx, serviceExport, service, settings)
+	stopFetch()
 	if err != nil {
 		r.Log.Error(err, "error extracting endpoints",
 			"Namespace", serviceExport.Namespace, "Name", serviceExport.Name)
 		return ctrl.Result{}, err
 	}
+	now := time.Now()
+	requeueAfter := nextRequeue(r.advanceCanary(serviceExport, endpoints, now),
+		r.advanceMigration(serviceExport, endpoints, now))
 
 	// Compute diff between Cloud Map and K8s endpoints, and apply changes
-	plan := model.Plan{
-		Current: cmService.Endpoints,
-		Desired: endpoints,
+	stopDiff := timer.Start(metrics.PhaseDiff)
+	plan, changes, withheld, err := r.exportChanges(serviceExport, serviceExportEndpoints(cmService.Endpoints), endpoints)
+	if err != nil {
+		stopDiff()
+		r.Log.Error(err, "instance attributes exceed the Cloud Map limits",
+			"namespace", service.Namespace, "name", service.Name)
+		setFailedEndpoints(serviceExport, endpoints, err)
+		return ctrl.Result{}, err
 	}
-	changes := plan.CalculateChanges()
+	if withheld > 0 {
+		r.Log.Info("withholding changes under the sync policy", "namespace", service.Namespace,
+			"name", service.Name, "syncPolicy", serviceExport.Spec.SyncPolicy, "withheld", withheld)
+	}
+	r.setConflictCondition(serviceExport, exportedMetadataConflicts(cmService.Endpoints, endpoints))
+	r.setDrainedCondition(serviceExport, cmService.Endpoints)
+	stopDiff()
+	serviceName := types.NamespacedName{Namespace: service.Namespace, Name: service.Name}
+	exportState := newExportState(serviceName, cmNamespace, plan, changes)
+	syncLagKey := serviceName.String()
 
 	if changes.HasUpdates() {
 		// merge creates and updates (Cloud Map RegisterEndpoints can handle both)
 		upserts := changes.Create
 		upserts = append(upserts, changes.Update...)
 
-		if err := r.CloudMap.RegisterEndpoints(ctx, service.Namespace, service.Name, upserts); err != nil {
+		if err := r.Registry.RegisterEndpoints(ctx, cmNamespace, service.Name, upserts); err != nil {
 			r.Log.Error(err, "error registering endpoints to Cloud Map",
 				"namespace", service.Namespace, "name", service.Name)
+			r.recordOperationFailures(serviceExport, err)
+			r.ExportStates.Record(exportState.failed(err))
+			setFailedEndpoints(serviceExport, upserts, err)
 			return ctrl.Result{}, err
 		}
+		r.observeSyncLag(syncLagKey, metrics.ExportOperationRegister, !changes.HasDeletes())
+		if len(cmService.Endpoints) == 0 {
+			r.unmarkEmptyService(ctx, serviceExport, cmNamespace, service.Name)
+		}
+		r.publishRegistered(ctx, cmNamespace, service.Name, changes.Create,
+			len(r.ownedEndpoints(cmService.Endpoints)) == 0)
 	}
 
+	setFailedEndpoints(serviceExport, endpoints, nil)
+
 	if changes.HasDeletes() {
-		if err := r.CloudMap.DeleteEndpoints(ctx, service.Namespace, service.Name, changes.Delete); err != nil {
+		if err := r.Registry.DeleteEndpoints(ctx, cmNamespace, service.Name, changes.Delete); err != nil {
 			r.Log.Error(err, "error deleting endpoints from Cloud Map",
 				"namespace", cmService.Namespace, "name", cmService.Name)
+			r.recordOperationFailures(serviceExport, err)
+			r.ExportStates.Record(exportState.failed(err))
 			return ctrl.Result{}, err
 		}
+		r.observeSyncLag(syncLagKey, metrics.ExportOperationDeregister, true)
+		r.publishDeregistered(ctx, cmNamespace, service.Name, changes.Delete, len(endpoints) == 0)
+		if len(endpoints) == 0 {
+			r.applyEmptyServicePolicy(ctx, serviceExport, cmNamespace, service.Name)
+		}
 	}
+	r.ExportStates.Record(exportState)
 
 	if changes.IsNone() {
 		r.Log.Info("no changes to export to Cloud Map", "namespace", service.Namespace, "name", service.Name)
+		r.syncLag.Forget(syncLagKey)
 	}
 
-	return ctrl.Result{}, nil
+	serviceExport.Status.Endpoints = int32(exportedEndpointCount(endpoints))
+	serviceExport.Status.CloudMapServiceId = cmService.Id
+
+	return ctrl.Result{RequeueAfter: requeueAfter}, nil
+}
+
+// observeSyncLag records the time since an endpoint change was first observed for the service. The pending change is
+// only cleared once all operations of the reconciliation have completed.
+func (r *ServiceExportReconciler) observeSyncLag(key string, operation string, complete bool) {
+	var lag time.Duration
+	var found bool
+	if complete {
+		lag, found = r.syncLag.Complete(key)
+	} else {
+		lag, found = r.syncLag.Peek(key)
+	}
+
+	if found {
+		metrics.ObserveExportSyncLag(operation, lag)
+	}
 }
 
-func (r *ServiceExportReconciler) createOrGetCloudMapService(ctx context.Context, service *v1.Service) (*model.Service, error) {
-	cmService, err := r.CloudMap.GetService(ctx, service.Namespace, service.Name)
+// observeReconcilePhases records the reconcile phase timings, and logs them if the reconcile exceeded the slow
+// reconcile threshold.
+func (r *ServiceExportReconciler) observeReconcilePhases(timer *metrics.PhaseTimer, req ctrl.Request) {
+	timer.Observe(ServiceExportControllerName)
+
+	total := timer.Total()
+	if r.SlowReconcileThreshold > 0 && total > r.SlowReconcileThreshold {
+		keysAndValues := []interface{}{"namespace", req.Namespace, "name", req.Name, "total", total.String()}
+		r.Log.Info("slow ServiceExport reconcile", append(keysAndValues, timer.KeysAndValues()...)...)
+	}
+}
+
+// setSyncedCondition sets the Synced condition of the ServiceExport from the result of the export.
+func (r *ServiceExportReconciler) setSyncedCondition(serviceExport *v1alpha1.ServiceExport, err error) {
+	condition := metav1.Condition{
+		Type:               SyncedCondition,
+		Status:             metav1.ConditionTrue,
+		ObservedGeneration: serviceExport.Generation,
+		Reason:             SyncedReason,
+		Message:            "endpoints are exported to Cloud Map",
+	}
+	if err != nil {
+		condition.Status = metav1.ConditionFalse
+		condition.Reason = SyncFailedReason
+		condition.Message = err.Error()
+	}
+	if goerrors.Is(err, tenancy.ErrNotPermitted) {
+		condition.Reason = TenancyDeniedReason
+	}
+	if goerrors.Is(err, ErrAttributeLimitExceeded) {
+		condition.Reason = AttributeLimitExceededReason
+	}
+
+	meta.SetStatusCondition(&serviceExport.Status.Conditions, condition)
+}
+
+// setConflictCondition sets the Conflict condition of the ServiceExport if exporting clusters specify conflicting
+// exported labels or annotations, and clears it once the conflict is resolved.
+func (r *ServiceExportReconciler) setConflictCondition(serviceExport *v1alpha1.ServiceExport, conflicts []string) {
+	condition := metav1.Condition{
+		Type:               string(v1alpha1.ServiceExportConflict),
+		Status:             metav1.ConditionFalse,
+		ObservedGeneration: serviceExport.Generation,
+		Reason:             NoConflictReason,
+		Message:            "exported labels and annotations are consistent across exporting clusters",
+	}
+	if len(conflicts) > 0 {
+		condition.Status = metav1.ConditionTrue
+		condition.Reason = ExportedMetadataConflictReason
+		condition.Message = fmt.Sprintf("exporting clusters specify conflicting values for %s, "+
+			"the values of the oldest export are used", strings.Join(conflicts, ", "))
+	}
+
+	if meta.FindStatusCondition(serviceExport.Status.Conditions, condition.Type) == nil && len(conflicts) == 0 {
+		return
+	}
+
+	meta.SetStatusCondition(&serviceExport.Status.Conditions, condition)
+}
+
+// setThrottledCondition sets the Throttled condition of the ServiceExport and emits a warning event if Cloud Map
+// requests were throttled during the export, and clears the condition once an export completes without throttling.
+func (r *ServiceExportReconciler) setThrottledCondition(serviceExport *v1alpha1.ServiceExport, throttled int) {
+	condition := metav1.Condition{
+		Type:               ThrottledCondition,
+		Status:             metav1.ConditionFalse,
+		ObservedGeneration: serviceExport.Generation,
+		Reason:             NotThrottledReason,
+		Message:            "Cloud Map requests were not throttled",
+	}
+	if throttled > 0 {
+		condition.Status = metav1.ConditionTrue
+		condition.Reason = ThrottledReason
+		condition.Message = fmt.Sprintf("%d Cloud Map requests were throttled, export to Cloud Map was delayed", throttled)
+		r.Recorder.Event(serviceExport, v1.EventTypeWarning, ThrottledReason, condition.Message)
+	}
+
+	if meta.FindStatusCondition(serviceExport.Status.Conditions, ThrottledCondition) == nil && throttled == 0 {
+		return
+	}
+
+	meta.SetStatusCondition(&serviceExport.Status.Conditions, condition)
+}
+
+// updateStatus writes the status of the ServiceExport through the status subresource if it differs from the
+// original status. Minor changes are batched by the StatusBatcher, the time until a deferred change may be written is
+// returned, zero if the status was written or unchanged.
+func (r *ServiceExportReconciler) updateStatus(ctx context.Context, serviceExport *v1alpha1.ServiceExport, original *v1alpha1.ServiceExportStatus) (time.Duration, error) {
+	if equality.Semantic.DeepEqual(original, &serviceExport.Status) {
+		return 0, nil
+	}
+	name := types.NamespacedName{Namespace: serviceExport.Namespace, Name: serviceExport.Name}
+	if deferred := r.StatusBatcher.Defer(name, original, &serviceExport.Status); deferred > 0 {
+		r.Log.Debug("deferring ServiceExport status update", "Namespace", serviceExport.Namespace,
+			"Name", serviceExport.Name, "deferred", deferred.String())
+		return deferred, nil
+	}
+
+	defer metrics.PhaseTimerFromContext(ctx).Start(metrics.PhaseStatusUpdate)()
+	if err := r.Client.Status().Update(ctx, serviceExport); err != nil {
+		r.Log.Error(err, "error updating ServiceExport status",
+			"Namespace", serviceExport.Namespace, "Name", serviceExport.Name)
+		return 0, err
+	}
+	r.StatusBatcher.Written(name)
+	return 0, nil
+}
+
+// recordOperationFailures emits a warning event on the ServiceExport for each failed Cloud Map operation.
+func (r *ServiceExportReconciler) recordOperationFailures(serviceExport *v1alpha1.ServiceExport, err error) {
+	var opErr *cloudmap.OperationFailureError
+	if !goerrors.As(err, &opErr) {
+		return
+	}
+
+	for _, failure := range opErr.Failures {
+		r.Recorder.Eventf(serviceExport, v1.EventTypeWarning, OperationFailedReason,
+			"Cloud Map %s operation %s failed with %s: %s",
+			opErr.OperationType, failure.OperationId, failure.ErrorCode, failure.ErrorMessage)
+	}
+}
+
+func (r *ServiceExportReconciler) createOrGetCloudMapService(ctx context.Context, cmNamespace string, name string) (*model.Service, error) {
+	cmService, err := r.Registry.GetService(ctx, cmNamespace, name)
 	if err != nil {
 		return nil, err
 	}
 
 	if cmService == nil {
-		if err := r.CloudMap.CreateService(ctx, service.Namespace, service.Name); err != nil {
+		if err := r.Registry.CreateService(ctx, cmNamespace, name); err != nil {
 			r.Log.Error(err, "error creating a new service in Cloud Map",
-				"namespace", service.Namespace, "name", service.Name)
+				"namespace", cmNamespace, "name", name)
 			return nil, err
 		}
-		if cmService, err = r.CloudMap.GetService(ctx, service.Namespace, service.Name); err != nil {
+		r.Publisher.Publish(ctx, events.ClusterSetEvent{Type: events.ServiceExported, Namespace: cmNamespace, Service: name})
+		if cmService, err = r.Registry.GetService(ctx, cmNamespace, name); err != nil {
 			return nil, err
 		}
 	}
@@ -186,19 +610,9 @@ func (r *ServiceExportReconciler) handleDelete(ctx context.Context, serviceExpor
 
 		r.Log.Info("removing service export", "namespace", serviceExport.Namespace, "name", serviceExport.Name)
 
-		cmService, err := r.CloudMap.GetService(ctx, serviceExport.Namespace, serviceExport.Name)
-		if err != nil {
-			r.Log.Error(err, "error fetching service from Cloud Map",
-				"namespace", serviceExport.Namespace, "name", serviceExport.Name)
+		if err := r.withdrawEndpoints(ctx, serviceExport); err != nil {
 			return ctrl.Result{}, err
 		}
-		if cmService != nil {
-			if err := r.CloudMap.DeleteEndpoints(ctx, cmService.Namespace, cmService.Name, cmService.Endpoints); err != nil {
-				r.Log.Error(err, "error deleting endpoints from Cloud Map",
-					"namespace", cmService.Namespace, "name", cmService.Name)
-				return ctrl.Result{}, err
-			}
-		}
 
 		// Remove finalizer. Once all finalizers have been
 		// removed, the ServiceExport object will be deleted.
@@ -206,47 +620,251 @@ func (r *ServiceExportReconciler) handleDelete(ctx context.Context, serviceExpor
 		if err := r.Client.Update(ctx, serviceExport); err != nil {
 			return ctrl.Result{}, err
 		}
+		r.ExportStates.Forget(types.NamespacedName{Namespace: serviceExport.Namespace, Name: serviceExport.Name})
+		r.StatusBatcher.Forget(types.NamespacedName{Namespace: serviceExport.Namespace, Name: serviceExport.Name})
+		r.Quarantine.Forget(types.NamespacedName{Namespace: serviceExport.Namespace, Name: serviceExport.Name})
+		r.syncLag.Forget(types.NamespacedName{Namespace: serviceExport.Namespace, Name: serviceExport.Name}.String())
 
 	}
 
 	return ctrl.Result{}, nil
 }
 
-func (r *ServiceExportReconciler) extractEndpoints(ctx context.Context, svc *v1.Service) ([]*model.Endpoint, error) {
+// withdrawEndpoints de-registers the endpoints exported by the ServiceExport from Cloud Map, unless the tenancy
+// policy, the cleanup policy or the sync policy retains them.
+func (r *ServiceExportReconciler) withdrawEndpoints(ctx context.Context, serviceExport *v1alpha1.ServiceExport) error {
+	settings, err := ResolveSyncSettings(ctx, r.Client, r.ClusterConfig, serviceExport.Namespace)
+	if err != nil {
+		r.Log.Error(err, "error resolving sync settings", "namespace", serviceExport.Namespace)
+		return err
+	}
+	ctx = settings.WithCredentials(ctx, serviceExport.Namespace)
+
+	deregister := true
+	if err := r.checkTenancy(ctx, serviceExport, settings.CloudMapNamespace); err != nil {
+		if !goerrors.Is(err, tenancy.ErrNotPermitted) {
+			return err
+		}
+		// never delete from Cloud Map namespaces of other tenants, but do not block the deletion of the export
+		r.Log.Info("skipping Cloud Map deregistration", "namespace", serviceExport.Namespace,
+			"name", serviceExport.Name, "reason", err.Error())
+		deregister = false
+	}
+	if settings.CleanupPolicy == cloudmapv1alpha1.CleanupPolicyRetain {
+		r.Log.Info("retaining endpoints in Cloud Map", "namespace", serviceExport.Namespace,
+			"name", serviceExport.Name)
+		deregister = false
+	}
+	if !allowsDeregistration(serviceExport.Spec.SyncPolicy) {
+		r.Log.Info("retaining endpoints in Cloud Map under the sync policy", "namespace", serviceExport.Namespace,
+			"name", serviceExport.Name, "syncPolicy", serviceExport.Spec.SyncPolicy)
+		deregister = false
+	}
+
+	var cmService *model.Service
+	if deregister {
+		if cmService, err = r.Registry.GetService(ctx, settings.CloudMapNamespace, serviceExport.Name); err != nil {
+			r.Log.Error(err, "error fetching service from Cloud Map",
+				"namespace", serviceExport.Namespace, "name", serviceExport.Name)
+			return err
+		}
+	}
+	if cmService != nil {
+		if err := r.deregisterEndpoints(ctx, serviceExport, cmService); err != nil {
+			return err
+		}
+		r.applyEmptyServicePolicy(ctx, serviceExport, cmService.Namespace, cmService.Name)
+	}
+	if settings.CleanupPolicy != cloudmapv1alpha1.CleanupPolicyRetain &&
+		allowsDeregistration(serviceExport.Spec.SyncPolicy) {
+		for _, cmNamespace := range exportedAdditionalNamespaces(serviceExport, settings.CloudMapNamespace) {
+			if err := r.unexportNamespace(ctx, serviceExport, cmNamespace); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// deregisterEndpoints de-registers the instances of the Cloud Map service registered by this cluster, keeping the
+// instances of other clusters, and reports the outcome in a single event on the ServiceExport.
+func (r *ServiceExportReconciler) deregisterEndpoints(ctx context.Context, serviceExport *v1alpha1.ServiceExport, cmService *model.Service) error {
+	owned := r.ownedEndpoints(cmService.Endpoints)
+	start := time.Now()
+	err := r.Registry.DeleteEndpoints(ctx, cmService.Namespace, cmService.Name, owned)
+	if err != nil {
+		r.Log.Error(err, "error deleting endpoints from Cloud Map",
+			"namespace", cmService.Namespace, "name", cmService.Name, "instances", len(owned))
+		var opErr *cloudmap.OperationFailureError
+		if goerrors.As(err, &opErr) {
+			r.Recorder.Eventf(serviceExport, v1.EventTypeWarning, OperationFailedReason,
+				"failed to de-register %d of %d instances from Cloud Map service %s/%s: %s",
+				len(opErr.Failures), len(owned), cmService.Namespace, cmService.Name, operationErrorCodes(opErr))
+		}
+		return err
+	}
+
+	r.Log.Info("de-registered endpoints from Cloud Map", "namespace", cmService.Namespace, "name", cmService.Name,
+		"instances", len(owned), "otherClusterInstances", len(cmService.Endpoints)-len(owned),
+		"duration", time.Since(start))
+	if len(owned) > 0 {
+		r.Recorder.Eventf(serviceExport, v1.EventTypeNormal, DeregisteredReason,
+			"de-registered %d instances from Cloud Map service %s/%s", len(owned), cmService.Namespace, cmService.Name)
+		r.publishDeregistered(ctx, cmService.Namespace, cmService.Name, owned, true)
+	}
+	return nil
+}
+
+// ownedEndpoints returns the endpoints registered by this cluster for the ServiceExport. Instances without a cluster
+// ID attribute were registered before ownership attributes were added and are owned by any cluster.
+func (r *ServiceExportReconciler) ownedEndpoints(endpoints []*model.Endpoint) []*model.Endpoint {
+	owned := make([]*model.Endpoint, 0, len(endpoints))
+	for _, endpoint := range serviceExportEndpoints(endpoints) {
+		if clusterId := endpoint.Attributes[ClusterIdAttr]; clusterId == "" || clusterId == r.ClusterId {
+			owned = append(owned, endpoint)
+		}
+	}
+	return owned
+}
+
+// serviceExportEndpoints returns the endpoints registered for ServiceExports, without the endpoints of
+// CloudMapStaticEndpoints and the endpoints replicated from other regions, which ServiceExports must not de-register.
+func serviceExportEndpoints(endpoints []*model.Endpoint) []*model.Endpoint {
+	result := make([]*model.Endpoint, 0, len(endpoints))
+	for _, endpoint := range withoutStaticEndpoints(endpoints) {
+		if _, replicated := endpoint.Attributes[replication.SourceRegionAttr]; !replicated {
+			result = append(result, endpoint)
+		}
+	}
+	return result
+}
+
+// operationErrorCodes summarizes the error codes of failed Cloud Map operations, e.g. "2x ResourceInUse".
+func operationErrorCodes(opErr *cloudmap.OperationFailureError) string {
+	counts := make(map[string]int)
+	for _, failure := range opErr.Failures {
+		counts[failure.ErrorCode]++
+	}
+	codes := make([]string, 0, len(counts))
+	for code, count := range counts {
+		codes = append(codes, fmt.Sprintf("%dx %s", count, code))
+	}
+	sort.Strings(codes)
+	return strings.Join(codes, ", ")
+}
+
+// checkTenancy returns an error wrapping tenancy.ErrNotPermitted if the tenancy policy does not permit the namespace
+// of the ServiceExport to use its Cloud Map namespace.
+func (r *ServiceExportReconciler) checkTenancy(ctx context.Context, serviceExport *v1alpha1.ServiceExport, cmNamespace string) error {
+	return checkNamespaceTenancy(ctx, namespaceReader(r.Client, r.NamespaceReader), r.Log, r.TenancyPolicy,
+		serviceExport.Namespace, cmNamespace)
+}
+
+// checkNamespaceTenancy returns an error wrapping tenancy.ErrNotPermitted if the tenancy policy does not permit the
+// Kubernetes namespace to use the Cloud Map namespace. A nil policy permits all namespaces.
+func checkNamespaceTenancy(ctx context.Context, c client.Reader, log common.Logger, policy *tenancy.Policy, namespaceName string, cmNamespace string) error {
+	if policy == nil {
+		return nil
+	}
+
+	namespace := &v1.Namespace{}
+	if err := c.Get(ctx, types.NamespacedName{Name: namespaceName}, namespace); err != nil {
+		log.Error(err, "error fetching namespace", "namespace", namespaceName)
+		return err
+	}
+
+	return policy.Permits(namespace, cmNamespace)
+}
+
+// namespaceReader returns the reader of namespaces, or the client if nil.
+func namespaceReader(c client.Client, reader client.Reader) client.Reader {
+	if reader != nil {
+		return reader
+	}
+	return c
+}
+
+func (r *ServiceExportReconciler) extractEndpoints(ctx context.Context, serviceExport *v1alpha1.ServiceExport, svc *v1.Service, settings SyncSettings) ([]*model.Endpoint, error) {
 	result := make([]*model.Endpoint, 0)
 
+	exportAttrs, err := exportedMetadataAttributes(serviceExport)
+	if err != nil {
+		return nil, err
+	}
+	// invalid custom attributes are reported by exportService
+	customAttrs, _ := instanceAttributes(serviceExport, svc)
+
 	endpointSlices := discovery.EndpointSliceList{}
-	err := r.Client.List(ctx, &endpointSlices,
+	err = r.Client.List(ctx, &endpointSlices,
 		client.InNamespace(svc.Namespace), client.MatchingLabels{discovery.LabelServiceName: svc.Name})
 
 	if err != nil {
 		return nil, err
 	}
 
+	nodeAttrs := make(map[string]map[string]string)
 	servicePortMap := make(map[string]model.Port)
+	appProtocols := make(map[string]string)
 	for _, svcPort := range svc.Spec.Ports {
 		servicePortMap[svcPort.Name] = ServicePortToPort(svcPort)
+		if svcPort.AppProtocol != nil {
+			appProtocols[svcPort.Name] = *svcPort.AppProtocol
+		}
 	}
 
 	for _, slice := range endpointSlices.Items {
+		if !r.exportsEndpointSlice(&slice) {
+			continue
+		}
 		if slice.AddressType != discovery.AddressTypeIPv4 {
 			return nil, fmt.Errorf("unsupported address type %s for service %s", slice.AddressType, svc.Name)
 		}
 		for _, endpointPort := range slice.Ports {
+			if endpointPort.Port == nil {
+				// externally managed EndpointSlices may leave the port unspecified, which can't be registered
+				continue
+			}
+			servicePort, found := servicePortMap[endpointPortName(endpointPort)]
+			if !found {
+				// the port was removed from the service
+				continue
+			}
+			// the target port of the service may be a named port, record the container port of the endpoints
+			servicePort.TargetPort = strconv.Itoa(int(*endpointPort.Port))
 			for _, endpoint := range slice.Endpoints {
 				for _, IP := range endpoint.Addresses {
 					attributes := make(map[string]string)
+					for key, value := range customAttrs {
+						attributes[key] = value
+					}
 					if version.GetVersion() != "" {
 						attributes[K8sVersionAttr] = version.PackageName + " " + version.GetVersion()
 					}
-					// TODO extract attributes - pod, node and other useful details if possible
+					for key, value := range exportAttrs {
+						attributes[key] = value
+					}
+					if region := endpoint.Topology[v1.LabelTopologyRegion]; region != "" {
+						attributes[RegionAttr] = region
+					}
+					if appProtocol := appProtocols[endpointPortName(endpointPort)]; appProtocol != "" {
+						attributes[AppProtocolAttr] = appProtocol
+					}
+					for key, value := range r.nodeAttributes(ctx, endpointNodename(endpoint), nodeAttrs) {
+						attributes[key] = value
+					}
+					settings.FilterAttributes(attributes)
+					r.addOwnershipAttributes(attributes)
 
 					port := EndpointPortToPort(endpointPort)
 					result = append(result, &model.Endpoint{
-						Id:           model.EndpointIdFromIPAddressAndPort(IP, port),
+						Id:           model.EndpointId(r.ClusterId, IP, port),
 						IP:           IP,
 						EndpointPort: port,
-						ServicePort:  servicePortMap[*endpointPort.Name],
+						ServicePort:  servicePort,
+						Ready:        endpointReady(endpoint),
+						Hostname:     endpointHostname(endpoint),
+						Nodename:     endpointNodename(endpoint),
+						Zone:         endpoint.Topology[v1.LabelTopologyZone],
 						Attributes:   attributes,
 					})
 				}
@@ -254,12 +872,28 @@ func (r *ServiceExportReconciler) extractEndpoints(ctx context.Context, svc *v1.
 		}
 	}
 
+	if r.MultiPortInstances {
+		return packEndpoints(result), nil
+	}
 	return result, nil
 }
 
+// addOwnershipAttributes adds the instance attributes identifying the registering cluster and clusterset.
+func (r *ServiceExportReconciler) addOwnershipAttributes(attributes map[string]string) {
+	if r.ClusterId != "" {
+		attributes[ClusterIdAttr] = r.ClusterId
+	}
+	if r.ClusterSetId != "" {
+		attributes[ClusterSetIdAttr] = r.ClusterSetId
+	}
+}
+
 func (r *ServiceExportReconciler) SetupWithManager(mgr ctrl.Manager) error {
+	r.syncLag = metrics.NewLagTracker()
+	r.backoff = newRequeueBackoff()
+
 	return ctrl.NewControllerManagedBy(mgr).
-		For(&v1alpha1.ServiceExport{}).
+		For(&v1alpha1.ServiceExport{}, builder.WithPredicates(serviceExportFilter())).
 		// Watch for the changes to the EndpointSlice object. This object is bound to be
 		// updated when Service or Deployment are updated. There is also a filtering logic
 		// to enqueue those EndpointSlice event which have corresponding ServiceExport
@@ -268,18 +902,93 @@ func (r *ServiceExportReconciler) SetupWithManager(mgr ctrl.Manager) error {
 			handler.EnqueueRequestsFromMapFunc(r.endpointSliceEventHandler()),
 			builder.WithPredicates(r.endpointSliceFilter()),
 		).
+		// Export the ServiceExports created before their Service once it appears, and the changes of the Services
+		Watches(
+			&source.Kind{Type: &v1.Service{}},
+			handler.EnqueueRequestsFromMapFunc(r.serviceEventHandler()),
+			builder.WithPredicates(r.serviceFilter()),
+		).
+		// Re-export the services of a namespace once its sync settings change
+		Watches(
+			&source.Kind{Type: &cloudmapv1alpha1.CloudMapSyncConfig{}},
+			handler.EnqueueRequestsFromMapFunc(r.syncConfigEventHandler()),
+			// the status updates of the CloudMapSyncConfig don't change the sync settings
+			builder.WithPredicates(predicate.Funcs{UpdateFunc: generationChanged}),
+		).
 		Complete(r)
 }
 
+// syncConfigEventHandler enqueues all ServiceExports in the namespace of a CloudMapSyncConfig.
+func (r *ServiceExportReconciler) syncConfigEventHandler() handler.MapFunc {
+	return func(object client.Object) []reconcile.Request {
+		if object.GetName() != cloudmapv1alpha1.CloudMapSyncConfigName {
+			return nil
+		}
+
+		serviceExports := &v1alpha1.ServiceExportList{}
+		if err := r.Client.List(context.TODO(), serviceExports, client.InNamespace(object.GetNamespace())); err != nil {
+			r.Log.Error(err, "error listing ServiceExports", "namespace", object.GetNamespace())
+			return nil
+		}
+
+		requests := make([]reconcile.Request, 0, len(serviceExports.Items))
+		for _, serviceExport := range serviceExports.Items {
+			requests = append(requests, reconcile.Request{
+				NamespacedName: types.NamespacedName{Namespace: serviceExport.Namespace, Name: serviceExport.Name},
+			})
+		}
+		return requests
+	}
+}
+
+// serviceEventHandler enqueues the ServiceExport of a Service.
+func (r *ServiceExportReconciler) serviceEventHandler() handler.MapFunc {
+	return func(object client.Object) []reconcile.Request {
+		return []reconcile.Request{
+			{NamespacedName: types.NamespacedName{Namespace: object.GetNamespace(), Name: object.GetName()}},
+		}
+	}
+}
+
+// serviceFilter passes the events of the Services with a ServiceExport, so a ServiceExport created before its Service
+// is exported once the Service appears, instead of after the backoff of a retry.
+func (r *ServiceExportReconciler) serviceFilter() predicate.Funcs {
+	return predicate.Funcs{
+		GenericFunc: func(e event.GenericEvent) bool {
+			return false
+		},
+		CreateFunc: func(e event.CreateEvent) bool {
+			return r.hasServiceExport(e.Object)
+		},
+		UpdateFunc: func(e event.UpdateEvent) bool {
+			// neither resyncs of the informer nor status updates change the exported Service
+			return serviceChanged(e) && r.hasServiceExport(e.ObjectNew)
+		},
+		DeleteFunc: func(e event.DeleteEvent) bool {
+			return r.hasServiceExport(e.Object)
+		},
+	}
+}
+
+// hasServiceExport returns true if the Service is exported by a ServiceExport.
+func (r *ServiceExportReconciler) hasServiceExport(service client.Object) bool {
+	svcExport := v1alpha1.ServiceExport{}
+	err := r.Client.Get(context.TODO(),
+		types.NamespacedName{Namespace: service.GetNamespace(), Name: service.GetName()}, &svcExport)
+	return err == nil
+}
+
 func (r *ServiceExportReconciler) endpointSliceEventHandler() handler.MapFunc {
 	return func(object client.Object) []reconcile.Request {
 		labels := object.GetLabels()
 		serviceName := labels[EndpointSliceServiceLabel]
+		namespacedName := types.NamespacedName{
+			Name:      serviceName,
+			Namespace: object.GetNamespace(),
+		}
+		r.syncLag.Observe(namespacedName.String())
 		return []reconcile.Request{
-			{NamespacedName: types.NamespacedName{
-				Name:      serviceName,
-				Namespace: object.GetNamespace(),
-			}},
+			{NamespacedName: namespacedName},
 		}
 	}
 }
@@ -287,16 +996,19 @@ func (r *ServiceExportReconciler) endpointSliceEventHandler() handler.MapFunc {
 func (r *ServiceExportReconciler) endpointSliceFilter() predicate.Funcs {
 	return predicate.Funcs{
 		GenericFunc: func(e event.GenericEvent) bool {
-			return r.doesEndpointSliceHaveServiceExport(e.Object)
+			return r.exportsEndpointSlice(e.Object) && r.doesEndpointSliceHaveServiceExport(e.Object)
 		},
 		CreateFunc: func(e event.CreateEvent) bool {
-			return r.doesEndpointSliceHaveServiceExport(e.Object)
+			return r.exportsEndpointSlice(e.Object) && r.doesEndpointSliceHaveServiceExport(e.Object)
 		},
 		UpdateFunc: func(e event.UpdateEvent) bool {
-			return r.doesEndpointSliceHaveServiceExport(e.ObjectNew)
+			// a change of manager may add or remove the endpoints of the EndpointSlice
+			return endpointSliceChanged(e) &&
+				(r.exportsEndpointSlice(e.ObjectOld) || r.exportsEndpointSlice(e.ObjectNew)) &&
+				r.doesEndpointSliceHaveServiceExport(e.ObjectNew)
 		},
 		DeleteFunc: func(e event.DeleteEvent) bool {
-			return r.doesEndpointSliceHaveServiceExport(e.Object)
+			return r.exportsEndpointSlice(e.Object) && r.doesEndpointSliceHaveServiceExport(e.Object)
 		},
 	}
 }
@@ -3,24 +3,34 @@ package controllers
 import (
 	"context"
 	"github.com/aws/aws-cloud-map-mcs-controller-for-k8s/mocks/pkg/cloudmap"
+	cloudmapv1alpha1 "github.com/aws/aws-cloud-map-mcs-controller-for-k8s/pkg/api/cloudmap/v1alpha1"
 	"github.com/aws/aws-cloud-map-mcs-controller-for-k8s/pkg/api/v1alpha1"
+	cmclient "github.com/aws/aws-cloud-map-mcs-controller-for-k8s/pkg/cloudmap"
 	"github.com/aws/aws-cloud-map-mcs-controller-for-k8s/pkg/common"
+	"github.com/aws/aws-cloud-map-mcs-controller-for-k8s/pkg/metrics"
 	"github.com/aws/aws-cloud-map-mcs-controller-for-k8s/pkg/model"
+	"github.com/aws/aws-cloud-map-mcs-controller-for-k8s/pkg/tenancy"
 	"github.com/aws/aws-cloud-map-mcs-controller-for-k8s/test"
 	"github.com/aws/aws-sdk-go-v2/aws"
+	sdtypes "github.com/aws/aws-sdk-go-v2/service/servicediscovery/types"
 	testing2 "github.com/go-logr/logr/testing"
 	"github.com/golang/mock/gomock"
 
 	"github.com/stretchr/testify/assert"
 	v1 "k8s.io/api/core/v1"
 	discovery "k8s.io/api/discovery/v1beta1"
+	"k8s.io/apimachinery/pkg/api/meta"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/runtime"
 	"k8s.io/apimachinery/pkg/types"
 	"k8s.io/apimachinery/pkg/util/intstr"
+	"k8s.io/client-go/tools/record"
 	ctrl "sigs.k8s.io/controller-runtime"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	"sigs.k8s.io/controller-runtime/pkg/event"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+	"strings"
 	"testing"
 )
 
@@ -70,6 +80,72 @@ func TestServiceExportReconciler_Reconcile_NewServiceExport(t *testing.T) {
 	assert.Contains(t, serviceExport.Finalizers, ServiceExportFinalizer, "Finalizer added to the service export")
 }
 
+func TestServiceExportReconciler_Reconcile_NamedTargetPort(t *testing.T) {
+	service := testServiceObj()
+	service.Spec.Ports[0].TargetPort = intstr.FromString("web")
+	fakeClient := fake.NewClientBuilder().
+		WithScheme(getServiceExportScheme()).
+		WithObjects(service, testServiceExportObj()).
+		WithLists(testEndpointSliceObj()).
+		Build()
+
+	mockController := gomock.NewController(t)
+	defer mockController.Finish()
+
+	// the container port of the endpoints is registered as target port
+	mock := cloudmap.NewMockServiceDiscoveryClient(mockController)
+	mock.EXPECT().GetService(gomock.Any(), test.NsName, test.SvcName).
+		Return(&model.Service{Namespace: test.NsName, Name: test.SvcName}, nil)
+	mock.EXPECT().RegisterEndpoints(gomock.Any(), test.NsName, test.SvcName,
+		[]*model.Endpoint{test.GetTestEndpoint1()}).Return(nil).Times(1)
+
+	reconciler := getServiceExportReconciler(t, mock, fakeClient)
+
+	request := ctrl.Request{
+		NamespacedName: types.NamespacedName{
+			Namespace: test.NsName,
+			Name:      test.SvcName,
+		},
+	}
+
+	_, err := reconciler.Reconcile(context.Background(), request)
+	assert.NoError(t, err)
+}
+
+func TestServiceExportReconciler_Reconcile_OwnershipAttributes(t *testing.T) {
+	fakeClient := fake.NewClientBuilder().
+		WithScheme(getServiceExportScheme()).
+		WithObjects(testServiceObj(), testServiceExportObj()).
+		WithLists(testEndpointSliceObj()).
+		Build()
+
+	mockController := gomock.NewController(t)
+	defer mockController.Finish()
+
+	mock := cloudmap.NewMockServiceDiscoveryClient(mockController)
+
+	// the instance registered before ownership attributes were added is replaced by an instance with a cluster ID
+	mock.EXPECT().GetService(gomock.Any(), test.NsName, test.SvcName).
+		Return(test.GetTestServiceWithEndpoint([]*model.Endpoint{test.GetTestEndpoint1()}), nil)
+	owned := test.GetTestEndpoint1()
+	owned.Id = model.EndpointId(test.ClusterId, owned.IP, owned.EndpointPort)
+	owned.Attributes[ClusterIdAttr] = test.ClusterId
+	owned.Attributes[ClusterSetIdAttr] = test.ClusterSetId
+	mock.EXPECT().RegisterEndpoints(gomock.Any(), test.NsName, test.SvcName,
+		[]*model.Endpoint{owned}).Return(nil).Times(1)
+	mock.EXPECT().DeleteEndpoints(gomock.Any(), test.NsName, test.SvcName,
+		[]*model.Endpoint{test.GetTestEndpoint1()}).Return(nil).Times(1)
+
+	reconciler := getServiceExportReconciler(t, mock, fakeClient)
+	reconciler.ClusterId = test.ClusterId
+	reconciler.ClusterSetId = test.ClusterSetId
+
+	_, err := reconciler.Reconcile(context.Background(), ctrl.Request{
+		NamespacedName: types.NamespacedName{Namespace: test.NsName, Name: test.SvcName},
+	})
+	assert.NoError(t, err)
+}
+
 func TestServiceExportReconciler_Reconcile_ExistingServiceExport(t *testing.T) {
 	// create a fake controller client and add some objects
 	fakeClient := fake.NewClientBuilder().
@@ -110,6 +186,9 @@ func TestServiceExportReconciler_Reconcile_ExistingServiceExport(t *testing.T) {
 	err = fakeClient.Get(context.TODO(), types.NamespacedName{Namespace: test.NsName, Name: test.SvcName}, serviceExport)
 	assert.NoError(t, err)
 	assert.Contains(t, serviceExport.Finalizers, ServiceExportFinalizer, "Finalizer added to the service export")
+	assert.True(t, meta.IsStatusConditionTrue(serviceExport.Status.Conditions, SyncedCondition), "Synced condition set")
+	assert.Equal(t, int32(1), serviceExport.Status.Endpoints)
+	assert.Equal(t, test.SvcId, serviceExport.Status.CloudMapServiceId)
 }
 
 func TestServiceExportReconciler_Reconcile_DeleteExistingService(t *testing.T) {
@@ -143,6 +222,8 @@ func TestServiceExportReconciler_Reconcile_DeleteExistingService(t *testing.T) {
 	}
 
 	reconciler := getServiceExportReconciler(t, mock, fakeClient)
+	reconciler.syncLag = metrics.NewLagTracker()
+	reconciler.syncLag.Observe(request.NamespacedName.String())
 
 	got, err := reconciler.Reconcile(context.Background(), request)
 	assert.NoError(t, err)
@@ -151,23 +232,350 @@ func TestServiceExportReconciler_Reconcile_DeleteExistingService(t *testing.T) {
 	serviceExport := &v1alpha1.ServiceExport{}
 	err = fakeClient.Get(context.TODO(), types.NamespacedName{Namespace: test.NsName, Name: test.SvcName}, serviceExport)
 	assert.NoError(t, err)
-	assert.Empty(t, serviceExport.Finalizers, "Finalizer removed from the service export")
+	assert.Equal(t, []string{ServiceExportFinalizer}, serviceExport.Finalizers,
+		"the service export is kept until it is deleted")
+	condition := meta.FindStatusCondition(serviceExport.Status.Conditions, string(v1alpha1.ServiceExportValid))
+	assert.Equal(t, metav1.ConditionFalse, condition.Status)
+	assert.Equal(t, ServiceNotFoundReason, condition.Reason)
+	_, found := reconciler.syncLag.Peek(request.NamespacedName.String())
+	assert.False(t, found, "the sync lag of the withdrawn endpoints is forgotten")
+}
+
+func TestServiceExportReconciler_Reconcile_DeleteKeepsOtherClusters(t *testing.T) {
+	serviceExportObj := testServiceExportObj()
+	serviceExportObj.Finalizers = []string{ServiceExportFinalizer}
+	fakeClient := fake.NewClientBuilder().
+		WithScheme(getServiceExportScheme()).
+		WithObjects(serviceExportObj).
+		Build()
+
+	mockController := gomock.NewController(t)
+	defer mockController.Finish()
+
+	mock := cloudmap.NewMockServiceDiscoveryClient(mockController)
+
+	owned := test.GetTestEndpoint1()
+	owned.Attributes[ClusterIdAttr] = test.ClusterId
+	other := test.GetTestEndpoint2()
+	other.Attributes[ClusterIdAttr] = "other-cluster"
+	mock.EXPECT().GetService(gomock.Any(), test.NsName, test.SvcName).
+		Return(test.GetTestServiceWithEndpoint([]*model.Endpoint{owned, other}), nil)
+	// only the instances of this cluster are de-registered, in a single call
+	mock.EXPECT().DeleteEndpoints(gomock.Any(), test.NsName, test.SvcName,
+		[]*model.Endpoint{owned}).Return(nil).Times(1)
+
+	reconciler := getServiceExportReconciler(t, mock, fakeClient)
+	reconciler.ClusterId = test.ClusterId
+	recorder := reconciler.Recorder.(*record.FakeRecorder)
+
+	_, err := reconciler.Reconcile(context.Background(), ctrl.Request{
+		NamespacedName: types.NamespacedName{Namespace: test.NsName, Name: test.SvcName},
+	})
+	assert.NoError(t, err)
+
+	assert.Len(t, recorder.Events, 1)
+	assert.Contains(t, <-recorder.Events, DeregisteredReason)
+}
+
+func TestServiceExportReconciler_Reconcile_DeleteFailureEvent(t *testing.T) {
+	serviceExportObj := testServiceExportObj()
+	serviceExportObj.Finalizers = []string{ServiceExportFinalizer}
+	fakeClient := fake.NewClientBuilder().
+		WithScheme(getServiceExportScheme()).
+		WithObjects(serviceExportObj).
+		Build()
+
+	mockController := gomock.NewController(t)
+	defer mockController.Finish()
+
+	mock := cloudmap.NewMockServiceDiscoveryClient(mockController)
+
+	mock.EXPECT().GetService(gomock.Any(), test.NsName, test.SvcName).
+		Return(test.GetTestService(), nil)
+	opErr := &cmclient.OperationFailureError{
+		OperationType: sdtypes.OperationTypeDeregisterInstance,
+		Failures: []cmclient.OperationFailure{
+			{OperationId: test.OpId1, ErrorCode: "ERROR_CODE", ErrorMessage: "error message"},
+			{OperationId: test.OpId2, ErrorCode: "ERROR_CODE", ErrorMessage: "error message"},
+		},
+	}
+	mock.EXPECT().DeleteEndpoints(gomock.Any(), test.NsName, test.SvcName, gomock.Any()).Return(opErr)
+
+	reconciler := getServiceExportReconciler(t, mock, fakeClient)
+	recorder := reconciler.Recorder.(*record.FakeRecorder)
+
+	_, err := reconciler.Reconcile(context.Background(), ctrl.Request{
+		NamespacedName: types.NamespacedName{Namespace: test.NsName, Name: test.SvcName},
+	})
+	assert.Equal(t, opErr, err)
+
+	// a single event reports all failures
+	assert.Len(t, recorder.Events, 1)
+	event := <-recorder.Events
+	assert.Contains(t, event, OperationFailedReason)
+	assert.Contains(t, event, "2 of 2 instances")
+	assert.Contains(t, event, "2x ERROR_CODE")
+}
+
+func TestServiceExportReconciler_Reconcile_OperationFailureEvent(t *testing.T) {
+	fakeClient := fake.NewClientBuilder().
+		WithScheme(getServiceExportScheme()).
+		WithObjects(testServiceObj(), testServiceExportObj()).
+		WithLists(testEndpointSliceObj()).
+		Build()
+
+	mockController := gomock.NewController(t)
+	defer mockController.Finish()
+
+	mock := cloudmap.NewMockServiceDiscoveryClient(mockController)
+
+	mock.EXPECT().GetService(gomock.Any(), test.NsName, test.SvcName).
+		Return(&model.Service{Namespace: test.NsName, Name: test.SvcName}, nil)
+	opErr := &cmclient.OperationFailureError{
+		OperationType: sdtypes.OperationTypeRegisterInstance,
+		Failures: []cmclient.OperationFailure{
+			{OperationId: test.OpId1, ErrorCode: "ERROR_CODE", ErrorMessage: "error message"},
+		},
+	}
+	mock.EXPECT().RegisterEndpoints(gomock.Any(), test.NsName, test.SvcName,
+		[]*model.Endpoint{test.GetTestEndpoint1()}).Return(opErr)
+
+	reconciler := getServiceExportReconciler(t, mock, fakeClient)
+	recorder := reconciler.Recorder.(*record.FakeRecorder)
+
+	request := ctrl.Request{
+		NamespacedName: types.NamespacedName{
+			Namespace: test.NsName,
+			Name:      test.SvcName,
+		},
+	}
+
+	_, err := reconciler.Reconcile(context.Background(), request)
+	assert.Equal(t, opErr, err)
+
+	assert.Len(t, recorder.Events, 1)
+	event := <-recorder.Events
+	assert.Contains(t, event, OperationFailedReason)
+	assert.Contains(t, event, test.OpId1)
+	assert.Contains(t, event, "ERROR_CODE")
+}
+
+func TestServiceExportReconciler_Reconcile_ThrottledCondition(t *testing.T) {
+	fakeClient := fake.NewClientBuilder().
+		WithScheme(getServiceExportScheme()).
+		WithObjects(testServiceObj(), testServiceExportObj()).
+		WithLists(testEndpointSliceObj()).
+		Build()
+
+	mockController := gomock.NewController(t)
+	defer mockController.Finish()
+
+	mock := cloudmap.NewMockServiceDiscoveryClient(mockController)
+
+	mock.EXPECT().GetService(gomock.Any(), test.NsName, test.SvcName).
+		Return(&model.Service{Namespace: test.NsName, Name: test.SvcName}, nil)
+	// the SDK retries throttled requests and reports them to the tracker of the request context
+	mock.EXPECT().RegisterEndpoints(gomock.Any(), test.NsName, test.SvcName,
+		[]*model.Endpoint{test.GetTestEndpoint1()}).
+		DoAndReturn(func(ctx context.Context, nsName string, svcName string, endpts []*model.Endpoint) error {
+			cmclient.ThrottleTrackerFromContext(ctx).Observe()
+			return nil
+		})
+
+	reconciler := getServiceExportReconciler(t, mock, fakeClient)
+	recorder := reconciler.Recorder.(*record.FakeRecorder)
+
+	request := ctrl.Request{
+		NamespacedName: types.NamespacedName{
+			Namespace: test.NsName,
+			Name:      test.SvcName,
+		},
+	}
+
+	_, err := reconciler.Reconcile(context.Background(), request)
+	assert.NoError(t, err)
+
+	serviceExport := &v1alpha1.ServiceExport{}
+	err = fakeClient.Get(context.TODO(), types.NamespacedName{Namespace: test.NsName, Name: test.SvcName}, serviceExport)
+	assert.NoError(t, err)
+	condition := meta.FindStatusCondition(serviceExport.Status.Conditions, ThrottledCondition)
+	if assert.NotNil(t, condition) {
+		assert.Equal(t, metav1.ConditionTrue, condition.Status)
+		assert.Equal(t, ThrottledReason, condition.Reason)
+	}
+
+	assert.Len(t, recorder.Events, 1)
+	assert.Contains(t, <-recorder.Events, ThrottledReason)
+}
+
+func TestServiceExportReconciler_Reconcile_TenancyDenied(t *testing.T) {
+	fakeClient := fake.NewClientBuilder().
+		WithScheme(getServiceExportScheme()).
+		WithObjects(testNamespace(), testServiceObj(), testServiceExportObj()).
+		WithLists(testEndpointSliceObj()).
+		Build()
+
+	mockController := gomock.NewController(t)
+	defer mockController.Finish()
+
+	// no Cloud Map calls are expected
+	mock := cloudmap.NewMockServiceDiscoveryClient(mockController)
+
+	reconciler := getServiceExportReconciler(t, mock, fakeClient)
+	reconciler.TenancyPolicy = &tenancy.Policy{DenyUnlisted: true}
+	recorder := reconciler.Recorder.(*record.FakeRecorder)
+
+	request := ctrl.Request{
+		NamespacedName: types.NamespacedName{
+			Namespace: test.NsName,
+			Name:      test.SvcName,
+		},
+	}
+
+	got, err := reconciler.Reconcile(context.Background(), request)
+	assert.NoError(t, err)
+	assert.Equal(t, ctrl.Result{}, got, "Result should be empty")
+
+	serviceExport := &v1alpha1.ServiceExport{}
+	err = fakeClient.Get(context.TODO(), types.NamespacedName{Namespace: test.NsName, Name: test.SvcName}, serviceExport)
+	assert.NoError(t, err)
+	condition := meta.FindStatusCondition(serviceExport.Status.Conditions, SyncedCondition)
+	if assert.NotNil(t, condition) {
+		assert.Equal(t, metav1.ConditionFalse, condition.Status)
+		assert.Equal(t, TenancyDeniedReason, condition.Reason)
+	}
+
+	assert.Len(t, recorder.Events, 1)
+	assert.Contains(t, <-recorder.Events, TenancyDeniedReason)
+}
+
+func TestServiceExportReconciler_Reconcile_TenancyNamespaceReader(t *testing.T) {
+	// the cache of the manager restricted to the watched namespaces doesn't hold the namespace
+	fakeClient := fake.NewClientBuilder().
+		WithScheme(getServiceExportScheme()).
+		WithObjects(testServiceObj(), testServiceExportObj()).
+		WithLists(testEndpointSliceObj()).
+		Build()
+
+	mockController := gomock.NewController(t)
+	defer mockController.Finish()
+
+	mock := cloudmap.NewMockServiceDiscoveryClient(mockController)
+
+	reconciler := getServiceExportReconciler(t, mock, fakeClient)
+	reconciler.TenancyPolicy = &tenancy.Policy{DenyUnlisted: true}
+	reconciler.NamespaceReader = fake.NewClientBuilder().WithScheme(getServiceExportScheme()).
+		WithObjects(testNamespace()).Build()
+
+	request := ctrl.Request{
+		NamespacedName: types.NamespacedName{
+			Namespace: test.NsName,
+			Name:      test.SvcName,
+		},
+	}
+
+	_, err := reconciler.Reconcile(context.Background(), request)
+	assert.NoError(t, err)
+
+	serviceExport := &v1alpha1.ServiceExport{}
+	err = fakeClient.Get(context.TODO(), types.NamespacedName{Namespace: test.NsName, Name: test.SvcName}, serviceExport)
+	assert.NoError(t, err)
+	condition := meta.FindStatusCondition(serviceExport.Status.Conditions, SyncedCondition)
+	if assert.NotNil(t, condition) {
+		assert.Equal(t, TenancyDeniedReason, condition.Reason, "the namespace is read with the namespace reader")
+	}
+}
+
+func TestServiceExportReconciler_Reconcile_AttributeLimitExceeded(t *testing.T) {
+	serviceExport := testServiceExportObj()
+	attributes := make([]string, 0)
+	for _, key := range []string{"a", "b", "c", "d", "e"} {
+		attributes = append(attributes, key+"="+strings.Repeat("v", 1000))
+	}
+	serviceExport.Annotations = map[string]string{InstanceAttributesAnnotation: strings.Join(attributes, ",")}
+	fakeClient := fake.NewClientBuilder().
+		WithScheme(getServiceExportScheme()).
+		WithObjects(testServiceObj(), serviceExport).
+		WithLists(testEndpointSliceObj()).
+		Build()
+
+	mockController := gomock.NewController(t)
+	defer mockController.Finish()
+
+	// no endpoints are registered
+	mock := cloudmap.NewMockServiceDiscoveryClient(mockController)
+	mock.EXPECT().GetService(gomock.Any(), test.NsName, test.SvcName).
+		Return(&model.Service{Namespace: test.NsName, Name: test.SvcName}, nil)
+
+	reconciler := getServiceExportReconciler(t, mock, fakeClient)
+	recorder := reconciler.Recorder.(*record.FakeRecorder)
+
+	request := ctrl.Request{
+		NamespacedName: types.NamespacedName{
+			Namespace: test.NsName,
+			Name:      test.SvcName,
+		},
+	}
+
+	_, err := reconciler.Reconcile(context.Background(), request)
+	assert.NoError(t, err, "retrying cannot succeed")
+
+	err = fakeClient.Get(context.TODO(), types.NamespacedName{Namespace: test.NsName, Name: test.SvcName}, serviceExport)
+	assert.NoError(t, err)
+	condition := meta.FindStatusCondition(serviceExport.Status.Conditions, SyncedCondition)
+	if assert.NotNil(t, condition) {
+		assert.Equal(t, metav1.ConditionFalse, condition.Status)
+		assert.Equal(t, AttributeLimitExceededReason, condition.Reason)
+	}
+	assert.Contains(t, <-recorder.Events, AttributeLimitExceededReason)
+}
+
+func TestServiceExportReconciler_ServiceFilter(t *testing.T) {
+	fakeClient := fake.NewClientBuilder().
+		WithScheme(getServiceExportScheme()).
+		WithObjects(testServiceExportObj()).
+		Build()
+	mockController := gomock.NewController(t)
+	defer mockController.Finish()
+
+	reconciler := getServiceExportReconciler(t, cloudmap.NewMockServiceDiscoveryClient(mockController), fakeClient)
+	filter := reconciler.serviceFilter()
+
+	exported := testServiceObj()
+	notExported := testServiceObj()
+	notExported.Name = "not-exported"
+	assert.True(t, filter.Create(event.CreateEvent{Object: exported}), "Service created after its ServiceExport")
+	assert.False(t, filter.Create(event.CreateEvent{Object: notExported}))
+	assert.True(t, filter.Delete(event.DeleteEvent{Object: exported}))
+
+	changed := exported.DeepCopy()
+	changed.ResourceVersion = "2"
+	assert.True(t, filter.Update(event.UpdateEvent{ObjectOld: exported, ObjectNew: changed}))
+	assert.False(t, filter.Update(event.UpdateEvent{ObjectOld: changed, ObjectNew: changed}), "resyncs are ignored")
+
+	expected := []reconcile.Request{{NamespacedName: types.NamespacedName{Namespace: test.NsName, Name: test.SvcName}}}
+	assert.Equal(t, expected, reconciler.serviceEventHandler()(exported))
 }
 
 func getServiceExportScheme() *runtime.Scheme {
 	scheme := runtime.NewScheme()
 	scheme.AddKnownTypes(v1alpha1.GroupVersion, &v1alpha1.ServiceExport{})
-	scheme.AddKnownTypes(v1.SchemeGroupVersion, &v1.Service{})
+	scheme.AddKnownTypes(v1.SchemeGroupVersion, &v1.Service{}, &v1.Namespace{}, &v1.Node{})
 	scheme.AddKnownTypes(discovery.SchemeGroupVersion, &discovery.EndpointSlice{}, &discovery.EndpointSliceList{})
+	scheme.AddKnownTypes(cloudmapv1alpha1.GroupVersion, &cloudmapv1alpha1.CloudMapSyncConfig{})
 	return scheme
 }
 
 func getServiceExportReconciler(t *testing.T, mockClient *cloudmap.MockServiceDiscoveryClient, client client.Client) *ServiceExportReconciler {
+	// the DNS configuration of the Cloud Map services never drifts
+	mockClient.EXPECT().CorrectDnsConfig(gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any()).
+		Return(model.DnsConfigDrift{}, nil).AnyTimes()
 	return &ServiceExportReconciler{
 		Client:   client,
 		Log:      common.NewLoggerWithLogr(testing2.TestLogger{T: t}),
 		Scheme:   client.Scheme(),
-		CloudMap: mockClient,
+		Registry: mockClient,
+		Recorder: record.NewFakeRecorder(10),
 	}
 }
 
@@ -0,0 +1,196 @@
+package controllers
+
+import (
+	"context"
+	"github.com/aws/aws-cloud-map-mcs-controller-for-k8s/pkg/common"
+	"github.com/aws/aws-cloud-map-mcs-controller-for-k8s/pkg/model"
+	"github.com/aws/aws-cloud-map-mcs-controller-for-k8s/pkg/registry"
+	"k8s.io/apimachinery/pkg/util/sets"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"time"
+)
+
+// Each cluster refreshes its heartbeat, an instance of the HeartbeatServiceName service of a dedicated Cloud Map
+// namespace holding the time of the last refresh in the HeartbeatAttr attribute. The instances of a cluster whose
+// heartbeat expired, e.g. a destroyed cluster, are de-registered by the surviving clusters. Clusters which never
+// refreshed a heartbeat are left alone.
+
+const (
+	// HeartbeatServiceName is the Cloud Map service holding the heartbeats of the clusters
+	HeartbeatServiceName = "mcs-controller-heartbeats"
+	// HeartbeatAttr is the attribute of a heartbeat instance holding the time of its last refresh, in RFC 3339
+	HeartbeatAttr = "HEARTBEAT"
+
+	// DefaultHeartbeatInterval is the default interval of the heartbeat refreshes and stale cluster collections
+	DefaultHeartbeatInterval = time.Minute
+	// DefaultHeartbeatExpiry is the default time after its last refresh a heartbeat expires
+	DefaultHeartbeatExpiry = 15 * time.Minute
+
+	// heartbeatIP and heartbeatPort fill the required attributes of the heartbeat instances, which are never served
+	heartbeatIP   = "127.0.0.1"
+	heartbeatPort = 1
+)
+
+// StaleClusterCollector refreshes the heartbeat of the cluster, and de-registers the instances registered by clusters
+// whose heartbeat expired from the Cloud Map namespaces of the namespaces of the cluster.
+type StaleClusterCollector struct {
+	Client        client.Client
+	Log           common.Logger
+	Registry      registry.ServiceRegistry
+	ClusterConfig *ClusterConfig
+	// Namespaces restricts the collection to the Cloud Map namespaces of the namespaces, all namespaces if empty
+	Namespaces []string
+	ClusterId  string
+
+	// HeartbeatNamespace is the dedicated Cloud Map namespace of the heartbeats, which isn't imported
+	HeartbeatNamespace string
+	// Interval is the interval of the heartbeat refreshes, DefaultHeartbeatInterval is used if not positive
+	Interval time.Duration
+	// Expiry is the time after its last refresh a heartbeat expires, DefaultHeartbeatExpiry is used if not positive
+	Expiry time.Duration
+}
+
+// Start refreshes the heartbeat and collects stale clusters every interval, until the context is done.
+func (c *StaleClusterCollector) Start(ctx context.Context) error {
+	interval := c.Interval
+	if interval <= 0 {
+		interval = DefaultHeartbeatInterval
+	}
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		if err := c.Collect(ctx, time.Now()); err != nil {
+			c.Log.Error(err, "unable to collect stale clusters")
+		}
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+		}
+	}
+}
+
+// Collect refreshes the heartbeat of the cluster, and de-registers the instances of the clusters whose heartbeat
+// expired. Nothing is collected if the heartbeat can't be refreshed, the cluster may be partitioned from Cloud Map.
+func (c *StaleClusterCollector) Collect(ctx context.Context, now time.Time) error {
+	heartbeats, err := c.refreshHeartbeat(ctx, now)
+	if err != nil {
+		return err
+	}
+
+	expiry := c.Expiry
+	if expiry <= 0 {
+		expiry = DefaultHeartbeatExpiry
+	}
+	expired := make([]*model.Endpoint, 0)
+	expiredClusters := sets.NewString()
+	for _, heartbeat := range heartbeats {
+		clusterId := heartbeat.Attributes[ClusterIdAttr]
+		refreshed, parseErr := time.Parse(time.RFC3339, heartbeat.Attributes[HeartbeatAttr])
+		if clusterId == "" || clusterId == c.ClusterId || parseErr != nil || now.Sub(refreshed) <= expiry {
+			continue
+		}
+		c.Log.Info("heartbeat of cluster expired", "clusterId", clusterId, "lastHeartbeat", refreshed.String())
+		expired = append(expired, heartbeat)
+		expiredClusters.Insert(clusterId)
+	}
+	if len(expired) == 0 {
+		return nil
+	}
+
+	credentials, err := c.cloudMapNamespaces(ctx)
+	if err != nil {
+		return err
+	}
+	for _, cmNamespace := range sets.StringKeySet(credentials).List() {
+		if err = c.collectNamespace(credentials[cmNamespace], cmNamespace, expiredClusters); err != nil {
+			return err
+		}
+	}
+
+	// the heartbeats are only dropped once all instances of the clusters are de-registered, replicas included
+	if err = registry.DeleteClusterReplicas(ctx, c.Registry, c.HeartbeatNamespace, expiredClusters.List()); err != nil {
+		return err
+	}
+	return c.Registry.DeleteEndpoints(ctx, c.HeartbeatNamespace, HeartbeatServiceName, expired)
+}
+
+// refreshHeartbeat registers the heartbeat of the cluster, and returns the heartbeats of all clusters.
+func (c *StaleClusterCollector) refreshHeartbeat(ctx context.Context, now time.Time) ([]*model.Endpoint, error) {
+	registry.EvictEndpoints(c.Registry, c.HeartbeatNamespace, HeartbeatServiceName)
+	svc, err := c.Registry.GetService(ctx, c.HeartbeatNamespace, HeartbeatServiceName)
+	if err != nil {
+		return nil, err
+	}
+	if svc == nil {
+		if err = c.Registry.CreateService(ctx, c.HeartbeatNamespace, HeartbeatServiceName); err != nil {
+			return nil, err
+		}
+		svc = &model.Service{Namespace: c.HeartbeatNamespace, Name: HeartbeatServiceName}
+	}
+
+	port := model.Port{Port: heartbeatPort, Protocol: model.TCPProtocol}
+	heartbeat := &model.Endpoint{
+		Id:           c.ClusterId,
+		IP:           heartbeatIP,
+		EndpointPort: port,
+		ServicePort:  port,
+		Attributes: map[string]string{
+			ClusterIdAttr: c.ClusterId,
+			HeartbeatAttr: now.UTC().Format(time.RFC3339),
+		},
+	}
+	if err = c.Registry.RegisterEndpoints(ctx, c.HeartbeatNamespace, HeartbeatServiceName,
+		[]*model.Endpoint{heartbeat}); err != nil {
+		return nil, err
+	}
+	return svc.Endpoints, nil
+}
+
+// cloudMapNamespaces returns the Cloud Map namespaces of the namespaces of the cluster, without the heartbeat namespace,
+// with the contexts calling each with the IAM role of its first namespace.
+func (c *StaleClusterCollector) cloudMapNamespaces(ctx context.Context) (map[string]context.Context, error) {
+	namespaceNames, err := listNamespaceNames(ctx, c.Client, c.Namespaces)
+	if err != nil {
+		return nil, err
+	}
+
+	credentials := make(map[string]context.Context)
+	for _, namespaceName := range namespaceNames {
+		settings, err := ResolveSyncSettings(ctx, c.Client, c.ClusterConfig, namespaceName)
+		if err != nil {
+			return nil, err
+		}
+		if _, ok := credentials[settings.CloudMapNamespace]; !ok {
+			credentials[settings.CloudMapNamespace] = settings.WithCredentials(ctx, namespaceName)
+		}
+	}
+	delete(credentials, c.HeartbeatNamespace)
+	return credentials, nil
+}
+
+// collectNamespace de-registers the instances of the expired clusters from the services of the Cloud Map namespace, and
+// from its replicas in other regions if the registry replicates endpoints.
+func (c *StaleClusterCollector) collectNamespace(ctx context.Context, cmNamespace string, expiredClusters sets.String) error {
+	services, err := c.Registry.ListServices(ctx, cmNamespace)
+	if err != nil {
+		return err
+	}
+	for _, svc := range services {
+		stale := make([]*model.Endpoint, 0)
+		for _, endpoint := range svc.Endpoints {
+			if expiredClusters.Has(endpoint.Attributes[ClusterIdAttr]) {
+				stale = append(stale, endpoint)
+			}
+		}
+		if len(stale) == 0 {
+			continue
+		}
+		if err = c.Registry.DeleteEndpoints(ctx, cmNamespace, svc.Name, stale); err != nil {
+			return err
+		}
+		c.Log.Info("de-registered the instances of expired clusters", "cloudMapNamespace", cmNamespace,
+			"name", svc.Name, "instances", len(stale))
+	}
+	return registry.DeleteClusterReplicas(ctx, c.Registry, cmNamespace, expiredClusters.List())
+}
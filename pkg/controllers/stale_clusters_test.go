@@ -0,0 +1,147 @@
+package controllers
+
+import (
+	"context"
+	"github.com/aws/aws-cloud-map-mcs-controller-for-k8s/mocks/pkg/cloudmap"
+	"github.com/aws/aws-cloud-map-mcs-controller-for-k8s/pkg/common"
+	"github.com/aws/aws-cloud-map-mcs-controller-for-k8s/pkg/model"
+	"github.com/aws/aws-cloud-map-mcs-controller-for-k8s/pkg/replication"
+	"github.com/aws/aws-cloud-map-mcs-controller-for-k8s/test"
+	testingLogger "github.com/go-logr/logr/testing"
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/assert"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	"testing"
+	"time"
+)
+
+const heartbeatNamespace = "heartbeats"
+
+func TestStaleClusterCollector_Collect(t *testing.T) {
+	now := time.Now()
+	live := testHeartbeat("live-cluster", now.Add(-time.Minute))
+	dead := testHeartbeat("dead-cluster", now.Add(-time.Hour))
+	own := testHeartbeat(test.ClusterId, now.Add(-time.Hour))
+
+	liveEndpoint := test.GetTestEndpoint1()
+	liveEndpoint.Attributes[ClusterIdAttr] = "live-cluster"
+	deadEndpoint := test.GetTestEndpoint2()
+	deadEndpoint.Attributes[ClusterIdAttr] = "dead-cluster"
+
+	mockController := gomock.NewController(t)
+	defer mockController.Finish()
+
+	mock := cloudmap.NewMockServiceDiscoveryClient(mockController)
+	mock.EXPECT().EvictEndpoints(heartbeatNamespace, HeartbeatServiceName)
+	mock.EXPECT().GetService(gomock.Any(), heartbeatNamespace, HeartbeatServiceName).
+		Return(&model.Service{Namespace: heartbeatNamespace, Name: HeartbeatServiceName,
+			Endpoints: []*model.Endpoint{live, dead, own}}, nil)
+	mock.EXPECT().RegisterEndpoints(gomock.Any(), heartbeatNamespace, HeartbeatServiceName, gomock.Any()).
+		DoAndReturn(func(_ context.Context, _ string, _ string, endpoints []*model.Endpoint) error {
+			assert.Equal(t, test.ClusterId, endpoints[0].Id)
+			assert.Equal(t, now.UTC().Format(time.RFC3339), endpoints[0].Attributes[HeartbeatAttr])
+			return nil
+		})
+	// only the instances of the expired cluster are de-registered, then its heartbeat
+	mock.EXPECT().ListServices(gomock.Any(), test.NsName).
+		Return([]*model.Service{{Namespace: test.NsName, Name: test.SvcName,
+			Endpoints: []*model.Endpoint{liveEndpoint, deadEndpoint}}}, nil)
+	mock.EXPECT().DeleteEndpoints(gomock.Any(), test.NsName, test.SvcName, []*model.Endpoint{deadEndpoint}).
+		Return(nil)
+	mock.EXPECT().DeleteEndpoints(gomock.Any(), heartbeatNamespace, HeartbeatServiceName, []*model.Endpoint{dead}).
+		Return(nil)
+
+	collector := getStaleClusterCollector(t, mock)
+	assert.NoError(t, collector.Collect(context.TODO(), now))
+}
+
+func TestStaleClusterCollector_Replication(t *testing.T) {
+	now := time.Now()
+	dead := testHeartbeat("dead-cluster", now.Add(-time.Hour))
+	deadEndpoint := test.GetTestEndpoint2()
+	deadEndpoint.Attributes[ClusterIdAttr] = "dead-cluster"
+	deadReplica := test.GetTestEndpoint1()
+	deadReplica.Attributes[ClusterIdAttr] = "dead-cluster"
+	deadReplica.Attributes[replication.SourceRegionAttr] = "eu-west-1"
+	deadHeartbeatReplica := testHeartbeat("dead-cluster", now.Add(-time.Hour))
+	deadHeartbeatReplica.Attributes[replication.SourceRegionAttr] = "eu-west-1"
+
+	mockController := gomock.NewController(t)
+	defer mockController.Finish()
+
+	primary := cloudmap.NewMockServiceDiscoveryClient(mockController)
+	primary.EXPECT().EvictEndpoints(heartbeatNamespace, HeartbeatServiceName)
+	primary.EXPECT().GetService(gomock.Any(), heartbeatNamespace, HeartbeatServiceName).
+		Return(&model.Service{Namespace: heartbeatNamespace, Name: HeartbeatServiceName,
+			Endpoints: []*model.Endpoint{dead}}, nil)
+	primary.EXPECT().RegisterEndpoints(gomock.Any(), heartbeatNamespace, HeartbeatServiceName, gomock.Any()).
+		Return(nil)
+	primary.EXPECT().ListServices(gomock.Any(), test.NsName).
+		Return([]*model.Service{{Namespace: test.NsName, Name: test.SvcName,
+			Endpoints: []*model.Endpoint{deadEndpoint}}}, nil)
+	primary.EXPECT().DeleteEndpoints(gomock.Any(), test.NsName, test.SvcName, []*model.Endpoint{deadEndpoint}).
+		Return(nil)
+	primary.EXPECT().DeleteEndpoints(gomock.Any(), heartbeatNamespace, HeartbeatServiceName, []*model.Endpoint{dead}).
+		Return(nil)
+
+	// the instances of the expired cluster replicated from its own region are de-registered from the replica too
+	replica := cloudmap.NewMockServiceDiscoveryClient(mockController)
+	replica.EXPECT().EvictEndpoints(heartbeatNamespace, HeartbeatServiceName)
+	replica.EXPECT().RegisterEndpoints(gomock.Any(), heartbeatNamespace, HeartbeatServiceName, gomock.Any()).
+		Return(nil)
+	replica.EXPECT().ListServices(gomock.Any(), test.NsName).
+		Return([]*model.Service{{Namespace: test.NsName, Name: test.SvcName,
+			Endpoints: []*model.Endpoint{deadReplica}}}, nil)
+	replica.EXPECT().DeleteEndpoints(gomock.Any(), test.NsName, test.SvcName, []*model.Endpoint{deadReplica}).
+		Return(nil)
+	replica.EXPECT().ListServices(gomock.Any(), heartbeatNamespace).
+		Return([]*model.Service{{Namespace: heartbeatNamespace, Name: HeartbeatServiceName,
+			Endpoints: []*model.Endpoint{deadHeartbeatReplica}}}, nil)
+	replica.EXPECT().DeleteEndpoints(gomock.Any(), heartbeatNamespace, HeartbeatServiceName,
+		[]*model.Endpoint{deadHeartbeatReplica}).Return(nil)
+
+	collector := getStaleClusterCollector(t, primary)
+	collector.Registry = replication.NewRegistry(primary, "us-west-2",
+		[]replication.Replica{{Region: "us-east-1", Registry: replica}}, test.ClusterId)
+	assert.NoError(t, collector.Collect(context.TODO(), now))
+}
+
+func TestStaleClusterCollector_HeartbeatFailure(t *testing.T) {
+	mockController := gomock.NewController(t)
+	defer mockController.Finish()
+
+	// nothing is collected while the heartbeat of the cluster can't be refreshed
+	mock := cloudmap.NewMockServiceDiscoveryClient(mockController)
+	mock.EXPECT().EvictEndpoints(heartbeatNamespace, HeartbeatServiceName)
+	mock.EXPECT().GetService(gomock.Any(), heartbeatNamespace, HeartbeatServiceName).Return(nil, nil)
+	mock.EXPECT().CreateService(gomock.Any(), heartbeatNamespace, HeartbeatServiceName).
+		Return(context.DeadlineExceeded)
+
+	collector := getStaleClusterCollector(t, mock)
+	assert.Error(t, collector.Collect(context.TODO(), time.Now()))
+}
+
+func getStaleClusterCollector(t *testing.T, mock *cloudmap.MockServiceDiscoveryClient) *StaleClusterCollector {
+	return &StaleClusterCollector{
+		Client:             fake.NewClientBuilder().WithScheme(getSyncConfigScheme()).Build(),
+		Log:                common.NewLoggerWithLogr(testingLogger.TestLogger{T: t}),
+		Registry:           mock,
+		Namespaces:         []string{test.NsName},
+		ClusterId:          test.ClusterId,
+		HeartbeatNamespace: heartbeatNamespace,
+	}
+}
+
+func testHeartbeat(clusterId string, refreshed time.Time) *model.Endpoint {
+	port := model.Port{Port: heartbeatPort, Protocol: model.TCPProtocol}
+	return &model.Endpoint{
+		Id:           clusterId,
+		IP:           heartbeatIP,
+		EndpointPort: port,
+		ServicePort:  port,
+		Attributes: map[string]string{
+			ClusterIdAttr: clusterId,
+			HeartbeatAttr: refreshed.UTC().Format(time.RFC3339),
+		},
+	}
+}
@@ -0,0 +1,105 @@
+package controllers
+
+import (
+	"github.com/aws/aws-cloud-map-mcs-controller-for-k8s/pkg/api/v1alpha1"
+	"k8s.io/apimachinery/pkg/api/equality"
+	"k8s.io/apimachinery/pkg/api/meta"
+	"k8s.io/apimachinery/pkg/types"
+	"sync"
+	"time"
+)
+
+// DefaultStatusUpdateInterval is the default minimum interval between the batched status writes of a ServiceExport
+const DefaultStatusUpdateInterval = 10 * time.Second
+
+// StatusBatcher coalesces the minor status changes of the ServiceExports, e.g. endpoint counts and progress messages
+// changing every few seconds during a rollout, into a write per interval. Transitions, i.e. changes of the status or
+// reason of a condition and changes of the other status fields, are written right away. It is safe for concurrent
+// use, and a nil StatusBatcher writes every change.
+type StatusBatcher struct {
+	interval time.Duration
+	mu       sync.Mutex
+	// written holds the time of the last status write of each ServiceExport
+	written map[types.NamespacedName]time.Time
+	now     func() time.Time
+}
+
+// NewStatusBatcher creates a batcher writing the minor status changes of a ServiceExport at most once per interval.
+func NewStatusBatcher(interval time.Duration) *StatusBatcher {
+	return &StatusBatcher{
+		interval: interval,
+		written:  make(map[types.NamespacedName]time.Time),
+		now:      time.Now,
+	}
+}
+
+// Defer returns the time until the status change of the ServiceExport may be written, zero if it is written right
+// away. The deferred change is written by the reconcile requeued after that time.
+func (b *StatusBatcher) Defer(name types.NamespacedName, original *v1alpha1.ServiceExportStatus, status *v1alpha1.ServiceExportStatus) time.Duration {
+	if b == nil || !minorStatusChange(original, status) {
+		return 0
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	written, found := b.written[name]
+	if !found {
+		return 0
+	}
+	if remaining := written.Add(b.interval).Sub(b.now()); remaining > 0 {
+		return remaining
+	}
+	return 0
+}
+
+// Written records the status write of the ServiceExport.
+func (b *StatusBatcher) Written(name types.NamespacedName) {
+	if b == nil {
+		return
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	now := b.now()
+	b.written[name] = now
+	// drop the ServiceExports which weren't written for a while, no change of theirs would be deferred
+	for key, written := range b.written {
+		if now.Sub(written) > b.interval {
+			delete(b.written, key)
+		}
+	}
+}
+
+// Forget removes the ServiceExport, which was deleted.
+func (b *StatusBatcher) Forget(name types.NamespacedName) {
+	if b == nil {
+		return
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	delete(b.written, name)
+}
+
+// minorStatusChange returns true if the status only differs from the original status by the endpoint count, and the
+// messages and timestamps of conditions keeping their status and reason.
+func minorStatusChange(original *v1alpha1.ServiceExportStatus, status *v1alpha1.ServiceExportStatus) bool {
+	if original == nil || len(original.Conditions) != len(status.Conditions) {
+		return false
+	}
+	for _, condition := range status.Conditions {
+		originalCondition := meta.FindStatusCondition(original.Conditions, condition.Type)
+		if originalCondition == nil || originalCondition.Status != condition.Status ||
+			originalCondition.Reason != condition.Reason {
+			return false
+		}
+	}
+
+	originalRest, rest := original.DeepCopy(), status.DeepCopy()
+	originalRest.Conditions, rest.Conditions = nil, nil
+	originalRest.Endpoints, rest.Endpoints = 0, 0
+	return equality.Semantic.DeepEqual(originalRest, rest)
+}
@@ -0,0 +1,66 @@
+package controllers
+
+import (
+	"github.com/aws/aws-cloud-map-mcs-controller-for-k8s/pkg/api/v1alpha1"
+	"github.com/aws/aws-cloud-map-mcs-controller-for-k8s/test"
+	"github.com/stretchr/testify/assert"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"testing"
+	"time"
+)
+
+func TestStatusBatcher_Defer(t *testing.T) {
+	now := time.Now()
+	batcher := NewStatusBatcher(10 * time.Second)
+	batcher.now = func() time.Time { return now }
+	name := types.NamespacedName{Namespace: test.NsName, Name: test.SvcName}
+
+	original := &v1alpha1.ServiceExportStatus{
+		Endpoints: 3,
+		Conditions: []metav1.Condition{
+			{Type: SyncingCondition, Status: metav1.ConditionTrue, Reason: SyncInProgressReason, Message: "1 of 3"},
+		},
+	}
+	progressed := original.DeepCopy()
+	progressed.Endpoints = 4
+	progressed.Conditions[0].Message = "2 of 3"
+	completed := progressed.DeepCopy()
+	completed.Conditions[0].Status = metav1.ConditionFalse
+	completed.Conditions[0].Reason = SyncCompleteReason
+
+	assert.Zero(t, batcher.Defer(name, original, progressed), "the first change is written right away")
+	batcher.Written(name)
+
+	now = now.Add(4 * time.Second)
+	assert.Equal(t, 6*time.Second, batcher.Defer(name, original, progressed), "minor changes are batched")
+	assert.Zero(t, batcher.Defer(name, original, completed), "transitions are written right away")
+
+	now = now.Add(6 * time.Second)
+	assert.Zero(t, batcher.Defer(name, original, progressed), "batched changes are written after the interval")
+
+	batcher.Written(name)
+	batcher.Forget(name)
+	assert.Zero(t, batcher.Defer(name, original, progressed))
+
+	var disabled *StatusBatcher
+	disabled.Written(name)
+	assert.Zero(t, disabled.Defer(name, original, progressed), "nil batchers write every change")
+}
+
+func TestMinorStatusChange(t *testing.T) {
+	original := &v1alpha1.ServiceExportStatus{Endpoints: 1, CloudMapServiceId: "srv-1"}
+
+	counted := original.DeepCopy()
+	counted.Endpoints = 2
+	assert.True(t, minorStatusChange(original, counted))
+
+	moved := counted.DeepCopy()
+	moved.CloudMapServiceId = "srv-2"
+	assert.False(t, minorStatusChange(original, moved))
+
+	conditioned := original.DeepCopy()
+	conditioned.Conditions = []metav1.Condition{{Type: SyncedCondition, Status: metav1.ConditionTrue}}
+	assert.False(t, minorStatusChange(original, conditioned), "added conditions are written right away")
+	assert.False(t, minorStatusChange(nil, counted))
+}
@@ -0,0 +1,52 @@
+package controllers
+
+import (
+	"fmt"
+	"github.com/aws/aws-cloud-map-mcs-controller-for-k8s/pkg/api/v1alpha1"
+	v1 "k8s.io/api/core/v1"
+	"strings"
+	"time"
+)
+
+const (
+	// SyncIntervalAnnotation overrides the interval the ServiceExport is periodically re-exported at, e.g. "1m" for a
+	// critical service or "6h" for a static one, "0" disables the periodic re-export of the service
+	SyncIntervalAnnotation = "multicluster.k8s.aws/sync-interval"
+
+	// InvalidSyncIntervalReason is the event reason for invalid sync interval annotations
+	InvalidSyncIntervalReason = "InvalidSyncInterval"
+
+	// DefaultExportResyncPeriod is the default interval ServiceExports are periodically re-exported at
+	DefaultExportResyncPeriod = 10 * time.Hour
+	// minSyncInterval is the shortest sync interval an annotation may set, to bound the Cloud Map reads of an export
+	minSyncInterval = 10 * time.Second
+)
+
+// parseSyncInterval returns the sync interval annotated on the ServiceExport, and false if none is annotated.
+func parseSyncInterval(serviceExport *v1alpha1.ServiceExport) (time.Duration, bool, error) {
+	value, found := serviceExport.Annotations[SyncIntervalAnnotation]
+	if !found {
+		return 0, false, nil
+	}
+	interval, err := time.ParseDuration(strings.TrimSpace(value))
+	if err != nil || interval < 0 || (interval > 0 && interval < minSyncInterval) {
+		return 0, false, fmt.Errorf("invalid annotation %s: %q is not 0 or a duration of at least %s",
+			SyncIntervalAnnotation, value, minSyncInterval)
+	}
+	return interval, true, nil
+}
+
+// syncInterval returns the interval the ServiceExport is re-exported at, the annotated interval or the resync period
+// of the reconciler. Zero disables the periodic re-export. Invalid annotations fall back to the resync period.
+func (r *ServiceExportReconciler) syncInterval(serviceExport *v1alpha1.ServiceExport) time.Duration {
+	interval, found, err := parseSyncInterval(serviceExport)
+	if err != nil {
+		r.Log.Info("ignoring invalid sync interval", "namespace", serviceExport.Namespace,
+			"name", serviceExport.Name, "reason", err.Error())
+		r.Recorder.Event(serviceExport, v1.EventTypeWarning, InvalidSyncIntervalReason, err.Error())
+	}
+	if found {
+		return interval
+	}
+	return r.ResyncPeriod
+}
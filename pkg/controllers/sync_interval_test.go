@@ -0,0 +1,45 @@
+package controllers
+
+import (
+	"github.com/aws/aws-cloud-map-mcs-controller-for-k8s/pkg/common"
+	"github.com/aws/aws-sdk-go-v2/aws"
+	testingLogger "github.com/go-logr/logr/testing"
+	"github.com/stretchr/testify/assert"
+	"k8s.io/client-go/tools/record"
+	"testing"
+	"time"
+)
+
+func TestServiceExportReconciler_SyncInterval(t *testing.T) {
+	tests := []struct {
+		name       string
+		annotation *string
+		want       time.Duration
+		wantEvent  bool
+	}{
+		{name: "default", want: time.Hour},
+		{name: "critical service", annotation: aws.String("1m"), want: time.Minute},
+		{name: "static service", annotation: aws.String(" 6h "), want: 6 * time.Hour},
+		{name: "disabled", annotation: aws.String("0"), want: 0},
+		{name: "too short", annotation: aws.String("1s"), want: time.Hour, wantEvent: true},
+		{name: "negative", annotation: aws.String("-1m"), want: time.Hour, wantEvent: true},
+		{name: "not a duration", annotation: aws.String("often"), want: time.Hour, wantEvent: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			recorder := record.NewFakeRecorder(1)
+			reconciler := &ServiceExportReconciler{
+				Log:          common.NewLoggerWithLogr(testingLogger.TestLogger{T: t}),
+				Recorder:     recorder,
+				ResyncPeriod: time.Hour,
+			}
+			serviceExport := testServiceExportObj()
+			if tt.annotation != nil {
+				serviceExport.Annotations = map[string]string{SyncIntervalAnnotation: *tt.annotation}
+			}
+
+			assert.Equal(t, tt.want, reconciler.syncInterval(serviceExport))
+			assert.Equal(t, tt.wantEvent, len(recorder.Events) == 1)
+		})
+	}
+}
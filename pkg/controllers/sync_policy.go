@@ -0,0 +1,28 @@
+package controllers
+
+import (
+	"github.com/aws/aws-cloud-map-mcs-controller-for-k8s/pkg/api/v1alpha1"
+	"github.com/aws/aws-cloud-map-mcs-controller-for-k8s/pkg/model"
+)
+
+// allowsDeregistration returns true if the sync policy permits de-registering instances, only the full sync policy,
+// the default, does.
+func allowsDeregistration(policy v1alpha1.SyncPolicy) bool {
+	return policy == "" || policy == v1alpha1.SyncPolicyFullSync
+}
+
+// allowedChanges returns the changes the sync policy permits, and the number of changes it withholds: the upsert only
+// policy withholds the de-registrations, the create only policy also withholds the updates of existing instances.
+func allowedChanges(policy v1alpha1.SyncPolicy, changes model.Changes) (model.Changes, int) {
+	allowed := changes
+	withheld := 0
+	if !allowsDeregistration(policy) {
+		allowed.Delete = nil
+		withheld += len(changes.Delete)
+	}
+	if policy == v1alpha1.SyncPolicyCreateOnly {
+		allowed.Update = nil
+		withheld += len(changes.Update)
+	}
+	return allowed, withheld
+}
@@ -0,0 +1,39 @@
+package controllers
+
+import (
+	"github.com/aws/aws-cloud-map-mcs-controller-for-k8s/pkg/api/v1alpha1"
+	"github.com/aws/aws-cloud-map-mcs-controller-for-k8s/pkg/model"
+	"github.com/aws/aws-cloud-map-mcs-controller-for-k8s/test"
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func TestAllowedChanges(t *testing.T) {
+	changes := model.Changes{
+		Create: []*model.Endpoint{test.GetTestEndpoint1()},
+		Update: []*model.Endpoint{test.GetTestEndpoint2()},
+		Delete: []*model.Endpoint{test.GetTestEndpoint1(), test.GetTestEndpoint2()},
+	}
+	tests := []struct {
+		name           string
+		policy         v1alpha1.SyncPolicy
+		want           model.Changes
+		wantWithheld   int
+		wantDeregister bool
+	}{
+		{name: "default", want: changes, wantDeregister: true},
+		{name: "full sync", policy: v1alpha1.SyncPolicyFullSync, want: changes, wantDeregister: true},
+		{name: "upsert only", policy: v1alpha1.SyncPolicyUpsertOnly,
+			want: model.Changes{Create: changes.Create, Update: changes.Update}, wantWithheld: 2},
+		{name: "create only", policy: v1alpha1.SyncPolicyCreateOnly,
+			want: model.Changes{Create: changes.Create}, wantWithheld: 3},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, withheld := allowedChanges(tt.policy, changes)
+			assert.Equal(t, tt.want, got)
+			assert.Equal(t, tt.wantWithheld, withheld)
+			assert.Equal(t, tt.wantDeregister, allowsDeregistration(tt.policy))
+		})
+	}
+}
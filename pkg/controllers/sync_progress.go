@@ -0,0 +1,61 @@
+package controllers
+
+import (
+	"context"
+	"fmt"
+	"github.com/aws/aws-cloud-map-mcs-controller-for-k8s/pkg/api/v1alpha1"
+	"github.com/aws/aws-cloud-map-mcs-controller-for-k8s/pkg/cloudmap"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+const (
+	// SyncingCondition is the ServiceExport condition type reporting the progress of exports spanning several chunks
+	// of endpoints
+	SyncingCondition = "Syncing"
+	// SyncInProgressReason is the condition reason while the chunks of an export are applied, or once an export
+	// failed part way, in which case the next sync resumes with the remaining endpoints
+	SyncInProgressReason = "CloudMapSyncInProgress"
+	// SyncCompleteReason is the condition reason once all chunks of an export are applied
+	SyncCompleteReason = "CloudMapSyncComplete"
+)
+
+// withSyncProgress returns a context writing the progress of exports spanning several chunks of endpoints to the
+// Syncing condition of the ServiceExport as each chunk completes. The original status is updated with the written
+// status, so the status is written again once the export completes.
+func (r *ServiceExportReconciler) withSyncProgress(ctx context.Context, serviceExport *v1alpha1.ServiceExport, original *v1alpha1.ServiceExportStatus) context.Context {
+	return cloudmap.WithSyncProgress(ctx, func(progress cloudmap.SyncProgress) {
+		verb := "registered"
+		if progress.Action == cloudmap.AuditActionDeregisterInstance {
+			verb = "de-registered"
+		}
+		meta.SetStatusCondition(&serviceExport.Status.Conditions, metav1.Condition{
+			Type:               SyncingCondition,
+			Status:             metav1.ConditionTrue,
+			ObservedGeneration: serviceExport.Generation,
+			Reason:             SyncInProgressReason,
+			Message:            fmt.Sprintf("%s %d of %d endpoints in Cloud Map", verb, progress.Done, progress.Total),
+		})
+
+		// progress is best effort, the status is written again once the export completes
+		if deferred, err := r.updateStatus(ctx, serviceExport, original); err == nil && deferred == 0 {
+			serviceExport.Status.DeepCopyInto(original)
+		}
+	})
+}
+
+// completeSyncingCondition clears the Syncing condition once an export completes. The condition is left unchanged if
+// the export failed, so it records the progress the next sync resumes from.
+func (r *ServiceExportReconciler) completeSyncingCondition(serviceExport *v1alpha1.ServiceExport, err error) {
+	if err != nil || meta.FindStatusCondition(serviceExport.Status.Conditions, SyncingCondition) == nil {
+		return
+	}
+
+	meta.SetStatusCondition(&serviceExport.Status.Conditions, metav1.Condition{
+		Type:               SyncingCondition,
+		Status:             metav1.ConditionFalse,
+		ObservedGeneration: serviceExport.Generation,
+		Reason:             SyncCompleteReason,
+		Message:            "all endpoints are exported to Cloud Map",
+	})
+}
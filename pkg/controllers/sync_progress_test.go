@@ -0,0 +1,70 @@
+package controllers
+
+import (
+	"context"
+	"errors"
+	"github.com/aws/aws-cloud-map-mcs-controller-for-k8s/mocks/pkg/cloudmap"
+	"github.com/aws/aws-cloud-map-mcs-controller-for-k8s/pkg/api/v1alpha1"
+	cmclient "github.com/aws/aws-cloud-map-mcs-controller-for-k8s/pkg/cloudmap"
+	"github.com/aws/aws-cloud-map-mcs-controller-for-k8s/pkg/model"
+	"github.com/aws/aws-cloud-map-mcs-controller-for-k8s/test"
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/assert"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	"testing"
+)
+
+func TestServiceExportReconciler_Reconcile_SyncProgress(t *testing.T) {
+	fakeClient := fake.NewClientBuilder().
+		WithScheme(getServiceExportScheme()).
+		WithObjects(testServiceObj(), testServiceExportObj()).
+		WithLists(testEndpointSliceObj()).
+		Build()
+
+	mockController := gomock.NewController(t)
+	defer mockController.Finish()
+
+	mock := cloudmap.NewMockServiceDiscoveryClient(mockController)
+	mock.EXPECT().GetService(gomock.Any(), test.NsName, test.SvcName).
+		Return(&model.Service{Namespace: test.NsName, Name: test.SvcName}, nil).Times(2)
+	serviceExportName := types.NamespacedName{Namespace: test.NsName, Name: test.SvcName}
+	gomock.InOrder(
+		// the first chunk is registered before the sync fails
+		mock.EXPECT().RegisterEndpoints(gomock.Any(), test.NsName, test.SvcName, gomock.Any()).
+			DoAndReturn(func(ctx context.Context, nsName string, svcName string, endpts []*model.Endpoint) error {
+				cmclient.ReportSyncProgress(ctx, cmclient.SyncProgress{
+					Action: cmclient.AuditActionRegisterInstance, Done: 500, Total: 1000})
+
+				// the progress is written before the sync completes
+				assertSyncingCondition(t, fakeClient, serviceExportName, metav1.ConditionTrue, SyncInProgressReason)
+				return errors.New("timed out polling the second chunk")
+			}),
+		mock.EXPECT().RegisterEndpoints(gomock.Any(), test.NsName, test.SvcName, gomock.Any()).Return(nil),
+	)
+
+	reconciler := getServiceExportReconciler(t, mock, fakeClient)
+	request := ctrl.Request{NamespacedName: serviceExportName}
+
+	_, err := reconciler.Reconcile(context.Background(), request)
+	assert.Error(t, err)
+	assertSyncingCondition(t, fakeClient, serviceExportName, metav1.ConditionTrue, SyncInProgressReason)
+
+	_, err = reconciler.Reconcile(context.Background(), request)
+	assert.NoError(t, err)
+	assertSyncingCondition(t, fakeClient, serviceExportName, metav1.ConditionFalse, SyncCompleteReason)
+}
+
+func assertSyncingCondition(t *testing.T, k8sClient client.Client, name types.NamespacedName, status metav1.ConditionStatus, reason string) {
+	serviceExport := &v1alpha1.ServiceExport{}
+	assert.NoError(t, k8sClient.Get(context.TODO(), name, serviceExport))
+	condition := meta.FindStatusCondition(serviceExport.Status.Conditions, SyncingCondition)
+	if assert.NotNil(t, condition) {
+		assert.Equal(t, status, condition.Status)
+		assert.Equal(t, reason, condition.Reason)
+	}
+}
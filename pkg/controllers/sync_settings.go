@@ -0,0 +1,190 @@
+package controllers
+
+import (
+	"context"
+	goerrors "errors"
+	"fmt"
+	cloudmapv1alpha1 "github.com/aws/aws-cloud-map-mcs-controller-for-k8s/pkg/api/cloudmap/v1alpha1"
+	"github.com/aws/aws-cloud-map-mcs-controller-for-k8s/pkg/cloudmap"
+	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/util/sets"
+	"k8s.io/apimachinery/pkg/util/validation"
+	"regexp"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"strings"
+)
+
+const (
+	// InvalidSyncConfigReason is the event reason for exports of namespaces with an invalid CloudMapSyncConfig
+	InvalidSyncConfigReason = "InvalidSyncConfig"
+
+	// maxDNSTTL is the maximum DNS record TTL accepted by Cloud Map
+	maxDNSTTL int64 = 2147483647
+)
+
+// ErrInvalidSyncConfig is returned for namespaces whose CloudMapSyncConfig is invalid or exceeds the limits of the
+// cluster config. The namespace isn't synchronized until its CloudMapSyncConfig is corrected, rather than with the
+// settings it was meant to override.
+var ErrInvalidSyncConfig = goerrors.New("invalid CloudMapSyncConfig")
+
+var roleArnPattern = regexp.MustCompile(`^arn:aws[a-z-]*:iam::[0-9]{12}:role/.+$`)
+
+// OptionalAttributes are the Cloud Map instance attributes which may be omitted by an attribute allowlist. The export
+// creation timestamp is published along with the exported labels or annotations.
+var OptionalAttributes = sets.NewString(K8sVersionAttr, ExportedLabelsAttr, ExportedAnnotationsAttr, RegionAttr,
+	NodeProviderIdAttr, Ec2InstanceIdAttr)
+
+// SyncSettings are the effective sync settings of a Kubernetes namespace, from the cluster config and the
+// CloudMapSyncConfig of the namespace.
+type SyncSettings struct {
+	// CloudMapNamespace is the Cloud Map namespace the services of the namespace are exported to and imported from
+	CloudMapNamespace string
+	// DNSTTL is the DNS record TTL of created Cloud Map services, the default TTL is used if nil
+	DNSTTL *int64
+	// AttributeAllowlist lists the optional attributes to publish, all attributes are published if empty
+	AttributeAllowlist []string
+	// CleanupPolicy controls what happens to the Cloud Map endpoints of a deleted ServiceExport
+	CleanupPolicy cloudmapv1alpha1.CleanupPolicy
+	// RoleArn is the IAM role assumed for the Cloud Map operations of the namespace, the credentials of the
+	// controller are used if empty
+	RoleArn string
+}
+
+// ResolveSyncSettings returns the sync settings of a Kubernetes namespace. An error wrapping ErrInvalidSyncConfig is
+// returned if the CloudMapSyncConfig of the namespace is invalid or exceeds the limits of the cluster config.
+func ResolveSyncSettings(ctx context.Context, c client.Client, clusterConfig *ClusterConfig, namespace string) (SyncSettings, error) {
+	limits := clusterConfig.SyncConfigLimits()
+	settings := SyncSettings{
+		CloudMapNamespace:  clusterConfig.CloudMapNamespace(namespace),
+		AttributeAllowlist: limits.AllowedAttributes,
+		CleanupPolicy:      clusterConfig.CleanupPolicy(),
+	}
+
+	syncConfig := &cloudmapv1alpha1.CloudMapSyncConfig{}
+	name := types.NamespacedName{Namespace: namespace, Name: cloudmapv1alpha1.CloudMapSyncConfigName}
+	if err := c.Get(ctx, name, syncConfig); err != nil {
+		if errors.IsNotFound(err) {
+			return settings, nil
+		}
+		return settings, err
+	}
+
+	spec := syncConfig.Spec
+	if errs := ValidateCloudMapSyncConfig(&spec, &limits); len(errs) > 0 {
+		return settings, fmt.Errorf("%w of namespace %s: %s", ErrInvalidSyncConfig, namespace, strings.Join(errs, "; "))
+	}
+
+	if spec.CloudMapNamespace != "" {
+		settings.CloudMapNamespace = spec.CloudMapNamespace
+	}
+	if spec.DNSTTL != nil {
+		ttl := *spec.DNSTTL
+		settings.DNSTTL = &ttl
+	}
+	if len(spec.AttributeAllowlist) > 0 {
+		settings.AttributeAllowlist = spec.AttributeAllowlist
+	}
+	if spec.CleanupPolicy != "" {
+		settings.CleanupPolicy = spec.CleanupPolicy
+	}
+	settings.RoleArn = spec.RoleArn
+	return settings, nil
+}
+
+// WithCredentials returns a context which calls Cloud Map with the IAM role of the namespace, if it has one.
+func (s SyncSettings) WithCredentials(ctx context.Context, namespace string) context.Context {
+	if s.RoleArn == "" {
+		return ctx
+	}
+	return cloudmap.WithNamespaceRole(ctx, namespace, s.RoleArn)
+}
+
+// FilterAttributes removes the optional attributes which are not in the allowlist, an empty allowlist keeps all.
+func (s SyncSettings) FilterAttributes(attributes map[string]string) {
+	if len(s.AttributeAllowlist) == 0 {
+		return
+	}
+
+	allowed := sets.NewString(s.AttributeAllowlist...)
+	for attr := range OptionalAttributes {
+		if !allowed.Has(attr) {
+			delete(attributes, attr)
+		}
+	}
+	if !allowed.HasAny(ExportedLabelsAttr, ExportedAnnotationsAttr) {
+		delete(attributes, ExportCreationTimestampAttr)
+	}
+}
+
+// ValidateCloudMapSyncConfig returns the validation errors of a CloudMapSyncConfig, including settings exceeding
+// the limits of the cluster config.
+func ValidateCloudMapSyncConfig(spec *cloudmapv1alpha1.CloudMapSyncConfigSpec, limits *cloudmapv1alpha1.SyncConfigLimits) []string {
+	errs := make([]string, 0)
+
+	if spec.CloudMapNamespace != "" {
+		if !limits.AllowCloudMapNamespaceOverride {
+			errs = append(errs, "the cluster config does not allow overriding the Cloud Map namespace")
+		} else if msgs := validation.IsDNS1123Subdomain(spec.CloudMapNamespace); len(msgs) > 0 {
+			errs = append(errs, fmt.Sprintf("cloudMapNamespace %s is not a valid Cloud Map namespace name: %s",
+				spec.CloudMapNamespace, strings.Join(msgs, ", ")))
+		}
+	}
+
+	if spec.DNSTTL != nil {
+		ttl := *spec.DNSTTL
+		switch {
+		case ttl < 0 || ttl > maxDNSTTL:
+			errs = append(errs, fmt.Sprintf("dnsTTL must be between 0 and %d", maxDNSTTL))
+		case limits.MinDNSTTL != nil && ttl < *limits.MinDNSTTL:
+			errs = append(errs, fmt.Sprintf("dnsTTL is below the minimum of %d", *limits.MinDNSTTL))
+		case limits.MaxDNSTTL != nil && ttl > *limits.MaxDNSTTL:
+			errs = append(errs, fmt.Sprintf("dnsTTL is above the maximum of %d", *limits.MaxDNSTTL))
+		}
+	}
+
+	allowedAttributes := OptionalAttributes
+	if len(limits.AllowedAttributes) > 0 {
+		allowedAttributes = sets.NewString(limits.AllowedAttributes...)
+	}
+	for _, attr := range spec.AttributeAllowlist {
+		if !OptionalAttributes.Has(attr) {
+			errs = append(errs, fmt.Sprintf("unknown attribute %s in attributeAllowlist, must be one of %s",
+				attr, strings.Join(OptionalAttributes.List(), ", ")))
+		} else if !allowedAttributes.Has(attr) {
+			errs = append(errs, fmt.Sprintf("the cluster config does not allow publishing attribute %s", attr))
+		}
+	}
+
+	if spec.RoleArn != "" {
+		if !limits.AllowRoleArnOverride {
+			errs = append(errs, "the cluster config does not allow assuming an IAM role of the namespace")
+		} else if !roleArnPattern.MatchString(spec.RoleArn) {
+			errs = append(errs, fmt.Sprintf("roleArn %s is not a valid IAM role ARN", spec.RoleArn))
+		}
+	}
+
+	switch spec.CleanupPolicy {
+	case "":
+	case cloudmapv1alpha1.CleanupPolicyDelete, cloudmapv1alpha1.CleanupPolicyRetain:
+		if !cleanupPolicyAllowed(spec.CleanupPolicy, limits.AllowedCleanupPolicies) {
+			errs = append(errs, fmt.Sprintf("the cluster config does not allow cleanupPolicy %s", spec.CleanupPolicy))
+		}
+	default:
+		errs = append(errs, fmt.Sprintf("unsupported cleanupPolicy %s", spec.CleanupPolicy))
+	}
+
+	return errs
+}
+
+func cleanupPolicyAllowed(policy cloudmapv1alpha1.CleanupPolicy, allowed []cloudmapv1alpha1.CleanupPolicy) bool {
+	if len(allowed) == 0 {
+		return true
+	}
+	for _, p := range allowed {
+		if p == policy {
+			return true
+		}
+	}
+	return false
+}
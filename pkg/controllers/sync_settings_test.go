@@ -0,0 +1,199 @@
+package controllers
+
+import (
+	"context"
+	"errors"
+	cloudmapv1alpha1 "github.com/aws/aws-cloud-map-mcs-controller-for-k8s/pkg/api/cloudmap/v1alpha1"
+	"github.com/aws/aws-cloud-map-mcs-controller-for-k8s/pkg/cloudmap"
+	"github.com/aws/aws-cloud-map-mcs-controller-for-k8s/test"
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/stretchr/testify/assert"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	"testing"
+)
+
+func TestResolveSyncSettings(t *testing.T) {
+	clusterSpec := &cloudmapv1alpha1.ClusterCloudMapConfigSpec{
+		NamespaceMapping: cloudmapv1alpha1.NamespaceMapping{Prefix: "prod-"},
+		SyncConfigLimits: cloudmapv1alpha1.SyncConfigLimits{
+			AllowCloudMapNamespaceOverride: true,
+			MaxDNSTTL:                      aws.Int64(300),
+			AllowedAttributes:              []string{ExportedLabelsAttr, ExportedAnnotationsAttr},
+			AllowRoleArnOverride:           true,
+		},
+	}
+	defaults := SyncSettings{
+		CloudMapNamespace:  "prod-" + test.NsName,
+		AttributeAllowlist: []string{ExportedLabelsAttr, ExportedAnnotationsAttr},
+		CleanupPolicy:      cloudmapv1alpha1.CleanupPolicyDelete,
+	}
+
+	tests := []struct {
+		name       string
+		syncConfig *cloudmapv1alpha1.CloudMapSyncConfig
+		want       SyncSettings
+		wantErr    bool
+	}{
+		{
+			name: "cluster settings without sync config",
+			want: defaults,
+		},
+		{
+			name: "sync config overrides cluster settings",
+			syncConfig: testSyncConfig(cloudmapv1alpha1.CloudMapSyncConfigName, cloudmapv1alpha1.CloudMapSyncConfigSpec{
+				CloudMapNamespace:  "shared",
+				DNSTTL:             aws.Int64(30),
+				AttributeAllowlist: []string{ExportedLabelsAttr},
+				CleanupPolicy:      cloudmapv1alpha1.CleanupPolicyRetain,
+				RoleArn:            "arn:aws:iam::123456789012:role/tenant",
+			}),
+			want: SyncSettings{
+				CloudMapNamespace:  "shared",
+				DNSTTL:             aws.Int64(30),
+				AttributeAllowlist: []string{ExportedLabelsAttr},
+				CleanupPolicy:      cloudmapv1alpha1.CleanupPolicyRetain,
+				RoleArn:            "arn:aws:iam::123456789012:role/tenant",
+			},
+		},
+		{
+			name: "sync config exceeding limits is rejected",
+			syncConfig: testSyncConfig(cloudmapv1alpha1.CloudMapSyncConfigName, cloudmapv1alpha1.CloudMapSyncConfigSpec{
+				CloudMapNamespace: "shared",
+				DNSTTL:            aws.Int64(3600),
+			}),
+			want:    defaults,
+			wantErr: true,
+		},
+		{
+			name: "sync config with other name is ignored",
+			syncConfig: testSyncConfig("other", cloudmapv1alpha1.CloudMapSyncConfigSpec{
+				CloudMapNamespace: "shared",
+			}),
+			want: defaults,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			builder := fake.NewClientBuilder().WithScheme(getSyncConfigScheme())
+			if tt.syncConfig != nil {
+				builder = builder.WithObjects(tt.syncConfig)
+			}
+			clusterConfig := NewClusterConfig()
+			clusterConfig.set(clusterSpec)
+
+			got, err := ResolveSyncSettings(context.TODO(), builder.Build(), clusterConfig, test.NsName)
+			if tt.wantErr {
+				assert.True(t, errors.Is(err, ErrInvalidSyncConfig))
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func TestValidateCloudMapSyncConfig(t *testing.T) {
+	limits := &cloudmapv1alpha1.SyncConfigLimits{
+		MinDNSTTL:              aws.Int64(10),
+		AllowedCleanupPolicies: []cloudmapv1alpha1.CleanupPolicy{cloudmapv1alpha1.CleanupPolicyDelete},
+	}
+
+	tests := []struct {
+		name     string
+		spec     cloudmapv1alpha1.CloudMapSyncConfigSpec
+		wantErrs int
+	}{
+		{
+			name: "within limits",
+			spec: cloudmapv1alpha1.CloudMapSyncConfigSpec{
+				DNSTTL:             aws.Int64(10),
+				AttributeAllowlist: []string{K8sVersionAttr},
+				CleanupPolicy:      cloudmapv1alpha1.CleanupPolicyDelete,
+			},
+		},
+		{
+			name:     "namespace override not allowed",
+			spec:     cloudmapv1alpha1.CloudMapSyncConfigSpec{CloudMapNamespace: "shared"},
+			wantErrs: 1,
+		},
+		{
+			name:     "ttl below minimum",
+			spec:     cloudmapv1alpha1.CloudMapSyncConfigSpec{DNSTTL: aws.Int64(5)},
+			wantErrs: 1,
+		},
+		{
+			name:     "unknown attribute",
+			spec:     cloudmapv1alpha1.CloudMapSyncConfigSpec{AttributeAllowlist: []string{"AWS_INSTANCE_IPV4"}},
+			wantErrs: 1,
+		},
+		{
+			name:     "role override not allowed",
+			spec:     cloudmapv1alpha1.CloudMapSyncConfigSpec{RoleArn: "arn:aws:iam::123456789012:role/tenant"},
+			wantErrs: 1,
+		},
+		{
+			name:     "cleanup policy not allowed",
+			spec:     cloudmapv1alpha1.CloudMapSyncConfigSpec{CleanupPolicy: cloudmapv1alpha1.CleanupPolicyRetain},
+			wantErrs: 1,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Len(t, ValidateCloudMapSyncConfig(&tt.spec, limits), tt.wantErrs)
+		})
+	}
+}
+
+func TestValidateCloudMapSyncConfig_RoleArn(t *testing.T) {
+	limits := &cloudmapv1alpha1.SyncConfigLimits{AllowRoleArnOverride: true}
+	for roleArn, wantErrs := range map[string]int{
+		"arn:aws:iam::123456789012:role/tenant":          0,
+		"arn:aws-cn:iam::123456789012:role/team/tenant":  0,
+		"arn:aws:iam::123456789012:user/tenant":          1,
+		"arn:aws:sts::123456789012:assumed-role/tenant/": 1,
+		"tenant": 1,
+	} {
+		spec := cloudmapv1alpha1.CloudMapSyncConfigSpec{RoleArn: roleArn}
+		assert.Len(t, ValidateCloudMapSyncConfig(&spec, limits), wantErrs, roleArn)
+	}
+}
+
+func TestSyncSettings_WithCredentials(t *testing.T) {
+	ctx := SyncSettings{}.WithCredentials(context.TODO(), test.NsName)
+	_, found := cloudmap.NamespaceRoleFromContext(ctx)
+	assert.False(t, found)
+
+	ctx = SyncSettings{RoleArn: "arn:aws:iam::123456789012:role/tenant"}.WithCredentials(context.TODO(), test.NsName)
+	role, found := cloudmap.NamespaceRoleFromContext(ctx)
+	assert.True(t, found)
+	assert.Equal(t, cloudmap.NamespaceRole{Namespace: test.NsName, RoleArn: "arn:aws:iam::123456789012:role/tenant"},
+		role)
+}
+
+func TestSyncSettings_FilterAttributes(t *testing.T) {
+	attributes := map[string]string{
+		K8sVersionAttr:              "version",
+		ExportedLabelsAttr:          "{}",
+		ExportedAnnotationsAttr:     "{}",
+		ExportCreationTimestampAttr: "2021-01-01T00:00:00Z",
+	}
+
+	SyncSettings{AttributeAllowlist: []string{K8sVersionAttr}}.FilterAttributes(attributes)
+	assert.Equal(t, map[string]string{K8sVersionAttr: "version"}, attributes)
+}
+
+func testSyncConfig(name string, spec cloudmapv1alpha1.CloudMapSyncConfigSpec) *cloudmapv1alpha1.CloudMapSyncConfig {
+	return &cloudmapv1alpha1.CloudMapSyncConfig{
+		ObjectMeta: metav1.ObjectMeta{Namespace: test.NsName, Name: name},
+		Spec:       spec,
+	}
+}
+
+func getSyncConfigScheme() *runtime.Scheme {
+	scheme := runtime.NewScheme()
+	scheme.AddKnownTypes(cloudmapv1alpha1.GroupVersion,
+		&cloudmapv1alpha1.CloudMapSyncConfig{}, &cloudmapv1alpha1.CloudMapSyncConfigList{})
+	return scheme
+}
@@ -18,11 +18,47 @@ func ServicePortToPort(svcPort v1.ServicePort) model.Port {
 }
 
 func EndpointPortToPort(port discovery.EndpointPort) model.Port {
+	protocol := v1.ProtocolTCP
+	if port.Protocol != nil {
+		protocol = *port.Protocol
+	}
 	return model.Port{
-		Name:     *port.Name,
+		Name:     endpointPortName(port),
 		Port:     *port.Port,
-		Protocol: protocolToString(*port.Protocol),
+		Protocol: protocolToString(protocol),
+	}
+}
+
+// endpointPortName returns the name of the EndpointSlice port, empty if unset.
+func endpointPortName(port discovery.EndpointPort) string {
+	if port.Name == nil {
+		return ""
+	}
+	return *port.Name
+}
+
+// endpointReady returns a copy of the ready condition of the endpoint, nil if unknown.
+func endpointReady(endpoint discovery.Endpoint) *bool {
+	if endpoint.Conditions.Ready == nil {
+		return nil
+	}
+	ready := *endpoint.Conditions.Ready
+	return &ready
+}
+
+func endpointHostname(endpoint discovery.Endpoint) string {
+	if endpoint.Hostname == nil {
+		return ""
+	}
+	return *endpoint.Hostname
+}
+
+// endpointNodename returns the node name of the endpoint, falling back to the deprecated hostname topology label.
+func endpointNodename(endpoint discovery.Endpoint) string {
+	if endpoint.NodeName != nil && *endpoint.NodeName != "" {
+		return *endpoint.NodeName
 	}
+	return endpoint.Topology[v1.LabelHostname]
 }
 
 func PortToServicePort(port model.Port) v1.ServicePort {
@@ -181,3 +181,13 @@ func TestPortToEndpointPort(t *testing.T) {
 		})
 	}
 }
+
+func TestEndpointNodename(t *testing.T) {
+	nodeName := "node-1"
+	if got := endpointNodename(v1beta1.Endpoint{NodeName: &nodeName, Topology: map[string]string{v1.LabelHostname: "node-2"}}); got != nodeName {
+		t.Errorf("endpointNodename() = %v, want %v", got, nodeName)
+	}
+	if got := endpointNodename(v1beta1.Endpoint{Topology: map[string]string{v1.LabelHostname: "node-2"}}); got != "node-2" {
+		t.Errorf("endpointNodename() = %v, want the hostname topology label", got)
+	}
+}
@@ -0,0 +1,83 @@
+package debug
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	authenticationv1 "k8s.io/api/authentication/v1"
+	authorizationv1 "k8s.io/api/authorization/v1"
+	"net/http"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"strings"
+)
+
+var (
+	// ErrUnauthenticated is returned for requests without valid credentials
+	ErrUnauthenticated = errors.New("unauthenticated")
+	// ErrForbidden is returned for authenticated requests which are not allowed
+	ErrForbidden = errors.New("forbidden")
+)
+
+// Authorizer decides whether a request may read the debug endpoints.
+type Authorizer interface {
+	// Authorize returns an error wrapping ErrUnauthenticated or ErrForbidden if the request is rejected.
+	Authorize(ctx context.Context, req *http.Request) error
+}
+
+// KubernetesAuthorizer authenticates the bearer token of requests with a TokenReview, and authorizes the user to get
+// the request path as non-resource URL with a SubjectAccessReview, like the API server does.
+type KubernetesAuthorizer struct {
+	Client client.Client
+}
+
+// +kubebuilder:rbac:groups=authentication.k8s.io,resources=tokenreviews,verbs=create
+// +kubebuilder:rbac:groups=authorization.k8s.io,resources=subjectaccessreviews,verbs=create
+
+func (a *KubernetesAuthorizer) Authorize(ctx context.Context, req *http.Request) error {
+	token := bearerToken(req)
+	if token == "" {
+		return fmt.Errorf("%w: missing bearer token", ErrUnauthenticated)
+	}
+
+	review := &authenticationv1.TokenReview{Spec: authenticationv1.TokenReviewSpec{Token: token}}
+	if err := a.Client.Create(ctx, review); err != nil {
+		return fmt.Errorf("unable to review token: %w", err)
+	}
+	if !review.Status.Authenticated {
+		return fmt.Errorf("%w: %s", ErrUnauthenticated, review.Status.Error)
+	}
+
+	user := review.Status.User
+	extra := make(map[string]authorizationv1.ExtraValue, len(user.Extra))
+	for key, value := range user.Extra {
+		extra[key] = authorizationv1.ExtraValue(value)
+	}
+	accessReview := &authorizationv1.SubjectAccessReview{
+		Spec: authorizationv1.SubjectAccessReviewSpec{
+			User:   user.Username,
+			UID:    user.UID,
+			Groups: user.Groups,
+			Extra:  extra,
+			NonResourceAttributes: &authorizationv1.NonResourceAttributes{
+				Path: req.URL.Path,
+				Verb: "get",
+			},
+		},
+	}
+	if err := a.Client.Create(ctx, accessReview); err != nil {
+		return fmt.Errorf("unable to review access: %w", err)
+	}
+	if !accessReview.Status.Allowed {
+		return fmt.Errorf("%w: user %s may not get %s", ErrForbidden, user.Username, req.URL.Path)
+	}
+	return nil
+}
+
+func bearerToken(req *http.Request) string {
+	header := req.Header.Get("Authorization")
+	const prefix = "Bearer "
+	if !strings.HasPrefix(header, prefix) {
+		return ""
+	}
+	return strings.TrimSpace(strings.TrimPrefix(header, prefix))
+}
@@ -0,0 +1,28 @@
+package debug
+
+import (
+	"context"
+	"errors"
+	"github.com/stretchr/testify/assert"
+	"net/http"
+	"net/http/httptest"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	"testing"
+)
+
+func TestKubernetesAuthorizer_MissingToken(t *testing.T) {
+	authorizer := &KubernetesAuthorizer{Client: fake.NewClientBuilder().Build()}
+	err := authorizer.Authorize(context.TODO(), httptest.NewRequest(http.MethodGet, StatePath, nil))
+	assert.True(t, errors.Is(err, ErrUnauthenticated))
+}
+
+func TestBearerToken(t *testing.T) {
+	req := httptest.NewRequest(http.MethodGet, StatePath, nil)
+	assert.Empty(t, bearerToken(req))
+
+	req.Header.Set("Authorization", "Basic dXNlcg==")
+	assert.Empty(t, bearerToken(req))
+
+	req.Header.Set("Authorization", "Bearer token")
+	assert.Equal(t, "token", bearerToken(req))
+}
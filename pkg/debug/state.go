@@ -0,0 +1,62 @@
+package debug
+
+import (
+	"encoding/json"
+	"errors"
+	"github.com/aws/aws-cloud-map-mcs-controller-for-k8s/pkg/cloudmap"
+	"github.com/aws/aws-cloud-map-mcs-controller-for-k8s/pkg/common"
+	"github.com/aws/aws-cloud-map-mcs-controller-for-k8s/pkg/controllers"
+	"net/http"
+	"time"
+)
+
+// StatePath is the path the state endpoint is served at.
+const StatePath = "/debug/state"
+
+// State is the internal state of the controller: the outcome of the last export of each service, and the pending
+// operations and cached resources of the Cloud Map client.
+type State struct {
+	GeneratedAt time.Time                 `json:"generatedAt"`
+	Exports     []controllers.ExportState `json:"exports"`
+	CloudMap    cloudmap.ClientState      `json:"cloudMap"`
+}
+
+// StateHandler serves the State of the controller as JSON to authorized requests.
+type StateHandler struct {
+	Log          common.Logger
+	Authorizer   Authorizer
+	ExportStates *controllers.ExportStates
+	CloudMap     cloudmap.ServiceDiscoveryClient
+}
+
+func (h *StateHandler) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	if req.Method != http.MethodGet {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	if err := h.Authorizer.Authorize(req.Context(), req); err != nil {
+		switch {
+		case errors.Is(err, ErrUnauthenticated):
+			http.Error(w, err.Error(), http.StatusUnauthorized)
+		case errors.Is(err, ErrForbidden):
+			http.Error(w, err.Error(), http.StatusForbidden)
+		default:
+			h.Log.Error(err, "unable to authorize debug state request")
+			http.Error(w, "unable to authorize request", http.StatusInternalServerError)
+		}
+		return
+	}
+
+	state := State{
+		GeneratedAt: time.Now().UTC(),
+		Exports:     h.ExportStates.List(),
+		CloudMap:    h.CloudMap.State(),
+	}
+	w.Header().Set("Content-Type", "application/json")
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	if err := encoder.Encode(state); err != nil {
+		h.Log.Error(err, "unable to write debug state")
+	}
+}
@@ -0,0 +1,92 @@
+package debug
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"github.com/aws/aws-cloud-map-mcs-controller-for-k8s/mocks/pkg/cloudmap"
+	cloudmapapi "github.com/aws/aws-cloud-map-mcs-controller-for-k8s/pkg/cloudmap"
+	"github.com/aws/aws-cloud-map-mcs-controller-for-k8s/pkg/common"
+	"github.com/aws/aws-cloud-map-mcs-controller-for-k8s/pkg/controllers"
+	"github.com/aws/aws-cloud-map-mcs-controller-for-k8s/test"
+	testing2 "github.com/go-logr/logr/testing"
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/assert"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+type authorizerFunc func(ctx context.Context, req *http.Request) error
+
+func (f authorizerFunc) Authorize(ctx context.Context, req *http.Request) error {
+	return f(ctx, req)
+}
+
+func TestStateHandler(t *testing.T) {
+	mockController := gomock.NewController(t)
+	defer mockController.Finish()
+
+	sdClient := cloudmap.NewMockServiceDiscoveryClient(mockController)
+	sdClient.EXPECT().State().Return(cloudmapapi.ClientState{
+		PendingOperations: []cloudmapapi.PendingOperation{{Action: cloudmapapi.AuditActionRegisterInstance,
+			Namespace: test.NsName, Service: test.SvcName, ServiceId: test.SvcId, OperationIds: []string{"op"}}},
+		Cache: cloudmapapi.CacheSummary{Namespaces: 1},
+	})
+
+	exportStates := controllers.NewExportStates()
+	exportStates.Record(controllers.ExportState{Namespace: test.NsName, Name: test.SvcName, DesiredEndpoints: 1})
+
+	handler := &StateHandler{
+		Log:          common.NewLoggerWithLogr(testing2.TestLogger{T: t}),
+		Authorizer:   authorizerFunc(func(ctx context.Context, req *http.Request) error { return nil }),
+		ExportStates: exportStates,
+		CloudMap:     sdClient,
+	}
+	recorder := httptest.NewRecorder()
+	handler.ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, StatePath, nil))
+
+	assert.Equal(t, http.StatusOK, recorder.Code)
+	assert.Equal(t, "application/json", recorder.Header().Get("Content-Type"))
+	state := State{}
+	assert.NoError(t, json.Unmarshal(recorder.Body.Bytes(), &state))
+	if assert.Len(t, state.Exports, 1) {
+		assert.Equal(t, test.SvcName, state.Exports[0].Name)
+		assert.Equal(t, 1, state.Exports[0].DesiredEndpoints)
+	}
+	if assert.Len(t, state.CloudMap.PendingOperations, 1) {
+		assert.Equal(t, []string{"op"}, state.CloudMap.PendingOperations[0].OperationIds)
+	}
+	assert.Equal(t, 1, state.CloudMap.Cache.Namespaces)
+}
+
+func TestStateHandler_Rejected(t *testing.T) {
+	tests := []struct {
+		name   string
+		err    error
+		status int
+	}{
+		{name: "unauthenticated", err: fmt.Errorf("%w: missing bearer token", ErrUnauthenticated),
+			status: http.StatusUnauthorized},
+		{name: "forbidden", err: fmt.Errorf("%w: user may not get", ErrForbidden), status: http.StatusForbidden},
+		{name: "error", err: fmt.Errorf("unable to review token"), status: http.StatusInternalServerError},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			handler := &StateHandler{
+				Log:        common.NewLoggerWithLogr(testing2.TestLogger{T: t}),
+				Authorizer: authorizerFunc(func(ctx context.Context, req *http.Request) error { return tt.err }),
+			}
+			recorder := httptest.NewRecorder()
+			handler.ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, StatePath, nil))
+			assert.Equal(t, tt.status, recorder.Code)
+		})
+	}
+}
+
+func TestStateHandler_MethodNotAllowed(t *testing.T) {
+	handler := &StateHandler{Log: common.NewLoggerWithLogr(testing2.TestLogger{T: t})}
+	recorder := httptest.NewRecorder()
+	handler.ServeHTTP(recorder, httptest.NewRequest(http.MethodPost, StatePath, nil))
+	assert.Equal(t, http.StatusMethodNotAllowed, recorder.Code)
+}
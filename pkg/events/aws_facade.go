@@ -0,0 +1,60 @@
+package events
+
+import (
+	"context"
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/eventbridge"
+	"github.com/aws/aws-sdk-go-v2/service/sns"
+	"github.com/aws/aws-sdk-go-v2/service/sqs"
+)
+
+// SqsFacade wraps the minimal surface area of SQS API calls required to consume Cloud Map change events. This enables
+// mock generation for unit testing.
+type SqsFacade interface {
+	// ReceiveMessage provides SQS ReceiveMessage wrapper interface.
+	ReceiveMessage(context.Context, *sqs.ReceiveMessageInput, ...func(*sqs.Options)) (*sqs.ReceiveMessageOutput, error)
+
+	// DeleteMessageBatch provides SQS DeleteMessageBatch wrapper interface.
+	DeleteMessageBatch(context.Context, *sqs.DeleteMessageBatchInput, ...func(*sqs.Options)) (*sqs.DeleteMessageBatchOutput, error)
+}
+
+type sqsFacade struct {
+	*sqs.Client
+}
+
+// NewSqsFacadeFromConfig creates a new SQS facade from an AWS client config.
+func NewSqsFacadeFromConfig(cfg *aws.Config) SqsFacade {
+	return &sqsFacade{sqs.NewFromConfig(*cfg)}
+}
+
+// SnsFacade wraps the SNS API calls required to publish clusterset events. This enables mock generation for unit
+// testing.
+type SnsFacade interface {
+	// Publish provides SNS Publish wrapper interface.
+	Publish(context.Context, *sns.PublishInput, ...func(*sns.Options)) (*sns.PublishOutput, error)
+}
+
+type snsFacade struct {
+	*sns.Client
+}
+
+// NewSnsFacadeFromConfig creates a new SNS facade from an AWS client config.
+func NewSnsFacadeFromConfig(cfg *aws.Config) SnsFacade {
+	return &snsFacade{sns.NewFromConfig(*cfg)}
+}
+
+// EventBridgeFacade wraps the EventBridge API calls required to publish clusterset events. This enables mock
+// generation for unit testing.
+type EventBridgeFacade interface {
+	// PutEvents provides EventBridge PutEvents wrapper interface.
+	PutEvents(context.Context, *eventbridge.PutEventsInput, ...func(*eventbridge.Options)) (*eventbridge.PutEventsOutput, error)
+}
+
+type eventBridgeFacade struct {
+	*eventbridge.Client
+}
+
+// NewEventBridgeFacadeFromConfig creates a new EventBridge facade from an AWS client config.
+func NewEventBridgeFacadeFromConfig(cfg *aws.Config) EventBridgeFacade {
+	return &eventBridgeFacade{eventbridge.NewFromConfig(*cfg)}
+}
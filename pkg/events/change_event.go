@@ -0,0 +1,69 @@
+package events
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+)
+
+const (
+	// cloudMapEventSource is the EventBridge source of the CloudTrail records of Cloud Map API calls
+	cloudMapEventSource  = "aws.servicediscovery"
+	cloudTrailDetailType = "AWS API Call via CloudTrail"
+)
+
+// ErrNotChangeEvent is returned for events which don't change the instances of a Cloud Map service.
+var ErrNotChangeEvent = errors.New("not a Cloud Map change event")
+
+// serviceIdParameters are the request parameters holding the service ID of the Cloud Map API calls which change the
+// instances of a service
+var serviceIdParameters = map[string]string{
+	"RegisterInstance":                 "serviceId",
+	"DeregisterInstance":               "serviceId",
+	"UpdateInstanceCustomHealthStatus": "serviceId",
+	"DeleteService":                    "id",
+}
+
+// ChangeEvent is a change of the instances of a Cloud Map service, parsed from the CloudTrail record of the Cloud Map
+// API call which EventBridge delivers.
+type ChangeEvent struct {
+	// Action is the Cloud Map API action, e.g. RegisterInstance
+	Action    string
+	ServiceId string
+	Time      time.Time
+}
+
+type eventBridgeEvent struct {
+	Source     string    `json:"source"`
+	DetailType string    `json:"detail-type"`
+	Time       time.Time `json:"time"`
+	Detail     struct {
+		EventName         string                 `json:"eventName"`
+		ErrorCode         string                 `json:"errorCode"`
+		RequestParameters map[string]interface{} `json:"requestParameters"`
+	} `json:"detail"`
+}
+
+// ParseChangeEvent parses a Cloud Map change event from an EventBridge event. Events of other sources, failed calls
+// and calls which don't change instances return ErrNotChangeEvent.
+func ParseChangeEvent(body []byte) (ChangeEvent, error) {
+	event := eventBridgeEvent{}
+	if err := json.Unmarshal(body, &event); err != nil {
+		return ChangeEvent{}, fmt.Errorf("invalid EventBridge event: %w", err)
+	}
+	if event.Source != cloudMapEventSource || event.DetailType != cloudTrailDetailType || event.Detail.ErrorCode != "" {
+		return ChangeEvent{}, ErrNotChangeEvent
+	}
+
+	parameter, found := serviceIdParameters[event.Detail.EventName]
+	if !found {
+		return ChangeEvent{}, ErrNotChangeEvent
+	}
+	serviceId, _ := event.Detail.RequestParameters[parameter].(string)
+	if serviceId == "" {
+		return ChangeEvent{}, fmt.Errorf("%s event without service ID", event.Detail.EventName)
+	}
+
+	return ChangeEvent{Action: event.Detail.EventName, ServiceId: serviceId, Time: event.Time}, nil
+}
@@ -0,0 +1,77 @@
+package events
+
+import (
+	"errors"
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func TestParseChangeEvent(t *testing.T) {
+	tests := []struct {
+		name      string
+		body      string
+		want      ChangeEvent
+		wantErr   error
+		wantFails bool
+	}{
+		{
+			name: "register instance",
+			body: cloudTrailEvent("RegisterInstance",
+				`{"serviceId": "srv-1", "instanceId": "i-1", "attributes": {"AWS_INSTANCE_IPV4": "192.168.0.1"}}`),
+			want: ChangeEvent{Action: "RegisterInstance", ServiceId: "srv-1"},
+		},
+		{
+			name: "delete service",
+			body: cloudTrailEvent("DeleteService", `{"id": "srv-1"}`),
+			want: ChangeEvent{Action: "DeleteService", ServiceId: "srv-1"},
+		},
+		{
+			name:    "other action",
+			body:    cloudTrailEvent("ListServices", `{}`),
+			wantErr: ErrNotChangeEvent,
+		},
+		{
+			name:    "other source",
+			body:    `{"source": "aws.ec2", "detail-type": "AWS API Call via CloudTrail"}`,
+			wantErr: ErrNotChangeEvent,
+		},
+		{
+			name: "failed call",
+			body: `{"source": "aws.servicediscovery", "detail-type": "AWS API Call via CloudTrail", ` +
+				`"detail": {"eventName": "RegisterInstance", "errorCode": "ServiceNotFound"}}`,
+			wantErr: ErrNotChangeEvent,
+		},
+		{
+			name:      "missing service ID",
+			body:      cloudTrailEvent("DeregisterInstance", `{"instanceId": "i-1"}`),
+			wantFails: true,
+		},
+		{
+			name:      "invalid JSON",
+			body:      "{",
+			wantFails: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ParseChangeEvent([]byte(tt.body))
+			switch {
+			case tt.wantErr != nil:
+				assert.True(t, errors.Is(err, tt.wantErr), err)
+			case tt.wantFails:
+				assert.Error(t, err)
+			default:
+				assert.NoError(t, err)
+				assert.Equal(t, tt.want.Action, got.Action)
+				assert.Equal(t, tt.want.ServiceId, got.ServiceId)
+				assert.False(t, got.Time.IsZero())
+			}
+		})
+	}
+}
+
+func cloudTrailEvent(eventName string, requestParameters string) string {
+	return `{"source": "aws.servicediscovery", "detail-type": "AWS API Call via CloudTrail", ` +
+		`"time": "2021-08-20T10:00:00Z", "detail": {"eventName": "` + eventName + `", ` +
+		`"requestParameters": ` + requestParameters + `}}`
+}
@@ -0,0 +1,106 @@
+package events
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/aws/aws-cloud-map-mcs-controller-for-k8s/pkg/common"
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/sqs"
+	"github.com/aws/aws-sdk-go-v2/service/sqs/types"
+	"k8s.io/apimachinery/pkg/util/sets"
+	"strconv"
+	"time"
+)
+
+const (
+	// maxMessages is the largest number of messages SQS returns per ReceiveMessage call
+	maxMessages = 10
+	// waitTimeSeconds long polls the queue for the longest time SQS allows
+	waitTimeSeconds = 20
+	// receiveRetryInterval is the delay before receiving messages again after a failed call
+	receiveRetryInterval = 10 * time.Second
+)
+
+// Consumer receives Cloud Map change events from an SQS queue and notifies about the changed services. An EventBridge
+// rule delivers the CloudTrail records of Cloud Map API calls to the queue. Consumer implements cloudmap.ChangeSource.
+type Consumer struct {
+	Log      common.Logger
+	Sqs      SqsFacade
+	QueueUrl string
+}
+
+// Start receives messages until the context is done. notify is called with the ID of each changed service, once per
+// service and batch of received messages, the messages are deleted once it returns.
+func (c *Consumer) Start(ctx context.Context, notify func(serviceId string)) error {
+	c.Log.Info("consuming Cloud Map change events", "queueUrl", c.QueueUrl)
+	for {
+		if err := c.receive(ctx, notify); err != nil && ctx.Err() == nil {
+			c.Log.Error(err, "error receiving Cloud Map change events", "queueUrl", c.QueueUrl)
+			select {
+			case <-time.After(receiveRetryInterval):
+			case <-ctx.Done():
+			}
+		}
+		if ctx.Err() != nil {
+			c.Log.Info("terminating Cloud Map change event consumer")
+			return nil
+		}
+	}
+}
+
+// receive long polls the queue for messages, notifies about the changed services and deletes the messages. Messages which
+// are no change events are deleted as well, as they can never be processed.
+func (c *Consumer) receive(ctx context.Context, notify func(serviceId string)) error {
+	out, err := c.Sqs.ReceiveMessage(ctx, &sqs.ReceiveMessageInput{
+		QueueUrl:            aws.String(c.QueueUrl),
+		MaxNumberOfMessages: maxMessages,
+		WaitTimeSeconds:     waitTimeSeconds,
+	})
+	if err != nil {
+		return err
+	}
+	if len(out.Messages) == 0 {
+		return nil
+	}
+
+	notified := sets.NewString()
+	entries := make([]types.DeleteMessageBatchRequestEntry, 0, len(out.Messages))
+	for i, message := range out.Messages {
+		entries = append(entries, types.DeleteMessageBatchRequestEntry{
+			Id:            aws.String(strconv.Itoa(i)),
+			ReceiptHandle: message.ReceiptHandle,
+		})
+
+		event, err := ParseChangeEvent([]byte(aws.ToString(message.Body)))
+		if errors.Is(err, ErrNotChangeEvent) {
+			c.Log.Debug("ignoring event", "messageId", aws.ToString(message.MessageId))
+			continue
+		}
+		if err != nil {
+			c.Log.Error(err, "ignoring invalid Cloud Map change event", "messageId", aws.ToString(message.MessageId))
+			continue
+		}
+		if notified.Has(event.ServiceId) {
+			continue
+		}
+
+		c.Log.Debug("Cloud Map service changed", "serviceId", event.ServiceId, "action", event.Action)
+		notify(event.ServiceId)
+		notified.Insert(event.ServiceId)
+	}
+
+	deleted, err := c.Sqs.DeleteMessageBatch(ctx, &sqs.DeleteMessageBatchInput{
+		QueueUrl: aws.String(c.QueueUrl),
+		Entries:  entries,
+	})
+	if err != nil {
+		return err
+	}
+	if len(deleted.Failed) > 0 {
+		// the messages are received again, refreshing a service twice is harmless
+		return fmt.Errorf("failed to delete %d of %d messages: %s", len(deleted.Failed), len(entries),
+			aws.ToString(deleted.Failed[0].Message))
+	}
+	return nil
+}
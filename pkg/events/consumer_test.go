@@ -0,0 +1,70 @@
+package events
+
+import (
+	"context"
+	"github.com/aws/aws-cloud-map-mcs-controller-for-k8s/mocks/pkg/events"
+	"github.com/aws/aws-cloud-map-mcs-controller-for-k8s/pkg/common"
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/sqs"
+	"github.com/aws/aws-sdk-go-v2/service/sqs/types"
+	testingLogger "github.com/go-logr/logr/testing"
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+const queueUrl = "https://sqs.us-west-2.amazonaws.com/123456789012/cloudmap-changes"
+
+func TestConsumer_Receive(t *testing.T) {
+	mockController := gomock.NewController(t)
+	defer mockController.Finish()
+
+	sqsFacade := events.NewMockSqsFacade(mockController)
+	sqsFacade.EXPECT().ReceiveMessage(gomock.Any(), &sqs.ReceiveMessageInput{
+		QueueUrl:            aws.String(queueUrl),
+		MaxNumberOfMessages: maxMessages,
+		WaitTimeSeconds:     waitTimeSeconds,
+	}).Return(&sqs.ReceiveMessageOutput{Messages: []types.Message{
+		{Body: aws.String(cloudTrailEvent("RegisterInstance", `{"serviceId": "srv-1"}`)), ReceiptHandle: aws.String("r-0")},
+		{Body: aws.String(cloudTrailEvent("DeregisterInstance", `{"serviceId": "srv-1"}`)), ReceiptHandle: aws.String("r-1")},
+		{Body: aws.String(cloudTrailEvent("ListServices", `{}`)), ReceiptHandle: aws.String("r-2")},
+		{Body: aws.String("{"), ReceiptHandle: aws.String("r-3")},
+	}}, nil)
+	sqsFacade.EXPECT().DeleteMessageBatch(gomock.Any(), gomock.Any()).
+		DoAndReturn(func(ctx context.Context, input *sqs.DeleteMessageBatchInput, _ ...func(*sqs.Options)) (*sqs.DeleteMessageBatchOutput, error) {
+			// all messages are deleted, including those which can't be processed
+			assert.Len(t, input.Entries, 4)
+			return &sqs.DeleteMessageBatchOutput{}, nil
+		})
+
+	notified := make([]string, 0)
+	consumer := &Consumer{
+		Log:      common.NewLoggerWithLogr(testingLogger.TestLogger{T: t}),
+		Sqs:      sqsFacade,
+		QueueUrl: queueUrl,
+	}
+	assert.NoError(t, consumer.receive(context.TODO(), func(serviceId string) {
+		notified = append(notified, serviceId)
+	}))
+	assert.Equal(t, []string{"srv-1"}, notified, "services are notified once per batch")
+}
+
+func TestConsumer_Receive_DeleteFailed(t *testing.T) {
+	mockController := gomock.NewController(t)
+	defer mockController.Finish()
+
+	sqsFacade := events.NewMockSqsFacade(mockController)
+	sqsFacade.EXPECT().ReceiveMessage(gomock.Any(), gomock.Any()).Return(&sqs.ReceiveMessageOutput{
+		Messages: []types.Message{{Body: aws.String("{}"), ReceiptHandle: aws.String("r-0")}},
+	}, nil)
+	sqsFacade.EXPECT().DeleteMessageBatch(gomock.Any(), gomock.Any()).Return(&sqs.DeleteMessageBatchOutput{
+		Failed: []types.BatchResultErrorEntry{{Id: aws.String("0"), Message: aws.String("receipt handle expired")}},
+	}, nil)
+
+	consumer := &Consumer{
+		Log:      common.NewLoggerWithLogr(testingLogger.TestLogger{T: t}),
+		Sqs:      sqsFacade,
+		QueueUrl: queueUrl,
+	}
+	assert.Error(t, consumer.receive(context.TODO(), func(string) {}))
+}
@@ -0,0 +1,191 @@
+package events
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"github.com/aws/aws-cloud-map-mcs-controller-for-k8s/pkg/common"
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/eventbridge"
+	ebtypes "github.com/aws/aws-sdk-go-v2/service/eventbridge/types"
+	"github.com/aws/aws-sdk-go-v2/service/sns"
+	snstypes "github.com/aws/aws-sdk-go-v2/service/sns/types"
+	"time"
+)
+
+const (
+	// ClusterSetEventSource is the EventBridge source of published clusterset events
+	ClusterSetEventSource = "multicluster.k8s.aws"
+
+	// maxPutEventsEntries is the largest number of entries EventBridge accepts per PutEvents call
+	maxPutEventsEntries = 10
+	// publishTimeout bounds the time publishing delays a reconciliation
+	publishTimeout = 5 * time.Second
+)
+
+// ClusterSetEventType is the type of a clusterset event.
+type ClusterSetEventType string
+
+const (
+	// ServiceExported is published when the Cloud Map service of an export is created, i.e. the first cluster exports
+	// the service to the clusterset
+	ServiceExported ClusterSetEventType = "ServiceExported"
+	// EndpointsAdded is published when the cluster registers new endpoints of a service
+	EndpointsAdded ClusterSetEventType = "EndpointsAdded"
+	// EndpointsRemoved is published when the cluster de-registers endpoints of a service
+	EndpointsRemoved ClusterSetEventType = "EndpointsRemoved"
+	// ClusterJoined is published when the cluster registers the first endpoints of a service
+	ClusterJoined ClusterSetEventType = "ClusterJoined"
+	// ClusterLeft is published when the cluster de-registers the last endpoints of a service
+	ClusterLeft ClusterSetEventType = "ClusterLeft"
+)
+
+// ClusterSetEvent is a change of the clusterset published for external automation, e.g. DNS, firewalls or dashboards.
+type ClusterSetEvent struct {
+	Time         time.Time           `json:"time"`
+	Type         ClusterSetEventType `json:"type"`
+	ClusterId    string              `json:"clusterId,omitempty"`
+	ClusterSetId string              `json:"clusterSetId,omitempty"`
+	// Namespace is the Cloud Map namespace of the service
+	Namespace string `json:"namespace"`
+	Service   string `json:"service"`
+	// Endpoints are the added or removed endpoints
+	Endpoints []Endpoint `json:"endpoints,omitempty"`
+}
+
+// Endpoint is the address of an endpoint in a clusterset event.
+type Endpoint struct {
+	IP   string `json:"ip"`
+	Port int32  `json:"port"`
+}
+
+// publishTarget sends clusterset events to an AWS service.
+type publishTarget interface {
+	publish(ctx context.Context, events []ClusterSetEvent) error
+	String() string
+}
+
+// Publisher publishes clusterset events to an SNS topic or an EventBridge bus. Events are published on a best effort
+// basis, failures are logged and the events dropped. A nil Publisher publishes nothing.
+type Publisher struct {
+	log          common.Logger
+	target       publishTarget
+	clusterId    string
+	clusterSetId string
+	now          func() time.Time
+}
+
+// NewSnsPublisher creates a publisher sending each event as JSON message to the SNS topic. The event type is set as
+// "type" message attribute, so subscriptions can filter on it.
+func NewSnsPublisher(sns SnsFacade, topicArn string, clusterId string, clusterSetId string) *Publisher {
+	return newPublisher(&snsTarget{sns: sns, topicArn: topicArn}, clusterId, clusterSetId)
+}
+
+// NewEventBridgePublisher creates a publisher putting the events to the EventBridge bus, with ClusterSetEventSource
+// as source and the event type as detail type.
+func NewEventBridgePublisher(eventBridge EventBridgeFacade, busName string, clusterId string, clusterSetId string) *Publisher {
+	return newPublisher(&eventBridgeTarget{eventBridge: eventBridge, busName: busName}, clusterId, clusterSetId)
+}
+
+func newPublisher(target publishTarget, clusterId string, clusterSetId string) *Publisher {
+	return &Publisher{
+		log:          common.NewLogger("events", "publisher"),
+		target:       target,
+		clusterId:    clusterId,
+		clusterSetId: clusterSetId,
+		now:          time.Now,
+	}
+}
+
+// Publish publishes the events, attributed to the cluster and clusterset of the publisher.
+func (p *Publisher) Publish(ctx context.Context, events ...ClusterSetEvent) {
+	if p == nil || len(events) == 0 {
+		return
+	}
+
+	now := p.now()
+	for i := range events {
+		events[i].Time = now
+		events[i].ClusterId = p.clusterId
+		events[i].ClusterSetId = p.clusterSetId
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, publishTimeout)
+	defer cancel()
+	if err := p.target.publish(ctx, events); err != nil {
+		p.log.Error(err, "error publishing clusterset events", "target", p.target.String(),
+			"events", len(events))
+		return
+	}
+	p.log.Debug("published clusterset events", "target", p.target.String(), "events", len(events))
+}
+
+type snsTarget struct {
+	sns      SnsFacade
+	topicArn string
+}
+
+func (t *snsTarget) publish(ctx context.Context, events []ClusterSetEvent) error {
+	for _, event := range events {
+		message, err := json.Marshal(event)
+		if err != nil {
+			return err
+		}
+		if _, err = t.sns.Publish(ctx, &sns.PublishInput{
+			TopicArn: aws.String(t.topicArn),
+			Message:  aws.String(string(message)),
+			MessageAttributes: map[string]snstypes.MessageAttributeValue{
+				"type": {DataType: aws.String("String"), StringValue: aws.String(string(event.Type))},
+			},
+		}); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (t *snsTarget) String() string {
+	return t.topicArn
+}
+
+type eventBridgeTarget struct {
+	eventBridge EventBridgeFacade
+	busName     string
+}
+
+func (t *eventBridgeTarget) publish(ctx context.Context, events []ClusterSetEvent) error {
+	for start := 0; start < len(events); start += maxPutEventsEntries {
+		end := start + maxPutEventsEntries
+		if end > len(events) {
+			end = len(events)
+		}
+
+		entries := make([]ebtypes.PutEventsRequestEntry, 0, end-start)
+		for _, event := range events[start:end] {
+			detail, err := json.Marshal(event)
+			if err != nil {
+				return err
+			}
+			entries = append(entries, ebtypes.PutEventsRequestEntry{
+				EventBusName: aws.String(t.busName),
+				Source:       aws.String(ClusterSetEventSource),
+				DetailType:   aws.String(string(event.Type)),
+				Detail:       aws.String(string(detail)),
+				Time:         aws.Time(event.Time),
+			})
+		}
+
+		out, err := t.eventBridge.PutEvents(ctx, &eventbridge.PutEventsInput{Entries: entries})
+		if err != nil {
+			return err
+		}
+		if out.FailedEntryCount > 0 {
+			return fmt.Errorf("failed to put %d of %d events", out.FailedEntryCount, len(entries))
+		}
+	}
+	return nil
+}
+
+func (t *eventBridgeTarget) String() string {
+	return t.busName
+}
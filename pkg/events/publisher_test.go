@@ -0,0 +1,111 @@
+package events
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"github.com/aws/aws-cloud-map-mcs-controller-for-k8s/mocks/pkg/events"
+	"github.com/aws/aws-cloud-map-mcs-controller-for-k8s/pkg/common"
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/eventbridge"
+	"github.com/aws/aws-sdk-go-v2/service/sns"
+	testingLogger "github.com/go-logr/logr/testing"
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/assert"
+	"testing"
+	"time"
+)
+
+const (
+	topicArn = "arn:aws:sns:us-west-2:123456789012:clusterset"
+	busName  = "clusterset"
+)
+
+var publishTime = time.Date(2021, 8, 20, 10, 0, 0, 0, time.UTC)
+
+func TestPublisher_Nil(t *testing.T) {
+	var publisher *Publisher
+	publisher.Publish(context.TODO(), ClusterSetEvent{Type: ServiceExported})
+}
+
+func TestSnsPublisher(t *testing.T) {
+	mockController := gomock.NewController(t)
+	defer mockController.Finish()
+
+	snsFacade := events.NewMockSnsFacade(mockController)
+	snsFacade.EXPECT().Publish(gomock.Any(), gomock.Any()).
+		DoAndReturn(func(ctx context.Context, input *sns.PublishInput, _ ...func(*sns.Options)) (*sns.PublishOutput, error) {
+			assert.Equal(t, topicArn, aws.ToString(input.TopicArn))
+			assert.Equal(t, string(EndpointsAdded), aws.ToString(input.MessageAttributes["type"].StringValue))
+
+			event := ClusterSetEvent{}
+			assert.NoError(t, json.Unmarshal([]byte(aws.ToString(input.Message)), &event))
+			assert.Equal(t, ClusterSetEvent{
+				Time:         publishTime,
+				Type:         EndpointsAdded,
+				ClusterId:    "cluster",
+				ClusterSetId: "clusterset",
+				Namespace:    "namespace",
+				Service:      "service",
+				Endpoints:    []Endpoint{{IP: "192.168.0.1", Port: 80}},
+			}, event)
+			return &sns.PublishOutput{}, nil
+		})
+
+	publisher := testPublisher(t, NewSnsPublisher(snsFacade, topicArn, "cluster", "clusterset"))
+	publisher.Publish(context.TODO(), ClusterSetEvent{Type: EndpointsAdded, Namespace: "namespace",
+		Service: "service", Endpoints: []Endpoint{{IP: "192.168.0.1", Port: 80}}})
+}
+
+func TestEventBridgePublisher(t *testing.T) {
+	mockController := gomock.NewController(t)
+	defer mockController.Finish()
+
+	published := make([]ClusterSetEvent, 0)
+	eventBridgeFacade := events.NewMockEventBridgeFacade(mockController)
+	eventBridgeFacade.EXPECT().PutEvents(gomock.Any(), gomock.Any()).Times(2).
+		DoAndReturn(func(ctx context.Context, input *eventbridge.PutEventsInput, _ ...func(*eventbridge.Options)) (*eventbridge.PutEventsOutput, error) {
+			assert.LessOrEqual(t, len(input.Entries), maxPutEventsEntries)
+			for _, entry := range input.Entries {
+				assert.Equal(t, busName, aws.ToString(entry.EventBusName))
+				assert.Equal(t, ClusterSetEventSource, aws.ToString(entry.Source))
+
+				event := ClusterSetEvent{}
+				assert.NoError(t, json.Unmarshal([]byte(aws.ToString(entry.Detail)), &event))
+				assert.Equal(t, string(event.Type), aws.ToString(entry.DetailType))
+				published = append(published, event)
+			}
+			return &eventbridge.PutEventsOutput{}, nil
+		})
+
+	toPublish := make([]ClusterSetEvent, 0)
+	for i := 0; i < maxPutEventsEntries+1; i++ {
+		toPublish = append(toPublish, ClusterSetEvent{Type: ClusterJoined, Namespace: "namespace", Service: "service"})
+	}
+	publisher := testPublisher(t, NewEventBridgePublisher(eventBridgeFacade, busName, "cluster", ""))
+	publisher.Publish(context.TODO(), toPublish...)
+	assert.Len(t, published, maxPutEventsEntries+1)
+}
+
+func TestEventBridgePublisher_FailedEntries(t *testing.T) {
+	mockController := gomock.NewController(t)
+	defer mockController.Finish()
+
+	eventBridgeFacade := events.NewMockEventBridgeFacade(mockController)
+	target := &eventBridgeTarget{eventBridge: eventBridgeFacade, busName: busName}
+
+	eventBridgeFacade.EXPECT().PutEvents(gomock.Any(), gomock.Any()).
+		Return(&eventbridge.PutEventsOutput{FailedEntryCount: 1}, nil)
+	assert.Error(t, target.publish(context.TODO(), []ClusterSetEvent{{Type: ClusterLeft}}))
+
+	eventBridgeFacade.EXPECT().PutEvents(gomock.Any(), gomock.Any()).Return(nil, errors.New("access denied"))
+	assert.Error(t, target.publish(context.TODO(), []ClusterSetEvent{{Type: ClusterLeft}}))
+}
+
+func testPublisher(t *testing.T, publisher *Publisher) *Publisher {
+	publisher.log = common.NewLoggerWithLogr(testingLogger.TestLogger{T: t})
+	publisher.now = func() time.Time {
+		return publishTime
+	}
+	return publisher
+}
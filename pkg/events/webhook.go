@@ -0,0 +1,167 @@
+package events
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"github.com/aws/aws-cloud-map-mcs-controller-for-k8s/pkg/common"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+const (
+	// webhookQueueSize is the number of import changes queued for delivery
+	webhookQueueSize = 100
+	// webhookTimeout bounds each webhook request
+	webhookTimeout = 5 * time.Second
+	// webhookAttempts is the number of times a notification is sent to a webhook which fails
+	webhookAttempts = 3
+	// webhookRetryInterval is the delay before a failed notification is sent again
+	webhookRetryInterval = time.Second
+)
+
+// ImportChangeType is the type of an import change notification.
+type ImportChangeType string
+
+const (
+	// ServiceImportCreated is sent when a service of the clusterset is imported into the cluster
+	ServiceImportCreated ImportChangeType = "ServiceImportCreated"
+	// ServiceImportUpdated is sent when the IPs, ports, labels or annotations of a ServiceImport change
+	ServiceImportUpdated ImportChangeType = "ServiceImportUpdated"
+	// ServiceImportDeleted is sent when a service is no longer imported into the cluster
+	ServiceImportDeleted ImportChangeType = "ServiceImportDeleted"
+	// ImportEndpointsChanged is sent when the endpoints of an imported service change
+	ImportEndpointsChanged ImportChangeType = "ImportEndpointsChanged"
+)
+
+// ImportChange is a change of a ServiceImport or its endpoints, which is sent as JSON to the webhooks.
+type ImportChange struct {
+	Time      time.Time        `json:"time"`
+	Type      ImportChangeType `json:"type"`
+	ClusterId string           `json:"clusterId,omitempty"`
+	Namespace string           `json:"namespace"`
+	Name      string           `json:"name"`
+	// Endpoints are all endpoints of the imported service after an ImportEndpointsChanged change
+	Endpoints []Endpoint `json:"endpoints,omitempty"`
+}
+
+// WebhookNotifier sends import changes to HTTP webhooks, for systems which can't watch the Kubernetes API. Changes are
+// queued and sent in the order they occurred by Start, so slow webhooks don't delay the import. Changes are dropped
+// while the queue is full, and after a webhook failed repeatedly. A nil WebhookNotifier sends nothing.
+// WebhookNotifier implements manager.Runnable.
+type WebhookNotifier struct {
+	log       common.Logger
+	client    *http.Client
+	urls      []string
+	clusterId string
+	queue     chan ImportChange
+	now       func() time.Time
+}
+
+// NewWebhookNotifier creates a notifier posting the import changes of the cluster to the webhook URLs.
+func NewWebhookNotifier(urls []string, clusterId string) *WebhookNotifier {
+	return &WebhookNotifier{
+		log:       common.NewLogger("events", "webhooks"),
+		client:    &http.Client{Timeout: webhookTimeout},
+		urls:      urls,
+		clusterId: clusterId,
+		queue:     make(chan ImportChange, webhookQueueSize),
+		now:       time.Now,
+	}
+}
+
+// ParseWebhookUrls parses a comma separated list of absolute HTTP or HTTPS URLs.
+func ParseWebhookUrls(urls string) ([]string, error) {
+	result := make([]string, 0)
+	for _, rawUrl := range strings.Split(urls, ",") {
+		rawUrl = strings.TrimSpace(rawUrl)
+		if rawUrl == "" {
+			continue
+		}
+		parsed, err := url.Parse(rawUrl)
+		if err != nil {
+			return nil, err
+		}
+		if (parsed.Scheme != "http" && parsed.Scheme != "https") || parsed.Host == "" {
+			return nil, fmt.Errorf("invalid webhook URL %q, expected an absolute HTTP or HTTPS URL", rawUrl)
+		}
+		result = append(result, rawUrl)
+	}
+	return result, nil
+}
+
+// Notify queues the import change for delivery without blocking.
+func (n *WebhookNotifier) Notify(change ImportChange) {
+	if n == nil {
+		return
+	}
+
+	change.Time = n.now()
+	change.ClusterId = n.clusterId
+	select {
+	case n.queue <- change:
+	default:
+		n.log.Info("webhook queue full, dropping import change", "type", change.Type,
+			"namespace", change.Namespace, "name", change.Name)
+	}
+}
+
+// Start sends the queued import changes until the context is done.
+func (n *WebhookNotifier) Start(ctx context.Context) error {
+	n.log.Info("sending import changes to webhooks", "webhooks", len(n.urls))
+	for {
+		select {
+		case change := <-n.queue:
+			for _, webhookUrl := range n.urls {
+				if err := n.send(ctx, webhookUrl, change); err != nil && ctx.Err() == nil {
+					n.log.Error(err, "error sending import change to webhook", "url", webhookUrl,
+						"type", change.Type, "namespace", change.Namespace, "name", change.Name)
+				}
+			}
+		case <-ctx.Done():
+			n.log.Info("terminating webhook notifier")
+			return nil
+		}
+	}
+}
+
+// send posts the import change to the webhook, retrying failed requests.
+func (n *WebhookNotifier) send(ctx context.Context, webhookUrl string, change ImportChange) error {
+	body, err := json.Marshal(change)
+	if err != nil {
+		return err
+	}
+
+	for attempt := 1; ; attempt++ {
+		err = n.post(ctx, webhookUrl, body)
+		if err == nil || attempt == webhookAttempts {
+			return err
+		}
+		select {
+		case <-time.After(webhookRetryInterval):
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+}
+
+func (n *WebhookNotifier) post(ctx context.Context, webhookUrl string, body []byte) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, webhookUrl, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := n.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return fmt.Errorf("webhook responded with status %s", resp.Status)
+	}
+	return nil
+}
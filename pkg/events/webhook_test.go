@@ -0,0 +1,98 @@
+package events
+
+import (
+	"context"
+	"encoding/json"
+	"github.com/aws/aws-cloud-map-mcs-controller-for-k8s/pkg/common"
+	testingLogger "github.com/go-logr/logr/testing"
+	"github.com/stretchr/testify/assert"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestParseWebhookUrls(t *testing.T) {
+	urls, err := ParseWebhookUrls(" https://example.com/hook, ,http://10.0.0.1:8080/imports ")
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"https://example.com/hook", "http://10.0.0.1:8080/imports"}, urls)
+
+	urls, err = ParseWebhookUrls("")
+	assert.NoError(t, err)
+	assert.Empty(t, urls)
+
+	for _, invalid := range []string{"example.com/hook", "ftp://example.com", "https://"} {
+		_, err = ParseWebhookUrls(invalid)
+		assert.Error(t, err, invalid)
+	}
+}
+
+func TestWebhookNotifier_Nil(t *testing.T) {
+	var notifier *WebhookNotifier
+	notifier.Notify(ImportChange{Type: ServiceImportCreated})
+}
+
+func TestWebhookNotifier(t *testing.T) {
+	received := make(chan ImportChange, 1)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "application/json", r.Header.Get("Content-Type"))
+		change := ImportChange{}
+		assert.NoError(t, json.NewDecoder(r.Body).Decode(&change))
+		received <- change
+	}))
+	defer server.Close()
+
+	notifier := testNotifier(t, NewWebhookNotifier([]string{server.URL}, "cluster"))
+	ctx, cancel := context.WithCancel(context.TODO())
+	defer cancel()
+	go func() {
+		_ = notifier.Start(ctx)
+	}()
+
+	notifier.Notify(ImportChange{Type: ImportEndpointsChanged, Namespace: "namespace", Name: "service",
+		Endpoints: []Endpoint{{IP: "192.168.0.1", Port: 80}}})
+	select {
+	case change := <-received:
+		assert.Equal(t, ImportChange{
+			Time:      publishTime,
+			Type:      ImportEndpointsChanged,
+			ClusterId: "cluster",
+			Namespace: "namespace",
+			Name:      "service",
+			Endpoints: []Endpoint{{IP: "192.168.0.1", Port: 80}},
+		}, change)
+	case <-time.After(5 * time.Second):
+		t.Fatal("webhook not called")
+	}
+}
+
+func TestWebhookNotifier_Retry(t *testing.T) {
+	var requests int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if atomic.AddInt32(&requests, 1) == 1 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+		}
+	}))
+	defer server.Close()
+
+	notifier := testNotifier(t, NewWebhookNotifier([]string{server.URL}, "cluster"))
+	assert.NoError(t, notifier.send(context.TODO(), server.URL, ImportChange{Type: ServiceImportDeleted}))
+	assert.Equal(t, int32(2), atomic.LoadInt32(&requests))
+}
+
+func TestWebhookNotifier_QueueFull(t *testing.T) {
+	notifier := testNotifier(t, NewWebhookNotifier([]string{"http://localhost"}, "cluster"))
+	for i := 0; i < webhookQueueSize+1; i++ {
+		notifier.Notify(ImportChange{Type: ServiceImportUpdated})
+	}
+	assert.Len(t, notifier.queue, webhookQueueSize)
+}
+
+func testNotifier(t *testing.T, notifier *WebhookNotifier) *WebhookNotifier {
+	notifier.log = common.NewLoggerWithLogr(testingLogger.TestLogger{T: t})
+	notifier.now = func() time.Time {
+		return publishTime
+	}
+	return notifier
+}
@@ -0,0 +1,43 @@
+package fixtures
+
+import (
+	cloudmapv1alpha1 "github.com/aws/aws-cloud-map-mcs-controller-for-k8s/pkg/api/cloudmap/v1alpha1"
+	"github.com/aws/aws-cloud-map-mcs-controller-for-k8s/pkg/api/v1alpha1"
+	"github.com/aws/aws-cloud-map-mcs-controller-for-k8s/pkg/cloudmap"
+	"github.com/aws/aws-cloud-map-mcs-controller-for-k8s/pkg/cloudmap/cloudmaptest"
+	"k8s.io/apimachinery/pkg/runtime"
+	utilruntime "k8s.io/apimachinery/pkg/util/runtime"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+// NewScheme returns a scheme with the Kubernetes, multicluster and Cloud Map config types the controller uses.
+func NewScheme() *runtime.Scheme {
+	scheme := runtime.NewScheme()
+	utilruntime.Must(clientgoscheme.AddToScheme(scheme))
+	utilruntime.Must(v1alpha1.AddToScheme(scheme))
+	utilruntime.Must(cloudmapv1alpha1.AddToScheme(scheme))
+	return scheme
+}
+
+// NewFakeClient returns an in-memory Kubernetes client with the scheme of NewScheme, holding the given objects.
+func NewFakeClient(objs ...runtime.Object) client.Client {
+	return fake.NewClientBuilder().WithScheme(NewScheme()).WithRuntimeObjects(objs...).Build()
+}
+
+// NewFakeCloudMap returns an in-memory Cloud Map, and a Cloud Map client backed by it which doesn't cache resources,
+// so changes of the in-memory Cloud Map are visible immediately.
+func NewFakeCloudMap(opts cloudmaptest.Options) (*cloudmaptest.Server, cloudmap.ServiceDiscoveryClient) {
+	server := cloudmaptest.NewServer(opts)
+	return server, server.NewServiceDiscoveryClient(cloudmap.SdClientConfig{Cache: &cloudmap.SdCacheConfig{}})
+}
+
+// AddService registers the endpoints of a service in the in-memory Cloud Map, creating its namespace and service.
+func AddService(server *cloudmaptest.Server, service *ServiceBuilder) {
+	svc := service.Build()
+	server.AddService(svc.Namespace, svc.Name)
+	for _, endpoint := range svc.Endpoints {
+		server.AddInstance(svc.Namespace, svc.Name, endpoint.Id, endpoint.GetCloudMapAttributes())
+	}
+}
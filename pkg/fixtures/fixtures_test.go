@@ -0,0 +1,77 @@
+package fixtures
+
+import (
+	"context"
+	"github.com/aws/aws-cloud-map-mcs-controller-for-k8s/pkg/api/v1alpha1"
+	"github.com/aws/aws-cloud-map-mcs-controller-for-k8s/pkg/cloudmap/cloudmaptest"
+	"github.com/aws/aws-cloud-map-mcs-controller-for-k8s/pkg/model"
+	"github.com/stretchr/testify/assert"
+	v1 "k8s.io/api/core/v1"
+	discovery "k8s.io/api/discovery/v1beta1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"testing"
+)
+
+func TestEndpoint(t *testing.T) {
+	builder := Endpoint("10.0.0.1").WithPort("grpc", 9090, model.TCPProtocol).WithAttribute("key", "value")
+	endpoint := builder.Build()
+	assert.Equal(t, "tcp-10_0_0_1-9090", endpoint.Id)
+	assert.Equal(t, int32(9090), endpoint.EndpointPort.Port)
+	assert.Equal(t, "9090", endpoint.ServicePort.TargetPort)
+	assert.Equal(t, int32(DefaultPort), endpoint.ServicePort.Port)
+
+	// built endpoints don't share attributes
+	endpoint.Attributes["key"] = "changed"
+	assert.Equal(t, "value", builder.Build().Attributes["key"])
+
+	clusterEndpoint := Endpoint("10.0.0.1").WithClusterId("cluster-a").Build()
+	assert.Equal(t, model.EndpointId("cluster-a", clusterEndpoint.IP, clusterEndpoint.EndpointPort), clusterEndpoint.Id)
+}
+
+func TestService(t *testing.T) {
+	svc := Service(DefaultNamespaceName, DefaultServiceName).
+		WithEndpoints(Endpoint("10.0.0.1"), Endpoint("10.0.0.2")).
+		Build()
+	assert.Equal(t, "srv-"+DefaultServiceName, svc.Id)
+	assert.Len(t, svc.Endpoints, 2)
+
+	ns := Namespace(DefaultNamespaceName).WithType(model.DnsPrivateNamespaceType).Build()
+	assert.Equal(t, "ns-"+DefaultNamespaceName, ns.Id)
+	assert.Equal(t, model.DnsPrivateNamespaceType, ns.Type)
+}
+
+func TestKubeResources(t *testing.T) {
+	svc := KubeService(DefaultNamespaceName, DefaultServiceName).WithPort("http", 80, 8080, v1.ProtocolTCP).Build()
+	slice := EndpointSlice(svc, DefaultServiceName+"-slice", "10.0.0.1")
+	assert.Equal(t, DefaultServiceName, slice.Labels[discovery.LabelServiceName])
+	assert.Equal(t, int32(8080), *slice.Ports[0].Port)
+
+	export := ServiceExport(DefaultNamespaceName, DefaultServiceName).
+		WithExportedLabel("team", "a").
+		WithCondition(v1alpha1.ServiceExportValid, metav1.ConditionTrue, "Valid").
+		Build()
+	assert.Equal(t, "a", export.Spec.ExportedLabels["team"])
+	assert.Len(t, export.Status.Conditions, 1)
+
+	imp := ServiceImport(DefaultNamespaceName, DefaultServiceName).WithPort("http", 80, v1.ProtocolTCP).Build()
+	assert.Equal(t, v1alpha1.ClusterSetIP, imp.Spec.Type)
+
+	c := NewFakeClient(KubeNamespace(DefaultNamespaceName, nil), svc, slice, export, imp)
+	name := types.NamespacedName{Namespace: DefaultNamespaceName, Name: DefaultServiceName}
+	assert.NoError(t, c.Get(context.TODO(), name, &v1alpha1.ServiceExport{}))
+	assert.NoError(t, c.Get(context.TODO(), name, &v1alpha1.ServiceImport{}))
+	assert.NoError(t, c.Get(context.TODO(), name, &v1.Service{}))
+}
+
+func TestNewFakeCloudMap(t *testing.T) {
+	server, sdClient := NewFakeCloudMap(cloudmaptest.Options{})
+	expected := Service(DefaultNamespaceName, DefaultServiceName).WithEndpoints(Endpoint("10.0.0.1"))
+	AddService(server, expected)
+
+	svc, err := sdClient.GetService(context.TODO(), DefaultNamespaceName, DefaultServiceName)
+	assert.NoError(t, err)
+	if assert.NotNil(t, svc) && assert.Len(t, svc.Endpoints, 1) {
+		assert.True(t, expected.Build().Endpoints[0].Equals(svc.Endpoints[0]))
+	}
+}
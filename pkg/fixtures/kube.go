@@ -0,0 +1,205 @@
+package fixtures
+
+import (
+	"github.com/aws/aws-cloud-map-mcs-controller-for-k8s/pkg/api/v1alpha1"
+	v1 "k8s.io/api/core/v1"
+	discovery "k8s.io/api/discovery/v1beta1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/intstr"
+)
+
+// KubeNamespace returns a Kubernetes namespace with the given name and labels.
+func KubeNamespace(name string, labels map[string]string) *v1.Namespace {
+	return &v1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: name, Labels: copyMap(labels)}}
+}
+
+// KubeServiceBuilder builds a Kubernetes Service, a ClusterIP service without ports by default.
+type KubeServiceBuilder struct {
+	service v1.Service
+}
+
+// KubeService starts building a Kubernetes Service with the given namespace and name.
+func KubeService(namespace string, name string) *KubeServiceBuilder {
+	return &KubeServiceBuilder{service: v1.Service{
+		ObjectMeta: metav1.ObjectMeta{Namespace: namespace, Name: name},
+		Spec:       v1.ServiceSpec{Type: v1.ServiceTypeClusterIP},
+	}}
+}
+
+// WithPort adds a port to the Service.
+func (b *KubeServiceBuilder) WithPort(name string, port int32, targetPort int32, protocol v1.Protocol) *KubeServiceBuilder {
+	b.service.Spec.Ports = append(b.service.Spec.Ports, v1.ServicePort{
+		Name:       name,
+		Port:       port,
+		TargetPort: intstr.FromInt(int(targetPort)),
+		Protocol:   protocol,
+	})
+	return b
+}
+
+// WithLabel sets a label of the Service.
+func (b *KubeServiceBuilder) WithLabel(key string, value string) *KubeServiceBuilder {
+	b.service.Labels = setKey(b.service.Labels, key, value)
+	return b
+}
+
+// WithAnnotation sets an annotation of the Service.
+func (b *KubeServiceBuilder) WithAnnotation(key string, value string) *KubeServiceBuilder {
+	b.service.Annotations = setKey(b.service.Annotations, key, value)
+	return b
+}
+
+// Build returns a new Service on each call.
+func (b *KubeServiceBuilder) Build() *v1.Service {
+	return b.service.DeepCopy()
+}
+
+// EndpointSlice returns an IPv4 EndpointSlice of the Service, with an endpoint per IP address listening on the target
+// ports of the Service.
+func EndpointSlice(service *v1.Service, name string, ips ...string) *discovery.EndpointSlice {
+	slice := &discovery.EndpointSlice{
+		ObjectMeta: metav1.ObjectMeta{
+			Namespace: service.Namespace,
+			Name:      name,
+			Labels:    map[string]string{discovery.LabelServiceName: service.Name},
+		},
+		AddressType: discovery.AddressTypeIPv4,
+		Endpoints:   make([]discovery.Endpoint, 0, len(ips)),
+		Ports:       make([]discovery.EndpointPort, 0, len(service.Spec.Ports)),
+	}
+	for _, ip := range ips {
+		slice.Endpoints = append(slice.Endpoints, discovery.Endpoint{Addresses: []string{ip}})
+	}
+	for _, servicePort := range service.Spec.Ports {
+		name, protocol, port := servicePort.Name, servicePort.Protocol, servicePort.TargetPort.IntVal
+		slice.Ports = append(slice.Ports, discovery.EndpointPort{Name: &name, Protocol: &protocol, Port: &port})
+	}
+	return slice
+}
+
+// ServiceExportBuilder builds a ServiceExport.
+type ServiceExportBuilder struct {
+	serviceExport v1alpha1.ServiceExport
+}
+
+// ServiceExport starts building a ServiceExport with the given namespace and name.
+func ServiceExport(namespace string, name string) *ServiceExportBuilder {
+	return &ServiceExportBuilder{serviceExport: v1alpha1.ServiceExport{
+		ObjectMeta: metav1.ObjectMeta{Namespace: namespace, Name: name},
+	}}
+}
+
+// WithLabel sets a label of the ServiceExport.
+func (b *ServiceExportBuilder) WithLabel(key string, value string) *ServiceExportBuilder {
+	b.serviceExport.Labels = setKey(b.serviceExport.Labels, key, value)
+	return b
+}
+
+// WithAnnotation sets an annotation of the ServiceExport.
+func (b *ServiceExportBuilder) WithAnnotation(key string, value string) *ServiceExportBuilder {
+	b.serviceExport.Annotations = setKey(b.serviceExport.Annotations, key, value)
+	return b
+}
+
+// WithFinalizer adds a finalizer to the ServiceExport.
+func (b *ServiceExportBuilder) WithFinalizer(finalizer string) *ServiceExportBuilder {
+	b.serviceExport.Finalizers = append(b.serviceExport.Finalizers, finalizer)
+	return b
+}
+
+// WithExportedLabel sets a label exported to the ServiceImports of the service.
+func (b *ServiceExportBuilder) WithExportedLabel(key string, value string) *ServiceExportBuilder {
+	b.serviceExport.Spec.ExportedLabels = setKey(b.serviceExport.Spec.ExportedLabels, key, value)
+	return b
+}
+
+// WithExportedAnnotation sets an annotation exported to the ServiceImports of the service.
+func (b *ServiceExportBuilder) WithExportedAnnotation(key string, value string) *ServiceExportBuilder {
+	b.serviceExport.Spec.ExportedAnnotations = setKey(b.serviceExport.Spec.ExportedAnnotations, key, value)
+	return b
+}
+
+// WithCondition adds a status condition to the ServiceExport.
+func (b *ServiceExportBuilder) WithCondition(conditionType v1alpha1.ServiceExportConditionType,
+	status metav1.ConditionStatus, reason string) *ServiceExportBuilder {
+	b.serviceExport.Status.Conditions = append(b.serviceExport.Status.Conditions, metav1.Condition{
+		Type:               string(conditionType),
+		Status:             status,
+		Reason:             reason,
+		LastTransitionTime: metav1.Now(),
+	})
+	return b
+}
+
+// Build returns a new ServiceExport on each call.
+func (b *ServiceExportBuilder) Build() *v1alpha1.ServiceExport {
+	return b.serviceExport.DeepCopy()
+}
+
+// ServiceImportBuilder builds a ServiceImport, a ClusterSetIP import without ports by default.
+type ServiceImportBuilder struct {
+	serviceImport v1alpha1.ServiceImport
+}
+
+// ServiceImport starts building a ServiceImport with the given namespace and name.
+func ServiceImport(namespace string, name string) *ServiceImportBuilder {
+	return &ServiceImportBuilder{serviceImport: v1alpha1.ServiceImport{
+		ObjectMeta: metav1.ObjectMeta{Namespace: namespace, Name: name},
+		Spec:       v1alpha1.ServiceImportSpec{Type: v1alpha1.ClusterSetIP, Ports: []v1alpha1.ServicePort{}},
+	}}
+}
+
+// WithType sets the type of the ServiceImport.
+func (b *ServiceImportBuilder) WithType(importType v1alpha1.ServiceImportType) *ServiceImportBuilder {
+	b.serviceImport.Spec.Type = importType
+	return b
+}
+
+// WithPort adds a port to the ServiceImport.
+func (b *ServiceImportBuilder) WithPort(name string, port int32, protocol v1.Protocol) *ServiceImportBuilder {
+	b.serviceImport.Spec.Ports = append(b.serviceImport.Spec.Ports,
+		v1alpha1.ServicePort{Name: name, Port: port, Protocol: protocol})
+	return b
+}
+
+// WithIPs sets the clusterset IPs of the ServiceImport.
+func (b *ServiceImportBuilder) WithIPs(ips ...string) *ServiceImportBuilder {
+	b.serviceImport.Spec.IPs = ips
+	return b
+}
+
+// WithAnnotation sets an annotation of the ServiceImport.
+func (b *ServiceImportBuilder) WithAnnotation(key string, value string) *ServiceImportBuilder {
+	b.serviceImport.Annotations = setKey(b.serviceImport.Annotations, key, value)
+	return b
+}
+
+// WithCluster adds an exporting cluster to the status of the ServiceImport.
+func (b *ServiceImportBuilder) WithCluster(clusterId string) *ServiceImportBuilder {
+	b.serviceImport.Status.Clusters = append(b.serviceImport.Status.Clusters, v1alpha1.ClusterStatus{Cluster: clusterId})
+	return b
+}
+
+// Build returns a new ServiceImport on each call.
+func (b *ServiceImportBuilder) Build() *v1alpha1.ServiceImport {
+	return b.serviceImport.DeepCopy()
+}
+
+func setKey(m map[string]string, key string, value string) map[string]string {
+	if m == nil {
+		m = make(map[string]string)
+	}
+	m[key] = value
+	return m
+}
+
+func copyMap(m map[string]string) map[string]string {
+	if m == nil {
+		return nil
+	}
+	result := make(map[string]string, len(m))
+	for key, value := range m {
+		result[key] = value
+	}
+	return result
+}
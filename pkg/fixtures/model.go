@@ -0,0 +1,144 @@
+// Package fixtures provides builders of the model types and Kubernetes resources the controller works with, and
+// pre-wired fake Kubernetes and Cloud Map clients, for writing tests without copying fixtures between packages.
+// Builders start from valid defaults, so tests only set what they are about.
+package fixtures
+
+import (
+	"github.com/aws/aws-cloud-map-mcs-controller-for-k8s/pkg/model"
+	"strconv"
+)
+
+// Defaults of the built resources
+const (
+	DefaultNamespaceName = "ns-name"
+	DefaultServiceName   = "svc-name"
+	DefaultPortName      = "http"
+	DefaultPort          = 80
+	DefaultProtocol      = model.TCPProtocol
+)
+
+// NamespaceBuilder builds a Cloud Map namespace, an HTTP namespace by default.
+type NamespaceBuilder struct {
+	namespace model.Namespace
+}
+
+// Namespace starts building a Cloud Map namespace with the given name.
+func Namespace(name string) *NamespaceBuilder {
+	return &NamespaceBuilder{namespace: model.Namespace{Id: "ns-" + name, Name: name, Type: model.HttpNamespaceType}}
+}
+
+// WithId sets the namespace ID, "ns-" and the name by default.
+func (b *NamespaceBuilder) WithId(id string) *NamespaceBuilder {
+	b.namespace.Id = id
+	return b
+}
+
+// WithType sets the namespace type.
+func (b *NamespaceBuilder) WithType(namespaceType model.NamespaceType) *NamespaceBuilder {
+	b.namespace.Type = namespaceType
+	return b
+}
+
+// Build returns a new namespace on each call.
+func (b *NamespaceBuilder) Build() *model.Namespace {
+	namespace := b.namespace
+	return &namespace
+}
+
+// ServiceBuilder builds a Cloud Map service, without endpoints by default.
+type ServiceBuilder struct {
+	service   model.Service
+	endpoints []*EndpointBuilder
+}
+
+// Service starts building a Cloud Map service with the given namespace and name.
+func Service(namespaceName string, name string) *ServiceBuilder {
+	return &ServiceBuilder{service: model.Service{Id: "srv-" + name, Namespace: namespaceName, Name: name}}
+}
+
+// WithId sets the service ID, "srv-" and the name by default.
+func (b *ServiceBuilder) WithId(id string) *ServiceBuilder {
+	b.service.Id = id
+	return b
+}
+
+// WithEndpoints adds endpoints to the service.
+func (b *ServiceBuilder) WithEndpoints(endpoints ...*EndpointBuilder) *ServiceBuilder {
+	b.endpoints = append(b.endpoints, endpoints...)
+	return b
+}
+
+// Build returns a new service with new endpoints on each call.
+func (b *ServiceBuilder) Build() *model.Service {
+	service := b.service
+	service.Endpoints = make([]*model.Endpoint, 0, len(b.endpoints))
+	for _, endpoint := range b.endpoints {
+		service.Endpoints = append(service.Endpoints, endpoint.Build())
+	}
+	return &service
+}
+
+// EndpointBuilder builds an endpoint, whose endpoint and service ports are DefaultPort by default.
+type EndpointBuilder struct {
+	endpoint  model.Endpoint
+	clusterId string
+}
+
+// Endpoint starts building an endpoint with the given IP address.
+func Endpoint(ip string) *EndpointBuilder {
+	port := model.Port{Name: DefaultPortName, Port: DefaultPort, Protocol: DefaultProtocol}
+	servicePort := port
+	servicePort.TargetPort = strconv.Itoa(DefaultPort)
+	return &EndpointBuilder{endpoint: model.Endpoint{
+		IP:           ip,
+		EndpointPort: port,
+		ServicePort:  servicePort,
+		Attributes:   make(map[string]string),
+	}}
+}
+
+// WithId sets the endpoint ID, which is derived from the cluster, IP address and endpoint port by default.
+func (b *EndpointBuilder) WithId(id string) *EndpointBuilder {
+	b.endpoint.Id = id
+	return b
+}
+
+// WithClusterId sets the cluster which registered the endpoint, included in the default endpoint ID.
+func (b *EndpointBuilder) WithClusterId(clusterId string) *EndpointBuilder {
+	b.clusterId = clusterId
+	return b
+}
+
+// WithPort sets the port of the endpoint, and the target port of the service port.
+func (b *EndpointBuilder) WithPort(name string, port int32, protocol string) *EndpointBuilder {
+	b.endpoint.EndpointPort = model.Port{Name: name, Port: port, Protocol: protocol}
+	b.endpoint.ServicePort.TargetPort = strconv.Itoa(int(port))
+	return b
+}
+
+// WithServicePort sets the service port exposing the endpoint.
+func (b *EndpointBuilder) WithServicePort(name string, port int32, protocol string) *EndpointBuilder {
+	b.endpoint.ServicePort.Name = name
+	b.endpoint.ServicePort.Port = port
+	b.endpoint.ServicePort.Protocol = protocol
+	return b
+}
+
+// WithAttribute sets a custom Cloud Map attribute of the endpoint.
+func (b *EndpointBuilder) WithAttribute(key string, value string) *EndpointBuilder {
+	b.endpoint.Attributes[key] = value
+	return b
+}
+
+// Build returns a new endpoint on each call.
+func (b *EndpointBuilder) Build() *model.Endpoint {
+	endpoint := b.endpoint
+	if endpoint.Id == "" {
+		endpoint.Id = model.EndpointId(b.clusterId, endpoint.IP, endpoint.EndpointPort)
+	}
+	endpoint.Attributes = make(map[string]string, len(b.endpoint.Attributes))
+	for key, value := range b.endpoint.Attributes {
+		endpoint.Attributes[key] = value
+	}
+	return &endpoint
+}
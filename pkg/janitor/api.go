@@ -0,0 +1,135 @@
+package janitor
+
+import (
+	"context"
+	"github.com/aws/aws-cloud-map-mcs-controller-for-k8s/pkg/cloudmap"
+	"github.com/aws/aws-sdk-go-v2/aws"
+	sd "github.com/aws/aws-sdk-go-v2/service/servicediscovery"
+	"github.com/aws/aws-sdk-go-v2/service/servicediscovery/types"
+)
+
+type ServiceDiscoveryJanitorApi interface {
+	ListNamespaceSummaries(ctx context.Context) (namespaces []types.NamespaceSummary, err error)
+	ListServiceSummaries(ctx context.Context, namespaceId string) (services []types.ServiceSummary, err error)
+	ListInstanceSummaries(ctx context.Context, serviceId string) (instances []types.InstanceSummary, err error)
+	DeregisterInstances(ctx context.Context, serviceId string, instanceIds []string) error
+	DeleteNamespace(ctx context.Context, namespaceId string) (operationId string, err error)
+	DeleteService(ctx context.Context, serviceId string) error
+	GetNamespaceTags(ctx context.Context, namespaceId string) (tags map[string]string, err error)
+	GetServiceTags(ctx context.Context, serviceId string) (tags map[string]string, err error)
+	cloudmap.ServiceDiscoveryApi
+}
+
+type serviceDiscoveryJanitorApi struct {
+	cloudmap.ServiceDiscoveryApi
+	janitorFacade SdkJanitorFacade
+}
+
+func NewServiceDiscoveryJanitorApiFromConfig(cfg *aws.Config) ServiceDiscoveryJanitorApi {
+	return &serviceDiscoveryJanitorApi{
+		ServiceDiscoveryApi: cloudmap.NewServiceDiscoveryApiFromConfig(cfg),
+		janitorFacade:       NewSdkJanitorFacadeFromConfig(cfg),
+	}
+}
+
+func (api *serviceDiscoveryJanitorApi) ListNamespaceSummaries(ctx context.Context) (namespaces []types.NamespaceSummary, err error) {
+	pages := sd.NewListNamespacesPaginator(api.janitorFacade, &sd.ListNamespacesInput{})
+	for pages.HasMorePages() {
+		output, err := pages.NextPage(ctx)
+		if err != nil {
+			return namespaces, err
+		}
+		namespaces = append(namespaces, output.Namespaces...)
+	}
+
+	return namespaces, nil
+}
+
+func (api *serviceDiscoveryJanitorApi) ListServiceSummaries(ctx context.Context, nsId string) (services []types.ServiceSummary, err error) {
+	filter := types.ServiceFilter{
+		Name:   types.ServiceFilterNameNamespaceId,
+		Values: []string{nsId},
+	}
+	pages := sd.NewListServicesPaginator(api.janitorFacade, &sd.ListServicesInput{Filters: []types.ServiceFilter{filter}})
+	for pages.HasMorePages() {
+		output, err := pages.NextPage(ctx)
+		if err != nil {
+			return services, err
+		}
+		services = append(services, output.Services...)
+	}
+
+	return services, nil
+}
+
+func (api *serviceDiscoveryJanitorApi) ListInstanceSummaries(ctx context.Context, svcId string) (instances []types.InstanceSummary, err error) {
+	pages := sd.NewListInstancesPaginator(api.janitorFacade, &sd.ListInstancesInput{ServiceId: &svcId})
+	for pages.HasMorePages() {
+		output, err := pages.NextPage(ctx)
+		if err != nil {
+			return instances, err
+		}
+		instances = append(instances, output.Instances...)
+	}
+
+	return instances, nil
+}
+
+// DeregisterInstances deregisters the instances of a service and waits until all operations completed, the service
+// can only be deleted once it has no instances left.
+func (api *serviceDiscoveryJanitorApi) DeregisterInstances(ctx context.Context, svcId string, instIds []string) error {
+	opColl := cloudmap.NewOperationCollector()
+	for _, instId := range instIds {
+		instId := instId
+		opColl.Add(func() (opId string, err error) {
+			return api.DeregisterInstance(ctx, svcId, instId)
+		})
+	}
+
+	return cloudmap.NewDeregisterInstancePoller(api, svcId, opColl.Collect(), opColl.GetStartTime(), nil).Poll(ctx)
+}
+
+func (api *serviceDiscoveryJanitorApi) DeleteNamespace(ctx context.Context, nsId string) (opId string, err error) {
+	out, err := api.janitorFacade.DeleteNamespace(ctx, &sd.DeleteNamespaceInput{Id: &nsId})
+	if err != nil {
+		return "", err
+	}
+
+	return aws.ToString(out.OperationId), nil
+}
+
+func (api *serviceDiscoveryJanitorApi) DeleteService(ctx context.Context, svcId string) error {
+	_, err := api.janitorFacade.DeleteService(ctx, &sd.DeleteServiceInput{Id: &svcId})
+	return err
+}
+
+func (api *serviceDiscoveryJanitorApi) GetNamespaceTags(ctx context.Context, nsId string) (tags map[string]string, err error) {
+	out, err := api.janitorFacade.GetNamespace(ctx, &sd.GetNamespaceInput{Id: &nsId})
+	if err != nil {
+		return nil, err
+	}
+
+	return api.listTags(ctx, out.Namespace.Arn)
+}
+
+func (api *serviceDiscoveryJanitorApi) GetServiceTags(ctx context.Context, svcId string) (tags map[string]string, err error) {
+	out, err := api.janitorFacade.GetService(ctx, &sd.GetServiceInput{Id: &svcId})
+	if err != nil {
+		return nil, err
+	}
+
+	return api.listTags(ctx, out.Service.Arn)
+}
+
+func (api *serviceDiscoveryJanitorApi) listTags(ctx context.Context, arn *string) (tags map[string]string, err error) {
+	out, err := api.janitorFacade.ListTagsForResource(ctx, &sd.ListTagsForResourceInput{ResourceARN: arn})
+	if err != nil {
+		return nil, err
+	}
+
+	tags = make(map[string]string, len(out.Tags))
+	for _, tag := range out.Tags {
+		tags[aws.ToString(tag.Key)] = aws.ToString(tag.Value)
+	}
+	return tags, nil
+}
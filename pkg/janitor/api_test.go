@@ -0,0 +1,125 @@
+package janitor
+
+import (
+	"context"
+	"github.com/aws/aws-cloud-map-mcs-controller-for-k8s/mocks/pkg/cloudmap"
+	"github.com/aws/aws-cloud-map-mcs-controller-for-k8s/mocks/pkg/janitor"
+	"github.com/aws/aws-cloud-map-mcs-controller-for-k8s/test"
+	"github.com/aws/aws-sdk-go-v2/aws"
+	sd "github.com/aws/aws-sdk-go-v2/service/servicediscovery"
+	"github.com/aws/aws-sdk-go-v2/service/servicediscovery/types"
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func TestNewServiceDiscoveryJanitorApiFromConfig(t *testing.T) {
+	assert.NotNil(t, NewServiceDiscoveryJanitorApiFromConfig(&aws.Config{}))
+}
+
+func TestServiceDiscoveryJanitorApi_DeleteNamespace_HappyCase(t *testing.T) {
+	mockController := gomock.NewController(t)
+	defer mockController.Finish()
+
+	mocksdk := janitor.NewMockSdkJanitorFacade(mockController)
+	jApi := getJanitorApi(t, mocksdk)
+
+	mocksdk.EXPECT().DeleteNamespace(context.TODO(), &sd.DeleteNamespaceInput{Id: aws.String(test.NsId)}).
+		Return(&sd.DeleteNamespaceOutput{OperationId: aws.String(test.OpId1)}, nil)
+
+	opId, err := jApi.DeleteNamespace(context.TODO(), test.NsId)
+	assert.Nil(t, err, "No error for happy case")
+	assert.Equal(t, test.OpId1, opId)
+}
+
+func TestServiceDiscoveryJanitorApi_DeleteService_HappyCase(t *testing.T) {
+	mockController := gomock.NewController(t)
+	defer mockController.Finish()
+
+	mocksdk := janitor.NewMockSdkJanitorFacade(mockController)
+	jApi := getJanitorApi(t, mocksdk)
+
+	mocksdk.EXPECT().DeleteService(context.TODO(), &sd.DeleteServiceInput{Id: aws.String(test.SvcId)}).
+		Return(&sd.DeleteServiceOutput{}, nil)
+
+	err := jApi.DeleteService(context.TODO(), test.SvcId)
+	assert.Nil(t, err, "No error for happy case")
+}
+
+func TestServiceDiscoveryJanitorApi_GetNamespaceTags_HappyCase(t *testing.T) {
+	mockController := gomock.NewController(t)
+	defer mockController.Finish()
+
+	mocksdk := janitor.NewMockSdkJanitorFacade(mockController)
+	jApi := getJanitorApi(t, mocksdk)
+
+	nsArn := aws.String("arn:aws:servicediscovery:us-west-2:123456789012:namespace/" + test.NsId)
+	mocksdk.EXPECT().GetNamespace(context.TODO(), &sd.GetNamespaceInput{Id: aws.String(test.NsId)}).
+		Return(&sd.GetNamespaceOutput{Namespace: &types.Namespace{Arn: nsArn}}, nil)
+	mocksdk.EXPECT().ListTagsForResource(context.TODO(), &sd.ListTagsForResourceInput{ResourceARN: nsArn}).
+		Return(&sd.ListTagsForResourceOutput{
+			Tags: []types.Tag{{Key: aws.String("team"), Value: aws.String("platform")}},
+		}, nil)
+
+	tags, err := jApi.GetNamespaceTags(context.TODO(), test.NsId)
+	assert.Nil(t, err, "No error for happy case")
+	assert.Equal(t, map[string]string{"team": "platform"}, tags)
+}
+
+func TestServiceDiscoveryJanitorApi_ListServiceSummaries_HappyCase(t *testing.T) {
+	mockController := gomock.NewController(t)
+	defer mockController.Finish()
+
+	mocksdk := janitor.NewMockSdkJanitorFacade(mockController)
+	jApi := getJanitorApi(t, mocksdk)
+
+	filter := types.ServiceFilter{
+		Name:   types.ServiceFilterNameNamespaceId,
+		Values: []string{test.NsId},
+	}
+	svc := types.ServiceSummary{Id: aws.String(test.SvcId), Name: aws.String(test.SvcName)}
+	mocksdk.EXPECT().ListServices(context.TODO(), &sd.ListServicesInput{Filters: []types.ServiceFilter{filter}}).
+		Return(&sd.ListServicesOutput{Services: []types.ServiceSummary{svc}}, nil)
+
+	svcs, err := jApi.ListServiceSummaries(context.TODO(), test.NsId)
+	assert.Nil(t, err, "No error for happy case")
+	assert.Equal(t, []types.ServiceSummary{svc}, svcs)
+}
+
+func TestServiceDiscoveryJanitorApi_ListInstanceSummaries_HappyCase(t *testing.T) {
+	mockController := gomock.NewController(t)
+	defer mockController.Finish()
+
+	mocksdk := janitor.NewMockSdkJanitorFacade(mockController)
+	jApi := getJanitorApi(t, mocksdk)
+
+	inst := types.InstanceSummary{Id: aws.String(test.EndptId1)}
+	mocksdk.EXPECT().ListInstances(context.TODO(), &sd.ListInstancesInput{ServiceId: aws.String(test.SvcId)}).
+		Return(&sd.ListInstancesOutput{Instances: []types.InstanceSummary{inst}}, nil)
+
+	insts, err := jApi.ListInstanceSummaries(context.TODO(), test.SvcId)
+	assert.Nil(t, err, "No error for happy case")
+	assert.Equal(t, []types.InstanceSummary{inst}, insts)
+}
+
+func TestServiceDiscoveryJanitorApi_DeregisterInstances_HappyCase(t *testing.T) {
+	mockController := gomock.NewController(t)
+	defer mockController.Finish()
+
+	mockApi := cloudmap.NewMockServiceDiscoveryApi(mockController)
+	jApi := &serviceDiscoveryJanitorApi{ServiceDiscoveryApi: mockApi}
+
+	mockApi.EXPECT().DeregisterInstance(gomock.Any(), test.SvcId, test.EndptId1).
+		Return(test.OpId1, nil)
+	mockApi.EXPECT().ListOperations(gomock.Any(), gomock.Any()).
+		Return(map[string]types.OperationStatus{test.OpId1: types.OperationStatusSuccess}, nil)
+
+	err := jApi.DeregisterInstances(context.TODO(), test.SvcId, []string{test.EndptId1})
+	assert.Nil(t, err, "No error for happy case")
+}
+
+func getJanitorApi(t *testing.T, sdk *janitor.MockSdkJanitorFacade) ServiceDiscoveryJanitorApi {
+	return &serviceDiscoveryJanitorApi{
+		janitorFacade: sdk,
+	}
+}
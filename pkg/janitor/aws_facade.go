@@ -10,11 +10,8 @@ import (
 // SdkJanitorFacade extends the minimal surface area of ServiceDiscovery API calls of the client
 // for integration test janitor operations.
 type SdkJanitorFacade interface {
-	// DeleteNamespace provides ServiceDiscovery DeleteNamespace wrapper interface.
-	DeleteNamespace(context.Context, *sd.DeleteNamespaceInput, ...func(*sd.Options)) (*sd.DeleteNamespaceOutput, error)
-
-	// DeleteService provides ServiceDiscovery DeleteService wrapper interface.
-	DeleteService(context.Context, *sd.DeleteServiceInput, ...func(*sd.Options)) (*sd.DeleteServiceOutput, error)
+	// ListInstances provides ServiceDiscovery ListInstances wrapper interface for paginator.
+	ListInstances(context.Context, *sd.ListInstancesInput, ...func(*sd.Options)) (*sd.ListInstancesOutput, error)
 
 	cloudmap.AwsFacade
 }
@@ -0,0 +1,31 @@
+package janitor
+
+import (
+	"fmt"
+	"path"
+	"regexp"
+)
+
+// NamespaceMatcher selects the namespaces to clean up by name.
+type NamespaceMatcher func(nsName string) bool
+
+// NewGlobMatcher returns a matcher of namespace names matching a shell glob pattern, e.g. "e2e-*".
+func NewGlobMatcher(pattern string) (NamespaceMatcher, error) {
+	if _, err := path.Match(pattern, ""); err != nil {
+		return nil, fmt.Errorf("invalid namespace glob %s: %w", pattern, err)
+	}
+	return func(nsName string) bool {
+		matched, _ := path.Match(pattern, nsName)
+		return matched
+	}, nil
+}
+
+// NewRegexMatcher returns a matcher of namespace names matching a regular expression. The expression is anchored, so
+// it has to match the whole namespace name.
+func NewRegexMatcher(expr string) (NamespaceMatcher, error) {
+	re, err := regexp.Compile("^(?:" + expr + ")$")
+	if err != nil {
+		return nil, fmt.Errorf("invalid namespace regex %s: %w", expr, err)
+	}
+	return re.MatchString, nil
+}
@@ -0,0 +1,26 @@
+package janitor
+
+import (
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func TestNewGlobMatcher(t *testing.T) {
+	matcher, err := NewGlobMatcher("e2e-*")
+	assert.NoError(t, err)
+	assert.True(t, matcher("e2e-1234"))
+	assert.False(t, matcher("prod-e2e-1234"))
+
+	_, err = NewGlobMatcher("e2e-[")
+	assert.Error(t, err)
+}
+
+func TestNewRegexMatcher(t *testing.T) {
+	matcher, err := NewRegexMatcher(`e2e-\d+`)
+	assert.NoError(t, err)
+	assert.True(t, matcher("e2e-1234"))
+	assert.False(t, matcher("e2e-1234-prod"), "expression is anchored")
+
+	_, err = NewRegexMatcher("e2e-(")
+	assert.Error(t, err)
+}
@@ -0,0 +1,386 @@
+package janitor
+
+import (
+	"context"
+	"fmt"
+	"github.com/aws/aws-cloud-map-mcs-controller-for-k8s/pkg/cloudmap"
+	"github.com/aws/aws-cloud-map-mcs-controller-for-k8s/pkg/common"
+	"github.com/aws/aws-cloud-map-mcs-controller-for-k8s/pkg/controllers"
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/config"
+	"github.com/aws/aws-sdk-go-v2/service/servicediscovery/types"
+	utilerrors "k8s.io/apimachinery/pkg/util/errors"
+	"k8s.io/client-go/util/workqueue"
+	"time"
+)
+
+// DefaultConcurrency is the default number of namespaces and services cleaned up in parallel
+const DefaultConcurrency = 10
+
+// CloudMapJanitor handles AWS Cloud Map resource cleanup, of integration tests as well as of decommissioned clusters.
+type CloudMapJanitor interface {
+	// Cleanup removes all instances, services and the namespace from AWS Cloud Map for a given namespace name. Only
+	// resources carrying the ownership tags of the controller are deleted, unless forced to delete untagged resources.
+	// In dry-run mode the resources are only listed. The failures are returned once all resources have been
+	// processed.
+	Cleanup(ctx context.Context, nsName string) error
+
+	// CleanupMatching cleans up every namespace whose name is selected by the matcher, as Cleanup does.
+	CleanupMatching(ctx context.Context, matcher NamespaceMatcher) error
+}
+
+// Options control which resources the janitor deletes.
+type Options struct {
+	// ForceUntagged deletes namespaces and services which don't carry the ownership tags of the controller
+	ForceUntagged bool
+	// DryRun lists the resources which would be deleted without deleting them
+	DryRun bool
+	// OlderThan restricts deletion to namespaces and services created longer ago, 0 deletes resources of any age
+	OlderThan time.Duration
+	// Concurrency is the number of namespaces, and of services per namespace, cleaned up in parallel
+	Concurrency int
+	// DrainClusterId only deregisters the instances registered by the cluster with this ID, keeping the services,
+	// namespaces and instances of other clusters. Ownership tags and age are ignored when draining a cluster.
+	DrainClusterId string
+	// ReportFile is the path of the report written after the cleanup, "-" writes to stdout and "" writes no report
+	ReportFile string
+	// ReportFormat is the format of the report, json or yaml
+	ReportFormat string
+	// Audit records the de-registrations and deletions in the audit log, they are not audited if nil
+	Audit cloudmap.AuditLogger
+}
+
+// cloudMapJanitor logs its progress rather than printing it, the report may be written to stdout.
+type cloudMapJanitor struct {
+	log    common.Logger
+	sdApi  ServiceDiscoveryJanitorApi
+	opts   Options
+	report *Report
+	now    func() time.Time
+}
+
+// NewDefaultJanitor returns a new janitor object with the given options, using the default AWS config.
+func NewDefaultJanitor(opts Options) (CloudMapJanitor, error) {
+	awsCfg, err := config.LoadDefaultConfig(context.TODO())
+	if err != nil {
+		return nil, fmt.Errorf("unable to configure AWS session: %w", err)
+	}
+
+	return NewJanitor(&awsCfg, opts), nil
+}
+
+// NewJanitor returns a new janitor object with the given options and AWS config.
+func NewJanitor(awsCfg *aws.Config, opts Options) CloudMapJanitor {
+	return &cloudMapJanitor{
+		log:    common.NewLogger("janitor"),
+		sdApi:  NewServiceDiscoveryJanitorApiFromConfig(awsCfg),
+		opts:   opts,
+		report: NewReport(opts.DryRun),
+		now:    time.Now,
+	}
+}
+
+func (j *cloudMapJanitor) Cleanup(ctx context.Context, nsName string) error {
+	j.log.Info("cleaning up all resources in Cloud Map for namespace", "namespace", nsName)
+	return j.cleanupNamespaces(ctx, func(name string) bool { return name == nsName })
+}
+
+func (j *cloudMapJanitor) CleanupMatching(ctx context.Context, matcher NamespaceMatcher) error {
+	j.log.Info("cleaning up all resources in Cloud Map for matching namespaces")
+	return j.cleanupNamespaces(ctx, matcher)
+}
+
+func (j *cloudMapJanitor) cleanupNamespaces(ctx context.Context, matcher NamespaceMatcher) error {
+	if j.opts.DryRun {
+		j.log.Info("dry run, no resources will be deleted")
+	}
+	if j.opts.DrainClusterId != "" {
+		j.log.Info("draining cluster, only its instances will be de-registered", "clusterId", j.opts.DrainClusterId)
+	}
+
+	err := j.cleanupMatchedNamespaces(ctx, matcher)
+	j.report.addFailure(err)
+	if j.opts.ReportFile != "" {
+		if reportErr := j.report.Write(j.opts.ReportFile, j.opts.ReportFormat); reportErr != nil && err == nil {
+			err = fmt.Errorf("could not write report: %w", reportErr)
+		}
+	}
+	if err != nil {
+		return fmt.Errorf("could not cleanup all resources: %w", err)
+	}
+	j.log.Info("clean up successful")
+	return nil
+}
+
+func (j *cloudMapJanitor) cleanupMatchedNamespaces(ctx context.Context, matcher NamespaceMatcher) error {
+	nsList, err := j.sdApi.ListNamespaceSummaries(ctx)
+	if err != nil {
+		return fmt.Errorf("could not find namespace to clean: %w", err)
+	}
+
+	var matched []types.NamespaceSummary
+	for _, ns := range nsList {
+		if matcher(aws.ToString(ns.Name)) {
+			matched = append(matched, ns)
+		}
+	}
+
+	if len(matched) == 0 {
+		j.log.Info("namespace does not exist in account, nothing to clean")
+		return nil
+	}
+
+	return j.parallelize(ctx, len(matched), func(i int) error {
+		if j.opts.DrainClusterId != "" {
+			return j.drainNamespace(ctx, matched[i])
+		}
+		return j.cleanupNamespace(ctx, matched[i])
+	})
+}
+
+func (j *cloudMapJanitor) cleanupNamespace(ctx context.Context, ns types.NamespaceSummary) error {
+	nsName := aws.ToString(ns.Name)
+	nsId := aws.ToString(ns.Id)
+	nsReport := ResourceReport{Type: NamespaceResource, Id: nsId, Name: nsName, Age: j.age(ns.CreateDate)}
+	j.log.Info("found namespace to clean", "namespaceId", nsId, "namespace", nsName, "age", nsReport.Age)
+	if !j.isOldEnough(ns.CreateDate) {
+		j.log.Info("namespace is too young, skipping cleanup", "namespaceId", nsId, "olderThan", j.opts.OlderThan.String())
+		j.report.add(nsReport.withAction(SkippedAction, fmt.Sprintf("younger than %s", j.opts.OlderThan)))
+		return nil
+	}
+
+	nsTags, err := j.sdApi.GetNamespaceTags(ctx, nsId)
+	if err != nil {
+		return j.failed(nsReport, fmt.Errorf("could not get tags of namespace %s: %w", nsId, err))
+	}
+	if !j.isDeletable(nsTags) {
+		j.log.Info("namespace was not created by the controller, skipping cleanup (use --force-untagged to delete)",
+			"namespaceId", nsId)
+		j.report.add(nsReport.withAction(SkippedAction, "not created by the controller"))
+		return nil
+	}
+
+	svcs, err := j.sdApi.ListServiceSummaries(ctx, nsId)
+	if err != nil {
+		return j.failed(nsReport, fmt.Errorf("could not find services of namespace %s to clean: %w", nsId, err))
+	}
+	j.log.Info("found services to clean", "namespaceId", nsId, "services", len(svcs))
+
+	skipped := make([]bool, len(svcs))
+	err = j.parallelize(ctx, len(svcs), func(i int) (svcErr error) {
+		skipped[i], svcErr = j.cleanupService(ctx, ns, svcs[i])
+		return svcErr
+	})
+	if err != nil {
+		j.report.add(nsReport.withAction(SkippedAction, "services could not be cleaned up"))
+		return err
+	}
+
+	for _, skip := range skipped {
+		if skip {
+			j.log.Info("namespace has skipped services, skipping namespace cleanup", "namespaceId", nsId)
+			j.report.add(nsReport.withAction(SkippedAction, "has skipped services"))
+			return nil
+		}
+	}
+
+	if j.opts.DryRun {
+		j.log.Info("would delete namespace", "namespaceId", nsId)
+		j.report.add(nsReport.withAction(WouldDeleteAction, ""))
+		return nil
+	}
+
+	opId, err := j.sdApi.DeleteNamespace(ctx, nsId)
+	j.audit(ctx, cloudmap.AuditRecord{
+		Action:      cloudmap.AuditActionDeleteNamespace,
+		Namespace:   nsName,
+		OperationId: opId,
+	}, err)
+	if err == nil {
+		j.log.Info("namespace delete in progress", "namespaceId", nsId)
+		_, err = j.sdApi.PollNamespaceOperation(ctx, opId)
+	}
+	if err != nil {
+		return j.failed(nsReport, fmt.Errorf("could not cleanup namespace %s: %w", nsId, err))
+	}
+	j.log.Info("namespace deleted", "namespaceId", nsId)
+	j.report.add(nsReport.withAction(DeletedAction, ""))
+	return nil
+}
+
+// drainNamespace deregisters the instances of the drained cluster from all services of a namespace.
+func (j *cloudMapJanitor) drainNamespace(ctx context.Context, ns types.NamespaceSummary) error {
+	nsId, nsName := aws.ToString(ns.Id), aws.ToString(ns.Name)
+	j.log.Info("found namespace to drain", "namespaceId", nsId, "namespace", nsName)
+
+	svcs, err := j.sdApi.ListServiceSummaries(ctx, nsId)
+	if err != nil {
+		return fmt.Errorf("could not find services of namespace %s to drain: %w", nsId, err)
+	}
+	j.log.Info("found services to drain", "namespaceId", nsId, "services", len(svcs))
+
+	return j.parallelize(ctx, len(svcs), func(i int) error {
+		return j.deregisterInstances(ctx, nsName, svcs[i])
+	})
+}
+
+// cleanupService deregisters the instances and deletes a service, it returns true if the service is skipped.
+func (j *cloudMapJanitor) cleanupService(ctx context.Context, ns types.NamespaceSummary, svc types.ServiceSummary) (
+	skipped bool, err error) {
+	nsId, nsName := aws.ToString(ns.Id), aws.ToString(ns.Name)
+	svcId, svcName := aws.ToString(svc.Id), aws.ToString(svc.Name)
+	svcReport := ResourceReport{
+		Type: ServiceResource, Id: svcId, Name: svcName, ParentId: nsId, Age: j.age(svc.CreateDate),
+	}
+	svcTags, err := j.sdApi.GetServiceTags(ctx, svcId)
+	if err != nil {
+		return false, j.failed(svcReport, fmt.Errorf("could not get tags of service %s: %w", svcId, err))
+	}
+	if !j.isDeletable(svcTags) {
+		j.log.Info("service was not created by the controller, skipping cleanup", "serviceId", svcId)
+		j.report.add(svcReport.withAction(SkippedAction, "not created by the controller"))
+		return true, nil
+	}
+
+	j.log.Info("found service to clean", "serviceId", svcId, "service", svcName, "age", svcReport.Age)
+	if !j.isOldEnough(svc.CreateDate) {
+		j.log.Info("service is too young, skipping cleanup", "serviceId", svcId, "olderThan", j.opts.OlderThan.String())
+		j.report.add(svcReport.withAction(SkippedAction, fmt.Sprintf("younger than %s", j.opts.OlderThan)))
+		return true, nil
+	}
+
+	if err = j.deregisterInstances(ctx, nsName, svc); err != nil {
+		j.report.add(svcReport.withAction(SkippedAction, "instances could not be de-registered"))
+		return false, err
+	}
+
+	if j.opts.DryRun {
+		j.log.Info("would delete service", "serviceId", svcId)
+		j.report.add(svcReport.withAction(WouldDeleteAction, ""))
+		return false, nil
+	}
+	err = j.sdApi.DeleteService(ctx, svcId)
+	j.audit(ctx, cloudmap.AuditRecord{
+		Action:    cloudmap.AuditActionDeleteService,
+		Namespace: nsName,
+		Service:   svcName,
+		ServiceId: svcId,
+	}, err)
+	if err != nil {
+		return false, j.failed(svcReport, fmt.Errorf("could not cleanup service %s: %w", svcId, err))
+	}
+	j.log.Info("service deleted", "serviceId", svcId)
+	j.report.add(svcReport.withAction(DeletedAction, ""))
+	return false, nil
+}
+
+func (j *cloudMapJanitor) deregisterInstances(ctx context.Context, nsName string, svc types.ServiceSummary) error {
+	svcId := aws.ToString(svc.Id)
+	insts, err := j.sdApi.ListInstanceSummaries(ctx, svcId)
+	if err != nil {
+		return fmt.Errorf("could not list instances of service %s to cleanup: %w", svcId, err)
+	}
+	j.log.Info("found instances", "serviceId", svcId, "instances", len(insts))
+
+	instIds := make([]string, 0, len(insts))
+	for _, inst := range insts {
+		instId := aws.ToString(inst.Id)
+		instReport := ResourceReport{Type: InstanceResource, Id: instId, ParentId: svcId}
+		if !j.isDrained(inst) {
+			j.report.add(instReport.withAction(SkippedAction, "registered by another cluster"))
+			continue
+		}
+		if j.opts.DryRun {
+			j.log.Info("would deregister instance", "serviceId", svcId, "instanceId", instId)
+			j.report.add(instReport.withAction(WouldDeregisterAction, ""))
+		} else {
+			j.log.Info("found instance to clean", "serviceId", svcId, "instanceId", instId)
+		}
+		instIds = append(instIds, instId)
+	}
+	if j.opts.DryRun || len(instIds) == 0 {
+		return nil
+	}
+
+	err = j.sdApi.DeregisterInstances(ctx, svcId, instIds)
+	if err != nil {
+		err = fmt.Errorf("could not cleanup instances of service %s: %w", svcId, err)
+	} else {
+		j.log.Info("instances de-registered", "serviceId", svcId)
+	}
+	for _, instId := range instIds {
+		j.audit(ctx, cloudmap.AuditRecord{
+			Action:     cloudmap.AuditActionDeregisterInstance,
+			Namespace:  nsName,
+			Service:    aws.ToString(svc.Name),
+			ServiceId:  svcId,
+			InstanceId: instId,
+		}, err)
+		instReport := ResourceReport{Type: InstanceResource, Id: instId, ParentId: svcId}
+		if err != nil {
+			j.report.add(instReport.withAction(FailedAction, err.Error()))
+		} else {
+			j.report.add(instReport.withAction(DeregisteredAction, ""))
+		}
+	}
+	return err
+}
+
+// parallelize runs the cleanup of n resources with bounded concurrency and aggregates the errors of all resources.
+func (j *cloudMapJanitor) parallelize(ctx context.Context, n int, cleanup func(i int) error) error {
+	concurrency := j.opts.Concurrency
+	if concurrency <= 0 {
+		concurrency = 1
+	}
+
+	errs := make([]error, n)
+	workqueue.ParallelizeUntil(ctx, concurrency, n, func(i int) {
+		errs[i] = cleanup(i)
+	})
+	return utilerrors.NewAggregate(errs)
+}
+
+// audit records a de-registration or deletion in the audit log, along with its error if it failed.
+func (j *cloudMapJanitor) audit(ctx context.Context, record cloudmap.AuditRecord, err error) {
+	if j.opts.Audit == nil {
+		return
+	}
+	if err != nil {
+		record.Error = err.Error()
+	}
+	j.opts.Audit.Record(cloudmap.WithAuditTrigger(ctx, "janitor"), record)
+}
+
+// failed records a failed cleanup of a resource in the report and returns the error.
+func (j *cloudMapJanitor) failed(resource ResourceReport, err error) error {
+	j.report.add(resource.withAction(FailedAction, err.Error()))
+	return err
+}
+
+// age returns the time since the creation of a resource, Cloud Map doesn't report the creation time of instances.
+func (j *cloudMapJanitor) age(createDate *time.Time) string {
+	if createDate == nil {
+		return "unknown"
+	}
+	return j.now().Sub(*createDate).Round(time.Second).String()
+}
+
+// isOldEnough returns true if a resource was created longer ago than the age filter. Resources of unknown age are
+// only deleted without age filter.
+func (j *cloudMapJanitor) isOldEnough(createDate *time.Time) bool {
+	if j.opts.OlderThan <= 0 {
+		return true
+	}
+	return createDate != nil && j.now().Sub(*createDate) >= j.opts.OlderThan
+}
+
+// isDrained returns true if an instance is to be de-registered, which are all instances unless a cluster is drained.
+func (j *cloudMapJanitor) isDrained(inst types.InstanceSummary) bool {
+	return j.opts.DrainClusterId == "" || inst.Attributes[controllers.ClusterIdAttr] == j.opts.DrainClusterId
+}
+
+// isDeletable returns true if the resource tags identify a resource created by the controller, or deleting untagged
+// resources is forced.
+func (j *cloudMapJanitor) isDeletable(tags map[string]string) bool {
+	return j.opts.ForceUntagged || cloudmap.IsOwned(tags)
+}
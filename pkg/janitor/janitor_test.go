@@ -0,0 +1,378 @@
+package janitor
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/aws/aws-cloud-map-mcs-controller-for-k8s/mocks/pkg/janitor"
+	"github.com/aws/aws-cloud-map-mcs-controller-for-k8s/pkg/cloudmap"
+	"github.com/aws/aws-cloud-map-mcs-controller-for-k8s/pkg/common"
+	"github.com/aws/aws-cloud-map-mcs-controller-for-k8s/pkg/controllers"
+	"github.com/aws/aws-cloud-map-mcs-controller-for-k8s/test"
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/servicediscovery/types"
+	testingLogger "github.com/go-logr/logr/testing"
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/assert"
+	utilerrors "k8s.io/apimachinery/pkg/util/errors"
+	"sync"
+	"testing"
+	"time"
+)
+
+type testJanitor struct {
+	janitor *cloudMapJanitor
+	mockApi *janitor.MockServiceDiscoveryJanitorApi
+	close   func()
+}
+
+func TestNewDefaultJanitor(t *testing.T) {
+	j, err := NewDefaultJanitor(Options{})
+	assert.NoError(t, err)
+	assert.NotNil(t, j)
+}
+
+func TestNewJanitor(t *testing.T) {
+	assert.NotNil(t, NewJanitor(&aws.Config{}, Options{DryRun: true}))
+}
+
+func TestCleanupHappyCase(t *testing.T) {
+	tj := getTestJanitor(t)
+	defer tj.close()
+
+	tj.mockApi.EXPECT().ListNamespaceSummaries(context.TODO()).
+		Return([]types.NamespaceSummary{{Id: aws.String(test.NsId), Name: aws.String(test.NsName)}}, nil)
+	tj.mockApi.EXPECT().GetNamespaceTags(context.TODO(), test.NsId).
+		Return(cloudmap.OwnershipTags(test.ClusterId, test.ClusterSetId), nil)
+	tj.mockApi.EXPECT().ListServiceSummaries(context.TODO(), test.NsId).
+		Return([]types.ServiceSummary{{Id: aws.String(test.SvcId), Name: aws.String(test.SvcName)}}, nil)
+	tj.mockApi.EXPECT().GetServiceTags(context.TODO(), test.SvcId).
+		Return(cloudmap.OwnershipTags(test.ClusterId, test.ClusterSetId), nil)
+	tj.mockApi.EXPECT().ListInstanceSummaries(context.TODO(), test.SvcId).
+		Return([]types.InstanceSummary{{Id: aws.String(test.EndptId1)}}, nil)
+
+	// the service is deleted after its instances are de-registered
+	gomock.InOrder(
+		tj.mockApi.EXPECT().DeregisterInstances(context.TODO(), test.SvcId, []string{test.EndptId1}).
+			Return(nil),
+		tj.mockApi.EXPECT().DeleteService(context.TODO(), test.SvcId).
+			Return(nil),
+	)
+	tj.mockApi.EXPECT().DeleteNamespace(context.TODO(), test.NsId).
+		Return(test.OpId2, nil)
+	tj.mockApi.EXPECT().PollNamespaceOperation(context.TODO(), test.OpId2).
+		Return(test.NsId, nil)
+
+	assert.NoError(t, tj.janitor.Cleanup(context.TODO(), test.NsName))
+	assert.True(t, tj.janitor.report.IsClean())
+	assert.ElementsMatch(t, []ResourceReport{
+		{Type: NamespaceResource, Id: test.NsId, Name: test.NsName, Age: "unknown", Action: DeletedAction},
+		{Type: ServiceResource, Id: test.SvcId, Name: test.SvcName, ParentId: test.NsId, Age: "unknown",
+			Action: DeletedAction},
+		{Type: InstanceResource, Id: test.EndptId1, ParentId: test.SvcId, Action: DeregisteredAction},
+	}, tj.janitor.report.Resources)
+}
+
+func TestCleanupAudit(t *testing.T) {
+	tj := getTestJanitor(t)
+	defer tj.close()
+	audit := &recordingAuditLogger{}
+	tj.janitor.opts.Audit = audit
+
+	tj.mockApi.EXPECT().ListNamespaceSummaries(context.TODO()).
+		Return([]types.NamespaceSummary{{Id: aws.String(test.NsId), Name: aws.String(test.NsName)}}, nil)
+	tj.mockApi.EXPECT().GetNamespaceTags(context.TODO(), test.NsId).
+		Return(cloudmap.OwnershipTags(test.ClusterId, test.ClusterSetId), nil)
+	tj.mockApi.EXPECT().ListServiceSummaries(context.TODO(), test.NsId).
+		Return([]types.ServiceSummary{{Id: aws.String(test.SvcId), Name: aws.String(test.SvcName)}}, nil)
+	tj.mockApi.EXPECT().GetServiceTags(context.TODO(), test.SvcId).
+		Return(cloudmap.OwnershipTags(test.ClusterId, test.ClusterSetId), nil)
+	tj.mockApi.EXPECT().ListInstanceSummaries(context.TODO(), test.SvcId).
+		Return([]types.InstanceSummary{{Id: aws.String(test.EndptId1)}}, nil)
+	tj.mockApi.EXPECT().DeregisterInstances(context.TODO(), test.SvcId, []string{test.EndptId1}).
+		Return(nil)
+	tj.mockApi.EXPECT().DeleteService(context.TODO(), test.SvcId).
+		Return(errors.New("resource in use"))
+
+	assert.Error(t, tj.janitor.Cleanup(context.TODO(), test.NsName))
+	assert.Equal(t, []cloudmap.AuditRecord{
+		{
+			Action:     cloudmap.AuditActionDeregisterInstance,
+			Trigger:    "janitor",
+			Namespace:  test.NsName,
+			Service:    test.SvcName,
+			ServiceId:  test.SvcId,
+			InstanceId: test.EndptId1,
+		},
+		{
+			Action:    cloudmap.AuditActionDeleteService,
+			Trigger:   "janitor",
+			Namespace: test.NsName,
+			Service:   test.SvcName,
+			ServiceId: test.SvcId,
+			Error:     "resource in use",
+		},
+	}, audit.records)
+}
+
+func TestCleanupDeregisterFailure(t *testing.T) {
+	tj := getTestJanitor(t)
+	defer tj.close()
+
+	tj.mockApi.EXPECT().ListNamespaceSummaries(context.TODO()).
+		Return([]types.NamespaceSummary{{Id: aws.String(test.NsId), Name: aws.String(test.NsName)}}, nil)
+	tj.mockApi.EXPECT().GetNamespaceTags(context.TODO(), test.NsId).
+		Return(cloudmap.OwnershipTags(test.ClusterId, test.ClusterSetId), nil)
+	tj.mockApi.EXPECT().ListServiceSummaries(context.TODO(), test.NsId).
+		Return([]types.ServiceSummary{{Id: aws.String(test.SvcId), Name: aws.String(test.SvcName)}}, nil)
+	tj.mockApi.EXPECT().GetServiceTags(context.TODO(), test.SvcId).
+		Return(cloudmap.OwnershipTags(test.ClusterId, test.ClusterSetId), nil)
+	tj.mockApi.EXPECT().ListInstanceSummaries(context.TODO(), test.SvcId).
+		Return([]types.InstanceSummary{{Id: aws.String(test.EndptId1)}}, nil)
+	tj.mockApi.EXPECT().DeregisterInstances(context.TODO(), test.SvcId, []string{test.EndptId1}).
+		Return(errors.New("operation timed out"))
+
+	// neither the service with remaining instances nor the namespace are deleted
+	assert.Error(t, tj.janitor.Cleanup(context.TODO(), test.NsName))
+}
+
+func TestCleanupAggregatesErrors(t *testing.T) {
+	tj := getTestJanitor(t)
+	defer tj.close()
+	tj.janitor.opts.Concurrency = 2
+	svcId2 := test.SvcId + "-2"
+
+	tj.mockApi.EXPECT().ListNamespaceSummaries(context.TODO()).
+		Return([]types.NamespaceSummary{{Id: aws.String(test.NsId), Name: aws.String(test.NsName)}}, nil)
+	tj.mockApi.EXPECT().GetNamespaceTags(context.TODO(), test.NsId).
+		Return(cloudmap.OwnershipTags(test.ClusterId, test.ClusterSetId), nil)
+	tj.mockApi.EXPECT().ListServiceSummaries(context.TODO(), test.NsId).
+		Return([]types.ServiceSummary{
+			{Id: aws.String(test.SvcId), Name: aws.String(test.SvcName)},
+			{Id: aws.String(svcId2), Name: aws.String(test.SvcName + "-2")},
+		}, nil)
+	tj.mockApi.EXPECT().GetServiceTags(context.TODO(), test.SvcId).
+		Return(nil, errors.New("throttled"))
+	tj.mockApi.EXPECT().GetServiceTags(context.TODO(), svcId2).
+		Return(cloudmap.OwnershipTags(test.ClusterId, test.ClusterSetId), nil)
+	tj.mockApi.EXPECT().ListInstanceSummaries(context.TODO(), svcId2).
+		Return([]types.InstanceSummary{}, nil)
+	tj.mockApi.EXPECT().DeleteService(context.TODO(), svcId2).
+		Return(nil)
+
+	// the failure of one service doesn't stop the cleanup of the other, but keeps the namespace
+	assert.Error(t, tj.janitor.Cleanup(context.TODO(), test.NsName))
+	assert.Len(t, tj.janitor.report.Failures, 1)
+	assert.Contains(t, tj.janitor.report.Failures[0], "throttled")
+}
+
+func TestCleanupDrainCluster(t *testing.T) {
+	tj := getTestJanitor(t)
+	defer tj.close()
+	tj.janitor.opts.DrainClusterId = test.ClusterId
+
+	tj.mockApi.EXPECT().ListNamespaceSummaries(context.TODO()).
+		Return([]types.NamespaceSummary{{Id: aws.String(test.NsId), Name: aws.String(test.NsName)}}, nil)
+	tj.mockApi.EXPECT().ListServiceSummaries(context.TODO(), test.NsId).
+		Return([]types.ServiceSummary{{Id: aws.String(test.SvcId), Name: aws.String(test.SvcName)}}, nil)
+	tj.mockApi.EXPECT().ListInstanceSummaries(context.TODO(), test.SvcId).
+		Return([]types.InstanceSummary{
+			{Id: aws.String(test.EndptId1), Attributes: map[string]string{controllers.ClusterIdAttr: test.ClusterId}},
+			{Id: aws.String(test.EndptId2), Attributes: map[string]string{controllers.ClusterIdAttr: "other-cluster"}},
+		}, nil)
+	tj.mockApi.EXPECT().DeregisterInstances(context.TODO(), test.SvcId, []string{test.EndptId1}).
+		Return(nil)
+
+	// the instances of other clusters, the service and the namespace are kept
+	assert.NoError(t, tj.janitor.Cleanup(context.TODO(), test.NsName))
+}
+
+func TestParallelize(t *testing.T) {
+	j := &cloudMapJanitor{opts: Options{Concurrency: 3}}
+
+	err := j.parallelize(context.TODO(), 5, func(i int) error {
+		if i%2 == 0 {
+			return fmt.Errorf("error %d", i)
+		}
+		return nil
+	})
+	assert.Error(t, err)
+	assert.Len(t, err.(utilerrors.Aggregate).Errors(), 3)
+
+	assert.NoError(t, j.parallelize(context.TODO(), 0, nil))
+}
+
+func TestCleanupUntaggedNamespace(t *testing.T) {
+	tj := getTestJanitor(t)
+	defer tj.close()
+
+	tj.mockApi.EXPECT().ListNamespaceSummaries(context.TODO()).
+		Return([]types.NamespaceSummary{{Id: aws.String(test.NsId), Name: aws.String(test.NsName)}}, nil)
+	tj.mockApi.EXPECT().GetNamespaceTags(context.TODO(), test.NsId).
+		Return(map[string]string{}, nil)
+
+	assert.NoError(t, tj.janitor.Cleanup(context.TODO(), test.NsName))
+}
+
+func TestCleanupUntaggedService(t *testing.T) {
+	tj := getTestJanitor(t)
+	defer tj.close()
+
+	tj.mockApi.EXPECT().ListNamespaceSummaries(context.TODO()).
+		Return([]types.NamespaceSummary{{Id: aws.String(test.NsId), Name: aws.String(test.NsName)}}, nil)
+	tj.mockApi.EXPECT().GetNamespaceTags(context.TODO(), test.NsId).
+		Return(cloudmap.OwnershipTags(test.ClusterId, test.ClusterSetId), nil)
+	tj.mockApi.EXPECT().ListServiceSummaries(context.TODO(), test.NsId).
+		Return([]types.ServiceSummary{{Id: aws.String(test.SvcId), Name: aws.String(test.SvcName)}}, nil)
+	tj.mockApi.EXPECT().GetServiceTags(context.TODO(), test.SvcId).
+		Return(map[string]string{"owner": "ecs"}, nil)
+
+	// neither the untagged service nor the namespace containing it are deleted
+	assert.NoError(t, tj.janitor.Cleanup(context.TODO(), test.NsName))
+}
+
+func TestCleanupForceUntagged(t *testing.T) {
+	tj := getTestJanitor(t)
+	defer tj.close()
+	tj.janitor.opts.ForceUntagged = true
+
+	tj.mockApi.EXPECT().ListNamespaceSummaries(context.TODO()).
+		Return([]types.NamespaceSummary{{Id: aws.String(test.NsId), Name: aws.String(test.NsName)}}, nil)
+	tj.mockApi.EXPECT().GetNamespaceTags(context.TODO(), test.NsId).
+		Return(map[string]string{}, nil)
+	tj.mockApi.EXPECT().ListServiceSummaries(context.TODO(), test.NsId).
+		Return([]types.ServiceSummary{}, nil)
+	tj.mockApi.EXPECT().DeleteNamespace(context.TODO(), test.NsId).
+		Return(test.OpId2, nil)
+	tj.mockApi.EXPECT().PollNamespaceOperation(context.TODO(), test.OpId2).
+		Return(test.NsId, nil)
+
+	assert.NoError(t, tj.janitor.Cleanup(context.TODO(), test.NsName))
+}
+
+func TestCleanupDryRun(t *testing.T) {
+	tj := getTestJanitor(t)
+	defer tj.close()
+	tj.janitor.opts.DryRun = true
+
+	tj.mockApi.EXPECT().ListNamespaceSummaries(context.TODO()).
+		Return([]types.NamespaceSummary{{Id: aws.String(test.NsId), Name: aws.String(test.NsName)}}, nil)
+	tj.mockApi.EXPECT().GetNamespaceTags(context.TODO(), test.NsId).
+		Return(cloudmap.OwnershipTags(test.ClusterId, test.ClusterSetId), nil)
+	tj.mockApi.EXPECT().ListServiceSummaries(context.TODO(), test.NsId).
+		Return([]types.ServiceSummary{{Id: aws.String(test.SvcId), Name: aws.String(test.SvcName)}}, nil)
+	tj.mockApi.EXPECT().GetServiceTags(context.TODO(), test.SvcId).
+		Return(cloudmap.OwnershipTags(test.ClusterId, test.ClusterSetId), nil)
+	tj.mockApi.EXPECT().ListInstanceSummaries(context.TODO(), test.SvcId).
+		Return([]types.InstanceSummary{{Id: aws.String(test.EndptId1)}}, nil)
+
+	// no deregister or delete calls are expected
+	assert.NoError(t, tj.janitor.Cleanup(context.TODO(), test.NsName))
+}
+
+func TestCleanupMatching(t *testing.T) {
+	tj := getTestJanitor(t)
+	defer tj.close()
+	tj.janitor.opts.DryRun = true
+
+	tj.mockApi.EXPECT().ListNamespaceSummaries(context.TODO()).
+		Return([]types.NamespaceSummary{
+			{Id: aws.String(test.NsId), Name: aws.String(test.NsName)},
+			{Id: aws.String("ns-prod"), Name: aws.String("prod")},
+		}, nil)
+	tj.mockApi.EXPECT().GetNamespaceTags(context.TODO(), test.NsId).
+		Return(cloudmap.OwnershipTags(test.ClusterId, test.ClusterSetId), nil)
+	tj.mockApi.EXPECT().ListServiceSummaries(context.TODO(), test.NsId).
+		Return([]types.ServiceSummary{}, nil)
+
+	// only the matching namespace is cleaned
+	matcher, err := NewGlobMatcher(test.NsName[:3] + "*")
+	assert.NoError(t, err)
+	assert.NoError(t, tj.janitor.CleanupMatching(context.TODO(), matcher))
+}
+
+func TestCleanupOlderThan(t *testing.T) {
+	tj := getTestJanitor(t)
+	defer tj.close()
+	now := time.Now()
+	tj.janitor.now = func() time.Time { return now }
+	tj.janitor.opts.OlderThan = time.Hour
+	oldDate, newDate := now.Add(-2*time.Hour), now.Add(-time.Minute)
+
+	tj.mockApi.EXPECT().ListNamespaceSummaries(context.TODO()).
+		Return([]types.NamespaceSummary{
+			{Id: aws.String(test.NsId), Name: aws.String(test.NsName), CreateDate: &oldDate},
+		}, nil)
+	tj.mockApi.EXPECT().GetNamespaceTags(context.TODO(), test.NsId).
+		Return(cloudmap.OwnershipTags(test.ClusterId, test.ClusterSetId), nil)
+	tj.mockApi.EXPECT().ListServiceSummaries(context.TODO(), test.NsId).
+		Return([]types.ServiceSummary{
+			{Id: aws.String(test.SvcId), Name: aws.String(test.SvcName), CreateDate: &newDate},
+		}, nil)
+	tj.mockApi.EXPECT().GetServiceTags(context.TODO(), test.SvcId).
+		Return(cloudmap.OwnershipTags(test.ClusterId, test.ClusterSetId), nil)
+
+	// neither the recent service nor the namespace containing it are deleted
+	assert.NoError(t, tj.janitor.Cleanup(context.TODO(), test.NsName))
+}
+
+func TestIsOldEnough(t *testing.T) {
+	now := time.Now()
+	j := &cloudMapJanitor{now: func() time.Time { return now }}
+	created := now.Add(-time.Hour)
+
+	assert.True(t, j.isOldEnough(nil), "no age filter")
+	j.opts.OlderThan = 30 * time.Minute
+	assert.True(t, j.isOldEnough(&created))
+	assert.False(t, j.isOldEnough(nil), "unknown age")
+	j.opts.OlderThan = 2 * time.Hour
+	assert.False(t, j.isOldEnough(&created))
+}
+
+func TestAge(t *testing.T) {
+	now := time.Date(2021, 8, 1, 12, 0, 0, 0, time.UTC)
+	j := &cloudMapJanitor{now: func() time.Time { return now }}
+
+	created := now.Add(-90 * time.Minute)
+	assert.Equal(t, "1h30m0s", j.age(&created))
+	assert.Equal(t, "unknown", j.age(nil))
+}
+
+func TestCleanupNothingToClean(t *testing.T) {
+	tj := getTestJanitor(t)
+	defer tj.close()
+
+	tj.mockApi.EXPECT().ListNamespaceSummaries(context.TODO()).
+		Return([]types.NamespaceSummary{}, nil)
+
+	assert.NoError(t, tj.janitor.Cleanup(context.TODO(), test.NsName))
+}
+
+// recordingAuditLogger keeps the audit records along with the trigger of their context.
+type recordingAuditLogger struct {
+	mu      sync.Mutex
+	records []cloudmap.AuditRecord
+}
+
+func (a *recordingAuditLogger) Record(ctx context.Context, record cloudmap.AuditRecord) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	record.Trigger = cloudmap.AuditTriggerFromContext(ctx)
+	a.records = append(a.records, record)
+}
+
+func getTestJanitor(t *testing.T) *testJanitor {
+	mockController := gomock.NewController(t)
+	api := janitor.NewMockServiceDiscoveryJanitorApi(mockController)
+	return &testJanitor{
+		janitor: &cloudMapJanitor{
+			log:    common.NewLoggerWithLogr(testingLogger.TestLogger{T: t}),
+			sdApi:  api,
+			opts:   Options{Concurrency: 1},
+			report: NewReport(false),
+			now:    time.Now,
+		},
+		mockApi: api,
+		close:   func() { mockController.Finish() },
+	}
+}
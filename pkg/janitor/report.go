@@ -0,0 +1,139 @@
+package janitor
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	utilerrors "k8s.io/apimachinery/pkg/util/errors"
+	"os"
+	"sigs.k8s.io/yaml"
+	"sort"
+	"sync"
+)
+
+const (
+	// JsonReportFormat writes the janitor report as JSON
+	JsonReportFormat = "json"
+	// YamlReportFormat writes the janitor report as YAML
+	YamlReportFormat = "yaml"
+)
+
+// Resource types of the janitor report
+const (
+	NamespaceResource = "namespace"
+	ServiceResource   = "service"
+	InstanceResource  = "instance"
+)
+
+// Actions taken by the janitor on a resource
+const (
+	DeletedAction         = "deleted"
+	DeregisteredAction    = "deregistered"
+	WouldDeleteAction     = "would-delete"
+	WouldDeregisterAction = "would-deregister"
+	SkippedAction         = "skipped"
+	FailedAction          = "failed"
+)
+
+// Report lists the Cloud Map resources discovered by the janitor, the actions taken and the failures. A report without
+// failures is clean.
+type Report struct {
+	DryRun    bool             `json:"dryRun"`
+	Resources []ResourceReport `json:"resources"`
+	Failures  []string         `json:"failures,omitempty"`
+
+	mutex sync.Mutex
+}
+
+// ResourceReport is the outcome of the cleanup of a single Cloud Map resource.
+type ResourceReport struct {
+	Type string `json:"type"`
+	Id   string `json:"id"`
+	Name string `json:"name,omitempty"`
+	// ParentId is the ID of the namespace of a service, or of the service of an instance
+	ParentId string `json:"parentId,omitempty"`
+	Age      string `json:"age,omitempty"`
+	Action   string `json:"action"`
+	// Reason explains skipped resources and failures
+	Reason string `json:"reason,omitempty"`
+}
+
+func (r ResourceReport) withAction(action string, reason string) ResourceReport {
+	r.Action = action
+	r.Reason = reason
+	return r
+}
+
+// NewReport returns an empty report.
+func NewReport(dryRun bool) *Report {
+	return &Report{DryRun: dryRun, Resources: []ResourceReport{}}
+}
+
+// IsClean returns true if the report has no failures.
+func (r *Report) IsClean() bool {
+	return r == nil || len(r.Failures) == 0
+}
+
+func (r *Report) add(resource ResourceReport) {
+	if r == nil {
+		return
+	}
+
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	r.Resources = append(r.Resources, resource)
+}
+
+func (r *Report) addFailure(err error) {
+	if r == nil || err == nil {
+		return
+	}
+
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	if agg, ok := err.(utilerrors.Aggregate); ok {
+		for _, e := range utilerrors.Flatten(agg).Errors() {
+			r.Failures = append(r.Failures, e.Error())
+		}
+		return
+	}
+	r.Failures = append(r.Failures, err.Error())
+}
+
+// Marshal returns the report in the given format. Resources are sorted by type and ID, independent of the order of
+// the parallel cleanup.
+func (r *Report) Marshal(format string) ([]byte, error) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	sort.SliceStable(r.Resources, func(i, j int) bool {
+		if r.Resources[i].Type != r.Resources[j].Type {
+			return r.Resources[i].Type < r.Resources[j].Type
+		}
+		return r.Resources[i].Id < r.Resources[j].Id
+	})
+
+	switch format {
+	case JsonReportFormat:
+		return json.MarshalIndent(r, "", "  ")
+	case YamlReportFormat:
+		return yaml.Marshal(r)
+	default:
+		return nil, fmt.Errorf("unsupported report format %s, expected %s or %s", format, JsonReportFormat,
+			YamlReportFormat)
+	}
+}
+
+// Write writes the report in the given format to a file, or to stdout if the path is "-".
+func (r *Report) Write(path string, format string) error {
+	data, err := r.Marshal(format)
+	if err != nil {
+		return err
+	}
+
+	if path == "-" {
+		_, err = os.Stdout.Write(data)
+		return err
+	}
+	return ioutil.WriteFile(path, data, 0644)
+}
@@ -0,0 +1,49 @@
+package janitor
+
+import (
+	"errors"
+	"github.com/stretchr/testify/assert"
+	utilerrors "k8s.io/apimachinery/pkg/util/errors"
+	"testing"
+)
+
+func TestReport_Marshal(t *testing.T) {
+	report := NewReport(true)
+	report.add(ResourceReport{Type: ServiceResource, Id: "srv-2", ParentId: "ns-1", Action: WouldDeleteAction})
+	report.add(ResourceReport{Type: NamespaceResource, Id: "ns-1", Name: "e2e", Action: WouldDeleteAction})
+
+	data, err := report.Marshal(YamlReportFormat)
+	assert.NoError(t, err)
+	assert.Equal(t, `dryRun: true
+resources:
+- action: would-delete
+  id: ns-1
+  name: e2e
+  type: namespace
+- action: would-delete
+  id: srv-2
+  parentId: ns-1
+  type: service
+`, string(data))
+
+	data, err = report.Marshal(JsonReportFormat)
+	assert.NoError(t, err)
+	assert.Contains(t, string(data), `"dryRun": true`)
+	assert.NotContains(t, string(data), "failures", "no failures in a clean report")
+
+	_, err = report.Marshal("xml")
+	assert.Error(t, err)
+}
+
+func TestReport_AddFailure(t *testing.T) {
+	report := NewReport(false)
+	report.addFailure(nil)
+	assert.True(t, report.IsClean())
+
+	report.addFailure(utilerrors.NewAggregate([]error{
+		errors.New("first"),
+		utilerrors.NewAggregate([]error{errors.New("second"), errors.New("third")}),
+	}))
+	assert.False(t, report.IsClean())
+	assert.Equal(t, []string{"first", "second", "third"}, report.Failures)
+}
@@ -0,0 +1,293 @@
+package loadtest
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/aws/aws-cloud-map-mcs-controller-for-k8s/pkg/api/v1alpha1"
+	"github.com/aws/aws-cloud-map-mcs-controller-for-k8s/pkg/cloudmap"
+	v1 "k8s.io/api/core/v1"
+	discovery "k8s.io/api/discovery/v1beta1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/intstr"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sort"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+const (
+	// LoadTestLabel marks the resources created by a load test, so they can be cleaned up
+	LoadTestLabel = "multicluster.k8s.aws/load-test"
+
+	// endpointsPerSlice is the number of endpoints of each EndpointSlice, the default of the EndpointSlice controller
+	endpointsPerSlice = 100
+	// maxEndpoints is the number of synthetic addresses, from the 198.18.0.0/15 benchmarking range
+	maxEndpoints = 1 << 17
+
+	servicePort  = 80
+	endpointPort = 8080
+	portName     = "http"
+)
+
+// Options configure a load test.
+type Options struct {
+	// Namespace is the Kubernetes namespace the synthetic services are created in
+	Namespace string
+	// Services is the number of ServiceExports to create
+	Services int
+	// EndpointsPerService is the number of endpoints of each service
+	EndpointsPerService int
+	// PollInterval is the interval Cloud Map is polled for the registered endpoints
+	PollInterval time.Duration
+	// Timeout is the time the services may take to be registered
+	Timeout time.Duration
+}
+
+// Validate checks the number of services and endpoints is positive and within the synthetic address range.
+func (o *Options) Validate() error {
+	if o.Namespace == "" {
+		return errors.New("namespace is required")
+	}
+	if o.Services <= 0 || o.EndpointsPerService <= 0 {
+		return errors.New("the number of services and endpoints per service must be positive")
+	}
+	if o.Services*o.EndpointsPerService > maxEndpoints {
+		return fmt.Errorf("at most %d endpoints are supported in total", maxEndpoints)
+	}
+	if o.PollInterval <= 0 || o.Timeout <= 0 {
+		return errors.New("poll interval and timeout must be positive")
+	}
+	return nil
+}
+
+// Result reports the propagation latency of the services of a load test, from the creation of their ServiceExport
+// until all their endpoints are registered in Cloud Map.
+type Result struct {
+	Services  int
+	Endpoints int
+	// Latencies are the latencies of the services registered within the timeout, in ascending order
+	Latencies []time.Duration
+	// TimedOut is the number of services which were not fully registered within the timeout
+	TimedOut int
+}
+
+// Percentile returns the latency below which the given percentage of the registered services propagated.
+func (r *Result) Percentile(percent float64) time.Duration {
+	if len(r.Latencies) == 0 {
+		return 0
+	}
+	index := int(float64(len(r.Latencies))*percent/100+0.5) - 1
+	if index < 0 {
+		index = 0
+	}
+	if index >= len(r.Latencies) {
+		index = len(r.Latencies) - 1
+	}
+	return r.Latencies[index]
+}
+
+// ServiceName returns the name of the i-th synthetic service.
+func ServiceName(i int) string {
+	return fmt.Sprintf("load-test-%d", i)
+}
+
+// Create creates the synthetic Services, their EndpointSlices and ServiceExports, and returns the creation time of
+// each ServiceExport by service name.
+func Create(ctx context.Context, c client.Client, opts Options) (map[string]time.Time, error) {
+	created := make(map[string]time.Time, opts.Services)
+	for i := 0; i < opts.Services; i++ {
+		svc := newService(opts.Namespace, ServiceName(i))
+		if err := c.Create(ctx, svc); err != nil {
+			return created, fmt.Errorf("unable to create service %s: %w", svc.Name, err)
+		}
+		for _, slice := range newEndpointSlices(svc, i*opts.EndpointsPerService, opts.EndpointsPerService) {
+			if err := c.Create(ctx, slice); err != nil {
+				return created, fmt.Errorf("unable to create endpoint slice %s: %w", slice.Name, err)
+			}
+		}
+
+		serviceExport := &v1alpha1.ServiceExport{ObjectMeta: loadTestMeta(opts.Namespace, svc.Name)}
+		if err := c.Create(ctx, serviceExport); err != nil {
+			return created, fmt.Errorf("unable to create service export %s: %w", svc.Name, err)
+		}
+		created[svc.Name] = time.Now()
+	}
+	return created, nil
+}
+
+// Await polls Cloud Map until all endpoints of the created services are registered, or the timeout expires.
+func Await(ctx context.Context, sdClient cloudmap.ServiceDiscoveryClient, cmNamespace string, created map[string]time.Time, opts Options) (*Result, error) {
+	result := &Result{
+		Services:  len(created),
+		Endpoints: len(created) * opts.EndpointsPerService,
+		Latencies: make([]time.Duration, 0, len(created)),
+	}
+	pending := make(map[string]time.Time, len(created))
+	for name, start := range created {
+		pending[name] = start
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, opts.Timeout)
+	defer cancel()
+	ticker := time.NewTicker(opts.PollInterval)
+	defer ticker.Stop()
+	for len(pending) > 0 {
+		for name, start := range pending {
+			svc, err := sdClient.GetService(ctx, cmNamespace, name)
+			if err != nil {
+				if ctx.Err() != nil {
+					break
+				}
+				return nil, err
+			}
+			if svc != nil && len(svc.Endpoints) >= opts.EndpointsPerService {
+				result.Latencies = append(result.Latencies, time.Since(start))
+				delete(pending, name)
+			}
+		}
+
+		select {
+		case <-ctx.Done():
+			result.TimedOut = len(pending)
+			pending = nil
+		case <-ticker.C:
+		}
+	}
+
+	sort.Slice(result.Latencies, func(i, j int) bool {
+		return result.Latencies[i] < result.Latencies[j]
+	})
+	return result, nil
+}
+
+// Cleanup deletes the ServiceExports, Services and EndpointSlices created by load tests in the namespace. The
+// controller de-registers the endpoints of the deleted ServiceExports.
+func Cleanup(ctx context.Context, c client.Client, namespace string) error {
+	selector := client.MatchingLabels{LoadTestLabel: "true"}
+	if err := c.DeleteAllOf(ctx, &v1alpha1.ServiceExport{}, client.InNamespace(namespace), selector); err != nil {
+		return fmt.Errorf("unable to delete service exports: %w", err)
+	}
+
+	// services can't be deleted by collection
+	services := v1.ServiceList{}
+	if err := c.List(ctx, &services, client.InNamespace(namespace), selector); err != nil {
+		return fmt.Errorf("unable to list services: %w", err)
+	}
+	for i := range services.Items {
+		if err := c.Delete(ctx, &services.Items[i]); client.IgnoreNotFound(err) != nil {
+			return fmt.Errorf("unable to delete service %s: %w", services.Items[i].Name, err)
+		}
+	}
+
+	if err := c.DeleteAllOf(ctx, &discovery.EndpointSlice{}, client.InNamespace(namespace), selector); err != nil {
+		return fmt.Errorf("unable to delete endpoint slices: %w", err)
+	}
+	return nil
+}
+
+// ReconcileFunc reconciles the ServiceExport of a synthetic service.
+type ReconcileFunc func(ctx context.Context, name string) error
+
+// Reconcile calls reconcile for each created service with the given number of concurrent workers, like the controller
+// manager does, until all succeed or the context is done. Failed reconciles are retried after the retry interval.
+func Reconcile(ctx context.Context, created map[string]time.Time, workers int, retryInterval time.Duration, reconcile ReconcileFunc) {
+	names := make(chan string, len(created))
+	for name := range created {
+		names <- name
+	}
+	remaining := int64(len(created))
+	if remaining == 0 {
+		return
+	}
+
+	var wg sync.WaitGroup
+	for i := 0; i < workers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for {
+				var name string
+				var ok bool
+				select {
+				case <-ctx.Done():
+					return
+				case name, ok = <-names:
+					if !ok {
+						return
+					}
+				}
+
+				if err := reconcile(ctx, name); err != nil {
+					select {
+					case <-ctx.Done():
+						return
+					case <-time.After(retryInterval):
+						names <- name
+					}
+					continue
+				}
+				if atomic.AddInt64(&remaining, -1) == 0 {
+					close(names)
+				}
+			}
+		}()
+	}
+	wg.Wait()
+}
+
+func loadTestMeta(namespace string, name string) metav1.ObjectMeta {
+	return metav1.ObjectMeta{
+		Namespace: namespace,
+		Name:      name,
+		Labels:    map[string]string{LoadTestLabel: "true"},
+	}
+}
+
+// newService creates a service without selector, whose endpoints are managed by the load test.
+func newService(namespace string, name string) *v1.Service {
+	return &v1.Service{
+		ObjectMeta: loadTestMeta(namespace, name),
+		Spec: v1.ServiceSpec{
+			Type: v1.ServiceTypeClusterIP,
+			Ports: []v1.ServicePort{{
+				Name:       portName,
+				Port:       servicePort,
+				TargetPort: intstr.FromInt(endpointPort),
+				Protocol:   v1.ProtocolTCP,
+			}},
+		},
+	}
+}
+
+// newEndpointSlices creates the slices of count endpoints, with the synthetic addresses from offset on.
+func newEndpointSlices(svc *v1.Service, offset int, count int) []*discovery.EndpointSlice {
+	name, port, protocol := portName, int32(endpointPort), v1.ProtocolTCP
+	ready := true
+
+	slices := make([]*discovery.EndpointSlice, 0)
+	for start := 0; start < count; start += endpointsPerSlice {
+		meta := loadTestMeta(svc.Namespace, svc.Name+"-"+strconv.Itoa(len(slices)))
+		meta.Labels[discovery.LabelServiceName] = svc.Name
+		slice := &discovery.EndpointSlice{
+			ObjectMeta:  meta,
+			AddressType: discovery.AddressTypeIPv4,
+			Ports:       []discovery.EndpointPort{{Name: &name, Port: &port, Protocol: &protocol}},
+		}
+		for i := start; i < count && i < start+endpointsPerSlice; i++ {
+			slice.Endpoints = append(slice.Endpoints, discovery.Endpoint{
+				Addresses:  []string{syntheticAddress(offset + i)},
+				Conditions: discovery.EndpointConditions{Ready: &ready},
+			})
+		}
+		slices = append(slices, slice)
+	}
+	return slices
+}
+
+// syntheticAddress returns the i-th address of the 198.18.0.0/15 benchmarking range.
+func syntheticAddress(i int) string {
+	return fmt.Sprintf("198.%d.%d.%d", 18+i>>16, i>>8&0xff, i&0xff)
+}
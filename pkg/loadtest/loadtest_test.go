@@ -0,0 +1,124 @@
+package loadtest
+
+import (
+	"context"
+	cloudmapv1alpha1 "github.com/aws/aws-cloud-map-mcs-controller-for-k8s/pkg/api/cloudmap/v1alpha1"
+	"github.com/aws/aws-cloud-map-mcs-controller-for-k8s/pkg/api/v1alpha1"
+	"github.com/aws/aws-cloud-map-mcs-controller-for-k8s/pkg/cloudmap"
+	"github.com/aws/aws-cloud-map-mcs-controller-for-k8s/pkg/cloudmap/cloudmaptest"
+	"github.com/aws/aws-cloud-map-mcs-controller-for-k8s/pkg/common"
+	"github.com/aws/aws-cloud-map-mcs-controller-for-k8s/pkg/controllers"
+	sdtypes "github.com/aws/aws-sdk-go-v2/service/servicediscovery/types"
+	testing2 "github.com/go-logr/logr/testing"
+	"github.com/stretchr/testify/assert"
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	"k8s.io/client-go/tools/record"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	"testing"
+	"time"
+)
+
+func TestOptions_Validate(t *testing.T) {
+	valid := Options{Namespace: "ns", Services: 2, EndpointsPerService: 3, PollInterval: time.Second, Timeout: time.Minute}
+	assert.NoError(t, valid.Validate())
+
+	tooMany := valid
+	tooMany.Services, tooMany.EndpointsPerService = 1000, 1000
+	assert.Error(t, tooMany.Validate())
+
+	noServices := valid
+	noServices.Services = 0
+	assert.Error(t, noServices.Validate())
+}
+
+func TestResult_Percentile(t *testing.T) {
+	result := &Result{}
+	assert.Equal(t, time.Duration(0), result.Percentile(50))
+
+	for i := 1; i <= 10; i++ {
+		result.Latencies = append(result.Latencies, time.Duration(i)*time.Second)
+	}
+	assert.Equal(t, 5*time.Second, result.Percentile(50))
+	assert.Equal(t, 9*time.Second, result.Percentile(90))
+	assert.Equal(t, 10*time.Second, result.Percentile(99))
+	assert.Equal(t, 10*time.Second, result.Percentile(100))
+}
+
+func TestSyntheticAddress(t *testing.T) {
+	assert.Equal(t, "198.18.0.0", syntheticAddress(0))
+	assert.Equal(t, "198.18.1.1", syntheticAddress(257))
+	assert.Equal(t, "198.19.255.255", syntheticAddress(maxEndpoints-1))
+}
+
+func TestNewEndpointSlices(t *testing.T) {
+	svc := newService("ns", "svc")
+	slices := newEndpointSlices(svc, 0, 250)
+	if assert.Len(t, slices, 3) {
+		assert.Len(t, slices[0].Endpoints, 100)
+		assert.Len(t, slices[2].Endpoints, 50)
+		assert.Equal(t, "svc-2", slices[2].Name)
+		assert.Equal(t, "svc", slices[2].Labels["kubernetes.io/service-name"])
+		assert.Equal(t, "198.18.0.249", slices[2].Endpoints[49].Addresses[0])
+	}
+}
+
+func TestLoadTest_Fake(t *testing.T) {
+	opts := Options{Namespace: "ns", Services: 3, EndpointsPerService: 150, PollInterval: 10 * time.Millisecond,
+		Timeout: 10 * time.Second}
+	scheme := testScheme(t)
+	c := fake.NewClientBuilder().WithScheme(scheme).Build()
+	server := cloudmaptest.NewServer(cloudmaptest.Options{})
+	server.AddNamespace("ns", sdtypes.NamespaceTypeHttp)
+	sdClient := server.NewServiceDiscoveryClient(cloudmap.SdClientConfig{Cache: &cloudmap.SdCacheConfig{}})
+	reconciler := &controllers.ServiceExportReconciler{
+		Client:   c,
+		Log:      common.NewLoggerWithLogr(testing2.TestLogger{T: t}),
+		Scheme:   scheme,
+		Registry: sdClient,
+		Recorder: &record.FakeRecorder{},
+	}
+
+	created, err := Create(context.TODO(), c, opts)
+	assert.NoError(t, err)
+	assert.Len(t, created, 3)
+
+	Reconcile(context.TODO(), created, 2, time.Millisecond, func(ctx context.Context, name string) error {
+		_, err := reconciler.Reconcile(ctx, ctrl.Request{NamespacedName: types.NamespacedName{Namespace: "ns", Name: name}})
+		return err
+	})
+	result, err := Await(context.TODO(), sdClient, "ns", created, opts)
+	assert.NoError(t, err)
+	assert.Equal(t, 3, result.Services)
+	assert.Equal(t, 450, result.Endpoints)
+	assert.Len(t, result.Latencies, 3)
+	assert.Zero(t, result.TimedOut)
+
+	assert.NoError(t, Cleanup(context.TODO(), c, "ns"))
+	services := v1.ServiceList{}
+	assert.NoError(t, c.List(context.TODO(), &services))
+	assert.Empty(t, services.Items)
+}
+
+func TestAwait_TimedOut(t *testing.T) {
+	opts := Options{Namespace: "ns", Services: 1, EndpointsPerService: 1, PollInterval: time.Millisecond,
+		Timeout: 10 * time.Millisecond}
+	created := map[string]time.Time{ServiceName(0): time.Now()}
+
+	sdClient := cloudmaptest.NewServer(cloudmaptest.Options{}).NewServiceDiscoveryClient(cloudmap.SdClientConfig{})
+	result, err := Await(context.TODO(), sdClient, "ns", created, opts)
+	assert.NoError(t, err)
+	assert.Empty(t, result.Latencies)
+	assert.Equal(t, 1, result.TimedOut)
+}
+
+func testScheme(t *testing.T) *runtime.Scheme {
+	scheme := runtime.NewScheme()
+	assert.NoError(t, clientgoscheme.AddToScheme(scheme))
+	assert.NoError(t, v1alpha1.AddToScheme(scheme))
+	assert.NoError(t, cloudmapv1alpha1.AddToScheme(scheme))
+	return scheme
+}
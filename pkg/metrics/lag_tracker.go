@@ -0,0 +1,92 @@
+package metrics
+
+import (
+	"sync"
+	"time"
+)
+
+// LagTracker remembers when a pending change was first observed for a key, so the propagation latency can be
+// reported once the change has been applied. It is safe for concurrent use. A nil LagTracker discards all calls.
+type LagTracker struct {
+	mu       sync.Mutex
+	observed map[string]time.Time
+	now      func() time.Time
+}
+
+// NewLagTracker creates a new empty lag tracker.
+func NewLagTracker() *LagTracker {
+	return &LagTracker{
+		observed: make(map[string]time.Time),
+		now:      time.Now,
+	}
+}
+
+// Observe marks a change as pending for the key. The earliest observation is kept until the change completes.
+func (t *LagTracker) Observe(key string) {
+	if t == nil {
+		return
+	}
+	t.ObserveAt(key, t.now())
+}
+
+// ObserveAt marks a change observed at the given time as pending for the key. The earliest observation is kept until
+// the change completes.
+func (t *LagTracker) ObserveAt(key string, observed time.Time) {
+	if t == nil {
+		return
+	}
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if start, pending := t.observed[key]; !pending || observed.Before(start) {
+		t.observed[key] = observed
+	}
+}
+
+// Complete clears the pending change for the key and returns the time elapsed since it was first observed.
+func (t *LagTracker) Complete(key string) (lag time.Duration, found bool) {
+	if t == nil {
+		return 0, false
+	}
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	start, found := t.observed[key]
+	if !found {
+		return 0, false
+	}
+
+	delete(t.observed, key)
+	return t.now().Sub(start), true
+}
+
+// Peek returns the time elapsed since the pending change for the key was first observed, without clearing it.
+func (t *LagTracker) Peek(key string) (lag time.Duration, found bool) {
+	if t == nil {
+		return 0, false
+	}
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	start, found := t.observed[key]
+	if !found {
+		return 0, false
+	}
+
+	return t.now().Sub(start), true
+}
+
+// Forget clears the pending change for the key without reporting it, e.g. when no change was required after all.
+func (t *LagTracker) Forget(key string) {
+	if t == nil {
+		return
+	}
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	delete(t.observed, key)
+}
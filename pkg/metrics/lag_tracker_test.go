@@ -0,0 +1,63 @@
+package metrics
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLagTracker_KeepsEarliestObservation(t *testing.T) {
+	tracker := NewLagTracker()
+	now := time.Unix(100, 0)
+	tracker.now = func() time.Time { return now }
+
+	tracker.Observe("ns/svc")
+	now = now.Add(5 * time.Second)
+	tracker.Observe("ns/svc")
+	now = now.Add(5 * time.Second)
+
+	lag, found := tracker.Peek("ns/svc")
+	assert.True(t, found)
+	assert.Equal(t, 10*time.Second, lag)
+
+	lag, found = tracker.Complete("ns/svc")
+	assert.True(t, found)
+	assert.Equal(t, 10*time.Second, lag)
+
+	_, found = tracker.Complete("ns/svc")
+	assert.False(t, found, "completed change is no longer pending")
+}
+
+func TestLagTracker_ObserveAt(t *testing.T) {
+	tracker := NewLagTracker()
+	now := time.Unix(100, 0)
+	tracker.now = func() time.Time { return now }
+
+	tracker.ObserveAt("ns/svc", now.Add(-5*time.Second))
+	tracker.ObserveAt("ns/svc", now.Add(-10*time.Second))
+	tracker.ObserveAt("ns/svc", now)
+
+	lag, found := tracker.Complete("ns/svc")
+	assert.True(t, found)
+	assert.Equal(t, 10*time.Second, lag, "lag since the earliest observed change")
+}
+
+func TestLagTracker_Forget(t *testing.T) {
+	tracker := NewLagTracker()
+
+	tracker.Observe("ns/svc")
+	tracker.Forget("ns/svc")
+
+	_, found := tracker.Complete("ns/svc")
+	assert.False(t, found)
+}
+
+func TestLagTracker_Nil(t *testing.T) {
+	var tracker *LagTracker
+
+	tracker.Observe("ns/svc")
+	tracker.Forget("ns/svc")
+	_, found := tracker.Complete("ns/svc")
+	assert.False(t, found)
+}
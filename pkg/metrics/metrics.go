@@ -0,0 +1,126 @@
+package metrics
+
+import (
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"sigs.k8s.io/controller-runtime/pkg/metrics"
+)
+
+const (
+	metricsNamespace = "cloud_map_mcs"
+
+	// ExportOperationRegister labels sync lag observed for endpoint registrations.
+	ExportOperationRegister = "register"
+	// ExportOperationDeregister labels sync lag observed for endpoint de-registrations.
+	ExportOperationDeregister = "deregister"
+
+	// SchemaSkewOlder labels instances decoded with an older attribute schema version than the controller's.
+	SchemaSkewOlder = "older"
+	// SchemaSkewNewer labels instances decoded with a newer attribute schema version than the controller's.
+	SchemaSkewNewer = "newer"
+)
+
+var (
+	syncLagBuckets = []float64{0.5, 1, 2.5, 5, 10, 20, 30, 60, 120, 300, 600}
+
+	exportSyncLag = prometheus.NewHistogramVec(
+		prometheus.HistogramOpts{
+			Namespace: metricsNamespace,
+			Subsystem: "export",
+			Name:      "sync_lag_seconds",
+			Help: "Time between observing an endpoint change in the cluster and the corresponding " +
+				"Cloud Map operations reaching SUCCESS.",
+			Buckets: syncLagBuckets,
+		},
+		[]string{"operation"},
+	)
+
+	importSyncLag = prometheus.NewHistogram(
+		prometheus.HistogramOpts{
+			Namespace: metricsNamespace,
+			Subsystem: "import",
+			Name:      "sync_lag_seconds",
+			Help: "Time between observing an endpoint change in Cloud Map and the corresponding " +
+				"EndpointSlice changes being applied in the cluster.",
+			Buckets: syncLagBuckets,
+		},
+	)
+
+	operationFailures = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Namespace: metricsNamespace,
+			Subsystem: "cloudmap",
+			Name:      "operation_failures_total",
+			Help:      "Number of Cloud Map operations that reached FAIL status.",
+		},
+		[]string{"operation_type", "error_code"},
+	)
+
+	schemaVersionSkew = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Namespace: metricsNamespace,
+			Subsystem: "cloudmap",
+			Name:      "instance_schema_skew_total",
+			Help: "Number of Cloud Map instances decoded with an attribute schema version different from the " +
+				"controller's, registered by controllers of other versions.",
+		},
+		[]string{"skew"},
+	)
+
+	importFailover = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Namespace: metricsNamespace,
+			Subsystem: "import",
+			Name:      "failover",
+			Help: "1 for the region services are looked up in while the preferred import region is unavailable, " +
+				"0 otherwise.",
+		},
+		[]string{"region"},
+	)
+
+	reconcilePhaseDuration = prometheus.NewHistogramVec(
+		prometheus.HistogramOpts{
+			Namespace: metricsNamespace,
+			Subsystem: "reconcile",
+			Name:      "phase_duration_seconds",
+			Help:      "Time spent in each phase of a reconcile.",
+			Buckets:   []float64{0.005, 0.01, 0.05, 0.1, 0.25, 0.5, 1, 2.5, 5, 10, 30, 60},
+		},
+		[]string{"controller", "phase"},
+	)
+)
+
+func init() {
+	metrics.Registry.MustRegister(exportSyncLag, importSyncLag, operationFailures, schemaVersionSkew, importFailover,
+		reconcilePhaseDuration)
+}
+
+// ObserveExportSyncLag records the propagation latency of an exported endpoint change for a given operation.
+func ObserveExportSyncLag(operation string, lag time.Duration) {
+	exportSyncLag.WithLabelValues(operation).Observe(lag.Seconds())
+}
+
+// ObserveImportSyncLag records the propagation latency of an imported endpoint change.
+func ObserveImportSyncLag(lag time.Duration) {
+	importSyncLag.Observe(lag.Seconds())
+}
+
+// IncOperationFailures counts a Cloud Map operation of the given type which failed with the given error code.
+func IncOperationFailures(operationType string, errorCode string) {
+	operationFailures.WithLabelValues(operationType, errorCode).Inc()
+}
+
+// SetImportFailover records whether services are looked up in the region as failover of the preferred import region.
+func SetImportFailover(region string, failover bool) {
+	value := 0.0
+	if failover {
+		value = 1
+	}
+	importFailover.WithLabelValues(region).Set(value)
+}
+
+// IncSchemaVersionSkew counts a Cloud Map instance decoded with an older or newer attribute schema version.
+func IncSchemaVersionSkew(skew string) {
+	schemaVersionSkew.WithLabelValues(skew).Inc()
+}
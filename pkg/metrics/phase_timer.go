@@ -0,0 +1,121 @@
+package metrics
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+const (
+	// PhaseFetchEndpoints times fetching the current endpoints from Cloud Map and the cluster.
+	PhaseFetchEndpoints = "fetch_endpoints"
+	// PhaseDiff times computing the changes between current and desired endpoints.
+	PhaseDiff = "diff"
+	// PhaseRegister times creating Cloud Map instance registration operations.
+	PhaseRegister = "register"
+	// PhaseDeregister times creating Cloud Map instance de-registration operations.
+	PhaseDeregister = "deregister"
+	// PhasePoll times polling Cloud Map operations until they complete.
+	PhasePoll = "poll"
+	// PhaseStatusUpdate times updating the status of the reconciled resource.
+	PhaseStatusUpdate = "status_update"
+	// PhaseImport times creating and updating the ServiceImport and derived Service of an imported service, and the
+	// resources published for it.
+	PhaseImport = "import"
+	// PhaseEndpointSlices times updating the EndpointSlices of an imported service.
+	PhaseEndpointSlices = "endpoint_slices"
+)
+
+type phaseTimerKey struct{}
+
+// PhaseTimer accumulates the time spent in each phase of a single reconcile. It is safe for concurrent use.
+// A nil PhaseTimer discards all calls.
+type PhaseTimer struct {
+	mu     sync.Mutex
+	start  time.Time
+	phases map[string]time.Duration
+	order  []string
+	now    func() time.Time
+}
+
+// NewPhaseTimer creates a new phase timer, the total reconcile time is measured from its creation.
+func NewPhaseTimer() *PhaseTimer {
+	return newPhaseTimer(time.Now)
+}
+
+func newPhaseTimer(now func() time.Time) *PhaseTimer {
+	return &PhaseTimer{
+		start:  now(),
+		phases: make(map[string]time.Duration),
+		now:    now,
+	}
+}
+
+// WithPhaseTimer returns a context carrying the phase timer, so phases can be timed by callees.
+func WithPhaseTimer(ctx context.Context, timer *PhaseTimer) context.Context {
+	return context.WithValue(ctx, phaseTimerKey{}, timer)
+}
+
+// PhaseTimerFromContext returns the phase timer of the context or nil if not set.
+func PhaseTimerFromContext(ctx context.Context) *PhaseTimer {
+	timer, _ := ctx.Value(phaseTimerKey{}).(*PhaseTimer)
+	return timer
+}
+
+// Start starts timing a phase, the returned function stops timing and adds the elapsed time to the phase.
+func (t *PhaseTimer) Start(phase string) (stop func()) {
+	if t == nil {
+		return func() {}
+	}
+
+	start := t.now()
+	return func() {
+		elapsed := t.now().Sub(start)
+
+		t.mu.Lock()
+		defer t.mu.Unlock()
+
+		if _, found := t.phases[phase]; !found {
+			t.order = append(t.order, phase)
+		}
+		t.phases[phase] += elapsed
+	}
+}
+
+// Total returns the time elapsed since the timer was created.
+func (t *PhaseTimer) Total() time.Duration {
+	if t == nil {
+		return 0
+	}
+	return t.now().Sub(t.start)
+}
+
+// KeysAndValues returns the phase timings as structured log fields, in the order the phases were first timed.
+func (t *PhaseTimer) KeysAndValues() []interface{} {
+	if t == nil {
+		return nil
+	}
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	keysAndValues := make([]interface{}, 0, 2*len(t.order))
+	for _, phase := range t.order {
+		keysAndValues = append(keysAndValues, phase, t.phases[phase].String())
+	}
+	return keysAndValues
+}
+
+// Observe records the timed phases of the reconcile for the controller in the phase duration histogram.
+func (t *PhaseTimer) Observe(controller string) {
+	if t == nil {
+		return
+	}
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	for phase, duration := range t.phases {
+		reconcilePhaseDuration.WithLabelValues(controller, phase).Observe(duration.Seconds())
+	}
+}
@@ -0,0 +1,45 @@
+package metrics
+
+import (
+	"context"
+	"github.com/stretchr/testify/assert"
+	"testing"
+	"time"
+)
+
+func TestPhaseTimer_AccumulatesPhases(t *testing.T) {
+	now := time.Unix(100, 0)
+	timer := newPhaseTimer(func() time.Time { return now })
+
+	stop := timer.Start(PhaseRegister)
+	now = now.Add(2 * time.Second)
+	stop()
+
+	stop = timer.Start(PhasePoll)
+	now = now.Add(3 * time.Second)
+	stop()
+
+	stop = timer.Start(PhaseRegister)
+	now = now.Add(time.Second)
+	stop()
+
+	assert.Equal(t, 6*time.Second, timer.Total())
+	assert.Equal(t, []interface{}{PhaseRegister, "3s", PhasePoll, "3s"}, timer.KeysAndValues())
+}
+
+func TestPhaseTimer_FromContext(t *testing.T) {
+	timer := NewPhaseTimer()
+	ctx := WithPhaseTimer(context.TODO(), timer)
+
+	assert.Same(t, timer, PhaseTimerFromContext(ctx))
+	assert.Nil(t, PhaseTimerFromContext(context.TODO()))
+}
+
+func TestPhaseTimer_NilSafe(t *testing.T) {
+	var timer *PhaseTimer
+
+	timer.Start(PhaseDiff)()
+	timer.Observe("test")
+	assert.Equal(t, time.Duration(0), timer.Total())
+	assert.Empty(t, timer.KeysAndValues())
+}
@@ -0,0 +1,16 @@
+package model
+
+// DnsConfigDrift describes the differences of the DNS configuration of a service in the service registry from its
+// desired configuration.
+type DnsConfigDrift struct {
+	// Corrected lists the differences which were corrected, e.g. "SRV record TTL 300 -> 60"
+	Corrected []string
+	// Uncorrectable lists the differences the service registry can't update, the service must be recreated to
+	// correct them
+	Uncorrectable []string
+}
+
+// IsEmpty returns true if the DNS configuration didn't differ from the desired configuration.
+func (d DnsConfigDrift) IsEmpty() bool {
+	return len(d.Corrected) == 0 && len(d.Uncorrectable) == 0
+}
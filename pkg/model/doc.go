@@ -0,0 +1,8 @@
+// Package model defines the Cloud Map namespaces, services and endpoints synced by the controller, independently of
+// the Kubernetes and AWS APIs, and the encoding of endpoints as Cloud Map instance attributes.
+//
+// The types are supported as a Go library along with the cloudmap package, and follow the semantic versioning of the
+// module. The instance attributes are versioned separately by SchemaVersion, so that clusters running different
+// versions of the controller, or other tooling, read each other's instances: NewEndpointFromInstance decodes instances
+// of older schema versions, and the known attributes of newer ones.
+package model
@@ -1,5 +1,6 @@
 package model
 
+// Plan compares the current instances of a service with the desired instances.
 type Plan struct {
 	// List of current instances
 	Current []*Endpoint
@@ -8,6 +9,7 @@ type Plan struct {
 	Desired []*Endpoint
 }
 
+// Changes are the instances to register and de-register to get from the current to the desired instances of a Plan.
 type Changes struct {
 	// List of endpoints that need to be created
 	Create []*Endpoint
@@ -46,14 +48,17 @@ func (p *Plan) CalculateChanges() Changes {
 	return changes
 }
 
+// HasUpdates returns whether instances need to be registered.
 func (c *Changes) HasUpdates() bool {
 	return len(c.Create) > 0 || len(c.Update) > 0
 }
 
+// HasDeletes returns whether instances need to be de-registered.
 func (c *Changes) HasDeletes() bool {
 	return len(c.Delete) > 0
 }
 
+// IsNone returns whether the current instances are the desired instances.
 func (c *Changes) IsNone() bool {
 	return len(c.Create) == 0 && len(c.Update) == 0 && len(c.Delete) == 0
 }
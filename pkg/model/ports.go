@@ -0,0 +1,106 @@
+package model
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// PortMapping is a service port and the port of the endpoint serving it.
+type PortMapping struct {
+	ServicePort  Port `json:"servicePort"`
+	EndpointPort Port `json:"endpointPort"`
+}
+
+const (
+	// separates the port mappings of the EndpointPortsAttr
+	portMappingSeparator = ","
+	// separates the fields of a port mapping, port names are DNS labels and can't contain it
+	portFieldSeparator = ":"
+	// number of fields of an encoded port mapping
+	portMappingFields = 6
+)
+
+// EncodePortMappings encodes the port mappings as value of the EndpointPortsAttr. Each mapping is encoded as
+// "<protocol>:<service port>:<service port name>:<target port>:<endpoint port>:<endpoint port name>", the service
+// and endpoint port share the protocol.
+func EncodePortMappings(mappings []PortMapping) string {
+	encoded := make([]string, 0, len(mappings))
+	for _, mapping := range mappings {
+		encoded = append(encoded, strings.Join([]string{
+			mapping.ServicePort.Protocol,
+			strconv.Itoa(int(mapping.ServicePort.Port)),
+			mapping.ServicePort.Name,
+			mapping.ServicePort.TargetPort,
+			strconv.Itoa(int(mapping.EndpointPort.Port)),
+			mapping.EndpointPort.Name,
+		}, portFieldSeparator))
+	}
+	return strings.Join(encoded, portMappingSeparator)
+}
+
+// DecodePortMappings decodes the value of the EndpointPortsAttr.
+func DecodePortMappings(value string) ([]PortMapping, error) {
+	mappings := make([]PortMapping, 0)
+	for _, encoded := range strings.Split(value, portMappingSeparator) {
+		fields := strings.Split(encoded, portFieldSeparator)
+		if len(fields) != portMappingFields {
+			return nil, fmt.Errorf("failed to parse the port mapping %q of the %s", encoded, EndpointPortsAttr)
+		}
+		servicePort, err := parsePortNumber(fields[1])
+		if err != nil {
+			return nil, err
+		}
+		endpointPort, err := parsePortNumber(fields[4])
+		if err != nil {
+			return nil, err
+		}
+		mappings = append(mappings, PortMapping{
+			ServicePort:  Port{Name: fields[2], Port: servicePort, TargetPort: fields[3], Protocol: fields[0]},
+			EndpointPort: Port{Name: fields[5], Port: endpointPort, Protocol: fields[0]},
+		})
+	}
+	return mappings, nil
+}
+
+func parsePortNumber(value string) (int32, error) {
+	port, err := strconv.ParseUint(value, 10, 16)
+	if err != nil {
+		return 0, fmt.Errorf("failed to parse the port of the %s with error %s", EndpointPortsAttr, err.Error())
+	}
+	return int32(port), nil
+}
+
+// Expand returns an endpoint for each port of a multi-port endpoint, or the endpoint itself if it has a single port.
+// The endpoint of the first port keeps the ID of the instance, the IDs of the others are suffixed with their port.
+func (e *Endpoint) Expand() []*Endpoint {
+	if len(e.AdditionalPorts) == 0 {
+		return []*Endpoint{e}
+	}
+
+	expanded := make([]*Endpoint, 0, len(e.AdditionalPorts)+1)
+	first := *e
+	first.AdditionalPorts = nil
+	expanded = append(expanded, &first)
+	for _, mapping := range e.AdditionalPorts {
+		endpoint := first
+		endpoint.Id = e.Id + "/" + mapping.EndpointPort.GetID()
+		endpoint.EndpointPort = mapping.EndpointPort
+		endpoint.ServicePort = mapping.ServicePort
+		endpoint.Attributes = make(map[string]string, len(e.Attributes))
+		for key, value := range e.Attributes {
+			endpoint.Attributes[key] = value
+		}
+		expanded = append(expanded, &endpoint)
+	}
+	return expanded
+}
+
+// ExpandEndpoints returns the endpoints with each multi-port endpoint expanded to an endpoint per port.
+func ExpandEndpoints(endpoints []*Endpoint) []*Endpoint {
+	expanded := make([]*Endpoint, 0, len(endpoints))
+	for _, endpoint := range endpoints {
+		expanded = append(expanded, endpoint.Expand()...)
+	}
+	return expanded
+}
@@ -0,0 +1,81 @@
+package model
+
+import (
+	"github.com/aws/aws-sdk-go-v2/service/servicediscovery/types"
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+var testPortMappings = []PortMapping{
+	{
+		ServicePort:  Port{Name: "https", Port: 443, TargetPort: "8443", Protocol: TCPProtocol},
+		EndpointPort: Port{Name: "https", Port: 8443, Protocol: TCPProtocol},
+	},
+	{
+		ServicePort:  Port{Port: 53, TargetPort: "53", Protocol: UDPProtocol},
+		EndpointPort: Port{Port: 53, Protocol: UDPProtocol},
+	},
+}
+
+func TestEncodePortMappings(t *testing.T) {
+	encoded := EncodePortMappings(testPortMappings)
+	assert.Equal(t, "TCP:443:https:8443:8443:https,UDP:53::53:53:", encoded)
+
+	decoded, err := DecodePortMappings(encoded)
+	assert.Nil(t, err)
+	assert.Equal(t, testPortMappings, decoded)
+}
+
+func TestDecodePortMappings_Invalid(t *testing.T) {
+	for _, value := range []string{"", "TCP:443:https:8443:8443", "TCP:x:https:8443:8443:https", "TCP:443:https:8443:70000:https"} {
+		_, err := DecodePortMappings(value)
+		assert.Error(t, err, value)
+	}
+}
+
+func TestEndpoint_MultiPortRoundTrip(t *testing.T) {
+	e := &Endpoint{
+		Id:              instId,
+		IP:              ip,
+		EndpointPort:    Port{Name: "http", Port: 80, Protocol: TCPProtocol},
+		ServicePort:     Port{Name: "http", Port: 30, TargetPort: "80", Protocol: TCPProtocol},
+		AdditionalPorts: testPortMappings,
+		Attributes:      map[string]string{},
+	}
+	decoded, err := NewEndpointFromInstance(&types.HttpInstanceSummary{InstanceId: &instId,
+		Attributes: e.GetCloudMapAttributes()})
+	assert.Nil(t, err)
+	assert.Equal(t, e, decoded)
+
+	invalid := e.GetCloudMapAttributes()
+	invalid[EndpointPortsAttr] = "invalid"
+	_, err = NewEndpointFromInstance(&types.HttpInstanceSummary{InstanceId: &instId, Attributes: invalid})
+	assert.Error(t, err)
+}
+
+func TestEndpoint_Expand(t *testing.T) {
+	e := &Endpoint{
+		Id:           instId,
+		IP:           ip,
+		EndpointPort: Port{Name: "http", Port: 80, Protocol: TCPProtocol},
+		ServicePort:  Port{Name: "http", Port: 30, TargetPort: "80", Protocol: TCPProtocol},
+		Attributes:   map[string]string{"custom-attr": "custom-val"},
+	}
+	assert.Equal(t, []*Endpoint{e}, e.Expand(), "single-port endpoints aren't expanded")
+
+	e.AdditionalPorts = testPortMappings
+	expanded := ExpandEndpoints([]*Endpoint{e})
+	assert.Len(t, expanded, 3)
+	assert.Equal(t, instId, expanded[0].Id)
+	assert.Equal(t, instId+"/TCP:8443", expanded[1].Id)
+	assert.Equal(t, instId+"/UDP:53", expanded[2].Id)
+	for i, mapping := range testPortMappings {
+		assert.Equal(t, mapping.EndpointPort, expanded[i+1].EndpointPort)
+		assert.Equal(t, mapping.ServicePort, expanded[i+1].ServicePort)
+	}
+	for _, endpoint := range expanded {
+		assert.Equal(t, ip, endpoint.IP)
+		assert.Empty(t, endpoint.AdditionalPorts)
+		assert.Equal(t, e.Attributes, endpoint.Attributes)
+	}
+}
@@ -0,0 +1,26 @@
+package model
+
+import (
+	"reflect"
+)
+
+// ServiceMetadata holds the description and the additional tags of a service in the service registry.
+type ServiceMetadata struct {
+	// Description is the description of the service, shown in the AWS console
+	Description string
+	// Tags are added to the tags of the service, the ownership tags take precedence
+	Tags map[string]string
+}
+
+// IsEmpty returns true if neither description nor tags are set.
+func (m ServiceMetadata) IsEmpty() bool {
+	return m.Description == "" && len(m.Tags) == 0
+}
+
+// Equals returns true if both metadata have the same description and tags.
+func (m ServiceMetadata) Equals(other ServiceMetadata) bool {
+	if len(m.Tags) == 0 && len(other.Tags) == 0 {
+		return m.Description == other.Description
+	}
+	return m.Description == other.Description && reflect.DeepEqual(m.Tags, other.Tags)
+}
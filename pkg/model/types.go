@@ -1,6 +1,8 @@
 package model
 
 import (
+	"crypto/sha256"
+	"encoding/hex"
 	"encoding/json"
 	"fmt"
 	"github.com/aws/aws-sdk-go-v2/service/servicediscovery/types"
@@ -22,36 +24,53 @@ const (
 	UnsupportedNamespaceType NamespaceType = ""
 )
 
+// NamespaceType is the type of a Cloud Map namespace, only HTTP and DNS private namespaces are supported.
 type NamespaceType string
 
+// Number of hex digits of the cluster ID hash in endpoint IDs
+const clusterHashLength = 8
+
 // Namespace hold namespace attributes
 type Namespace struct {
-	Id   string
-	Name string
-	Type NamespaceType
+	Id   string        `json:"id"`
+	Name string        `json:"name"`
+	Type NamespaceType `json:"type"`
 }
 
 // Service holds namespace and endpoint state for a named service.
 type Service struct {
-	Namespace string
-	Name      string
-	Endpoints []*Endpoint
+	Id        string      `json:"id"`
+	Namespace string      `json:"namespace"`
+	Name      string      `json:"name"`
+	Endpoints []*Endpoint `json:"endpoints"`
 }
 
 // Endpoint holds basic values and attributes for an endpoint.
 type Endpoint struct {
-	Id           string
-	IP           string
-	EndpointPort Port
-	ServicePort  Port
-	Attributes   map[string]string
+	Id           string `json:"id"`
+	IP           string `json:"ip"`
+	EndpointPort Port   `json:"endpointPort"`
+	ServicePort  Port   `json:"servicePort"`
+	// Ready is the readiness of the endpoint, nil if unknown, which is interpreted as ready
+	Ready *bool `json:"ready,omitempty"`
+	// Hostname is the hostname of the endpoint, e.g. the pod hostname of a headless service
+	Hostname string `json:"hostname,omitempty"`
+	// Nodename is the name of the node hosting the endpoint
+	Nodename string `json:"nodename,omitempty"`
+	// Zone is the topology zone of the endpoint
+	Zone string `json:"zone,omitempty"`
+	// AdditionalPorts are the ports of a multi-port endpoint besides EndpointPort and ServicePort, which are
+	// registered as a single instance
+	AdditionalPorts []PortMapping     `json:"additionalPorts,omitempty"`
+	Attributes      map[string]string `json:"attributes"`
 }
 
+// Port is a port of a service or endpoint.
 type Port struct {
-	Name       string
-	Port       int32
-	TargetPort string
-	Protocol   string // TCP, UDP, SCTP
+	Name       string `json:"name,omitempty"`
+	Port       int32  `json:"port"`
+	TargetPort string `json:"targetPort,omitempty"`
+	Protocol   string `json:"protocol"` // TCP, UDP, SCTP
 }
 
 // Cloudmap Instances IP and Port is supposed to be AWS_INSTANCE_IPV4 and AWS_INSTANCE_PORT
@@ -65,12 +84,40 @@ const (
 	ServicePortAttr       = "SERVICE_PORT"
 	ServiceTargetPortAttr = "SERVICE_TARGET_PORT"
 	ServiceProtocolAttr   = "SERVICE_PROTOCOL"
+	EndpointReadyAttr     = "READY"
+	EndpointHostnameAttr  = "HOSTNAME"
+	EndpointNodenameAttr  = "NODENAME"
+	EndpointZoneAttr      = "AVAILABILITY_ZONE"
+	EndpointPortsAttr     = "ENDPOINT_PORTS"
+	SchemaVersionAttr     = "SCHEMA_VERSION"
 	TCPProtocol           = "TCP"
 	UDPProtocol           = "UDP"
 	SCTPProtocol          = "SCTP"
 )
 
-// NewEndpointFromInstance converts a Cloud Map HttpInstanceSummary to an endpoint.
+// Versions of the instance attribute schema. Controllers of different versions register instances of the same service
+// while clusters are upgraded, so the decoder accepts instances of older and newer schema versions.
+const (
+	// LegacySchemaVersion is the version of instances registered without a schema version attribute
+	LegacySchemaVersion = 1
+	// SchemaVersion is the version of the instances registered by this controller. Version 2 adds the readiness,
+	// hostname, nodename and zone attributes, version 3 the additional ports of multi-port instances.
+	SchemaVersion = 3
+)
+
+// InstanceSchemaVersion returns the schema version of the instance attributes. Instances without a valid schema version
+// attribute are of the LegacySchemaVersion.
+func InstanceSchemaVersion(attributes map[string]string) int {
+	version, err := strconv.Atoi(attributes[SchemaVersionAttr])
+	if err != nil || version < LegacySchemaVersion {
+		return LegacySchemaVersion
+	}
+	return version
+}
+
+// NewEndpointFromInstance converts a Cloud Map HttpInstanceSummary to an endpoint. Instances of a newer schema version
+// are decoded leniently: invalid optional attributes are ignored, and unknown attributes are kept as custom
+// attributes.
 func NewEndpointFromInstance(inst *types.HttpInstanceSummary) (endpointPtr *Endpoint, err error) {
 	endpoint := Endpoint{
 		Id:         *inst.InstanceId,
@@ -80,6 +127,8 @@ func NewEndpointFromInstance(inst *types.HttpInstanceSummary) (endpointPtr *Endp
 	for key, value := range inst.Attributes {
 		attributes[key] = value
 	}
+	newerSchema := InstanceSchemaVersion(attributes) > SchemaVersion
+	delete(attributes, SchemaVersionAttr)
 
 	// Remove and set the IP, Port, Port
 	if endpoint.IP, err = removeStringAttr(attributes, EndpointIpv4Attr); err != nil {
@@ -94,6 +143,31 @@ func NewEndpointFromInstance(inst *types.HttpInstanceSummary) (endpointPtr *Endp
 		return nil, err
 	}
 
+	// the optional attributes are missing on instances registered by older controllers
+	if ready, hasReady := attributes[EndpointReadyAttr]; hasReady {
+		parsed, parseErr := strconv.ParseBool(ready)
+		switch {
+		case parseErr == nil:
+			endpoint.Ready = &parsed
+		case !newerSchema:
+			return nil, fmt.Errorf("failed to parse the %s as bool with error %s", EndpointReadyAttr, parseErr.Error())
+		}
+		delete(attributes, EndpointReadyAttr)
+	}
+	endpoint.Hostname, _ = removeStringAttr(attributes, EndpointHostnameAttr)
+	endpoint.Nodename, _ = removeStringAttr(attributes, EndpointNodenameAttr)
+	endpoint.Zone, _ = removeStringAttr(attributes, EndpointZoneAttr)
+	if ports, hasPorts := attributes[EndpointPortsAttr]; hasPorts {
+		mappings, parseErr := DecodePortMappings(ports)
+		switch {
+		case parseErr == nil:
+			endpoint.AdditionalPorts = mappings
+		case !newerSchema:
+			return nil, parseErr
+		}
+		delete(attributes, EndpointPortsAttr)
+	}
+
 	// Add the remaining attributes
 	endpoint.Attributes = attributes
 
@@ -164,6 +238,22 @@ func (e *Endpoint) GetCloudMapAttributes() map[string]string {
 	attrs[ServicePortAttr] = strconv.Itoa(int(e.ServicePort.Port))
 	attrs[ServiceTargetPortAttr] = e.ServicePort.TargetPort
 	attrs[ServiceProtocolAttr] = e.ServicePort.Protocol
+	attrs[SchemaVersionAttr] = strconv.Itoa(SchemaVersion)
+	if e.Ready != nil {
+		attrs[EndpointReadyAttr] = strconv.FormatBool(*e.Ready)
+	}
+	if e.Hostname != "" {
+		attrs[EndpointHostnameAttr] = e.Hostname
+	}
+	if e.Nodename != "" {
+		attrs[EndpointNodenameAttr] = e.Nodename
+	}
+	if e.Zone != "" {
+		attrs[EndpointZoneAttr] = e.Zone
+	}
+	if len(e.AdditionalPorts) > 0 {
+		attrs[EndpointPortsAttr] = EncodePortMappings(e.AdditionalPorts)
+	}
 
 	for key, val := range e.Attributes {
 		attrs[key] = val
@@ -172,6 +262,11 @@ func (e *Endpoint) GetCloudMapAttributes() map[string]string {
 	return attrs
 }
 
+// IsReady returns true unless the endpoint is known not to be ready.
+func (e *Endpoint) IsReady() bool {
+	return e.Ready == nil || *e.Ready
+}
+
 // Equals evaluates if two Endpoints are "deeply equal" (including all fields).
 func (e *Endpoint) Equals(other *Endpoint) bool {
 	return reflect.DeepEqual(e, other)
@@ -194,6 +289,21 @@ func EndpointIdFromIPAddressAndPort(address string, port Port) string {
 	return fmt.Sprintf("%s-%s-%d", strings.ToLower(port.Protocol), address, port.Port)
 }
 
+// EndpointId derives the Cloud Map instance ID of an endpoint from its IP address, port and the ID of the cluster
+// exporting it. The ID only depends on the identity of the endpoint, so registering the endpoint again is an upsert of
+// the same instance, and endpoints with the same address in different clusters don't overwrite each other. The cluster
+// is appended as a short hash, keeping the ID within the 64 characters Cloud Map allows. Without a cluster ID, the ID
+// is the one of EndpointIdFromIPAddressAndPort.
+func EndpointId(clusterId string, address string, port Port) string {
+	id := EndpointIdFromIPAddressAndPort(address, port)
+	if clusterId == "" {
+		return id
+	}
+	hash := sha256.Sum256([]byte(clusterId))
+	return id + "-" + hex.EncodeToString(hash[:])[:clusterHashLength]
+}
+
+// ConvertNamespaceType converts a Cloud Map namespace type, UnsupportedNamespaceType for public DNS namespaces.
 func ConvertNamespaceType(nsType types.NamespaceType) (namespaceType NamespaceType) {
 	switch nsType {
 	case types.NamespaceTypeDnsPrivate:
@@ -205,10 +315,12 @@ func ConvertNamespaceType(nsType types.NamespaceType) (namespaceType NamespaceTy
 	}
 }
 
+// IsUnsupported returns whether services of the namespace can't be exported.
 func (namespaceType *NamespaceType) IsUnsupported() bool {
 	return *namespaceType == UnsupportedNamespaceType
 }
 
+// GetID returns the protocol and port number identifying the port, e.g. TCP:80.
 func (port *Port) GetID() string {
 	return fmt.Sprintf("%s:%d", port.Protocol, port.Port)
 }
@@ -2,12 +2,14 @@ package model
 
 import (
 	"github.com/aws/aws-sdk-go-v2/service/servicediscovery/types"
+	"github.com/stretchr/testify/assert"
 	"reflect"
 	"testing"
 )
 
 var instId = "my-instance"
 var ip = "192.168.0.1"
+var notReady = false
 
 func TestNewEndpointFromInstance(t *testing.T) {
 	tests := []struct {
@@ -51,6 +53,101 @@ func TestNewEndpointFromInstance(t *testing.T) {
 				},
 			},
 		},
+		{
+			name: "endpoint details",
+			inst: &types.HttpInstanceSummary{
+				InstanceId: &instId,
+				Attributes: map[string]string{
+					EndpointIpv4Attr:      ip,
+					EndpointPortAttr:      "80",
+					EndpointProtocolAttr:  "TCP",
+					EndpointPortNameAttr:  "http",
+					ServicePortNameAttr:   "http",
+					ServiceProtocolAttr:   "TCP",
+					ServicePortAttr:       "65535",
+					ServiceTargetPortAttr: "80",
+					EndpointReadyAttr:     "false",
+					EndpointHostnameAttr:  "pod-0",
+					EndpointNodenameAttr:  "node-1",
+					EndpointZoneAttr:      "us-west-2a",
+				},
+			},
+			want: &Endpoint{
+				Id: instId,
+				IP: ip,
+				EndpointPort: Port{
+					Name:     "http",
+					Port:     80,
+					Protocol: "TCP",
+				},
+				ServicePort: Port{
+					Name:       "http",
+					Port:       65535,
+					TargetPort: "80",
+					Protocol:   "TCP",
+				},
+				Ready:      &notReady,
+				Hostname:   "pod-0",
+				Nodename:   "node-1",
+				Zone:       "us-west-2a",
+				Attributes: map[string]string{},
+			},
+		},
+		{
+			name: "invalid readiness",
+			inst: &types.HttpInstanceSummary{
+				InstanceId: &instId,
+				Attributes: map[string]string{
+					EndpointIpv4Attr:      ip,
+					EndpointPortAttr:      "80",
+					EndpointProtocolAttr:  "TCP",
+					EndpointPortNameAttr:  "http",
+					ServicePortNameAttr:   "http",
+					ServiceProtocolAttr:   "TCP",
+					ServicePortAttr:       "65535",
+					ServiceTargetPortAttr: "80",
+					EndpointReadyAttr:     "maybe",
+				},
+			},
+			wantErr: true,
+		},
+		{
+			name: "newer schema version",
+			inst: &types.HttpInstanceSummary{
+				InstanceId: &instId,
+				Attributes: map[string]string{
+					EndpointIpv4Attr:      ip,
+					EndpointPortAttr:      "80",
+					EndpointProtocolAttr:  "TCP",
+					EndpointPortNameAttr:  "http",
+					ServicePortNameAttr:   "http",
+					ServiceProtocolAttr:   "TCP",
+					ServicePortAttr:       "65535",
+					ServiceTargetPortAttr: "80",
+					SchemaVersionAttr:     "4",
+					EndpointReadyAttr:     "serving",
+					"FUTURE_ATTR":         "future-val",
+				},
+			},
+			want: &Endpoint{
+				Id: instId,
+				IP: ip,
+				EndpointPort: Port{
+					Name:     "http",
+					Port:     80,
+					Protocol: "TCP",
+				},
+				ServicePort: Port{
+					Name:       "http",
+					Port:       65535,
+					TargetPort: "80",
+					Protocol:   "TCP",
+				},
+				Attributes: map[string]string{
+					"FUTURE_ATTR": "future-val",
+				},
+			},
+		},
 		{
 			name: "invalid port",
 			inst: &types.HttpInstanceSummary{
@@ -147,6 +244,7 @@ func TestEndpoint_GetAttributes(t *testing.T) {
 				ServiceProtocolAttr:   "TCP",
 				ServicePortAttr:       "30",
 				ServiceTargetPortAttr: "80",
+				SchemaVersionAttr:     "3",
 				"custom-attr":         "custom-val",
 			},
 		},
@@ -167,6 +265,44 @@ func TestEndpoint_GetAttributes(t *testing.T) {
 	}
 }
 
+func TestEndpoint_GetAttributesWithDetails(t *testing.T) {
+	e := &Endpoint{
+		IP:           ip,
+		EndpointPort: Port{Name: "http", Port: 80, Protocol: "TCP"},
+		ServicePort:  Port{Name: "http", Port: 30, TargetPort: "80", Protocol: "TCP"},
+		Ready:        &notReady,
+		Hostname:     "pod-0",
+		Nodename:     "node-1",
+		Zone:         "us-west-2a",
+		Attributes:   map[string]string{},
+	}
+	attrs := e.GetCloudMapAttributes()
+	assert.Equal(t, "false", attrs[EndpointReadyAttr])
+	assert.Equal(t, "pod-0", attrs[EndpointHostnameAttr])
+	assert.Equal(t, "node-1", attrs[EndpointNodenameAttr])
+	assert.Equal(t, "us-west-2a", attrs[EndpointZoneAttr])
+
+	inst := &types.HttpInstanceSummary{InstanceId: &instId, Attributes: attrs}
+	decoded, err := NewEndpointFromInstance(inst)
+	assert.Nil(t, err)
+	e.Id = instId
+	assert.Equal(t, e, decoded, "the details survive a round trip")
+}
+
+func TestInstanceSchemaVersion(t *testing.T) {
+	assert.Equal(t, LegacySchemaVersion, InstanceSchemaVersion(map[string]string{}))
+	assert.Equal(t, LegacySchemaVersion, InstanceSchemaVersion(map[string]string{SchemaVersionAttr: "x"}))
+	assert.Equal(t, SchemaVersion, InstanceSchemaVersion((&Endpoint{}).GetCloudMapAttributes()))
+	assert.Equal(t, 4, InstanceSchemaVersion(map[string]string{SchemaVersionAttr: "4"}))
+}
+
+func TestEndpoint_IsReady(t *testing.T) {
+	ready := true
+	assert.True(t, (&Endpoint{}).IsReady(), "unknown readiness is ready")
+	assert.True(t, (&Endpoint{Ready: &ready}).IsReady())
+	assert.False(t, (&Endpoint{Ready: &notReady}).IsReady())
+}
+
 func TestEndpointIdFromIPAddressAndPort(t *testing.T) {
 	tests := []struct {
 		name    string
@@ -194,6 +330,50 @@ func TestEndpointIdFromIPAddressAndPort(t *testing.T) {
 	}
 }
 
+func TestEndpointId(t *testing.T) {
+	port := Port{Name: "http", Port: 80, Protocol: "TCP"}
+	tests := []struct {
+		name      string
+		clusterId string
+		address   string
+		port      Port
+		want      string
+	}{
+		{
+			name:    "no cluster id",
+			address: ip,
+			port:    port,
+			want:    "tcp-192_168_0_1-80",
+		},
+		{
+			name:      "cluster id",
+			clusterId: "cluster-1",
+			address:   ip,
+			port:      port,
+			want:      "tcp-192_168_0_1-80-4afb32cc",
+		},
+		{
+			name:      "other cluster id",
+			clusterId: "cluster-2",
+			address:   ip,
+			port:      port,
+			want:      "tcp-192_168_0_1-80-5dddc7a4",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := EndpointId(tt.clusterId, tt.address, tt.port); got != tt.want {
+				t.Errorf("EndpointId() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+
+	longest := EndpointId("cluster-1", "ffff:ffff:ffff:ffff:ffff:ffff:255.255.255.255", Port{Port: 65535, Protocol: "TCP"})
+	if len(longest) > 64 {
+		t.Errorf("EndpointId() = %v, longer than 64 characters", longest)
+	}
+}
+
 func TestEndpoint_Equals(t *testing.T) {
 	firstEndpoint := Endpoint{
 		Id: instId,
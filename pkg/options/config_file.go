@@ -0,0 +1,132 @@
+package options
+
+import (
+	"flag"
+	"fmt"
+	configv1alpha1 "github.com/aws/aws-cloud-map-mcs-controller-for-k8s/pkg/api/config/v1alpha1"
+	"io/ioutil"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/serializer"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+var scheme = runtime.NewScheme()
+
+func init() {
+	_ = configv1alpha1.AddToScheme(scheme)
+}
+
+// LoadConfigFile reads a controller configuration file.
+func LoadConfigFile(path string) (*configv1alpha1.ControllerConfig, error) {
+	content, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	config := &configv1alpha1.ControllerConfig{}
+	if err = runtime.DecodeInto(serializer.NewCodecFactory(scheme).UniversalDecoder(), content, config); err != nil {
+		return nil, fmt.Errorf("invalid controller configuration %s: %w", path, err)
+	}
+	return config, nil
+}
+
+// ApplyConfigFile sets the flags of the flag set from the settings of the controller configuration file. Flags set
+// explicitly on the command line take precedence over the configuration file. Must be called after parsing the flags.
+func ApplyConfigFile(path string, fs *flag.FlagSet) error {
+	config, err := LoadConfigFile(path)
+	if err != nil {
+		return err
+	}
+	return applyConfig(config, path, fs)
+}
+
+func applyConfig(config *configv1alpha1.ControllerConfig, path string, fs *flag.FlagSet) error {
+	explicit := explicitFlags(fs)
+	for name, value := range flagValues(config) {
+		if explicit[name] || fs.Lookup(name) == nil {
+			continue
+		}
+		if err := fs.Set(name, value); err != nil {
+			return fmt.Errorf("invalid value %q for %s in %s: %w", value, name, path, err)
+		}
+	}
+	return nil
+}
+
+// explicitFlags returns the names of the flags set on the command line.
+func explicitFlags(fs *flag.FlagSet) map[string]bool {
+	explicit := make(map[string]bool)
+	fs.Visit(func(f *flag.Flag) {
+		explicit[f.Name] = true
+	})
+	return explicit
+}
+
+// flagValues returns the flag values for the settings specified in the configuration.
+func flagValues(config *configv1alpha1.ControllerConfig) map[string]string {
+	values := make(map[string]string)
+	setString := func(name string, value string) {
+		if value != "" {
+			values[name] = value
+		}
+	}
+	setDuration := func(name string, value *metav1.Duration) {
+		if value != nil {
+			values[name] = value.Duration.String()
+		}
+	}
+	setList := func(name string, value []string) {
+		if value != nil {
+			values[name] = strings.Join(value, ",")
+		}
+	}
+	setMap := func(name string, value map[string]string) {
+		if value != nil {
+			pairs := make([]string, 0, len(value))
+			for key, val := range value {
+				pairs = append(pairs, key+"="+val)
+			}
+			sort.Strings(pairs)
+			values[name] = strings.Join(pairs, ",")
+		}
+	}
+
+	setString("metrics-bind-address", config.Metrics.BindAddress)
+	setString("health-probe-bind-address", config.Health.HealthProbeBindAddress)
+	setString("webhook-cert-dir", config.Webhook.CertDir)
+	if config.LeaderElection != nil && config.LeaderElection.LeaderElect != nil {
+		values["leader-elect"] = strconv.FormatBool(*config.LeaderElection.LeaderElect)
+	}
+
+	setString("log-level", config.Logging.Level)
+	setString("log-format", config.Logging.Format)
+	setMap("log-component-levels", config.Logging.ComponentLevels)
+
+	setString("cluster-id", config.ClusterId)
+	setString("clusterset-id", config.ClusterSetId)
+	setString("aws-region", config.AWS.Region)
+	setString("aws-profile", config.AWS.Profile)
+	setString("aws-shared-credentials-file", config.AWS.SharedCredentialsFile)
+	setString("aws-web-identity-token-file", config.AWS.WebIdentityTokenFile)
+	setString("aws-role-arn", config.AWS.RoleARN)
+	setString("aws-role-session-name", config.AWS.RoleSessionName)
+	setMap("aws-role-session-tags", config.AWS.RoleSessionTags)
+	setString("aws-sts-regional-endpoints", config.AWS.STSRegionalEndpoints)
+	setMap("aws-resource-tags", config.AWS.ResourceTags)
+	setDuration("aws-api-call-timeout", config.AWS.APICallTimeout)
+	setDuration("aws-api-attempt-timeout", config.AWS.APIAttemptTimeout)
+	setDuration("operation-poll-interval", config.AWS.OperationPollInterval)
+	setDuration("operation-poll-timeout", config.AWS.OperationPollTimeout)
+	setDuration("namespace-cache-ttl", config.Cache.NamespaceTTL)
+	setDuration("service-cache-ttl", config.Cache.ServiceTTL)
+	setDuration("endpoint-cache-ttl", config.Cache.EndpointTTL)
+	setDuration("cloudmap-sync-period", config.Sync.CloudMapSyncPeriod)
+	setDuration("slow-reconcile-threshold", config.Sync.SlowReconcileThreshold)
+	setList("watch-namespaces", config.Filters.WatchNamespaces)
+	setList("protected-namespaces", config.Filters.ProtectedNamespaces)
+
+	return values
+}
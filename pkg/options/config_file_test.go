@@ -0,0 +1,62 @@
+package options
+
+import (
+	"flag"
+	"github.com/stretchr/testify/assert"
+	"io/ioutil"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+const testConfig = `apiVersion: config.multicluster.k8s.aws/v1alpha1
+kind: ControllerConfig
+metrics:
+  bindAddress: 127.0.0.1:8080
+leaderElection:
+  leaderElect: true
+aws:
+  region: us-west-2
+  apiCallTimeout: 20s
+cache:
+  endpointTTL: 10s
+sync:
+  cloudMapSyncPeriod: 30s
+filters:
+  watchNamespaces: [ns1, ns2]
+`
+
+func TestApplyConfigFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	assert.NoError(t, ioutil.WriteFile(path, []byte(testConfig), 0600))
+
+	fs := flag.NewFlagSet("test", flag.ContinueOnError)
+	metricsAddr := fs.String("metrics-bind-address", ":8080", "")
+	leaderElect := fs.Bool("leader-elect", false, "")
+	region := fs.String("aws-region", "", "")
+	apiCallTimeout := fs.Duration("aws-api-call-timeout", time.Minute, "")
+	endpointTTL := fs.Duration("endpoint-cache-ttl", 5*time.Second, "")
+	syncPeriod := fs.Duration("cloudmap-sync-period", 2*time.Second, "")
+	watchNamespaces := fs.String("watch-namespaces", "", "")
+	clusterId := fs.String("cluster-id", "", "")
+	assert.NoError(t, fs.Parse([]string{"--aws-region=eu-west-1"}))
+
+	assert.NoError(t, ApplyConfigFile(path, fs))
+	assert.Equal(t, "127.0.0.1:8080", *metricsAddr)
+	assert.True(t, *leaderElect)
+	assert.Equal(t, "eu-west-1", *region, "command line takes precedence")
+	assert.Equal(t, 20*time.Second, *apiCallTimeout)
+	assert.Equal(t, 10*time.Second, *endpointTTL)
+	assert.Equal(t, 30*time.Second, *syncPeriod)
+	assert.Equal(t, "ns1,ns2", *watchNamespaces)
+	assert.Equal(t, "", *clusterId, "unspecified settings keep the flag value")
+}
+
+func TestLoadConfigFile_InvalidKind(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	content := "apiVersion: config.multicluster.k8s.aws/v1alpha1\nkind: Unknown\n"
+	assert.NoError(t, ioutil.WriteFile(path, []byte(content), 0600))
+
+	_, err := LoadConfigFile(path)
+	assert.Error(t, err)
+}
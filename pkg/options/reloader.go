@@ -0,0 +1,136 @@
+package options
+
+import (
+	"bytes"
+	"context"
+	"flag"
+	"github.com/aws/aws-cloud-map-mcs-controller-for-k8s/pkg/common"
+	"io/ioutil"
+	"time"
+)
+
+const defaultReloadInterval = 10 * time.Second
+
+// ConfigReloader watches the controller configuration file, e.g. mounted from a ConfigMap, and applies changes of
+// the settings registered with OnChange without restarting the controller. Settings removed from the file revert to
+// their flag default, and flags set explicitly on the command line are never reloaded.
+type ConfigReloader struct {
+	path     string
+	log      common.Logger
+	fs       *flag.FlagSet
+	interval time.Duration
+	explicit map[string]bool
+	handlers map[string]func(value string) error
+
+	content []byte
+	values  map[string]string
+}
+
+// NewConfigReloader creates a reloader for the configuration file. Must be called after parsing the flags and
+// before applying the configuration file to the flags.
+func NewConfigReloader(path string, fs *flag.FlagSet) *ConfigReloader {
+	return &ConfigReloader{
+		path:     path,
+		log:      common.NewLogger("options", "ConfigReloader"),
+		fs:       fs,
+		interval: defaultReloadInterval,
+		explicit: explicitFlags(fs),
+		handlers: make(map[string]func(value string) error),
+		values:   make(map[string]string),
+	}
+}
+
+// OnChange registers the handler applying a changed value of the flag.
+func (r *ConfigReloader) OnChange(flagName string, handler func(value string) error) {
+	r.handlers[flagName] = handler
+}
+
+// Load applies the configuration file to the flags and records the loaded settings as the baseline for reloading.
+func (r *ConfigReloader) Load() error {
+	content, err := ioutil.ReadFile(r.path)
+	if err != nil {
+		return err
+	}
+	config, err := LoadConfigFile(r.path)
+	if err != nil {
+		return err
+	}
+	if err = applyConfig(config, r.path, r.fs); err != nil {
+		return err
+	}
+
+	r.content = content
+	r.values = flagValues(config)
+	return nil
+}
+
+// Start polls the configuration file for changes until the context is done.
+func (r *ConfigReloader) Start(ctx context.Context) error {
+	ticker := time.NewTicker(r.interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+			r.reload()
+		}
+	}
+}
+
+// NeedLeaderElection returns false, every replica applies the configuration.
+func (r *ConfigReloader) NeedLeaderElection() bool {
+	return false
+}
+
+func (r *ConfigReloader) reload() {
+	content, err := ioutil.ReadFile(r.path)
+	if err != nil {
+		r.log.Error(err, "unable to read configuration file", "path", r.path)
+		return
+	}
+	if bytes.Equal(content, r.content) {
+		return
+	}
+
+	config, err := LoadConfigFile(r.path)
+	if err != nil {
+		// keep the current settings until the file is fixed
+		r.log.Error(err, "ignoring invalid configuration file", "path", r.path)
+		return
+	}
+	r.content = content
+
+	values := flagValues(config)
+	for name, handler := range r.handlers {
+		if r.explicit[name] {
+			continue
+		}
+
+		value := r.valueOrDefault(values, name)
+		previous := r.valueOrDefault(r.values, name)
+		if value == previous {
+			continue
+		}
+
+		if err = handler(value); err != nil {
+			r.log.Error(err, "unable to apply configuration change", "setting", name, "value", value)
+			values[name] = previous
+			continue
+		}
+		r.log.Info("applied configuration change", "setting", name, "value", value)
+	}
+	r.values = values
+}
+
+// valueOrDefault returns the value of the flag in the settings, or the flag default if not set.
+func (r *ConfigReloader) valueOrDefault(values map[string]string, name string) string {
+	if value, found := values[name]; found {
+		return value
+	}
+	if f := r.fs.Lookup(name); f != nil {
+		return f.DefValue
+	}
+	return ""
+}
@@ -0,0 +1,58 @@
+package options
+
+import (
+	"flag"
+	"github.com/stretchr/testify/assert"
+	"io/ioutil"
+	"path/filepath"
+	"testing"
+)
+
+const testReloadConfig = `apiVersion: config.multicluster.k8s.aws/v1alpha1
+kind: ControllerConfig
+logging:
+  level: debug
+sync:
+  cloudMapSyncPeriod: 30s
+`
+
+func TestConfigReloader_Reload(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	assert.NoError(t, ioutil.WriteFile(path, []byte(testReloadConfig), 0600))
+
+	fs := flag.NewFlagSet("test", flag.ContinueOnError)
+	logLevel := fs.String("log-level", "info", "")
+	fs.Duration("cloudmap-sync-period", 0, "")
+	fs.String("aws-region", "", "")
+	assert.NoError(t, fs.Parse([]string{"--aws-region=us-west-2"}))
+
+	reloader := NewConfigReloader(path, fs)
+	assert.NoError(t, reloader.Load())
+	assert.Equal(t, "debug", *logLevel)
+
+	changes := make(map[string]string)
+	for _, name := range []string{"log-level", "cloudmap-sync-period", "aws-region"} {
+		name := name
+		reloader.OnChange(name, func(value string) error {
+			changes[name] = value
+			return nil
+		})
+	}
+
+	// unchanged file
+	reloader.reload()
+	assert.Empty(t, changes)
+
+	// changed and removed settings, the explicitly set flag is not reloaded
+	content := "apiVersion: config.multicluster.k8s.aws/v1alpha1\nkind: ControllerConfig\n" +
+		"logging:\n  level: error\naws:\n  region: eu-west-1\n"
+	assert.NoError(t, ioutil.WriteFile(path, []byte(content), 0600))
+	reloader.reload()
+	assert.Equal(t, map[string]string{"log-level": "error", "cloudmap-sync-period": "0s"}, changes)
+
+	// invalid file keeps the current settings
+	changes = make(map[string]string)
+	assert.NoError(t, ioutil.WriteFile(path, []byte("kind: Unknown\n"), 0600))
+	reloader.reload()
+	assert.Empty(t, changes)
+}
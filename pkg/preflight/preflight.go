@@ -0,0 +1,199 @@
+package preflight
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	cloudmapv1alpha1 "github.com/aws/aws-cloud-map-mcs-controller-for-k8s/pkg/api/cloudmap/v1alpha1"
+	"github.com/aws/aws-cloud-map-mcs-controller-for-k8s/pkg/api/v1alpha1"
+	"github.com/aws/aws-cloud-map-mcs-controller-for-k8s/pkg/controllers"
+	"github.com/aws/aws-sdk-go-v2/aws"
+	sd "github.com/aws/aws-sdk-go-v2/service/servicediscovery"
+	"github.com/aws/smithy-go"
+	k8serrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/util/sets"
+	"k8s.io/apimachinery/pkg/util/validation"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"strings"
+	"time"
+)
+
+const (
+	// SeverityError marks findings which stop the controller, it would reconcile into failure loops
+	SeverityError = "ERROR"
+	// SeverityWarning marks findings which are allowed, but likely unintended
+	SeverityWarning = "WARNING"
+
+	// DefaultTimeout bounds the time the preflight checks may take, e.g. if the Cloud Map endpoint doesn't respond
+	DefaultTimeout = 30 * time.Second
+
+	// maxClusterIdLength is the maximum length of a cluster ID, as for the cluster properties of multi-cluster services
+	maxClusterIdLength = 128
+)
+
+var accessDeniedCodes = sets.NewString("AccessDeniedException", "AccessDenied", "UnauthorizedOperation")
+
+// requiredKinds are the kinds of the CRDs the controller watches.
+var requiredKinds = []schema.GroupVersionKind{
+	v1alpha1.GroupVersion.WithKind("ServiceExport"),
+	v1alpha1.GroupVersion.WithKind("ServiceImport"),
+	cloudmapv1alpha1.GroupVersion.WithKind("ClusterCloudMapConfig"),
+	cloudmapv1alpha1.GroupVersion.WithKind("CloudMapSyncConfig"),
+	cloudmapv1alpha1.GroupVersion.WithKind("CloudMapStaticEndpoint"),
+}
+
+// NamespaceLister lists Cloud Map namespaces, e.g. a cloudmap.AwsFacade.
+type NamespaceLister interface {
+	ListNamespaces(context.Context, *sd.ListNamespacesInput, ...func(*sd.Options)) (*sd.ListNamespacesOutput, error)
+}
+
+// Config holds the settings verified by the preflight checks.
+type Config struct {
+	// Mapper resolves the kinds of the CRDs
+	Mapper meta.RESTMapper
+	// Reader reads the ClusterCloudMapConfig, it must not be the cache of the manager, which isn't started yet
+	Reader client.Reader
+	// Namespaces lists the Cloud Map namespaces of the region, the region isn't checked if nil, e.g. with Route53
+	Namespaces NamespaceLister
+	Region     string
+
+	ClusterId    string
+	ClusterSetId string
+	// RequireClusterId is set if enabled features require the cluster ID, e.g. replication
+	RequireClusterId bool
+}
+
+// Finding is a problem found by a preflight check.
+type Finding struct {
+	Check    string
+	Severity string
+	Message  string
+}
+
+func (f Finding) String() string {
+	return fmt.Sprintf("%s %s: %s", f.Severity, f.Check, f.Message)
+}
+
+// Run checks that the CRDs are installed, the Cloud Map API of the region is reachable, the namespace mapping of the
+// ClusterCloudMapConfig is valid, and the cluster and clusterset IDs are set and valid. Each finding says how to fix
+// the problem.
+func Run(ctx context.Context, cfg Config) []Finding {
+	findings := make([]Finding, 0)
+	crdsInstalled := true
+	for _, gvk := range requiredKinds {
+		if finding := checkKind(cfg.Mapper, gvk); finding != nil {
+			findings = append(findings, *finding)
+			crdsInstalled = false
+		}
+	}
+	if crdsInstalled {
+		findings = append(findings, checkClusterConfig(ctx, cfg.Reader)...)
+	}
+	if cfg.Namespaces != nil {
+		findings = append(findings, checkRegion(ctx, cfg.Namespaces, cfg.Region)...)
+	}
+	findings = append(findings, checkClusterIds(cfg)...)
+	return findings
+}
+
+// HasErrors returns true if any finding is an error.
+func HasErrors(findings []Finding) bool {
+	for _, finding := range findings {
+		if finding.Severity == SeverityError {
+			return true
+		}
+	}
+	return false
+}
+
+func checkKind(mapper meta.RESTMapper, gvk schema.GroupVersionKind) *Finding {
+	_, err := mapper.RESTMapping(gvk.GroupKind(), gvk.Version)
+	switch {
+	case err == nil:
+		return nil
+	case meta.IsNoMatchError(err):
+		return &Finding{Check: "crds", Severity: SeverityError, Message: fmt.Sprintf(
+			"the %s CRD is not installed, install the CRDs of the release", gvk.GroupKind())}
+	default:
+		return &Finding{Check: "crds", Severity: SeverityError, Message: fmt.Sprintf(
+			"unable to look up the %s CRD, check the access of the controller to the API server: %s", gvk.Kind, err)}
+	}
+}
+
+func checkClusterConfig(ctx context.Context, reader client.Reader) []Finding {
+	config := &cloudmapv1alpha1.ClusterCloudMapConfig{}
+	err := reader.Get(ctx, types.NamespacedName{Name: cloudmapv1alpha1.ClusterCloudMapConfigName}, config)
+	if k8serrors.IsNotFound(err) {
+		return nil
+	}
+	if err != nil {
+		return []Finding{{Check: "namespace-mapping", Severity: SeverityError, Message: fmt.Sprintf(
+			"unable to read the ClusterCloudMapConfig %s: %s", cloudmapv1alpha1.ClusterCloudMapConfigName, err)}}
+	}
+
+	findings := make([]Finding, 0)
+	for _, msg := range controllers.ValidateClusterCloudMapConfig(&config.Spec) {
+		findings = append(findings, Finding{Check: "namespace-mapping", Severity: SeverityError, Message: fmt.Sprintf(
+			"invalid ClusterCloudMapConfig %s, fix or delete it: %s", cloudmapv1alpha1.ClusterCloudMapConfigName, msg)})
+	}
+	return findings
+}
+
+func checkRegion(ctx context.Context, namespaces NamespaceLister, region string) []Finding {
+	_, err := namespaces.ListNamespaces(ctx, &sd.ListNamespacesInput{MaxResults: aws.Int32(1)})
+	if err == nil {
+		return nil
+	}
+
+	var apiErr smithy.APIError
+	switch {
+	case errors.As(err, &apiErr) && accessDeniedCodes.Has(apiErr.ErrorCode()):
+		// the region is reachable, the permissions are checked by the check-iam command
+		return []Finding{{Check: "region", Severity: SeverityWarning, Message: fmt.Sprintf(
+			"the credentials aren't authorized to list the Cloud Map namespaces of %s, run kubectl cloudmap "+
+				"check-iam to check the permissions of the controller: %s", region, apiErr.ErrorCode())}}
+	case errors.As(err, &apiErr):
+		return []Finding{{Check: "region", Severity: SeverityError, Message: fmt.Sprintf(
+			"Cloud Map rejected the requests in region %s, check the region and credentials: %s", region, err)}}
+	default:
+		return []Finding{{Check: "region", Severity: SeverityError, Message: fmt.Sprintf(
+			"Cloud Map is unreachable in region %s, check AWS_REGION and the network access to the Cloud Map "+
+				"endpoint of the region: %s", region, err)}}
+	}
+}
+
+func checkClusterIds(cfg Config) []Finding {
+	findings := make([]Finding, 0)
+	switch {
+	case cfg.ClusterId == "" && cfg.RequireClusterId:
+		findings = append(findings, Finding{Check: "cluster-id", Severity: SeverityError,
+			Message: "the enabled features require the cluster ID, set --cluster-id"})
+	case cfg.ClusterId == "" && cfg.ClusterSetId != "":
+		findings = append(findings, Finding{Check: "cluster-id", Severity: SeverityError,
+			Message: "--clusterset-id requires --cluster-id, the instances of the cluster can't be owned otherwise"})
+	case cfg.ClusterId == "":
+		findings = append(findings, Finding{Check: "cluster-id", Severity: SeverityWarning,
+			Message: "no cluster ID is set, the clusters can't tell their instances apart, set --cluster-id"})
+	}
+
+	ids := []struct{ flag, id string }{{"--cluster-id", cfg.ClusterId}, {"--clusterset-id", cfg.ClusterSetId}}
+	for _, flagId := range ids {
+		flag, id := flagId.flag, flagId.id
+		if id == "" {
+			continue
+		}
+		msgs := validation.IsDNS1123Subdomain(id)
+		if len(id) > maxClusterIdLength {
+			msgs = append(msgs, fmt.Sprintf("must be no more than %d characters", maxClusterIdLength))
+		}
+		if len(msgs) > 0 {
+			findings = append(findings, Finding{Check: "cluster-id", Severity: SeverityError, Message: fmt.Sprintf(
+				"%s %q is not a valid ID, use a DNS subdomain of at most %d characters: %s", flag, id,
+				maxClusterIdLength, strings.Join(msgs, ", "))})
+		}
+	}
+	return findings
+}
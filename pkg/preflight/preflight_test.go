@@ -0,0 +1,114 @@
+package preflight
+
+import (
+	"context"
+	"errors"
+	"github.com/aws/aws-cloud-map-mcs-controller-for-k8s/mocks/pkg/cloudmap"
+	cloudmapv1alpha1 "github.com/aws/aws-cloud-map-mcs-controller-for-k8s/pkg/api/cloudmap/v1alpha1"
+	sd "github.com/aws/aws-sdk-go-v2/service/servicediscovery"
+	"github.com/aws/smithy-go"
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/assert"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	"strings"
+	"testing"
+)
+
+func TestRun(t *testing.T) {
+	tests := []struct {
+		name       string
+		kinds      []schema.GroupVersionKind
+		config     *cloudmapv1alpha1.ClusterCloudMapConfig
+		listErr    error
+		clusterId  string
+		clusterSet string
+		require    bool
+		want       []string
+		wantErrors bool
+	}{
+		{name: "valid", kinds: requiredKinds, clusterId: "cluster1", clusterSet: "clusterset1"},
+		{name: "missing CRD", kinds: requiredKinds[1:], clusterId: "cluster1",
+			want: []string{"ERROR crds: the ServiceExport.multicluster.x-k8s.io CRD is not installed"}, wantErrors: true},
+		{name: "invalid namespace mapping", kinds: requiredKinds, clusterId: "cluster1",
+			config: testClusterConfig(cloudmapv1alpha1.NamespaceMapping{Prefix: "Invalid_"}),
+			want:   []string{"ERROR namespace-mapping: invalid ClusterCloudMapConfig default"}, wantErrors: true},
+		{name: "unreachable region", kinds: requiredKinds, clusterId: "cluster1", listErr: errors.New("no such host"),
+			want: []string{"ERROR region: Cloud Map is unreachable in region us-west-2"}, wantErrors: true},
+		{name: "access denied", kinds: requiredKinds, clusterId: "cluster1",
+			listErr: &smithy.GenericAPIError{Code: "AccessDeniedException"},
+			want:    []string{"WARNING region: the credentials aren't authorized"}},
+		{name: "no cluster ID", kinds: requiredKinds,
+			want: []string{"WARNING cluster-id: no cluster ID is set"}},
+		{name: "cluster ID required", kinds: requiredKinds, require: true,
+			want: []string{"ERROR cluster-id: the enabled features require the cluster ID"}, wantErrors: true},
+		{name: "clusterset ID only", kinds: requiredKinds, clusterSet: "clusterset1",
+			want: []string{"ERROR cluster-id: --clusterset-id requires --cluster-id"}, wantErrors: true},
+		{name: "invalid cluster ID", kinds: requiredKinds, clusterId: "Cluster_1",
+			want: []string{"ERROR cluster-id: --cluster-id \"Cluster_1\" is not a valid ID"}, wantErrors: true},
+		{name: "too long cluster ID", kinds: requiredKinds, clusterId: "cluster1", clusterSet: strings.Repeat("a", 129),
+			want: []string{"ERROR cluster-id: --clusterset-id"}, wantErrors: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockController := gomock.NewController(t)
+			defer mockController.Finish()
+
+			mapper := meta.NewDefaultRESTMapper(nil)
+			for _, gvk := range tt.kinds {
+				mapper.Add(gvk, meta.RESTScopeNamespace)
+			}
+			scheme := runtime.NewScheme()
+			assert.NoError(t, cloudmapv1alpha1.AddToScheme(scheme))
+			objs := make([]client.Object, 0)
+			if tt.config != nil {
+				objs = append(objs, tt.config)
+			}
+			namespaces := cloudmap.NewMockAwsFacade(mockController)
+			namespaces.EXPECT().ListNamespaces(gomock.Any(), gomock.Any()).Return(&sd.ListNamespacesOutput{}, tt.listErr)
+
+			findings := Run(context.TODO(), Config{
+				Mapper:           mapper,
+				Reader:           fake.NewClientBuilder().WithScheme(scheme).WithObjects(objs...).Build(),
+				Namespaces:       namespaces,
+				Region:           "us-west-2",
+				ClusterId:        tt.clusterId,
+				ClusterSetId:     tt.clusterSet,
+				RequireClusterId: tt.require,
+			})
+
+			assert.Equal(t, len(tt.want), len(findings), "findings: %v", findings)
+			for i := 0; i < len(tt.want) && i < len(findings); i++ {
+				assert.True(t, strings.HasPrefix(findings[i].String(), tt.want[i]), findings[i].String())
+			}
+			assert.Equal(t, tt.wantErrors, HasErrors(findings))
+		})
+	}
+}
+
+func TestRun_WithoutCloudMap(t *testing.T) {
+	mapper := meta.NewDefaultRESTMapper(nil)
+	for _, gvk := range requiredKinds {
+		mapper.Add(gvk, meta.RESTScopeNamespace)
+	}
+	scheme := runtime.NewScheme()
+	assert.NoError(t, cloudmapv1alpha1.AddToScheme(scheme))
+
+	findings := Run(context.TODO(), Config{
+		Mapper:    mapper,
+		Reader:    fake.NewClientBuilder().WithScheme(scheme).Build(),
+		ClusterId: "cluster1",
+	})
+	assert.Empty(t, findings, "the region isn't checked without a namespace lister")
+}
+
+func testClusterConfig(mapping cloudmapv1alpha1.NamespaceMapping) *cloudmapv1alpha1.ClusterCloudMapConfig {
+	return &cloudmapv1alpha1.ClusterCloudMapConfig{
+		ObjectMeta: metav1.ObjectMeta{Name: cloudmapv1alpha1.ClusterCloudMapConfigName},
+		Spec:       cloudmapv1alpha1.ClusterCloudMapConfigSpec{NamespaceMapping: mapping},
+	}
+}
@@ -0,0 +1,124 @@
+package registry
+
+import (
+	"context"
+	"errors"
+	"github.com/aws/aws-cloud-map-mcs-controller-for-k8s/pkg/model"
+	"time"
+)
+
+// ErrNotSupported is returned for optional operations which the service registry doesn't implement.
+var ErrNotSupported = errors.New("not supported by the service registry")
+
+// ServiceRegistry registers the endpoints of the services exported by the cluster, and discovers the services of the
+// clusterset to import. The controllers only depend on ServiceRegistry, so registries other than AWS Cloud Map can be
+// plugged in. Services are identified by the name of their registry namespace and their name.
+type ServiceRegistry interface {
+	// ListServices returns all services and their endpoints for a given namespace.
+	ListServices(ctx context.Context, namespaceName string) ([]*model.Service, error)
+
+	// GetService returns a service and its endpoints, or nil if not found.
+	GetService(ctx context.Context, namespaceName string, serviceName string) (*model.Service, error)
+
+	// CreateService creates a service, and its namespace if necessary.
+	CreateService(ctx context.Context, namespaceName string, serviceName string) error
+
+	// DeleteService deletes a service without endpoints. Deleting a service which doesn't exist does nothing.
+	DeleteService(ctx context.Context, namespaceName string, serviceName string) error
+
+	// RegisterEndpoints registers new endpoints of a service, or updates the attributes of registered endpoints.
+	RegisterEndpoints(ctx context.Context, namespaceName string, serviceName string, endpoints []*model.Endpoint) error
+
+	// DeleteEndpoints de-registers endpoints of a service.
+	DeleteEndpoints(ctx context.Context, namespaceName string, serviceName string, endpoints []*model.Endpoint) error
+}
+
+// MetadataUpdater is implemented by service registries which keep a description and tags of services.
+type MetadataUpdater interface {
+	// UpdateServiceMetadata updates the description and adds the tags of a service.
+	UpdateServiceMetadata(ctx context.Context, namespaceName string, serviceName string, metadata model.ServiceMetadata) error
+}
+
+// EmptyServiceMarker is implemented by service registries which can mark services without endpoints.
+type EmptyServiceMarker interface {
+	// MarkServiceEmpty marks a service as empty, or removes the mark if not empty.
+	MarkServiceEmpty(ctx context.Context, namespaceName string, serviceName string, empty bool) error
+}
+
+// DnsConfigCorrector is implemented by service registries which publish the endpoints of services as DNS records.
+type DnsConfigCorrector interface {
+	// CorrectDnsConfig updates the DNS configuration of a service which differs from the desired record TTL, and
+	// returns the differences.
+	CorrectDnsConfig(ctx context.Context, namespaceName string, serviceName string, ttl int64) (model.DnsConfigDrift, error)
+}
+
+// NamespaceCollector is implemented by service registries which can delete the namespaces they created once empty.
+type NamespaceCollector interface {
+	// DeleteEmptyNamespaces deletes the namespaces created by the cluster which have had no services for the grace
+	// period, and returns their names.
+	DeleteEmptyNamespaces(ctx context.Context, gracePeriod time.Duration) ([]string, error)
+}
+
+// EndpointEvicter is implemented by service registries which cache the endpoints of services.
+type EndpointEvicter interface {
+	// EvictEndpoints drops the cached endpoints of a service, so they are fetched on the next lookup.
+	EvictEndpoints(namespaceName string, serviceName string)
+}
+
+// ReplicaCollector is implemented by service registries replicating endpoints to other service registries.
+type ReplicaCollector interface {
+	// DeleteClusterReplicas de-registers the replicated endpoints of the clusters from all services of a namespace.
+	DeleteClusterReplicas(ctx context.Context, namespaceName string, clusterIds []string) error
+}
+
+// UpdateServiceMetadata updates the metadata of a service if the registry keeps metadata, the metadata is dropped
+// otherwise.
+func UpdateServiceMetadata(ctx context.Context, registry ServiceRegistry, namespaceName string, serviceName string, metadata model.ServiceMetadata) error {
+	if updater, ok := registry.(MetadataUpdater); ok {
+		return updater.UpdateServiceMetadata(ctx, namespaceName, serviceName, metadata)
+	}
+	return nil
+}
+
+// MarkServiceEmpty marks a service as empty, or removes the mark, and returns ErrNotSupported if the registry can't
+// mark services.
+func MarkServiceEmpty(ctx context.Context, registry ServiceRegistry, namespaceName string, serviceName string, empty bool) error {
+	if marker, ok := registry.(EmptyServiceMarker); ok {
+		return marker.MarkServiceEmpty(ctx, namespaceName, serviceName, empty)
+	}
+	return ErrNotSupported
+}
+
+// CorrectDnsConfig corrects the DNS configuration of a service if the registry publishes DNS records, no drift is
+// reported otherwise.
+func CorrectDnsConfig(ctx context.Context, registry ServiceRegistry, namespaceName string, serviceName string, ttl int64) (model.DnsConfigDrift, error) {
+	if corrector, ok := registry.(DnsConfigCorrector); ok {
+		return corrector.CorrectDnsConfig(ctx, namespaceName, serviceName, ttl)
+	}
+	return model.DnsConfigDrift{}, nil
+}
+
+// DeleteEmptyNamespaces deletes the empty namespaces created by the cluster, and returns ErrNotSupported if the
+// registry can't delete namespaces.
+func DeleteEmptyNamespaces(ctx context.Context, registry ServiceRegistry, gracePeriod time.Duration) ([]string, error) {
+	if collector, ok := registry.(NamespaceCollector); ok {
+		return collector.DeleteEmptyNamespaces(ctx, gracePeriod)
+	}
+	return nil, ErrNotSupported
+}
+
+// EvictEndpoints drops the cached endpoints of a service if the registry caches endpoints.
+func EvictEndpoints(registry ServiceRegistry, namespaceName string, serviceName string) {
+	if evicter, ok := registry.(EndpointEvicter); ok {
+		evicter.EvictEndpoints(namespaceName, serviceName)
+	}
+}
+
+// DeleteClusterReplicas de-registers the replicated endpoints of the clusters from a namespace if the registry replicates
+// endpoints.
+func DeleteClusterReplicas(ctx context.Context, registry ServiceRegistry, namespaceName string, clusterIds []string) error {
+	if collector, ok := registry.(ReplicaCollector); ok {
+		return collector.DeleteClusterReplicas(ctx, namespaceName, clusterIds)
+	}
+	return nil
+}
@@ -0,0 +1,91 @@
+package registry
+
+import (
+	"context"
+	"errors"
+	"github.com/aws/aws-cloud-map-mcs-controller-for-k8s/pkg/model"
+	"github.com/stretchr/testify/assert"
+	"testing"
+	"time"
+)
+
+// minimalRegistry only implements the required operations of a ServiceRegistry.
+type minimalRegistry struct{}
+
+func (minimalRegistry) ListServices(context.Context, string) ([]*model.Service, error) {
+	return nil, nil
+}
+func (minimalRegistry) GetService(context.Context, string, string) (*model.Service, error) {
+	return nil, nil
+}
+func (minimalRegistry) CreateService(context.Context, string, string) error { return nil }
+func (minimalRegistry) DeleteService(context.Context, string, string) error { return nil }
+func (minimalRegistry) RegisterEndpoints(context.Context, string, string, []*model.Endpoint) error {
+	return nil
+}
+func (minimalRegistry) DeleteEndpoints(context.Context, string, string, []*model.Endpoint) error {
+	return nil
+}
+
+// fullRegistry also implements the optional operations, recording their calls.
+type fullRegistry struct {
+	minimalRegistry
+	calls []string
+}
+
+func (r *fullRegistry) UpdateServiceMetadata(context.Context, string, string, model.ServiceMetadata) error {
+	r.calls = append(r.calls, "UpdateServiceMetadata")
+	return nil
+}
+
+func (r *fullRegistry) MarkServiceEmpty(context.Context, string, string, bool) error {
+	r.calls = append(r.calls, "MarkServiceEmpty")
+	return nil
+}
+
+func (r *fullRegistry) CorrectDnsConfig(context.Context, string, string, int64) (model.DnsConfigDrift, error) {
+	r.calls = append(r.calls, "CorrectDnsConfig")
+	return model.DnsConfigDrift{}, nil
+}
+
+func (r *fullRegistry) DeleteEmptyNamespaces(context.Context, time.Duration) ([]string, error) {
+	r.calls = append(r.calls, "DeleteEmptyNamespaces")
+	return nil, nil
+}
+
+func (r *fullRegistry) EvictEndpoints(string, string) {
+	r.calls = append(r.calls, "EvictEndpoints")
+}
+
+func (r *fullRegistry) DeleteClusterReplicas(context.Context, string, []string) error {
+	r.calls = append(r.calls, "DeleteClusterReplicas")
+	return nil
+}
+
+func TestOptionalOperations(t *testing.T) {
+	full := &fullRegistry{}
+	assert.NoError(t, UpdateServiceMetadata(context.TODO(), full, "ns", "svc", model.ServiceMetadata{}))
+	assert.NoError(t, MarkServiceEmpty(context.TODO(), full, "ns", "svc", true))
+	_, err := CorrectDnsConfig(context.TODO(), full, "ns", "svc", 60)
+	assert.NoError(t, err)
+	_, err = DeleteEmptyNamespaces(context.TODO(), full, time.Hour)
+	assert.NoError(t, err)
+	EvictEndpoints(full, "ns", "svc")
+	assert.NoError(t, DeleteClusterReplicas(context.TODO(), full, "ns", []string{"cluster"}))
+	assert.Equal(t, []string{"UpdateServiceMetadata", "MarkServiceEmpty", "CorrectDnsConfig", "DeleteEmptyNamespaces",
+		"EvictEndpoints", "DeleteClusterReplicas"}, full.calls)
+}
+
+func TestOptionalOperations_NotSupported(t *testing.T) {
+	minimal := minimalRegistry{}
+	assert.NoError(t, UpdateServiceMetadata(context.TODO(), minimal, "ns", "svc", model.ServiceMetadata{}),
+		"the metadata is dropped")
+	assert.True(t, errors.Is(MarkServiceEmpty(context.TODO(), minimal, "ns", "svc", true), ErrNotSupported))
+	drift, err := CorrectDnsConfig(context.TODO(), minimal, "ns", "svc", 60)
+	assert.NoError(t, err)
+	assert.True(t, drift.IsEmpty())
+	_, err = DeleteEmptyNamespaces(context.TODO(), minimal, time.Hour)
+	assert.True(t, errors.Is(err, ErrNotSupported))
+	EvictEndpoints(minimal, "ns", "svc")
+	assert.NoError(t, DeleteClusterReplicas(context.TODO(), minimal, "ns", []string{"cluster"}))
+}
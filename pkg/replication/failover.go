@@ -0,0 +1,195 @@
+package replication
+
+import (
+	"context"
+	"github.com/aws/aws-cloud-map-mcs-controller-for-k8s/pkg/common"
+	"github.com/aws/aws-cloud-map-mcs-controller-for-k8s/pkg/metrics"
+	"github.com/aws/aws-cloud-map-mcs-controller-for-k8s/pkg/model"
+	"github.com/aws/aws-cloud-map-mcs-controller-for-k8s/pkg/registry"
+	"sync"
+	"time"
+)
+
+// DefaultRetryInterval is the default time a region failing a lookup is skipped by the lookups of a Failover.
+const DefaultRetryInterval = 30 * time.Second
+
+var (
+	_ registry.ServiceRegistry    = &Failover{}
+	_ registry.MetadataUpdater    = &Failover{}
+	_ registry.EmptyServiceMarker = &Failover{}
+	_ registry.DnsConfigCorrector = &Failover{}
+	_ registry.NamespaceCollector = &Failover{}
+	_ registry.EndpointEvicter    = &Failover{}
+)
+
+// Failover is a registry.ServiceRegistry looking up services in a prioritized list of regions: a lookup failing in a
+// region is retried in the next region, e.g. a replica region of the Registry when Cloud Map is unavailable in the
+// primary region. Regions failing a lookup are skipped for RetryInterval, so lookups don't wait for the timeouts of
+// an unavailable region. Changes are applied by the writer registry, regardless of the regions.
+type Failover struct {
+	log     common.Logger
+	writer  registry.ServiceRegistry
+	regions []Replica
+
+	// RetryInterval is the time a region failing a lookup is skipped, DefaultRetryInterval is used if not positive
+	RetryInterval time.Duration
+
+	mutex sync.Mutex
+	// unavailableUntil is the time until which each region failing a lookup is skipped
+	unavailableUntil map[string]time.Time
+	// serving is the region of the last successful lookup
+	serving string
+}
+
+// NewFailover creates a registry looking up services in the regions in the given order, and applying changes with
+// the writer registry.
+func NewFailover(writer registry.ServiceRegistry, regions []Replica) *Failover {
+	return &Failover{
+		log:              common.NewLogger("replication", "failover"),
+		writer:           writer,
+		regions:          regions,
+		unavailableUntil: make(map[string]time.Time),
+	}
+}
+
+// ListServices returns the services of the namespace in the first available region.
+func (f *Failover) ListServices(ctx context.Context, namespaceName string) (services []*model.Service, err error) {
+	err = f.lookup(ctx, func(lookupRegistry registry.ServiceRegistry) (lookupErr error) {
+		services, lookupErr = lookupRegistry.ListServices(ctx, namespaceName)
+		return lookupErr
+	})
+	return services, err
+}
+
+// GetService returns the service in the first available region.
+func (f *Failover) GetService(ctx context.Context, namespaceName string, serviceName string) (svc *model.Service, err error) {
+	err = f.lookup(ctx, func(lookupRegistry registry.ServiceRegistry) (lookupErr error) {
+		svc, lookupErr = lookupRegistry.GetService(ctx, namespaceName, serviceName)
+		return lookupErr
+	})
+	return svc, err
+}
+
+// CreateService creates the service with the writer registry.
+func (f *Failover) CreateService(ctx context.Context, namespaceName string, serviceName string) error {
+	return f.writer.CreateService(ctx, namespaceName, serviceName)
+}
+
+// DeleteService deletes the service with the writer registry.
+func (f *Failover) DeleteService(ctx context.Context, namespaceName string, serviceName string) error {
+	return f.writer.DeleteService(ctx, namespaceName, serviceName)
+}
+
+// RegisterEndpoints registers the endpoints with the writer registry.
+func (f *Failover) RegisterEndpoints(ctx context.Context, namespaceName string, serviceName string, endpoints []*model.Endpoint) error {
+	return f.writer.RegisterEndpoints(ctx, namespaceName, serviceName, endpoints)
+}
+
+// DeleteEndpoints de-registers the endpoints with the writer registry.
+func (f *Failover) DeleteEndpoints(ctx context.Context, namespaceName string, serviceName string, endpoints []*model.Endpoint) error {
+	return f.writer.DeleteEndpoints(ctx, namespaceName, serviceName, endpoints)
+}
+
+// UpdateServiceMetadata updates the metadata of the service with the writer registry.
+func (f *Failover) UpdateServiceMetadata(ctx context.Context, namespaceName string, serviceName string, metadata model.ServiceMetadata) error {
+	return registry.UpdateServiceMetadata(ctx, f.writer, namespaceName, serviceName, metadata)
+}
+
+// CorrectDnsConfig corrects the DNS configuration of the service with the writer registry.
+func (f *Failover) CorrectDnsConfig(ctx context.Context, namespaceName string, serviceName string, ttl int64) (model.DnsConfigDrift, error) {
+	return registry.CorrectDnsConfig(ctx, f.writer, namespaceName, serviceName, ttl)
+}
+
+// DeleteEmptyNamespaces deletes the empty namespaces with the writer registry.
+func (f *Failover) DeleteEmptyNamespaces(ctx context.Context, gracePeriod time.Duration) ([]string, error) {
+	return registry.DeleteEmptyNamespaces(ctx, f.writer, gracePeriod)
+}
+
+// MarkServiceEmpty marks the service with the writer registry.
+func (f *Failover) MarkServiceEmpty(ctx context.Context, namespaceName string, serviceName string, empty bool) error {
+	return registry.MarkServiceEmpty(ctx, f.writer, namespaceName, serviceName, empty)
+}
+
+// EvictEndpoints drops the cached endpoints of the service of the writer registry and of all regions.
+func (f *Failover) EvictEndpoints(namespaceName string, serviceName string) {
+	registry.EvictEndpoints(f.writer, namespaceName, serviceName)
+	for _, region := range f.regions {
+		registry.EvictEndpoints(region.Registry, namespaceName, serviceName)
+	}
+}
+
+// ServingRegion returns the region of the last successful lookup, and whether it isn't the first region.
+func (f *Failover) ServingRegion() (region string, failover bool) {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+	return f.serving, len(f.regions) > 0 && f.serving != f.regions[0].Region
+}
+
+// lookup runs the lookup in the available regions in order, until it succeeds. All regions are tried if none is
+// available. The error of the first region tried is returned if the lookup fails in all regions.
+func (f *Failover) lookup(ctx context.Context, lookup func(lookupRegistry registry.ServiceRegistry) error) error {
+	var firstErr error
+	for _, region := range f.availableRegions() {
+		err := lookup(region.Registry)
+		if err == nil {
+			f.setServing(region.Region)
+			return nil
+		}
+		if ctx.Err() != nil {
+			return err
+		}
+		if firstErr == nil {
+			firstErr = err
+		}
+		f.setUnavailable(region.Region, err)
+	}
+	return firstErr
+}
+
+// availableRegions returns the regions which didn't fail a lookup within the retry interval, all regions if none.
+func (f *Failover) availableRegions() []Replica {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+	now := time.Now()
+	available := make([]Replica, 0, len(f.regions))
+	for _, region := range f.regions {
+		if now.After(f.unavailableUntil[region.Region]) {
+			available = append(available, region)
+		}
+	}
+	if len(available) == 0 {
+		return f.regions
+	}
+	return available
+}
+
+func (f *Failover) setUnavailable(region string, err error) {
+	retryInterval := f.RetryInterval
+	if retryInterval <= 0 {
+		retryInterval = DefaultRetryInterval
+	}
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+	f.unavailableUntil[region] = time.Now().Add(retryInterval)
+	f.log.Error(err, "lookup failed, trying the next region", "region", region,
+		"retryAfter", retryInterval.String())
+}
+
+func (f *Failover) setServing(region string) {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+	if region == f.serving {
+		return
+	}
+	failover := len(f.regions) > 0 && region != f.regions[0].Region
+	if failover {
+		f.log.Info("looking up services in a failover region", "region", region)
+	} else if f.serving != "" {
+		f.log.Info("looking up services in the preferred region again", "region", region)
+	}
+	if f.serving != "" {
+		metrics.SetImportFailover(f.serving, false)
+	}
+	metrics.SetImportFailover(region, failover)
+	f.serving = region
+}
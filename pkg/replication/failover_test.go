@@ -0,0 +1,79 @@
+package replication
+
+import (
+	"context"
+	"github.com/aws/aws-cloud-map-mcs-controller-for-k8s/pkg/model"
+	"github.com/stretchr/testify/assert"
+	"testing"
+	"time"
+)
+
+func TestFailover_GetService(t *testing.T) {
+	primary, replica := newMemoryRegistry(), newMemoryRegistry()
+	for _, r := range []*memoryRegistry{primary, replica} {
+		assert.NoError(t, r.CreateService(context.TODO(), nsName, svcName))
+		assert.NoError(t, r.RegisterEndpoints(context.TODO(), nsName, svcName,
+			[]*model.Endpoint{testEndpoint("owned", clusterId)}))
+	}
+	failover := NewFailover(primary, []Replica{
+		{Region: primaryRegion, Registry: primary},
+		{Region: replicaRegion, Registry: replica},
+	})
+
+	svc, err := failover.GetService(context.TODO(), nsName, svcName)
+	assert.NoError(t, err)
+	assert.Len(t, svc.Endpoints, 1)
+	region, failedOver := failover.ServingRegion()
+	assert.Equal(t, primaryRegion, region)
+	assert.False(t, failedOver)
+
+	primary.unavailable = true
+	svc, err = failover.GetService(context.TODO(), nsName, svcName)
+	assert.NoError(t, err, "the lookup falls back to the replica region")
+	assert.Len(t, svc.Endpoints, 1)
+	region, failedOver = failover.ServingRegion()
+	assert.Equal(t, replicaRegion, region)
+	assert.True(t, failedOver)
+
+	// the primary region is skipped until the retry interval passed
+	primary.unavailable = false
+	services, err := failover.ListServices(context.TODO(), nsName)
+	assert.NoError(t, err)
+	assert.Len(t, services, 1)
+	region, _ = failover.ServingRegion()
+	assert.Equal(t, replicaRegion, region)
+
+	failover.unavailableUntil[primaryRegion] = time.Now().Add(-time.Second)
+	_, err = failover.GetService(context.TODO(), nsName, svcName)
+	assert.NoError(t, err)
+	region, failedOver = failover.ServingRegion()
+	assert.Equal(t, primaryRegion, region)
+	assert.False(t, failedOver)
+}
+
+func TestFailover_AllRegionsUnavailable(t *testing.T) {
+	primary, replica := newMemoryRegistry(), newMemoryRegistry()
+	primary.unavailable, replica.unavailable = true, true
+	failover := NewFailover(primary, []Replica{
+		{Region: primaryRegion, Registry: primary},
+		{Region: replicaRegion, Registry: replica},
+	})
+
+	_, err := failover.GetService(context.TODO(), nsName, svcName)
+	assert.Error(t, err)
+	// all regions are tried again while all are unavailable
+	replica.unavailable = false
+	_, err = failover.GetService(context.TODO(), nsName, svcName)
+	assert.NoError(t, err)
+}
+
+func TestFailover_WritesToWriter(t *testing.T) {
+	writer, replica := newMemoryRegistry(), newMemoryRegistry()
+	failover := NewFailover(writer, []Replica{{Region: replicaRegion, Registry: replica}})
+
+	assert.NoError(t, failover.CreateService(context.TODO(), nsName, svcName))
+	svc, _ := writer.GetService(context.TODO(), nsName, svcName)
+	assert.NotNil(t, svc)
+	svc, _ = replica.GetService(context.TODO(), nsName, svcName)
+	assert.Nil(t, svc)
+}
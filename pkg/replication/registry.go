@@ -0,0 +1,366 @@
+// Package replication mirrors the registrations of the cluster to the Cloud Map namespaces of secondary regions, so
+// clients in those regions discover the services of the cluster locally, and keep discovering them during an outage
+// of Cloud Map in the primary region.
+package replication
+
+import (
+	"context"
+	"github.com/aws/aws-cloud-map-mcs-controller-for-k8s/pkg/common"
+	"github.com/aws/aws-cloud-map-mcs-controller-for-k8s/pkg/model"
+	"github.com/aws/aws-cloud-map-mcs-controller-for-k8s/pkg/registry"
+	"sync"
+	"time"
+)
+
+const (
+	// SourceRegionAttr is the attribute of replicated instances holding the region of the original registration
+	SourceRegionAttr = "SOURCE_REGION"
+
+	// DefaultResyncInterval is the default interval of the resync of replicas which failed to replicate a change
+	DefaultResyncInterval = time.Minute
+
+	// clusterIdAttr is the endpoint attribute identifying the exporting cluster, see controllers.ClusterIdAttr
+	clusterIdAttr = "CLUSTER_ID"
+)
+
+var (
+	_ registry.ServiceRegistry    = &Registry{}
+	_ registry.MetadataUpdater    = &Registry{}
+	_ registry.EmptyServiceMarker = &Registry{}
+	_ registry.DnsConfigCorrector = &Registry{}
+	_ registry.NamespaceCollector = &Registry{}
+	_ registry.EndpointEvicter    = &Registry{}
+	_ registry.ReplicaCollector   = &Registry{}
+)
+
+// Replica is the service registry of a secondary region.
+type Replica struct {
+	Region   string
+	Registry registry.ServiceRegistry
+}
+
+// Registry is a registry.ServiceRegistry registering endpoints in the service registry of the primary region, and
+// replicating the endpoints of the cluster to the replicas with the SourceRegionAttr attribute. Services are read from
+// the primary region only.
+//
+// Changes fail if they fail in the primary region. Replicas failing a change are logged and resynced from the primary
+// region by Start, so an outage of a secondary region doesn't fail exports.
+type Registry struct {
+	log           common.Logger
+	primary       registry.ServiceRegistry
+	primaryRegion string
+	replicas      []Replica
+	clusterId     string
+
+	// ResyncInterval is the interval of the resync of failed replicas, DefaultResyncInterval is used if not positive
+	ResyncInterval time.Duration
+
+	mutex sync.Mutex
+	// pending are the services failed to replicate, by replica region
+	pending map[string]map[serviceKey]bool
+}
+
+// serviceKey identifies a service by its namespace and name.
+type serviceKey struct {
+	namespaceName string
+	serviceName   string
+}
+
+// NewRegistry creates a registry replicating the endpoints of the cluster from the primary registry to the replicas.
+func NewRegistry(primary registry.ServiceRegistry, primaryRegion string, replicas []Replica, clusterId string) *Registry {
+	return &Registry{
+		log:           common.NewLogger("replication"),
+		primary:       primary,
+		primaryRegion: primaryRegion,
+		replicas:      replicas,
+		clusterId:     clusterId,
+		pending:       make(map[string]map[serviceKey]bool),
+	}
+}
+
+// ListServices returns the services of the namespace in the primary region.
+func (r *Registry) ListServices(ctx context.Context, namespaceName string) ([]*model.Service, error) {
+	return r.primary.ListServices(ctx, namespaceName)
+}
+
+// GetService returns the service in the primary region.
+func (r *Registry) GetService(ctx context.Context, namespaceName string, serviceName string) (*model.Service, error) {
+	return r.primary.GetService(ctx, namespaceName, serviceName)
+}
+
+// CreateService creates the service in the primary region and the replicas.
+func (r *Registry) CreateService(ctx context.Context, namespaceName string, serviceName string) error {
+	if err := r.primary.CreateService(ctx, namespaceName, serviceName); err != nil {
+		return err
+	}
+	r.replicate(namespaceName, serviceName, "create service", func(replica Replica) error {
+		return replica.Registry.CreateService(ctx, namespaceName, serviceName)
+	})
+	return nil
+}
+
+// DeleteService deletes the service in the primary region and the replicas. Replicas holding the endpoints of other
+// clusters keep the service.
+func (r *Registry) DeleteService(ctx context.Context, namespaceName string, serviceName string) error {
+	if err := r.primary.DeleteService(ctx, namespaceName, serviceName); err != nil {
+		return err
+	}
+	for _, replica := range r.replicas {
+		if err := replica.Registry.DeleteService(ctx, namespaceName, serviceName); err != nil {
+			r.log.Info("unable to delete the replicated service", "region", replica.Region,
+				"namespace", namespaceName, "name", serviceName, "error", err.Error())
+		}
+	}
+	return nil
+}
+
+// RegisterEndpoints registers the endpoints in the primary region and the replicas.
+func (r *Registry) RegisterEndpoints(ctx context.Context, namespaceName string, serviceName string, endpoints []*model.Endpoint) error {
+	if err := r.primary.RegisterEndpoints(ctx, namespaceName, serviceName, endpoints); err != nil {
+		return err
+	}
+	replicated := r.replicatedEndpoints(endpoints)
+	if len(replicated) == 0 {
+		return nil
+	}
+	r.replicate(namespaceName, serviceName, "register endpoints", func(replica Replica) error {
+		return replica.Registry.RegisterEndpoints(ctx, namespaceName, serviceName, replicated)
+	})
+	return nil
+}
+
+// DeleteEndpoints de-registers the endpoints in the primary region and the replicas.
+func (r *Registry) DeleteEndpoints(ctx context.Context, namespaceName string, serviceName string, endpoints []*model.Endpoint) error {
+	if err := r.primary.DeleteEndpoints(ctx, namespaceName, serviceName, endpoints); err != nil {
+		return err
+	}
+	replicated := r.replicatedEndpoints(endpoints)
+	if len(replicated) == 0 {
+		return nil
+	}
+	r.replicate(namespaceName, serviceName, "de-register endpoints", func(replica Replica) error {
+		return replica.Registry.DeleteEndpoints(ctx, namespaceName, serviceName, replicated)
+	})
+	return nil
+}
+
+// UpdateServiceMetadata updates the metadata of the service in the primary region and the replicas.
+func (r *Registry) UpdateServiceMetadata(ctx context.Context, namespaceName string, serviceName string, metadata model.ServiceMetadata) error {
+	if err := registry.UpdateServiceMetadata(ctx, r.primary, namespaceName, serviceName, metadata); err != nil {
+		return err
+	}
+	for _, replica := range r.replicas {
+		if err := registry.UpdateServiceMetadata(ctx, replica.Registry, namespaceName, serviceName, metadata); err != nil {
+			r.log.Info("unable to update the metadata of the replicated service", "region", replica.Region,
+				"namespace", namespaceName, "name", serviceName, "error", err.Error())
+		}
+	}
+	return nil
+}
+
+// CorrectDnsConfig corrects the DNS configuration of the service in the primary region and the replicas, and returns
+// the drift of the primary region.
+func (r *Registry) CorrectDnsConfig(ctx context.Context, namespaceName string, serviceName string, ttl int64) (model.DnsConfigDrift, error) {
+	drift, err := registry.CorrectDnsConfig(ctx, r.primary, namespaceName, serviceName, ttl)
+	if err != nil {
+		return drift, err
+	}
+	for _, replica := range r.replicas {
+		if _, err := registry.CorrectDnsConfig(ctx, replica.Registry, namespaceName, serviceName, ttl); err != nil {
+			r.log.Info("unable to correct the DNS configuration of the replicated service", "region", replica.Region,
+				"namespace", namespaceName, "name", serviceName, "error", err.Error())
+		}
+	}
+	return drift, nil
+}
+
+// DeleteEmptyNamespaces deletes the empty namespaces in the primary region and the replicas, and returns the namespaces
+// deleted in the primary region.
+func (r *Registry) DeleteEmptyNamespaces(ctx context.Context, gracePeriod time.Duration) ([]string, error) {
+	deleted, err := registry.DeleteEmptyNamespaces(ctx, r.primary, gracePeriod)
+	if err != nil {
+		return deleted, err
+	}
+	for _, replica := range r.replicas {
+		if _, err := registry.DeleteEmptyNamespaces(ctx, replica.Registry, gracePeriod); err != nil {
+			r.log.Info("unable to delete the empty namespaces of the replica", "region", replica.Region,
+				"error", err.Error())
+		}
+	}
+	return deleted, nil
+}
+
+// MarkServiceEmpty marks the service in the primary region. The replicas aren't marked, they may hold the endpoints of
+// other clusters.
+func (r *Registry) MarkServiceEmpty(ctx context.Context, namespaceName string, serviceName string, empty bool) error {
+	return registry.MarkServiceEmpty(ctx, r.primary, namespaceName, serviceName, empty)
+}
+
+// EvictEndpoints drops the cached endpoints of the service in the primary region and the replicas.
+func (r *Registry) EvictEndpoints(namespaceName string, serviceName string) {
+	registry.EvictEndpoints(r.primary, namespaceName, serviceName)
+	for _, replica := range r.replicas {
+		registry.EvictEndpoints(replica.Registry, namespaceName, serviceName)
+	}
+}
+
+// DeleteClusterReplicas de-registers the endpoints of the clusters from the services of the namespace in the replicas,
+// whatever their source region. Unlike other changes, a replica failing the change fails it, so the caller retries
+// until the endpoints of the clusters are gone from all regions.
+func (r *Registry) DeleteClusterReplicas(ctx context.Context, namespaceName string, clusterIds []string) error {
+	clusters := make(map[string]bool, len(clusterIds))
+	for _, id := range clusterIds {
+		clusters[id] = true
+	}
+	for _, replica := range r.replicas {
+		services, err := replica.Registry.ListServices(ctx, namespaceName)
+		if err != nil {
+			return err
+		}
+		for _, svc := range services {
+			stale := make([]*model.Endpoint, 0)
+			for _, endpoint := range svc.Endpoints {
+				if clusters[endpoint.Attributes[clusterIdAttr]] {
+					stale = append(stale, endpoint)
+				}
+			}
+			if len(stale) == 0 {
+				continue
+			}
+			if err = replica.Registry.DeleteEndpoints(ctx, namespaceName, svc.Name, stale); err != nil {
+				return err
+			}
+			r.log.Info("de-registered the replicated instances of clusters", "region", replica.Region,
+				"namespace", namespaceName, "name", svc.Name, "instances", len(stale))
+		}
+	}
+	return nil
+}
+
+// Start resyncs the services which failed to replicate every ResyncInterval, until the context is done.
+func (r *Registry) Start(ctx context.Context) error {
+	interval := r.ResyncInterval
+	if interval <= 0 {
+		interval = DefaultResyncInterval
+	}
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+			r.Resync(ctx)
+		}
+	}
+}
+
+// Resync replicates the current endpoints of the cluster in the primary region to the replicas which failed to
+// replicate a change of the service, registering the missing endpoints and de-registering the stale ones.
+func (r *Registry) Resync(ctx context.Context) {
+	for _, replica := range r.replicas {
+		for key := range r.pendingServices(replica.Region) {
+			if err := r.resync(ctx, replica, key); err != nil {
+				r.log.Info("unable to resync the replicated service", "region", replica.Region,
+					"namespace", key.namespaceName, "name", key.serviceName, "error", err.Error())
+				continue
+			}
+			r.mutex.Lock()
+			delete(r.pending[replica.Region], key)
+			r.mutex.Unlock()
+		}
+	}
+}
+
+func (r *Registry) resync(ctx context.Context, replica Replica, key serviceKey) error {
+	desired := make([]*model.Endpoint, 0)
+	svc, err := r.primary.GetService(ctx, key.namespaceName, key.serviceName)
+	if err != nil {
+		return err
+	}
+	if svc != nil {
+		desired = r.replicatedEndpoints(svc.Endpoints)
+	}
+
+	current := make([]*model.Endpoint, 0)
+	replicaSvc, err := replica.Registry.GetService(ctx, key.namespaceName, key.serviceName)
+	if err != nil {
+		return err
+	}
+	if replicaSvc == nil && len(desired) > 0 {
+		if err = replica.Registry.CreateService(ctx, key.namespaceName, key.serviceName); err != nil {
+			return err
+		}
+	}
+	if replicaSvc != nil {
+		for _, endpoint := range replicaSvc.Endpoints {
+			if endpoint.Attributes[clusterIdAttr] == r.clusterId &&
+				endpoint.Attributes[SourceRegionAttr] == r.primaryRegion {
+				current = append(current, endpoint)
+			}
+		}
+	}
+
+	changes := (&model.Plan{Current: current, Desired: desired}).CalculateChanges()
+	if changes.HasUpdates() {
+		upserts := append(changes.Create, changes.Update...)
+		if err = replica.Registry.RegisterEndpoints(ctx, key.namespaceName, key.serviceName, upserts); err != nil {
+			return err
+		}
+	}
+	if changes.HasDeletes() {
+		if err = replica.Registry.DeleteEndpoints(ctx, key.namespaceName, key.serviceName, changes.Delete); err != nil {
+			return err
+		}
+	}
+	r.log.Info("resynced the replicated service", "region", replica.Region,
+		"namespace", key.namespaceName, "name", key.serviceName,
+		"registered", len(changes.Create)+len(changes.Update), "deregistered", len(changes.Delete))
+	return nil
+}
+
+// replicate applies a change to each replica, the services of the replicas failing the change are resynced later.
+func (r *Registry) replicate(namespaceName string, serviceName string, change string, apply func(replica Replica) error) {
+	for _, replica := range r.replicas {
+		if err := apply(replica); err != nil {
+			r.log.Error(err, "unable to replicate, resyncing later", "change", change, "region", replica.Region,
+				"namespace", namespaceName, "name", serviceName)
+			r.mutex.Lock()
+			if r.pending[replica.Region] == nil {
+				r.pending[replica.Region] = make(map[serviceKey]bool)
+			}
+			r.pending[replica.Region][serviceKey{namespaceName: namespaceName, serviceName: serviceName}] = true
+			r.mutex.Unlock()
+		}
+	}
+}
+
+// pendingServices returns a copy of the services of the replica which failed to replicate.
+func (r *Registry) pendingServices(region string) map[serviceKey]bool {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	pending := make(map[serviceKey]bool, len(r.pending[region]))
+	for key := range r.pending[region] {
+		pending[key] = true
+	}
+	return pending
+}
+
+// replicatedEndpoints returns copies of the endpoints registered by the cluster in the primary region, with the
+// attribute of the source region.
+func (r *Registry) replicatedEndpoints(endpoints []*model.Endpoint) []*model.Endpoint {
+	replicated := make([]*model.Endpoint, 0, len(endpoints))
+	for _, endpoint := range endpoints {
+		if endpoint.Attributes[clusterIdAttr] != r.clusterId || endpoint.Attributes[SourceRegionAttr] != "" {
+			continue
+		}
+		replica := *endpoint
+		replica.Attributes = make(map[string]string, len(endpoint.Attributes)+1)
+		for key, value := range endpoint.Attributes {
+			replica.Attributes[key] = value
+		}
+		replica.Attributes[SourceRegionAttr] = r.primaryRegion
+		replicated = append(replicated, &replica)
+	}
+	return replicated
+}
@@ -0,0 +1,188 @@
+package replication
+
+import (
+	"context"
+	"errors"
+	"github.com/aws/aws-cloud-map-mcs-controller-for-k8s/pkg/model"
+	"github.com/stretchr/testify/assert"
+	"strings"
+	"testing"
+)
+
+const (
+	clusterId     = "cluster-1"
+	primaryRegion = "us-west-2"
+	replicaRegion = "us-east-1"
+	nsName        = "ns"
+	svcName       = "svc"
+)
+
+// memoryRegistry keeps the endpoints of services in memory, failing all calls while unavailable.
+type memoryRegistry struct {
+	services    map[string]map[string]*model.Endpoint
+	unavailable bool
+}
+
+func newMemoryRegistry() *memoryRegistry {
+	return &memoryRegistry{services: make(map[string]map[string]*model.Endpoint)}
+}
+
+func (m *memoryRegistry) ListServices(ctx context.Context, namespaceName string) ([]*model.Service, error) {
+	if m.unavailable {
+		return nil, errors.New("unavailable")
+	}
+	services := make([]*model.Service, 0)
+	for key := range m.services {
+		if parts := strings.SplitN(key, "/", 2); parts[0] == namespaceName {
+			svc, _ := m.GetService(ctx, namespaceName, parts[1])
+			services = append(services, svc)
+		}
+	}
+	return services, nil
+}
+
+func (m *memoryRegistry) GetService(_ context.Context, namespaceName string, serviceName string) (*model.Service, error) {
+	if m.unavailable {
+		return nil, errors.New("unavailable")
+	}
+	endpoints, found := m.services[namespaceName+"/"+serviceName]
+	if !found {
+		return nil, nil
+	}
+	svc := &model.Service{Namespace: namespaceName, Name: serviceName, Endpoints: []*model.Endpoint{}}
+	for _, endpoint := range endpoints {
+		svc.Endpoints = append(svc.Endpoints, endpoint)
+	}
+	return svc, nil
+}
+
+func (m *memoryRegistry) CreateService(_ context.Context, namespaceName string, serviceName string) error {
+	if m.unavailable {
+		return errors.New("unavailable")
+	}
+	if _, found := m.services[namespaceName+"/"+serviceName]; !found {
+		m.services[namespaceName+"/"+serviceName] = make(map[string]*model.Endpoint)
+	}
+	return nil
+}
+
+func (m *memoryRegistry) DeleteService(_ context.Context, namespaceName string, serviceName string) error {
+	if m.unavailable {
+		return errors.New("unavailable")
+	}
+	delete(m.services, namespaceName+"/"+serviceName)
+	return nil
+}
+
+func (m *memoryRegistry) RegisterEndpoints(_ context.Context, namespaceName string, serviceName string, endpoints []*model.Endpoint) error {
+	svc, found := m.services[namespaceName+"/"+serviceName]
+	if m.unavailable || !found {
+		return errors.New("unavailable")
+	}
+	for _, endpoint := range endpoints {
+		svc[endpoint.Id] = endpoint
+	}
+	return nil
+}
+
+func (m *memoryRegistry) DeleteEndpoints(_ context.Context, namespaceName string, serviceName string, endpoints []*model.Endpoint) error {
+	svc, found := m.services[namespaceName+"/"+serviceName]
+	if m.unavailable || !found {
+		return errors.New("unavailable")
+	}
+	for _, endpoint := range endpoints {
+		delete(svc, endpoint.Id)
+	}
+	return nil
+}
+
+func TestRegistry_ReplicatesEndpoints(t *testing.T) {
+	primary, replica := newMemoryRegistry(), newMemoryRegistry()
+	r := NewRegistry(primary, primaryRegion, []Replica{{Region: replicaRegion, Registry: replica}}, clusterId)
+
+	owned := testEndpoint("owned", clusterId)
+	other := testEndpoint("other", "cluster-2")
+	assert.NoError(t, r.CreateService(context.TODO(), nsName, svcName))
+	assert.NoError(t, r.RegisterEndpoints(context.TODO(), nsName, svcName, []*model.Endpoint{owned, other}))
+
+	replicated, _ := replica.GetService(context.TODO(), nsName, svcName)
+	assert.Len(t, replicated.Endpoints, 1, "only the endpoints of the cluster are replicated")
+	assert.Equal(t, owned.Id, replicated.Endpoints[0].Id)
+	assert.Equal(t, primaryRegion, replicated.Endpoints[0].Attributes[SourceRegionAttr])
+	assert.Empty(t, owned.Attributes[SourceRegionAttr], "the registered endpoints are unchanged")
+
+	assert.NoError(t, r.DeleteEndpoints(context.TODO(), nsName, svcName, []*model.Endpoint{owned}))
+	replicated, _ = replica.GetService(context.TODO(), nsName, svcName)
+	assert.Empty(t, replicated.Endpoints)
+}
+
+func TestRegistry_ResyncsFailedReplicas(t *testing.T) {
+	primary, replica := newMemoryRegistry(), newMemoryRegistry()
+	r := NewRegistry(primary, primaryRegion, []Replica{{Region: replicaRegion, Registry: replica}}, clusterId)
+
+	stale := testEndpoint("stale", clusterId)
+	stale.Attributes[SourceRegionAttr] = primaryRegion
+	otherRegion := testEndpoint("other-region", "cluster-2")
+	otherRegion.Attributes[SourceRegionAttr] = "eu-west-1"
+	assert.NoError(t, replica.CreateService(context.TODO(), nsName, svcName))
+	assert.NoError(t, replica.RegisterEndpoints(context.TODO(), nsName, svcName, []*model.Endpoint{stale, otherRegion}))
+
+	// the replica is unavailable, the change succeeds in the primary region
+	replica.unavailable = true
+	owned := testEndpoint("owned", clusterId)
+	assert.NoError(t, r.CreateService(context.TODO(), nsName, svcName))
+	assert.NoError(t, r.RegisterEndpoints(context.TODO(), nsName, svcName, []*model.Endpoint{owned}))
+	assert.Len(t, r.pendingServices(replicaRegion), 1)
+
+	replica.unavailable = false
+	r.Resync(context.TODO())
+	assert.Empty(t, r.pendingServices(replicaRegion))
+
+	replicated, _ := replica.GetService(context.TODO(), nsName, svcName)
+	ids := make([]string, 0)
+	for _, endpoint := range replicated.Endpoints {
+		ids = append(ids, endpoint.Id)
+	}
+	assert.ElementsMatch(t, []string{"owned", "other-region"}, ids)
+}
+
+func TestRegistry_DeleteClusterReplicas(t *testing.T) {
+	primary, replica := newMemoryRegistry(), newMemoryRegistry()
+	r := NewRegistry(primary, primaryRegion, []Replica{{Region: replicaRegion, Registry: replica}}, clusterId)
+
+	dead := testEndpoint("dead", "cluster-2")
+	dead.Attributes[SourceRegionAttr] = "eu-west-1"
+	live := testEndpoint("live", "cluster-3")
+	live.Attributes[SourceRegionAttr] = "eu-west-1"
+	assert.NoError(t, replica.CreateService(context.TODO(), nsName, svcName))
+	assert.NoError(t, replica.RegisterEndpoints(context.TODO(), nsName, svcName, []*model.Endpoint{dead, live}))
+
+	assert.NoError(t, r.DeleteClusterReplicas(context.TODO(), nsName, []string{"cluster-2"}))
+	replicated, _ := replica.GetService(context.TODO(), nsName, svcName)
+	assert.Len(t, replicated.Endpoints, 1)
+	assert.Equal(t, live.Id, replicated.Endpoints[0].Id)
+
+	replica.unavailable = true
+	assert.Error(t, r.DeleteClusterReplicas(context.TODO(), nsName, []string{"cluster-3"}),
+		"the caller retries until the replicas are collected")
+}
+
+func TestRegistry_PrimaryFailure(t *testing.T) {
+	primary, replica := newMemoryRegistry(), newMemoryRegistry()
+	r := NewRegistry(primary, primaryRegion, []Replica{{Region: replicaRegion, Registry: replica}}, clusterId)
+
+	primary.unavailable = true
+	assert.Error(t, r.CreateService(context.TODO(), nsName, svcName))
+	svc, _ := replica.GetService(context.TODO(), nsName, svcName)
+	assert.Nil(t, svc, "changes failing in the primary region aren't replicated")
+}
+
+func testEndpoint(id string, endpointClusterId string) *model.Endpoint {
+	return &model.Endpoint{
+		Id:           id,
+		IP:           "10.0.0.1",
+		EndpointPort: model.Port{Port: 80, Protocol: model.TCPProtocol},
+		ServicePort:  model.Port{Port: 80, Protocol: model.TCPProtocol},
+		Attributes:   map[string]string{clusterIdAttr: endpointClusterId},
+	}
+}
@@ -0,0 +1,29 @@
+package route53
+
+import (
+	"context"
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/route53"
+)
+
+// AwsFacade wraps the minimal surface area of Route53 API calls required to manage the records of exported services.
+// This enables mock generation for unit testing.
+type AwsFacade interface {
+	// GetHostedZone provides Route53 GetHostedZone wrapper interface.
+	GetHostedZone(context.Context, *route53.GetHostedZoneInput, ...func(*route53.Options)) (*route53.GetHostedZoneOutput, error)
+
+	// ListResourceRecordSets provides Route53 ListResourceRecordSets wrapper interface.
+	ListResourceRecordSets(context.Context, *route53.ListResourceRecordSetsInput, ...func(*route53.Options)) (*route53.ListResourceRecordSetsOutput, error)
+
+	// ChangeResourceRecordSets provides Route53 ChangeResourceRecordSets wrapper interface.
+	ChangeResourceRecordSets(context.Context, *route53.ChangeResourceRecordSetsInput, ...func(*route53.Options)) (*route53.ChangeResourceRecordSetsOutput, error)
+}
+
+type awsFacade struct {
+	*route53.Client
+}
+
+// NewAwsFacadeFromConfig creates a new Route53 facade from an AWS client config.
+func NewAwsFacadeFromConfig(cfg *aws.Config) AwsFacade {
+	return &awsFacade{route53.NewFromConfig(*cfg)}
+}
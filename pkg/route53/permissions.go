@@ -0,0 +1,45 @@
+package route53
+
+import (
+	"context"
+	"github.com/aws/aws-cloud-map-mcs-controller-for-k8s/pkg/cloudmap"
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/route53"
+	"github.com/aws/aws-sdk-go-v2/service/route53/types"
+)
+
+const (
+	// probeZoneId is a syntactically valid ID of a hosted zone which doesn't exist
+	probeZoneId = "Z00000000000000000000"
+	probeName   = "cloudmap-mcs-permission-check.example."
+)
+
+// CheckPermissions checks whether the credentials of the AWS facade are authorized for the Route 53 actions of DNS
+// namespaces and of the Route 53 registry, without modifying Route 53: the requests reference a hosted zone which
+// doesn't exist, and fail with NoSuchHostedZone if authorized.
+func CheckPermissions(ctx context.Context, facade AwsFacade) []cloudmap.PermissionCheck {
+	_, err := facade.GetHostedZone(ctx, &route53.GetHostedZoneInput{Id: aws.String(probeZoneId)})
+	getHostedZone := cloudmap.NewPermissionCheck("route53:GetHostedZone", err, "NoSuchHostedZone")
+
+	_, err = facade.ListResourceRecordSets(ctx, &route53.ListResourceRecordSetsInput{
+		HostedZoneId: aws.String(probeZoneId),
+		MaxItems:     aws.Int32(1),
+	})
+	listRecordSets := cloudmap.NewPermissionCheck("route53:ListResourceRecordSets", err, "NoSuchHostedZone")
+
+	_, err = facade.ChangeResourceRecordSets(ctx, &route53.ChangeResourceRecordSetsInput{
+		HostedZoneId: aws.String(probeZoneId),
+		ChangeBatch: &types.ChangeBatch{Changes: []types.Change{{
+			Action: types.ChangeActionUpsert,
+			ResourceRecordSet: &types.ResourceRecordSet{
+				Name:            aws.String(probeName),
+				Type:            types.RRTypeTxt,
+				TTL:             aws.Int64(60),
+				ResourceRecords: []types.ResourceRecord{{Value: aws.String(`"probe"`)}},
+			},
+		}}},
+	})
+	changeRecordSets := cloudmap.NewPermissionCheck("route53:ChangeResourceRecordSets", err, "NoSuchHostedZone")
+
+	return []cloudmap.PermissionCheck{getHostedZone, listRecordSets, changeRecordSets}
+}
@@ -0,0 +1,42 @@
+package route53
+
+import (
+	"context"
+	"github.com/aws/aws-cloud-map-mcs-controller-for-k8s/mocks/pkg/route53"
+	"github.com/aws/aws-cloud-map-mcs-controller-for-k8s/pkg/cloudmap"
+	"github.com/aws/smithy-go"
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func TestCheckPermissions(t *testing.T) {
+	tests := []struct {
+		name   string
+		code   string
+		result string
+	}{
+		{name: "authorized", code: "NoSuchHostedZone", result: cloudmap.PermissionGranted},
+		{name: "denied", code: "AccessDenied", result: cloudmap.PermissionDenied},
+		{name: "unexpected error", code: "InvalidInput", result: cloudmap.PermissionUnknown},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockController := gomock.NewController(t)
+			defer mockController.Finish()
+
+			err := &smithy.GenericAPIError{Code: tt.code}
+			facade := route53.NewMockAwsFacade(mockController)
+			facade.EXPECT().GetHostedZone(gomock.Any(), gomock.Any()).Return(nil, err)
+			facade.EXPECT().ListResourceRecordSets(gomock.Any(), gomock.Any()).Return(nil, err)
+			facade.EXPECT().ChangeResourceRecordSets(gomock.Any(), gomock.Any()).Return(nil, err)
+
+			checks := CheckPermissions(context.TODO(), facade)
+			assert.Len(t, checks, 3)
+			for _, check := range checks {
+				assert.Equal(t, tt.result, check.Result, check.Action)
+				assert.Equal(t, tt.code, check.Detail)
+			}
+		})
+	}
+}
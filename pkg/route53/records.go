@@ -0,0 +1,258 @@
+package route53
+
+import (
+	"fmt"
+	"github.com/aws/aws-cloud-map-mcs-controller-for-k8s/pkg/model"
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/route53/types"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// The records of a service are owned by the clusters exporting it. Each cluster writes weighted record sets named after
+// the service, identified by the ID of the cluster:
+//   <service>.<namespace>.<zone> A       the IP addresses of the endpoints of the cluster
+//   _<proto>.<service>.<namespace>.<zone> SRV "0 0 <port> <service>.<namespace>.<zone>" for each endpoint port
+//   <service>.<namespace>.<zone> TXT     the ownership record of the cluster
+// DNS queries return the record set of one cluster, chosen by a weight proportional to its number of IP addresses,
+// scaled by the weight attribute of the endpoints of a cluster running a canary.
+// Record sets without the ownership record of the cluster are never changed.
+
+const (
+	// ownerValue prefixes the value of the ownership TXT record, followed by the cluster ID
+	ownerValue = "\"heritage=aws-cloud-map-mcs-controller-for-k8s,multicluster.k8s.aws/cluster="
+	// maxWeight is the largest weight of a weighted record set
+	maxWeight = 255
+	// clusterIdAttr is the endpoint attribute identifying the exporting cluster, see controllers.ClusterIdAttr
+	clusterIdAttr = "CLUSTER_ID"
+	// weightAttr is the endpoint attribute holding the percentage of the weight of the cluster, see
+	// controllers.WeightAttr
+	weightAttr = "WEIGHT"
+)
+
+// serviceRecords are the record sets of a service, by cluster ID.
+type serviceRecords map[string]*clusterRecords
+
+// clusterRecords are the record sets of a service written by a cluster.
+type clusterRecords struct {
+	a     *types.ResourceRecordSet
+	srv   map[string]*types.ResourceRecordSet
+	owner *types.ResourceRecordSet
+}
+
+func (r serviceRecords) cluster(clusterId string) *clusterRecords {
+	if r[clusterId] == nil {
+		r[clusterId] = &clusterRecords{srv: make(map[string]*types.ResourceRecordSet)}
+	}
+	return r[clusterId]
+}
+
+// add adds a record set of the service, record sets without set identifier are ignored.
+func (r serviceRecords) add(svcName string, recordSet types.ResourceRecordSet) {
+	clusterId := aws.ToString(recordSet.SetIdentifier)
+	if clusterId == "" {
+		return
+	}
+
+	name := strings.TrimSuffix(aws.ToString(recordSet.Name), ".")
+	switch {
+	case recordSet.Type == types.RRTypeA && name == svcName:
+		r.cluster(clusterId).a = &recordSet
+	case recordSet.Type == types.RRTypeTxt && name == svcName:
+		r.cluster(clusterId).owner = &recordSet
+	case recordSet.Type == types.RRTypeSrv && strings.HasPrefix(name, "_") && strings.HasSuffix(name, "."+svcName):
+		protocol := strings.TrimSuffix(strings.TrimPrefix(name, "_"), "."+svcName)
+		r.cluster(clusterId).srv[strings.ToUpper(protocol)] = &recordSet
+	}
+}
+
+// owned returns true if the record sets have the ownership record of the cluster.
+func (c *clusterRecords) owned(clusterId string) bool {
+	if c.owner == nil || len(c.owner.ResourceRecords) != 1 {
+		return false
+	}
+	return aws.ToString(c.owner.ResourceRecords[0].Value) == ownerValue+clusterId+"\""
+}
+
+// empty returns true if the cluster has no record sets.
+func (c *clusterRecords) empty() bool {
+	return c.a == nil && len(c.srv) == 0 && c.owner == nil
+}
+
+// all returns all record sets of the cluster.
+func (c *clusterRecords) all() []*types.ResourceRecordSet {
+	result := make([]*types.ResourceRecordSet, 0, len(c.srv)+2)
+	if c.a != nil {
+		result = append(result, c.a)
+	}
+	protocols := make([]string, 0, len(c.srv))
+	for protocol := range c.srv {
+		protocols = append(protocols, protocol)
+	}
+	sort.Strings(protocols)
+	for _, protocol := range protocols {
+		result = append(result, c.srv[protocol])
+	}
+	if c.owner != nil {
+		result = append(result, c.owner)
+	}
+	return result
+}
+
+// endpoints returns the endpoints of the record sets, each IP address of the A record set with each port of the SRV
+// record sets. Records which can't be parsed are skipped.
+func (c *clusterRecords) endpoints(clusterId string) []*model.Endpoint {
+	if c.a == nil {
+		return nil
+	}
+
+	ports := make([]model.Port, 0)
+	for protocol, srv := range c.srv {
+		for _, record := range srv.ResourceRecords {
+			fields := strings.Fields(aws.ToString(record.Value))
+			if len(fields) != 4 {
+				continue
+			}
+			port, err := strconv.ParseInt(fields[2], 10, 32)
+			if err != nil {
+				continue
+			}
+			ports = append(ports, model.Port{Port: int32(port), Protocol: protocol})
+		}
+	}
+
+	endpoints := make([]*model.Endpoint, 0, len(c.a.ResourceRecords)*len(ports))
+	for _, record := range c.a.ResourceRecords {
+		ip := aws.ToString(record.Value)
+		for _, port := range ports {
+			endpoints = append(endpoints, &model.Endpoint{
+				Id:           model.EndpointId(clusterId, ip, port),
+				IP:           ip,
+				EndpointPort: port,
+				ServicePort:  port,
+				Attributes:   map[string]string{clusterIdAttr: clusterId},
+			})
+		}
+	}
+	return endpoints
+}
+
+// buildClusterRecords returns the record sets of the cluster for the endpoints of the service, or empty record sets if
+// there are no endpoints.
+func buildClusterRecords(svcName string, clusterId string, ttl int64, endpoints []*model.Endpoint) *clusterRecords {
+	records := &clusterRecords{srv: make(map[string]*types.ResourceRecordSet)}
+	if len(endpoints) == 0 {
+		return records
+	}
+
+	ips := make(map[string]bool)
+	ports := make(map[string]map[int32]bool)
+	for _, endpoint := range endpoints {
+		ips[endpoint.IP] = true
+		protocol := endpoint.EndpointPort.Protocol
+		if protocol == "" {
+			protocol = model.TCPProtocol
+		}
+		if ports[protocol] == nil {
+			ports[protocol] = make(map[int32]bool)
+		}
+		ports[protocol][endpoint.EndpointPort.Port] = true
+	}
+
+	weight := int64(len(ips))
+	if weight > maxWeight {
+		weight = maxWeight
+	}
+	if percentage, found := endpointsWeight(endpoints); found {
+		// rounded up, so the cluster keeps receiving traffic unless its weight is 0
+		weight = (weight*percentage + 99) / 100
+	}
+	recordSet := func(name string, rrType types.RRType, values []string) *types.ResourceRecordSet {
+		sort.Strings(values)
+		resourceRecords := make([]types.ResourceRecord, 0, len(values))
+		for _, value := range values {
+			resourceRecords = append(resourceRecords, types.ResourceRecord{Value: aws.String(value)})
+		}
+		return &types.ResourceRecordSet{
+			Name:            aws.String(name),
+			Type:            rrType,
+			SetIdentifier:   aws.String(clusterId),
+			Weight:          aws.Int64(weight),
+			TTL:             aws.Int64(ttl),
+			ResourceRecords: resourceRecords,
+		}
+	}
+
+	ipValues := make([]string, 0, len(ips))
+	for ip := range ips {
+		ipValues = append(ipValues, ip)
+	}
+	records.a = recordSet(svcName, types.RRTypeA, ipValues)
+	for protocol, protocolPorts := range ports {
+		srvValues := make([]string, 0, len(protocolPorts))
+		for port := range protocolPorts {
+			srvValues = append(srvValues, fmt.Sprintf("0 0 %d %s", port, svcName))
+		}
+		records.srv[protocol] = recordSet("_"+strings.ToLower(protocol)+"."+svcName, types.RRTypeSrv, srvValues)
+	}
+	records.owner = recordSet(svcName, types.RRTypeTxt, []string{ownerValue + clusterId + "\""})
+	return records
+}
+
+// changes returns the changes turning the current record sets into the desired record sets.
+func changes(current *clusterRecords, desired *clusterRecords) []types.Change {
+	result := make([]types.Change, 0)
+	desiredSets := make(map[string]*types.ResourceRecordSet)
+	for _, recordSet := range desired.all() {
+		desiredSets[recordSetKey(recordSet)] = recordSet
+	}
+	currentSets := make(map[string]*types.ResourceRecordSet)
+	for _, recordSet := range current.all() {
+		currentSets[recordSetKey(recordSet)] = recordSet
+	}
+
+	for _, recordSet := range desired.all() {
+		if existing := currentSets[recordSetKey(recordSet)]; existing == nil || !recordSetEqual(existing, recordSet) {
+			result = append(result, types.Change{Action: types.ChangeActionUpsert, ResourceRecordSet: recordSet})
+		}
+	}
+	for _, recordSet := range current.all() {
+		if desiredSets[recordSetKey(recordSet)] == nil {
+			// a deletion must match the current record set exactly
+			result = append(result, types.Change{Action: types.ChangeActionDelete, ResourceRecordSet: recordSet})
+		}
+	}
+	return result
+}
+
+func recordSetKey(recordSet *types.ResourceRecordSet) string {
+	return strings.TrimSuffix(aws.ToString(recordSet.Name), ".") + "/" + string(recordSet.Type)
+}
+
+func recordSetEqual(a *types.ResourceRecordSet, b *types.ResourceRecordSet) bool {
+	return aws.ToInt64(a.TTL) == aws.ToInt64(b.TTL) && aws.ToInt64(a.Weight) == aws.ToInt64(b.Weight) &&
+		reflect.DeepEqual(recordValues(a), recordValues(b))
+}
+
+func recordValues(recordSet *types.ResourceRecordSet) []string {
+	values := make([]string, 0, len(recordSet.ResourceRecords))
+	for _, record := range recordSet.ResourceRecords {
+		values = append(values, aws.ToString(record.Value))
+	}
+	sort.Strings(values)
+	return values
+}
+
+// endpointsWeight returns the weight percentage of the endpoints from their weight attribute, if any.
+func endpointsWeight(endpoints []*model.Endpoint) (percentage int64, found bool) {
+	for _, endpoint := range endpoints {
+		if value, hasWeight := endpoint.Attributes[weightAttr]; hasWeight {
+			if percentage, err := strconv.ParseInt(value, 10, 64); err == nil && percentage >= 0 && percentage <= 100 {
+				return percentage, true
+			}
+		}
+	}
+	return 0, false
+}
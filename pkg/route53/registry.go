@@ -0,0 +1,262 @@
+package route53
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/aws/aws-cloud-map-mcs-controller-for-k8s/pkg/cloudmap"
+	"github.com/aws/aws-cloud-map-mcs-controller-for-k8s/pkg/common"
+	"github.com/aws/aws-cloud-map-mcs-controller-for-k8s/pkg/model"
+	"github.com/aws/aws-cloud-map-mcs-controller-for-k8s/pkg/registry"
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/route53"
+	"github.com/aws/aws-sdk-go-v2/service/route53/types"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// ErrNotOwned is returned when the record sets of the cluster ID exist without the ownership record of the cluster,
+// e.g. because they were created by hand or by another cluster with the same ID.
+var ErrNotOwned = errors.New("record sets not owned by the cluster")
+
+var _ registry.ServiceRegistry = &Registry{}
+
+// Registry is a registry.ServiceRegistry writing the endpoints of exported services as A and SRV records into a
+// private Route53 hosted zone, without Cloud Map services. Each cluster only changes the record sets it owns, and
+// removes them once the service has no endpoints left. SRV records carry the endpoint port, which imported services
+// expose as service port.
+type Registry struct {
+	log       common.Logger
+	route53   AwsFacade
+	zoneId    string
+	clusterId string
+
+	mutex    sync.Mutex
+	zoneName string
+}
+
+// NewRegistry creates a registry managing the records of the cluster in the private hosted zone.
+func NewRegistry(route53 AwsFacade, zoneId string, clusterId string) *Registry {
+	return &Registry{
+		log:       common.NewLogger("route53"),
+		route53:   route53,
+		zoneId:    zoneId,
+		clusterId: clusterId,
+	}
+}
+
+// ListServices returns the services of the namespace which have records in the hosted zone.
+func (r *Registry) ListServices(ctx context.Context, namespaceName string) ([]*model.Service, error) {
+	zoneName, err := r.getZoneName(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	nsName := namespaceName + "." + zoneName
+	recordsByService := make(map[string]serviceRecords)
+	err = r.listRecordSets(ctx, nsName, func(name string) bool {
+		return name == nsName || strings.HasSuffix(name, "."+nsName)
+	}, func(recordSet types.ResourceRecordSet) {
+		if !strings.HasSuffix(aws.ToString(recordSet.Name), "."+nsName+".") {
+			return
+		}
+		labels := strings.Split(strings.TrimSuffix(aws.ToString(recordSet.Name), "."+nsName+"."), ".")
+		svcName := labels[len(labels)-1]
+		if recordsByService[svcName] == nil {
+			recordsByService[svcName] = make(serviceRecords)
+		}
+		recordsByService[svcName].add(svcName+"."+nsName, recordSet)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	svcNames := make([]string, 0, len(recordsByService))
+	for svcName := range recordsByService {
+		svcNames = append(svcNames, svcName)
+	}
+	sort.Strings(svcNames)
+	services := make([]*model.Service, 0, len(svcNames))
+	for _, svcName := range svcNames {
+		if svc := newService(namespaceName, svcName, nsName, recordsByService[svcName]); svc != nil {
+			services = append(services, svc)
+		}
+	}
+	return services, nil
+}
+
+// GetService returns the service and the endpoints of all clusters, or nil if the service has no records.
+func (r *Registry) GetService(ctx context.Context, namespaceName string, serviceName string) (*model.Service, error) {
+	records, err := r.getServiceRecords(ctx, namespaceName, serviceName)
+	if err != nil {
+		return nil, err
+	}
+	zoneName, err := r.getZoneName(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return newService(namespaceName, serviceName, namespaceName+"."+zoneName, records), nil
+}
+
+// CreateService does nothing, the records of a service are created with its first endpoints.
+func (r *Registry) CreateService(_ context.Context, _ string, _ string) error {
+	return nil
+}
+
+// DeleteService does nothing, the records of a service are deleted with its last endpoints.
+func (r *Registry) DeleteService(_ context.Context, _ string, _ string) error {
+	return nil
+}
+
+// RegisterEndpoints adds the endpoints to the records of the cluster, replacing endpoints with the same ID.
+func (r *Registry) RegisterEndpoints(ctx context.Context, namespaceName string, serviceName string, endpoints []*model.Endpoint) error {
+	return r.updateEndpoints(ctx, namespaceName, serviceName, func(current map[string]*model.Endpoint) {
+		for _, endpoint := range endpoints {
+			current[endpoint.Id] = endpoint
+		}
+	})
+}
+
+// DeleteEndpoints removes the endpoints from the records of the cluster, and deletes the records of the cluster once
+// no endpoints are left.
+func (r *Registry) DeleteEndpoints(ctx context.Context, namespaceName string, serviceName string, endpoints []*model.Endpoint) error {
+	return r.updateEndpoints(ctx, namespaceName, serviceName, func(current map[string]*model.Endpoint) {
+		for _, endpoint := range endpoints {
+			delete(current, endpoint.Id)
+		}
+	})
+}
+
+// updateEndpoints applies the update to the endpoints of the cluster, and changes the record sets of the cluster to
+// match the updated endpoints.
+func (r *Registry) updateEndpoints(ctx context.Context, namespaceName string, serviceName string, update func(map[string]*model.Endpoint)) error {
+	zoneName, err := r.getZoneName(ctx)
+	if err != nil {
+		return err
+	}
+	records, err := r.getServiceRecords(ctx, namespaceName, serviceName)
+	if err != nil {
+		return err
+	}
+
+	svcName := serviceName + "." + namespaceName + "." + zoneName
+	current := records.cluster(r.clusterId)
+	if !current.empty() && !current.owned(r.clusterId) {
+		return fmt.Errorf("%s in hosted zone %s: %w", svcName, r.zoneId, ErrNotOwned)
+	}
+
+	endpoints := make(map[string]*model.Endpoint)
+	for _, endpoint := range current.endpoints(r.clusterId) {
+		endpoints[endpoint.Id] = endpoint
+	}
+	update(endpoints)
+	updated := make([]*model.Endpoint, 0, len(endpoints))
+	for _, endpoint := range endpoints {
+		updated = append(updated, endpoint)
+	}
+
+	desired := buildClusterRecords(svcName, r.clusterId, cloudmap.DnsTTLFromContext(ctx), updated)
+	changeList := changes(current, desired)
+	if len(changeList) == 0 {
+		return nil
+	}
+	if _, err = r.route53.ChangeResourceRecordSets(ctx, &route53.ChangeResourceRecordSetsInput{
+		HostedZoneId: aws.String(r.zoneId),
+		ChangeBatch: &types.ChangeBatch{
+			Comment: aws.String("multicluster service " + svcName + " of cluster " + r.clusterId),
+			Changes: changeList,
+		},
+	}); err != nil {
+		return err
+	}
+	r.log.Info("changed Route53 records", "name", svcName, "changes", len(changeList), "endpoints", len(updated))
+	return nil
+}
+
+// getServiceRecords returns the record sets of the service, by cluster ID.
+func (r *Registry) getServiceRecords(ctx context.Context, namespaceName string, serviceName string) (serviceRecords, error) {
+	zoneName, err := r.getZoneName(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	svcName := serviceName + "." + namespaceName + "." + zoneName
+	records := make(serviceRecords)
+	err = r.listRecordSets(ctx, svcName, func(name string) bool {
+		return name == svcName || strings.HasSuffix(name, "."+svcName)
+	}, func(recordSet types.ResourceRecordSet) {
+		records.add(svcName, recordSet)
+	})
+	return records, err
+}
+
+// listRecordSets calls visit with the record sets from the start name for as long as their names match. Route53 sorts
+// record sets by their labels in reverse order, so the names of a domain and its subdomains are contiguous.
+func (r *Registry) listRecordSets(ctx context.Context, start string, match func(name string) bool, visit func(types.ResourceRecordSet)) error {
+	input := &route53.ListResourceRecordSetsInput{
+		HostedZoneId:    aws.String(r.zoneId),
+		StartRecordName: aws.String(start + "."),
+	}
+	for {
+		out, err := r.route53.ListResourceRecordSets(ctx, input)
+		if err != nil {
+			return err
+		}
+		for _, recordSet := range out.ResourceRecordSets {
+			if !match(strings.TrimSuffix(aws.ToString(recordSet.Name), ".")) {
+				return nil
+			}
+			visit(recordSet)
+		}
+		if !out.IsTruncated {
+			return nil
+		}
+		input.StartRecordName = out.NextRecordName
+		input.StartRecordType = out.NextRecordType
+		input.StartRecordIdentifier = out.NextRecordIdentifier
+	}
+}
+
+// getZoneName returns the name of the hosted zone without trailing dot, which is fetched once. Public hosted zones are
+// rejected, as they would publish the IP addresses of the endpoints.
+func (r *Registry) getZoneName(ctx context.Context) (string, error) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	if r.zoneName != "" {
+		return r.zoneName, nil
+	}
+
+	out, err := r.route53.GetHostedZone(ctx, &route53.GetHostedZoneInput{Id: aws.String(r.zoneId)})
+	if err != nil {
+		return "", err
+	}
+	if out.HostedZone.Config == nil || !out.HostedZone.Config.PrivateZone {
+		return "", fmt.Errorf("hosted zone %s is not private", r.zoneId)
+	}
+	r.zoneName = strings.TrimSuffix(aws.ToString(out.HostedZone.Name), ".")
+	return r.zoneName, nil
+}
+
+// newService returns the service with the endpoints of all clusters, or nil if no cluster has endpoints.
+func newService(namespaceName string, serviceName string, nsName string, records serviceRecords) *model.Service {
+	clusterIds := make([]string, 0, len(records))
+	for clusterId := range records {
+		clusterIds = append(clusterIds, clusterId)
+	}
+	sort.Strings(clusterIds)
+
+	endpoints := make([]*model.Endpoint, 0)
+	for _, clusterId := range clusterIds {
+		endpoints = append(endpoints, records[clusterId].endpoints(clusterId)...)
+	}
+	if len(endpoints) == 0 {
+		return nil
+	}
+	return &model.Service{
+		Id:        serviceName + "." + nsName,
+		Namespace: namespaceName,
+		Name:      serviceName,
+		Endpoints: endpoints,
+	}
+}
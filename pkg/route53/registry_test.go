@@ -0,0 +1,196 @@
+package route53
+
+import (
+	"context"
+	"errors"
+	"github.com/aws/aws-cloud-map-mcs-controller-for-k8s/pkg/model"
+	"github.com/aws/aws-cloud-map-mcs-controller-for-k8s/test"
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/route53"
+	"github.com/aws/aws-sdk-go-v2/service/route53/types"
+	"github.com/stretchr/testify/assert"
+	"reflect"
+	"sort"
+	"strings"
+	"testing"
+)
+
+const (
+	zoneId   = "Z0123456789"
+	zoneName = "clusterset.example"
+)
+
+// fakeZone is an in-memory hosted zone, listing one record set per page.
+type fakeZone struct {
+	private    bool
+	recordSets []types.ResourceRecordSet
+	changes    int
+}
+
+func (z *fakeZone) GetHostedZone(_ context.Context, _ *route53.GetHostedZoneInput, _ ...func(*route53.Options)) (*route53.GetHostedZoneOutput, error) {
+	return &route53.GetHostedZoneOutput{HostedZone: &types.HostedZone{
+		Id:     aws.String(zoneId),
+		Name:   aws.String(zoneName + "."),
+		Config: &types.HostedZoneConfig{PrivateZone: z.private},
+	}}, nil
+}
+
+func (z *fakeZone) ListResourceRecordSets(_ context.Context, in *route53.ListResourceRecordSetsInput, _ ...func(*route53.Options)) (*route53.ListResourceRecordSetsOutput, error) {
+	sort.Slice(z.recordSets, func(i, j int) bool {
+		return recordSetOrder(z.recordSets[i]) < recordSetOrder(z.recordSets[j])
+	})
+	start := reverseLabels(aws.ToString(in.StartRecordName)) + "/" + string(in.StartRecordType) + "/" +
+		aws.ToString(in.StartRecordIdentifier)
+	for i, recordSet := range z.recordSets {
+		if recordSetOrder(recordSet) < start {
+			continue
+		}
+		out := &route53.ListResourceRecordSetsOutput{ResourceRecordSets: []types.ResourceRecordSet{recordSet}}
+		if i+1 < len(z.recordSets) {
+			next := z.recordSets[i+1]
+			out.IsTruncated = true
+			out.NextRecordName, out.NextRecordType, out.NextRecordIdentifier = next.Name, next.Type, next.SetIdentifier
+		}
+		return out, nil
+	}
+	return &route53.ListResourceRecordSetsOutput{}, nil
+}
+
+func (z *fakeZone) ChangeResourceRecordSets(_ context.Context, in *route53.ChangeResourceRecordSetsInput, _ ...func(*route53.Options)) (*route53.ChangeResourceRecordSetsOutput, error) {
+	z.changes++
+	for _, change := range in.ChangeBatch.Changes {
+		index := -1
+		for i, recordSet := range z.recordSets {
+			if recordSetOrder(recordSet) == recordSetOrder(*change.ResourceRecordSet) {
+				index = i
+			}
+		}
+		switch change.Action {
+		case types.ChangeActionUpsert:
+			if index < 0 {
+				z.recordSets = append(z.recordSets, *change.ResourceRecordSet)
+			} else {
+				z.recordSets[index] = *change.ResourceRecordSet
+			}
+		case types.ChangeActionDelete:
+			if index < 0 || !reflect.DeepEqual(z.recordSets[index], *change.ResourceRecordSet) {
+				return nil, errors.New("InvalidChangeBatch")
+			}
+			z.recordSets = append(z.recordSets[:index], z.recordSets[index+1:]...)
+		}
+	}
+	return &route53.ChangeResourceRecordSetsOutput{}, nil
+}
+
+func recordSetOrder(recordSet types.ResourceRecordSet) string {
+	return reverseLabels(aws.ToString(recordSet.Name)) + "/" + string(recordSet.Type) + "/" +
+		aws.ToString(recordSet.SetIdentifier)
+}
+
+func reverseLabels(name string) string {
+	labels := strings.Split(strings.TrimSuffix(name, "."), ".")
+	for i, j := 0, len(labels)-1; i < j; i, j = i+1, j-1 {
+		labels[i], labels[j] = labels[j], labels[i]
+	}
+	return strings.Join(labels, ".")
+}
+
+func endpoint(clusterId string, ip string, port int32) *model.Endpoint {
+	p := model.Port{Port: port, Protocol: model.TCPProtocol}
+	return &model.Endpoint{
+		Id:           model.EndpointId(clusterId, ip, p),
+		IP:           ip,
+		EndpointPort: p,
+		ServicePort:  p,
+		Attributes:   map[string]string{clusterIdAttr: clusterId},
+	}
+}
+
+func TestRegistry_RegisterEndpoints(t *testing.T) {
+	zone := &fakeZone{private: true}
+	r := NewRegistry(zone, zoneId, test.ClusterId)
+	other := NewRegistry(zone, zoneId, "other-cluster")
+
+	assert.NoError(t, r.RegisterEndpoints(context.TODO(), test.NsName, test.SvcName,
+		[]*model.Endpoint{endpoint(test.ClusterId, test.EndptIp1, test.Port1)}))
+	assert.NoError(t, other.RegisterEndpoints(context.TODO(), test.NsName, test.SvcName,
+		[]*model.Endpoint{endpoint("other-cluster", test.EndptIp2, test.Port1)}))
+	assert.Len(t, zone.recordSets, 6, "each cluster writes A, SRV and TXT record sets")
+
+	svc, err := r.GetService(context.TODO(), test.NsName, test.SvcName)
+	assert.NoError(t, err)
+	assert.Equal(t, test.SvcName+"."+test.NsName+"."+zoneName, svc.Id)
+	assert.ElementsMatch(t, []*model.Endpoint{
+		endpoint(test.ClusterId, test.EndptIp1, test.Port1),
+		endpoint("other-cluster", test.EndptIp2, test.Port1),
+	}, svc.Endpoints)
+
+	// registering the same endpoints changes nothing
+	changes := zone.changes
+	assert.NoError(t, r.RegisterEndpoints(context.TODO(), test.NsName, test.SvcName,
+		[]*model.Endpoint{endpoint(test.ClusterId, test.EndptIp1, test.Port1)}))
+	assert.Equal(t, changes, zone.changes)
+
+	services, err := r.ListServices(context.TODO(), test.NsName)
+	assert.NoError(t, err)
+	assert.Equal(t, []*model.Service{svc}, services)
+}
+
+func TestRegistry_DeleteEndpoints(t *testing.T) {
+	zone := &fakeZone{private: true}
+	r := NewRegistry(zone, zoneId, test.ClusterId)
+	first, second := endpoint(test.ClusterId, test.EndptIp1, test.Port1), endpoint(test.ClusterId, test.EndptIp2, test.Port1)
+
+	assert.NoError(t, r.RegisterEndpoints(context.TODO(), test.NsName, test.SvcName, []*model.Endpoint{first, second}))
+	assert.NoError(t, r.DeleteEndpoints(context.TODO(), test.NsName, test.SvcName, []*model.Endpoint{first}))
+	svc, err := r.GetService(context.TODO(), test.NsName, test.SvcName)
+	assert.NoError(t, err)
+	assert.Equal(t, []*model.Endpoint{second}, svc.Endpoints)
+
+	assert.NoError(t, r.DeleteEndpoints(context.TODO(), test.NsName, test.SvcName, []*model.Endpoint{second}))
+	assert.Empty(t, zone.recordSets, "the record sets are deleted with the last endpoint")
+	svc, err = r.GetService(context.TODO(), test.NsName, test.SvcName)
+	assert.NoError(t, err)
+	assert.Nil(t, svc)
+}
+
+func TestRegistry_NotOwned(t *testing.T) {
+	zone := &fakeZone{private: true, recordSets: []types.ResourceRecordSet{{
+		Name:            aws.String(test.SvcName + "." + test.NsName + "." + zoneName + "."),
+		Type:            types.RRTypeA,
+		SetIdentifier:   aws.String(test.ClusterId),
+		Weight:          aws.Int64(1),
+		TTL:             aws.Int64(60),
+		ResourceRecords: []types.ResourceRecord{{Value: aws.String(test.EndptIp2)}},
+	}}}
+	r := NewRegistry(zone, zoneId, test.ClusterId)
+
+	err := r.RegisterEndpoints(context.TODO(), test.NsName, test.SvcName,
+		[]*model.Endpoint{endpoint(test.ClusterId, test.EndptIp1, test.Port1)})
+	assert.True(t, errors.Is(err, ErrNotOwned))
+	assert.Zero(t, zone.changes)
+}
+
+func TestRegistry_PublicZone(t *testing.T) {
+	r := NewRegistry(&fakeZone{}, zoneId, test.ClusterId)
+	_, err := r.ListServices(context.TODO(), test.NsName)
+	assert.Error(t, err)
+}
+
+func TestBuildClusterRecords_CanaryWeight(t *testing.T) {
+	endpoints := make([]*model.Endpoint, 0)
+	for _, ip := range []string{"10.0.0.1", "10.0.0.2", "10.0.0.3", "10.0.0.4"} {
+		e := endpoint(test.ClusterId, ip, test.Port1)
+		e.Attributes[weightAttr] = "30"
+		endpoints = append(endpoints, e)
+	}
+
+	records := buildClusterRecords(test.SvcName, test.ClusterId, 60, endpoints)
+	assert.Equal(t, int64(2), aws.ToInt64(records.a.Weight), "weight of 4 IPs at 30% is rounded up")
+
+	for _, e := range endpoints {
+		e.Attributes[weightAttr] = "0"
+	}
+	records = buildClusterRecords(test.SvcName, test.ClusterId, 60, endpoints)
+	assert.Equal(t, int64(0), aws.ToInt64(records.a.Weight))
+}
@@ -0,0 +1,133 @@
+package snapshot
+
+import (
+	"context"
+	"fmt"
+	"github.com/aws/aws-cloud-map-mcs-controller-for-k8s/pkg/cloudmap"
+	"github.com/aws/aws-cloud-map-mcs-controller-for-k8s/pkg/model"
+	"io/ioutil"
+	utilerrors "k8s.io/apimachinery/pkg/util/errors"
+	"k8s.io/apimachinery/pkg/util/sets"
+	"os"
+	"sigs.k8s.io/yaml"
+)
+
+// RestoreOptions control how a snapshot is restored.
+type RestoreOptions struct {
+	// DryRun computes the services and instances which would be restored without changing Cloud Map
+	DryRun bool
+}
+
+// RestoreResult lists the services created and the instances registered by a restore.
+type RestoreResult struct {
+	// CreatedServices are the namespace/name of the re-created services
+	CreatedServices []string
+	// RegisteredEndpoints are the instances re-registered per namespace/name of their service
+	RegisteredEndpoints map[string][]*model.Endpoint
+}
+
+// Load reads a snapshot from a JSON or YAML file, or from stdin if the path is "-".
+func Load(path string) (*Snapshot, error) {
+	var data []byte
+	var err error
+	if path == "-" {
+		data, err = ioutil.ReadAll(os.Stdin)
+	} else {
+		data, err = ioutil.ReadFile(path)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	snapshot := &Snapshot{}
+	if err = yaml.Unmarshal(data, snapshot); err != nil {
+		return nil, fmt.Errorf("invalid snapshot %s: %w", path, err)
+	}
+	return snapshot, nil
+}
+
+// Restore re-creates the services of the snapshot missing in Cloud Map, along with their namespace, and re-registers
+// the missing instances. Only HTTP namespaces are re-created, the services of DNS namespaces missing in Cloud Map fail
+// to restore since the VPC of the namespace isn't recorded. Instances registered or updated since the snapshot was
+// taken are kept as they are, the controllers of the clusters de-register stale instances on their next reconcile.
+// Services failing to restore don't stop the restore of the others, their errors are returned once all services
+// have been processed.
+func Restore(ctx context.Context, sdApi cloudmap.ServiceDiscoveryApi, sdClient cloudmap.ServiceDiscoveryClient, snapshot *Snapshot, opts RestoreOptions) (*RestoreResult, error) {
+	result := &RestoreResult{
+		CreatedServices:     make([]string, 0),
+		RegisteredEndpoints: make(map[string][]*model.Endpoint),
+	}
+
+	existing, err := existingNamespaces(ctx, sdApi, snapshot)
+	if err != nil {
+		return result, fmt.Errorf("unable to list namespaces: %w", err)
+	}
+
+	errs := make([]error, 0)
+	for _, ns := range snapshot.Namespaces {
+		if ns.Type != model.HttpNamespaceType && !existing.Has(ns.Name) {
+			errs = append(errs, fmt.Errorf("unable to restore the services of namespace %s: the %s namespace is "+
+				"missing in Cloud Map, only HTTP namespaces are re-created", ns.Name, ns.Type))
+			continue
+		}
+		for _, svc := range ns.Services {
+			if err := restoreService(ctx, sdClient, ns.Name, svc, opts, result); err != nil {
+				errs = append(errs, fmt.Errorf("unable to restore service %s/%s: %w", ns.Name, svc.Name, err))
+			}
+		}
+	}
+	return result, utilerrors.NewAggregate(errs)
+}
+
+// existingNamespaces returns the names of the Cloud Map namespaces, which are only listed if the snapshot records
+// namespaces which can't be re-created.
+func existingNamespaces(ctx context.Context, sdApi cloudmap.ServiceDiscoveryApi, snapshot *Snapshot) (sets.String, error) {
+	existing := sets.NewString()
+	for _, ns := range snapshot.Namespaces {
+		if ns.Type == model.HttpNamespaceType {
+			continue
+		}
+		namespaces, err := sdApi.ListNamespaces(ctx)
+		if err != nil {
+			return nil, err
+		}
+		for _, namespace := range namespaces {
+			existing.Insert(namespace.Name)
+		}
+		break
+	}
+	return existing, nil
+}
+
+func restoreService(ctx context.Context, sdClient cloudmap.ServiceDiscoveryClient, nsName string, svc *model.Service, opts RestoreOptions, result *RestoreResult) error {
+	key := nsName + "/" + svc.Name
+	current, err := sdClient.GetService(ctx, nsName, svc.Name)
+	if err != nil {
+		return err
+	}
+
+	currentEndpoints := make([]*model.Endpoint, 0)
+	if current == nil {
+		if !opts.DryRun {
+			if err = sdClient.CreateService(ctx, nsName, svc.Name); err != nil {
+				return err
+			}
+		}
+		result.CreatedServices = append(result.CreatedServices, key)
+	} else {
+		currentEndpoints = current.Endpoints
+	}
+
+	missing := (&model.Plan{Current: currentEndpoints, Desired: svc.Endpoints}).CalculateChanges().Create
+	if len(missing) == 0 {
+		return nil
+	}
+
+	if !opts.DryRun {
+		if err = sdClient.RegisterEndpoints(ctx, nsName, svc.Name, missing); err != nil {
+			return err
+		}
+	}
+	result.RegisteredEndpoints[key] = missing
+	return nil
+}
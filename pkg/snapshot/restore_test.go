@@ -0,0 +1,122 @@
+package snapshot
+
+import (
+	"context"
+	"errors"
+	"github.com/aws/aws-cloud-map-mcs-controller-for-k8s/mocks/pkg/cloudmap"
+	"github.com/aws/aws-cloud-map-mcs-controller-for-k8s/pkg/model"
+	"github.com/aws/aws-cloud-map-mcs-controller-for-k8s/test"
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/assert"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestRestore(t *testing.T) {
+	mockController := gomock.NewController(t)
+	defer mockController.Finish()
+
+	sdClient := cloudmap.NewMockServiceDiscoveryClient(mockController)
+	// the existing service misses endpoint 2, the deleted service is re-created
+	sdClient.EXPECT().GetService(gomock.Any(), test.NsName, test.SvcName).
+		Return(test.GetTestServiceWithEndpoint([]*model.Endpoint{test.GetTestEndpoint1()}), nil)
+	sdClient.EXPECT().RegisterEndpoints(gomock.Any(), test.NsName, test.SvcName,
+		[]*model.Endpoint{test.GetTestEndpoint2()}).Return(nil)
+	sdClient.EXPECT().GetService(gomock.Any(), test.NsName, "deleted").Return(nil, nil)
+	sdClient.EXPECT().CreateService(gomock.Any(), test.NsName, "deleted").Return(nil)
+	sdClient.EXPECT().RegisterEndpoints(gomock.Any(), test.NsName, "deleted",
+		[]*model.Endpoint{test.GetTestEndpoint1()}).Return(nil)
+
+	result, err := Restore(context.TODO(), cloudmap.NewMockServiceDiscoveryApi(mockController), sdClient, testSnapshot(), RestoreOptions{})
+	assert.NoError(t, err)
+	assert.Equal(t, []string{test.NsName + "/deleted"}, result.CreatedServices)
+	assert.Len(t, result.RegisteredEndpoints, 2)
+}
+
+func TestRestore_DryRun(t *testing.T) {
+	mockController := gomock.NewController(t)
+	defer mockController.Finish()
+
+	// only reads are expected
+	sdClient := cloudmap.NewMockServiceDiscoveryClient(mockController)
+	sdClient.EXPECT().GetService(gomock.Any(), test.NsName, test.SvcName).Return(test.GetTestService(), nil)
+	sdClient.EXPECT().GetService(gomock.Any(), test.NsName, "deleted").Return(nil, nil)
+
+	result, err := Restore(context.TODO(), cloudmap.NewMockServiceDiscoveryApi(mockController), sdClient, testSnapshot(), RestoreOptions{DryRun: true})
+	assert.NoError(t, err)
+	assert.Equal(t, []string{test.NsName + "/deleted"}, result.CreatedServices)
+	assert.Equal(t, map[string][]*model.Endpoint{
+		test.NsName + "/deleted": {test.GetTestEndpoint1()},
+	}, result.RegisteredEndpoints)
+}
+
+func TestRestore_ContinuesOnError(t *testing.T) {
+	mockController := gomock.NewController(t)
+	defer mockController.Finish()
+
+	sdClient := cloudmap.NewMockServiceDiscoveryClient(mockController)
+	sdClient.EXPECT().GetService(gomock.Any(), test.NsName, test.SvcName).Return(nil, errors.New("error"))
+	sdClient.EXPECT().GetService(gomock.Any(), test.NsName, "deleted").Return(nil, nil)
+	sdClient.EXPECT().CreateService(gomock.Any(), test.NsName, "deleted").Return(nil)
+	sdClient.EXPECT().RegisterEndpoints(gomock.Any(), test.NsName, "deleted", gomock.Any()).Return(nil)
+
+	_, err := Restore(context.TODO(), cloudmap.NewMockServiceDiscoveryApi(mockController), sdClient, testSnapshot(), RestoreOptions{})
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), test.NsName+"/"+test.SvcName)
+}
+
+func TestRestore_MissingDnsNamespace(t *testing.T) {
+	mockController := gomock.NewController(t)
+	defer mockController.Finish()
+
+	s := testSnapshot()
+	dnsNamespace := test.GetTestDnsNamespace()
+	dnsNamespace.Name = "dns-namespace"
+	s.Namespaces = append(s.Namespaces, Namespace{
+		Namespace: *dnsNamespace,
+		Services:  []*model.Service{{Namespace: dnsNamespace.Name, Name: test.SvcName}},
+	})
+
+	// the DNS namespace is not re-created as an HTTP namespace, the services of the HTTP namespace are restored
+	sdApi := cloudmap.NewMockServiceDiscoveryApi(mockController)
+	sdApi.EXPECT().ListNamespaces(gomock.Any()).Return([]*model.Namespace{test.GetTestHttpNamespace()}, nil)
+	sdClient := cloudmap.NewMockServiceDiscoveryClient(mockController)
+	sdClient.EXPECT().GetService(gomock.Any(), test.NsName, test.SvcName).Return(test.GetTestService(), nil)
+	sdClient.EXPECT().GetService(gomock.Any(), test.NsName, "deleted").Return(nil, nil)
+
+	result, err := Restore(context.TODO(), sdApi, sdClient, s, RestoreOptions{DryRun: true})
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "dns-namespace")
+	assert.Equal(t, []string{test.NsName + "/deleted"}, result.CreatedServices)
+}
+
+func TestLoad(t *testing.T) {
+	snapshot := testSnapshot()
+	dir, err := ioutil.TempDir("", "snapshot")
+	assert.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	for _, format := range []string{JsonFormat, YamlFormat} {
+		path := filepath.Join(dir, "snapshot."+format)
+		assert.NoError(t, snapshot.Write(path, format))
+
+		loaded, err := Load(path)
+		assert.NoError(t, err)
+		assert.Equal(t, snapshot.Namespaces[0].Services[0].Endpoints, loaded.Namespaces[0].Services[0].Endpoints)
+	}
+
+	_, err = Load(filepath.Join(dir, "missing"))
+	assert.Error(t, err)
+}
+
+func testSnapshot() *Snapshot {
+	return &Snapshot{Namespaces: []Namespace{{
+		Namespace: *test.GetTestHttpNamespace(),
+		Services: []*model.Service{
+			test.GetTestService(),
+			{Namespace: test.NsName, Name: "deleted", Endpoints: []*model.Endpoint{test.GetTestEndpoint1()}},
+		},
+	}}}
+}
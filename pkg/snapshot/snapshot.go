@@ -0,0 +1,158 @@
+package snapshot
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"github.com/aws/aws-cloud-map-mcs-controller-for-k8s/pkg/cloudmap"
+	"github.com/aws/aws-cloud-map-mcs-controller-for-k8s/pkg/controllers"
+	"github.com/aws/aws-cloud-map-mcs-controller-for-k8s/pkg/model"
+	"io/ioutil"
+	"k8s.io/apimachinery/pkg/util/sets"
+	"os"
+	"sigs.k8s.io/yaml"
+	"sort"
+	"time"
+)
+
+const (
+	// JsonFormat writes the snapshot as JSON
+	JsonFormat = "json"
+	// YamlFormat writes the snapshot as YAML
+	YamlFormat = "yaml"
+)
+
+// Snapshot records the Cloud Map namespaces, services and instances of a clusterset at a point in time.
+type Snapshot struct {
+	CreatedAt time.Time `json:"createdAt"`
+	// ClusterSetId is the clusterset the instances were restricted to, empty if all instances were recorded
+	ClusterSetId string      `json:"clusterSetId,omitempty"`
+	Namespaces   []Namespace `json:"namespaces"`
+}
+
+// Namespace records a Cloud Map namespace with its services and their instances.
+type Namespace struct {
+	model.Namespace `json:",inline"`
+	Services        []*model.Service `json:"services"`
+}
+
+// Options select the Cloud Map resources recorded in a snapshot.
+type Options struct {
+	// Namespaces are the names of the recorded namespaces, all namespaces are recorded if empty
+	Namespaces []string
+	// ClusterSetId restricts the recorded instances to those registered by clusters of the clusterset
+	ClusterSetId string
+}
+
+// Take records the namespaces, services and instances selected by the options. Instances which were not registered
+// by the controller, and can't be converted to endpoints, are omitted.
+func Take(ctx context.Context, sdApi cloudmap.ServiceDiscoveryApi, opts Options) (*Snapshot, error) {
+	namespaces, err := sdApi.ListNamespaces(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("unable to list namespaces: %w", err)
+	}
+	selected := sets.NewString(opts.Namespaces...)
+
+	snapshot := &Snapshot{
+		CreatedAt:    time.Now().UTC(),
+		ClusterSetId: opts.ClusterSetId,
+		Namespaces:   make([]Namespace, 0),
+	}
+	for _, ns := range namespaces {
+		if selected.Len() > 0 && !selected.Has(ns.Name) {
+			continue
+		}
+
+		services, err := takeServices(ctx, sdApi, ns, opts.ClusterSetId)
+		if err != nil {
+			return nil, err
+		}
+		snapshot.Namespaces = append(snapshot.Namespaces, Namespace{Namespace: *ns, Services: services})
+	}
+
+	if missing := selected.Difference(snapshot.namespaceNames()); missing.Len() > 0 {
+		return nil, fmt.Errorf("namespaces not found in Cloud Map: %v", missing.List())
+	}
+
+	sort.Slice(snapshot.Namespaces, func(i, j int) bool {
+		return snapshot.Namespaces[i].Name < snapshot.Namespaces[j].Name
+	})
+	return snapshot, nil
+}
+
+func takeServices(ctx context.Context, sdApi cloudmap.ServiceDiscoveryApi, ns *model.Namespace, clusterSetId string) ([]*model.Service, error) {
+	svcs, err := sdApi.ListServices(ctx, ns.Id)
+	if err != nil {
+		return nil, fmt.Errorf("unable to list services of namespace %s: %w", ns.Name, err)
+	}
+
+	services := make([]*model.Service, 0, len(svcs))
+	for _, svc := range svcs {
+		// instances are listed instead of discovered, DiscoverInstances truncates large services
+		insts, err := sdApi.ListInstances(ctx, ns.Name, svc.Name, svc.Id)
+		if err != nil {
+			return nil, fmt.Errorf("unable to list instances of service %s/%s: %w", ns.Name, svc.Name, err)
+		}
+
+		endpoints := make([]*model.Endpoint, 0, len(insts))
+		for i := range insts {
+			endpoint, err := model.NewEndpointFromInstance(&insts[i])
+			if err != nil {
+				continue
+			}
+			if clusterSetId != "" && endpoint.Attributes[controllers.ClusterSetIdAttr] != clusterSetId {
+				continue
+			}
+			endpoints = append(endpoints, endpoint)
+		}
+		sort.Slice(endpoints, func(i, j int) bool {
+			return endpoints[i].Id < endpoints[j].Id
+		})
+
+		services = append(services, &model.Service{
+			Id:        svc.Id,
+			Namespace: ns.Name,
+			Name:      svc.Name,
+			Endpoints: endpoints,
+		})
+	}
+
+	sort.Slice(services, func(i, j int) bool {
+		return services[i].Name < services[j].Name
+	})
+	return services, nil
+}
+
+func (s *Snapshot) namespaceNames() sets.String {
+	names := sets.NewString()
+	for _, ns := range s.Namespaces {
+		names.Insert(ns.Name)
+	}
+	return names
+}
+
+// Marshal returns the snapshot in the given format, json or yaml.
+func (s *Snapshot) Marshal(format string) ([]byte, error) {
+	switch format {
+	case JsonFormat:
+		return json.MarshalIndent(s, "", "  ")
+	case YamlFormat:
+		return yaml.Marshal(s)
+	default:
+		return nil, fmt.Errorf("unsupported snapshot format %s, expected %s or %s", format, JsonFormat, YamlFormat)
+	}
+}
+
+// Write writes the snapshot in the given format to a file, or to stdout if the path is "-".
+func (s *Snapshot) Write(path string, format string) error {
+	data, err := s.Marshal(format)
+	if err != nil {
+		return err
+	}
+
+	if path == "-" {
+		_, err = os.Stdout.Write(data)
+		return err
+	}
+	return ioutil.WriteFile(path, data, 0644)
+}
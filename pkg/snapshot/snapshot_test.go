@@ -0,0 +1,91 @@
+package snapshot
+
+import (
+	"context"
+	"encoding/json"
+	"github.com/aws/aws-cloud-map-mcs-controller-for-k8s/mocks/pkg/cloudmap"
+	"github.com/aws/aws-cloud-map-mcs-controller-for-k8s/pkg/controllers"
+	"github.com/aws/aws-cloud-map-mcs-controller-for-k8s/pkg/model"
+	"github.com/aws/aws-cloud-map-mcs-controller-for-k8s/test"
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/servicediscovery/types"
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/assert"
+	"sigs.k8s.io/yaml"
+	"testing"
+)
+
+func TestTake(t *testing.T) {
+	mockController := gomock.NewController(t)
+	defer mockController.Finish()
+
+	member := test.GetTestEndpoint1()
+	member.Attributes[controllers.ClusterSetIdAttr] = test.ClusterSetId
+	other := test.GetTestEndpoint2()
+	other.Attributes[controllers.ClusterSetIdAttr] = "other-clusterset"
+
+	sdApi := cloudmap.NewMockServiceDiscoveryApi(mockController)
+	sdApi.EXPECT().ListNamespaces(gomock.Any()).
+		Return([]*model.Namespace{test.GetTestHttpNamespace(), {Id: "other-ns-id", Name: "other-ns"}}, nil)
+	sdApi.EXPECT().ListServices(gomock.Any(), test.NsId).
+		Return([]*model.Resource{{Id: test.SvcId, Name: test.SvcName}}, nil)
+	sdApi.EXPECT().ListInstances(gomock.Any(), test.NsName, test.SvcName, test.SvcId).
+		Return([]types.HttpInstanceSummary{
+			instance(other),
+			instance(member),
+			// not registered by the controller
+			{InstanceId: aws.String("unknown"), Attributes: map[string]string{}},
+		}, nil)
+
+	snapshot, err := Take(context.TODO(), sdApi, Options{
+		Namespaces:   []string{test.NsName},
+		ClusterSetId: test.ClusterSetId,
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, test.ClusterSetId, snapshot.ClusterSetId)
+	assert.Equal(t, []Namespace{{
+		Namespace: *test.GetTestHttpNamespace(),
+		Services:  []*model.Service{test.GetTestServiceWithEndpoint([]*model.Endpoint{member})},
+	}}, snapshot.Namespaces)
+}
+
+func TestTake_NamespaceNotFound(t *testing.T) {
+	mockController := gomock.NewController(t)
+	defer mockController.Finish()
+
+	sdApi := cloudmap.NewMockServiceDiscoveryApi(mockController)
+	sdApi.EXPECT().ListNamespaces(gomock.Any()).Return([]*model.Namespace{}, nil)
+
+	_, err := Take(context.TODO(), sdApi, Options{Namespaces: []string{test.NsName}})
+	assert.Error(t, err)
+}
+
+func TestSnapshot_Marshal(t *testing.T) {
+	snapshot := &Snapshot{Namespaces: []Namespace{{
+		Namespace: *test.GetTestHttpNamespace(),
+		Services:  []*model.Service{test.GetTestService()},
+	}}}
+
+	data, err := snapshot.Marshal(JsonFormat)
+	assert.NoError(t, err)
+	fromJson := &Snapshot{}
+	assert.NoError(t, json.Unmarshal(data, fromJson))
+	assert.Equal(t, test.NsId, fromJson.Namespaces[0].Id)
+	assert.Contains(t, string(data), `"name": "`+test.NsName+`"`)
+
+	data, err = snapshot.Marshal(YamlFormat)
+	assert.NoError(t, err)
+	fromYaml := &Snapshot{}
+	assert.NoError(t, yaml.Unmarshal(data, fromYaml))
+	assert.Equal(t, fromJson, fromYaml)
+
+	_, err = snapshot.Marshal("xml")
+	assert.Error(t, err)
+}
+
+func instance(endpoint *model.Endpoint) types.HttpInstanceSummary {
+	return types.HttpInstanceSummary{
+		InstanceId: aws.String(endpoint.Id),
+		Attributes: endpoint.GetCloudMapAttributes(),
+	}
+}
@@ -0,0 +1,104 @@
+package tenancy
+
+import (
+	"errors"
+	"fmt"
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/util/yaml"
+	"os"
+)
+
+// ErrNotPermitted is returned if a Kubernetes namespace is not permitted to use a Cloud Map namespace.
+var ErrNotPermitted = errors.New("not permitted by tenancy policy")
+
+// Policy maps Kubernetes namespaces and teams to the Cloud Map namespaces they are permitted to publish into and
+// delete from. A Cloud Map namespace is claimed once it is listed for any namespace or team, and claimed namespaces
+// can only be used by the namespaces and teams they are listed for. For example:
+//
+//	teamLabel: team
+//	teams:
+//	  payments: [payments, payments-shared]
+//	namespaces:
+//	  legacy-billing: [billing]
+//
+// Namespaces without a policy entry may only use unclaimed Cloud Map namespaces, or none if DenyUnlisted is set.
+type Policy struct {
+	// TeamLabel is the namespace label identifying the team owning a Kubernetes namespace.
+	TeamLabel string `json:"teamLabel,omitempty"`
+	// Teams maps team names to their permitted Cloud Map namespaces.
+	Teams map[string][]string `json:"teams,omitempty"`
+	// Namespaces maps Kubernetes namespace names to their permitted Cloud Map namespaces.
+	Namespaces map[string][]string `json:"namespaces,omitempty"`
+	// DenyUnlisted denies namespaces without a policy entry from using any Cloud Map namespace.
+	DenyUnlisted bool `json:"denyUnlisted,omitempty"`
+}
+
+// LoadPolicy reads a tenancy policy from a YAML or JSON file.
+func LoadPolicy(path string) (*Policy, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	policy := &Policy{}
+	if err = yaml.NewYAMLOrJSONDecoder(file, 4096).Decode(policy); err != nil {
+		return nil, fmt.Errorf("invalid tenancy policy %s: %w", path, err)
+	}
+	return policy, nil
+}
+
+// Permits returns an error wrapping ErrNotPermitted if the Kubernetes namespace may not use the Cloud Map namespace.
+// A nil policy permits everything.
+func (p *Policy) Permits(namespace *v1.Namespace, cloudMapNamespace string) error {
+	if p == nil {
+		return nil
+	}
+
+	permitted, listed := p.permittedNamespaces(namespace)
+	for _, ns := range permitted {
+		if ns == cloudMapNamespace {
+			return nil
+		}
+	}
+
+	if !listed && !p.DenyUnlisted && !p.claimed(cloudMapNamespace) {
+		return nil
+	}
+
+	return fmt.Errorf("%w: namespace %s may not use Cloud Map namespace %s",
+		ErrNotPermitted, namespace.Name, cloudMapNamespace)
+}
+
+// permittedNamespaces returns the Cloud Map namespaces permitted for the Kubernetes namespace, and whether the
+// namespace has a policy entry either by name or by team.
+func (p *Policy) permittedNamespaces(namespace *v1.Namespace) (permitted []string, listed bool) {
+	if namespaces, found := p.Namespaces[namespace.Name]; found {
+		permitted = append(permitted, namespaces...)
+		listed = true
+	}
+
+	if p.TeamLabel == "" {
+		return permitted, listed
+	}
+	if team, found := namespace.Labels[p.TeamLabel]; found {
+		if namespaces, found := p.Teams[team]; found {
+			permitted = append(permitted, namespaces...)
+			listed = true
+		}
+	}
+	return permitted, listed
+}
+
+func (p *Policy) claimed(cloudMapNamespace string) bool {
+	for _, entries := range []map[string][]string{p.Namespaces, p.Teams} {
+		for _, namespaces := range entries {
+			for _, ns := range namespaces {
+				if ns == cloudMapNamespace {
+					return true
+				}
+			}
+		}
+	}
+	return false
+}
@@ -0,0 +1,111 @@
+package tenancy
+
+import (
+	"errors"
+	"github.com/stretchr/testify/assert"
+	"io/ioutil"
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"path/filepath"
+	"testing"
+)
+
+func TestPolicy_Permits(t *testing.T) {
+	policy := &Policy{
+		TeamLabel:  "team",
+		Teams:      map[string][]string{"payments": {"payments", "payments-shared"}},
+		Namespaces: map[string][]string{"legacy-billing": {"billing"}},
+	}
+
+	tests := []struct {
+		name      string
+		policy    *Policy
+		namespace *v1.Namespace
+		cloudMap  string
+		permitted bool
+	}{
+		{
+			name:      "nil policy",
+			namespace: testNamespace("payments", ""),
+			cloudMap:  "payments",
+			permitted: true,
+		},
+		{
+			name:      "team namespace",
+			policy:    policy,
+			namespace: testNamespace("payments-prod", "payments"),
+			cloudMap:  "payments-shared",
+			permitted: true,
+		},
+		{
+			name:      "other team claimed namespace",
+			policy:    policy,
+			namespace: testNamespace("payments", "growth"),
+			cloudMap:  "payments",
+			permitted: false,
+		},
+		{
+			name:      "listed namespace",
+			policy:    policy,
+			namespace: testNamespace("legacy-billing", ""),
+			cloudMap:  "billing",
+			permitted: true,
+		},
+		{
+			name:      "listed namespace outside its list",
+			policy:    policy,
+			namespace: testNamespace("legacy-billing", ""),
+			cloudMap:  "legacy-billing",
+			permitted: false,
+		},
+		{
+			name:      "unlisted namespace unclaimed",
+			policy:    policy,
+			namespace: testNamespace("sandbox", ""),
+			cloudMap:  "sandbox",
+			permitted: true,
+		},
+		{
+			name:      "unlisted namespace denied",
+			policy:    &Policy{DenyUnlisted: true},
+			namespace: testNamespace("sandbox", ""),
+			cloudMap:  "sandbox",
+			permitted: false,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.policy.Permits(tt.namespace, tt.cloudMap)
+			if tt.permitted {
+				assert.NoError(t, err)
+			} else {
+				assert.True(t, errors.Is(err, ErrNotPermitted), err)
+			}
+		})
+	}
+}
+
+func TestLoadPolicy(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "policy.yaml")
+	content := "teamLabel: team\nteams:\n  payments: [payments]\ndenyUnlisted: true\n"
+	assert.NoError(t, ioutil.WriteFile(path, []byte(content), 0600))
+
+	policy, err := LoadPolicy(path)
+	assert.NoError(t, err)
+	assert.Equal(t, &Policy{
+		TeamLabel:    "team",
+		Teams:        map[string][]string{"payments": {"payments"}},
+		DenyUnlisted: true,
+	}, policy)
+
+	_, err = LoadPolicy(filepath.Join(t.TempDir(), "missing.yaml"))
+	assert.Error(t, err)
+}
+
+func testNamespace(name string, team string) *v1.Namespace {
+	namespace := &v1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: name}}
+	if team != "" {
+		namespace.Labels = map[string]string{"team": team}
+	}
+	return namespace
+}
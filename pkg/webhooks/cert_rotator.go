@@ -0,0 +1,369 @@
+package webhooks
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/base64"
+	"encoding/pem"
+	"errors"
+	"flag"
+	"fmt"
+	"github.com/aws/aws-cloud-map-mcs-controller-for-k8s/pkg/common"
+	"io/ioutil"
+	admissionregistrationv1 "k8s.io/api/admissionregistration/v1"
+	v1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/types"
+	"math/big"
+	"os"
+	"path/filepath"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"time"
+)
+
+const (
+	caCertKey = "ca.crt"
+
+	defaultCertValidity      = 365 * 24 * time.Hour
+	defaultCertRefreshBefore = 30 * 24 * time.Hour
+	defaultCertCheckInterval = time.Hour
+)
+
+var crdGroupVersionKind = schema.GroupVersionKind{
+	Group:   "apiextensions.k8s.io",
+	Version: "v1",
+	Kind:    "CustomResourceDefinition",
+}
+
+// +kubebuilder:rbac:groups="",resources=secrets,verbs=get;create;update
+// +kubebuilder:rbac:groups=admissionregistration.k8s.io,resources=mutatingwebhookconfigurations;validatingwebhookconfigurations,verbs=get;patch
+// +kubebuilder:rbac:groups=apiextensions.k8s.io,resources=customresourcedefinitions,verbs=get;patch
+
+// CertRotatorConfig configures the built-in self-signed webhook serving certificate management.
+type CertRotatorConfig struct {
+	// Enabled turns on self-signed certificate provisioning and rotation.
+	Enabled bool
+	// SecretName is the Secret in the controller namespace the certificates are shared through between replicas.
+	SecretName string
+	// ServiceName is the name of the Service in front of the webhook server.
+	ServiceName string
+	// MutatingWebhookConfiguration is the name of the mutating webhook configuration to inject the CA bundle into.
+	MutatingWebhookConfiguration string
+	// ValidatingWebhookConfiguration is the name of the validating webhook configuration to inject the CA bundle into.
+	ValidatingWebhookConfiguration string
+	// Validity is the lifetime of generated certificates.
+	Validity time.Duration
+	// RefreshBefore is the remaining lifetime below which certificates are rotated.
+	RefreshBefore time.Duration
+	// CheckInterval is the interval the certificates are checked for rotation, the default interval is used if 0.
+	CheckInterval time.Duration
+}
+
+// NewDefaultCertRotatorConfig returns the default self-signed certificate settings, matching the names of the
+// default kustomize deployment.
+func NewDefaultCertRotatorConfig() *CertRotatorConfig {
+	return &CertRotatorConfig{
+		SecretName:                     "cloud-map-mcs-webhook-server-cert",
+		ServiceName:                    "cloud-map-mcs-webhook-service",
+		MutatingWebhookConfiguration:   "cloud-map-mcs-mutating-webhook-configuration",
+		ValidatingWebhookConfiguration: "cloud-map-mcs-validating-webhook-configuration",
+		Validity:                       defaultCertValidity,
+		RefreshBefore:                  defaultCertRefreshBefore,
+		CheckInterval:                  defaultCertCheckInterval,
+	}
+}
+
+// BindFlags registers the self-signed certificate settings as command line flags.
+func (c *CertRotatorConfig) BindFlags(fs *flag.FlagSet) {
+	fs.BoolVar(&c.Enabled, "self-signed-webhook-certs", c.Enabled,
+		"Provision and rotate self-signed webhook serving certificates and inject the CA bundle into the webhook "+
+			"configurations and CRDs, instead of relying on externally managed certificates.")
+	fs.StringVar(&c.SecretName, "webhook-cert-secret", c.SecretName,
+		"Secret in the controller namespace holding the self-signed webhook certificates.")
+	fs.StringVar(&c.ServiceName, "webhook-service", c.ServiceName,
+		"Name of the Service in front of the webhook server, used as subject of the self-signed certificate.")
+	fs.StringVar(&c.MutatingWebhookConfiguration, "mutating-webhook-configuration", c.MutatingWebhookConfiguration,
+		"Mutating webhook configuration to inject the self-signed CA bundle into.")
+	fs.StringVar(&c.ValidatingWebhookConfiguration, "validating-webhook-configuration", c.ValidatingWebhookConfiguration,
+		"Validating webhook configuration to inject the self-signed CA bundle into.")
+	fs.DurationVar(&c.Validity, "webhook-cert-validity", c.Validity,
+		"Lifetime of self-signed webhook certificates.")
+	fs.DurationVar(&c.RefreshBefore, "webhook-cert-refresh-before", c.RefreshBefore,
+		"Remaining lifetime below which self-signed webhook certificates are rotated.")
+	fs.DurationVar(&c.CheckInterval, "webhook-cert-check-interval", c.CheckInterval,
+		"Interval the self-signed webhook certificates are checked for rotation.")
+}
+
+// CertRotator provisions self-signed webhook serving certificates, shares them between replicas through a Secret,
+// writes them into the certificate directory watched by the webhook server, and injects the CA bundle into the
+// webhook configurations and the CRD conversion webhooks.
+type CertRotator struct {
+	Client    client.Client
+	Log       common.Logger
+	Config    *CertRotatorConfig
+	Namespace string
+	CertDir   string
+	// CRDs are the names of the CustomResourceDefinitions served by the conversion webhook.
+	CRDs []string
+
+	now func() time.Time
+}
+
+// certArtifacts are the PEM encoded certificate authority and serving certificate.
+type certArtifacts struct {
+	CACert []byte
+	Cert   []byte
+	Key    []byte
+}
+
+// Start periodically checks the certificates for rotation until the context is done.
+func (r *CertRotator) Start(ctx context.Context) error {
+	interval := r.Config.CheckInterval
+	if interval <= 0 {
+		interval = defaultCertCheckInterval
+	}
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+			if err := r.EnsureCerts(ctx); err != nil {
+				r.Log.Error(err, "failed to rotate webhook certificates")
+			}
+		}
+	}
+}
+
+// NeedLeaderElection returns false, every replica serves webhooks and needs the current certificates.
+func (r *CertRotator) NeedLeaderElection() bool {
+	return false
+}
+
+// EnsureCerts rotates the certificates if missing or about to expire, writes them to the certificate directory and
+// injects the CA bundle.
+func (r *CertRotator) EnsureCerts(ctx context.Context) error {
+	artifacts, err := r.ensureSecret(ctx)
+	if err != nil {
+		return err
+	}
+
+	if err = r.writeCerts(artifacts); err != nil {
+		return err
+	}
+
+	return r.injectCABundle(ctx, artifacts.CACert)
+}
+
+func (r *CertRotator) ensureSecret(ctx context.Context) (*certArtifacts, error) {
+	secret := &v1.Secret{}
+	err := r.Client.Get(ctx, types.NamespacedName{Namespace: r.Namespace, Name: r.Config.SecretName}, secret)
+	if err != nil && !apierrors.IsNotFound(err) {
+		return nil, err
+	}
+	exists := err == nil
+
+	if exists {
+		artifacts := &certArtifacts{
+			CACert: secret.Data[caCertKey],
+			Cert:   secret.Data[v1.TLSCertKey],
+			Key:    secret.Data[v1.TLSPrivateKeyKey],
+		}
+		if verifyCerts(artifacts, r.dnsName(), r.clock().Add(r.Config.RefreshBefore)) == nil {
+			return artifacts, nil
+		}
+	}
+
+	r.Log.Info("generating webhook certificates", "secret", r.Config.SecretName, "dnsName", r.dnsName())
+	artifacts, err := generateCerts(r.dnsName(), r.clock(), r.Config.Validity)
+	if err != nil {
+		return nil, err
+	}
+
+	secret.Data = map[string][]byte{
+		caCertKey:           artifacts.CACert,
+		v1.TLSCertKey:       artifacts.Cert,
+		v1.TLSPrivateKeyKey: artifacts.Key,
+	}
+
+	if exists {
+		err = r.Client.Update(ctx, secret)
+	} else {
+		secret.ObjectMeta = metav1.ObjectMeta{Namespace: r.Namespace, Name: r.Config.SecretName}
+		secret.Type = v1.SecretTypeTLS
+		err = r.Client.Create(ctx, secret)
+	}
+
+	// another replica rotated the certificates concurrently, use its certificates
+	if apierrors.IsConflict(err) || apierrors.IsAlreadyExists(err) {
+		return r.ensureSecret(ctx)
+	}
+
+	return artifacts, err
+}
+
+func (r *CertRotator) writeCerts(artifacts *certArtifacts) error {
+	if err := os.MkdirAll(r.CertDir, 0700); err != nil {
+		return err
+	}
+
+	files := map[string][]byte{
+		v1.TLSCertKey:       artifacts.Cert,
+		v1.TLSPrivateKeyKey: artifacts.Key,
+	}
+	for name, content := range files {
+		path := filepath.Join(r.CertDir, name)
+		if current, err := ioutil.ReadFile(path); err == nil && bytes.Equal(current, content) {
+			continue
+		}
+		if err := ioutil.WriteFile(path, content, 0600); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (r *CertRotator) injectCABundle(ctx context.Context, caBundle []byte) error {
+	if name := r.Config.MutatingWebhookConfiguration; name != "" {
+		config := &admissionregistrationv1.MutatingWebhookConfiguration{}
+		if err := r.Client.Get(ctx, types.NamespacedName{Name: name}, config); err != nil {
+			return err
+		}
+		patch := client.MergeFrom(config.DeepCopy())
+		for i := range config.Webhooks {
+			config.Webhooks[i].ClientConfig.CABundle = caBundle
+		}
+		if err := r.Client.Patch(ctx, config, patch); err != nil {
+			return err
+		}
+	}
+
+	if name := r.Config.ValidatingWebhookConfiguration; name != "" {
+		config := &admissionregistrationv1.ValidatingWebhookConfiguration{}
+		if err := r.Client.Get(ctx, types.NamespacedName{Name: name}, config); err != nil {
+			return err
+		}
+		patch := client.MergeFrom(config.DeepCopy())
+		for i := range config.Webhooks {
+			config.Webhooks[i].ClientConfig.CABundle = caBundle
+		}
+		if err := r.Client.Patch(ctx, config, patch); err != nil {
+			return err
+		}
+	}
+
+	for _, name := range r.CRDs {
+		crd := &unstructured.Unstructured{}
+		crd.SetGroupVersionKind(crdGroupVersionKind)
+		if err := r.Client.Get(ctx, types.NamespacedName{Name: name}, crd); err != nil {
+			return err
+		}
+		patch := client.MergeFrom(crd.DeepCopy())
+		err := unstructured.SetNestedField(crd.Object, base64.StdEncoding.EncodeToString(caBundle),
+			"spec", "conversion", "webhook", "clientConfig", "caBundle")
+		if err != nil {
+			return err
+		}
+		if err = r.Client.Patch(ctx, crd, patch); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (r *CertRotator) dnsName() string {
+	return fmt.Sprintf("%s.%s.svc", r.Config.ServiceName, r.Namespace)
+}
+
+func (r *CertRotator) clock() time.Time {
+	if r.now != nil {
+		return r.now()
+	}
+	return time.Now()
+}
+
+// generateCerts creates a self-signed certificate authority and a serving certificate for the DNS name signed by it.
+func generateCerts(dnsName string, now time.Time, validity time.Duration) (*certArtifacts, error) {
+	caKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		return nil, err
+	}
+	caTemplate := &x509.Certificate{
+		SerialNumber:          big.NewInt(now.UnixNano()),
+		Subject:               pkix.Name{CommonName: dnsName + "-ca"},
+		NotBefore:             now.Add(-time.Hour),
+		NotAfter:              now.Add(validity),
+		KeyUsage:              x509.KeyUsageCertSign | x509.KeyUsageDigitalSignature,
+		BasicConstraintsValid: true,
+		IsCA:                  true,
+	}
+	caDER, err := x509.CreateCertificate(rand.Reader, caTemplate, caTemplate, &caKey.PublicKey, caKey)
+	if err != nil {
+		return nil, err
+	}
+	caCert, err := x509.ParseCertificate(caDER)
+	if err != nil {
+		return nil, err
+	}
+
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		return nil, err
+	}
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(now.UnixNano() + 1),
+		Subject:      pkix.Name{CommonName: dnsName},
+		DNSNames:     []string{dnsName, dnsName + ".cluster.local"},
+		NotBefore:    now.Add(-time.Hour),
+		NotAfter:     now.Add(validity),
+		KeyUsage:     x509.KeyUsageKeyEncipherment | x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, caCert, &key.PublicKey, caKey)
+	if err != nil {
+		return nil, err
+	}
+
+	return &certArtifacts{
+		CACert: pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: caDER}),
+		Cert:   pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}),
+		Key:    pem.EncodeToMemory(&pem.Block{Type: "RSA PRIVATE KEY", Bytes: x509.MarshalPKCS1PrivateKey(key)}),
+	}, nil
+}
+
+// verifyCerts checks the serving certificate matches its key, is signed by the certificate authority, and is valid
+// for the DNS name at the given time.
+func verifyCerts(artifacts *certArtifacts, dnsName string, at time.Time) error {
+	roots := x509.NewCertPool()
+	if !roots.AppendCertsFromPEM(artifacts.CACert) {
+		return errors.New("invalid CA certificate")
+	}
+
+	block, _ := pem.Decode(artifacts.Cert)
+	if block == nil {
+		return errors.New("invalid serving certificate")
+	}
+	cert, err := x509.ParseCertificate(block.Bytes)
+	if err != nil {
+		return err
+	}
+
+	if _, err = tls.X509KeyPair(artifacts.Cert, artifacts.Key); err != nil {
+		return err
+	}
+
+	_, err = cert.Verify(x509.VerifyOptions{DNSName: dnsName, Roots: roots, CurrentTime: at})
+	return err
+}
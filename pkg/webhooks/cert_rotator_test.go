@@ -0,0 +1,84 @@
+package webhooks
+
+import (
+	"context"
+	"github.com/aws/aws-cloud-map-mcs-controller-for-k8s/pkg/common"
+	testingLogger "github.com/go-logr/logr/testing"
+	"github.com/stretchr/testify/assert"
+	"io/ioutil"
+	admissionregistrationv1 "k8s.io/api/admissionregistration/v1"
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"path/filepath"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	"testing"
+	"time"
+)
+
+const testControllerNamespace = "cloud-map-mcs-system"
+
+func TestGenerateCerts(t *testing.T) {
+	now := time.Now()
+	artifacts, err := generateCerts("svc.ns.svc", now, time.Hour)
+	assert.NoError(t, err)
+
+	assert.NoError(t, verifyCerts(artifacts, "svc.ns.svc", now))
+	assert.Error(t, verifyCerts(artifacts, "other.ns.svc", now), "wrong DNS name")
+	assert.Error(t, verifyCerts(artifacts, "svc.ns.svc", now.Add(2*time.Hour)), "expired")
+	assert.Error(t, verifyCerts(&certArtifacts{}, "svc.ns.svc", now), "empty")
+}
+
+func TestCertRotator_EnsureCerts(t *testing.T) {
+	scheme := testScheme()
+	_ = admissionregistrationv1.AddToScheme(scheme)
+	fakeClient := fake.NewClientBuilder().WithScheme(scheme).WithObjects(
+		&admissionregistrationv1.ValidatingWebhookConfiguration{
+			ObjectMeta: metav1.ObjectMeta{Name: "validating"},
+			Webhooks:   []admissionregistrationv1.ValidatingWebhook{{Name: "vserviceexport.multicluster.x-k8s.io"}},
+		}).Build()
+
+	now := time.Now()
+	rotator := &CertRotator{
+		Client: fakeClient,
+		Log:    common.NewLoggerWithLogr(testingLogger.TestLogger{T: t}),
+		Config: &CertRotatorConfig{
+			SecretName:                     "cert",
+			ServiceName:                    "webhook-service",
+			ValidatingWebhookConfiguration: "validating",
+			Validity:                       48 * time.Hour,
+			RefreshBefore:                  24 * time.Hour,
+		},
+		Namespace: testControllerNamespace,
+		CertDir:   t.TempDir(),
+		now:       func() time.Time { return now },
+	}
+
+	assert.NoError(t, rotator.EnsureCerts(context.TODO()))
+	initial := getCertSecret(t, rotator)
+
+	cert, err := ioutil.ReadFile(filepath.Join(rotator.CertDir, v1.TLSCertKey))
+	assert.NoError(t, err)
+	assert.Equal(t, initial.Data[v1.TLSCertKey], cert)
+
+	config := &admissionregistrationv1.ValidatingWebhookConfiguration{}
+	assert.NoError(t, fakeClient.Get(context.TODO(), types.NamespacedName{Name: "validating"}, config))
+	assert.Equal(t, initial.Data[caCertKey], config.Webhooks[0].ClientConfig.CABundle)
+
+	// certificates are kept while valid
+	now = now.Add(12 * time.Hour)
+	assert.NoError(t, rotator.EnsureCerts(context.TODO()))
+	assert.Equal(t, initial.Data, getCertSecret(t, rotator).Data)
+
+	// certificates are rotated before they expire
+	now = now.Add(24 * time.Hour)
+	assert.NoError(t, rotator.EnsureCerts(context.TODO()))
+	assert.NotEqual(t, initial.Data[v1.TLSCertKey], getCertSecret(t, rotator).Data[v1.TLSCertKey])
+}
+
+func getCertSecret(t *testing.T, rotator *CertRotator) *v1.Secret {
+	secret := &v1.Secret{}
+	err := rotator.Client.Get(context.TODO(), types.NamespacedName{Namespace: testControllerNamespace, Name: "cert"}, secret)
+	assert.NoError(t, err)
+	return secret
+}
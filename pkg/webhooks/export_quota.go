@@ -0,0 +1,90 @@
+package webhooks
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"github.com/aws/aws-cloud-map-mcs-controller-for-k8s/pkg/api/v1alpha1"
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// +kubebuilder:rbac:groups="",resources=namespaces,verbs=get;list
+
+// ExportQuota limits the number of ServiceExports per namespace and per team, protecting the shared Cloud Map
+// namespaces from runaway automation. Teams are identified by the value of TeamLabel on Kubernetes namespaces.
+// Concurrent creations may exceed a quota by the number of requests admitted in parallel.
+type ExportQuota struct {
+	// MaxPerNamespace is the maximum number of ServiceExports in a namespace, 0 disables the limit.
+	MaxPerNamespace int
+	// TeamLabel is the namespace label identifying the team owning the namespace.
+	TeamLabel string
+	// MaxPerTeam is the maximum number of ServiceExports across all namespaces of a team, 0 disables the limit.
+	MaxPerTeam int
+}
+
+// BindFlags registers the export quota settings as command line flags.
+func (q *ExportQuota) BindFlags(fs *flag.FlagSet) {
+	fs.IntVar(&q.MaxPerNamespace, "max-exports-per-namespace", q.MaxPerNamespace,
+		"Maximum number of ServiceExports per namespace, 0 disables the limit.")
+	fs.StringVar(&q.TeamLabel, "team-label", q.TeamLabel,
+		"Namespace label identifying the team owning a namespace, used for per-team export quotas.")
+	fs.IntVar(&q.MaxPerTeam, "max-exports-per-team", q.MaxPerTeam,
+		"Maximum number of ServiceExports across all namespaces of a team, 0 disables the limit.")
+}
+
+// check returns a message describing the exceeded quota if creating the ServiceExport would exceed a quota, or an
+// empty string otherwise. The namespaces of the team and their ServiceExports are read with the namespace reader, they
+// may not be watched by the manager.
+func (q *ExportQuota) check(ctx context.Context, c client.Client, namespaceReader client.Reader, serviceExport *v1alpha1.ServiceExport) (string, error) {
+	if q.MaxPerNamespace > 0 {
+		count, err := countExports(ctx, c, serviceExport.Namespace)
+		if err != nil {
+			return "", err
+		}
+		if count >= q.MaxPerNamespace {
+			return fmt.Sprintf("namespace %s exceeds the quota of %d ServiceExports",
+				serviceExport.Namespace, q.MaxPerNamespace), nil
+		}
+	}
+
+	if q.MaxPerTeam > 0 && q.TeamLabel != "" {
+		namespace := &v1.Namespace{}
+		if err := namespaceReader.Get(ctx, types.NamespacedName{Name: serviceExport.Namespace}, namespace); err != nil {
+			return "", err
+		}
+
+		team, found := namespace.Labels[q.TeamLabel]
+		if !found {
+			return "", nil
+		}
+
+		teamNamespaces := &v1.NamespaceList{}
+		if err := namespaceReader.List(ctx, teamNamespaces, client.MatchingLabels{q.TeamLabel: team}); err != nil {
+			return "", err
+		}
+
+		count := 0
+		for _, ns := range teamNamespaces.Items {
+			nsCount, err := countExports(ctx, namespaceReader, ns.Name)
+			if err != nil {
+				return "", err
+			}
+			count += nsCount
+		}
+		if count >= q.MaxPerTeam {
+			return fmt.Sprintf("team %s exceeds the quota of %d ServiceExports", team, q.MaxPerTeam), nil
+		}
+	}
+
+	return "", nil
+}
+
+func countExports(ctx context.Context, c client.Reader, namespace string) (int, error) {
+	exports := &v1alpha1.ServiceExportList{}
+	if err := c.List(ctx, exports, client.InNamespace(namespace)); err != nil {
+		return 0, err
+	}
+	return len(exports.Items), nil
+}
@@ -0,0 +1,88 @@
+package webhooks
+
+import (
+	"context"
+	"github.com/aws/aws-cloud-map-mcs-controller-for-k8s/pkg/api/v1alpha1"
+	"github.com/aws/aws-cloud-map-mcs-controller-for-k8s/test"
+	"github.com/stretchr/testify/assert"
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	"testing"
+)
+
+func TestExportQuota_Check(t *testing.T) {
+	objs := []client.Object{
+		testTeamNamespace(test.NsName, "a"),
+		testTeamNamespace("other-ns", "a"),
+		testTeamNamespace("unowned-ns", ""),
+		testServiceExport(test.NsName, "svc-1"),
+		testServiceExport("other-ns", "svc-2"),
+	}
+
+	tests := []struct {
+		name      string
+		quota     ExportQuota
+		namespace string
+		exceeded  bool
+	}{
+		{
+			name:      "no quota",
+			quota:     ExportQuota{},
+			namespace: test.NsName,
+			exceeded:  false,
+		},
+		{
+			name:      "below namespace quota",
+			quota:     ExportQuota{MaxPerNamespace: 2},
+			namespace: test.NsName,
+			exceeded:  false,
+		},
+		{
+			name:      "namespace quota reached",
+			quota:     ExportQuota{MaxPerNamespace: 1},
+			namespace: test.NsName,
+			exceeded:  true,
+		},
+		{
+			name:      "below team quota",
+			quota:     ExportQuota{TeamLabel: "team", MaxPerTeam: 3},
+			namespace: test.NsName,
+			exceeded:  false,
+		},
+		{
+			name:      "team quota reached across namespaces",
+			quota:     ExportQuota{TeamLabel: "team", MaxPerTeam: 2},
+			namespace: test.NsName,
+			exceeded:  true,
+		},
+		{
+			name:      "namespace without team",
+			quota:     ExportQuota{TeamLabel: "team", MaxPerTeam: 1},
+			namespace: "unowned-ns",
+			exceeded:  false,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := fake.NewClientBuilder().WithScheme(testScheme()).WithObjects(objs...).Build()
+
+			exceeded, err := tt.quota.check(context.TODO(), c, c, testServiceExport(tt.namespace, test.SvcName))
+			assert.NoError(t, err)
+			assert.Equal(t, tt.exceeded, exceeded != "", exceeded)
+		})
+	}
+}
+
+func testTeamNamespace(name string, team string) *v1.Namespace {
+	namespace := &v1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: name}}
+	if team != "" {
+		namespace.Labels = map[string]string{"team": team}
+	}
+	return namespace
+}
+
+func testServiceExport(namespace string, name string) *v1alpha1.ServiceExport {
+	return &v1alpha1.ServiceExport{ObjectMeta: metav1.ObjectMeta{Namespace: namespace, Name: name}}
+}
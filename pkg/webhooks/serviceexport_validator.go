@@ -0,0 +1,213 @@
+package webhooks
+
+import (
+	"context"
+	"encoding/json"
+	goerrors "errors"
+	"fmt"
+	"github.com/aws/aws-cloud-map-mcs-controller-for-k8s/pkg/api/v1alpha1"
+	"github.com/aws/aws-cloud-map-mcs-controller-for-k8s/pkg/common"
+	"github.com/aws/aws-cloud-map-mcs-controller-for-k8s/pkg/controllers"
+	"github.com/aws/aws-cloud-map-mcs-controller-for-k8s/pkg/tenancy"
+	admissionv1 "k8s.io/api/admission/v1"
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/util/sets"
+	"k8s.io/apimachinery/pkg/util/validation"
+	"net/http"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/webhook/admission"
+	"strings"
+	"sync"
+)
+
+const (
+	// ServiceExportValidatePath is the path the ServiceExport validating webhook is served on.
+	ServiceExportValidatePath = "/validate-multicluster-x-k8s-io-v1alpha1-serviceexport"
+
+	// maxAttributeValueLength is the maximum length of a Cloud Map instance attribute value
+	maxAttributeValueLength = 1024
+	// reservedKeyPrefix is the prefix of labels and annotations managed by the controller
+	reservedKeyPrefix = "multicluster.k8s.aws/"
+
+	// DefaultProtectedNamespaces are the namespaces whose Services are never exported by default.
+	DefaultProtectedNamespaces = "kube-system,kube-public"
+)
+
+// +kubebuilder:webhook:path=/validate-multicluster-x-k8s-io-v1alpha1-serviceexport,mutating=false,failurePolicy=fail,sideEffects=None,groups=multicluster.x-k8s.io,resources=serviceexports,verbs=create;update,versions=v1alpha1,name=vserviceexport.multicluster.x-k8s.io,admissionReviewVersions={v1,v1beta1}
+
+// ServiceExportValidator rejects ServiceExports which cannot be exported to Cloud Map, so users get synchronous
+// feedback instead of reconcile failures.
+type ServiceExportValidator struct {
+	Client client.Client
+	Log    common.Logger
+	Quota  ExportQuota
+	// AllowMissingServices admits ServiceExports whose Service doesn't exist yet with a warning, e.g. for GitOps tools
+	// applying both at once, the controller reports the pending export in its Valid condition. They are denied
+	// otherwise.
+	AllowMissingServices bool
+	// ProtectedNamespaces are the namespaces in which ServiceExports cannot be created, use SetProtectedNamespaces
+	// to change them at runtime.
+	ProtectedNamespaces sets.String
+	// TenancyPolicy restricts the Cloud Map namespaces exports may publish into, nil permits all.
+	TenancyPolicy *tenancy.Policy
+	// NamespaceReader reads the namespaces checked by the tenancy policy and the export quota bypassing the cache of
+	// the manager, which can't read cluster scoped objects when restricted to the watched namespaces. The client is
+	// used if nil.
+	NamespaceReader client.Reader
+	// ClusterConfig maps Kubernetes namespaces to Cloud Map namespaces along with their CloudMapSyncConfigs, nil
+	// uses the same names.
+	ClusterConfig *controllers.ClusterConfig
+	decoder       *admission.Decoder
+	mu            sync.RWMutex
+}
+
+// SetProtectedNamespaces changes the protected namespaces at runtime.
+func (v *ServiceExportValidator) SetProtectedNamespaces(namespaces sets.String) {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	v.ProtectedNamespaces = namespaces
+}
+
+func (v *ServiceExportValidator) namespaceReader() client.Reader {
+	if v.NamespaceReader != nil {
+		return v.NamespaceReader
+	}
+	return v.Client
+}
+
+func (v *ServiceExportValidator) isProtected(namespace string) bool {
+	v.mu.RLock()
+	defer v.mu.RUnlock()
+	return v.ProtectedNamespaces.Has(namespace)
+}
+
+// NewProtectedNamespaces returns the set of protected namespaces from a comma separated list, including the
+// namespace the controller runs in if known.
+func NewProtectedNamespaces(namespaces string, controllerNamespace string) sets.String {
+	protected := sets.NewString(common.SplitNamespaces(namespaces)...)
+	if controllerNamespace != "" {
+		protected.Insert(controllerNamespace)
+	}
+	return protected
+}
+
+// Handle validates the ServiceExport of an admission request.
+func (v *ServiceExportValidator) Handle(ctx context.Context, req admission.Request) admission.Response {
+	serviceExport := &v1alpha1.ServiceExport{}
+	if err := v.decoder.Decode(req, serviceExport); err != nil {
+		return admission.Errored(http.StatusBadRequest, err)
+	}
+
+	// never block finalizer removal of deleted exports
+	if serviceExport.DeletionTimestamp != nil {
+		return admission.Allowed("")
+	}
+
+	if errs := ValidateExportedMetadata(&serviceExport.Spec); len(errs) > 0 {
+		return admission.Denied(strings.Join(errs, "; "))
+	}
+
+	if req.Operation != admissionv1.Create {
+		return admission.Allowed("")
+	}
+
+	if v.isProtected(serviceExport.Namespace) {
+		return admission.Denied(fmt.Sprintf("Services in protected namespace %s cannot be exported", serviceExport.Namespace))
+	}
+
+	if errs := validation.IsDNS1035Label(serviceExport.Name); len(errs) > 0 {
+		return admission.Denied(fmt.Sprintf("name %s is not a valid Cloud Map service name: %s",
+			serviceExport.Name, strings.Join(errs, ", ")))
+	}
+
+	warnings := make([]string, 0)
+	service := &v1.Service{}
+	namespacedName := types.NamespacedName{Namespace: serviceExport.Namespace, Name: serviceExport.Name}
+	if err := v.Client.Get(ctx, namespacedName, service); err != nil {
+		if !errors.IsNotFound(err) {
+			v.Log.Error(err, "error fetching service", "namespace", serviceExport.Namespace, "name", serviceExport.Name)
+			return admission.Errored(http.StatusInternalServerError, err)
+		}
+		if !v.AllowMissingServices {
+			return admission.Denied(fmt.Sprintf("no Service found for ServiceExport %s", namespacedName))
+		}
+		warnings = append(warnings, fmt.Sprintf("no Service found for ServiceExport %s, the export is pending "+
+			"until the Service is created", namespacedName))
+	} else if service.Spec.Type == v1.ServiceTypeExternalName {
+		return admission.Denied(fmt.Sprintf("Service %s of type %s cannot be exported", namespacedName, service.Spec.Type))
+	}
+
+	if v.TenancyPolicy != nil {
+		namespace := &v1.Namespace{}
+		if err := v.namespaceReader().Get(ctx, types.NamespacedName{Name: serviceExport.Namespace}, namespace); err != nil {
+			v.Log.Error(err, "error fetching namespace", "namespace", serviceExport.Namespace)
+			return admission.Errored(http.StatusInternalServerError, err)
+		}
+		settings, err := controllers.ResolveSyncSettings(ctx, v.Client, v.ClusterConfig, serviceExport.Namespace)
+		if goerrors.Is(err, controllers.ErrInvalidSyncConfig) {
+			return admission.Denied(err.Error())
+		}
+		if err != nil {
+			v.Log.Error(err, "error resolving sync settings", "namespace", serviceExport.Namespace)
+			return admission.Errored(http.StatusInternalServerError, err)
+		}
+		if err := v.TenancyPolicy.Permits(namespace, settings.CloudMapNamespace); err != nil {
+			return admission.Denied(err.Error())
+		}
+	}
+
+	exceeded, err := v.Quota.check(ctx, v.Client, v.namespaceReader(), serviceExport)
+	if err != nil {
+		v.Log.Error(err, "error checking export quota", "namespace", serviceExport.Namespace, "name", serviceExport.Name)
+		return admission.Errored(http.StatusInternalServerError, err)
+	}
+	if exceeded != "" {
+		return admission.Denied(exceeded)
+	}
+
+	response := admission.Allowed("")
+	response.Warnings = warnings
+	return response
+}
+
+// InjectDecoder injects the decoder for admission request objects.
+func (v *ServiceExportValidator) InjectDecoder(d *admission.Decoder) error {
+	v.decoder = d
+	return nil
+}
+
+// ValidateExportedMetadata returns the validation errors of the labels and annotations exported by a ServiceExport.
+func ValidateExportedMetadata(spec *v1alpha1.ServiceExportSpec) (errs []string) {
+	for key, value := range spec.ExportedLabels {
+		for _, msg := range validation.IsQualifiedName(key) {
+			errs = append(errs, fmt.Sprintf("spec.exportedLabels key %q: %s", key, msg))
+		}
+		for _, msg := range validation.IsValidLabelValue(value) {
+			errs = append(errs, fmt.Sprintf("spec.exportedLabels[%s] value %q: %s", key, value, msg))
+		}
+		if strings.HasPrefix(key, reservedKeyPrefix) {
+			errs = append(errs, fmt.Sprintf("spec.exportedLabels key %q uses the reserved prefix %s", key, reservedKeyPrefix))
+		}
+	}
+
+	for key := range spec.ExportedAnnotations {
+		for _, msg := range validation.IsQualifiedName(strings.ToLower(key)) {
+			errs = append(errs, fmt.Sprintf("spec.exportedAnnotations key %q: %s", key, msg))
+		}
+		if strings.HasPrefix(key, reservedKeyPrefix) {
+			errs = append(errs, fmt.Sprintf("spec.exportedAnnotations key %q uses the reserved prefix %s", key, reservedKeyPrefix))
+		}
+	}
+
+	// exported metadata is stored JSON encoded in Cloud Map instance attributes
+	if encoded, _ := json.Marshal(spec.ExportedLabels); len(spec.ExportedLabels) > 0 && len(encoded) > maxAttributeValueLength {
+		errs = append(errs, fmt.Sprintf("spec.exportedLabels must not exceed %d bytes when encoded", maxAttributeValueLength))
+	}
+	if encoded, _ := json.Marshal(spec.ExportedAnnotations); len(spec.ExportedAnnotations) > 0 && len(encoded) > maxAttributeValueLength {
+		errs = append(errs, fmt.Sprintf("spec.exportedAnnotations must not exceed %d bytes when encoded", maxAttributeValueLength))
+	}
+
+	return errs
+}
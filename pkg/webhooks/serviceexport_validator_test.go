@@ -0,0 +1,175 @@
+package webhooks
+
+import (
+	"context"
+	"encoding/json"
+	"github.com/aws/aws-cloud-map-mcs-controller-for-k8s/pkg/api/v1alpha1"
+	"github.com/aws/aws-cloud-map-mcs-controller-for-k8s/pkg/common"
+	"github.com/aws/aws-cloud-map-mcs-controller-for-k8s/test"
+	testingLogger "github.com/go-logr/logr/testing"
+	"github.com/stretchr/testify/assert"
+	admissionv1 "k8s.io/api/admission/v1"
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	"sigs.k8s.io/controller-runtime/pkg/webhook/admission"
+	"testing"
+)
+
+func TestServiceExportValidator_Handle(t *testing.T) {
+	tests := []struct {
+		name      string
+		export    string
+		services  []client.Object
+		labels    map[string]string
+		namespace string
+		// allowMissing admits exports of missing Services
+		allowMissing bool
+		op           admissionv1.Operation
+		allowed      bool
+		warned       bool
+	}{
+		{
+			name:     "valid export",
+			export:   test.SvcName,
+			services: []client.Object{testService(test.SvcName, v1.ServiceTypeClusterIP)},
+			op:       admissionv1.Create,
+			allowed:  true,
+		},
+		{
+			name:    "service not found",
+			export:  test.SvcName,
+			op:      admissionv1.Create,
+			allowed: false,
+		},
+		{
+			name:         "service not found allowed",
+			export:       test.SvcName,
+			allowMissing: true,
+			op:           admissionv1.Create,
+			allowed:      true,
+			warned:       true,
+		},
+		{
+			name:         "external name service",
+			export:       test.SvcName,
+			services:     []client.Object{testService(test.SvcName, v1.ServiceTypeExternalName)},
+			allowMissing: true,
+			op:           admissionv1.Create,
+			allowed:      false,
+		},
+		{
+			name:     "invalid name",
+			export:   "1-svc",
+			services: []client.Object{testService("1-svc", v1.ServiceTypeClusterIP)},
+			op:       admissionv1.Create,
+			allowed:  false,
+		},
+		{
+			name:    "update skips service checks",
+			export:  test.SvcName,
+			op:      admissionv1.Update,
+			allowed: true,
+		},
+		{
+			name:     "invalid exported label",
+			export:   test.SvcName,
+			services: []client.Object{testService(test.SvcName, v1.ServiceTypeClusterIP)},
+			labels:   map[string]string{"team": "not a label value"},
+			op:       admissionv1.Update,
+			allowed:  false,
+		},
+		{
+			name:     "reserved exported label",
+			export:   test.SvcName,
+			services: []client.Object{testService(test.SvcName, v1.ServiceTypeClusterIP)},
+			labels:   map[string]string{"multicluster.k8s.aws/team": "a"},
+			op:       admissionv1.Create,
+			allowed:  false,
+		},
+		{
+			name:     "valid exported label",
+			export:   test.SvcName,
+			services: []client.Object{testService(test.SvcName, v1.ServiceTypeClusterIP)},
+			labels:   map[string]string{"example.com/team": "a"},
+			op:       admissionv1.Create,
+			allowed:  true,
+		},
+		{
+			name:      "protected namespace",
+			export:    test.SvcName,
+			namespace: "kube-system",
+			op:        admissionv1.Create,
+			allowed:   false,
+		},
+		{
+			name:      "controller namespace",
+			export:    test.SvcName,
+			namespace: "cloud-map-mcs-system",
+			op:        admissionv1.Create,
+			allowed:   false,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			validator := &ServiceExportValidator{
+				Client:               fake.NewClientBuilder().WithScheme(testScheme()).WithObjects(tt.services...).Build(),
+				Log:                  common.NewLoggerWithLogr(testingLogger.TestLogger{T: t}),
+				ProtectedNamespaces:  NewProtectedNamespaces(DefaultProtectedNamespaces, "cloud-map-mcs-system"),
+				AllowMissingServices: tt.allowMissing,
+			}
+			decoder, err := admission.NewDecoder(testScheme())
+			assert.NoError(t, err)
+			assert.NoError(t, validator.InjectDecoder(decoder))
+
+			namespace := tt.namespace
+			if namespace == "" {
+				namespace = test.NsName
+			}
+			serviceExport := &v1alpha1.ServiceExport{
+				TypeMeta:   metav1.TypeMeta{APIVersion: v1alpha1.GroupVersion.String(), Kind: "ServiceExport"},
+				ObjectMeta: metav1.ObjectMeta{Namespace: namespace, Name: tt.export},
+				Spec:       v1alpha1.ServiceExportSpec{ExportedLabels: tt.labels},
+			}
+
+			resp := validator.Handle(context.TODO(), admissionRequest(t, tt.op, serviceExport))
+			assert.Equal(t, tt.allowed, resp.Allowed, resp.Result)
+			assert.Equal(t, tt.warned, len(resp.Warnings) > 0, resp.Warnings)
+		})
+	}
+}
+
+func testScheme() *runtime.Scheme {
+	scheme := runtime.NewScheme()
+	_ = v1.AddToScheme(scheme)
+	_ = v1alpha1.AddToScheme(scheme)
+	return scheme
+}
+
+func testService(name string, serviceType v1.ServiceType) *v1.Service {
+	return &v1.Service{
+		ObjectMeta: metav1.ObjectMeta{Namespace: test.NsName, Name: name},
+		Spec:       v1.ServiceSpec{Type: serviceType},
+	}
+}
+
+func admissionRequest(t *testing.T, op admissionv1.Operation, obj runtime.Object) admission.Request {
+	raw, err := json.Marshal(obj)
+	assert.NoError(t, err)
+
+	return admission.Request{
+		AdmissionRequest: admissionv1.AdmissionRequest{
+			Operation: op,
+			Object:    runtime.RawExtension{Raw: raw},
+		},
+	}
+}
+
+func TestNewProtectedNamespaces(t *testing.T) {
+	protected := NewProtectedNamespaces(" kube-system, ,kube-public", "cloud-map-mcs-system")
+	assert.Equal(t, []string{"cloud-map-mcs-system", "kube-public", "kube-system"}, protected.List())
+
+	assert.Empty(t, NewProtectedNamespaces("", "").List())
+}
@@ -0,0 +1,59 @@
+package webhooks
+
+import (
+	"context"
+	"encoding/json"
+	"github.com/aws/aws-cloud-map-mcs-controller-for-k8s/pkg/api/v1alpha1"
+	v1 "k8s.io/api/core/v1"
+	"net/http"
+	"sigs.k8s.io/controller-runtime/pkg/webhook/admission"
+)
+
+// ServiceImportDefaultPath is the path the ServiceImport defaulting webhook is served on.
+const ServiceImportDefaultPath = "/mutate-multicluster-x-k8s-io-v1alpha1-serviceimport"
+
+// +kubebuilder:webhook:path=/mutate-multicluster-x-k8s-io-v1alpha1-serviceimport,mutating=true,failurePolicy=fail,sideEffects=None,groups=multicluster.x-k8s.io,resources=serviceimports,verbs=create;update,versions=v1alpha1,name=mserviceimport.multicluster.x-k8s.io,admissionReviewVersions={v1,v1beta1}
+
+// ServiceImportDefaulter sets defaults for fields omitted from ServiceImports.
+type ServiceImportDefaulter struct {
+	decoder *admission.Decoder
+}
+
+// Handle defaults the ServiceImport of an admission request.
+func (d *ServiceImportDefaulter) Handle(_ context.Context, req admission.Request) admission.Response {
+	serviceImport := &v1alpha1.ServiceImport{}
+	if err := d.decoder.Decode(req, serviceImport); err != nil {
+		return admission.Errored(http.StatusBadRequest, err)
+	}
+
+	DefaultServiceImport(serviceImport)
+
+	marshaled, err := json.Marshal(serviceImport)
+	if err != nil {
+		return admission.Errored(http.StatusInternalServerError, err)
+	}
+	return admission.PatchResponseFromRaw(req.Object.Raw, marshaled)
+}
+
+// InjectDecoder injects the decoder for admission request objects.
+func (d *ServiceImportDefaulter) InjectDecoder(decoder *admission.Decoder) error {
+	d.decoder = decoder
+	return nil
+}
+
+// DefaultServiceImport sets the type, session affinity and port protocols of a ServiceImport if omitted.
+func DefaultServiceImport(serviceImport *v1alpha1.ServiceImport) {
+	if serviceImport.Spec.Type == "" {
+		serviceImport.Spec.Type = v1alpha1.ClusterSetIP
+	}
+
+	if serviceImport.Spec.SessionAffinity == "" {
+		serviceImport.Spec.SessionAffinity = v1.ServiceAffinityNone
+	}
+
+	for i := range serviceImport.Spec.Ports {
+		if serviceImport.Spec.Ports[i].Protocol == "" {
+			serviceImport.Spec.Ports[i].Protocol = v1.ProtocolTCP
+		}
+	}
+}
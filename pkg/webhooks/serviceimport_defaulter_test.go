@@ -0,0 +1,44 @@
+package webhooks
+
+import (
+	"context"
+	"github.com/aws/aws-cloud-map-mcs-controller-for-k8s/pkg/api/v1alpha1"
+	"github.com/aws/aws-cloud-map-mcs-controller-for-k8s/test"
+	"github.com/stretchr/testify/assert"
+	admissionv1 "k8s.io/api/admission/v1"
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/webhook/admission"
+	"testing"
+)
+
+func TestDefaultServiceImport(t *testing.T) {
+	serviceImport := &v1alpha1.ServiceImport{
+		Spec: v1alpha1.ServiceImportSpec{
+			Ports: []v1alpha1.ServicePort{{Port: test.ServicePort1}, {Port: test.Port2, Protocol: v1.ProtocolUDP}},
+		},
+	}
+
+	DefaultServiceImport(serviceImport)
+
+	assert.Equal(t, v1alpha1.ClusterSetIP, serviceImport.Spec.Type)
+	assert.Equal(t, v1.ServiceAffinityNone, serviceImport.Spec.SessionAffinity)
+	assert.Equal(t, v1.ProtocolTCP, serviceImport.Spec.Ports[0].Protocol)
+	assert.Equal(t, v1.ProtocolUDP, serviceImport.Spec.Ports[1].Protocol)
+}
+
+func TestServiceImportDefaulter_Handle(t *testing.T) {
+	defaulter := &ServiceImportDefaulter{}
+	decoder, err := admission.NewDecoder(testScheme())
+	assert.NoError(t, err)
+	assert.NoError(t, defaulter.InjectDecoder(decoder))
+
+	serviceImport := &v1alpha1.ServiceImport{
+		TypeMeta:   metav1.TypeMeta{APIVersion: v1alpha1.GroupVersion.String(), Kind: "ServiceImport"},
+		ObjectMeta: metav1.ObjectMeta{Namespace: test.NsName, Name: test.SvcName},
+	}
+
+	resp := defaulter.Handle(context.TODO(), admissionRequest(t, admissionv1.Create, serviceImport))
+	assert.True(t, resp.Allowed)
+	assert.NotEmpty(t, resp.Patches, "type and session affinity are defaulted")
+}
@@ -0,0 +1,145 @@
+package webhooks
+
+import (
+	"context"
+	"fmt"
+	"github.com/aws/aws-cloud-map-mcs-controller-for-k8s/pkg/api/v1alpha1"
+	"github.com/aws/aws-cloud-map-mcs-controller-for-k8s/pkg/common"
+	"github.com/aws/aws-cloud-map-mcs-controller-for-k8s/pkg/controllers"
+	admissionv1 "k8s.io/api/admission/v1"
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/util/validation"
+	"net"
+	"net/http"
+	"reflect"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/webhook/admission"
+	"strings"
+)
+
+// ServiceImportValidatePath is the path the ServiceImport validating webhook is served on.
+const ServiceImportValidatePath = "/validate-multicluster-x-k8s-io-v1alpha1-serviceimport"
+
+// +kubebuilder:webhook:path=/validate-multicluster-x-k8s-io-v1alpha1-serviceimport,mutating=false,failurePolicy=fail,sideEffects=None,groups=multicluster.x-k8s.io,resources=serviceimports,verbs=create;update,versions=v1alpha1,name=vserviceimport.multicluster.x-k8s.io,admissionReviewVersions={v1,v1beta1}
+
+// ServiceImportValidator rejects malformed ServiceImports, which would otherwise wedge the derived Service reconciler.
+type ServiceImportValidator struct {
+	Client  client.Client
+	Log     common.Logger
+	decoder *admission.Decoder
+}
+
+// Handle validates the ServiceImport of an admission request.
+func (v *ServiceImportValidator) Handle(ctx context.Context, req admission.Request) admission.Response {
+	serviceImport := &v1alpha1.ServiceImport{}
+	if err := v.decoder.Decode(req, serviceImport); err != nil {
+		return admission.Errored(http.StatusBadRequest, err)
+	}
+
+	if errs := ValidateServiceImport(serviceImport); len(errs) > 0 {
+		return admission.Denied(strings.Join(errs, "; "))
+	}
+
+	if req.Operation == admissionv1.Update {
+		oldServiceImport := &v1alpha1.ServiceImport{}
+		if err := v.decoder.DecodeRaw(req.OldObject, oldServiceImport); err != nil {
+			return admission.Errored(http.StatusBadRequest, err)
+		}
+		return v.validateIPsUpdate(ctx, oldServiceImport, serviceImport)
+	}
+
+	return admission.Allowed("")
+}
+
+// InjectDecoder injects the decoder for admission request objects.
+func (v *ServiceImportValidator) InjectDecoder(d *admission.Decoder) error {
+	v.decoder = d
+	return nil
+}
+
+// validateIPsUpdate rejects changes to assigned ServiceImport IPs, unless the IPs follow the cluster IP of the
+// derived Service.
+func (v *ServiceImportValidator) validateIPsUpdate(ctx context.Context, oldServiceImport *v1alpha1.ServiceImport, serviceImport *v1alpha1.ServiceImport) admission.Response {
+	if len(oldServiceImport.Spec.IPs) == 0 || reflect.DeepEqual(oldServiceImport.Spec.IPs, serviceImport.Spec.IPs) {
+		return admission.Allowed("")
+	}
+
+	derivedServiceName, found := serviceImport.Annotations[controllers.DerivedServiceAnnotation]
+	if !found {
+		return admission.Denied("spec.ips is immutable once assigned")
+	}
+
+	derivedService := &v1.Service{}
+	namespacedName := types.NamespacedName{Namespace: serviceImport.Namespace, Name: derivedServiceName}
+	if err := v.Client.Get(ctx, namespacedName, derivedService); err != nil {
+		if errors.IsNotFound(err) {
+			return admission.Denied("spec.ips is immutable once assigned")
+		}
+		v.Log.Error(err, "error fetching derived service", "namespace", namespacedName.Namespace, "name", namespacedName.Name)
+		return admission.Errored(http.StatusInternalServerError, err)
+	}
+
+	if !reflect.DeepEqual(serviceImport.Spec.IPs, []string{derivedService.Spec.ClusterIP}) {
+		return admission.Denied(fmt.Sprintf("spec.ips is immutable once assigned, except to follow the cluster IP of Service %s",
+			namespacedName))
+	}
+
+	return admission.Allowed("")
+}
+
+// ValidateServiceImport returns the validation errors of a ServiceImport spec.
+func ValidateServiceImport(serviceImport *v1alpha1.ServiceImport) (errs []string) {
+	spec := serviceImport.Spec
+
+	if spec.Type != v1alpha1.ClusterSetIP && spec.Type != v1alpha1.Headless {
+		errs = append(errs, fmt.Sprintf("spec.type %q must be %s or %s", spec.Type, v1alpha1.ClusterSetIP, v1alpha1.Headless))
+	}
+
+	if len(spec.IPs) > 1 {
+		errs = append(errs, "spec.ips must contain at most one IP")
+	}
+	if spec.Type == v1alpha1.Headless && len(spec.IPs) > 0 {
+		errs = append(errs, "spec.ips must be empty for Headless ServiceImports")
+	}
+	for _, ip := range spec.IPs {
+		if net.ParseIP(ip) == nil {
+			errs = append(errs, fmt.Sprintf("spec.ips %q is not a valid IP address", ip))
+		}
+	}
+
+	if spec.SessionAffinity != "" && spec.SessionAffinity != v1.ServiceAffinityNone &&
+		spec.SessionAffinity != v1.ServiceAffinityClientIP {
+		errs = append(errs, fmt.Sprintf("spec.sessionAffinity %q must be %s or %s",
+			spec.SessionAffinity, v1.ServiceAffinityClientIP, v1.ServiceAffinityNone))
+	}
+
+	portNames := make(map[string]bool)
+	for i, port := range spec.Ports {
+		if port.Port < 1 || port.Port > 65535 {
+			errs = append(errs, fmt.Sprintf("spec.ports[%d].port %d must be between 1 and 65535", i, port.Port))
+		}
+
+		switch port.Protocol {
+		case "", v1.ProtocolTCP, v1.ProtocolUDP, v1.ProtocolSCTP:
+		default:
+			errs = append(errs, fmt.Sprintf("spec.ports[%d].protocol %q must be TCP, UDP or SCTP", i, port.Protocol))
+		}
+
+		if len(spec.Ports) > 1 && port.Name == "" {
+			errs = append(errs, fmt.Sprintf("spec.ports[%d].name is required when multiple ports are defined", i))
+		}
+		if port.Name != "" {
+			for _, msg := range validation.IsDNS1123Label(port.Name) {
+				errs = append(errs, fmt.Sprintf("spec.ports[%d].name %q: %s", i, port.Name, msg))
+			}
+			if portNames[port.Name] {
+				errs = append(errs, fmt.Sprintf("spec.ports[%d].name %q is not unique", i, port.Name))
+			}
+			portNames[port.Name] = true
+		}
+	}
+
+	return errs
+}
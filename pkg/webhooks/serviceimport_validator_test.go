@@ -0,0 +1,148 @@
+package webhooks
+
+import (
+	"context"
+	"encoding/json"
+	"github.com/aws/aws-cloud-map-mcs-controller-for-k8s/pkg/api/v1alpha1"
+	"github.com/aws/aws-cloud-map-mcs-controller-for-k8s/pkg/common"
+	"github.com/aws/aws-cloud-map-mcs-controller-for-k8s/pkg/controllers"
+	"github.com/aws/aws-cloud-map-mcs-controller-for-k8s/test"
+	testingLogger "github.com/go-logr/logr/testing"
+	"github.com/stretchr/testify/assert"
+	admissionv1 "k8s.io/api/admission/v1"
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	"sigs.k8s.io/controller-runtime/pkg/webhook/admission"
+	"testing"
+)
+
+func TestValidateServiceImport(t *testing.T) {
+	tests := []struct {
+		name    string
+		spec    v1alpha1.ServiceImportSpec
+		wantErr bool
+	}{
+		{
+			name: "valid",
+			spec: v1alpha1.ServiceImportSpec{
+				Type:  v1alpha1.ClusterSetIP,
+				IPs:   []string{"10.0.0.1"},
+				Ports: []v1alpha1.ServicePort{{Name: "http", Port: 80}, {Name: "https", Port: 443}},
+			},
+		},
+		{
+			name:    "invalid type",
+			spec:    v1alpha1.ServiceImportSpec{Type: "LoadBalancer"},
+			wantErr: true,
+		},
+		{
+			name:    "multiple ips",
+			spec:    v1alpha1.ServiceImportSpec{Type: v1alpha1.ClusterSetIP, IPs: []string{"10.0.0.1", "10.0.0.2"}},
+			wantErr: true,
+		},
+		{
+			name:    "invalid ip",
+			spec:    v1alpha1.ServiceImportSpec{Type: v1alpha1.ClusterSetIP, IPs: []string{"10.0.0"}},
+			wantErr: true,
+		},
+		{
+			name:    "headless with ip",
+			spec:    v1alpha1.ServiceImportSpec{Type: v1alpha1.Headless, IPs: []string{"10.0.0.1"}},
+			wantErr: true,
+		},
+		{
+			name:    "port out of range",
+			spec:    v1alpha1.ServiceImportSpec{Type: v1alpha1.ClusterSetIP, Ports: []v1alpha1.ServicePort{{Port: 0}}},
+			wantErr: true,
+		},
+		{
+			name: "unnamed ports",
+			spec: v1alpha1.ServiceImportSpec{
+				Type:  v1alpha1.ClusterSetIP,
+				Ports: []v1alpha1.ServicePort{{Port: 80}, {Port: 443}},
+			},
+			wantErr: true,
+		},
+		{
+			name: "duplicate port names",
+			spec: v1alpha1.ServiceImportSpec{
+				Type:  v1alpha1.ClusterSetIP,
+				Ports: []v1alpha1.ServicePort{{Name: "http", Port: 80}, {Name: "http", Port: 443}},
+			},
+			wantErr: true,
+		},
+		{
+			name: "invalid protocol",
+			spec: v1alpha1.ServiceImportSpec{
+				Type:  v1alpha1.ClusterSetIP,
+				Ports: []v1alpha1.ServicePort{{Port: 80, Protocol: "HTTP"}},
+			},
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			errs := ValidateServiceImport(&v1alpha1.ServiceImport{Spec: tt.spec})
+			assert.Equal(t, tt.wantErr, len(errs) > 0, errs)
+		})
+	}
+}
+
+func TestServiceImportValidator_Handle_IPsUpdate(t *testing.T) {
+	derivedService := &v1.Service{
+		ObjectMeta: metav1.ObjectMeta{Namespace: test.NsName, Name: "imported-svc"},
+		Spec:       v1.ServiceSpec{ClusterIP: "10.0.0.2"},
+	}
+
+	tests := []struct {
+		name    string
+		oldIPs  []string
+		newIPs  []string
+		allowed bool
+	}{
+		{name: "assign ip", oldIPs: []string{}, newIPs: []string{"10.0.0.1"}, allowed: true},
+		{name: "unchanged ip", oldIPs: []string{"10.0.0.1"}, newIPs: []string{"10.0.0.1"}, allowed: true},
+		{name: "follow derived service", oldIPs: []string{"10.0.0.1"}, newIPs: []string{"10.0.0.2"}, allowed: true},
+		{name: "change ip", oldIPs: []string{"10.0.0.1"}, newIPs: []string{"10.0.0.3"}, allowed: false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			validator := &ServiceImportValidator{
+				Client: fake.NewClientBuilder().WithScheme(testScheme()).
+					WithObjects(derivedService).Build(),
+				Log: common.NewLoggerWithLogr(testingLogger.TestLogger{T: t}),
+			}
+			decoder, err := admission.NewDecoder(testScheme())
+			assert.NoError(t, err)
+			assert.NoError(t, validator.InjectDecoder(decoder))
+
+			oldImport := testServiceImport(tt.oldIPs)
+			newImport := testServiceImport(tt.newIPs)
+
+			req := admissionRequest(t, admissionv1.Update, newImport)
+			raw, err := json.Marshal(oldImport)
+			assert.NoError(t, err)
+			req.OldObject = runtime.RawExtension{Raw: raw}
+
+			resp := validator.Handle(context.TODO(), req)
+			assert.Equal(t, tt.allowed, resp.Allowed, resp.Result)
+		})
+	}
+}
+
+func testServiceImport(ips []string) *v1alpha1.ServiceImport {
+	return &v1alpha1.ServiceImport{
+		TypeMeta: metav1.TypeMeta{APIVersion: v1alpha1.GroupVersion.String(), Kind: "ServiceImport"},
+		ObjectMeta: metav1.ObjectMeta{
+			Namespace:   test.NsName,
+			Name:        test.SvcName,
+			Annotations: map[string]string{controllers.DerivedServiceAnnotation: "imported-svc"},
+		},
+		Spec: v1alpha1.ServiceImportSpec{
+			Type: v1alpha1.ClusterSetIP,
+			IPs:  ips,
+		},
+	}
+}
@@ -28,6 +28,8 @@ const (
 	OpId1           = "operation-id-1"
 	OpId2           = "operation-id-2"
 	OpStart         = 1
+	ClusterId       = "cluster-id"
+	ClusterSetId    = "clusterset-id"
 )
 
 func GetTestHttpNamespace() *model.Namespace {
@@ -48,6 +50,7 @@ func GetTestDnsNamespace() *model.Namespace {
 
 func GetTestService() *model.Service {
 	return &model.Service{
+		Id:        SvcId,
 		Namespace: NsName,
 		Name:      SvcName,
 		Endpoints: []*model.Endpoint{GetTestEndpoint1(), GetTestEndpoint2()},
@@ -56,6 +59,7 @@ func GetTestService() *model.Service {
 
 func GetTestServiceWithEndpoint(endpoints []*model.Endpoint) *model.Service {
 	return &model.Service{
+		Id:        SvcId,
 		Namespace: NsName,
 		Name:      SvcName,
 		Endpoints: endpoints,